@@ -0,0 +1,624 @@
+// SPDX-License-Identifier: LGPL-3.0
+pragma solidity ^0.6.0;
+pragma experimental ABIEncoderV2;
+
+// layer2合约的以太坊实现，接口与layer2.py一致，资产地址为ERC20/ERC721/ERC1155合约地址的20字节
+
+interface IERC20 {
+    function transfer(address to, uint256 amount) external returns (bool);
+    function transferFrom(address from, address to, uint256 amount) external returns (bool);
+}
+
+interface IERC721 {
+    function transferFrom(address from, address to, uint256 tokenId) external;
+}
+
+interface IERC1155 {
+    function safeTransferFrom(address from, address to, uint256 id, uint256 amount, bytes calldata data) external;
+}
+
+contract Layer2 {
+    uint256 constant TOKEN_ENABLED = 1;
+    uint256 constant TOKEN_PAUSED = 2;
+    // OEP5对应ERC721，OEP8对应ERC1155
+    uint256 constant OEP5 = 5;
+    uint256 constant OEP8 = 8;
+    // 提现证明的路径终点：0 空子树，1 提现账户的叶子，2 其他账户的叶子
+    uint8 constant PROOF_EMPTY = 0;
+    uint8 constant PROOF_LEAF = 1;
+    uint8 constant PROOF_OTHER_LEAF = 2;
+    uint256 constant SMT_DEPTH = 256;
+
+    struct StateRoot {
+        string stateRootHash;
+        uint256 height;
+        string version;
+    }
+
+    // status: 0 未提交, 1 已提交到layer2, 2 已退还
+    struct DepositRecord {
+        address player;
+        uint256 amount;
+        uint256 height;
+        uint256 status;
+        bytes assetAddress;
+        bytes tokenId;
+    }
+
+    // 提交记录，挑战成功时用于回滚，block为提交时的区块高度
+    struct Commit {
+        uint256 block;
+        uint256 preHeight;
+        uint256[] depositIds;
+        uint256 withdrawStart;
+    }
+
+    // status: 0 未赎回, 1 已赎回
+    struct WithdrawRecord {
+        uint256 amount;
+        address toAddress;
+        uint256 height;
+        uint256 status;
+        bytes assetAddress;
+        bytes tokenId;
+    }
+
+    // 强制退出，status: 0 申请中, 1 已支付, 2 已取消。height为证明的layer2高度，block为申请时的区块高度
+    struct ExitRecord {
+        address player;
+        bytes assetAddress;
+        uint256 amount;
+        uint256 height;
+        uint256 block;
+        uint256 status;
+        uint256 challengeHeight;
+        uint256 challengeAmount;
+    }
+
+    address public operator;
+    // 轮换中的新operator和可以切换的区块高度，以太坊交易只有一个签名，过渡期内仍由旧密钥发送operator的交易
+    address public pendingOperator;
+    uint256 public operatorSwitchBlock;
+    uint256 public confirmHeight;
+    uint256 currentHeight;
+    uint256 currentDepositId = 1;
+    uint256 currentWithdrawId = 1;
+    mapping(uint256 => StateRoot) stateRoots;
+    mapping(uint256 => DepositRecord) deposits;
+    mapping(uint256 => WithdrawRecord) withdraws;
+    mapping(bytes => uint256) tokens;
+    mapping(bytes => uint256) nftStandards;
+    mapping(bytes => bytes) boundLayer2Assets;
+    mapping(uint256 => Commit) commits;
+    // 挑战期为区块数，为0时提交立即生效且不能被挑战
+    uint256 public challengePeriod;
+    bool challengeInited;
+    mapping(address => bool) challengers;
+    uint256 currentExitId = 1;
+    mapping(uint256 => ExitRecord) exits;
+    mapping(bytes => uint256) exitAccounts;
+    // 连续massExitPeriod个区块没有提交时任何人都可以开启批量退出，开启后不能再提交，用户以最新提交的状态根中的余额领取资产
+    uint256 public massExitPeriod;
+    uint256 lastCommitBlock;
+    uint256 massExitBlock;
+    mapping(bytes => uint256) massExitClaims;
+
+    event DepositEvent(uint256 id, address player, uint256 amount, uint256 height, uint256 status, bytes assetAddress);
+    event NFTDepositEvent(uint256 id, address player, uint256 amount, uint256 height, uint256 status, bytes assetAddress, bytes tokenId);
+    event WithdrawEvent(uint256 id, uint256 amount, address toAddress, uint256 height, uint256 status, bytes assetAddress);
+    event NFTWithdrawEvent(uint256 id, uint256 amount, address toAddress, uint256 height, uint256 status, bytes assetAddress, bytes tokenId);
+    event UpdateStateEvent(string stateRootHash, uint256 height, string version);
+    event RevertStateEvent(uint256 height, uint256 preHeight);
+    event ExitRequestEvent(uint256 id, address player, bytes assetAddress, uint256 amount, uint256 height, uint256 blockHeight);
+    event ExitChallengeEvent(uint256 id, uint256 height, uint256 amount);
+    event ExitFinalizeEvent(uint256 id, address player, bytes assetAddress, uint256 amount, uint256 status);
+    event MassExitStartEvent(uint256 blockHeight, uint256 height);
+    event OperatorRegisterEvent(address operator, address newOperator, uint256 switchBlock);
+    event OperatorSwitchEvent(address operator);
+    event OperatorCancelEvent(address newOperator);
+    event MassExitClaimEvent(address player, bytes assetAddress, uint256 amount, uint256 height);
+
+    modifier onlyOperator() {
+        require(msg.sender == operator, "only operator");
+        _;
+    }
+
+    constructor(address _operator, string memory stateRootHash, uint256 height, string memory version, uint256 _confirmHeight) public {
+        require(_confirmHeight > 0, "invalid confirm height");
+        operator = _operator;
+        confirmHeight = _confirmHeight;
+        currentHeight = height;
+        stateRoots[height] = StateRoot(stateRootHash, height, version);
+        lastCommitBlock = block.number;
+    }
+
+    // 用户为了使用Layer2发起交易将ERC20资产质押在合约中
+    function deposit(address player, uint256 amount, bytes memory assetAddress) public returns (bool) {
+        require(msg.sender == player, "player must be sender");
+        require(tokens[assetAddress] != TOKEN_PAUSED, "token is paused");
+        require(IERC20(toAddress(assetAddress)).transferFrom(player, address(this), amount), "transfer failed");
+
+        uint256 id = currentDepositId;
+        currentDepositId = id + 1;
+        deposits[id] = DepositRecord(player, amount, currentHeight, 0, assetAddress, "");
+        emit DepositEvent(id, player, amount, currentHeight, 0, assetAddress);
+        return true;
+    }
+
+    // 用户将ERC721或ERC1155资产质押在合约中，ERC721的数量为1
+    function depositNFT(address player, bytes memory assetAddress, bytes memory tokenId, uint256 amount, uint256 standard) public returns (bool) {
+        require(msg.sender == player, "player must be sender");
+        require(tokenId.length > 0 && amount > 0, "invalid token id or amount");
+        require(tokens[assetAddress] != TOKEN_PAUSED, "token is paused");
+        // 同一资产只能使用一种标准，提现时按该标准转出
+        if (nftStandards[assetAddress] != 0) {
+            require(nftStandards[assetAddress] == standard, "standard mismatch");
+        } else {
+            nftStandards[assetAddress] = standard;
+        }
+        transferNFT(assetAddress, player, address(this), tokenId, amount);
+
+        uint256 id = currentDepositId;
+        currentDepositId = id + 1;
+        deposits[id] = DepositRecord(player, amount, currentHeight, 0, assetAddress, tokenId);
+        emit NFTDepositEvent(id, player, amount, currentHeight, 0, assetAddress, tokenId);
+        return true;
+    }
+
+    // 用户将质押在合约中用于Layer2交易的资产赎回，批量退出开启后不再等待确认高度
+    function withdraw(uint256 withdrawId) public returns (bool) {
+        WithdrawRecord storage record = withdraws[withdrawId];
+        require(record.amount > 0, "withdraw not found");
+        require(record.status == 0, "withdraw is done");
+        require(massExitBlock > 0 || currentHeight >= record.height + confirmHeight, "withdraw is not confirmed");
+        require(isFinal(record.height), "withdraw is in challenge period");
+        record.status = 1;
+        if (record.tokenId.length > 0) {
+            transferNFT(record.assetAddress, address(this), record.toAddress, record.tokenId, record.amount);
+            emit NFTWithdrawEvent(withdrawId, record.amount, record.toAddress, record.height, 1, record.assetAddress, record.tokenId);
+        } else {
+            require(IERC20(toAddress(record.assetAddress)).transfer(record.toAddress, record.amount), "transfer failed");
+            emit WithdrawEvent(withdrawId, record.amount, record.toAddress, record.height, 1, record.assetAddress);
+        }
+        return true;
+    }
+
+    // operator登记资产，enabled为1表示开启，为2表示暂停充值
+    function setToken(bytes memory assetAddress, uint256 enabled) public onlyOperator returns (bool) {
+        require(assetAddress.length == 20, "invalid asset address");
+        require(enabled == TOKEN_ENABLED || enabled == TOKEN_PAUSED, "invalid token state");
+        tokens[assetAddress] = enabled;
+        return true;
+    }
+
+    // operator登记新密钥，过渡期为区块数，过渡期后新密钥可以切换为operator
+    function registerOperator(address newOperator, uint256 transitionPeriod) public onlyOperator returns (bool) {
+        require(pendingOperator == address(0), "operator is rotating");
+        require(newOperator != address(0), "invalid operator");
+        pendingOperator = newOperator;
+        operatorSwitchBlock = block.number + transitionPeriod;
+        emit OperatorRegisterEvent(operator, newOperator, operatorSwitchBlock);
+        return true;
+    }
+
+    // 过渡期后新密钥切换为operator，旧密钥失效
+    function switchOperator() public returns (bool) {
+        require(pendingOperator != address(0), "operator is not rotating");
+        require(msg.sender == pendingOperator, "only new operator");
+        require(block.number >= operatorSwitchBlock, "in transition period");
+        operator = pendingOperator;
+        pendingOperator = address(0);
+        operatorSwitchBlock = 0;
+        emit OperatorSwitchEvent(operator);
+        return true;
+    }
+
+    // 切换前旧密钥可以取消轮换，新密钥丢失时也可以取消
+    function cancelOperator() public onlyOperator returns (bool) {
+        require(pendingOperator != address(0), "operator is not rotating");
+        emit OperatorCancelEvent(pendingOperator);
+        pendingOperator = address(0);
+        operatorSwitchBlock = 0;
+        return true;
+    }
+
+    // 获取operator，轮换中的新operator和可以切换的区块高度
+    function getOperator() public view returns (address, address, uint256) {
+        return (operator, pendingOperator, operatorSwitchBlock);
+    }
+
+    // 获取资产的登记状态，0表示未登记
+    function getToken(bytes memory assetAddress) public view returns (uint256) {
+        return tokens[assetAddress];
+    }
+
+    // operator退还未登记或暂停资产的充值，退还后的充值不能再提交到layer2。批量退出开启后任何人都可以退还未提交的充值
+    function refundDeposit(uint256 depositId) public returns (bool) {
+        require(msg.sender == operator || massExitBlock > 0, "only operator");
+        DepositRecord storage record = deposits[depositId];
+        require(record.player != address(0), "deposit not found");
+        require(record.status == 0, "deposit is committed or refunded");
+        record.status = 2;
+        if (record.tokenId.length > 0) {
+            transferNFT(record.assetAddress, address(this), record.player, record.tokenId, record.amount);
+        } else {
+            require(IERC20(toAddress(record.assetAddress)).transfer(record.player, record.amount), "transfer failed");
+        }
+        return true;
+    }
+
+    // 更新全局的状态根，可以聚合多个layer2区块一次提交，只保存最后一个高度的状态根，每笔提现需要附带提现账户在状态根中的证明
+    function updateState(string memory stateRootHash, uint256 height, string memory version, uint256[] memory depositIds,
+        uint256[] memory withdrawAmounts, address[] memory toAddresses, bytes[] memory assetAddresses, bytes[] memory tokenIds,
+        bytes[] memory layer2Assets, bytes[] memory withdrawProofs)
+        public onlyOperator returns (bool) {
+        require(massExitBlock == 0, "mass exit is started");
+        require(currentHeight < height, "height is committed");
+        require(withdrawAmounts.length == toAddresses.length && withdrawAmounts.length == assetAddresses.length &&
+            withdrawAmounts.length == tokenIds.length, "invalid withdraws");
+        commits[height] = Commit(block.number, currentHeight, depositIds, currentWithdrawId);
+        currentHeight = height;
+        lastCommitBlock = block.number;
+        stateRoots[height] = StateRoot(stateRootHash, height, version);
+        for (uint256 i = 0; i < depositIds.length; i++) {
+            DepositRecord storage record = deposits[depositIds[i]];
+            if (record.player != address(0)) {
+                require(record.status == 0, "deposit is committed or refunded");
+                record.status = 1;
+            }
+        }
+        verifyWithdrawProofs(stateRootHash, toAddresses, assetAddresses, layer2Assets, withdrawProofs);
+        createWithdraws(height, withdrawAmounts, toAddresses, assetAddresses, tokenIds);
+        emit UpdateStateEvent(stateRootHash, height, version);
+        return true;
+    }
+
+    // operator设置挑战期和挑战者，只能设置一次。以太坊无法验证layer2记账人的签名，因此只有登记的挑战者可以挑战
+    function initChallenge(uint256 period, address[] memory _challengers) public onlyOperator returns (bool) {
+        require(!challengeInited, "challenge is initialized");
+        challengeInited = true;
+        challengePeriod = period;
+        for (uint256 i = 0; i < _challengers.length; i++) {
+            challengers[_challengers[i]] = true;
+        }
+        return true;
+    }
+
+    // 挑战者在挑战期内提交欺诈证明，证明为layer2记账人签名的该高度的layer2状态，状态根与提交的不一致时回滚该高度及之后的所有提交
+    function challengeState(uint256 height, bytes memory layer2State) public returns (bool) {
+        require(challengers[msg.sender], "only challenger");
+        Commit storage commit = commits[height];
+        require(commit.block > 0, "commit not found");
+        require(!isFinal(height), "commit is final");
+        // layer2状态格式：[version, height, statesRoot, sigData]，height为小端序
+        require(layer2State.length >= 37, "invalid layer2 state");
+        uint256 stateHeight;
+        for (uint256 i = 4; i > 0; i--) {
+            stateHeight = (stateHeight << 8) | uint256(uint8(layer2State[i]));
+        }
+        require(stateHeight == height, "height mismatch");
+        require(readBytes32(layer2State, 5) != parseStateRoot(stateRoots[height].stateRootHash), "state root is the same");
+        revertState(height, commit.preHeight);
+        return true;
+    }
+
+    // 用户以最新提交的状态根中layer2余额的证明申请强制退出，operator冻结layer2账户，挑战期后任何人都可以完成退出
+    function requestExit(address player, bytes memory assetAddress, bytes memory value, bytes memory proof) public returns (bool) {
+        require(msg.sender == player, "player must be sender");
+        // 挑战期内operator冻结layer2账户，并用冻结后提交的状态根挑战更低的余额
+        require(challengePeriod > 0, "challenge period is not set");
+        require(massExitBlock == 0, "mass exit is started");
+        require(nftStandards[assetAddress] == 0, "nft can not exit");
+        bytes memory accountKey = abi.encodePacked(assetAddress, player);
+        require(exitAccounts[accountKey] == 0, "account is exited");
+        uint256 amount = provenBalance(currentHeight, assetAddress, player, value, proof);
+        require(amount > 0, "empty balance");
+
+        uint256 id = currentExitId;
+        currentExitId = id + 1;
+        exits[id] = ExitRecord(player, assetAddress, amount, currentHeight, block.number, 0, 0, 0);
+        exitAccounts[accountKey] = id;
+        emit ExitRequestEvent(id, player, assetAddress, amount, currentHeight, block.number);
+        return true;
+    }
+
+    // 在挑战期内以申请之后提交的状态根中更低的余额挑战退出，冻结后的账户余额不会再减少
+    function challengeExit(uint256 exitId, uint256 height, bytes memory value, bytes memory proof) public returns (bool) {
+        ExitRecord storage record = exits[exitId];
+        require(record.player != address(0), "exit not found");
+        require(record.status == 0, "exit is done");
+        require(block.number < record.block + challengePeriod, "exit is final");
+        require(height > record.height, "height is not after the exit");
+        uint256 amount = provenBalance(height, record.assetAddress, record.player, value, proof);
+        require(amount < exitAmount(record), "balance is not lower");
+        record.challengeHeight = height;
+        record.challengeAmount = amount;
+        emit ExitChallengeEvent(exitId, height, amount);
+        return true;
+    }
+
+    // 挑战期后任何人都可以完成退出，operator不在线时也可以完成。证明所用的状态根已被回滚时取消退出，用户可以重新申请
+    function finalizeExit(uint256 exitId) public returns (bool) {
+        ExitRecord storage record = exits[exitId];
+        require(record.player != address(0), "exit not found");
+        require(record.status == 0, "exit is done");
+        require(block.number >= record.block + challengePeriod, "exit is in challenge period");
+        if (bytes(stateRoots[record.height].stateRootHash).length == 0) {
+            record.status = 2;
+            delete exitAccounts[abi.encodePacked(record.assetAddress, record.player)];
+            emit ExitFinalizeEvent(exitId, record.player, record.assetAddress, 0, 2);
+            return true;
+        }
+        // 退出后账户记录保留，同一账户的同一资产不能再次退出
+        record.status = 1;
+        uint256 amount = exitAmount(record);
+        if (amount > 0) {
+            require(IERC20(toAddress(record.assetAddress)).transfer(record.player, amount), "transfer failed");
+        }
+        emit ExitFinalizeEvent(exitId, record.player, record.assetAddress, amount, 1);
+        return true;
+    }
+
+    // 获取退出记录
+    function getExit(uint256 exitId) public view returns (address, bytes memory, uint256, uint256, uint256, uint256) {
+        ExitRecord storage record = exits[exitId];
+        return (record.player, record.assetAddress, exitAmount(record), record.height, record.block, record.status);
+    }
+
+    // operator设置批量退出的区块数，只能设置一次
+    function initMassExit(uint256 period) public onlyOperator returns (bool) {
+        require(massExitPeriod == 0, "mass exit is initialized");
+        require(period > 0, "invalid mass exit period");
+        massExitPeriod = period;
+        return true;
+    }
+
+    // operator连续massExitPeriod个区块没有提交时任何人都可以开启批量退出，开启后不能关闭
+    function startMassExit() public returns (bool) {
+        require(massExitPeriod > 0, "mass exit is not initialized");
+        require(massExitBlock == 0, "mass exit is started");
+        require(block.number >= lastCommitBlock + massExitPeriod, "operator is committing");
+        massExitBlock = block.number;
+        emit MassExitStartEvent(block.number, currentHeight);
+        return true;
+    }
+
+    // 批量退出开启且最新提交已过挑战期后，用户以最新提交的状态根中的余额证明领取资产，每个账户的每种资产只能领取一次
+    function claimMassExit(address player, bytes memory assetAddress, bytes memory value, bytes memory proof) public returns (bool) {
+        require(massExitBlock > 0, "mass exit is not started");
+        require(isFinal(currentHeight), "commit is in challenge period");
+        require(nftStandards[assetAddress] == 0, "nft can not exit");
+        bytes memory accountKey = abi.encodePacked(assetAddress, player);
+        // 申请过强制退出的账户通过finalizeExit退出
+        require(exitAccounts[accountKey] == 0, "account is exited");
+        require(massExitClaims[accountKey] == 0, "account is claimed");
+        uint256 amount = provenBalance(currentHeight, assetAddress, player, value, proof);
+        require(amount > 0, "empty balance");
+        massExitClaims[accountKey] = amount;
+        require(IERC20(toAddress(assetAddress)).transfer(player, amount), "transfer failed");
+        emit MassExitClaimEvent(player, assetAddress, amount, currentHeight);
+        return true;
+    }
+
+    // 获取批量退出开启时的区块高度，未开启时为0
+    function getMassExitHeight() public view returns (uint256) {
+        return massExitBlock;
+    }
+
+    // 根据高度获取状态根信息
+    function getStateRootByHeight(uint256 height) public view returns (string memory, uint256, string memory) {
+        StateRoot storage stateRoot = stateRoots[height];
+        return (stateRoot.stateRootHash, stateRoot.height, stateRoot.version);
+    }
+
+    // 获取最新提交的layer2高度
+    function getCurrentHeight() public view returns (uint256) {
+        return currentHeight;
+    }
+
+    function isFinal(uint256 height) internal view returns (bool) {
+        Commit storage commit = commits[height];
+        if (challengePeriod == 0 || commit.block == 0) {
+            return true;
+        }
+        return block.number >= commit.block + challengePeriod;
+    }
+
+    // 从最新的提交开始回滚到height，回滚的充值可以再次提交，回滚的提现被删除
+    function revertState(uint256 height, uint256 preHeight) internal {
+        uint256 revertHeight = currentHeight;
+        uint256 withdrawStart = currentWithdrawId;
+        while (revertHeight >= height) {
+            Commit storage commit = commits[revertHeight];
+            for (uint256 i = 0; i < commit.depositIds.length; i++) {
+                DepositRecord storage record = deposits[commit.depositIds[i]];
+                if (record.player != address(0) && record.status == 1) {
+                    record.status = 0;
+                }
+            }
+            withdrawStart = commit.withdrawStart;
+            uint256 pre = commit.preHeight;
+            delete commits[revertHeight];
+            delete stateRoots[revertHeight];
+            revertHeight = pre;
+        }
+        for (uint256 id = withdrawStart; id < currentWithdrawId; id++) {
+            delete withdraws[id];
+        }
+        currentWithdrawId = withdrawStart;
+        currentHeight = preHeight;
+        emit RevertStateEvent(height, preHeight);
+    }
+
+    // 证明账户在height的状态根中的余额，账户不存在时余额为0
+    function provenBalance(uint256 height, bytes memory assetAddress, address player, bytes memory value, bytes memory proof)
+        internal view returns (uint256) {
+        string storage stateRootHash = stateRoots[height].stateRootHash;
+        require(bytes(stateRootHash).length > 0, "state root not found");
+        bytes memory layer2Asset = boundLayer2Assets[assetAddress];
+        require(layer2Asset.length == 20, "layer2 asset is not bound");
+        bytes memory key = abi.encodePacked(layer2Asset, player);
+        require(verifyWithdrawProof(parseStateRoot(stateRootHash), key, proof), "invalid balance proof");
+        if (uint8(proof[0]) != PROOF_LEAF) {
+            return 0;
+        }
+        require(readBytes32(proof, 1) == sha256(value), "value mismatch");
+        // 账户的值为序列化的存储项：版本号，长度和小端序的余额
+        require(value.length > 2 && value.length <= 34 && uint8(value[1]) == value.length - 2, "invalid balance value");
+        uint256 balance;
+        for (uint256 i = value.length; i > 2; i--) {
+            balance = (balance << 8) | uint256(uint8(value[i - 1]));
+        }
+        return balance;
+    }
+
+    // 挑战所用的状态根被回滚时恢复申请的数量
+    function exitAmount(ExitRecord storage record) internal view returns (uint256) {
+        if (record.challengeHeight > 0 && bytes(stateRoots[record.challengeHeight].stateRootHash).length > 0) {
+            return record.challengeAmount;
+        }
+        return record.amount;
+    }
+
+    function createWithdraws(uint256 height, uint256[] memory withdrawAmounts, address[] memory toAddresses,
+        bytes[] memory assetAddresses, bytes[] memory tokenIds) internal {
+        uint256 id = currentWithdrawId;
+        for (uint256 i = 0; i < withdrawAmounts.length; i++) {
+            require(withdrawAmounts[i] > 0, "invalid withdraw amount");
+            withdraws[id] = WithdrawRecord(withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i], tokenIds[i]);
+            if (tokenIds[i].length > 0) {
+                emit NFTWithdrawEvent(id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i], tokenIds[i]);
+            } else {
+                emit WithdrawEvent(id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i]);
+            }
+            id++;
+        }
+        currentWithdrawId = id;
+    }
+
+    function verifyWithdrawProofs(string memory stateRootHash, address[] memory toAddresses, bytes[] memory assetAddresses,
+        bytes[] memory layer2Assets, bytes[] memory withdrawProofs) internal {
+        require(toAddresses.length == layer2Assets.length && toAddresses.length == withdrawProofs.length, "invalid withdraw proofs");
+        bytes32 root = parseStateRoot(stateRootHash);
+        for (uint256 i = 0; i < toAddresses.length; i++) {
+            require(layer2Assets[i].length == 20, "invalid layer2 asset");
+            // 资产第一次提现时绑定layer2资产地址，之后的提现必须使用同一地址
+            bytes memory saved = boundLayer2Assets[assetAddresses[i]];
+            if (saved.length > 0) {
+                require(keccak256(saved) == keccak256(layer2Assets[i]), "layer2 asset mismatch");
+            } else {
+                boundLayer2Assets[assetAddresses[i]] = layer2Assets[i];
+            }
+            bytes memory key = abi.encodePacked(layer2Assets[i], toAddresses[i]);
+            require(verifyWithdrawProof(root, key, withdrawProofs[i]), "invalid withdraw proof");
+        }
+    }
+
+    // 证明格式：[路径终点, 其他账户叶子的路径和值哈希 | 提现账户叶子的值哈希, 从根开始的兄弟节点哈希]
+    function verifyWithdrawProof(bytes32 root, bytes memory key, bytes memory proof) internal pure returns (bool) {
+        require(proof.length > 0, "empty withdraw proof");
+        bytes32 path = sha256(key);
+        bytes32 hash;
+        uint256 offset = 1;
+        uint8 mode = uint8(proof[0]);
+        if (mode == PROOF_LEAF) {
+            require(proof.length >= 33, "invalid withdraw proof");
+            hash = sha256(abi.encodePacked(uint8(0), path, readBytes32(proof, 1)));
+            offset = 33;
+        } else if (mode == PROOF_OTHER_LEAF) {
+            require(proof.length >= 65, "invalid withdraw proof");
+            bytes32 leafPath = readBytes32(proof, 1);
+            require(leafPath != path, "invalid withdraw proof");
+            hash = sha256(abi.encodePacked(uint8(0), leafPath, readBytes32(proof, 33)));
+            offset = 65;
+        } else {
+            require(mode == PROOF_EMPTY, "invalid withdraw proof");
+        }
+        require((proof.length - offset) % 32 == 0, "invalid withdraw proof");
+        uint256 depth = (proof.length - offset) / 32;
+        require(depth <= SMT_DEPTH, "invalid withdraw proof");
+        if (mode == PROOF_OTHER_LEAF) {
+            bytes32 leafPath = readBytes32(proof, 1);
+            for (uint256 i = 0; i < depth; i++) {
+                require(bitAt(leafPath, i) == bitAt(path, i), "invalid withdraw proof");
+            }
+        }
+        for (uint256 i = depth; i > 0; i--) {
+            bytes32 sibling = readBytes32(proof, offset + 32 * (i - 1));
+            if (bitAt(path, i - 1) == 0) {
+                hash = sha256(abi.encodePacked(uint8(1), hash, sibling));
+            } else {
+                hash = sha256(abi.encodePacked(uint8(1), sibling, hash));
+            }
+        }
+        return hash == root;
+    }
+
+    function bitAt(bytes32 path, uint256 depth) internal pure returns (uint256) {
+        return (uint256(path) >> (255 - depth)) & 1;
+    }
+
+    function readBytes32(bytes memory data, uint256 offset) internal pure returns (bytes32 value) {
+        assembly {
+            value := mload(add(add(data, 32), offset))
+        }
+    }
+
+    // 状态根哈希为字节逆序的十六进制字符串
+    function parseStateRoot(string memory stateRootHash) internal pure returns (bytes32) {
+        bytes memory data = bytes(stateRootHash);
+        require(data.length == 64, "invalid state root hash");
+        uint256 root;
+        for (uint256 i = 0; i < 32; i++) {
+            uint256 value = hexValue(uint8(data[2 * i])) * 16 + hexValue(uint8(data[2 * i + 1]));
+            root |= value << (8 * i);
+        }
+        return bytes32(root);
+    }
+
+    function hexValue(uint8 c) internal pure returns (uint256) {
+        if (c >= 48 && c <= 57) {
+            return c - 48;
+        }
+        if (c >= 97 && c <= 102) {
+            return c - 87;
+        }
+        if (c >= 65 && c <= 70) {
+            return c - 55;
+        }
+        revert("invalid state root hash");
+    }
+
+    function transferNFT(bytes memory assetAddress, address from, address to, bytes memory tokenId, uint256 amount) internal {
+        uint256 standard = nftStandards[assetAddress];
+        uint256 id = toUint(tokenId);
+        if (standard == OEP5) {
+            require(amount == 1, "invalid amount");
+            IERC721(toAddress(assetAddress)).transferFrom(from, to, id);
+        } else if (standard == OEP8) {
+            IERC1155(toAddress(assetAddress)).safeTransferFrom(from, to, id, amount, "");
+        } else {
+            revert("invalid standard");
+        }
+    }
+
+    // ERC1155转入合约时需要确认接收
+    function onERC1155Received(address, address, uint256, uint256, bytes calldata) external pure returns (bytes4) {
+        return this.onERC1155Received.selector;
+    }
+
+    function toAddress(bytes memory data) internal pure returns (address addr) {
+        require(data.length == 20, "invalid asset address");
+        assembly {
+            addr := mload(add(data, 20))
+        }
+    }
+
+    // tokenId为大端字节序的uint256
+    function toUint(bytes memory data) internal pure returns (uint256 value) {
+        require(data.length <= 32, "invalid token id");
+        for (uint256 i = 0; i < data.length; i++) {
+            value = (value << 8) | uint256(uint8(data[i]));
+        }
+    }
+}
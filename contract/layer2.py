@@ -1,12 +1,13 @@
 OntCversion = '2.0.0'
-from ontology.builtins import state, concat
+from ontology.builtins import state, concat, sha256
 from ontology.interop.Ontology.Native import Invoke
+from ontology.interop.Ontology.Runtime import VerifyMutiSig
 from ontology.interop.System.Action import RegisterAction
 from ontology.interop.System.App import DynamicAppCall
 from ontology.interop.System.Blockchain import GetHeight
 from ontology.interop.System.ExecutionEngine import GetExecutingScriptHash
 from ontology.interop.System.Runtime import CheckWitness, Serialize, Deserialize, Notify
-from ontology.interop.System.Storage import GetContext, Get, Put
+from ontology.interop.System.Storage import GetContext, Get, Put, Delete
 from ontology.libont import bytearray_reverse
 
 ONGAddress = bytearray(b'\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02')
@@ -19,6 +20,12 @@ DepositEvent = RegisterAction('deposit', 'depositId', 'fromAddress', 'amount', '
 
 WithdrawEvent = RegisterAction('withdraw', 'withdrawId', 'amount', 'toAddress', 'height', 'status', 'assetAddress')
 
+NFTDepositEvent = RegisterAction('depositNFT', 'depositId', 'fromAddress', 'amount', 'height', 'status', 'assetAddress', 'tokenId')
+
+NFTWithdrawEvent = RegisterAction('withdrawNFT', 'withdrawId', 'amount', 'toAddress', 'height', 'status', 'assetAddress', 'tokenId')
+
+ExitEvent = RegisterAction('requestExit', 'exitId', 'player', 'assetAddress', 'amount', 'height', 'blockHeight')
+
 DEPOSIT_PREFIX = 'deposit'
 
 WITHDRAW_PREFIX = 'withdraw'
@@ -27,6 +34,8 @@ CURRENT_DEPOSIT_ID = 'currentDepositId'
 
 CURRENT_WITHDRAW_ID = 'currentWithdrawId'
 
+PAID_WITHDRAW_ID = 'paidWithdrawId'
+
 CURRENT_HEIGHT = 'currentHeight'
 
 Current_STATE_PREFIX = 'stateRoot'
@@ -37,6 +46,62 @@ INITED = 'Initialized'
 
 OPERATOR_ADDRESS = 'operator'
 
+# 轮换中的新operator和可以切换的区块高度，过渡期内operator的交易需要新旧密钥同时签名
+PENDING_OPERATOR = 'pendingOperator'
+
+OPERATOR_SWITCH_BLOCK = 'operatorSwitchBlock'
+
+TOKEN_PREFIX = 'token'
+
+TOKEN_ENABLED = 1
+
+TOKEN_PAUSED = 2
+
+NFT_PREFIX = 'nft'
+
+OEP5 = 5
+
+OEP8 = 8
+
+LAYER2_ASSET_PREFIX = 'layer2Asset'
+
+# 提现证明的路径终点：0 空子树，1 提现账户的叶子，2 其他账户的叶子
+PROOF_EMPTY = 0
+
+PROOF_LEAF = 1
+
+PROOF_OTHER_LEAF = 2
+
+SMT_DEPTH = 256
+
+HEX_CHARS = '0123456789abcdef'
+
+# 提交记录：[提交时的区块高度, 上一次提交的layer2高度, 充值ID, 第一个提现ID]
+COMMIT_PREFIX = 'commit'
+
+CHALLENGE_PERIOD = 'challengePeriod'
+
+BOOKKEEPERS = 'bookkeepers'
+
+BOOKKEEPER_M = 'bookkeeperM'
+
+# 退出记录：[退出ID, 用户地址, 资产地址, 数量, 证明的layer2高度, 申请时的区块高度, 状态, 挑战的layer2高度, 挑战的数量]
+# 状态0申请中，1已支付，2已取消
+EXIT_PREFIX = 'exit'
+
+EXIT_ACCOUNT_PREFIX = 'exitAccount'
+
+CURRENT_EXIT_ID = 'currentExitId'
+
+# 连续massExitPeriod个区块没有提交时任何人都可以开启批量退出，开启后不能再提交，用户以最新提交的状态根中的余额领取资产
+MASS_EXIT_PERIOD = 'massExitPeriod'
+
+LAST_COMMIT_BLOCK = 'lastCommitBlock'
+
+MASS_EXIT_HEIGHT = 'massExitHeight'
+
+MASS_EXIT_CLAIM_PREFIX = 'massExitClaim'
+
 
 def Main(operation, args):
     ## FOR OPERATOR INVOkE ONLY
@@ -54,8 +119,17 @@ def Main(operation, args):
         assetAddress = args[2]
         return deposit(player, amount, assetAddress)
 
+    if operation == 'depositNFT':
+        assert (len(args) == 5)
+        player = args[0]
+        assetAddress = args[1]
+        tokenId = args[2]
+        amount = args[3]
+        standard = args[4]
+        return depositNFT(player, assetAddress, tokenId, amount, standard)
+
     if operation == 'updateState':
-        assert (len(args) == 7)
+        assert (len(args) == 10)
         stateRootHash = args[0]
         height = args[1]
         version = args[2]
@@ -63,12 +137,117 @@ def Main(operation, args):
         withdrawAmounts = args[4]
         toAddresses = args[5]
         assetAddresses = args[6]
-        return updateState(stateRootHash, height, version, depositIds, withdrawAmounts, toAddresses, assetAddresses)
+        tokenIds = args[7]
+        layer2Assets = args[8]
+        withdrawProofs = args[9]
+        return updateState(stateRootHash, height, version, depositIds, withdrawAmounts, toAddresses, assetAddresses, tokenIds, layer2Assets, withdrawProofs)
+
+    if operation == 'initChallenge':
+        assert (len(args) == 3)
+        challengePeriod = args[0]
+        bookkeepers = args[1]
+        m = args[2]
+        return initChallenge(challengePeriod, bookkeepers, m)
+
+    if operation == 'challengeState':
+        assert (len(args) == 2)
+        height = args[0]
+        layer2State = args[1]
+        return challengeState(height, layer2State)
+
+    if operation == 'requestExit':
+        assert (len(args) == 4)
+        player = args[0]
+        assetAddress = args[1]
+        value = args[2]
+        proof = args[3]
+        return requestExit(player, assetAddress, value, proof)
+
+    if operation == 'challengeExit':
+        assert (len(args) == 4)
+        exitId = args[0]
+        height = args[1]
+        value = args[2]
+        proof = args[3]
+        return challengeExit(exitId, height, value, proof)
+
+    if operation == 'finalizeExit':
+        assert (len(args) == 1)
+        exitId = args[0]
+        return finalizeExit(exitId)
+
+    if operation == 'initMassExit':
+        assert (len(args) == 1)
+        massExitPeriod = args[0]
+        return initMassExit(massExitPeriod)
+
+    if operation == 'startMassExit':
+        assert (len(args) == 0)
+        return startMassExit()
+
+    if operation == 'claimMassExit':
+        assert (len(args) == 4)
+        player = args[0]
+        assetAddress = args[1]
+        value = args[2]
+        proof = args[3]
+        return claimMassExit(player, assetAddress, value, proof)
+
+    if operation == 'payMassExitWithdraws':
+        assert (len(args) == 0)
+        return payMassExitWithdraws()
+
+    if operation == 'setToken':
+        assert (len(args) == 2)
+        assetAddress = args[0]
+        enabled = args[1]
+        return setToken(assetAddress, enabled)
+
+    if operation == 'refundDeposit':
+        assert (len(args) == 1)
+        depositId = args[0]
+        return refundDeposit(depositId)
+
+    if operation == 'registerOperator':
+        assert (len(args) == 2)
+        newOperator = args[0]
+        transitionPeriod = args[1]
+        return registerOperator(newOperator, transitionPeriod)
+
+    if operation == 'switchOperator':
+        assert (len(args) == 0)
+        return switchOperator()
+
+    if operation == 'cancelOperator':
+        assert (len(args) == 0)
+        return cancelOperator()
+
+    if operation == 'getOperator':
+        assert (len(args) == 0)
+        return getOperator()
+
+    if operation == 'getToken':
+        assert (len(args) == 1)
+        assetAddress = args[0]
+        return getToken(assetAddress)
 
     if operation == 'getStateRootByHeight':
         assert (len(args) == 1)
         height = args[0]
         return getStateRootByHeight(height)
+
+    if operation == 'getCurrentHeight':
+        assert (len(args) == 0)
+        return getCurrentHeight()
+
+    if operation == 'getExit':
+        assert (len(args) == 1)
+        exitId = args[0]
+        return getExit(exitId)
+
+    if operation == 'getMassExitHeight':
+        assert (len(args) == 0)
+        return getMassExitHeight()
     return True
 
 
@@ -87,12 +266,57 @@ def init(operator, stateRoot, confirmHeight):
         Put(GetContext(), CONFRIM_HEIGHT, confirmHeight)
         Put(GetContext(), OPERATOR_ADDRESS, operator)
         Put(GetContext(), CURRENT_HEIGHT, stateRoot[1])
+        Put(GetContext(), LAST_COMMIT_BLOCK, GetHeight())
         stateRootInfo = Serialize(stateRoot)
         Put(GetContext(), concatKey(Current_STATE_PREFIX, stateRoot[1]), stateRootInfo)
         Notify(["Initialized contract successfully"])
     return True
 
 
+## operator登记新密钥，新旧密钥同时签名。过渡期为区块数，过渡期内operator的交易需要新旧密钥同时签名，过渡期后新密钥可以切换为operator
+def registerOperator(newOperator, transitionPeriod):
+    assert (not Get(GetContext(), PENDING_OPERATOR))
+    assert (_checkOperator())
+    assert (len(newOperator) == 20)
+    assert (CheckWitness(newOperator))
+    assert (transitionPeriod >= 0)
+    switchBlock = GetHeight() + transitionPeriod
+    Put(GetContext(), PENDING_OPERATOR, newOperator)
+    Put(GetContext(), OPERATOR_SWITCH_BLOCK, switchBlock)
+    Notify(['registerOperator', Get(GetContext(), OPERATOR_ADDRESS), newOperator, switchBlock])
+    return True
+
+
+## 过渡期后新密钥切换为operator，旧密钥失效
+def switchOperator():
+    newOperator = Get(GetContext(), PENDING_OPERATOR)
+    assert (newOperator)
+    assert (GetHeight() >= Get(GetContext(), OPERATOR_SWITCH_BLOCK))
+    assert (CheckWitness(newOperator))
+    Put(GetContext(), OPERATOR_ADDRESS, newOperator)
+    Delete(GetContext(), PENDING_OPERATOR)
+    Delete(GetContext(), OPERATOR_SWITCH_BLOCK)
+    Notify(['switchOperator', newOperator])
+    return True
+
+
+## 切换前旧密钥可以取消轮换，新密钥丢失时也可以取消
+def cancelOperator():
+    operator = Get(GetContext(), OPERATOR_ADDRESS)
+    assert (CheckWitness(operator))
+    newOperator = Get(GetContext(), PENDING_OPERATOR)
+    assert (newOperator)
+    Delete(GetContext(), PENDING_OPERATOR)
+    Delete(GetContext(), OPERATOR_SWITCH_BLOCK)
+    Notify(['cancelOperator', newOperator])
+    return True
+
+
+## 获取operator，轮换中的新operator和可以切换的区块高度
+def getOperator():
+    return [Get(GetContext(), OPERATOR_ADDRESS), Get(GetContext(), PENDING_OPERATOR), Get(GetContext(), OPERATOR_SWITCH_BLOCK)]
+
+
 ## 用户为了使用Layer2发起交易将资金质押在合约中
 def deposit(player, amount, assetAddress):
     assert (CheckWitness(player))
@@ -101,6 +325,8 @@ def deposit(player, amount, assetAddress):
     if not currentId:
         currentId = 1
     height = Get(GetContext(), CURRENT_HEIGHT)
+    # 资产登记表中暂停的资产不能充值
+    assert (Get(GetContext(), concatKey(TOKEN_PREFIX, assetAddress)) != TOKEN_PAUSED)
 
     if assetAddress == ONTAddress:
         assert (_transferONT(player, ContractAddress, amount))
@@ -110,7 +336,7 @@ def deposit(player, amount, assetAddress):
         reverseAssetAddress = bytearray_reverse(assetAddress)
         assert (_transferOEP4(reverseAssetAddress, player, ContractAddress, amount))
 
-    depositRecord = [player, amount, height, 0]
+    depositRecord = [player, amount, height, 0, assetAddress]
     depositRecordInfo = Serialize(depositRecord)
 
     Put(GetContext(), CURRENT_DEPOSIT_ID, currentId + 1)
@@ -118,6 +344,42 @@ def deposit(player, amount, assetAddress):
     DepositEvent(currentId, player, amount, height, 0, assetAddress)
     return True
 
+## 用户将OEP5或OEP8资产质押在合约中，OEP5的数量为1
+def depositNFT(player, assetAddress, tokenId, amount, standard):
+    assert (CheckWitness(player))
+    assert (len(assetAddress) == 20)
+    assert (len(tokenId) > 0)
+    assert (amount > 0)
+    assert (Get(GetContext(), concatKey(TOKEN_PREFIX, assetAddress)) != TOKEN_PAUSED)
+    # 同一资产只能使用一种标准，提现时按该标准转出
+    savedStandard = Get(GetContext(), concatKey(NFT_PREFIX, assetAddress))
+    if savedStandard:
+        assert (savedStandard == standard)
+    else:
+        Put(GetContext(), concatKey(NFT_PREFIX, assetAddress), standard)
+
+    currentId = Get(GetContext(), CURRENT_DEPOSIT_ID)
+    if not currentId:
+        currentId = 1
+    height = Get(GetContext(), CURRENT_HEIGHT)
+
+    reverseAssetAddress = bytearray_reverse(assetAddress)
+    if standard == OEP5:
+        assert (amount == 1)
+        assert (_transferOEP5(reverseAssetAddress, ContractAddress, tokenId))
+    elif standard == OEP8:
+        assert (_transferOEP8(reverseAssetAddress, player, ContractAddress, tokenId, amount))
+    else:
+        assert (False)
+
+    depositRecord = [player, amount, height, 0, assetAddress, tokenId]
+    depositRecordInfo = Serialize(depositRecord)
+
+    Put(GetContext(), CURRENT_DEPOSIT_ID, currentId + 1)
+    Put(GetContext(), concatKey(DEPOSIT_PREFIX, currentId), depositRecordInfo)
+    NFTDepositEvent(currentId, player, amount, height, 0, assetAddress, tokenId)
+    return True
+
 ## 用户将质押在合约中用于Layer2交易的资产赎回
 def withdraw(withdrawId):
     withdrawStatusInfo = Get(GetContext(), concatKey(WITHDRAW_PREFIX, withdrawId))
@@ -126,52 +388,268 @@ def withdraw(withdrawId):
     currentHeight = GetHeight()
     confirmHeight = Get(GetContext(), CONFRIM_HEIGHT)
     assert (currentHeight - withdrawStatusInfo[3] >= confirmHeight)
-    assetAddress = withdrawStatus[5]
 
     assert (withdrawStatus[4] == 0)
-    if assetAddress == ONTAddress:
-        assert (_transferONTFromContact(withdrawStatus[2], withdrawStatus[1]))
+    return _payWithdraw(withdrawStatus)
+
+
+## operator登记资产，enabled为1表示开启，为2表示暂停充值
+def setToken(assetAddress, enabled):
+    assert (_checkOperator())
+    assert (len(assetAddress) == 20)
+    assert (enabled == TOKEN_ENABLED or enabled == TOKEN_PAUSED)
+    Put(GetContext(), concatKey(TOKEN_PREFIX, assetAddress), enabled)
+    Notify(['setToken', assetAddress, enabled])
+    return True
+
+
+## 获取资产的登记状态，0表示未登记
+def getToken(assetAddress):
+    return Get(GetContext(), concatKey(TOKEN_PREFIX, assetAddress))
+
+
+## operator退还未登记或暂停资产的充值，退还后的充值不能再提交到layer2。批量退出开启后任何人都可以退还未提交的充值
+def refundDeposit(depositId):
+    if not Get(GetContext(), MASS_EXIT_HEIGHT):
+        assert (_checkOperator())
+    depositStatusInfo = Get(GetContext(), concatKey(DEPOSIT_PREFIX, depositId))
+    assert (depositStatusInfo)
+    depositStatus = Deserialize(depositStatusInfo)
+    assert (len(depositStatus) >= 5)
+    assert (depositStatus[3] == 0)
+    assetAddress = depositStatus[4]
+    if len(depositStatus) == 6:
+        assert (_transferNFTFromContact(assetAddress, depositStatus[0], depositStatus[5], depositStatus[1]))
+    elif assetAddress == ONTAddress:
+        assert (_transferONTFromContact(depositStatus[0], depositStatus[1]))
     elif assetAddress == ONGAddress:
-        assert (_transferONGFromContact(withdrawStatus[2], withdrawStatus[1]))
+        assert (_transferONGFromContact(depositStatus[0], depositStatus[1]))
     else:
         reverseAssetAddress = bytearray_reverse(assetAddress)
-        assert (_transferOEP4FromContact(reverseAssetAddress, withdrawStatus[2], withdrawStatus[1]))
-
-    WithdrawEvent(withdrawStatus[0], withdrawStatus[1], withdrawStatus[2], withdrawStatus[3], 1, withdrawStatus[5])
+        assert (_transferOEP4FromContact(reverseAssetAddress, depositStatus[0], depositStatus[1]))
+    depositStatus[3] = 2
+    Put(GetContext(), concatKey(DEPOSIT_PREFIX, depositId), Serialize(depositStatus))
+    Notify(['refundDeposit', depositId])
     return True
 
 
-## 更新全局的状态根，合约需要验证签名的有效性
-def updateState(stateRootHash, height, version, depositIds, withdrawAmounts, toAddresses, assetAddresses):
-    operator = Get(GetContext(), OPERATOR_ADDRESS)
-    assert (CheckWitness(operator))
+## 更新全局的状态根，合约需要验证签名的有效性，每笔提现需要附带提现账户在状态根中的证明
+def updateState(stateRootHash, height, version, depositIds, withdrawAmounts, toAddresses, assetAddresses, tokenIds, layer2Assets, withdrawProofs):
+    assert (_checkOperator())
+    assert (not Get(GetContext(), MASS_EXIT_HEIGHT))
     preHeight = Get(GetContext(), CURRENT_HEIGHT)
-    assert (preHeight + 1 == height)
+    # 可以聚合多个layer2区块一次提交，只保存最后一个高度的状态根
+    assert (preHeight < height)
 
     Put(GetContext(), CURRENT_HEIGHT, height)
+    Put(GetContext(), LAST_COMMIT_BLOCK, GetHeight())
     stateRoot = [stateRootHash, height, version]
     stateRootInfo = Serialize(stateRoot)
     Put(GetContext(), concatKey(Current_STATE_PREFIX, height), stateRootInfo)
-    # 返回满足条件用户的钱
-    currentWithDrawId = Get(GetContext(), CURRENT_WITHDRAW_ID)
-    confirmHeight = Get(GetContext(), CONFRIM_HEIGHT)
-    if currentWithDrawId:
-        while currentWithDrawId > 1:
-            withdrawStatusInfo = Get(GetContext(), concatKey(WITHDRAW_PREFIX, currentWithDrawId - 1))
-            withdrawStatus = Deserialize(withdrawStatusInfo)
-            if (height - withdrawStatus[3] == confirmHeight):
-                assert (withdraw(withdrawStatus[0]))
-            elif height - withdrawStatus[3] > confirmHeight:
-                break
-            currentWithDrawId = currentWithDrawId - 1
+    # 返回满足条件用户的钱，确认高度已到且所在提交已过挑战期的提现按顺序返还
+    _payWithdraws(height)
+    # 记录提交，挑战成功时用于回滚
+    withdrawStartId = Get(GetContext(), CURRENT_WITHDRAW_ID)
+    if not withdrawStartId:
+        withdrawStartId = 1
+    commit = [GetHeight(), preHeight, depositIds, withdrawStartId]
+    Put(GetContext(), concatKey(COMMIT_PREFIX, height), Serialize(commit))
     # 更新deposit状态
     _updateDepositState(depositIds)
+    # 验证提现证明
+    _verifyWithdrawProofs(stateRootHash, toAddresses, assetAddresses, layer2Assets, withdrawProofs)
     # 更新withdraw状态
-    _createWithdrawState(height, withdrawAmounts, toAddresses, assetAddresses)
-    Notify(['updateState', stateRootHash, height, version, depositIds, withdrawAmounts, toAddresses, assetAddresses])
+    _createWithdrawState(height, withdrawAmounts, toAddresses, assetAddresses, tokenIds)
+    Notify(['updateState', stateRootHash, height, version, depositIds, withdrawAmounts, toAddresses, assetAddresses, tokenIds, layer2Assets])
+    return True
+
+
+## operator设置挑战期和layer2记账人，只能设置一次。挑战期为区块数，为0时提交立即生效且不能被挑战
+def initChallenge(challengePeriod, bookkeepers, m):
+    assert (_checkOperator())
+    assert (not Get(GetContext(), BOOKKEEPERS))
+    assert (challengePeriod >= 0)
+    assert (m > 0 and m <= len(bookkeepers))
+    Put(GetContext(), CHALLENGE_PERIOD, challengePeriod)
+    Put(GetContext(), BOOKKEEPERS, Serialize(bookkeepers))
+    Put(GetContext(), BOOKKEEPER_M, m)
+    Notify(['initChallenge', challengePeriod, bookkeepers, m])
+    return True
+
+
+## 任何人都可以在挑战期内提交欺诈证明，证明为layer2记账人签名的该高度的layer2状态，状态根与提交的不一致时回滚该高度及之后的所有提交
+def challengeState(height, layer2State):
+    commitInfo = Get(GetContext(), concatKey(COMMIT_PREFIX, height))
+    assert (commitInfo)
+    commit = Deserialize(commitInfo)
+    assert (not _isFinal(height))
+    # layer2状态格式：[version, height, statesRoot, sigData]
+    assert (len(layer2State) > 38)
+    assert (concat(layer2State[1:5], bytearray(b'\x00')) + 0 == height)
+    stateRoot = Deserialize(Get(GetContext(), concatKey(Current_STATE_PREFIX, height)))
+    assert (_toHexString(bytearray_reverse(layer2State[5:37])) != stateRoot[0])
+    assert (_verifyLayer2State(layer2State))
+    _revertState(height, commit[1])
+    return True
+
+
+## 用户以最新提交的状态根中layer2余额的证明申请强制退出，operator冻结layer2账户，挑战期后任何人都可以完成退出
+def requestExit(player, assetAddress, value, proof):
+    assert (CheckWitness(player))
+    assert (len(player) == 20)
+    # 挑战期内operator冻结layer2账户，并用冻结后提交的状态根挑战更低的余额
+    assert (Get(GetContext(), CHALLENGE_PERIOD) > 0)
+    assert (not Get(GetContext(), MASS_EXIT_HEIGHT))
+    assert (not Get(GetContext(), concatKey(NFT_PREFIX, assetAddress)))
+    accountKey = concatKey(EXIT_ACCOUNT_PREFIX, concat(assetAddress, player))
+    assert (not Get(GetContext(), accountKey))
+    height = Get(GetContext(), CURRENT_HEIGHT)
+    amount = _provenBalance(height, assetAddress, player, value, proof)
+    assert (amount > 0)
+
+    id = Get(GetContext(), CURRENT_EXIT_ID)
+    if not id:
+        id = 1
+    blockHeight = GetHeight()
+    exitRecord = [id, player, assetAddress, amount, height, blockHeight, 0, 0, 0]
+    Put(GetContext(), concatKey(EXIT_PREFIX, id), Serialize(exitRecord))
+    Put(GetContext(), accountKey, id)
+    Put(GetContext(), CURRENT_EXIT_ID, id + 1)
+    ExitEvent(id, player, assetAddress, amount, height, blockHeight)
+    return True
+
+
+## 在挑战期内以申请之后提交的状态根中更低的余额挑战退出，冻结后的账户余额不会再减少
+def challengeExit(exitId, height, value, proof):
+    exitInfo = Get(GetContext(), concatKey(EXIT_PREFIX, exitId))
+    assert (exitInfo)
+    exitRecord = Deserialize(exitInfo)
+    assert (exitRecord[6] == 0)
+    challengePeriod = Get(GetContext(), CHALLENGE_PERIOD)
+    assert (GetHeight() < exitRecord[5] + challengePeriod)
+    assert (height > exitRecord[4])
+    amount = _provenBalance(height, exitRecord[2], exitRecord[1], value, proof)
+    assert (amount < _exitAmount(exitRecord))
+    exitRecord[7] = height
+    exitRecord[8] = amount
+    Put(GetContext(), concatKey(EXIT_PREFIX, exitId), Serialize(exitRecord))
+    Notify(['challengeExit', exitId, height, amount])
+    return True
+
+
+## 挑战期后任何人都可以完成退出，operator不在线时也可以完成。证明所用的状态根已被回滚时取消退出，用户可以重新申请
+def finalizeExit(exitId):
+    exitInfo = Get(GetContext(), concatKey(EXIT_PREFIX, exitId))
+    assert (exitInfo)
+    exitRecord = Deserialize(exitInfo)
+    assert (exitRecord[6] == 0)
+    challengePeriod = Get(GetContext(), CHALLENGE_PERIOD)
+    assert (GetHeight() >= exitRecord[5] + challengePeriod)
+    player = exitRecord[1]
+    assetAddress = exitRecord[2]
+    if not Get(GetContext(), concatKey(Current_STATE_PREFIX, exitRecord[4])):
+        exitRecord[6] = 2
+        Put(GetContext(), concatKey(EXIT_PREFIX, exitId), Serialize(exitRecord))
+        Delete(GetContext(), concatKey(EXIT_ACCOUNT_PREFIX, concat(assetAddress, player)))
+        Notify(['cancelExit', exitId])
+        return True
+    amount = _exitAmount(exitRecord)
+    if amount > 0:
+        if assetAddress == ONTAddress:
+            assert (_transferONTFromContact(player, amount))
+        elif assetAddress == ONGAddress:
+            assert (_transferONGFromContact(player, amount))
+        else:
+            reverseAssetAddress = bytearray_reverse(assetAddress)
+            assert (_transferOEP4FromContact(reverseAssetAddress, player, amount))
+    # 退出后账户记录保留，同一账户的同一资产不能再次退出
+    exitRecord[6] = 1
+    Put(GetContext(), concatKey(EXIT_PREFIX, exitId), Serialize(exitRecord))
+    Notify(['finalizeExit', exitId, player, assetAddress, amount])
+    return True
+
+
+## operator设置批量退出的区块数，只能设置一次
+def initMassExit(massExitPeriod):
+    assert (_checkOperator())
+    assert (not Get(GetContext(), MASS_EXIT_PERIOD))
+    assert (massExitPeriod > 0)
+    Put(GetContext(), MASS_EXIT_PERIOD, massExitPeriod)
+    if not Get(GetContext(), LAST_COMMIT_BLOCK):
+        Put(GetContext(), LAST_COMMIT_BLOCK, GetHeight())
+    Notify(['initMassExit', massExitPeriod])
     return True
 
 
+## operator连续massExitPeriod个区块没有提交时任何人都可以开启批量退出，开启后不能关闭
+def startMassExit():
+    massExitPeriod = Get(GetContext(), MASS_EXIT_PERIOD)
+    assert (massExitPeriod > 0)
+    assert (not Get(GetContext(), MASS_EXIT_HEIGHT))
+    blockHeight = GetHeight()
+    assert (blockHeight >= Get(GetContext(), LAST_COMMIT_BLOCK) + massExitPeriod)
+    Put(GetContext(), MASS_EXIT_HEIGHT, blockHeight)
+    Notify(['startMassExit', blockHeight, Get(GetContext(), CURRENT_HEIGHT)])
+    return True
+
+
+## 批量退出开启且最新提交已过挑战期后，用户以最新提交的状态根中的余额证明领取资产，每个账户的每种资产只能领取一次
+def claimMassExit(player, assetAddress, value, proof):
+    assert (len(player) == 20)
+    assert (Get(GetContext(), MASS_EXIT_HEIGHT))
+    height = Get(GetContext(), CURRENT_HEIGHT)
+    assert (_isFinal(height))
+    assert (not Get(GetContext(), concatKey(NFT_PREFIX, assetAddress)))
+    # 申请过强制退出的账户通过finalizeExit退出
+    assert (not Get(GetContext(), concatKey(EXIT_ACCOUNT_PREFIX, concat(assetAddress, player))))
+    claimKey = concatKey(MASS_EXIT_CLAIM_PREFIX, concat(assetAddress, player))
+    assert (not Get(GetContext(), claimKey))
+    amount = _provenBalance(height, assetAddress, player, value, proof)
+    assert (amount > 0)
+    if assetAddress == ONTAddress:
+        assert (_transferONTFromContact(player, amount))
+    elif assetAddress == ONGAddress:
+        assert (_transferONGFromContact(player, amount))
+    else:
+        reverseAssetAddress = bytearray_reverse(assetAddress)
+        assert (_transferOEP4FromContact(reverseAssetAddress, player, amount))
+    Put(GetContext(), claimKey, amount)
+    Notify(['claimMassExit', player, assetAddress, amount, height])
+    return True
+
+
+## 批量退出开启且最新提交已过挑战期后，任何人都可以按顺序支付已提交的提现，不再等待确认高度
+def payMassExitWithdraws():
+    assert (Get(GetContext(), MASS_EXIT_HEIGHT))
+    assert (_isFinal(Get(GetContext(), CURRENT_HEIGHT)))
+    currentWithDrawId = Get(GetContext(), CURRENT_WITHDRAW_ID)
+    paidId = Get(GetContext(), PAID_WITHDRAW_ID)
+    if not paidId:
+        paidId = 1
+    while paidId < currentWithDrawId:
+        withdrawStatus = Deserialize(Get(GetContext(), concatKey(WITHDRAW_PREFIX, paidId)))
+        assert (_payWithdraw(withdrawStatus))
+        paidId = paidId + 1
+    Put(GetContext(), PAID_WITHDRAW_ID, paidId)
+    return True
+
+
+## 获取批量退出开启时的区块高度，未开启时为0
+def getMassExitHeight():
+    massExitHeight = Get(GetContext(), MASS_EXIT_HEIGHT)
+    if not massExitHeight:
+        return 0
+    return massExitHeight
+
+
+## 获取退出记录
+def getExit(exitId):
+    exitInfo = Get(GetContext(), concatKey(EXIT_PREFIX, exitId))
+    if exitInfo:
+        return Deserialize(exitInfo)
+    return []
+
+
 ## 根据高度获取状态根信息
 def getStateRootByHeight(height):
     stateRootInfo = Get(GetContext(), concatKey(Current_STATE_PREFIX, height))
@@ -181,6 +659,11 @@ def getStateRootByHeight(height):
     return []
 
 
+## 获取最新提交的layer2高度
+def getCurrentHeight():
+    return Get(GetContext(), CURRENT_HEIGHT)
+
+
 def _updateDepositState(depositIds):
     for i in range(len(depositIds)):
         depositStatusInfo = Get(GetContext(), concatKey(DEPOSIT_PREFIX, depositIds[i]))
@@ -194,8 +677,9 @@ def _updateDepositState(depositIds):
     return True
 
 
-def _createWithdrawState(height, withdrawAmounts, toAddresses, assetAddresses):
+def _createWithdrawState(height, withdrawAmounts, toAddresses, assetAddresses, tokenIds):
     assert (len(withdrawAmounts) == len(toAddresses))
+    assert (len(withdrawAmounts) == len(tokenIds))
     length = len(withdrawAmounts)
     id = Get(GetContext(), CURRENT_WITHDRAW_ID)
     if not id:
@@ -203,15 +687,226 @@ def _createWithdrawState(height, withdrawAmounts, toAddresses, assetAddresses):
     for i in range(length):
         assert (withdrawAmounts[i] > 0)
         assert (len(toAddresses[i]) == 20)
-        withdrawStatus = [id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i]]
+        withdrawStatus = [id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i], tokenIds[i]]
         withdrawStatusInfo = Serialize(withdrawStatus)
         Put(GetContext(), concatKey(WITHDRAW_PREFIX, id), withdrawStatusInfo)
-        WithdrawEvent(id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i])
+        if len(tokenIds[i]) > 0:
+            NFTWithdrawEvent(id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i], tokenIds[i])
+        else:
+            WithdrawEvent(id, withdrawAmounts[i], toAddresses[i], height, 0, assetAddresses[i])
         id = id + 1
     Put(GetContext(), CURRENT_WITHDRAW_ID, id)
     return True
 
 
+# operator签名，轮换密钥的过渡期内新密钥也需要签名
+def _checkOperator():
+    if not CheckWitness(Get(GetContext(), OPERATOR_ADDRESS)):
+        return False
+    newOperator = Get(GetContext(), PENDING_OPERATOR)
+    if newOperator:
+        return CheckWitness(newOperator)
+    return True
+
+
+def _isFinal(height):
+    challengePeriod = Get(GetContext(), CHALLENGE_PERIOD)
+    if not challengePeriod:
+        return True
+    commitInfo = Get(GetContext(), concatKey(COMMIT_PREFIX, height))
+    if not commitInfo:
+        return True
+    commit = Deserialize(commitInfo)
+    return GetHeight() >= commit[0] + challengePeriod
+
+
+def _payWithdraws(height):
+    confirmHeight = Get(GetContext(), CONFRIM_HEIGHT)
+    currentWithDrawId = Get(GetContext(), CURRENT_WITHDRAW_ID)
+    paidId = Get(GetContext(), PAID_WITHDRAW_ID)
+    if not paidId:
+        paidId = 1
+    while paidId < currentWithDrawId:
+        withdrawStatus = Deserialize(Get(GetContext(), concatKey(WITHDRAW_PREFIX, paidId)))
+        if withdrawStatus[3] + confirmHeight > height:
+            break
+        if not _isFinal(withdrawStatus[3]):
+            break
+        assert (withdraw(paidId))
+        paidId = paidId + 1
+    Put(GetContext(), PAID_WITHDRAW_ID, paidId)
+    return True
+
+
+# 按提现记录转出资产
+def _payWithdraw(withdrawStatus):
+    assetAddress = withdrawStatus[5]
+    # 早期的提现记录没有tokenId
+    tokenId = bytearray(b'')
+    if len(withdrawStatus) > 6:
+        tokenId = withdrawStatus[6]
+    if len(tokenId) > 0:
+        assert (_transferNFTFromContact(assetAddress, withdrawStatus[2], tokenId, withdrawStatus[1]))
+        NFTWithdrawEvent(withdrawStatus[0], withdrawStatus[1], withdrawStatus[2], withdrawStatus[3], 1, assetAddress, tokenId)
+        return True
+    if assetAddress == ONTAddress:
+        assert (_transferONTFromContact(withdrawStatus[2], withdrawStatus[1]))
+    elif assetAddress == ONGAddress:
+        assert (_transferONGFromContact(withdrawStatus[2], withdrawStatus[1]))
+    else:
+        reverseAssetAddress = bytearray_reverse(assetAddress)
+        assert (_transferOEP4FromContact(reverseAssetAddress, withdrawStatus[2], withdrawStatus[1]))
+
+    WithdrawEvent(withdrawStatus[0], withdrawStatus[1], withdrawStatus[2], withdrawStatus[3], 1, withdrawStatus[5])
+    return True
+
+
+def _verifyLayer2State(layer2State):
+    bookkeepersInfo = Get(GetContext(), BOOKKEEPERS)
+    assert (bookkeepersInfo)
+    bookkeepers = Deserialize(bookkeepersInfo)
+    m = Get(GetContext(), BOOKKEEPER_M)
+    hash = sha256(sha256(layer2State[0:37]))
+    sigCount = _byteAt(layer2State, 37)
+    assert (sigCount < 253)
+    sigs = []
+    offset = 38
+    for i in range(sigCount):
+        sigLen = _byteAt(layer2State, offset)
+        assert (sigLen < 253)
+        sigs.append(layer2State[offset + 1:offset + 1 + sigLen])
+        offset = offset + 1 + sigLen
+    return VerifyMutiSig(hash, bookkeepers, m, sigs)
+
+
+# 从最新的提交开始回滚到height，回滚的充值可以再次提交，回滚的提现被删除
+def _revertState(height, preHeight):
+    revertHeight = Get(GetContext(), CURRENT_HEIGHT)
+    withdrawStartId = 0
+    while revertHeight >= height:
+        commit = Deserialize(Get(GetContext(), concatKey(COMMIT_PREFIX, revertHeight)))
+        depositIds = commit[2]
+        for i in range(len(depositIds)):
+            depositStatusInfo = Get(GetContext(), concatKey(DEPOSIT_PREFIX, depositIds[i]))
+            if depositStatusInfo:
+                depositStatus = Deserialize(depositStatusInfo)
+                depositStatus[3] = 0
+                Put(GetContext(), concatKey(DEPOSIT_PREFIX, depositIds[i]), Serialize(depositStatus))
+        Delete(GetContext(), concatKey(COMMIT_PREFIX, revertHeight))
+        Delete(GetContext(), concatKey(Current_STATE_PREFIX, revertHeight))
+        withdrawStartId = commit[3]
+        revertHeight = commit[1]
+    currentWithDrawId = Get(GetContext(), CURRENT_WITHDRAW_ID)
+    id = withdrawStartId
+    while id < currentWithDrawId:
+        Delete(GetContext(), concatKey(WITHDRAW_PREFIX, id))
+        id = id + 1
+    Put(GetContext(), CURRENT_WITHDRAW_ID, withdrawStartId)
+    Put(GetContext(), CURRENT_HEIGHT, preHeight)
+    Notify(['revertState', height, preHeight])
+    return True
+
+
+# 证明账户在height的状态根中的余额，账户不存在时余额为0
+def _provenBalance(height, assetAddress, player, value, proof):
+    stateRootInfo = Get(GetContext(), concatKey(Current_STATE_PREFIX, height))
+    assert (stateRootInfo)
+    stateRoot = Deserialize(stateRootInfo)
+    key = concat(_exitLayer2Asset(assetAddress), player)
+    assert (_verifyWithdrawProof(stateRoot[0], key, proof))
+    if _byteAt(proof, 0) != PROOF_LEAF:
+        return 0
+    assert (proof[1:33] == sha256(value))
+    # 账户的值为序列化的存储项：版本号，长度和小端序的余额
+    assert (len(value) > 2 and len(value) < 255)
+    assert (_byteAt(value, 1) == len(value) - 2)
+    return concat(value[2:], bytearray(b'\x00')) + 0
+
+
+# ONT和ONG在layer2上为同一地址的原生合约，其他资产使用提现时绑定的layer2资产地址
+def _exitLayer2Asset(assetAddress):
+    if assetAddress == ONTAddress or assetAddress == ONGAddress:
+        return assetAddress
+    layer2Asset = Get(GetContext(), concatKey(LAYER2_ASSET_PREFIX, assetAddress))
+    assert (layer2Asset)
+    return layer2Asset
+
+
+# 挑战所用的状态根被回滚时恢复申请的数量
+def _exitAmount(exitRecord):
+    if exitRecord[7] > 0 and Get(GetContext(), concatKey(Current_STATE_PREFIX, exitRecord[7])):
+        return exitRecord[8]
+    return exitRecord[3]
+
+
+def _verifyWithdrawProofs(stateRootHash, toAddresses, assetAddresses, layer2Assets, withdrawProofs):
+    assert (len(toAddresses) == len(layer2Assets))
+    assert (len(toAddresses) == len(withdrawProofs))
+    for i in range(len(toAddresses)):
+        assert (len(layer2Assets[i]) == 20)
+        # 资产第一次提现时绑定layer2资产地址，之后的提现必须使用同一地址
+        savedLayer2Asset = Get(GetContext(), concatKey(LAYER2_ASSET_PREFIX, assetAddresses[i]))
+        if savedLayer2Asset:
+            assert (savedLayer2Asset == layer2Assets[i])
+        else:
+            Put(GetContext(), concatKey(LAYER2_ASSET_PREFIX, assetAddresses[i]), layer2Assets[i])
+        assert (_verifyWithdrawProof(stateRootHash, concat(layer2Assets[i], toAddresses[i]), withdrawProofs[i]))
+    return True
+
+
+# 证明格式：[路径终点, 其他账户叶子的路径和值哈希 | 提现账户叶子的值哈希, 从根开始的兄弟节点哈希]
+def _verifyWithdrawProof(stateRootHash, key, proof):
+    path = sha256(key)
+    mode = _byteAt(proof, 0)
+    hash = bytearray(b'\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00')
+    offset = 1
+    if mode == PROOF_LEAF:
+        hash = sha256(concat(concat(bytearray(b'\x00'), path), proof[1:33]))
+        offset = 33
+    elif mode == PROOF_OTHER_LEAF:
+        leafPath = proof[1:33]
+        assert (leafPath != path)
+        hash = sha256(concat(concat(bytearray(b'\x00'), leafPath), proof[33:65]))
+        offset = 65
+    else:
+        assert (mode == PROOF_EMPTY)
+    assert ((len(proof) - offset) % 32 == 0)
+    depth = (len(proof) - offset) / 32
+    assert (depth <= SMT_DEPTH)
+    if mode == PROOF_OTHER_LEAF:
+        for i in range(depth):
+            assert (_bitAt(leafPath, i) == _bitAt(path, i))
+    i = depth
+    while i > 0:
+        i = i - 1
+        sibling = proof[offset + 32 * i:offset + 32 * i + 32]
+        if _bitAt(path, i) == 0:
+            hash = sha256(concat(concat(bytearray(b'\x01'), hash), sibling))
+        else:
+            hash = sha256(concat(concat(bytearray(b'\x01'), sibling), hash))
+    # 状态根哈希为字节逆序的十六进制字符串
+    return _toHexString(bytearray_reverse(hash)) == stateRootHash
+
+
+def _byteAt(data, index):
+    value = data[index:index + 1] + 0
+    if value < 0:
+        value = value + 256
+    return value
+
+
+def _bitAt(path, depth):
+    return (_byteAt(path, depth / 8) >> (7 - depth % 8)) & 1
+
+
+def _toHexString(data):
+    result = ''
+    for i in range(len(data)):
+        value = _byteAt(data, i)
+        result = concat(result, concat(HEX_CHARS[value / 16:value / 16 + 1], HEX_CHARS[value % 16:value % 16 + 1]))
+    return result
+
+
 ### 内部调用方法
 def concatKey(str1, str2):
     return concat(concat(str1, '_'), str2)
@@ -293,3 +988,31 @@ def _transferOEP4FromContact(oep4ReverseAddr, toAcct, amount):
         return True
     else:
         return False
+
+
+def _transferOEP5(oep5ReverseAddr, toAcct, tokenId):
+    params = [toAcct, tokenId]
+    res = DynamicAppCall(oep5ReverseAddr, 'transfer', params)
+    if res and res == b'\x01':
+        return True
+    else:
+        return False
+
+
+def _transferOEP8(oep8ReverseAddr, fromAcct, toAcct, tokenId, amount):
+    params = [fromAcct, toAcct, tokenId, amount]
+    res = DynamicAppCall(oep8ReverseAddr, 'transfer', params)
+    if res and res == b'\x01':
+        return True
+    else:
+        return False
+
+
+def _transferNFTFromContact(assetAddress, toAcct, tokenId, amount):
+    standard = Get(GetContext(), concatKey(NFT_PREFIX, assetAddress))
+    reverseAssetAddress = bytearray_reverse(assetAddress)
+    if standard == OEP5:
+        return _transferOEP5(reverseAssetAddress, toAcct, tokenId)
+    elif standard == OEP8:
+        return _transferOEP8(reverseAssetAddress, ContractAddress, toAcct, tokenId, amount)
+    return False
@@ -0,0 +1,201 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package layer2_go_sdk
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/service/neovm"
+)
+
+const (
+	GLOBAL_PARAM_GAS_PRICE   = "gasPrice" //Name of min gas price in global params
+	GAS_LIMIT_MARGIN_PERCENT = 20         //Extra gas limit added to the pre-executed gas
+	GAS_PRICE_SAMPLE_BLOCKS  = 20         //Number of latest blocks whose transactions are sampled for the gas price
+	GAS_PRICE_PERCENTILE     = 60         //Percentile of the sampled gas prices recommended
+)
+
+//FeeEstimate is the recommended gas price and gas limit of transaction
+type FeeEstimate struct {
+	GasPrice uint64
+	GasLimit uint64
+}
+
+//Fee return the max fee of transaction
+func (this *FeeEstimate) Fee() uint64 {
+	return this.GasPrice * this.GasLimit
+}
+
+//SuggestGasPrice return the recommended gas price, which is the GAS_PRICE_PERCENTILE percentile of the gas prices
+//of the transactions in the latest GAS_PRICE_SAMPLE_BLOCKS blocks and not less than the min gas price in global params
+func (this *OntologySdk) SuggestGasPrice() (uint64, error) {
+	return this.SuggestGasPriceByHistory(GAS_PRICE_SAMPLE_BLOCKS, GAS_PRICE_PERCENTILE)
+}
+
+//SuggestGasPriceByHistory return the percentile of the gas prices of the transactions in the latest blocks,
+//which is not less than the min gas price in global params. The min gas price is returned if the blocks are empty
+func (this *OntologySdk) SuggestGasPriceByHistory(blocks uint32, percentile int) (uint64, error) {
+	minGasPrice, err := this.GetMinGasPrice()
+	if err != nil {
+		return 0, err
+	}
+	currentHeight, err := this.GetCurrentBlockHeight()
+	if err != nil {
+		return 0, fmt.Errorf("GetCurrentBlockHeight error:%s", err)
+	}
+	gasPrices := make([]uint64, 0)
+	for i := uint32(0); i < blocks && i <= currentHeight; i++ {
+		block, err := this.GetBlockByHeight(currentHeight - i)
+		if err != nil {
+			return 0, fmt.Errorf("GetBlockByHeight:%d error:%s", currentHeight-i, err)
+		}
+		for _, tx := range block.Transactions {
+			gasPrices = append(gasPrices, tx.GasPrice)
+		}
+	}
+	gasPrice := gasPricePercentile(gasPrices, percentile)
+	if gasPrice < minGasPrice {
+		return minGasPrice, nil
+	}
+	return gasPrice, nil
+}
+
+//GetMinGasPrice return the min gas price in global params, which is the base fee of the node, 0 if it is not set
+func (this *OntologySdk) GetMinGasPrice() (uint64, error) {
+	params, err := this.Native.GlobalParams.GetGlobalParams([]string{GLOBAL_PARAM_GAS_PRICE})
+	if err != nil {
+		return 0, fmt.Errorf("GetGlobalParams error:%s", err)
+	}
+	value, ok := params[GLOBAL_PARAM_GAS_PRICE]
+	if !ok {
+		return 0, nil
+	}
+	minGasPrice, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse global param %s:%s error:%s", GLOBAL_PARAM_GAS_PRICE, value, err)
+	}
+	return minGasPrice, nil
+}
+
+//gasPricePercentile return the nearest-rank percentile of the gas prices, 0 if there is no gas price
+func gasPricePercentile(gasPrices []uint64, percentile int) uint64 {
+	if len(gasPrices) == 0 {
+		return 0
+	}
+	if percentile < 0 {
+		percentile = 0
+	}
+	if percentile > 100 {
+		percentile = 100
+	}
+	sorted := make([]uint64, len(gasPrices))
+	copy(sorted, gasPrices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (len(sorted)*percentile + 99) / 100
+	if rank == 0 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+//gasLimitWithMargin return the gas limit of the pre-executed gas with GAS_LIMIT_MARGIN_PERCENT margin, which is not
+//less than the min transaction gas
+func gasLimitWithMargin(gas uint64) uint64 {
+	gasLimit := gas + gas*GAS_LIMIT_MARGIN_PERCENT/100
+	if gasLimit < neovm.MIN_TRANSACTION_GAS {
+		gasLimit = neovm.MIN_TRANSACTION_GAS
+	}
+	return gasLimit
+}
+
+//EstimateGasLimit return the recommended gas limit of transaction by pre-execution.
+//Transaction should be signed if the contract checks witness
+func (this *OntologySdk) EstimateGasLimit(mutTx *types.MutableTransaction) (uint64, error) {
+	preResult, err := this.PreExecTransaction(mutTx)
+	if err != nil {
+		return 0, fmt.Errorf("PreExecTransaction error:%s", err)
+	}
+	if preResult.State == 0 {
+		return 0, fmt.Errorf("pre-execute transaction failed")
+	}
+	return gasLimitWithMargin(preResult.Gas), nil
+}
+
+//EstimateFee return the recommended gas price and gas limit of transaction
+func (this *OntologySdk) EstimateFee(mutTx *types.MutableTransaction) (*FeeEstimate, error) {
+	gasPrice, err := this.SuggestGasPrice()
+	if err != nil {
+		return nil, err
+	}
+	gasLimit, err := this.EstimateGasLimit(mutTx)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeEstimate{GasPrice: gasPrice, GasLimit: gasLimit}, nil
+}
+
+//BaseFee return the fee of the transaction of calls ont or ong transfers, at the min gas price in global params and
+//the base gas of the gas table for each transfer. Unlike EstimateFee it does not change with the latest blocks, so the
+//transaction built again by the same params has the same hash
+func (this *OntologySdk) BaseFee(calls int) (*FeeEstimate, error) {
+	gasPrice, err := this.GetMinGasPrice()
+	if err != nil {
+		return nil, err
+	}
+	if calls < 1 {
+		calls = 1
+	}
+	return &FeeEstimate{GasPrice: gasPrice, GasLimit: neovm.MIN_TRANSACTION_GAS * uint64(calls)}, nil
+}
+
+//EstimateTransferFee return the recommended fee of ont or ong transfer, which always cost the base gas
+func (this *OntologySdk) EstimateTransferFee() (*FeeEstimate, error) {
+	gasPrice, err := this.SuggestGasPrice()
+	if err != nil {
+		return nil, err
+	}
+	return &FeeEstimate{GasPrice: gasPrice, GasLimit: neovm.MIN_TRANSACTION_GAS}, nil
+}
+
+//EstimateInvokeNeoVMFee return the recommended fee of neovm contract invoking, the transaction is signed by signer for
+//the pre-execution if it is not nil
+func (this *OntologySdk) EstimateInvokeNeoVMFee(signer Signer, contractAddress common.Address, params []interface{}) (*FeeEstimate, error) {
+	tx, err := this.NeoVM.NewNeoVMInvokeTransaction(0, 0, contractAddress, params)
+	if err != nil {
+		return nil, err
+	}
+	if signer != nil {
+		err = this.SignToTransaction(tx, signer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return this.EstimateFee(tx)
+}
+
+//EstimateOep4TransferFee return the recommended fee of oep4 token transfer from the account of signer
+func (this *OntologySdk) EstimateOep4TransferFee(contractAddress common.Address, from Signer, to common.Address, amount *big.Int) (*FeeEstimate, error) {
+	fromAddress := types.AddressFromPubKey(from.GetPublicKey())
+	return this.EstimateInvokeNeoVMFee(from, contractAddress, []interface{}{"transfer", []interface{}{fromAddress, to, amount}})
+}
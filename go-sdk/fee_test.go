@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package layer2_go_sdk
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/smartcontract/service/neovm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasPricePercentile(t *testing.T) {
+	gasPrices := []uint64{500, 0, 2500, 500, 1000, 0, 500, 3000, 500, 1000}
+	cases := []struct {
+		percentile int
+		expected   uint64
+	}{
+		{0, 0},
+		{10, 0},
+		{50, 500},
+		{60, 500},
+		{70, 1000},
+		{90, 2500},
+		{100, 3000},
+		{150, 3000},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, gasPricePercentile(gasPrices, c.percentile), "percentile %d", c.percentile)
+	}
+	assert.Equal(t, []uint64{500, 0, 2500, 500, 1000, 0, 500, 3000, 500, 1000}, gasPrices)
+	assert.Equal(t, uint64(0), gasPricePercentile(nil, GAS_PRICE_PERCENTILE))
+	assert.Equal(t, uint64(800), gasPricePercentile([]uint64{800}, GAS_PRICE_PERCENTILE))
+}
+
+func TestGasLimitWithMargin(t *testing.T) {
+	assert.Equal(t, neovm.MIN_TRANSACTION_GAS, gasLimitWithMargin(0))
+	assert.Equal(t, neovm.MIN_TRANSACTION_GAS, gasLimitWithMargin(10000))
+	assert.Equal(t, uint64(120000), gasLimitWithMargin(100000))
+}
+
+func TestFeeEstimate_Fee(t *testing.T) {
+	fee := &FeeEstimate{GasPrice: 500, GasLimit: 20000}
+	assert.Equal(t, uint64(10000000), fee.Fee())
+}
@@ -9,6 +9,7 @@ require (
 	github.com/ontio/go-bip32 v0.0.0-20190520025953-d3cea6894a2b
 	github.com/ontio/layer2/node v0.0.0-20200429091234-c4911b865a2c
 	github.com/ontio/ontology-crypto v1.0.8
+	github.com/stretchr/testify v1.4.0
 	github.com/tyler-smith/go-bip39 v1.0.2
 	golang.org/x/crypto v0.0.0-20200427165652-729f1e841bcc
 )
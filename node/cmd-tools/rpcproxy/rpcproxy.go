@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/ontio/layer2/node/cmd"
+	"github.com/ontio/layer2/node/cmd/utils"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/http/rpcproxy"
+	"github.com/urfave/cli"
+)
+
+func setupRpcProxy() *cli.App {
+	app := cli.NewApp()
+	app.Usage = "Layer2 read-only json rpc proxy with response cache"
+	app.Action = startRpcProxy
+	app.Version = config.Version
+	app.Copyright = "Copyright in 2020 The Ontology Authors"
+	app.Flags = []cli.Flag{
+		utils.LogLevelFlag,
+		utils.ProxyUpstreamFlag,
+		utils.ProxyAddressFlag,
+		utils.ProxyPortFlag,
+		utils.ProxyCacheSizeFlag,
+		utils.ProxyPollIntervalFlag,
+	}
+	app.Before = func(context *cli.Context) error {
+		runtime.GOMAXPROCS(runtime.NumCPU())
+		return nil
+	}
+	return app
+}
+
+func startRpcProxy(ctx *cli.Context) {
+	logLevel := ctx.GlobalInt(utils.GetFlagName(utils.LogLevelFlag))
+	log.InitLog(logLevel, log.PATH, log.Stdout)
+
+	upstream := ctx.String(utils.GetFlagName(utils.ProxyUpstreamFlag))
+	cacheSize := ctx.Uint(utils.GetFlagName(utils.ProxyCacheSizeFlag))
+	pollInterval := ctx.Uint(utils.GetFlagName(utils.ProxyPollIntervalFlag))
+	if cacheSize == 0 || pollInterval == 0 {
+		log.Errorf("Please set positive cache size and poll interval")
+		return
+	}
+	proxy, err := rpcproxy.NewProxyServer(upstream, int(cacheSize), time.Duration(pollInterval)*time.Second)
+	if err != nil {
+		log.Errorf("NewProxyServer error:%s", err)
+		return
+	}
+	address := fmt.Sprintf("%s:%d", ctx.String(utils.GetFlagName(utils.ProxyAddressFlag)),
+		ctx.Uint(utils.GetFlagName(utils.ProxyPortFlag)))
+	go func() {
+		err := proxy.Start(address)
+		if err != nil {
+			log.Errorf("Rpc proxy start error:%s", err)
+			os.Exit(1)
+		}
+	}()
+	log.Infof("Rpc proxy of %s listening on: %s", upstream, address)
+
+	exit := make(chan bool, 0)
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		for sig := range sc {
+			log.Infof("Rpc proxy received exit signal:%v.", sig.String())
+			proxy.Close()
+			close(exit)
+			break
+		}
+	}()
+	<-exit
+}
+
+func main() {
+	if err := setupRpcProxy().Run(os.Args); err != nil {
+		cmd.PrintErrorMsg(err.Error())
+		os.Exit(1)
+	}
+}
@@ -37,6 +37,10 @@ func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
 	setRpcConfig(ctx, cfg.Rpc)
 	setRestfulConfig(ctx, cfg.Restful)
 	setWebSocketConfig(ctx, cfg.Ws)
+	err = setChainSpec(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("setChainSpec error:%s", err)
+	}
 	if cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
 		cfg.Ws.EnableHttpWs = true
 		cfg.Restful.EnableHttpRestful = true
@@ -56,13 +60,41 @@ func setGenesis(ctx *cli.Context, cfg *config.OntologyConfig) error {
 	return nil
 }
 
+func setChainSpec(ctx *cli.Context, cfg *config.OntologyConfig) error {
+	file := ctx.String(utils.GetFlagName(utils.ChainSpecFlag))
+	if file == "" {
+		return nil
+	}
+	spec, err := config.LoadChainSpec(file)
+	if err != nil {
+		return err
+	}
+	spec.Apply(cfg)
+	log.Infof("Chain spec %s loaded, network id %d", spec.Name, spec.NetworkId)
+	return nil
+}
+
 func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.LogLevel = ctx.Uint(utils.GetFlagName(utils.LogLevelFlag))
 	cfg.EnableEventLog = !ctx.Bool(utils.GetFlagName(utils.DisableEventLogFlag))
+	cfg.EnableTxIndex = ctx.Bool(utils.GetFlagName(utils.EnableTxIndexFlag))
+	cfg.EnableCompression = ctx.Bool(utils.GetFlagName(utils.EnableCompressionFlag))
+	cfg.CompactWindow = ctx.String(utils.GetFlagName(utils.CompactWindowFlag))
 	cfg.GasLimit = ctx.Uint64(utils.GetFlagName(utils.GasLimitFlag))
 	cfg.GasPrice = ctx.Uint64(utils.GetFlagName(utils.GasPriceFlag))
 	cfg.MinOngLimit = ctx.Uint64(utils.GetFlagName(utils.MinOngLimitFlag))
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
+	cfg.StateCacheSize = ctx.Uint(utils.GetFlagName(utils.StateCacheSizeFlag))
+	cfg.HeaderIndexBatchSize = ctx.Uint(utils.GetFlagName(utils.HeaderIndexBatchSizeFlag))
+	cfg.InvariantCheckInterval = ctx.Uint(utils.GetFlagName(utils.InvariantCheckIntervalFlag))
+	cfg.Layer2Retention = ctx.Uint(utils.GetFlagName(utils.Layer2RetentionFlag))
+	cfg.Layer2CheckpointInterval = ctx.Uint(utils.GetFlagName(utils.Layer2CheckpointIntervalFlag))
+	cfg.EnableStateHistory = ctx.Bool(utils.GetFlagName(utils.EnableStateHistoryFlag))
+	cfg.EnableFullStateRoot = ctx.Bool(utils.GetFlagName(utils.EnableFullStateRootFlag))
+	cfg.CheckpointSnapshotDir = ctx.String(utils.GetFlagName(utils.CheckpointSnapshotDirFlag))
+	cfg.EncryptKeyFile = ctx.String(utils.GetFlagName(utils.EncryptKeyFileFlag))
+	cfg.IntegrityCheckBlocks = ctx.Uint(utils.GetFlagName(utils.IntegrityCheckBlocksFlag))
+	cfg.EnableAutoRepair = !ctx.Bool(utils.GetFlagName(utils.DisableAutoRepairFlag))
 }
 
 func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
@@ -74,6 +106,7 @@ func setRpcConfig(ctx *cli.Context, cfg *config.RpcConfig) {
 	cfg.EnableHttpJsonRpc = !ctx.Bool(utils.GetFlagName(utils.RPCDisabledFlag))
 	cfg.HttpJsonPort = ctx.Uint(utils.GetFlagName(utils.RPCPortFlag))
 	cfg.HttpLocalPort = ctx.Uint(utils.GetFlagName(utils.RPCLocalProtFlag))
+	cfg.SignProof = ctx.Bool(utils.GetFlagName(utils.RPCSignProofFlag))
 }
 
 func setRestfulConfig(ctx *cli.Context, cfg *config.RestfulConfig) {
@@ -28,7 +28,9 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/ontio/layer2/node/cmd/utils"
+	"github.com/ontio/layer2/node/common/log"
 	"github.com/ontio/layer2/node/common/serialization"
+	"github.com/ontio/layer2/node/core/ledger"
 )
 
 var ExportCommand = cli.Command{
@@ -42,11 +44,20 @@ var ExportCommand = cli.Command{
 		utils.ExportStartHeightFlag,
 		utils.ExportEndHeightFlag,
 		utils.ExportSpeedFlag,
+		utils.ExportOfflineFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.EncryptKeyFileFlag,
 	},
-	Description: "",
+	Description: "Export blocks of the running node by json rpc, or with --offline from the ledger in --data-dir " +
+		"to a versioned and checksummed chain dump file, which is verified and re-executed by import",
 }
 
 func exportBlocks(ctx *cli.Context) error {
+	if ctx.Bool(utils.GetFlagName(utils.ExportOfflineFlag)) {
+		return exportChainDump(ctx)
+	}
 	SetRpcPort(ctx)
 	exportFile := ctx.String(utils.GetFlagName(utils.ExportFileFlag))
 	if exportFile == "" {
@@ -170,3 +181,51 @@ func exportBlocks(ctx *cli.Context) error {
 	PrintInfoMsg("Export file:%s", exportFile)
 	return nil
 }
+
+//exportChainDump export blocks from the ledger in data dir to chain dump file
+func exportChainDump(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	_, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	exportFile := ctx.String(utils.GetFlagName(utils.ExportFileFlag))
+	if exportFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.ExportFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	startHeight := uint32(ctx.Uint(utils.GetFlagName(utils.ExportStartHeightFlag)))
+	endHeight := uint32(ctx.Uint(utils.GetFlagName(utils.ExportEndHeightFlag)))
+	if endHeight > 0 && startHeight > endHeight {
+		return fmt.Errorf("export error: start height should smaller than end height")
+	}
+	err = openCmdLedger()
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+	currentBlockHeight := ledger.DefLedger.GetCurrentBlockHeight()
+	if startHeight > currentBlockHeight {
+		PrintWarnMsg("StartBlockHeight:%d larger than CurrentBlockHeight:%d, No blocks to export.", startHeight, currentBlockHeight)
+		return nil
+	}
+	if endHeight == 0 || endHeight > currentBlockHeight {
+		endHeight = currentBlockHeight
+	}
+
+	exportFile = utils.GenExportBlocksFileName(exportFile, startHeight, endHeight)
+	PrintInfoMsg("Start export.")
+	err = ledger.DefLedger.ExportBlocks(exportFile, startHeight, endHeight)
+	if err != nil {
+		return fmt.Errorf("export blocks error:%s", err)
+	}
+	PrintInfoMsg("Export blocks successfully.")
+	PrintInfoMsg("StartBlockHeight:%d", startHeight)
+	PrintInfoMsg("EndBlockHeight:%d", endHeight)
+	PrintInfoMsg("Export file:%s", exportFile)
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/layer2/node/cmd/utils"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/store/ledgerstore"
+)
+
+var FastSyncCommand = cli.Command{
+	Name:      "fastsync",
+	Usage:     "Init the ledger in DB from a checkpoint state snapshot",
+	ArgsUsage: "",
+	Action:    fastSync,
+	Flags: []cli.Flag{
+		utils.SnapshotFileFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.EncryptKeyFileFlag,
+	},
+	Description: "Note that the data dir should not have a ledger, the blocks after the checkpoint should be imported or synced after fastsync",
+}
+
+func fastSync(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	_, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		return fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.NETWORK_NAME_SOLO_NET)
+	height, err := ledgerstore.ImportCheckpointSnapshot(snapshotFile, dbDir, genesisBlock, bookKeepers)
+	if err != nil {
+		return fmt.Errorf("import checkpoint snapshot error:%s", err)
+	}
+	PrintInfoMsg("Fast sync from checkpoint at height %d to %s complete.", height, dbDir)
+	return nil
+}
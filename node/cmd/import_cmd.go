@@ -34,6 +34,7 @@ import (
 	"github.com/ontio/layer2/node/common/serialization"
 	"github.com/ontio/layer2/node/core/genesis"
 	"github.com/ontio/layer2/node/core/ledger"
+	"github.com/ontio/layer2/node/core/store/ledgerstore"
 	"github.com/ontio/layer2/node/core/types"
 )
 
@@ -48,6 +49,7 @@ var ImportCommand = cli.Command{
 		utils.DataDirFlag,
 		utils.ConfigFlag,
 		utils.NetworkIdFlag,
+		utils.EncryptKeyFileFlag,
 		utils.DisableEventLogFlag,
 	},
 	Description: "Note that import cmd doesn't support testmode",
@@ -62,25 +64,9 @@ func importBlocks(ctx *cli.Context) error {
 		cli.ShowSubcommandHelp(ctx)
 		return nil
 	}
-	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.NETWORK_NAME_SOLO_NET)
-
-	stateHashHeight := config.GetStateHashCheckHeight(config.NETWORK_ID_SOLO_NET)
-	ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
-	if err != nil {
-		return fmt.Errorf("NewLedger error:%s", err)
-	}
-	bookKeepers, err := config.DefConfig.GetBookkeepers()
-	if err != nil {
-		return fmt.Errorf("GetBookkeepers error:%s", err)
-	}
-	genesisConfig := config.DefConfig.Genesis
-	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, genesisConfig)
-	if err != nil {
-		return fmt.Errorf("BuildGenesisBlock error %s", err)
-	}
-	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	err = openCmdLedger()
 	if err != nil {
-		return fmt.Errorf("init ledger error:%s", err)
+		return err
 	}
 
 	dataDir := ctx.String(utils.GetFlagName(utils.DataDirFlag))
@@ -110,6 +96,19 @@ func importBlocks(ctx *cli.Context) error {
 	defer ifile.Close()
 	fReader := bufio.NewReader(ifile)
 
+	magic, _ := fReader.Peek(4)
+	if ledgerstore.IsChainDump(magic) {
+		if endBlockHeight > 0 {
+			PrintWarnMsg("%s is ignored for chain dump file.", utils.ImportEndHeightFlag.Name)
+		}
+		PrintInfoMsg("Start import blocks.")
+		err = ledger.DefLedger.ImportBlocks(importFile)
+		if err != nil {
+			return fmt.Errorf("import blocks error:%s", err)
+		}
+		PrintInfoMsg("Import block completed, current block height:%d.", ledger.DefLedger.GetCurrentBlockHeight())
+		return nil
+	}
 	metadata := utils.NewExportBlockMetadata()
 	err = metadata.Deserialize(fReader)
 	if err != nil {
@@ -154,7 +153,7 @@ func importBlocks(ctx *cli.Context) error {
 		}
 		var crossMsgCompressData []byte
 		if crossMsgSize != 0 {
-			crossMsgCompressData = make([]byte, size)
+			crossMsgCompressData = make([]byte, crossMsgSize)
 			_, err = io.ReadFull(fReader, crossMsgCompressData)
 			if err != nil {
 				return fmt.Errorf("read block data height:%d error:%s", i, err)
@@ -197,3 +196,29 @@ func importBlocks(ctx *cli.Context) error {
 	PrintInfoMsg("Import block completed, current block height:%d.", ledger.DefLedger.GetCurrentBlockHeight())
 	return nil
 }
+
+//openCmdLedger open the ledger in data dir as DefLedger, and init it with the genesis block of config
+func openCmdLedger() error {
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.NETWORK_NAME_SOLO_NET)
+
+	stateHashHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
+	var err error
+	ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
+	if err != nil {
+		return fmt.Errorf("NewLedger error:%s", err)
+	}
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisConfig := config.DefConfig.Genesis
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, genesisConfig)
+	if err != nil {
+		return fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	if err != nil {
+		return fmt.Errorf("init ledger error:%s", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/layer2/node/cmd/utils"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/store/ledgerstore"
+)
+
+var RestoreCommand = cli.Command{
+	Name:      "restore",
+	Usage:     "Restore the ledger in DB from a backup",
+	ArgsUsage: "",
+	Action:    restoreLedger,
+	Flags: []cli.Flag{
+		utils.BackupDirFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.EncryptKeyFileFlag,
+	},
+	Description: "Note that the node should be stopped, and the backup should be on the same chain with the current ledger",
+}
+
+func restoreLedger(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	_, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	backupDir := ctx.String(utils.GetFlagName(utils.BackupDirFlag))
+	if backupDir == "" {
+		PrintErrorMsg("Missing %s argument.", utils.BackupDirFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.NETWORK_NAME_SOLO_NET)
+	err = ledgerstore.RestoreBackup(backupDir, dbDir)
+	if err != nil {
+		return fmt.Errorf("restore ledger error:%s", err)
+	}
+	PrintInfoMsg("Restore ledger from %s to %s complete.", backupDir, dbDir)
+	return nil
+}
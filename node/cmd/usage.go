@@ -98,10 +98,32 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "ONTOLOGY",
 		Flags: []cli.Flag{
 			utils.ConfigFlag,
+			utils.ChainSpecFlag,
 			utils.LogLevelFlag,
 			utils.DisableLogFileFlag,
 			utils.DisableEventLogFlag,
+			utils.EnableTxIndexFlag,
+			utils.EnableCompressionFlag,
+			utils.CompactWindowFlag,
 			utils.DataDirFlag,
+			utils.StateCacheSizeFlag,
+			utils.HeaderIndexBatchSizeFlag,
+			utils.InvariantCheckIntervalFlag,
+			utils.Layer2RetentionFlag,
+			utils.Layer2CheckpointIntervalFlag,
+			utils.EnableStateHistoryFlag,
+			utils.EnableFullStateRootFlag,
+			utils.CheckpointSnapshotDirFlag,
+			utils.EncryptKeyFileFlag,
+			utils.RecoverOnlyFlag,
+			utils.IntegrityCheckBlocksFlag,
+			utils.DisableAutoRepairFlag,
+			utils.LightUpstreamFlag,
+			utils.ReadOnlyFlag,
+			utils.ReadOnlyViewDirFlag,
+			utils.ReadOnlyRefreshFlag,
+			utils.MetricsFlag,
+			utils.MetricsPortFlag,
 		},
 	},
 	{
@@ -162,6 +184,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.RPCDisabledFlag,
 			utils.RPCPortFlag,
+			utils.RPCSignProofFlag,
 			utils.RPCLocalEnableFlag,
 			utils.RPCLocalProtFlag,
 		},
@@ -243,6 +266,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ExportSpeedFlag,
 			utils.ExportStartHeightFlag,
 			utils.ExportEndHeightFlag,
+			utils.ExportOfflineFlag,
 		},
 	},
 	{
@@ -252,6 +276,26 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ImportEndHeightFlag,
 		},
 	},
+	{
+		Name: "VERIFY",
+		Flags: []cli.Flag{
+			utils.VerifyDirFlag,
+			utils.CheckpointDirFlag,
+			utils.VerifyEndHeightFlag,
+		},
+	},
+	{
+		Name: "RESTORE",
+		Flags: []cli.Flag{
+			utils.BackupDirFlag,
+		},
+	},
+	{
+		Name: "FASTSYNC",
+		Flags: []cli.Flag{
+			utils.SnapshotFileFlag,
+		},
+	},
 	{
 		Name: "MISC",
 	},
@@ -31,6 +31,12 @@ const (
 	DEFAULT_ABI_PATH      = "./abi"
 	DEFAULT_EXPORT_HEIGHT = 0
 	DEFAULT_WALLET_PATH   = "./wallet_data"
+
+	DEFAULT_PROXY_UPSTREAM      = "http://127.0.0.1:20336"
+	DEFAULT_PROXY_ADDRESS       = "0.0.0.0"
+	DEFAULT_PROXY_PORT          = uint(20346)
+	DEFAULT_PROXY_CACHE_SIZE    = uint(100000)
+	DEFAULT_PROXY_POLL_INTERVAL = uint(1)
 )
 
 var (
@@ -39,6 +45,10 @@ var (
 		Name:  "config",
 		Usage: "Genesis block config `<file>`. If doesn't specifies, use main net config as default.",
 	}
+	ChainSpecFlag = cli.StringFlag{
+		Name:  "chainspec",
+		Usage: "Chain spec `<file>` shared with the operator. Overrides genesis bookkeepers, block time and protocol params.",
+	}
 	LogLevelFlag = cli.UintFlag{
 		Name:  "loglevel",
 		Usage: "Set the log level to `<level>` (0~6). 0:Trace 1:Debug 2:Info 3:Warn 4:Error 5:Fatal 6:MaxLevel",
@@ -52,6 +62,18 @@ var (
 		Name:  "disable-event-log",
 		Usage: "Discard event log output by smart contract execution",
 	}
+	EnableTxIndexFlag = cli.BoolFlag{
+		Name:  "enable-tx-index",
+		Usage: "Index transactions by payer and signer address for gettransactionsbyaddress, only blocks saved after enabled are indexed",
+	}
+	EnableCompressionFlag = cli.BoolFlag{
+		Name:  "enable-compression",
+		Usage: "Compress transactions and event notifies saved to disk by snappy, data saved before is still readable",
+	}
+	CompactWindowFlag = cli.StringFlag{
+		Name:  "compact-window",
+		Usage: "Compact the store databases once a day in local time window `<HH:MM-HH:MM>`, empty to disable",
+	}
 	WalletFileFlag = cli.StringFlag{
 		Name:  "wallet,w",
 		Value: config.DEFAULT_WALLET_FILE_NAME,
@@ -67,11 +89,110 @@ var (
 		Usage: "Stop import block `<height>` of the import.",
 		Value: DEFAULT_EXPORT_HEIGHT,
 	}
+	BackupDirFlag = cli.StringFlag{
+		Name:  "backup-dir",
+		Usage: "Ledger backup `<path>` made by local rpc backupledger",
+	}
+	VerifyDirFlag = cli.StringFlag{
+		Name:  "verify-dir",
+		Usage: "Ledger data `<path>` to verify, the directory contains block and states databases",
+	}
+	CheckpointDirFlag = cli.StringFlag{
+		Name:  "checkpoint-dir",
+		Usage: "Trusted ledger backup `<path>` to start the verification from, empty to start from genesis",
+	}
+	VerifyEndHeightFlag = cli.UintFlag{
+		Name:  "end-height",
+		Usage: "Stop verification at block `<height>`, 0 to verify to the last executed block",
+	}
 	DataDirFlag = cli.StringFlag{
 		Name:  "data-dir",
 		Usage: "Block data storage `<path>`",
 		Value: config.DEFAULT_DATA_DIR,
 	}
+	StateCacheSizeFlag = cli.UintFlag{
+		Name:  "state-cache-size",
+		Usage: "Max `<number>` of contract states and storages cached in memory, 0 to disable the cache",
+		Value: config.DEFAULT_STATE_CACHE_SIZE,
+	}
+	HeaderIndexBatchSizeFlag = cli.UintFlag{
+		Name:  "header-index-batch-size",
+		Usage: "Persist the block hashes of header index in batches of `<number>` heights",
+		Value: config.DEFAULT_HEADER_INDEX_BATCH_SIZE,
+	}
+	InvariantCheckIntervalFlag = cli.UintFlag{
+		Name:  "invariant-check-interval",
+		Usage: "Check the chain invariants every `<seconds>` in background, 0 to disable the checker",
+		Value: config.DEFAULT_INVARIANT_CHECK_INTERVAL,
+	}
+	Layer2RetentionFlag = cli.UintFlag{
+		Name:  "layer2-retention",
+		Usage: "Keep the layer2 states of the latest `<number>` blocks and the checkpoint heights, 0 to keep all",
+	}
+	Layer2CheckpointIntervalFlag = cli.UintFlag{
+		Name:  "layer2-checkpoint-interval",
+		Usage: "Keep the layer2 states of the heights which are multiple of `<number>` out of retention",
+		Value: config.DEFAULT_LAYER2_CHECKPOINT_INTERVAL,
+	}
+	EnableStateHistoryFlag = cli.BoolFlag{
+		Name:  "enable-state-history",
+		Usage: "Save the storage values before each block to query the state at history heights",
+	}
+	EnableFullStateRootFlag = cli.BoolFlag{
+		Name:  "enable-full-state-root",
+		Usage: "Maintain the merkle patricia trie root of all contract storage to prove any storage key",
+	}
+	CheckpointSnapshotDirFlag = cli.StringFlag{
+		Name:  "checkpoint-snapshot-dir",
+		Usage: "Write the state snapshot at each checkpoint to `<path>` for fast sync, empty to disable",
+	}
+	EncryptKeyFileFlag = cli.StringFlag{
+		Name:  "encrypt-key-file",
+		Usage: "Encrypt the values of block, state and event store by AES-GCM with the keys in json `<file>`",
+	}
+	SnapshotFileFlag = cli.StringFlag{
+		Name:  "snapshot-file",
+		Usage: "Checkpoint snapshot `<file>` written by a node started with --checkpoint-snapshot-dir",
+	}
+	RecoverOnlyFlag = cli.BoolFlag{
+		Name:  "recover-only",
+		Usage: "Exit after the saved blocks are re-executed to the state store at startup",
+	}
+	IntegrityCheckBlocksFlag = cli.UintFlag{
+		Name:  "integrity-check-blocks",
+		Usage: "Verify the latest `<number>` blocks at startup, 0 to disable the integrity check",
+		Value: config.DEFAULT_INTEGRITY_CHECK_BLOCKS,
+	}
+	DisableAutoRepairFlag = cli.BoolFlag{
+		Name:  "disable-auto-repair",
+		Usage: "Refuse to start instead of truncating the blocks from the corrupted height found by the integrity check",
+	}
+	LightUpstreamFlag = cli.StringFlag{
+		Name:  "light-upstream",
+		Usage: "Run as header-only light node which syncs block headers and layer2 states from the json rpc `<url>` of full node",
+	}
+	ReadOnlyFlag = cli.BoolFlag{
+		Name:  "read-only",
+		Usage: "Run as read-only replica which serves the queries from the data dir written by other node process",
+	}
+	ReadOnlyViewDirFlag = cli.StringFlag{
+		Name:  "read-only-view-dir",
+		Usage: "Create the views of read-only replica in `<path>`, system temp dir if empty. Should be on the same file system as data dir to link the table files",
+	}
+	ReadOnlyRefreshFlag = cli.UintFlag{
+		Name:  "read-only-refresh",
+		Usage: "Refresh the views of read-only replica every `<seconds>`",
+		Value: config.DEFAULT_READ_ONLY_REFRESH_INTERVAL,
+	}
+	MetricsFlag = cli.BoolFlag{
+		Name:  "metrics",
+		Usage: "Collect the ledger metrics and serve them in prometheus format",
+	}
+	MetricsPortFlag = cli.UintFlag{
+		Name:  "metrics-port",
+		Usage: "Prometheus metrics server listening `<port>`",
+		Value: config.DEFAULT_METRICS_PORT,
+	}
 
 	//Consensus setting
 	EnableConsensusFlag = cli.BoolFlag{
@@ -161,6 +282,10 @@ var (
 		Usage: "Json rpc server listening port `<number>`",
 		Value: config.DEFAULT_RPC_PORT,
 	}
+	RPCSignProofFlag = cli.BoolFlag{
+		Name:  "rpc-sign-proof",
+		Usage: "Sign the result of proof and layer2 state json rpc with the bookkeeper key",
+	}
 	RPCLocalEnableFlag = cli.BoolFlag{
 		Name:  "localrpc",
 		Usage: "Enable local rpc server",
@@ -453,6 +578,33 @@ var (
 		Value: DEFAULT_WALLET_PATH,
 	}
 
+	//Rpc proxy setting
+	ProxyUpstreamFlag = cli.StringFlag{
+		Name:  "upstream",
+		Usage: "Json rpc `<url>` of the upstream node",
+		Value: DEFAULT_PROXY_UPSTREAM,
+	}
+	ProxyAddressFlag = cli.StringFlag{
+		Name:  "proxyaddress",
+		Usage: "Proxy bind `<address>`",
+		Value: DEFAULT_PROXY_ADDRESS,
+	}
+	ProxyPortFlag = cli.UintFlag{
+		Name:  "proxyport",
+		Usage: "Proxy bind port `<number>`",
+		Value: DEFAULT_PROXY_PORT,
+	}
+	ProxyCacheSizeFlag = cli.UintFlag{
+		Name:  "proxy-cache-size",
+		Usage: "Max `<number>` of cached responses of each cache scope",
+		Value: DEFAULT_PROXY_CACHE_SIZE,
+	}
+	ProxyPollIntervalFlag = cli.UintFlag{
+		Name:  "proxy-poll-interval",
+		Usage: "Interval `<time>`(s) to poll the block height of upstream node",
+		Value: DEFAULT_PROXY_POLL_INTERVAL,
+	}
+
 	//Export setting
 	ExportFileFlag = cli.StringFlag{
 		Name:  "export-file",
@@ -474,6 +626,10 @@ var (
 		Usage: "Export block speed `<level>` (h|m|l), h for high speed, m for middle speed and l for low speed",
 		Value: "m",
 	}
+	ExportOfflineFlag = cli.BoolFlag{
+		Name:  "offline",
+		Usage: "Export blocks from the ledger in --data-dir of a stopped node to a checksummed chain dump file instead of json rpc",
+	}
 
 	//PreExecute switcher
 	TxpoolPreExecDisableFlag = cli.BoolFlag{
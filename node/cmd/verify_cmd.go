@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/layer2/node/cmd/utils"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/store/ledgerstore"
+)
+
+var VerifyCommand = cli.Command{
+	Name:      "verify",
+	Usage:     "Verify a ledger data directory by re-executing the blocks",
+	ArgsUsage: "",
+	Action:    verifyChain,
+	Flags: []cli.Flag{
+		utils.VerifyDirFlag,
+		utils.CheckpointDirFlag,
+		utils.VerifyEndHeightFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.EncryptKeyFileFlag,
+		utils.DisableEventLogFlag,
+	},
+	Description: "Re-execute the blocks from genesis or a checkpoint, and compare the state merkle roots, " +
+		"updated account states and block roots with the stored values",
+}
+
+func verifyChain(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	_, err := SetOntologyConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOntologyConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	verifyDir := ctx.String(utils.GetFlagName(utils.VerifyDirFlag))
+	if verifyDir == "" {
+		PrintErrorMsg("Missing %s argument.", utils.VerifyDirFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	checkpointDir := ctx.String(utils.GetFlagName(utils.CheckpointDirFlag))
+	endHeight := uint32(ctx.Uint(utils.GetFlagName(utils.VerifyEndHeightFlag)))
+
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		return fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	workDir, err := ioutil.TempDir("", "layer2-verify")
+	if err != nil {
+		return fmt.Errorf("TempDir error:%s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	result, err := ledgerstore.VerifyChain(verifyDir, workDir, checkpointDir, genesisBlock, bookKeepers, endHeight)
+	if err != nil {
+		return fmt.Errorf("verify chain error:%s", err)
+	}
+	if result.Diverged {
+		PrintErrorMsg("Verify failed, first divergent height:%d, %s", result.DivergedHeight, result.Reason)
+		return fmt.Errorf("chain diverged at height %d", result.DivergedHeight)
+	}
+	PrintInfoMsg("Verify blocks from height %d to %d success.", result.StartHeight, result.EndHeight)
+	return nil
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+//ChainSpec is the single network definition shared by the layer2 node and the operator.
+//The operator reads the same file format, so one file describes the whole network.
+type ChainSpec struct {
+	Name              string
+	NetworkId         uint32
+	Genesis           *ChainSpecGenesis
+	Bridge            *ChainSpecBridge
+	Tokens            []*ChainSpecToken
+	Params            *ChainSpecParams
+	Features          []*ChainSpecFeature
+	AccountRules      []*ChainSpecAccountRule //Storage of 20 bytes account address key of all contracts if not set
+	AccountTreeHeight uint32                  //First block whose account state root is the sparse merkle tree root
+	HardForks         []*ChainSpecHardFork    //Sorted by height
+	Operators         []*ChainSpecOperator    //Address of the first bookkeeper if not set
+}
+
+type ChainSpecGenesis struct {
+	ConsensusType string
+	GenBlockTime  uint
+	Bookkeepers   []string
+	SeedList      []string
+}
+
+//ChainSpecBridge describe the layer2 contract deployed on ontology
+type ChainSpecBridge struct {
+	OntologyRestURL       string
+	Layer2ContractAddress string
+	GasPrice              uint64
+	GasLimit              uint64
+}
+
+//ChainSpecToken is one entry of the token registry, the address is the same on ontology and layer2
+type ChainSpecToken struct {
+	Name     string
+	Address  string
+	Decimals uint32
+}
+
+//ChainSpecFeature override the activation height of a registered feature
+type ChainSpecFeature struct {
+	Name             string
+	ActivationHeight uint32
+	Disabled         bool
+}
+
+//ChainSpecAccountRule select the storage of contract which feeds the account state root from the activation height. The
+//storage key of account is the hex KeyPrefix followed by the account address, and an empty Contract matches all contracts
+type ChainSpecAccountRule struct {
+	Contract         string
+	KeyPrefix        string
+	ActivationHeight uint32
+}
+
+//ChainSpecHardFork is a protocol upgrade of the layer2 without resetting the chain. From the height, the entries of GasTable
+//override the neovm gas table and the Features are active
+type ChainSpecHardFork struct {
+	Name     string
+	Height   uint32
+	GasTable map[string]uint64
+	Features []string
+}
+
+//ChainSpecOperator is the payer account of the operator transactions in the blocks from the activation height until the
+//retire height, 0 if it is not retired. The operator key is rotated by the overlapping heights of the old and new account
+type ChainSpecOperator struct {
+	Address          string
+	ActivationHeight uint32
+	RetireHeight     uint32
+}
+
+type ChainSpecParams struct {
+	GasLimit     uint64
+	MinOngLimit  uint64
+	MaxTxInBlock uint
+}
+
+//LoadChainSpec read and validate the chain spec file
+func LoadChainSpec(file string) (*ChainSpec, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read chain spec %s error %s", file, err)
+	}
+	spec := &ChainSpec{}
+	err = json.Unmarshal(data, spec)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal chain spec %s error %s", file, err)
+	}
+	err = spec.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain spec %s: %s", file, err)
+	}
+	return spec, nil
+}
+
+//Validate check the chain spec is usable by the node
+func (this *ChainSpec) Validate() error {
+	if this.NetworkId == 0 {
+		return fmt.Errorf("NetworkId is not set")
+	}
+	if this.Genesis != nil {
+		if this.Genesis.ConsensusType != "" && this.Genesis.ConsensusType != CONSENSUS_TYPE_SOLO {
+			return fmt.Errorf("does not support %s consensus", this.Genesis.ConsensusType)
+		}
+		if len(this.Genesis.Bookkeepers) > SOLO_MIN_NODE_NUM {
+			return fmt.Errorf("solo consensus only support %d bookkeeper", SOLO_MIN_NODE_NUM)
+		}
+		for _, key := range this.Genesis.Bookkeepers {
+			data, err := hex.DecodeString(key)
+			if err != nil {
+				return fmt.Errorf("bookkeeper %s is not hex: %s", key, err)
+			}
+			_, err = keypair.DeserializePublicKey(data)
+			if err != nil {
+				return fmt.Errorf("bookkeeper %s is not a public key: %s", key, err)
+			}
+		}
+	}
+	if this.Bridge != nil && this.Bridge.Layer2ContractAddress != "" {
+		data, err := hex.DecodeString(this.Bridge.Layer2ContractAddress)
+		if err != nil || len(data) != 20 {
+			return fmt.Errorf("Layer2ContractAddress %s is not a hex address", this.Bridge.Layer2ContractAddress)
+		}
+	}
+	names := make(map[string]bool)
+	for _, token := range this.Tokens {
+		if token.Name == "" {
+			return fmt.Errorf("token %s has no name", token.Address)
+		}
+		if names[token.Name] {
+			return fmt.Errorf("token %s is duplicated", token.Name)
+		}
+		names[token.Name] = true
+		data, err := hex.DecodeString(token.Address)
+		if err != nil || len(data) != 20 {
+			return fmt.Errorf("token %s address %s is not a hex address", token.Name, token.Address)
+		}
+	}
+	for _, rule := range this.AccountRules {
+		if rule.Contract != "" {
+			data, err := hex.DecodeString(rule.Contract)
+			if err != nil || len(data) != 20 {
+				return fmt.Errorf("account rule contract %s is not a hex address", rule.Contract)
+			}
+		}
+		if _, err := hex.DecodeString(rule.KeyPrefix); err != nil {
+			return fmt.Errorf("account rule key prefix %s is not hex", rule.KeyPrefix)
+		}
+	}
+	for _, operator := range this.Operators {
+		if _, err := common.AddressFromBase58(operator.Address); err != nil {
+			return fmt.Errorf("operator %s is not an address: %s", operator.Address, err)
+		}
+		if operator.RetireHeight != 0 && operator.RetireHeight <= operator.ActivationHeight {
+			return fmt.Errorf("operator %s retire height %d is not above activation height %d", operator.Address,
+				operator.RetireHeight, operator.ActivationHeight)
+		}
+	}
+	features := make(map[string]bool)
+	for _, feature := range this.Features {
+		if GetFeature(feature.Name) == nil {
+			return fmt.Errorf("unknown feature %s", feature.Name)
+		}
+		if features[feature.Name] {
+			return fmt.Errorf("feature %s is duplicated", feature.Name)
+		}
+		features[feature.Name] = true
+	}
+	forks := make(map[string]bool)
+	for i, fork := range this.HardForks {
+		if fork.Name == "" {
+			return fmt.Errorf("hard fork at height %d has no name", fork.Height)
+		}
+		if forks[fork.Name] {
+			return fmt.Errorf("hard fork %s is duplicated", fork.Name)
+		}
+		forks[fork.Name] = true
+		if i > 0 && fork.Height <= this.HardForks[i-1].Height {
+			return fmt.Errorf("hard fork %s height %d is not above hard fork %s", fork.Name, fork.Height,
+				this.HardForks[i-1].Name)
+		}
+		for _, name := range fork.Features {
+			if GetFeature(name) == nil {
+				return fmt.Errorf("hard fork %s has unknown feature %s", fork.Name, name)
+			}
+			if features[name] {
+				return fmt.Errorf("feature %s of hard fork %s is duplicated", name, fork.Name)
+			}
+			features[name] = true
+		}
+	}
+	return nil
+}
+
+//Apply override the genesis and protocol params of the node config by the chain spec
+func (this *ChainSpec) Apply(cfg *OntologyConfig) {
+	if this.Genesis != nil {
+		if this.Genesis.GenBlockTime > 0 {
+			cfg.Genesis.SOLO.GenBlockTime = this.Genesis.GenBlockTime
+		}
+		if len(this.Genesis.Bookkeepers) > 0 {
+			cfg.Genesis.SOLO.Bookkeepers = this.Genesis.Bookkeepers
+		}
+		if len(this.Genesis.SeedList) > 0 {
+			cfg.Genesis.SeedList = this.Genesis.SeedList
+		}
+	}
+	if this.Params != nil {
+		if this.Params.GasLimit > 0 {
+			cfg.Common.GasLimit = this.Params.GasLimit
+		}
+		if this.Params.MinOngLimit > 0 {
+			cfg.Common.MinOngLimit = this.Params.MinOngLimit
+		}
+		if this.Params.MaxTxInBlock > 0 {
+			cfg.Consensus.MaxTxInBlock = this.Params.MaxTxInBlock
+		}
+	}
+	for _, feature := range this.Features {
+		SetFeature(feature.Name, feature.ActivationHeight, feature.Disabled)
+	}
+	for _, fork := range this.HardForks {
+		//feature is active above the activation height, and hard fork from the height
+		activation := fork.Height
+		if activation > 0 {
+			activation--
+		}
+		for _, name := range fork.Features {
+			SetFeature(name, activation, false)
+		}
+	}
+	cfg.ChainSpec = this
+}
+
+//IsOperator return whether the address is an operator of the chain spec at the block height
+func (this *ChainSpec) IsOperator(address string, height uint32) bool {
+	for _, operator := range this.Operators {
+		if operator.Address == address && height >= operator.ActivationHeight &&
+			(operator.RetireHeight == 0 || height < operator.RetireHeight) {
+			return true
+		}
+	}
+	return false
+}
+
+//GetToken return the registered token by name
+func (this *ChainSpec) GetToken(name string) *ChainSpecToken {
+	for _, token := range this.Tokens {
+		if token.Name == name {
+			return token
+		}
+	}
+	return nil
+}
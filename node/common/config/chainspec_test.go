@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainSpec(t *testing.T) {
+	_, pub, _ := keypair.GenerateKeyPair(keypair.PK_ECDSA, keypair.P256)
+	pk := hex.EncodeToString(keypair.SerializePublicKey(pub))
+	dir, err := ioutil.TempDir("", "chainspec")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "chainspec.json")
+	data := `{"Name":"devnet","NetworkId":1000,
+		"Genesis":{"GenBlockTime":6,"Bookkeepers":["` + pk + `"]},
+		"Bridge":{"Layer2ContractAddress":"4229a92d90d446d1598e12e35698b681ae4d4642"},
+		"Tokens":[{"Name":"ONG","Address":"0000000000000000000000000000000000000002","Decimals":9}],
+		"Params":{"GasLimit":30000,"MaxTxInBlock":100}}`
+	assert.Nil(t, ioutil.WriteFile(file, []byte(data), 0644))
+
+	spec, err := LoadChainSpec(file)
+	assert.Nil(t, err)
+	cfg := NewOntologyConfig()
+	spec.Apply(cfg)
+	assert.Equal(t, uint32(1000), cfg.GetNetworkId())
+	assert.Equal(t, uint(6), cfg.Genesis.SOLO.GenBlockTime)
+	assert.Equal(t, []string{pk}, cfg.Genesis.SOLO.Bookkeepers)
+	assert.Equal(t, uint64(30000), cfg.Common.GasLimit)
+	assert.Equal(t, uint64(DEFAULT_MIN_ONG_LIMIT), cfg.Common.MinOngLimit)
+	assert.Equal(t, uint(100), cfg.Consensus.MaxTxInBlock)
+	assert.Equal(t, uint32(9), spec.GetToken("ONG").Decimals)
+	assert.Nil(t, spec.GetToken("ONT"))
+
+	spec.Tokens = append(spec.Tokens, &ChainSpecToken{Name: "ONG", Address: "0000000000000000000000000000000000000001"})
+	assert.NotNil(t, spec.Validate())
+	spec.Tokens = spec.Tokens[:1]
+	spec.Genesis.Bookkeepers = []string{"00"}
+	assert.NotNil(t, spec.Validate())
+	spec.Genesis.Bookkeepers = nil
+	spec.AccountRules = []*ChainSpecAccountRule{{Contract: "4229a92d90d446d1598e12e35698b681ae4d4642", KeyPrefix: "01"}}
+	assert.Nil(t, spec.Validate())
+	spec.AccountRules[0].KeyPrefix = "0x01"
+	assert.NotNil(t, spec.Validate())
+	spec.AccountRules[0] = &ChainSpecAccountRule{Contract: "00"}
+	assert.NotNil(t, spec.Validate())
+	spec.AccountRules = nil
+	spec.Operators = []*ChainSpecOperator{
+		{Address: "AVXbvxotHerCjbRkVPg8drJ3TUaS7EJPkb", RetireHeight: 200},
+		{Address: "AMAx993nE6NEqZjwBssUfopxnnvTdob9ij", ActivationHeight: 100},
+	}
+	assert.Nil(t, spec.Validate())
+	assert.True(t, spec.IsOperator("AVXbvxotHerCjbRkVPg8drJ3TUaS7EJPkb", 199))
+	assert.False(t, spec.IsOperator("AVXbvxotHerCjbRkVPg8drJ3TUaS7EJPkb", 200))
+	assert.False(t, spec.IsOperator("AMAx993nE6NEqZjwBssUfopxnnvTdob9ij", 99))
+	assert.True(t, spec.IsOperator("AMAx993nE6NEqZjwBssUfopxnnvTdob9ij", 100))
+	spec.Operators[0].RetireHeight = 0
+	assert.Nil(t, spec.Validate())
+	spec.Operators[1].RetireHeight = 100
+	assert.NotNil(t, spec.Validate())
+	spec.Operators[1] = &ChainSpecOperator{Address: "00"}
+	assert.NotNil(t, spec.Validate())
+	spec.Operators = nil
+	spec.NetworkId = 0
+	assert.NotNil(t, spec.Validate())
+	assert.Equal(t, uint32(NETWORK_ID_SOLO_NET), NewOntologyConfig().GetNetworkId())
+}
+
+func TestHardFork(t *testing.T) {
+	RegisterFeature("fork-feature", "feature for hard fork test", 0)
+	spec := &ChainSpec{NetworkId: 1000, HardForks: []*ChainSpecHardFork{
+		{Name: "first", Height: 100, GasTable: map[string]uint64{"SHA256": 20}},
+		{Name: "second", Height: 200, Features: []string{"fork-feature"}},
+	}}
+	assert.Nil(t, spec.Validate())
+	spec.Apply(NewOntologyConfig())
+	assert.False(t, IsFeatureActive("fork-feature", 199))
+	assert.True(t, IsFeatureActive("fork-feature", 200))
+
+	spec.HardForks[1].Height = 100
+	assert.NotNil(t, spec.Validate())
+	spec.HardForks[1].Height = 200
+	spec.HardForks[1].Name = "first"
+	assert.NotNil(t, spec.Validate())
+	spec.HardForks[1].Name = "second"
+	spec.Features = []*ChainSpecFeature{{Name: "fork-feature"}}
+	assert.NotNil(t, spec.Validate())
+	spec.Features = nil
+	spec.HardForks[1].Features = []string{"unknown-feature"}
+	assert.NotNil(t, spec.Validate())
+}
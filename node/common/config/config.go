@@ -62,9 +62,17 @@ const (
 	DEFAULT_MAX_SYNC_HEADER                 = 500
 	DEFAULT_ENABLE_CONSENSUS                = true
 	DEFAULT_ENABLE_EVENT_LOG                = true
+	DEFAULT_ENABLE_AUTO_REPAIR              = true
 	DEFAULT_CLI_RPC_PORT                    = uint(20000)
 	DEFUALT_CLI_RPC_ADDRESS                 = "127.0.0.1"
 	DEFAULT_GAS_LIMIT                       = 20000
+	DEFAULT_STATE_CACHE_SIZE                = uint(10000)
+	DEFAULT_INVARIANT_CHECK_INTERVAL        = uint(60)
+	DEFAULT_LAYER2_CHECKPOINT_INTERVAL      = uint(1000)
+	DEFAULT_METRICS_PORT                    = uint(20339)
+	DEFAULT_HEADER_INDEX_BATCH_SIZE         = uint(2000)
+	DEFAULT_READ_ONLY_REFRESH_INTERVAL      = uint(3)
+	DEFAULT_INTEGRITY_CHECK_BLOCKS          = uint(100)
 	DEFAULT_MIN_ONG_LIMIT                  = 100000000
 	DEFAULT_GAS_PRICE                       = 500
 	DEFAULT_WASM_GAS_FACTOR                 = uint64(10)
@@ -153,15 +161,29 @@ type SOLOConfig struct {
 }
 
 type CommonConfig struct {
-	LogLevel         uint
-	NodeType         string
-	EnableEventLog   bool
-	SystemFee        map[string]int64
-	GasLimit         uint64
-	GasPrice         uint64
-	MinOngLimit      uint64
-	DataDir          string
-	WasmVerifyMethod VerifyMethod
+	LogLevel                 uint
+	NodeType                 string
+	EnableEventLog           bool
+	EnableTxIndex            bool
+	EnableCompression        bool
+	SystemFee                map[string]int64
+	GasLimit                 uint64
+	GasPrice                 uint64
+	MinOngLimit              uint64
+	DataDir                  string
+	WasmVerifyMethod         VerifyMethod
+	StateCacheSize           uint
+	CompactWindow            string
+	InvariantCheckInterval   uint
+	Layer2Retention          uint
+	Layer2CheckpointInterval uint
+	EnableStateHistory       bool
+	CheckpointSnapshotDir    string
+	HeaderIndexBatchSize     uint
+	EnableFullStateRoot      bool
+	EncryptKeyFile           string
+	IntegrityCheckBlocks     uint
+	EnableAutoRepair         bool
 }
 
 type ConsensusConfig struct {
@@ -173,6 +195,7 @@ type RpcConfig struct {
 	EnableHttpJsonRpc bool
 	HttpJsonPort      uint
 	HttpLocalPort     uint
+	SignProof         bool
 }
 
 type RestfulConfig struct {
@@ -197,19 +220,26 @@ type OntologyConfig struct {
 	Rpc       *RpcConfig
 	Restful   *RestfulConfig
 	Ws        *WebSocketConfig
+	ChainSpec *ChainSpec `json:"-"`
 }
 
 func NewOntologyConfig() *OntologyConfig {
 	return &OntologyConfig{
 		Genesis: NewGenesisConfig(),
 		Common: &CommonConfig{
-			LogLevel:         DEFAULT_LOG_LEVEL,
-			EnableEventLog:   DEFAULT_ENABLE_EVENT_LOG,
-			SystemFee:        make(map[string]int64),
-			GasLimit:         DEFAULT_GAS_LIMIT,
-			MinOngLimit:      DEFAULT_MIN_ONG_LIMIT,
-			DataDir:          DEFAULT_DATA_DIR,
-			WasmVerifyMethod: InterpVerifyMethod,
+			LogLevel:                 DEFAULT_LOG_LEVEL,
+			EnableEventLog:           DEFAULT_ENABLE_EVENT_LOG,
+			SystemFee:                make(map[string]int64),
+			GasLimit:                 DEFAULT_GAS_LIMIT,
+			MinOngLimit:              DEFAULT_MIN_ONG_LIMIT,
+			DataDir:                  DEFAULT_DATA_DIR,
+			WasmVerifyMethod:         InterpVerifyMethod,
+			StateCacheSize:           DEFAULT_STATE_CACHE_SIZE,
+			InvariantCheckInterval:   DEFAULT_INVARIANT_CHECK_INTERVAL,
+			Layer2CheckpointInterval: DEFAULT_LAYER2_CHECKPOINT_INTERVAL,
+			HeaderIndexBatchSize:     DEFAULT_HEADER_INDEX_BATCH_SIZE,
+			IntegrityCheckBlocks:     DEFAULT_INTEGRITY_CHECK_BLOCKS,
+			EnableAutoRepair:         DEFAULT_ENABLE_AUTO_REPAIR,
 		},
 		Consensus: &ConsensusConfig{
 			EnableConsensus: true,
@@ -252,6 +282,14 @@ func (this *OntologyConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 	return pubKeys, nil
 }
 
+//GetNetworkId return the network id of the chain spec, or the solo network id without chain spec
+func (this *OntologyConfig) GetNetworkId() uint32 {
+	if this.ChainSpec != nil {
+		return this.ChainSpec.NetworkId
+	}
+	return NETWORK_ID_SOLO_NET
+}
+
 func (this *OntologyConfig) getDefNetworkIDFromGenesisConfig(genCfg *GenesisConfig) (uint32, error) {
 	var configData []byte
 	var err error
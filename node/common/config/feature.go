@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	FEATURE_STATE_HASH_CHECK = "state-hash-check" //Calculate state merkle root of blocks
+	FEATURE_OPCODE_HASKEY    = "opcode-haskey"    //Enable neovm HASKEY opcode and disallow reader EOF
+	FEATURE_GAS_ROUND_TUNE   = "gas-round-tune"   //Round up the gas fee of transaction to gas round
+)
+
+//Feature is a protocol switch of a module, it is active at the blocks above the activation height
+type Feature struct {
+	Name             string
+	Description      string
+	ActivationHeight uint32
+	Disabled         bool
+}
+
+//IsActive return whether the feature is active at the block height
+func (this *Feature) IsActive(height uint32) bool {
+	return !this.Disabled && height > this.ActivationHeight
+}
+
+var features = struct {
+	sync.RWMutex
+	registry map[string]*Feature
+}{registry: make(map[string]*Feature)}
+
+func init() {
+	RegisterFeature(FEATURE_STATE_HASH_CHECK, "Calculate state merkle root of blocks",
+		GetStateHashCheckHeight(NETWORK_ID_SOLO_NET))
+	RegisterFeature(FEATURE_OPCODE_HASKEY, "Enable neovm HASKEY opcode and disallow reader EOF",
+		GetOpcodeUpdateCheckHeight(NETWORK_ID_SOLO_NET))
+	RegisterFeature(FEATURE_GAS_ROUND_TUNE, "Round up the gas fee of transaction to gas round",
+		GetGasRoundTuneHeight(NETWORK_ID_SOLO_NET))
+}
+
+//RegisterFeature add a feature with default activation height to the registry
+func RegisterFeature(name, description string, activationHeight uint32) {
+	features.Lock()
+	defer features.Unlock()
+	features.registry[name] = &Feature{
+		Name:             name,
+		Description:      description,
+		ActivationHeight: activationHeight,
+	}
+}
+
+//SetFeature override the activation height of registered feature
+func SetFeature(name string, activationHeight uint32, disabled bool) error {
+	features.Lock()
+	defer features.Unlock()
+	feature, ok := features.registry[name]
+	if !ok {
+		return fmt.Errorf("unknown feature %s", name)
+	}
+	feature.ActivationHeight = activationHeight
+	feature.Disabled = disabled
+	return nil
+}
+
+//GetFeature return a copy of registered feature, nil if not registered
+func GetFeature(name string) *Feature {
+	features.RLock()
+	defer features.RUnlock()
+	feature, ok := features.registry[name]
+	if !ok {
+		return nil
+	}
+	f := *feature
+	return &f
+}
+
+//GetFeatures return the copy of all registered features sorted by name
+func GetFeatures() []*Feature {
+	features.RLock()
+	defer features.RUnlock()
+	list := make([]*Feature, 0, len(features.registry))
+	for _, feature := range features.registry {
+		f := *feature
+		list = append(list, &f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+//IsFeatureActive return whether the feature is active at the block height, unknown feature is never active
+func IsFeatureActive(name string, height uint32) bool {
+	features.RLock()
+	defer features.RUnlock()
+	feature, ok := features.registry[name]
+	if !ok {
+		return false
+	}
+	return feature.IsActive(height)
+}
+
+//GetFeatureActivationHeight return the activation height of feature, math.MaxUint32 if disabled or not registered
+func GetFeatureActivationHeight(name string) uint32 {
+	features.RLock()
+	defer features.RUnlock()
+	feature, ok := features.registry[name]
+	if !ok || feature.Disabled {
+		return math.MaxUint32
+	}
+	return feature.ActivationHeight
+}
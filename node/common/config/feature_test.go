@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeature(t *testing.T) {
+	RegisterFeature("test-feature", "feature for test", 10)
+	assert.False(t, IsFeatureActive("test-feature", 10))
+	assert.True(t, IsFeatureActive("test-feature", 11))
+	assert.False(t, IsFeatureActive("unknown-feature", 11))
+	assert.Equal(t, uint32(math.MaxUint32), GetFeatureActivationHeight("unknown-feature"))
+	assert.NotNil(t, SetFeature("unknown-feature", 0, false))
+
+	spec := &ChainSpec{NetworkId: 1000, Features: []*ChainSpecFeature{{Name: "test-feature", ActivationHeight: 20}}}
+	assert.Nil(t, spec.Validate())
+	spec.Apply(NewOntologyConfig())
+	assert.False(t, IsFeatureActive("test-feature", 11))
+	assert.True(t, IsFeatureActive("test-feature", 21))
+	assert.Equal(t, uint32(20), GetFeatureActivationHeight("test-feature"))
+
+	spec.Features[0].Disabled = true
+	spec.Apply(NewOntologyConfig())
+	assert.False(t, IsFeatureActive("test-feature", 21))
+	assert.Equal(t, uint32(math.MaxUint32), GetFeatureActivationHeight("test-feature"))
+	assert.True(t, GetFeature("test-feature").Disabled)
+
+	names := make([]string, 0)
+	for _, feature := range GetFeatures() {
+		names = append(names, feature.Name)
+	}
+	assert.Contains(t, names, FEATURE_OPCODE_HASKEY)
+	assert.Contains(t, names, "test-feature")
+
+	spec.Features = append(spec.Features, &ChainSpecFeature{Name: "test-feature"})
+	assert.NotNil(t, spec.Validate())
+	spec.Features = []*ChainSpecFeature{{Name: "unknown-feature"}}
+	assert.NotNil(t, spec.Validate())
+}
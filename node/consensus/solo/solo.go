@@ -175,9 +175,34 @@ func (self *SoloService) genBlock() error {
 	if err != nil {
 		return fmt.Errorf("genBlock DefLedgerPid.RequestFuture Height:%d error:%s", block.Header.Height, err)
 	}
+	self.signCheckpoint(block.Header.Height)
 	return nil
 }
 
+//signCheckpoint sign and save the checkpoint if height is a checkpoint height, the block is already saved so the
+//error is only logged
+func (self *SoloService) signCheckpoint(height uint32) {
+	checkpoint, err := ledger.DefLedger.NewCheckpoint(height)
+	if err != nil {
+		log.Errorf("signCheckpoint NewCheckpoint Height:%d error:%s", height, err)
+		return
+	}
+	if checkpoint == nil {
+		return
+	}
+	hash := checkpoint.Hash()
+	sig, err := signature.Sign(self.Account, hash[:])
+	if err != nil {
+		log.Errorf("signCheckpoint Sign Height:%d error:%s", height, err)
+		return
+	}
+	checkpoint.SigData = [][]byte{sig}
+	err = ledger.DefLedger.SaveCheckpoint(checkpoint)
+	if err != nil {
+		log.Errorf("signCheckpoint SaveCheckpoint Height:%d error:%s", height, err)
+	}
+}
+
 func (self *SoloService) makeBlock() (*types.Block, error) {
 	log.Debug()
 	owner := self.Account.PublicKey
@@ -20,6 +20,8 @@ package ledger
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/core/payload"
 	"github.com/ontio/layer2/node/core/states"
@@ -47,6 +49,18 @@ func NewLedger(dataDir string, stateHashHeight uint32) (*Ledger, error) {
 	}, nil
 }
 
+//NewReadOnlyLedger return the ledger reading the data dir written by other node process, see
+//ledgerstore.NewReadOnlyLedgerStore
+func NewReadOnlyLedger(dataDir, viewRoot string, stateHashHeight uint32, refreshInterval time.Duration) (*Ledger, error) {
+	ldgStore, err := ledgerstore.NewReadOnlyLedgerStore(dataDir, viewRoot, stateHashHeight, refreshInterval)
+	if err != nil {
+		return nil, fmt.Errorf("NewReadOnlyLedgerStore error %s", err)
+	}
+	return &Ledger{
+		ldgStore: ldgStore,
+	}, nil
+}
+
 func (self *Ledger) GetStore() store.LedgerStore {
 	return self.ldgStore
 }
@@ -79,6 +93,10 @@ func (self *Ledger) GetBlockByHeight(height uint32) (*types.Block, error) {
 	return self.ldgStore.GetBlockByHeight(height)
 }
 
+func (self *Ledger) GetBlocksByHeightRange(start, end uint32) ([]*types.Block, error) {
+	return self.ldgStore.GetBlocksByHeightRange(start, end)
+}
+
 func (self *Ledger) GetBlockByHash(blockHash common.Uint256) (*types.Block, error) {
 	return self.ldgStore.GetBlockByHash(blockHash)
 }
@@ -154,6 +172,90 @@ func (self *Ledger) GetStorageItem(codeHash common.Address, key []byte) ([]byte,
 	return storageItem.Value, nil
 }
 
+func (self *Ledger) GetStorageItemAt(codeHash common.Address, key []byte, height uint32) ([]byte, error) {
+	storageKey := &states.StorageKey{
+		ContractAddress: codeHash,
+		Key:             key,
+	}
+	storageItem, err := self.ldgStore.GetStorageItemAt(storageKey, height)
+	if err != nil {
+		return nil, err
+	}
+	return storageItem.Value, nil
+}
+
+func (self *Ledger) IterateStorage(contract common.Address, prefix []byte, height uint32) ([]*store.StorageEntry, error) {
+	return self.ldgStore.IterateStorage(contract, prefix, height)
+}
+
+func (self *Ledger) GetBalanceAt(addr common.Address, height uint32) (uint64, uint64, error) {
+	return self.ldgStore.GetBalanceAt(addr, height)
+}
+
+func (self *Ledger) GetStateDiff(height uint32) ([]*store.StateChange, error) {
+	return self.ldgStore.GetStateDiff(height)
+}
+
+func (self *Ledger) GetReceipt(txHash common.Uint256) (*types.Receipt, error) {
+	return self.ldgStore.GetReceipt(txHash)
+}
+
+func (self *Ledger) GetGasStats(fromHeight, toHeight uint32) (*store.GasStats, error) {
+	return self.ldgStore.GetGasStats(fromHeight, toHeight)
+}
+
+func (self *Ledger) GetRecentGasStats(blocks uint32) (*store.GasStats, error) {
+	return self.ldgStore.GetRecentGasStats(blocks)
+}
+
+func (self *Ledger) SubscribeEvents(fromHeight uint32, contracts []common.Address) (store.EventSubscription, error) {
+	return self.ldgStore.SubscribeEvents(fromHeight, contracts)
+}
+
+func (self *Ledger) SetCheckpointSnapshotDir(dir string) error {
+	return self.ldgStore.SetCheckpointSnapshotDir(dir)
+}
+
+func (self *Ledger) NewCheckpoint(height uint32) (*types.Checkpoint, error) {
+	return self.ldgStore.NewCheckpoint(height)
+}
+
+func (self *Ledger) SaveCheckpoint(checkpoint *types.Checkpoint) error {
+	return self.ldgStore.SaveCheckpoint(checkpoint)
+}
+
+func (self *Ledger) GetCheckpoint(height uint32) (*types.Checkpoint, error) {
+	return self.ldgStore.GetCheckpoint(height)
+}
+
+func (self *Ledger) EnableStateHistory(enable bool) error {
+	return self.ldgStore.EnableStateHistory(enable)
+}
+
+func (self *Ledger) EnableFullStateRoot(enable bool) error {
+	return self.ldgStore.EnableFullStateRoot(enable)
+}
+
+func (self *Ledger) GetStateCacheStats() store.StateCacheStats {
+	return self.ldgStore.GetStateCacheStats()
+}
+
+func (self *Ledger) GetRecoverStatus() store.RecoverStatus {
+	return self.ldgStore.GetRecoverStatus()
+}
+
+func (self *Ledger) StartInvariantChecker(interval time.Duration) {
+	self.ldgStore.StartInvariantChecker(interval)
+}
+
+func (self *Ledger) GetInvariantStatus() store.InvariantStatus {
+	return self.ldgStore.GetInvariantStatus()
+}
+
+func (self *Ledger) SetLayer2Retention(retention, checkpointInterval uint32) error {
+	return self.ldgStore.SetLayer2Retention(retention, checkpointInterval)
+}
+
 func (self *Ledger) GetContractState(contractHash common.Address) (*payload.DeployCode, error) {
 	return self.ldgStore.GetContractState(contractHash)
 }
@@ -178,14 +280,86 @@ func (self *Ledger) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify
 	return self.ldgStore.GetEventNotifyByBlock(height)
 }
 
+func (self *Ledger) GetEventNotifyByBlockFilter(height uint32, contract common.Address, topic string) ([]*event.ExecuteNotify, error) {
+	return self.ldgStore.GetEventNotifyByBlockFilter(height, contract, topic)
+}
+
+func (self *Ledger) GetEventNotifyByContract(contract common.Address, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error) {
+	return self.ldgStore.GetEventNotifyByContract(contract, fromHeight, toHeight)
+}
+
+func (self *Ledger) GetEventNotifyByAddress(addr common.Address, fromHeight, toHeight uint32, limit uint32) ([]*event.ExecuteNotify, error) {
+	return self.ldgStore.GetEventNotifyByAddress(addr, fromHeight, toHeight, limit)
+}
+
+func (self *Ledger) GetTransactionsByAddress(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.AddressTxPage, error) {
+	return self.ldgStore.GetTransactionsByAddress(addr, fromHeight, toHeight, cursor, limit)
+}
+
+func (self *Ledger) GetEventNotifyByBlockPage(height uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return self.ldgStore.GetEventNotifyByBlockPage(height, cursor, limit)
+}
+
+func (self *Ledger) GetEventNotifyByContractPage(contract common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return self.ldgStore.GetEventNotifyByContractPage(contract, fromHeight, toHeight, cursor, limit)
+}
+
+func (self *Ledger) GetEventNotifyByAddressPage(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return self.ldgStore.GetEventNotifyByAddressPage(addr, fromHeight, toHeight, cursor, limit)
+}
+
 func (self *Ledger) GetLayer2State(height uint32) (*types.Layer2State, error) {
 	return self.ldgStore.GetLayer2State(height)
 }
 
+func (self *Ledger) GetStorageStateProof(height uint32, contract common.Address, key []byte) (common.Uint256, []byte, error) {
+	return self.ldgStore.GetStorageStateProof(height, contract, key)
+}
+
+func (self *Ledger) GetFullStateProof(height uint32, contract common.Address, key []byte) (common.Uint256, []byte, error) {
+	return self.ldgStore.GetFullStateProof(height, contract, key)
+}
+
+func (self *Ledger) GetLayer2States(fromHeight, toHeight uint32) ([]*types.Layer2State, error) {
+	return self.ldgStore.GetLayer2States(fromHeight, toHeight)
+}
+
 func (self *Ledger) GetLayer2StateProof(height uint32, key []byte) ([]byte, error) {
 	return self.ldgStore.GetLayer2StateProof(height, key)
 }
 
+func (self *Ledger) GetLayer2AccountStates(height uint32) (*store.Layer2AccountStates, error) {
+	return self.ldgStore.GetLayer2AccountStates(height)
+}
+
+func (self *Ledger) GetLayer2AccountSnapshot(height uint32) (*store.Layer2AccountSnapshot, error) {
+	return self.ldgStore.GetLayer2AccountSnapshot(height)
+}
+
 func (self *Ledger) Close() error {
 	return self.ldgStore.Close()
 }
+
+func (self *Ledger) CompactStores() error {
+	return self.ldgStore.CompactStores()
+}
+
+func (self *Ledger) Backup(dir string) error {
+	return self.ldgStore.Backup(dir)
+}
+
+func (self *Ledger) ExportBlocks(path string, from, to uint32) error {
+	return self.ldgStore.ExportBlocks(path, from, to)
+}
+
+func (self *Ledger) ImportBlocks(path string) error {
+	return self.ldgStore.ImportBlocks(path)
+}
+
+func (self *Ledger) IsReadOnly() bool {
+	return self.ldgStore.IsReadOnly()
+}
+
+func (self *Ledger) Refresh() error {
+	return self.ldgStore.Refresh()
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/ontio/layer2/node/core/store/ledgerstore"
+	"github.com/ontio/layer2/node/core/types"
+)
+
+//DefLightLedger is the ledger of header-only light node, nil if the node is not in light mode
+var DefLightLedger *LightLedger
+
+type LightLedger struct {
+	lightStore *ledgerstore.LightStore
+}
+
+func NewLightLedger(dataDir string) (*LightLedger, error) {
+	lightStore, err := ledgerstore.NewLightStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("NewLightStore error %s", err)
+	}
+	return &LightLedger{
+		lightStore: lightStore,
+	}, nil
+}
+
+func (self *LightLedger) Init(genesisBlock *types.Block) error {
+	err := self.lightStore.InitWithGenesisBlock(genesisBlock)
+	if err != nil {
+		return fmt.Errorf("InitWithGenesisBlock error %s", err)
+	}
+	return nil
+}
+
+func (self *LightLedger) AddHeader(header *types.Header, layer2State *types.Layer2State) error {
+	return self.lightStore.AddHeader(header, layer2State)
+}
+
+func (self *LightLedger) GetCurrentHeight() uint32 {
+	return self.lightStore.GetCurrentHeight()
+}
+
+func (self *LightLedger) GetHeaderByHeight(height uint32) (*types.Header, error) {
+	return self.lightStore.GetHeaderByHeight(height)
+}
+
+func (self *LightLedger) GetLayer2State(height uint32) (*types.Layer2State, error) {
+	return self.lightStore.GetLayer2State(height)
+}
+
+func (self *LightLedger) VerifyLayer2StateProof(height uint32, key, value, proof []byte) (bool, error) {
+	return self.lightStore.VerifyLayer2StateProof(height, key, value, proof)
+}
+
+func (self *LightLedger) Close() error {
+	return self.lightStore.Close()
+}
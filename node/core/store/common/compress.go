@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+//COMPRESSED_VALUE_MAGIC mark the value compressed by snappy. Transaction value starts with the little endian block height,
+//the magic equals height 0xff504e53 which can not be reached, and event notify value is json starts with '{'
+var COMPRESSED_VALUE_MAGIC = []byte{'S', 'N', 'P', 0xff}
+
+//CompressValue return the snappy compressed value with magic prefix
+func CompressValue(value []byte) []byte {
+	buf := make([]byte, len(COMPRESSED_VALUE_MAGIC)+snappy.MaxEncodedLen(len(value)))
+	copy(buf, COMPRESSED_VALUE_MAGIC)
+	encoded := snappy.Encode(buf[len(COMPRESSED_VALUE_MAGIC):], value)
+	return buf[:len(COMPRESSED_VALUE_MAGIC)+len(encoded)]
+}
+
+//DecompressValue return the original value of compressed value, value without magic prefix is returned as it is
+func DecompressValue(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, COMPRESSED_VALUE_MAGIC) {
+		return value, nil
+	}
+	data, err := snappy.Decode(nil, value[len(COMPRESSED_VALUE_MAGIC):])
+	if err != nil {
+		return nil, fmt.Errorf("snappy.Decode error %s", err)
+	}
+	return data, nil
+}
@@ -44,6 +44,27 @@ const (
 	SYS_BLOCK_MERKLE_TREE    DataEntryPrefix = 0x13 // Block merkle tree root key prefix
 	SYS_STATE_MERKLE_TREE    DataEntryPrefix = 0x20 // state merkle tree root key prefix
 	SYS_CROSS_CHAIN_MSG      DataEntryPrefix = 0x22 // state merkle tree root key prefix
+	SYS_LAYER2_ACCOUNT_STATES DataEntryPrefix = 0x23 //Block height => updated accounts and values of layer2 states
+	SYS_RECOVER_PROGRESS     DataEntryPrefix = 0x24 //Progress of the unfinished recovery at startup
+	SYS_ACCOUNT_TREE_NODE    DataEntryPrefix = 0x25 //Node hash => node of account state and storage state sparse merkle trees
+	SYS_ACCOUNT_TREE_ROOT    DataEntryPrefix = 0x26 //Block height => root of account state sparse merkle tree
+	SYS_LAYER2_PRUNED_HEIGHT DataEntryPrefix = 0x27 //Height below which the layer2 states out of retention are pruned
+	SYS_STORAGE_TREE_ROOT    DataEntryPrefix = 0x28 //Block height => root of storage state sparse merkle tree
+	SYS_STATE_HISTORY        DataEntryPrefix = 0x29 //Storage key + block height => value of storage key before block
+	SYS_STATE_HISTORY_START  DataEntryPrefix = 0x2a //First block height whose state history is saved
+	SYS_STATE_DIFF           DataEntryPrefix = 0x2b //Block height => state keys updated by block and their values before and after
+	SYS_CHECKPOINT           DataEntryPrefix = 0x2c //Block height => checkpoint signed by bookkeepers
+	SYS_LATEST_CHECKPOINT    DataEntryPrefix = 0x2d //Height of the latest checkpoint
+	SYS_FULL_STATE_NODE      DataEntryPrefix = 0x2e //Node hash => node of full state merkle patricia trie
+	SYS_FULL_STATE_ROOT      DataEntryPrefix = 0x2f //Block height => root of full state merkle patricia trie
+	SYS_GAS_STATS            DataEntryPrefix = 0x30 //Block height => gas and fee statistics of block
+	SYS_ENCRYPTION_STATE     DataEntryPrefix = 0x32 //Active key id once all values of store are encrypted, plaintext values are rejected after it
 
-	EVENT_NOTIFY DataEntryPrefix = 0x14 //Event notify key prefix
+	EVENT_NOTIFY   DataEntryPrefix = 0x14 //Event notify key prefix
+	EVENT_BLOOM    DataEntryPrefix = 0x15 //Block height => event bloom filter key prefix
+	EVENT_CONTRACT DataEntryPrefix = 0x16 //Contract address + block height + tx hash => nil, index of event notify by contract
+	EVENT_ADDRESS  DataEntryPrefix = 0x17 //Account address + block height + tx hash => nil, index of event notify by account
+	EVENT_RECEIPT  DataEntryPrefix = 0x31 //Transaction hash => receipt of transaction
+
+	IX_ADDRESS_TX DataEntryPrefix = 0x18 //Account address + block height + tx index in block => tx hash, index of transaction by payer and signer
 )
@@ -26,6 +26,9 @@ import (
 
 var ErrNotFound = errors.New("not found")
 
+//ErrInvalidCursor is returned by paged query with a malformed continuation token
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 //Store iterator for iterate store
 type StoreIterator interface {
 	Next() bool //Next item. If item available return true, otherwise return false
@@ -39,18 +42,27 @@ type StoreIterator interface {
 	Error() error  // Error returns any accumulated error.
 }
 
+//StoreSnapshot is a read-only point-in-time view of store
+type StoreSnapshot interface {
+	NewIterator(prefix []byte) StoreIterator //Return the iterator of snapshot
+	Release()                                //Release snapshot
+}
+
 //PersistStore of ledger
 type PersistStore interface {
-	Put(key []byte, value []byte) error      //Put the key-value pair to store
-	Get(key []byte) ([]byte, error)          //Get the value if key in store
-	Has(key []byte) (bool, error)            //Whether the key is exist in store
-	Delete(key []byte) error                 //Delete the key in store
-	NewBatch()                               //Start commit batch
-	BatchPut(key []byte, value []byte)       //Put a key-value pair to batch
-	BatchDelete(key []byte)                  //Delete the key in batch
-	BatchCommit() error                      //Commit batch to store
-	Close() error                            //Close store
-	NewIterator(prefix []byte) StoreIterator //Return the iterator of store
+	Put(key []byte, value []byte) error                 //Put the key-value pair to store
+	Get(key []byte) ([]byte, error)                     //Get the value if key in store
+	Has(key []byte) (bool, error)                       //Whether the key is exist in store
+	Delete(key []byte) error                            //Delete the key in store
+	NewBatch()                                          //Start commit batch
+	BatchPut(key []byte, value []byte)                  //Put a key-value pair to batch
+	BatchDelete(key []byte)                             //Delete the key in batch
+	BatchCommit() error                                 //Commit batch to store
+	Close() error                                       //Close store
+	Compact() error                                     //Compact the whole store to discard deleted and overwritten data
+	NewIterator(prefix []byte) StoreIterator            //Return the iterator of store
+	NewRangeIterator(start, limit []byte) StoreIterator //Return the iterator of store with the key in [start, limit)
+	NewSnapshot() (StoreSnapshot, error)                //Return the snapshot of current store
 }
 
 //EventStore save event notify
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	scom "github.com/ontio/layer2/node/core/store/common"
+)
+
+//AccountRootRule select the storage writes which feed the account state root. The storage key of account is the key
+//prefix followed by the account address
+type AccountRootRule struct {
+	Contract         *common.Address //Nil to match all contracts
+	KeyPrefix        []byte
+	ActivationHeight uint32 //Rule applies to the blocks from the height
+}
+
+//DefaultAccountRootRules select the storage of 20 bytes account address key of all contracts
+var DefaultAccountRootRules = []*AccountRootRule{{}}
+
+//NewAccountRootRules return the account root rules of chain spec, or the default rules if not set
+func NewAccountRootRules(spec *config.ChainSpec) ([]*AccountRootRule, error) {
+	if spec == nil || len(spec.AccountRules) == 0 {
+		return DefaultAccountRootRules, nil
+	}
+	rules := make([]*AccountRootRule, 0, len(spec.AccountRules))
+	for _, r := range spec.AccountRules {
+		rule := &AccountRootRule{ActivationHeight: r.ActivationHeight}
+		if r.Contract != "" {
+			contract, err := common.AddressFromHexString(r.Contract)
+			if err != nil {
+				return nil, fmt.Errorf("account rule contract %s error %s", r.Contract, err)
+			}
+			rule.Contract = &contract
+		}
+		prefix, err := hex.DecodeString(r.KeyPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("account rule key prefix %s error %s", r.KeyPrefix, err)
+		}
+		rule.KeyPrefix = prefix
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+//accountTreeActivation return the first block height whose account state root is the sparse merkle tree root of chain
+//spec, 0 if not set
+func accountTreeActivation(spec *config.ChainSpec) uint32 {
+	if spec == nil {
+		return 0
+	}
+	return spec.AccountTreeHeight
+}
+
+//Match return whether the storage key written at height feeds the account state root
+func (this *AccountRootRule) Match(height uint32, key []byte) bool {
+	// [ST_STORAGE:ContractAddr:KeyPrefix:UserAddr] = 1 + 20 + len(KeyPrefix) + 20
+	if height < this.ActivationHeight || len(key) != 1+2*common.ADDR_LEN+len(this.KeyPrefix) ||
+		key[0] != byte(scom.ST_STORAGE) {
+		return false
+	}
+	if this.Contract != nil && !bytes.Equal(this.Contract[:], key[1:1+common.ADDR_LEN]) {
+		return false
+	}
+	return bytes.HasPrefix(key[1+common.ADDR_LEN:], this.KeyPrefix)
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountRootRules(t *testing.T) {
+	contract1, contract2 := common.Address{1}, common.Address{2}
+	user := common.Address{3}
+	key := func(contract common.Address, prefix []byte) []byte {
+		key := append([]byte{byte(scom.ST_STORAGE)}, contract[:]...)
+		return append(append(key, prefix...), user[:]...)
+	}
+
+	rules, err := NewAccountRootRules(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultAccountRootRules, rules)
+	assert.True(t, rules[0].Match(0, key(contract1, nil)))
+	assert.False(t, rules[0].Match(0, key(contract1, []byte{1})))
+	assert.False(t, rules[0].Match(0, append([]byte{byte(scom.ST_CONTRACT)}, key(contract1, nil)[1:]...)))
+
+	spec := &config.ChainSpec{AccountRules: []*config.ChainSpecAccountRule{
+		{},
+		{Contract: contract2.ToHexString(), KeyPrefix: "0102", ActivationHeight: 2},
+	}}
+	rules, err = NewAccountRootRules(spec)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rules))
+	assert.False(t, rules[1].Match(1, key(contract2, []byte{1, 2})))
+	assert.True(t, rules[1].Match(2, key(contract2, []byte{1, 2})))
+	assert.False(t, rules[1].Match(2, key(contract1, []byte{1, 2})))
+	assert.False(t, rules[1].Match(2, key(contract2, []byte{1, 3})))
+	spec.AccountRules[1].KeyPrefix = "zz"
+	_, err = NewAccountRootRules(spec)
+	assert.NotNil(t, err)
+
+	spec.AccountRules[1].KeyPrefix = "0102"
+	rules, err = NewAccountRootRules(spec)
+	assert.Nil(t, err)
+	db := NewMemStateStore(0)
+	ledgerStore := &LedgerStoreImp{stateStore: db, accountRules: rules}
+	db.NewBatch()
+	db.SaveAccountTreeRoot(1, common.UINT256_EMPTY)
+	db.SaveStorageTreeRoot(1, common.UINT256_EMPTY)
+	assert.Nil(t, db.CommitTo())
+	overlay := db.NewOverlayDB()
+	cache := storage.NewCacheDB(overlay)
+	cache.Put(key(contract2, []byte{1, 2})[1:], []byte("balance"))
+	cache.Commit()
+	result := store.ExecuteResult{WriteSet: overlay.GetWriteSet()}
+	assert.Nil(t, ledgerStore.updateAccountTree(2, overlay, &result))
+	assert.Equal(t, []*store.AccountState{{Contract: contract2, Address: user, Value: []byte("balance")}},
+		result.UpdatedAccounts)
+}
@@ -0,0 +1,430 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/overlaydb"
+	"github.com/ontio/layer2/node/merkle"
+	"github.com/ontio/layer2/node/smartcontract/storage"
+)
+
+type KeyState struct {
+	Key   []byte
+	Value []byte
+}
+
+type KeyStateSlice []*KeyState
+
+func (this KeyStateSlice) Len() int {
+	return len(this)
+}
+func (this KeyStateSlice) Swap(i, j int) {
+	key := this[i]
+	this[i] = this[j]
+	this[j] = key
+}
+func (this KeyStateSlice) Less(i, j int) bool {
+	return bytes.Compare(this[i].Key, this[j].Key) > 0
+}
+
+//isLegacyAccountStore return whether the storage feeds the legacy account state root of the blocks below the account
+//tree height
+func isLegacyAccountStore(key, val []byte) bool {
+	// [ST_STORAGE:ContractAddr:UserAddr] = 1 + 20 + 20
+	if len(key) != 41 {
+		return false
+	}
+	return true
+}
+
+//calculateChangeStateRoot return the legacy root, leaves and accounts of updated account states of the blocks below
+//the account tree height. The leaf of account is the sha256 of its updated values concatenated in key order, and the
+//leaves are ordered by account address descending
+func calculateChangeStateRoot(cache *storage.CacheDB) (common.Uint256, []common.Uint256, []*store.AccountState) {
+	memdb := cache.GetMemDb()
+	states := make(map[string]*KeyState, 0)
+	memdb.ForEach(func(key, val []byte) {
+		if isLegacyAccountStore(key, val) == false {
+			return
+		}
+		accountAddr, _ := common.AddressParseFromBytes(key[common.ADDR_LEN+1:])
+		item, ok := states[hex.EncodeToString(accountAddr[:])]
+		if !ok {
+			//copy the value, appending to the slice of memdb overwrites the following entries
+			states[hex.EncodeToString(accountAddr[:])] = &KeyState{
+				Key:   accountAddr[:],
+				Value: append([]byte{}, val...),
+			}
+		} else {
+			item.Value = append(item.Value, val...)
+		}
+	})
+	stateSlice := make([]*KeyState, 0)
+	for _, value := range states {
+		stateSlice = append(stateSlice, value)
+	}
+	sort.Sort(KeyStateSlice(stateSlice))
+	hashs := make([]common.Uint256, 0)
+	accounts := make([]*store.AccountState, 0, len(stateSlice))
+	for _, item := range stateSlice {
+		state := sha256.New()
+		var result common.Uint256
+		state.Write(item.Value)
+		state.Sum(result[:0])
+		hashs = append(hashs, result)
+		accountAddr, _ := common.AddressParseFromBytes(item.Key)
+		accounts = append(accounts, &store.AccountState{Address: accountAddr, Value: item.Value})
+	}
+	return legacyAccountStateRoot(hashs), hashs, accounts
+}
+
+//legacyAccountStateRoot return the legacy account state root of the updated account state leaves
+func legacyAccountStateRoot(leaves []common.Uint256) common.Uint256 {
+	if len(leaves) == 0 {
+		return common.UINT256_EMPTY
+	}
+	return merkle.TreeHasher{}.HashFullTreeWithLeafHash(leaves)
+}
+
+//accountStateRoot return the account state root of block, which is the legacy root of the updated account states below
+//the account tree height and the root of account state sparse merkle tree from it
+func (this *LedgerStoreImp) accountStateRoot(height uint32) (common.Uint256, error) {
+	if height >= this.accountTreeHeight {
+		return this.stateStore.GetAccountTreeRoot(height)
+	}
+	leaves, err := this.stateStore.GetLayer2States(height)
+	if err != nil && err != scom.ErrNotFound {
+		return common.UINT256_EMPTY, err
+	}
+	return legacyAccountStateRoot(leaves), nil
+}
+
+//isAccountUpdateStore return whether the storage written at height matches any account root rule
+func (this *LedgerStoreImp) isAccountUpdateStore(height uint32, key, val []byte) bool {
+	rules := this.accountRules
+	if rules == nil {
+		rules = DefaultAccountRootRules
+	}
+	for _, rule := range rules {
+		if rule.Match(height, key) {
+			return true
+		}
+	}
+	return false
+}
+
+//updateAccountTree apply the storage updated in block to the account state and storage state sparse merkle trees of
+//previous block, and set the roots, leaves, accounts and new tree nodes of result. The account state tree is not
+//updated below the account tree height, and is built from all the storage of overlay at the height, so the block at the
+//height updates every account of the tree
+func (this *LedgerStoreImp) updateAccountTree(height uint32, overlay *overlaydb.OverlayDB, result *store.ExecuteResult) error {
+	updateAccount := height >= this.accountTreeHeight
+	buildAccount := height != 0 && height == this.accountTreeHeight
+	root, storageRoot := common.UINT256_EMPTY, common.UINT256_EMPTY
+	if height != 0 {
+		var err error
+		if updateAccount && !buildAccount {
+			root, err = this.stateStore.GetAccountTreeRoot(height - 1)
+			if err != nil {
+				return fmt.Errorf("GetAccountTreeRoot height:%d error %s", height-1, err)
+			}
+		}
+		storageRoot, err = this.stateStore.GetStorageTreeRoot(height - 1)
+		if err != nil {
+			return fmt.Errorf("GetStorageTreeRoot height:%d error %s", height-1, err)
+		}
+	}
+	tree := merkle.NewSparseMerkleTree(this.stateStore, root)
+	storageTree := merkle.NewSparseMerkleTree(this.stateStore, storageRoot)
+	updateAccountState := func(key, val []byte) error {
+		err := tree.Update(key[1:], val)
+		if err != nil {
+			return err
+		}
+		account := &store.AccountState{Value: append([]byte{}, val...)}
+		copy(account.Contract[:], key[1:1+common.ADDR_LEN])
+		copy(account.Address[:], key[len(key)-common.ADDR_LEN:])
+		leaf := common.UINT256_EMPTY
+		if len(val) != 0 {
+			leaf = merkle.SMTLeafHash(key[1:], val)
+		}
+		result.UpdatedAccountState = append(result.UpdatedAccountState, leaf)
+		result.UpdatedAccounts = append(result.UpdatedAccounts, account)
+		return nil
+	}
+	var err error
+	result.WriteSet.ForEach(func(key, val []byte) {
+		if err != nil || len(key) == 0 || key[0] != byte(scom.ST_STORAGE) {
+			return
+		}
+		err = storageTree.Update(key[1:], val)
+		if err != nil || !updateAccount || buildAccount || !this.isAccountUpdateStore(height, key, val) {
+			return
+		}
+		err = updateAccountState(key, val)
+	})
+	if err != nil {
+		return fmt.Errorf("update account tree error %s", err)
+	}
+	if buildAccount {
+		iter := overlay.NewIterator([]byte{byte(scom.ST_STORAGE)})
+		for iter.Next() {
+			if !this.isAccountUpdateStore(height, iter.Key(), iter.Value()) {
+				continue
+			}
+			err = updateAccountState(iter.Key(), iter.Value())
+			if err != nil {
+				iter.Release()
+				return fmt.Errorf("build account tree error %s", err)
+			}
+		}
+		iter.Release()
+		if err = iter.Error(); err != nil {
+			return fmt.Errorf("iterate storage error %s", err)
+		}
+		log.Infof("account state tree of %d accounts activated at height %d", len(result.UpdatedAccounts), height)
+	}
+	result.StateTreeNodes = storageTree.NewNodes()
+	result.StorageStateRoot = storageTree.Root()
+	if updateAccount {
+		result.UpdatedAccountStateRoot = tree.Root()
+		for hash, node := range tree.NewNodes() {
+			result.StateTreeNodes[hash] = node
+		}
+	}
+	return nil
+}
+
+//initAccountTree build the account state and storage state sparse merkle trees from the storage for the store saved
+//before the trees are introduced, the trees of later blocks are updated from them
+func (this *LedgerStoreImp) initAccountTree() error {
+	_, height, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	_, err = this.stateStore.GetAccountTreeRoot(height)
+	if err != nil && err != scom.ErrNotFound {
+		return fmt.Errorf("GetAccountTreeRoot error %s", err)
+	}
+	//the account state tree below the account tree height is built by the block at the height
+	buildAccount := err == scom.ErrNotFound && height >= this.accountTreeHeight
+	_, err = this.stateStore.GetStorageTreeRoot(height)
+	if err != nil && err != scom.ErrNotFound {
+		return fmt.Errorf("GetStorageTreeRoot error %s", err)
+	}
+	buildStorage := err == scom.ErrNotFound
+	if !buildAccount && !buildStorage {
+		return nil
+	}
+	log.Infof("build state trees at height %d, account tree %v, storage tree %v", height, buildAccount, buildStorage)
+	tree := merkle.NewSparseMerkleTree(this.stateStore, common.UINT256_EMPTY)
+	storageTree := merkle.NewSparseMerkleTree(this.stateStore, common.UINT256_EMPTY)
+	iter := this.stateStore.store.NewIterator([]byte{byte(scom.ST_STORAGE)})
+	count, accounts := 0, 0
+	for iter.Next() {
+		if buildStorage {
+			err = storageTree.Update(iter.Key()[1:], iter.Value())
+			if err != nil {
+				iter.Release()
+				return fmt.Errorf("update storage tree error %s", err)
+			}
+			count++
+		}
+		if !buildAccount || !this.isAccountUpdateStore(height, iter.Key(), iter.Value()) {
+			continue
+		}
+		err = tree.Update(iter.Key()[1:], iter.Value())
+		if err != nil {
+			iter.Release()
+			return fmt.Errorf("update account tree error %s", err)
+		}
+		accounts++
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("iterate storage error %s", err)
+	}
+	this.stateStore.NewBatch()
+	if buildAccount {
+		this.stateStore.SaveStateTreeNodes(tree.NewNodes())
+		this.stateStore.SaveAccountTreeRoot(height, tree.Root())
+		root := tree.Root()
+		log.Infof("account state tree of %d accounts at height %d, root %s", accounts, height, root.ToHexString())
+	}
+	if buildStorage {
+		this.stateStore.SaveStateTreeNodes(storageTree.NewNodes())
+		this.stateStore.SaveStorageTreeRoot(height, storageTree.Root())
+		root := storageTree.Root()
+		log.Infof("storage state tree of %d keys at height %d, root %s", count, height, root.ToHexString())
+	}
+	err = this.stateStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("stateStore.CommitTo error %s", err)
+	}
+	return nil
+}
+
+//GetLayer2StateProof return the serialized sparse merkle proof of the storage key of contract and account in the account
+//state root of block, it proves the inclusion of the current value or the exclusion of the key. The key is contract
+//address, key prefix of account root rule and account address. Below the account tree height it return the legacy
+//merkle path of the leaf data key in the updated account states of block
+func (this *LedgerStoreImp) GetLayer2StateProof(height uint32, key []byte) ([]byte, error) {
+	if height < this.accountTreeHeight {
+		hashs, err := this.stateStore.GetLayer2States(height)
+		if err != nil {
+			return nil, fmt.Errorf("GetLayer2StateProof:%s", err)
+		}
+		return merkle.MerkleLeafPath(key, hashs)
+	}
+	if len(key) < 2*common.ADDR_LEN {
+		return nil, fmt.Errorf("GetLayer2StateProof: key should be contract address, key prefix and account address")
+	}
+	root, err := this.stateStore.GetAccountTreeRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetLayer2StateProof:%s", err)
+	}
+	proof, err := merkle.NewSparseMerkleTree(this.stateStore, root).Prove(key)
+	if err != nil {
+		return nil, fmt.Errorf("GetLayer2StateProof:%s", err)
+	}
+	sink := common.NewZeroCopySink(nil)
+	proof.Serialization(sink)
+	return sink.Bytes(), nil
+}
+
+//accountTreeKeys return the tree keys the account update at height may be written to, one for each matched account root
+//rule
+func (this *LedgerStoreImp) accountTreeKeys(height uint32, account *store.AccountState) [][]byte {
+	rules := this.accountRules
+	if rules == nil {
+		rules = DefaultAccountRootRules
+	}
+	keys := make([][]byte, 0, len(rules))
+	for _, rule := range rules {
+		if height < rule.ActivationHeight || (rule.Contract != nil && *rule.Contract != account.Contract) {
+			continue
+		}
+		key := make([]byte, 0, 2*common.ADDR_LEN+len(rule.KeyPrefix))
+		key = append(key, account.Contract[:]...)
+		key = append(key, rule.KeyPrefix...)
+		keys = append(keys, append(key, account.Address[:]...))
+	}
+	return keys
+}
+
+//GetLayer2AccountSnapshot return all the accounts in the account state tree of block, which is replayed from the accounts
+//updated in the blocks from the account tree height up to the height and checked against the account state root. It
+//fails if the accounts of any of the blocks are pruned or not stored
+func (this *LedgerStoreImp) GetLayer2AccountSnapshot(height uint32) (*store.Layer2AccountSnapshot, error) {
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d is not executed", height)
+	}
+	if height < this.accountTreeHeight {
+		return nil, fmt.Errorf("block height %d is below the account tree height %d", height, this.accountTreeHeight)
+	}
+	accounts := make(map[string]*store.AccountState)
+	for h := this.accountTreeHeight; h <= height; h++ {
+		states, err := this.GetLayer2AccountStates(h)
+		if err != nil {
+			return nil, err
+		}
+		if len(states.Leaves) != len(states.Accounts) {
+			return nil, fmt.Errorf("layer2 accounts of height %d are not stored", h)
+		}
+		for i, account := range states.Accounts {
+			keys := this.accountTreeKeys(h, account)
+			if states.Leaves[i] == common.UINT256_EMPTY {
+				for _, key := range keys {
+					delete(accounts, string(key))
+				}
+				continue
+			}
+			found := false
+			for _, key := range keys {
+				if merkle.SMTLeafHash(key, account.Value) == states.Leaves[i] {
+					accounts[string(key)] = account
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("layer2 account %s of contract %s at height %d matches no account rule",
+					account.Address.ToBase58(), account.Contract.ToHexString(), h)
+			}
+		}
+	}
+	root, err := this.stateStore.GetAccountTreeRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountTreeRoot height:%d error %s", height, err)
+	}
+	keys := make([]string, 0, len(accounts))
+	for key := range accounts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	snapshot := &store.Layer2AccountSnapshot{
+		Height:   height,
+		Root:     root,
+		Keys:     make([][]byte, 0, len(keys)),
+		Accounts: make([]*store.AccountState, 0, len(keys)),
+	}
+	tree := merkle.NewSparseMerkleTree(this.stateStore, common.UINT256_EMPTY)
+	for _, key := range keys {
+		account := accounts[key]
+		if err := tree.Update([]byte(key), account.Value); err != nil {
+			return nil, fmt.Errorf("update account tree error %s", err)
+		}
+		snapshot.Keys = append(snapshot.Keys, []byte(key))
+		snapshot.Accounts = append(snapshot.Accounts, account)
+	}
+	if snapshotRoot := tree.Root(); snapshotRoot != root {
+		return nil, fmt.Errorf("layer2 account snapshot root %s mismatch account state root %s of height %d",
+			snapshotRoot.ToHexString(), root.ToHexString(), height)
+	}
+	return snapshot, nil
+}
+
+//GetStorageStateProof return the storage state root of block and the serialized sparse merkle proof of the storage key
+//of contract in it, it proves the inclusion of the value hash at the height or the exclusion of the key
+func (this *LedgerStoreImp) GetStorageStateProof(height uint32, contract common.Address, key []byte) (common.Uint256,
+	[]byte, error) {
+	if height > this.GetCurrentBlockHeight() {
+		return common.UINT256_EMPTY, nil, fmt.Errorf("block height %d is not executed", height)
+	}
+	root, err := this.stateStore.GetStorageTreeRoot(height)
+	if err != nil {
+		return common.UINT256_EMPTY, nil, fmt.Errorf("GetStorageTreeRoot height:%d error %s", height, err)
+	}
+	proof, err := merkle.NewSparseMerkleTree(this.stateStore, root).Prove(append(contract[:], key...))
+	if err != nil {
+		return common.UINT256_EMPTY, nil, fmt.Errorf("GetStorageStateProof:%s", err)
+	}
+	sink := common.NewZeroCopySink(nil)
+	proof.Serialization(sink)
+	return root, sink.Bytes(), nil
+}
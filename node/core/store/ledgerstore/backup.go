@@ -0,0 +1,286 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+)
+
+//BACKUP_BATCH_SIZE is the count of key-value pairs written to backup store in one batch
+const BACKUP_BATCH_SIZE = 10000
+
+//backupStoreDirs is the database directories in the backup, same as in the data dir
+var backupStoreDirs = []string{DBDirBlock, DBDirState, DBDirEvent, DBDirLayer2}
+
+//Backup take a consistent snapshot of block, state, event and layer2 store and copy it to dir without stopping
+//the ledger. The snapshots are taken under the saving block lock, so the backup is at a block boundary.
+//dir should not exist.
+func (this *LedgerStoreImp) Backup(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("backup dir %s already exists", dir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	this.getSavingBlockLock()
+	if this.closing {
+		this.releaseSavingBlockLock()
+		return fmt.Errorf("ledger is closing")
+	}
+	//keep compaction and close waiting until the copy finished
+	this.compactLock.Lock()
+	defer this.compactLock.Unlock()
+	height := this.GetCurrentBlockHeight()
+	stores := []scom.PersistStore{this.blockStore.store, this.stateStore.store, this.eventStore.store, this.layer2Store.store}
+	snapshots := make([]scom.StoreSnapshot, 0, len(stores))
+	defer func() {
+		for _, snapshot := range snapshots {
+			snapshot.Release()
+		}
+	}()
+	for i, store := range stores {
+		snapshot, err := store.NewSnapshot()
+		if err != nil {
+			this.releaseSavingBlockLock()
+			return fmt.Errorf("%s store snapshot error %s", backupStoreDirs[i], err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	this.releaseSavingBlockLock()
+
+	start := time.Now()
+	err := this.copyBackup(dir, snapshots)
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	log.Infof("backup ledger at height %d to %s cost %s", height, dir, time.Since(start))
+	return nil
+}
+
+func (this *LedgerStoreImp) copyBackup(dir string, snapshots []scom.StoreSnapshot) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	//the backup of encrypted store is encrypted by the same keys
+	ciphers := []*leveldbstore.ValueCipher{this.blockStore.store.Cipher(), nil, this.eventStore.store.Cipher(), nil}
+	if db, ok := this.stateStore.store.(*leveldbstore.LevelDBStore); ok {
+		ciphers[1] = db.Cipher()
+	}
+	for i, snapshot := range snapshots {
+		err = copySnapshot(snapshot, filepath.Join(dir, backupStoreDirs[i]), ciphers[i])
+		if err != nil {
+			return fmt.Errorf("backup %s store error %s", backupStoreDirs[i], err)
+		}
+	}
+	//merkle hash file is append only, the hashes appended after snapshot are overwritten when the backup is opened
+	if this.stateStore.merklePath != "" {
+		err = copyFile(this.stateStore.merklePath, filepath.Join(dir, MerkleTreeStorePath))
+		if err != nil {
+			return fmt.Errorf("backup merkle tree error %s", err)
+		}
+	}
+	return nil
+}
+
+func copySnapshot(snapshot scom.StoreSnapshot, dir string, cipher *leveldbstore.ValueCipher) error {
+	store, err := leveldbstore.NewLevelDBStore(dir)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	store.SetCipher(cipher)
+
+	iter := snapshot.NewIterator(nil)
+	defer iter.Release()
+	store.NewBatch()
+	count := 0
+	for iter.Next() {
+		store.BatchPut(iter.Key(), iter.Value())
+		count++
+		if count%BACKUP_BATCH_SIZE == 0 {
+			err = store.BatchCommit()
+			if err != nil {
+				return err
+			}
+			store.NewBatch()
+		}
+	}
+	if err = iter.Error(); err != nil {
+		return err
+	}
+	return store.BatchCommit()
+}
+
+//RestoreBackup replace the stores in dataDir with the backup in backupDir, the node should be stopped.
+//The backup should be complete and on the same chain with the current stores in dataDir, the current stores
+//are kept in dataDir with suffix .old-<unix time>
+func RestoreBackup(backupDir, dataDir string) error {
+	height, err := checkBackup(backupDir, dataDir)
+	if err != nil {
+		return err
+	}
+	suffix := fmt.Sprintf(".old-%d", time.Now().Unix())
+	for _, name := range append(backupStoreDirs, MerkleTreeStorePath) {
+		dst := filepath.Join(dataDir, name)
+		if _, err := os.Stat(dst); err == nil {
+			err = os.Rename(dst, dst+suffix)
+			if err != nil {
+				return err
+			}
+		}
+		src := filepath.Join(backupDir, name)
+		if name == MerkleTreeStorePath {
+			err = copyFile(src, dst)
+		} else {
+			err = copyDir(src, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s error %s", name, err)
+		}
+	}
+	log.Infof("restore ledger at height %d from %s", height, backupDir)
+	return nil
+}
+
+//checkBackup return the height of backup after check the block store and state store in backup are at the same
+//block, and the block is on the chain of the current block store in dataDir
+func checkBackup(backupDir, dataDir string) (uint32, error) {
+	for _, name := range append(backupStoreDirs, MerkleTreeStorePath) {
+		if _, err := os.Stat(filepath.Join(backupDir, name)); err != nil {
+			return 0, fmt.Errorf("backup %s error %s", name, err)
+		}
+	}
+	blockStore, err := openBackupBlockStore(filepath.Join(backupDir, DBDirBlock))
+	if err != nil {
+		return 0, err
+	}
+	defer blockStore.Close()
+	blockHash, blockHeight, err := blockStore.GetCurrentBlock()
+	if err != nil {
+		return 0, fmt.Errorf("backup block store GetCurrentBlock error %s", err)
+	}
+
+	hashCheckHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
+	stateStore, err := NewStateStore(filepath.Join(backupDir, DBDirState), filepath.Join(backupDir, MerkleTreeStorePath),
+		hashCheckHeight)
+	if err != nil {
+		return 0, fmt.Errorf("open backup state store error %s", err)
+	}
+	defer stateStore.Close()
+	stateHash, stateHeight, err := stateStore.GetCurrentBlock()
+	if err != nil {
+		return 0, fmt.Errorf("backup state store GetCurrentBlock error %s", err)
+	}
+	if stateHash != blockHash || stateHeight != blockHeight {
+		return 0, fmt.Errorf("backup state store at height %d is inconsistent with block store at height %d",
+			stateHeight, blockHeight)
+	}
+
+	currDir := filepath.Join(dataDir, DBDirBlock)
+	if _, err := os.Stat(currDir); os.IsNotExist(err) {
+		return blockHeight, nil
+	}
+	currStore, err := openBackupBlockStore(currDir)
+	if err != nil {
+		return 0, err
+	}
+	defer currStore.Close()
+	currHash, currHeight, err := currStore.GetCurrentBlock()
+	if err == scom.ErrNotFound {
+		return blockHeight, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("current block store GetCurrentBlock error %s", err)
+	}
+	//the store with lower tip should be the prefix of the other one
+	higher, hash, height := currStore, blockHash, blockHeight
+	if blockHeight > currHeight {
+		higher, hash, height = blockStore, currHash, currHeight
+	}
+	expected, err := higher.GetBlockHash(height)
+	if err != nil {
+		return 0, fmt.Errorf("GetBlockHash height:%d error %s", height, err)
+	}
+	if expected != hash {
+		return 0, fmt.Errorf("backup block %s at height %d is not on the current chain with tip %d",
+			blockHash.ToHexString(), blockHeight, currHeight)
+	}
+	return blockHeight, nil
+}
+
+func openBackupBlockStore(dir string) (*BlockStore, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	blockStore, err := NewBlockStore(dir, false)
+	if err != nil {
+		return nil, fmt.Errorf("open block store %s error %s", dir, err)
+	}
+	return blockStore, nil
+}
+
+func copyDir(src, dst string) error {
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dst, 0755)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		err = copyFile(filepath.Join(src, file.Name()), filepath.Join(dst, file.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBackupLedger(t *testing.T, dataDir string, hashes []common.Uint256) *LedgerStoreImp {
+	blockStore, err := NewBlockStore(filepath.Join(dataDir, DBDirBlock), false)
+	assert.Nil(t, err)
+	stateStore, err := NewStateStore(filepath.Join(dataDir, DBDirState), filepath.Join(dataDir, MerkleTreeStorePath), 0)
+	assert.Nil(t, err)
+	eventStore, err := NewEventStore(filepath.Join(dataDir, DBDirEvent))
+	assert.Nil(t, err)
+	layer2Store, err := NewLayer2Store(dataDir)
+	assert.Nil(t, err)
+
+	height := uint32(len(hashes) - 1)
+	blockStore.NewBatch()
+	for i, hash := range hashes {
+		blockStore.SaveBlockHash(uint32(i), hash)
+	}
+	assert.Nil(t, blockStore.SaveCurrentBlock(height, hashes[height]))
+	assert.Nil(t, blockStore.CommitTo())
+	stateStore.NewBatch()
+	assert.Nil(t, stateStore.SaveCurrentBlock(height, hashes[height]))
+	assert.Nil(t, stateStore.CommitTo())
+	return &LedgerStoreImp{
+		blockStore:           blockStore,
+		stateStore:           stateStore,
+		eventStore:           eventStore,
+		layer2Store:          layer2Store,
+		currBlockHeight:      height,
+		savingBlockSemaphore: make(chan bool, 1),
+	}
+}
+
+func closeTestBackupLedger(ledgerStore *LedgerStoreImp) {
+	ledgerStore.blockStore.Close()
+	ledgerStore.stateStore.Close()
+	ledgerStore.eventStore.Close()
+	ledgerStore.layer2Store.store.Close()
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	hashes := []common.Uint256{{1}, {2}, {3}}
+	ledgerStore := newTestBackupLedger(t, filepath.Join(dir, "src"), hashes)
+	backupDir := filepath.Join(dir, "backup")
+	assert.Nil(t, ledgerStore.Backup(backupDir))
+	assert.NotNil(t, ledgerStore.Backup(backupDir))
+	closeTestBackupLedger(ledgerStore)
+
+	//the current chain is ahead of the backup
+	dataDir := filepath.Join(dir, "data")
+	closeTestBackupLedger(newTestBackupLedger(t, dataDir, append(hashes, common.Uint256{4})))
+	height, err := checkBackup(backupDir, dataDir)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), height)
+	assert.Nil(t, RestoreBackup(backupDir, dataDir))
+	blockStore, err := NewBlockStore(filepath.Join(dataDir, DBDirBlock), false)
+	assert.Nil(t, err)
+	hash, height, err := blockStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, hashes[2], hash)
+	assert.Equal(t, uint32(2), height)
+	blockStore.Close()
+
+	//the current chain is forked from the backup
+	forkDir := filepath.Join(dir, "fork")
+	closeTestBackupLedger(newTestBackupLedger(t, forkDir, []common.Uint256{{1}, {5}}))
+	_, err = checkBackup(backupDir, forkDir)
+	assert.NotNil(t, err)
+	assert.NotNil(t, RestoreBackup(backupDir, forkDir))
+
+	_, err = checkBackup(filepath.Join(dir, "missing"), dataDir)
+	assert.NotNil(t, err)
+}
@@ -21,7 +21,9 @@ package ledgerstore
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"math"
 
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/common/serialization"
@@ -33,10 +35,12 @@ import (
 
 //Block store save the data of block & transaction
 type BlockStore struct {
-	enableCache bool                       //Is enable lru cache
-	dbDir       string                     //The path of store file
-	cache       *BlockCache                //The cache of block, if have.
-	store       *leveldbstore.LevelDBStore //block store handler
+	enableCache       bool                       //Is enable lru cache
+	enableTxIndex     bool                       //Is enable the index of transaction by address
+	enableCompression bool                       //Is enable the compression of transaction
+	dbDir             string                     //The path of store file
+	cache             *BlockCache                //The cache of block, if have.
+	store             *leveldbstore.LevelDBStore //block store handler
 }
 
 //NewBlockStore return the block store instance
@@ -50,7 +54,7 @@ func NewBlockStore(dbDir string, enableCache bool) (*BlockStore, error) {
 		}
 	}
 
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := openLevelDBStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -70,21 +74,107 @@ func (this *BlockStore) NewBatch() {
 
 //SaveBlock persist block to store
 func (this *BlockStore) SaveBlock(block *types.Block) error {
+	return this.SaveBlockWithSysFee(block, 0)
+}
+
+//SaveBlockWithSysFee persist block to store with the sys fee charged by its transactions
+func (this *BlockStore) SaveBlockWithSysFee(block *types.Block, sysFee common.Fixed64) error {
 	if this.enableCache {
 		this.cache.AddBlock(block)
 	}
 
 	blockHeight := block.Header.Height
-	err := this.SaveHeader(block, 0)
+	err := this.SaveHeader(block, sysFee)
 	if err != nil {
 		return fmt.Errorf("SaveHeader error %s", err)
 	}
 	for _, tx := range block.Transactions {
 		this.SaveTransaction(tx, blockHeight)
 	}
+	if this.enableTxIndex {
+		this.SaveAddressTxIndex(block)
+	}
 	return nil
 }
 
+//SaveAddressTxIndex persist the index of transactions in block by payer and signer addresses
+func (this *BlockStore) SaveAddressTxIndex(block *types.Block) {
+	for i, tx := range block.Transactions {
+		txHash := tx.Hash()
+		addrs := map[common.Address]bool{tx.Payer: true}
+		for _, addr := range tx.GetSignatureAddresses() {
+			addrs[addr] = true
+		}
+		for addr := range addrs {
+			this.store.BatchPut(this.getAddressTxIndexKey(addr, block.Header.Height, uint32(i)), txHash.ToArray())
+		}
+	}
+}
+
+//GetTransactionsByAddress return at most limit transaction hash and index position of payer or signer address in [fromHeight, toHeight]
+//after the cursor, in block order. Nil cursor start from fromHeight and limit 0 means no limit
+func (this *BlockStore) GetTransactionsByAddress(addr common.Address, fromHeight, toHeight uint32, after *AddressTxCursor,
+	limit uint32) ([]*AddressTxCursor, []common.Uint256, error) {
+	start := this.getAddressTxIndexKey(addr, fromHeight, 0)
+	if after != nil && after.Height >= fromHeight {
+		start = append(this.getAddressTxIndexKey(addr, after.Height, after.TxIndex), 0)
+	}
+	end := append(this.getAddressTxIndexKey(addr, toHeight, math.MaxUint32), 0)
+	iter := this.store.NewRangeIterator(start, end)
+	defer iter.Release()
+	positions := make([]*AddressTxCursor, 0)
+	txHashes := make([]common.Uint256, 0)
+	prefixLen := 1 + common.ADDR_LEN
+	for iter.Next() {
+		if limit != 0 && uint32(len(txHashes)) >= limit {
+			break
+		}
+		key := iter.Key()
+		if len(key) != prefixLen+8 {
+			continue
+		}
+		txHash, err := common.Uint256ParseFromBytes(iter.Value())
+		if err != nil {
+			return nil, nil, err
+		}
+		positions = append(positions, &AddressTxCursor{
+			Height:  binary.BigEndian.Uint32(key[prefixLen : prefixLen+4]),
+			TxIndex: binary.BigEndian.Uint32(key[prefixLen+4:]),
+		})
+		txHashes = append(txHashes, txHash)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+	return positions, txHashes, nil
+}
+
+//AddressTxCursor is the position of an entry of transaction index, used as continuation token of paged transaction query
+type AddressTxCursor struct {
+	Height  uint32
+	TxIndex uint32
+}
+
+//String return the hex continuation token of cursor
+func (this *AddressTxCursor) String() string {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, this.Height)
+	binary.BigEndian.PutUint32(data[4:], this.TxIndex)
+	return hex.EncodeToString(data)
+}
+
+//ParseAddressTxCursor parse the continuation token, empty token return nil cursor
+func ParseAddressTxCursor(token string) (*AddressTxCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := hex.DecodeString(token)
+	if err != nil || len(data) != 8 {
+		return nil, scom.ErrInvalidCursor
+	}
+	return &AddressTxCursor{Height: binary.BigEndian.Uint32(data), TxIndex: binary.BigEndian.Uint32(data[4:])}, nil
+}
+
 //ContainBlock return the block specified by block hash save in store
 func (this *BlockStore) ContainBlock(blockHash common.Uint256) (bool, error) {
 	if this.enableCache {
@@ -315,6 +405,30 @@ func (this *BlockStore) GetHeaderIndexList() (map[uint32]common.Uint256, error)
 	return result, nil
 }
 
+//GetHeaderIndexCount return the count of heights saved in header index list, without loading the block hashes
+func (this *BlockStore) GetHeaderIndexCount() (uint32, error) {
+	count := uint32(0)
+	iter := this.store.NewIterator([]byte{byte(scom.IX_HEADER_HASH_LIST)})
+	defer iter.Release()
+	for iter.Next() {
+		startCount, err := this.getStartHeightByHeaderIndexKey(iter.Key())
+		if err != nil {
+			return 0, fmt.Errorf("getStartHeightByHeaderIndexKey error %s", err)
+		}
+		size, err := serialization.ReadUint32(bytes.NewReader(iter.Value()))
+		if err != nil {
+			return 0, fmt.Errorf("serialization.ReadUint32 count error %s", err)
+		}
+		if startCount+size > count {
+			count = startCount + size
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 //SaveHeaderIndexList persist header index list to store
 func (this *BlockStore) SaveHeaderIndexList(startIndex uint32, indexList []common.Uint256) {
 	indexKey := this.getHeaderIndexListKey(startIndex)
@@ -328,6 +442,26 @@ func (this *BlockStore) SaveHeaderIndexList(startIndex uint32, indexList []commo
 	this.store.BatchPut(indexKey, value.Bytes())
 }
 
+//deleteHeaderIndexListAbove delete the header index lists containing the heights above height in batch
+func (this *BlockStore) deleteHeaderIndexListAbove(height uint32) error {
+	iter := this.store.NewIterator([]byte{byte(scom.IX_HEADER_HASH_LIST)})
+	defer iter.Release()
+	for iter.Next() {
+		startHeight, err := this.getStartHeightByHeaderIndexKey(iter.Key())
+		if err != nil {
+			return fmt.Errorf("getStartHeightByHeaderIndexKey error %s", err)
+		}
+		size, err := serialization.ReadUint32(bytes.NewReader(iter.Value()))
+		if err != nil {
+			return fmt.Errorf("serialization.ReadUint32 count error %s", err)
+		}
+		if startHeight+size > height+1 {
+			this.store.BatchDelete(iter.Key())
+		}
+	}
+	return iter.Error()
+}
+
 //GetBlockHash return block hash by block height
 func (this *BlockStore) GetBlockHash(height uint32) (common.Uint256, error) {
 	key := this.getBlockHashKey(height)
@@ -348,6 +482,21 @@ func (this *BlockStore) SaveBlockHash(height uint32, blockHash common.Uint256) {
 	this.store.BatchPut(key, blockHash.ToArray())
 }
 
+//deleteBlock delete the block hash index of height, and the header and transactions of the block if readable, in batch
+func (this *BlockStore) deleteBlock(height uint32) {
+	blockHash, err := this.GetBlockHash(height)
+	if err == nil {
+		_, txHashes, err := this.loadHeaderWithTx(blockHash)
+		if err == nil {
+			for _, txHash := range txHashes {
+				this.store.BatchDelete(this.getTransactionKey(txHash))
+			}
+		}
+		this.store.BatchDelete(this.getHeaderKey(blockHash))
+	}
+	this.store.BatchDelete(this.getBlockHashKey(height))
+}
+
 //SaveTransaction persist transaction to store
 func (this *BlockStore) SaveTransaction(tx *types.Transaction, height uint32) {
 	if this.enableCache {
@@ -362,6 +511,10 @@ func (this *BlockStore) putTransaction(tx *types.Transaction, height uint32) {
 	value := common.NewZeroCopySink(nil)
 	value.WriteUint32(height)
 	tx.Serialization(value)
+	if this.enableCompression {
+		this.store.BatchPut(key, scom.CompressValue(value.Bytes()))
+		return
+	}
 	this.store.BatchPut(key, value.Bytes())
 }
 
@@ -392,6 +545,10 @@ func (this *BlockStore) loadTransaction(txHash common.Uint256) (*types.Transacti
 	if err != nil {
 		return nil, 0, err
 	}
+	value, err = scom.DecompressValue(value)
+	if err != nil {
+		return nil, 0, err
+	}
 	source := common.NewZeroCopySource(value)
 	var eof bool
 	height, eof = source.NextUint32()
@@ -466,6 +623,11 @@ func (this *BlockStore) Close() error {
 	return this.store.Close()
 }
 
+//Compact block store
+func (this *BlockStore) Compact() error {
+	return this.store.Compact()
+}
+
 func (this *BlockStore) getTransactionKey(txHash common.Uint256) []byte {
 	key := bytes.NewBuffer(nil)
 	key.WriteByte(byte(scom.DATA_TRANSACTION))
@@ -473,6 +635,16 @@ func (this *BlockStore) getTransactionKey(txHash common.Uint256) []byte {
 	return key.Bytes()
 }
 
+//height and tx index are big endian to keep the index in block order
+func (this *BlockStore) getAddressTxIndexKey(addr common.Address, height uint32, txIndex uint32) []byte {
+	key := make([]byte, 1+common.ADDR_LEN+8)
+	key[0] = byte(scom.IX_ADDRESS_TX)
+	copy(key[1:], addr[:])
+	binary.BigEndian.PutUint32(key[1+common.ADDR_LEN:], height)
+	binary.BigEndian.PutUint32(key[1+common.ADDR_LEN+4:], txIndex)
+	return key
+}
+
 func (this *BlockStore) getHeaderKey(blockHash common.Uint256) []byte {
 	data := blockHash.ToArray()
 	key := make([]byte, 1+len(data))
@@ -21,10 +21,13 @@ package ledgerstore
 import (
 	"crypto/sha256"
 	"fmt"
+	"github.com/hashicorp/golang-lru"
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/layer2/node/account"
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/core/payload"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
 	"github.com/ontio/layer2/node/core/types"
 	"github.com/ontio/layer2/node/core/utils"
 	"github.com/ontio/layer2/node/smartcontract/service/native/ont"
@@ -226,6 +229,10 @@ func TestHeaderIndexList(t *testing.T) {
 			return
 		}
 	}
+
+	count, err := testBlockStore.GetHeaderIndexCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(len(indexList)), count)
 }
 
 func TestSaveHeader(t *testing.T) {
@@ -364,6 +371,142 @@ func TestBlock(t *testing.T) {
 	}
 }
 
+func TestAddressTxIndex(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	blockStore := &BlockStore{store: store, enableTxIndex: true}
+	ledgerStore := &LedgerStoreImp{blockStore: blockStore}
+
+	alice := common.AddressFromVmCode([]byte("alice"))
+	bob := common.AddressFromVmCode([]byte("bob"))
+	nonce := uint32(0)
+	newTx := func(payer common.Address) *types.Transaction {
+		nonce++
+		mutable := &types.MutableTransaction{TxType: types.InvokeNeo, Nonce: nonce, Payer: payer,
+			Payload: &payload.InvokeCode{Code: []byte{0}}, Sigs: []types.Sig{}}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		return tx
+	}
+	txs := []*types.Transaction{newTx(alice), newTx(bob), newTx(alice), newTx(alice)}
+	blockStore.NewBatch()
+	blockStore.SaveAddressTxIndex(&types.Block{Header: &types.Header{Height: 1}, Transactions: txs[:2]})
+	blockStore.SaveAddressTxIndex(&types.Block{Header: &types.Header{Height: 2}, Transactions: txs[2:]})
+	assert.Nil(t, blockStore.CommitTo())
+
+	page, err := ledgerStore.GetTransactionsByAddress(alice, 0, 10, "", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 2}, page.Heights)
+	assert.Equal(t, []common.Uint256{txs[0].Hash(), txs[2].Hash()}, page.TxHashes)
+	assert.NotEqual(t, "", page.Next)
+	page, err = ledgerStore.GetTransactionsByAddress(alice, 0, 10, page.Next, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Uint256{txs[3].Hash()}, page.TxHashes)
+	assert.Equal(t, "", page.Next)
+
+	page, err = ledgerStore.GetTransactionsByAddress(bob, 2, 10, "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(page.TxHashes))
+	page, err = ledgerStore.GetTransactionsByAddress(bob, 0, 1, "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Uint256{txs[1].Hash()}, page.TxHashes)
+
+	_, err = ledgerStore.GetTransactionsByAddress(bob, 0, 1, "zz", 0)
+	assert.Equal(t, scom.ErrInvalidCursor, err)
+}
+
+func TestGetBlocksByHeightRange(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	blockStore := &BlockStore{store: store}
+	headerCache, err := lru.New(HEADER_INDEX_CACHE_SIZE)
+	assert.Nil(t, err)
+	ledgerStore := &LedgerStoreImp{blockStore: blockStore, headerCache: headerCache}
+
+	blockStore.NewBatch()
+	hashes := make([]common.Uint256, 0)
+	for height := uint32(0); height < 3; height++ {
+		block := &types.Block{Header: &types.Header{Height: height, Bookkeepers: []keypair.PublicKey{},
+			SigData: [][]byte{}}, Transactions: []*types.Transaction{}}
+		assert.Nil(t, blockStore.SaveBlock(block))
+		ledgerStore.setHeaderIndex(height, block.Hash())
+		hashes = append(hashes, block.Hash())
+	}
+	assert.Nil(t, blockStore.CommitTo())
+	ledgerStore.currBlockHeight = 2
+
+	blocks, err := ledgerStore.GetBlocksByHeightRange(1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(blocks))
+	assert.Equal(t, hashes[1], blocks[0].Hash())
+	assert.Equal(t, hashes[2], blocks[1].Hash())
+
+	blocks, err = ledgerStore.GetBlocksByHeightRange(3, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(blocks))
+
+	_, err = ledgerStore.GetBlocksByHeightRange(2, 1)
+	assert.NotNil(t, err)
+}
+
+func TestHeaderIndexLookup(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	blockStore := &BlockStore{store: store}
+	headerCache, err := lru.New(2)
+	assert.Nil(t, err)
+	ledgerStore := &LedgerStoreImp{blockStore: blockStore, headerCache: headerCache}
+	assert.Equal(t, common.Uint256{}, ledgerStore.GetBlockHash(0))
+
+	blockStore.NewBatch()
+	hashes := make([]common.Uint256, 0)
+	for height := uint32(0); height < 5; height++ {
+		hash := common.Uint256(sha256.Sum256([]byte(fmt.Sprintf("%v", height))))
+		blockStore.SaveBlockHash(height, hash)
+		ledgerStore.setHeaderIndex(height, hash)
+		hashes = append(hashes, hash)
+	}
+	assert.Nil(t, blockStore.CommitTo())
+
+	assert.Equal(t, 2, headerCache.Len())
+	assert.Equal(t, uint32(4), ledgerStore.GetCurrentHeaderHeight())
+	assert.Equal(t, hashes[4], ledgerStore.GetCurrentHeaderHash())
+	for height, hash := range hashes {
+		assert.Equal(t, hash, ledgerStore.GetBlockHash(uint32(height)))
+	}
+	assert.Equal(t, common.Uint256{}, ledgerStore.GetBlockHash(5))
+}
+
+func TestTransactionCompression(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	blockStore := &BlockStore{store: store}
+
+	acc := account.NewAccount("")
+	legacyTx, err := transferTx(acc.Address, acc.Address, 1)
+	assert.Nil(t, err)
+	compressedTx, err := transferTx(acc.Address, acc.Address, 2)
+	assert.Nil(t, err)
+	blockStore.NewBatch()
+	blockStore.SaveTransaction(legacyTx, 1)
+	blockStore.enableCompression = true
+	blockStore.SaveTransaction(compressedTx, 2)
+	assert.Nil(t, blockStore.CommitTo())
+
+	value, err := store.Get(blockStore.getTransactionKey(compressedTx.Hash()))
+	assert.Nil(t, err)
+	assert.Equal(t, scom.COMPRESSED_VALUE_MAGIC, value[:len(scom.COMPRESSED_VALUE_MAGIC)])
+
+	tx, height, err := blockStore.GetTransaction(legacyTx.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), height)
+	assert.Equal(t, legacyTx.Hash(), tx.Hash())
+	tx, height, err = blockStore.GetTransaction(compressedTx.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), height)
+	assert.Equal(t, compressedTx.Hash(), tx.Hash())
+}
+
 func transferTx(from, to common.Address, amount uint64) (*types.Transaction, error) {
 	var sts []ont.State
 	sts = append(sts, ont.State{
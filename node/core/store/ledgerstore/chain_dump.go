@@ -0,0 +1,265 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/common/serialization"
+	"github.com/ontio/layer2/node/core/types"
+)
+
+const (
+	CHAIN_DUMP_VERSION         = byte(0) //Version of chain dump file
+	CHAIN_DUMP_IMPORT_LOG_SIZE = 1000    //Count of imported blocks between progress logs
+)
+
+var chainDumpMagic = []byte("L2CD")
+
+//chainDumpHeader is the head of chain dump file
+type chainDumpHeader struct {
+	genesisHash common.Uint256
+	startHeight uint32
+	endHeight   uint32
+}
+
+//IsChainDump return whether the data begins with the magic of chain dump file
+func IsChainDump(data []byte) bool {
+	return bytes.HasPrefix(data, chainDumpMagic)
+}
+
+//ExportBlocks write the blocks and layer2 states in [from, to] to the chain dump file at path. The file is the magic,
+//version, genesis block hash and height range, followed by the block and layer2 state of each height, and ends with
+//the sha256 of all of them. The layer2 state is empty if there is none at the height or it is pruned
+func (this *LedgerStoreImp) ExportBlocks(path string, from, to uint32) error {
+	if from > to {
+		return fmt.Errorf("from height %d is larger than to height %d", from, to)
+	}
+	if currentHeight := this.GetCurrentBlockHeight(); to > currentHeight {
+		return fmt.Errorf("to height %d is larger than current block height %d", to, currentHeight)
+	}
+	header := &chainDumpHeader{
+		genesisHash: this.GetBlockHash(0),
+		startHeight: from,
+		endHeight:   to,
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return this.writeChainDump(w, header)
+	})
+}
+
+func (this *LedgerStoreImp) writeChainDump(w io.Writer, header *chainDumpHeader) error {
+	hasher := sha256.New()
+	writer := io.MultiWriter(w, hasher)
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteBytes(chainDumpMagic)
+	sink.WriteByte(CHAIN_DUMP_VERSION)
+	sink.WriteHash(header.genesisHash)
+	sink.WriteUint32(header.startHeight)
+	sink.WriteUint32(header.endHeight)
+	_, err := writer.Write(sink.Bytes())
+	if err != nil {
+		return err
+	}
+	item := common.NewZeroCopySink(nil)
+	for height := header.startHeight; height <= header.endHeight; height++ {
+		block, err := this.GetBlockByHeight(height)
+		if err != nil {
+			return fmt.Errorf("GetBlockByHeight %d error %s", height, err)
+		}
+		layer2State, err := this.GetLayer2State(height)
+		if err != nil {
+			if !this.isLayer2StatePruned(height) {
+				return fmt.Errorf("GetLayer2State %d error %s", height, err)
+			}
+			log.Warnf("export blocks: layer2 state of height %d is pruned", height)
+		}
+		sink.Reset()
+		item.Reset()
+		block.Serialization(item)
+		sink.WriteVarBytes(item.Bytes())
+		item.Reset()
+		if layer2State != nil {
+			layer2State.Serialization(item)
+		}
+		sink.WriteVarBytes(item.Bytes())
+		if _, err = writer.Write(sink.Bytes()); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(hasher.Sum(nil))
+	return err
+}
+
+//ImportBlocks import the blocks in the chain dump file at path written by ExportBlocks. The checksum of file is verified
+//before any block is imported, and the dump should be of the same genesis block and not skip the next block height.
+//The blocks not after current block height should be the same as in ledger, the others are verified and re-executed
+func (this *LedgerStoreImp) ImportBlocks(path string) error {
+	if err := verifyChainDumpChecksum(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	header, err := readChainDumpHeader(reader)
+	if err != nil {
+		return err
+	}
+	if genesisHash := this.GetBlockHash(0); header.genesisHash != genesisHash {
+		return fmt.Errorf("genesis block hash %s of chain dump is different from ledger %s",
+			header.genesisHash.ToHexString(), genesisHash.ToHexString())
+	}
+	currHeight := this.GetCurrentBlockHeight()
+	if header.startHeight > currHeight+1 {
+		return fmt.Errorf("start height %d of chain dump is larger than next block height %d", header.startHeight,
+			currHeight+1)
+	}
+	for height := header.startHeight; height <= header.endHeight; height++ {
+		block, layer2State, err := readChainDumpBlock(reader)
+		if err != nil {
+			return fmt.Errorf("read block of height %d error %s", height, err)
+		}
+		if block.Header.Height != height || (layer2State != nil && layer2State.Height != height) {
+			return fmt.Errorf("block of height %d is out of order", height)
+		}
+		if height <= currHeight {
+			if block.Hash() != this.GetBlockHash(height) {
+				return fmt.Errorf("block of height %d is different from ledger", height)
+			}
+			continue
+		}
+		err = this.importBlock(block, layer2State)
+		if err != nil {
+			return fmt.Errorf("import block of height %d error %s", height, err)
+		}
+		if (height-header.startHeight)%CHAIN_DUMP_IMPORT_LOG_SIZE == 0 || height == header.endHeight {
+			log.Infof("import blocks: imported block height %d of %d", height, header.endHeight)
+		}
+	}
+	return nil
+}
+
+//importBlock verify the transactions root and header of block before executing and submitting it
+func (this *LedgerStoreImp) importBlock(block *types.Block, layer2State *types.Layer2State) error {
+	txHashes := make([]common.Uint256, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		txHashes = append(txHashes, tx.Hash())
+	}
+	if common.ComputeMerkleRoot(txHashes) != block.Header.TransactionsRoot {
+		return fmt.Errorf("transactions root is incorrect")
+	}
+	if err := this.verifyHeader(block.Header); err != nil {
+		return fmt.Errorf("verifyHeader error %s", err)
+	}
+	result, err := this.ExecuteBlock(block)
+	if err != nil {
+		return fmt.Errorf("ExecuteBlock error %s", err)
+	}
+	return this.SubmitBlock(block, layer2State, result)
+}
+
+//verifyChainDumpChecksum compare the sha256 of chain dump file with the one at its end
+func verifyChainDumpChecksum(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < sha256.Size {
+		return fmt.Errorf("not a chain dump")
+	}
+	hasher := sha256.New()
+	reader := bufio.NewReader(f)
+	_, err = io.CopyN(hasher, reader, info.Size()-sha256.Size)
+	if err != nil {
+		return err
+	}
+	checksum, err := serialization.ReadBytes(reader, sha256.Size)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(checksum, hasher.Sum(nil)) {
+		return fmt.Errorf("chain dump checksum mismatch")
+	}
+	return nil
+}
+
+func readChainDumpHeader(reader io.Reader) (*chainDumpHeader, error) {
+	magic, err := serialization.ReadBytes(reader, uint64(len(chainDumpMagic)))
+	if err != nil || !IsChainDump(magic) {
+		return nil, fmt.Errorf("not a chain dump")
+	}
+	version, err := serialization.ReadByte(reader)
+	if err != nil {
+		return nil, err
+	}
+	if version != CHAIN_DUMP_VERSION {
+		return nil, fmt.Errorf("unsupported chain dump version %d", version)
+	}
+	data, err := serialization.ReadBytes(reader, common.UINT256_SIZE+8)
+	if err != nil {
+		return nil, err
+	}
+	source := common.NewZeroCopySource(data)
+	header := new(chainDumpHeader)
+	header.genesisHash, _ = source.NextHash()
+	header.startHeight, _ = source.NextUint32()
+	header.endHeight, _ = source.NextUint32()
+	if header.startHeight > header.endHeight {
+		return nil, fmt.Errorf("start height %d is larger than end height %d", header.startHeight, header.endHeight)
+	}
+	return header, nil
+}
+
+func readChainDumpBlock(reader io.Reader) (*types.Block, *types.Layer2State, error) {
+	data, err := serialization.ReadVarBytes(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := types.BlockFromRawBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = serialization.ReadVarBytes(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) == 0 {
+		return block, nil, nil
+	}
+	layer2State := new(types.Layer2State)
+	if err = layer2State.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, nil, err
+	}
+	return block, layer2State, nil
+}
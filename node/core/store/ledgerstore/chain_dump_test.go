@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportAndImportBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaindump")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	src, err := NewLedgerStore(filepath.Join(dir, "src"), 0)
+	assert.Nil(t, err)
+	defer src.Close()
+	assert.Nil(t, src.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	for i := 0; i < 3; i++ {
+		newTestCheckpointBlock(t, src, acc)
+	}
+
+	dump := filepath.Join(dir, "chain.dump")
+	assert.NotNil(t, src.ExportBlocks(dump, 2, 1))
+	assert.NotNil(t, src.ExportBlocks(dump, 0, 4))
+	assert.Nil(t, src.ExportBlocks(dump, 0, 3))
+	data, err := ioutil.ReadFile(dump)
+	assert.Nil(t, err)
+	assert.True(t, IsChainDump(data))
+
+	dst, err := NewLedgerStore(filepath.Join(dir, "dst"), 0)
+	assert.Nil(t, err)
+	defer dst.Close()
+	assert.Nil(t, dst.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+
+	//the modified dump is rejected before any block imported
+	data[len(data)/2] ^= 1
+	modified := filepath.Join(dir, "modified.dump")
+	assert.Nil(t, ioutil.WriteFile(modified, data, 0644))
+	assert.NotNil(t, dst.ImportBlocks(modified))
+	assert.Equal(t, uint32(0), dst.GetCurrentBlockHeight())
+
+	//the dump skipping the next block height is rejected
+	gap := filepath.Join(dir, "gap.dump")
+	assert.Nil(t, src.ExportBlocks(gap, 2, 3))
+	assert.NotNil(t, dst.ImportBlocks(gap))
+
+	assert.Nil(t, dst.ImportBlocks(dump))
+	assert.Equal(t, uint32(3), dst.GetCurrentBlockHeight())
+	for height := uint32(0); height <= 3; height++ {
+		assert.Equal(t, src.GetBlockHash(height), dst.GetBlockHash(height))
+		root, err := src.GetStateMerkleRoot(height)
+		assert.Nil(t, err)
+		imported, err := dst.GetStateMerkleRoot(height)
+		assert.Nil(t, err)
+		assert.Equal(t, root, imported)
+	}
+	layer2State, err := dst.GetLayer2State(3)
+	assert.Nil(t, err)
+	assert.NotNil(t, layer2State)
+
+	//the blocks already in ledger are skipped
+	assert.Nil(t, dst.ImportBlocks(gap))
+
+	//the dump of other chain is rejected
+	other := account.NewAccount("")
+	otherBookkeepers := []keypair.PublicKey{other.PublicKey}
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(other.PublicKey))}
+	otherGenesis, err := genesis.BuildGenesisBlock(otherBookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	otherStore, err := NewLedgerStore(filepath.Join(dir, "other"), 0)
+	assert.Nil(t, err)
+	defer otherStore.Close()
+	assert.Nil(t, otherStore.InitLedgerStoreWithGenesisBlock(otherGenesis, otherBookkeepers))
+	assert.NotNil(t, otherStore.ImportBlocks(dump))
+}
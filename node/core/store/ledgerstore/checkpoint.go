@@ -0,0 +1,728 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/common/serialization"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/signature"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/merkle"
+)
+
+const CHECKPOINT_SNAPSHOT_VERSION = byte(0) //Version of checkpoint snapshot file
+
+var checkpointSnapshotMagic = []byte("L2CP")
+
+//checkpointStatePrefixes are the prefixes of the state store keys written to checkpoint snapshot, the storage is
+//verified by the storage state root of checkpoint, and the bookkeeper and contract state by its contract hash
+var checkpointStatePrefixes = []scom.DataEntryPrefix{scom.ST_BOOKKEEPER, scom.ST_CONTRACT, scom.ST_STORAGE}
+
+//checkpointSnapshot is the ledger data at checkpoint written to snapshot file
+type checkpointSnapshot struct {
+	checkpoint  *types.Checkpoint
+	block       *types.Block
+	layer2State *types.Layer2State
+	blockHashes []common.Uint256   //Block hashes from genesis block to checkpoint
+	blockTree   []byte             //Value of block merkle tree in state store
+	stateTree   []byte             //Value of state merkle tree in state store, nil before state hash check height
+	stateRoot   []byte             //Value of state merkle root of checkpoint, nil before state hash check height
+	merkleSize  int64              //Size of block merkle hash file
+	state       scom.StoreSnapshot //Snapshot of state store
+}
+
+//SetCheckpointSnapshotDir write the state snapshot at each checkpoint saved to dir, only the latest snapshot is kept
+func (this *LedgerStoreImp) SetCheckpointSnapshotDir(dir string) error {
+	if dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		log.Infof("checkpoint snapshot is written to %s", dir)
+	}
+	this.getSavingBlockLock()
+	this.snapshotDir = dir
+	this.releaseSavingBlockLock()
+	return nil
+}
+
+//NewCheckpoint return the unsigned checkpoint of current block if height is a checkpoint height, otherwise nil.
+//Checkpoint heights are the multiples of layer2 checkpoint interval
+func (this *LedgerStoreImp) NewCheckpoint(height uint32) (*types.Checkpoint, error) {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	return this.newCheckpoint(height)
+}
+
+func (this *LedgerStoreImp) newCheckpoint(height uint32) (*types.Checkpoint, error) {
+	this.layer2PruneLock.RLock()
+	isCheckpoint := height != 0 && this.isLayer2Checkpoint(height)
+	this.layer2PruneLock.RUnlock()
+	if !isCheckpoint {
+		return nil, nil
+	}
+	currHeight, blockHash := this.GetCurrentBlock()
+	if height != currHeight {
+		return nil, fmt.Errorf("checkpoint height %d is not current block height %d", height, currHeight)
+	}
+	stateRoot, err := this.stateStore.GetStateMerkleRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateMerkleRoot error %s", err)
+	}
+	accountRoot, err := this.accountStateRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("accountStateRoot error %s", err)
+	}
+	storageRoot, err := this.stateStore.GetStorageTreeRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetStorageTreeRoot error %s", err)
+	}
+	contractHash, err := checkpointContractHash(this.stateStore.store)
+	if err != nil {
+		return nil, fmt.Errorf("checkpointContractHash error %s", err)
+	}
+	return &types.Checkpoint{
+		Version:          types.CURR_CHECKPOINT_VERSION,
+		Height:           height,
+		BlockHash:        blockHash,
+		BlockRoot:        this.stateStore.merkleTree.Root(),
+		StateRoot:        stateRoot,
+		AccountStateRoot: accountRoot,
+		StorageStateRoot: storageRoot,
+		ContractHash:     contractHash,
+	}, nil
+}
+
+//checkpointContractHash return the sha256 of the bookkeeper and contract state entries of store in key order, each
+//entry is the var bytes of key and value as in the snapshot
+func checkpointContractHash(store scom.PersistStore) (common.Uint256, error) {
+	hasher := sha256.New()
+	sink := common.NewZeroCopySink(nil)
+	for _, prefix := range []scom.DataEntryPrefix{scom.ST_BOOKKEEPER, scom.ST_CONTRACT} {
+		iter := store.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			sink.Reset()
+			sink.WriteVarBytes(iter.Key())
+			sink.WriteVarBytes(iter.Value())
+			hasher.Write(sink.Bytes())
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return common.UINT256_EMPTY, err
+		}
+	}
+	var hash common.Uint256
+	hasher.Sum(hash[:0])
+	return hash, nil
+}
+
+//SaveCheckpoint save the checkpoint of current block signed by the bookkeepers of the block, and start writing the
+//state snapshot at the checkpoint if snapshot dir is set
+func (this *LedgerStoreImp) SaveCheckpoint(checkpoint *types.Checkpoint) error {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	expected, err := this.newCheckpoint(checkpoint.Height)
+	if err != nil {
+		return err
+	}
+	if expected == nil {
+		return fmt.Errorf("height %d is not a checkpoint height", checkpoint.Height)
+	}
+	if expected.Hash() != checkpoint.Hash() {
+		return fmt.Errorf("checkpoint at height %d is inconsistent with ledger", checkpoint.Height)
+	}
+	header, err := this.GetHeaderByHash(checkpoint.BlockHash)
+	if err != nil {
+		return fmt.Errorf("GetHeaderByHash error %s", err)
+	}
+	err = verifyCheckpoint(checkpoint, header.Bookkeepers)
+	if err != nil {
+		return fmt.Errorf("verifyCheckpoint error %s", err)
+	}
+	err = this.stateStore.SaveCheckpoint(checkpoint)
+	if err != nil {
+		return fmt.Errorf("stateStore.SaveCheckpoint error %s", err)
+	}
+	log.Infof("checkpoint at height %d saved, block %s", checkpoint.Height, checkpoint.BlockHash.ToHexString())
+	if this.snapshotDir != "" {
+		this.startCheckpointSnapshot(checkpoint)
+	}
+	return nil
+}
+
+//GetCheckpoint return the checkpoint of height, or the latest checkpoint if height is 0
+func (this *LedgerStoreImp) GetCheckpoint(height uint32) (*types.Checkpoint, error) {
+	if height == 0 {
+		var err error
+		height, err = this.stateStore.GetLatestCheckpointHeight()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return this.stateStore.GetCheckpoint(height)
+}
+
+func verifyCheckpoint(checkpoint *types.Checkpoint, bookkeepers []keypair.PublicKey) error {
+	hash := checkpoint.Hash()
+	m := len(bookkeepers) - (len(bookkeepers)-1)/3
+	return signature.VerifyMultiSignature(hash[:], bookkeepers, m, checkpoint.SigData)
+}
+
+//startCheckpointSnapshot take the snapshot of state store at checkpoint and write it to snapshot dir in background,
+//the caller should hold saving block lock. The checkpoint is skipped if the previous snapshot is still being written
+func (this *LedgerStoreImp) startCheckpointSnapshot(checkpoint *types.Checkpoint) {
+	if !atomic.CompareAndSwapInt32(&this.snapshotWriting, 0, 1) {
+		log.Warnf("skip snapshot of checkpoint at height %d, the previous snapshot is being written", checkpoint.Height)
+		return
+	}
+	snap, err := this.newCheckpointSnapshot(checkpoint)
+	if err != nil {
+		atomic.StoreInt32(&this.snapshotWriting, 0)
+		log.Errorf("snapshot of checkpoint at height %d error %s", checkpoint.Height, err)
+		return
+	}
+	dir := this.snapshotDir
+	go func() {
+		defer atomic.StoreInt32(&this.snapshotWriting, 0)
+		defer snap.state.Release()
+		//keep the stores open until the snapshot written
+		this.compactLock.Lock()
+		defer this.compactLock.Unlock()
+		if this.compactClosed {
+			return
+		}
+		start := time.Now()
+		path, err := this.writeCheckpointSnapshotFile(dir, snap)
+		if err != nil {
+			log.Errorf("write snapshot of checkpoint at height %d error %s", checkpoint.Height, err)
+			return
+		}
+		log.Infof("write snapshot of checkpoint at height %d to %s cost %s", checkpoint.Height, path, time.Since(start))
+	}()
+}
+
+func (this *LedgerStoreImp) newCheckpointSnapshot(checkpoint *types.Checkpoint) (*checkpointSnapshot, error) {
+	height := checkpoint.Height
+	block, err := this.GetBlockByHash(checkpoint.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockByHash error %s", err)
+	}
+	layer2State, err := this.layer2Store.GetLayer2State(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetLayer2State error %s", err)
+	}
+	blockHashes := make([]common.Uint256, 0, height+1)
+	for h := uint32(0); h <= height; h++ {
+		blockHashes = append(blockHashes, this.getHeaderIndex(h))
+	}
+	snap := &checkpointSnapshot{
+		checkpoint:  checkpoint,
+		block:       block,
+		layer2State: layer2State,
+		blockHashes: blockHashes,
+	}
+	snap.blockTree, err = this.stateStore.store.Get(this.stateStore.genBlockMerkleTreeKey())
+	if err != nil {
+		return nil, fmt.Errorf("get block merkle tree error %s", err)
+	}
+	if height >= this.stateStore.stateHashCheckHeight {
+		snap.stateTree, err = this.stateStore.store.Get(this.stateStore.genStateMerkleTreeKey())
+		if err != nil {
+			return nil, fmt.Errorf("get state merkle tree error %s", err)
+		}
+		snap.stateRoot, err = this.stateStore.store.Get(this.stateStore.genStateMerkleRootKey(height))
+		if err != nil {
+			return nil, fmt.Errorf("get state merkle root error %s", err)
+		}
+	}
+	//the merkle hashes are flushed before the block committed, the hashes appended later are not in the size
+	if this.stateStore.merkleHashStore != nil {
+		info, err := os.Stat(this.stateStore.merklePath)
+		if err != nil {
+			return nil, err
+		}
+		snap.merkleSize = info.Size()
+	}
+	snap.state, err = this.stateStore.store.NewSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("state store snapshot error %s", err)
+	}
+	return snap, nil
+}
+
+//writeCheckpointSnapshotFile write the snapshot to file of dir and remove the snapshots of earlier checkpoints
+func (this *LedgerStoreImp) writeCheckpointSnapshotFile(dir string, snap *checkpointSnapshot) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("checkpoint_%d.snap", snap.checkpoint.Height))
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		var merkleFile io.Reader = bytes.NewReader(nil)
+		if snap.merkleSize > 0 {
+			f, err := os.Open(this.stateStore.merklePath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			merkleFile = f
+		}
+		return writeCheckpointSnapshot(w, snap, merkleFile)
+	})
+	if err != nil {
+		return "", err
+	}
+	olds, err := filepath.Glob(filepath.Join(dir, "checkpoint_*.snap"))
+	if err != nil {
+		return path, nil
+	}
+	for _, old := range olds {
+		if old != path {
+			os.Remove(old)
+		}
+	}
+	return path, nil
+}
+
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+//writeCheckpointSnapshot write the checkpoint, the block and layer2 state at checkpoint, the block hashes, the merkle
+//trees, the merkle hash file and the state entries, followed by the sha256 of all of them
+func writeCheckpointSnapshot(w io.Writer, snap *checkpointSnapshot, merkleFile io.Reader) error {
+	hasher := sha256.New()
+	writer := io.MultiWriter(w, hasher)
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteBytes(checkpointSnapshotMagic)
+	sink.WriteByte(CHECKPOINT_SNAPSHOT_VERSION)
+	item := common.NewZeroCopySink(nil)
+	snap.checkpoint.Serialization(item)
+	sink.WriteVarBytes(item.Bytes())
+	item.Reset()
+	snap.block.Serialization(item)
+	sink.WriteVarBytes(item.Bytes())
+	item.Reset()
+	snap.layer2State.Serialization(item)
+	sink.WriteVarBytes(item.Bytes())
+	sink.WriteVarBytes(snap.blockTree)
+	sink.WriteVarBytes(snap.stateTree)
+	sink.WriteVarBytes(snap.stateRoot)
+	sink.WriteUint32(uint32(len(snap.blockHashes)))
+	for _, hash := range snap.blockHashes {
+		sink.WriteHash(hash)
+	}
+	sink.WriteVarUint(uint64(snap.merkleSize))
+	_, err := writer.Write(sink.Bytes())
+	if err != nil {
+		return err
+	}
+	n, err := io.CopyN(writer, merkleFile, snap.merkleSize)
+	if err != nil {
+		return fmt.Errorf("copy merkle hash file error %s, copied %d of %d", err, n, snap.merkleSize)
+	}
+	for _, prefix := range checkpointStatePrefixes {
+		iter := snap.state.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			sink.Reset()
+			sink.WriteVarBytes(iter.Key())
+			sink.WriteVarBytes(iter.Value())
+			if _, err = writer.Write(sink.Bytes()); err != nil {
+				break
+			}
+		}
+		iter.Release()
+		if err == nil {
+			err = iter.Error()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	//empty key ends the state entries
+	_, err = writer.Write([]byte{0})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(hasher.Sum(nil))
+	return err
+}
+
+//ImportCheckpointSnapshot init the ledger in dataDir with the state snapshot at checkpoint and return the height of
+//checkpoint, the dataDir should not have a ledger. The checkpoint should be signed by bookkeepers, and its account state
+//root should be the root of the layer2 state at its height. The snapshot is verified against the checkpoint, the
+//blocks after the checkpoint are replayed by the node
+func ImportCheckpointSnapshot(file, dataDir string, genesisBlock *types.Block, bookkeepers []keypair.PublicKey) (uint32, error) {
+	for _, name := range []string{DBDirBlock, DBDirState} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); err == nil {
+			return 0, fmt.Errorf("ledger already exists in %s", dataDir)
+		}
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	err = os.MkdirAll(dataDir, 0755)
+	if err != nil {
+		return 0, err
+	}
+	checkpoint, err := importCheckpointSnapshot(bufio.NewReader(f), dataDir, genesisBlock, bookkeepers)
+	if err != nil {
+		for _, name := range append(backupStoreDirs, MerkleTreeStorePath, MerkleTreeStorePath+".tmp") {
+			os.RemoveAll(filepath.Join(dataDir, name))
+		}
+		return 0, err
+	}
+	return checkpoint.Height, nil
+}
+
+func importCheckpointSnapshot(reader io.Reader, dataDir string, genesisBlock *types.Block,
+	bookkeepers []keypair.PublicKey) (*types.Checkpoint, error) {
+	hashCheckHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
+	hasher := sha256.New()
+	source := io.TeeReader(reader, hasher)
+	snap, err := readCheckpointSnapshotHeader(source, genesisBlock, bookkeepers, hashCheckHeight)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := snap.checkpoint
+	height := checkpoint.Height
+
+	merklePath := filepath.Join(dataDir, MerkleTreeStorePath)
+	merkleSize, err := serialization.ReadVarUint(source, 0)
+	if err != nil {
+		return nil, fmt.Errorf("read merkle hash file size error %s", err)
+	}
+	//the merkle hash file is renamed after the state store written, so it is not opened before consistent
+	merkleFile, err := os.Create(merklePath + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.CopyN(merkleFile, source, int64(merkleSize))
+	closeErr := merkleFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write merkle hash file error %s", err)
+	}
+
+	db, err := leveldbstore.NewLevelDBStore(filepath.Join(dataDir, DBDirState))
+	if err != nil {
+		return nil, err
+	}
+	stateStore := &StateStore{store: db}
+	err = importCheckpointState(source, stateStore)
+	if err == nil {
+		sum := hasher.Sum(nil)
+		var expected []byte
+		expected, err = serialization.ReadBytes(reader, uint64(len(sum)))
+		if err == nil && !bytes.Equal(sum, expected) {
+			err = fmt.Errorf("snapshot checksum mismatch")
+		}
+	}
+	if err == nil {
+		var contractHash common.Uint256
+		contractHash, err = checkpointContractHash(stateStore.store)
+		if err == nil && contractHash != checkpoint.ContractHash {
+			err = fmt.Errorf("contract state of snapshot is inconsistent with checkpoint, contract hash %s",
+				contractHash.ToHexString())
+		}
+	}
+	if err == nil {
+		stateStore.NewBatch()
+		stateStore.store.BatchPut(stateStore.genBlockMerkleTreeKey(), snap.blockTree)
+		if len(snap.stateTree) != 0 {
+			stateStore.store.BatchPut(stateStore.genStateMerkleTreeKey(), snap.stateTree)
+			stateStore.store.BatchPut(stateStore.genStateMerkleRootKey(height), snap.stateRoot)
+		}
+		stateStore.SaveCurrentBlock(height, checkpoint.BlockHash)
+		err = stateStore.CommitTo()
+	}
+	if err == nil {
+		err = stateStore.SaveCheckpoint(checkpoint)
+	}
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = os.Rename(merklePath+".tmp", merklePath)
+	if err != nil {
+		return nil, err
+	}
+
+	layer2Store, err := NewLayer2Store(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	err = layer2Store.SaveMsgToLayer2Store(snap.layer2State)
+	layer2Store.Close()
+	if err != nil {
+		return nil, fmt.Errorf("SaveMsgToLayer2Store error %s", err)
+	}
+
+	blockStore, err := NewBlockStore(filepath.Join(dataDir, DBDirBlock), false)
+	if err != nil {
+		return nil, err
+	}
+	blockStore.enableCompression = config.DefConfig.Common.EnableCompression
+	err = importCheckpointBlocks(blockStore, genesisBlock, snap)
+	if err == nil {
+		//the version is saved at last, the ledger without version is cleared at next start
+		err = blockStore.SaveVersion(SYSTEM_VERSION)
+	}
+	blockStore.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	//the state trees are built from the state at start, which should have the roots of checkpoint
+	ledgerStore, err := NewLedgerStore(dataDir, hashCheckHeight)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers)
+	if err == nil {
+		//the legacy account state root below the account tree height is of the accounts updated in the block, which
+		//is not rebuilt from the state
+		accountRoot, storageRoot := checkpoint.AccountStateRoot, common.UINT256_EMPTY
+		if height >= ledgerStore.accountTreeHeight {
+			accountRoot, err = ledgerStore.stateStore.GetAccountTreeRoot(height)
+		}
+		if err == nil {
+			storageRoot, err = ledgerStore.stateStore.GetStorageTreeRoot(height)
+		}
+		if err == nil && (accountRoot != checkpoint.AccountStateRoot || storageRoot != checkpoint.StorageStateRoot) {
+			err = fmt.Errorf("state roots of snapshot are inconsistent with checkpoint, account state root %s, "+
+				"storage state root %s", accountRoot.ToHexString(), storageRoot.ToHexString())
+		}
+	}
+	closeErr = ledgerStore.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+//readCheckpointSnapshotHeader read the snapshot before merkle hash file, and verify it against the checkpoint
+func readCheckpointSnapshotHeader(source io.Reader, genesisBlock *types.Block, bookkeepers []keypair.PublicKey,
+	hashCheckHeight uint32) (*checkpointSnapshot, error) {
+	magic, err := serialization.ReadBytes(source, uint64(len(checkpointSnapshotMagic)))
+	if err != nil || !bytes.Equal(magic, checkpointSnapshotMagic) {
+		return nil, fmt.Errorf("not a checkpoint snapshot")
+	}
+	version, err := serialization.ReadByte(source)
+	if err != nil {
+		return nil, err
+	}
+	if version != CHECKPOINT_SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("unsupported checkpoint snapshot version %d", version)
+	}
+	items := make([][]byte, 0, 6)
+	for i := 0; i < 6; i++ {
+		item, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return nil, fmt.Errorf("read snapshot error %s", err)
+		}
+		items = append(items, item)
+	}
+	snap := &checkpointSnapshot{
+		checkpoint:  new(types.Checkpoint),
+		layer2State: new(types.Layer2State),
+		blockTree:   items[3],
+		stateTree:   items[4],
+		stateRoot:   items[5],
+	}
+	err = snap.checkpoint.Deserialization(common.NewZeroCopySource(items[0]))
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := snap.checkpoint
+	height := checkpoint.Height
+	err = verifyCheckpoint(checkpoint, bookkeepers)
+	if err != nil {
+		return nil, fmt.Errorf("verify checkpoint signature error %s", err)
+	}
+	//the bookkeeper and contract state are not verified by the checkpoint of version 0
+	if checkpoint.Version < 1 {
+		return nil, fmt.Errorf("checkpoint version %d has no contract hash", checkpoint.Version)
+	}
+	snap.block, err = types.BlockFromRawBytes(items[1])
+	if err != nil {
+		return nil, fmt.Errorf("read block error %s", err)
+	}
+	if snap.block.Hash() != checkpoint.BlockHash || snap.block.Header.Height != height {
+		return nil, fmt.Errorf("block is inconsistent with checkpoint")
+	}
+	err = snap.layer2State.Deserialization(common.NewZeroCopySource(items[2]))
+	if err != nil {
+		return nil, err
+	}
+	if snap.layer2State.Height != height || snap.layer2State.StatesRoot != checkpoint.AccountStateRoot {
+		return nil, fmt.Errorf("layer2 state is inconsistent with checkpoint")
+	}
+	hash := snap.layer2State.Hash()
+	m := len(bookkeepers) - (len(bookkeepers)-1)/3
+	err = signature.VerifyMultiSignature(hash[:], bookkeepers, m, snap.layer2State.SigData)
+	if err != nil {
+		return nil, fmt.Errorf("verify layer2 state signature error %s", err)
+	}
+
+	treeSize, hashes, err := parseMerkleTree(snap.blockTree)
+	if err != nil {
+		return nil, fmt.Errorf("read block merkle tree error %s", err)
+	}
+	if treeSize != height+1 || merkle.NewTree(treeSize, hashes, nil).Root() != checkpoint.BlockRoot {
+		return nil, fmt.Errorf("block merkle tree is inconsistent with checkpoint")
+	}
+	if height >= hashCheckHeight {
+		treeSize, hashes, err = parseMerkleTree(snap.stateTree)
+		if err != nil {
+			return nil, fmt.Errorf("read state merkle tree error %s", err)
+		}
+		rootSource := common.NewZeroCopySource(snap.stateRoot)
+		_, eof := rootSource.NextHash()
+		root, eof := rootSource.NextHash()
+		if eof || root != checkpoint.StateRoot || treeSize != height-hashCheckHeight+1 ||
+			merkle.NewTree(treeSize, hashes, nil).Root() != checkpoint.StateRoot {
+			return nil, fmt.Errorf("state merkle tree is inconsistent with checkpoint")
+		}
+	} else if checkpoint.StateRoot != common.UINT256_EMPTY || len(snap.stateTree) != 0 {
+		return nil, fmt.Errorf("state merkle tree is inconsistent with checkpoint")
+	}
+
+	count, err := serialization.ReadUint32(source)
+	if err != nil {
+		return nil, fmt.Errorf("read block hashes error %s", err)
+	}
+	if count != height+1 {
+		return nil, fmt.Errorf("block hash count %d is inconsistent with checkpoint", count)
+	}
+	snap.blockHashes = make([]common.Uint256, 0, count)
+	for i := uint32(0); i < count; i++ {
+		data, err := serialization.ReadBytes(source, common.UINT256_SIZE)
+		if err != nil {
+			return nil, fmt.Errorf("read block hashes error %s", err)
+		}
+		hash, _ := common.Uint256ParseFromBytes(data)
+		snap.blockHashes = append(snap.blockHashes, hash)
+	}
+	if snap.blockHashes[0] != genesisBlock.Hash() || snap.blockHashes[height] != checkpoint.BlockHash {
+		return nil, fmt.Errorf("block hashes are inconsistent with checkpoint")
+	}
+	return snap, nil
+}
+
+//importCheckpointState write the state entries of snapshot to state store
+func importCheckpointState(source io.Reader, stateStore *StateStore) error {
+	stateStore.NewBatch()
+	count := 0
+	for {
+		key, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return fmt.Errorf("read state key error %s", err)
+		}
+		if len(key) == 0 {
+			break
+		}
+		valid := false
+		for _, prefix := range checkpointStatePrefixes {
+			valid = valid || key[0] == byte(prefix)
+		}
+		if !valid {
+			return fmt.Errorf("invalid state key %x in snapshot", key)
+		}
+		value, err := serialization.ReadVarBytes(source)
+		if err != nil {
+			return fmt.Errorf("read state value error %s", err)
+		}
+		stateStore.store.BatchPut(key, value)
+		count++
+		if count%BACKUP_BATCH_SIZE == 0 {
+			err = stateStore.CommitTo()
+			if err != nil {
+				return err
+			}
+			stateStore.NewBatch()
+		}
+	}
+	log.Infof("import %d state entries from checkpoint snapshot", count)
+	return stateStore.CommitTo()
+}
+
+//importCheckpointBlocks save the genesis block, the block at checkpoint and the block hashes to block store
+func importCheckpointBlocks(blockStore *BlockStore, genesisBlock *types.Block, snap *checkpointSnapshot) error {
+	height := snap.checkpoint.Height
+	blockStore.NewBatch()
+	err := blockStore.SaveBlock(genesisBlock)
+	if err != nil {
+		return fmt.Errorf("save genesis block error %s", err)
+	}
+	err = blockStore.SaveBlock(snap.block)
+	if err != nil {
+		return fmt.Errorf("save block error %s", err)
+	}
+	for h, hash := range snap.blockHashes {
+		blockStore.SaveBlockHash(uint32(h), hash)
+		if (h+1)%BACKUP_BATCH_SIZE == 0 {
+			err = blockStore.CommitTo()
+			if err != nil {
+				return err
+			}
+			blockStore.NewBatch()
+		}
+	}
+	blockStore.SaveCurrentBlock(height, snap.checkpoint.BlockHash)
+	return blockStore.CommitTo()
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/signature"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCheckpointBlock(t *testing.T, ledgerStore *LedgerStoreImp, acc *account.Account) (*types.Block,
+	*types.Layer2State) {
+	prevHeader, err := ledgerStore.GetHeaderByHash(ledgerStore.GetCurrentBlockHash())
+	assert.Nil(t, err)
+	txRoot := common.ComputeMerkleRoot(nil)
+	header := &types.Header{
+		PrevBlockHash:    prevHeader.Hash(),
+		TransactionsRoot: txRoot,
+		BlockRoot:        ledgerStore.GetBlockRootWithNewTxRoots(prevHeader.Height+1, []common.Uint256{txRoot}),
+		Timestamp:        prevHeader.Timestamp + 1,
+		Height:           prevHeader.Height + 1,
+		NextBookkeeper:   prevHeader.NextBookkeeper,
+		Bookkeepers:      []keypair.PublicKey{acc.PublicKey},
+	}
+	hash := header.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	assert.Nil(t, err)
+	header.SigData = [][]byte{sig}
+	block := &types.Block{Header: header, Transactions: []*types.Transaction{}}
+
+	result, err := ledgerStore.ExecuteBlock(block)
+	assert.Nil(t, err)
+	layer2State := &types.Layer2State{Height: header.Height, StatesRoot: result.UpdatedAccountStateRoot}
+	hash = layer2State.Hash()
+	sig, err = signature.Sign(acc, hash[:])
+	assert.Nil(t, err)
+	layer2State.SigData = [][]byte{sig}
+	assert.Nil(t, ledgerStore.SubmitBlock(block, layer2State, result))
+	return block, layer2State
+}
+
+func TestCheckpointSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(filepath.Join(dir, "src"), 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	assert.Nil(t, ledgerStore.SetLayer2Retention(0, 2))
+	snapshotDir := filepath.Join(dir, "snapshot")
+	assert.Nil(t, ledgerStore.SetCheckpointSnapshotDir(snapshotDir))
+
+	blocks := make([]*types.Block, 0)
+	layer2States := make([]*types.Layer2State, 0)
+	for height := uint32(1); height <= 4; height++ {
+		block, layer2State := newTestCheckpointBlock(t, ledgerStore, acc)
+		blocks = append(blocks, block)
+		layer2States = append(layer2States, layer2State)
+		checkpoint, err := ledgerStore.NewCheckpoint(height)
+		assert.Nil(t, err)
+		if height%2 != 0 {
+			assert.Nil(t, checkpoint)
+			continue
+		}
+		assert.Equal(t, block.Hash(), checkpoint.BlockHash)
+		assert.Equal(t, layer2State.StatesRoot, checkpoint.AccountStateRoot)
+		assert.NotNil(t, ledgerStore.SaveCheckpoint(checkpoint))
+		hash := checkpoint.Hash()
+		sig, err := signature.Sign(acc, hash[:])
+		assert.Nil(t, err)
+		checkpoint.SigData = [][]byte{sig}
+		assert.Nil(t, ledgerStore.SaveCheckpoint(checkpoint))
+		for atomic.LoadInt32(&ledgerStore.snapshotWriting) != 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if height == 2 {
+			assert.Nil(t, os.Rename(filepath.Join(snapshotDir, "checkpoint_2.snap"), filepath.Join(dir, "checkpoint_2.snap")))
+		}
+	}
+	checkpoint, err := ledgerStore.GetCheckpoint(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), checkpoint.Height)
+	_, err = os.Stat(filepath.Join(snapshotDir, "checkpoint_4.snap"))
+	assert.Nil(t, err)
+
+	//the snapshot signed by other bookkeepers or modified is rejected
+	snapshot := filepath.Join(dir, "checkpoint_2.snap")
+	other := account.NewAccount("")
+	_, err = ImportCheckpointSnapshot(snapshot, filepath.Join(dir, "other"), genesisBlock, []keypair.PublicKey{other.PublicKey})
+	assert.NotNil(t, err)
+	data, err := ioutil.ReadFile(snapshot)
+	assert.Nil(t, err)
+	data[len(data)-40] ^= 1
+	modified := filepath.Join(dir, "modified.snap")
+	assert.Nil(t, ioutil.WriteFile(modified, data, 0644))
+	_, err = ImportCheckpointSnapshot(modified, filepath.Join(dir, "modified"), genesisBlock, bookkeepers)
+	assert.NotNil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "modified", DBDirBlock))
+	assert.True(t, os.IsNotExist(err))
+
+	//the node synced at checkpoint replays the blocks after it to the same state
+	fastDir := filepath.Join(dir, "fast")
+	height, err := ImportCheckpointSnapshot(snapshot, fastDir, genesisBlock, bookkeepers)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), height)
+	_, err = ImportCheckpointSnapshot(snapshot, fastDir, genesisBlock, bookkeepers)
+	assert.NotNil(t, err)
+	fastStore, err := NewLedgerStore(fastDir, 0)
+	assert.Nil(t, err)
+	defer fastStore.Close()
+	assert.Nil(t, fastStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	assert.Equal(t, uint32(2), fastStore.GetCurrentBlockHeight())
+	checkpoint, err = fastStore.GetCheckpoint(0)
+	assert.Nil(t, err)
+	assert.Equal(t, blocks[1].Hash(), checkpoint.BlockHash)
+	for i := 2; i < len(blocks); i++ {
+		result, err := fastStore.ExecuteBlock(blocks[i])
+		assert.Nil(t, err)
+		assert.Nil(t, fastStore.SubmitBlock(blocks[i], layer2States[i], result))
+	}
+	assert.Equal(t, ledgerStore.GetCurrentBlockHash(), fastStore.GetCurrentBlockHash())
+	for _, get := range []func(*StateStore, uint32) (common.Uint256, error){(*StateStore).GetStateMerkleRoot,
+		(*StateStore).GetAccountTreeRoot, (*StateStore).GetStorageTreeRoot} {
+		expected, err := get(ledgerStore.stateStore, 4)
+		assert.Nil(t, err)
+		root, err := get(fastStore.stateStore, 4)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, root)
+	}
+	expected, err := ledgerStore.GetMerkleProof(1, 4)
+	assert.Nil(t, err)
+	proof, err := fastStore.GetMerkleProof(1, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, proof)
+
+	//the contract state which is not signed by the checkpoint is rejected, though the snapshot checksum is valid
+	checkpoint, err = ledgerStore.GetCheckpoint(4)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(types.CURR_CHECKPOINT_VERSION), checkpoint.Version)
+	contractKey := append([]byte{byte(scom.ST_CONTRACT)}, make([]byte, common.ADDR_LEN)...)
+	assert.Nil(t, ledgerStore.stateStore.store.Put(contractKey, []byte("contract")))
+	snap, err := ledgerStore.newCheckpointSnapshot(checkpoint)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "injected"), 0755))
+	injected, err := ledgerStore.writeCheckpointSnapshotFile(filepath.Join(dir, "injected"), snap)
+	snap.state.Release()
+	assert.Nil(t, err)
+	_, err = ImportCheckpointSnapshot(injected, filepath.Join(dir, "injected_ledger"), genesisBlock, bookkeepers)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "contract state of snapshot is inconsistent with checkpoint")
+	_, err = os.Stat(filepath.Join(dir, "injected_ledger", DBDirState))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ontio/layer2/node/common/log"
+)
+
+//COMPACT_CHECK_INTERVAL is the interval to check whether now is in the compaction window
+const COMPACT_CHECK_INTERVAL = time.Minute
+
+//CompactWindow is the daily local time range [Start, End) in minutes of day, End less than Start means crossing midnight
+type CompactWindow struct {
+	Start int
+	End   int
+}
+
+//ParseCompactWindow parse the window in format HH:MM-HH:MM
+func ParseCompactWindow(window string) (*CompactWindow, error) {
+	var startHour, startMin, endHour, endMin int
+	n, err := fmt.Sscanf(window, "%d:%d-%d:%d", &startHour, &startMin, &endHour, &endMin)
+	if err != nil || n != 4 {
+		return nil, fmt.Errorf("compact window %s is not in format HH:MM-HH:MM", window)
+	}
+	if startHour < 0 || startHour > 23 || endHour < 0 || endHour > 23 || startMin < 0 || startMin > 59 || endMin < 0 || endMin > 59 {
+		return nil, fmt.Errorf("compact window %s is not a valid time range", window)
+	}
+	start, end := startHour*60+startMin, endHour*60+endMin
+	if start == end {
+		return nil, fmt.Errorf("compact window %s is empty", window)
+	}
+	return &CompactWindow{Start: start, End: end}, nil
+}
+
+//Contains return whether the time is in the window
+func (this *CompactWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if this.Start < this.End {
+		return minute >= this.Start && minute < this.End
+	}
+	return minute >= this.Start || minute < this.End
+}
+
+//openDay return the date when the window containing t opened
+func (this *CompactWindow) openDay(t time.Time) string {
+	if this.Start > this.End && t.Hour()*60+t.Minute() < this.End {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t.Format("2006-01-02")
+}
+
+func (this *CompactWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", this.Start/60, this.Start%60, this.End/60, this.End%60)
+}
+
+//CompactStores compact all store databases to discard the deleted and overwritten data, concurrent call waits
+//for the running compaction
+func (this *LedgerStoreImp) CompactStores() error {
+	this.compactLock.Lock()
+	defer this.compactLock.Unlock()
+	if this.compactClosed {
+		return fmt.Errorf("ledger is closing")
+	}
+	start := time.Now()
+	stores := []struct {
+		name    string
+		compact func() error
+	}{
+		{"block", this.blockStore.Compact},
+		{"state", this.stateStore.Compact},
+		{"event", this.eventStore.Compact},
+		{"layer2", this.layer2Store.Compact},
+	}
+	for _, store := range stores {
+		if err := store.compact(); err != nil {
+			return fmt.Errorf("%s store compact error %s", store.name, err)
+		}
+	}
+	log.Infof("compact stores cost %s", time.Since(start))
+	return nil
+}
+
+//startCompactScheduler compact the stores once a day in the window
+func (this *LedgerStoreImp) startCompactScheduler(window *CompactWindow) {
+	this.compactExit = make(chan struct{})
+	go this.compactLoop(window, this.compactExit)
+	log.Infof("compact stores in window %s", window)
+}
+
+func (this *LedgerStoreImp) compactLoop(window *CompactWindow, exit chan struct{}) {
+	ticker := time.NewTicker(COMPACT_CHECK_INTERVAL)
+	defer ticker.Stop()
+	lastDay := ""
+	for {
+		select {
+		case <-exit:
+			return
+		case now := <-ticker.C:
+			if !window.Contains(now) {
+				continue
+			}
+			day := window.openDay(now)
+			if day == lastDay {
+				continue
+			}
+			lastDay = day
+			if err := this.CompactStores(); err != nil {
+				log.Errorf("scheduled compaction error %s", err)
+			}
+		}
+	}
+}
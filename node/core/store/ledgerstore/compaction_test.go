@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactWindow(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2020, time.May, 2, hour, min, 0, 0, time.Local)
+	}
+	window, err := ParseCompactWindow("02:00-04:30")
+	assert.Nil(t, err)
+	assert.Equal(t, "02:00-04:30", window.String())
+	assert.False(t, window.Contains(at(1, 59)))
+	assert.True(t, window.Contains(at(2, 0)))
+	assert.True(t, window.Contains(at(4, 29)))
+	assert.False(t, window.Contains(at(4, 30)))
+
+	window, err = ParseCompactWindow("23:00-01:00")
+	assert.Nil(t, err)
+	assert.True(t, window.Contains(at(23, 30)))
+	assert.True(t, window.Contains(at(0, 30)))
+	assert.False(t, window.Contains(at(1, 0)))
+	assert.Equal(t, window.openDay(at(23, 30)), window.openDay(at(0, 30).AddDate(0, 0, 1)))
+
+	for _, s := range []string{"", "2:00", "02:00-02:00", "24:00-01:00", "01:60-02:00"} {
+		_, err = ParseCompactWindow(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestCompactStores(t *testing.T) {
+	newStore := func() *leveldbstore.LevelDBStore {
+		store, err := leveldbstore.NewMemLevelDBStore()
+		assert.Nil(t, err)
+		return store
+	}
+	blockStore := &BlockStore{store: newStore()}
+	ledgerStore := &LedgerStoreImp{
+		blockStore:  blockStore,
+		stateStore:  &StateStore{store: newStore()},
+		eventStore:  &EventStore{store: newStore()},
+		layer2Store: &Layer2Store{store: newStore()},
+	}
+	for i := byte(0); i < 100; i++ {
+		assert.Nil(t, blockStore.store.Put([]byte{i}, []byte{i}))
+		assert.Nil(t, blockStore.store.Delete([]byte{i}))
+	}
+	assert.Nil(t, ledgerStore.CompactStores())
+	_, err := blockStore.store.Get([]byte{1})
+	assert.NotNil(t, err)
+
+	ledgerStore.compactClosed = true
+	assert.NotNil(t, ledgerStore.CompactStores())
+}
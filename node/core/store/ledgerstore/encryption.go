@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+)
+
+const (
+	REENCRYPT_BATCH_SIZE = 1000                  //Count of values scanned by re-encryption in one write lock
+	REENCRYPT_INTERVAL   = 10 * time.Millisecond //Pause between re-encryption batches, so block saving is not stalled
+)
+
+//storeCipher return the value cipher of the key file in config, nil if encryption is disabled
+func storeCipher() (*leveldbstore.ValueCipher, error) {
+	file := config.DefConfig.Common.EncryptKeyFile
+	if file == "" {
+		return nil, nil
+	}
+	cipher, err := leveldbstore.LoadValueCipher(file)
+	if err != nil {
+		return nil, fmt.Errorf("LoadValueCipher error %s", err)
+	}
+	return cipher, nil
+}
+
+//openLevelDBStore open the leveldb of block, state or event store, the values are encrypted if the key file is set
+func openLevelDBStore(dbDir string) (*leveldbstore.LevelDBStore, error) {
+	cipher, err := storeCipher()
+	if err != nil {
+		return nil, err
+	}
+	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	if err != nil {
+		return nil, err
+	}
+	store.SetCipher(cipher)
+	return store, nil
+}
+
+//startReEncryption re-encrypt the values of block, state and event store which are plaintext or encrypted by retired
+//keys in background, so the retired keys can be removed from the key file after it finished. Each store is recorded as
+//encrypted after its pass, and the plaintext values read from it are rejected then
+func (this *LedgerStoreImp) startReEncryption() {
+	if this.blockStore.store.Cipher() == nil {
+		return
+	}
+	this.reEncryptExit = make(chan struct{})
+	go this.reEncryptLoop(this.reEncryptExit)
+}
+
+func (this *LedgerStoreImp) reEncryptLoop(exit chan struct{}) {
+	stores := []struct {
+		name  string
+		store *leveldbstore.LevelDBStore
+	}{
+		{"block", this.blockStore.store},
+		{"state", this.stateStore.store.(*leveldbstore.LevelDBStore)},
+		{"event", this.eventStore.store},
+	}
+	for _, s := range stores {
+		if !this.reEncryptStore(s.name, s.store, exit) {
+			return
+		}
+	}
+	log.Infof("values of block, state and event store are encrypted by key %d",
+		this.blockStore.store.Cipher().ActiveKeyId())
+}
+
+//reEncryptStore return false if stopped by exit or error
+func (this *LedgerStoreImp) reEncryptStore(name string, store *leveldbstore.LevelDBStore, exit chan struct{}) bool {
+	start := time.Now()
+	var key []byte
+	total := 0
+	for {
+		//keep close waiting until the batch finished
+		this.compactLock.Lock()
+		if this.compactClosed {
+			this.compactLock.Unlock()
+			return false
+		}
+		next, count, err := store.ReEncrypt(key, REENCRYPT_BATCH_SIZE)
+		this.compactLock.Unlock()
+		if err != nil {
+			log.Errorf("re-encrypt %s store error %s", name, err)
+			return false
+		}
+		total += count
+		if next == nil {
+			break
+		}
+		key = next
+		select {
+		case <-exit:
+			return false
+		case <-time.After(REENCRYPT_INTERVAL):
+		}
+	}
+	if total > 0 {
+		log.Infof("re-encrypt %d values of %s store cost %s", total, name, time.Since(start))
+	}
+	//the values written during the pass are encrypted by the active key, so no plaintext value is left
+	this.compactLock.Lock()
+	defer this.compactLock.Unlock()
+	if this.compactClosed {
+		return false
+	}
+	if err := store.MarkEncrypted(); err != nil {
+		log.Errorf("mark %s store encrypted error %s", name, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+)
+
+//EventTopic return the topic of notify, which is the first state of event or the state itself if it is string
+func EventTopic(states interface{}) (string, bool) {
+	switch v := states.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if len(v) == 0 {
+			return "", false
+		}
+		topic, ok := v[0].(string)
+		return topic, ok
+	}
+	return "", false
+}
+
+//CreateEventBloom return the bloom of all contract address and (contract address, topic) in notifies
+func CreateEventBloom(notifies []*event.ExecuteNotify) types.Bloom {
+	var bloom types.Bloom
+	for _, notify := range notifies {
+		for _, evt := range notify.Notify {
+			bloom.Add(evt.ContractAddress[:])
+			if topic, ok := EventTopic(evt.States); ok {
+				bloom.Add(eventBloomItem(evt.ContractAddress, topic))
+			}
+		}
+	}
+	return bloom
+}
+
+//MatchEventBloom return false if no event of contract with topic in block, empty topic match all topics
+func MatchEventBloom(bloom *types.Bloom, contract common.Address, topic string) bool {
+	if topic == "" {
+		return bloom.Test(contract[:])
+	}
+	return bloom.Test(eventBloomItem(contract, topic))
+}
+
+//FilterEventNotify return the notify only with events of contract with topic, empty topic match all topics.
+//nil is returned if no event matched
+func FilterEventNotify(notify *event.ExecuteNotify, contract common.Address, topic string) *event.ExecuteNotify {
+	evts := make([]*event.NotifyEventInfo, 0)
+	for _, evt := range notify.Notify {
+		if evt.ContractAddress != contract {
+			continue
+		}
+		if evtTopic, _ := EventTopic(evt.States); topic != "" && evtTopic != topic {
+			continue
+		}
+		evts = append(evts, evt)
+	}
+	if len(evts) == 0 {
+		return nil
+	}
+	filtered := *notify
+	filtered.Notify = evts
+	return &filtered
+}
+
+//EventAddresses return the account addresses in the states of event, native contracts notify base58 address and
+//neovm contracts notify the hex string of address bytes
+func EventAddresses(states interface{}) []common.Address {
+	addrs := make([]common.Address, 0)
+	switch v := states.(type) {
+	case string:
+		if addr, err := common.AddressFromBase58(v); err == nil {
+			addrs = append(addrs, addr)
+		} else if len(v) == 2*common.ADDR_LEN {
+			if data, err := hex.DecodeString(v); err == nil {
+				addr, _ := common.AddressParseFromBytes(data)
+				addrs = append(addrs, addr)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			addrs = append(addrs, EventAddresses(item)...)
+		}
+	}
+	return addrs
+}
+
+//FilterEventNotifyByAddress return the notify only with events which have address in states, nil is returned if no event matched
+func FilterEventNotifyByAddress(notify *event.ExecuteNotify, addr common.Address) *event.ExecuteNotify {
+	evts := make([]*event.NotifyEventInfo, 0)
+	for _, evt := range notify.Notify {
+		for _, evtAddr := range EventAddresses(evt.States) {
+			if evtAddr == addr {
+				evts = append(evts, evt)
+				break
+			}
+		}
+	}
+	if len(evts) == 0 {
+		return nil
+	}
+	filtered := *notify
+	filtered.Notify = evts
+	return &filtered
+}
+
+func eventBloomItem(contract common.Address, topic string) []byte {
+	item := make([]byte, 0, len(contract)+len(topic))
+	item = append(item, contract[:]...)
+	return append(item, topic...)
+}
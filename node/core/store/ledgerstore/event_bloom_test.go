@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBloom(t *testing.T) {
+	contract := common.AddressFromVmCode([]byte("contract"))
+	other := common.AddressFromVmCode([]byte("other"))
+	notifies := []*event.ExecuteNotify{
+		{
+			Notify: []*event.NotifyEventInfo{
+				{ContractAddress: contract, States: []interface{}{"transfer", "from", "to"}},
+			},
+		},
+	}
+	bloom := CreateEventBloom(notifies)
+	assert.True(t, MatchEventBloom(&bloom, contract, ""))
+	assert.True(t, MatchEventBloom(&bloom, contract, "transfer"))
+	assert.False(t, MatchEventBloom(&bloom, other, ""))
+	assert.False(t, MatchEventBloom(&bloom, contract, "approve"))
+
+	data, err := types.BytesToBloom(bloom[:])
+	assert.Nil(t, err)
+	assert.Equal(t, bloom, data)
+	_, err = types.BytesToBloom(bloom[1:])
+	assert.NotNil(t, err)
+}
@@ -21,6 +21,7 @@ package ledgerstore
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/ontio/layer2/node/common"
@@ -28,18 +29,20 @@ import (
 	"github.com/ontio/layer2/node/common/serialization"
 	scom "github.com/ontio/layer2/node/core/store/common"
 	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/types"
 	"github.com/ontio/layer2/node/smartcontract/event"
 )
 
 //Saving event notifies gen by smart contract execution
 type EventStore struct {
-	dbDir string                     //Store path
-	store *leveldbstore.LevelDBStore //Store handler
+	dbDir             string                     //Store path
+	store             *leveldbstore.LevelDBStore //Store handler
+	enableCompression bool                       //Is enable the compression of event notify
 }
 
 //NewEventStore return event store instance
 func NewEventStore(dbDir string) (*EventStore, error) {
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := openLevelDBStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +64,9 @@ func (this *EventStore) SaveEventNotifyByTx(txHash common.Uint256, notify *event
 		return fmt.Errorf("json.Marshal error %s", err)
 	}
 	key := genEventNotifyByTxKey(txHash)
+	if this.enableCompression {
+		result = scom.CompressValue(result)
+	}
 	this.store.BatchPut(key, result)
 	return nil
 }
@@ -83,6 +89,10 @@ func (this *EventStore) GetEventNotifyByTx(txHash common.Uint256) (*event.Execut
 	if err != nil {
 		return nil, err
 	}
+	data, err = scom.DecompressValue(data)
+	if err != nil {
+		return nil, err
+	}
 	var notify event.ExecuteNotify
 	if err = json.Unmarshal(data, &notify); err != nil {
 		return nil, fmt.Errorf("json.Unmarshal error %s", err)
@@ -92,6 +102,15 @@ func (this *EventStore) GetEventNotifyByTx(txHash common.Uint256) (*event.Execut
 
 //GetEventNotifyByBlock return all event notify of transaction in block
 func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
+	txHashes, err := this.GetEventNotifyTxsByBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	return this.getEventNotifyByTxs(height, txHashes), nil
+}
+
+//GetEventNotifyTxsByBlock return the transaction hash which have event notify in block, in execution order
+func (this *EventStore) GetEventNotifyTxsByBlock(height uint32) ([]common.Uint256, error) {
 	key := genEventNotifyByBlockKey(height)
 	data, err := this.store.Get(key)
 	if err != nil {
@@ -102,13 +121,39 @@ func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNo
 	if err != nil {
 		return nil, fmt.Errorf("ReadUint32 error %s", err)
 	}
-	evtNotifies := make([]*event.ExecuteNotify, 0)
+	txHashes := make([]common.Uint256, 0, size)
 	for i := uint32(0); i < size; i++ {
 		var txHash common.Uint256
 		err = txHash.Deserialize(reader)
 		if err != nil {
 			return nil, fmt.Errorf("txHash.Deserialize error %s", err)
 		}
+		txHashes = append(txHashes, txHash)
+	}
+	return txHashes, nil
+}
+
+//GetEventNotifyByBlockPage return at most limit event notify of block from the offset of transactions which have event notify,
+//total is the count of these transactions, limit 0 means no limit
+func (this *EventStore) GetEventNotifyByBlockPage(height uint32, offset, limit uint32) ([]*event.ExecuteNotify, uint32, error) {
+	txHashes, err := this.GetEventNotifyTxsByBlock(height)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := uint32(len(txHashes))
+	if offset >= total {
+		return []*event.ExecuteNotify{}, total, nil
+	}
+	end := total
+	if limit != 0 && limit < total-offset {
+		end = offset + limit
+	}
+	return this.getEventNotifyByTxs(height, txHashes[offset:end]), total, nil
+}
+
+func (this *EventStore) getEventNotifyByTxs(height uint32, txHashes []common.Uint256) []*event.ExecuteNotify {
+	evtNotifies := make([]*event.ExecuteNotify, 0, len(txHashes))
+	for _, txHash := range txHashes {
 		evtNotify, err := this.GetEventNotifyByTx(txHash)
 		if err != nil {
 			log.Errorf("getEventNotifyByTx Height:%d by txhash:%s error:%s", height, txHash.ToHexString(), err)
@@ -116,7 +161,167 @@ func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNo
 		}
 		evtNotifies = append(evtNotifies, evtNotify)
 	}
-	return evtNotifies, nil
+	return evtNotifies
+}
+
+//SaveEventBloom persist the event bloom filter of block
+func (this *EventStore) SaveEventBloom(height uint32, bloom types.Bloom) {
+	key := genEventBloomKey(height)
+	this.store.BatchPut(key, bloom[:])
+}
+
+//GetEventBloom return the event bloom filter of block
+func (this *EventStore) GetEventBloom(height uint32) (*types.Bloom, error) {
+	key := genEventBloomKey(height)
+	data, err := this.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	bloom, err := types.BytesToBloom(data)
+	if err != nil {
+		return nil, err
+	}
+	return &bloom, nil
+}
+
+//SaveReceipt persist the receipt of transaction
+func (this *EventStore) SaveReceipt(receipt *types.Receipt) {
+	sink := common.NewZeroCopySink(nil)
+	receipt.Serialization(sink)
+	this.store.BatchPut(genReceiptKey(receipt.TxHash), sink.Bytes())
+}
+
+//GetReceipt return the receipt of transaction
+func (this *EventStore) GetReceipt(txHash common.Uint256) (*types.Receipt, error) {
+	data, err := this.store.Get(genReceiptKey(txHash))
+	if err != nil {
+		return nil, err
+	}
+	receipt := new(types.Receipt)
+	err = receipt.Deserialization(common.NewZeroCopySource(data))
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+//SaveContractEventIndex persist the index of event notify by contract address
+func (this *EventStore) SaveContractEventIndex(height uint32, notifies []*event.ExecuteNotify) {
+	for _, notify := range notifies {
+		contracts := make(map[common.Address]bool)
+		for _, evt := range notify.Notify {
+			if contracts[evt.ContractAddress] {
+				continue
+			}
+			contracts[evt.ContractAddress] = true
+			this.store.BatchPut(genContractEventIndexKey(evt.ContractAddress, height, notify.TxHash), nil)
+		}
+	}
+}
+
+//SaveAddressEventIndex persist the index of event notify by the account addresses in event states
+func (this *EventStore) SaveAddressEventIndex(height uint32, notifies []*event.ExecuteNotify) {
+	for _, notify := range notifies {
+		addrs := make(map[common.Address]bool)
+		for _, evt := range notify.Notify {
+			for _, addr := range EventAddresses(evt.States) {
+				if addrs[addr] {
+					continue
+				}
+				addrs[addr] = true
+				this.store.BatchPut(genAddressEventIndexKey(addr, height, notify.TxHash), nil)
+			}
+		}
+	}
+}
+
+//GetEventNotifyTxsByContract return the transaction hash and height which have event notify of contract in [fromHeight, toHeight]
+func (this *EventStore) GetEventNotifyTxsByContract(contract common.Address, fromHeight, toHeight uint32) ([]uint32, []common.Uint256, error) {
+	return this.getEventNotifyTxsByIndex(scom.EVENT_CONTRACT, contract, fromHeight, toHeight, nil, 0)
+}
+
+//GetEventNotifyTxsByAddress return at most limit transaction hash and height which have event notify of address in [fromHeight, toHeight],
+//limit 0 means no limit
+func (this *EventStore) GetEventNotifyTxsByAddress(addr common.Address, fromHeight, toHeight uint32, limit uint32) ([]uint32, []common.Uint256, error) {
+	return this.getEventNotifyTxsByIndex(scom.EVENT_ADDRESS, addr, fromHeight, toHeight, nil, limit)
+}
+
+//GetEventNotifyTxsByContractAfter is GetEventNotifyTxsByContract which start after the cursor and return at most limit transactions,
+//nil cursor start from fromHeight and limit 0 means no limit
+func (this *EventStore) GetEventNotifyTxsByContractAfter(contract common.Address, fromHeight, toHeight uint32, after *EventIndexCursor, limit uint32) ([]uint32, []common.Uint256, error) {
+	return this.getEventNotifyTxsByIndex(scom.EVENT_CONTRACT, contract, fromHeight, toHeight, after, limit)
+}
+
+//GetEventNotifyTxsByAddressAfter is GetEventNotifyTxsByAddress which start after the cursor
+func (this *EventStore) GetEventNotifyTxsByAddressAfter(addr common.Address, fromHeight, toHeight uint32, after *EventIndexCursor, limit uint32) ([]uint32, []common.Uint256, error) {
+	return this.getEventNotifyTxsByIndex(scom.EVENT_ADDRESS, addr, fromHeight, toHeight, after, limit)
+}
+
+func (this *EventStore) getEventNotifyTxsByIndex(prefix scom.DataEntryPrefix, addr common.Address, fromHeight, toHeight uint32,
+	after *EventIndexCursor, limit uint32) ([]uint32, []common.Uint256, error) {
+	start := genEventIndexKey(prefix, addr, fromHeight, common.UINT256_EMPTY)
+	if after != nil && after.Height >= fromHeight {
+		// smallest key larger than the cursor
+		start = append(genEventIndexKey(prefix, addr, after.Height, after.TxHash), 0)
+	}
+	var maxHash common.Uint256
+	for i := range maxHash {
+		maxHash[i] = 0xff
+	}
+	// larger than all keys of toHeight
+	end := append(genEventIndexKey(prefix, addr, toHeight, maxHash), 0)
+	iter := this.store.NewRangeIterator(start, end)
+	defer iter.Release()
+	heights := make([]uint32, 0)
+	txHashes := make([]common.Uint256, 0)
+	prefixLen := 1 + common.ADDR_LEN
+	for iter.Next() {
+		if limit != 0 && uint32(len(txHashes)) >= limit {
+			break
+		}
+		key := iter.Key()
+		if len(key) != prefixLen+4+common.UINT256_SIZE {
+			continue
+		}
+		txHash, err := common.Uint256ParseFromBytes(key[prefixLen+4:])
+		if err != nil {
+			return nil, nil, err
+		}
+		heights = append(heights, binary.BigEndian.Uint32(key[prefixLen:prefixLen+4]))
+		txHashes = append(txHashes, txHash)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+	return heights, txHashes, nil
+}
+
+//EventIndexCursor is the position of an entry of event index, used as continuation token of paged event query
+type EventIndexCursor struct {
+	Height uint32
+	TxHash common.Uint256
+}
+
+//String return the hex continuation token of cursor
+func (this *EventIndexCursor) String() string {
+	data := make([]byte, 4, 4+common.UINT256_SIZE)
+	binary.BigEndian.PutUint32(data, this.Height)
+	data = append(data, this.TxHash[:]...)
+	return hex.EncodeToString(data)
+}
+
+//ParseEventIndexCursor parse the continuation token, empty token return nil cursor
+func ParseEventIndexCursor(token string) (*EventIndexCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := hex.DecodeString(token)
+	if err != nil || len(data) != 4+common.UINT256_SIZE {
+		return nil, scom.ErrInvalidCursor
+	}
+	cursor := &EventIndexCursor{Height: binary.BigEndian.Uint32(data[:4])}
+	copy(cursor.TxHash[:], data[4:])
+	return cursor, nil
 }
 
 //CommitTo event store batch to store
@@ -129,6 +334,11 @@ func (this *EventStore) Close() error {
 	return this.store.Close()
 }
 
+//Compact event store
+func (this *EventStore) Compact() error {
+	return this.store.Compact()
+}
+
 //ClearAll all data in event store
 func (this *EventStore) ClearAll() error {
 	this.NewBatch()
@@ -183,6 +393,39 @@ func genEventNotifyByBlockKey(height uint32) []byte {
 	return key
 }
 
+func genEventBloomKey(height uint32) []byte {
+	key := make([]byte, 5, 5)
+	key[0] = byte(scom.EVENT_BLOOM)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+func genReceiptKey(txHash common.Uint256) []byte {
+	key := make([]byte, 1+common.UINT256_SIZE)
+	key[0] = byte(scom.EVENT_RECEIPT)
+	copy(key[1:], txHash[:])
+	return key
+}
+
+func genContractEventIndexKey(contract common.Address, height uint32, txHash common.Uint256) []byte {
+	return genEventIndexKey(scom.EVENT_CONTRACT, contract, height, txHash)
+}
+
+func genAddressEventIndexKey(addr common.Address, height uint32, txHash common.Uint256) []byte {
+	return genEventIndexKey(scom.EVENT_ADDRESS, addr, height, txHash)
+}
+
+//height is big endian to keep the index in height order
+func genEventIndexKey(prefix scom.DataEntryPrefix, addr common.Address, height uint32, txHash common.Uint256) []byte {
+	key := make([]byte, 0, 1+common.ADDR_LEN+4+common.UINT256_SIZE)
+	key = append(key, byte(prefix))
+	key = append(key, addr[:]...)
+	var h [4]byte
+	binary.BigEndian.PutUint32(h[:], height)
+	key = append(key, h[:]...)
+	return append(key, txHash[:]...)
+}
+
 func genEventNotifyByTxKey(txHash common.Uint256) []byte {
 	data := txHash.ToArray()
 	key := make([]byte, 1+len(data))
@@ -0,0 +1,199 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractEventIndex(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	eventStore := &EventStore{store: store}
+
+	contract := common.AddressFromVmCode([]byte("contract"))
+	other := common.AddressFromVmCode([]byte("other"))
+	newNotify := func(height uint32, addrs ...common.Address) *event.ExecuteNotify {
+		notify := &event.ExecuteNotify{TxHash: common.Uint256{byte(height)}}
+		for _, addr := range addrs {
+			notify.Notify = append(notify.Notify, &event.NotifyEventInfo{ContractAddress: addr})
+		}
+		return notify
+	}
+	eventStore.NewBatch()
+	eventStore.SaveContractEventIndex(1, []*event.ExecuteNotify{newNotify(1, contract, contract)})
+	eventStore.SaveContractEventIndex(2, []*event.ExecuteNotify{newNotify(2, other)})
+	eventStore.SaveContractEventIndex(3, []*event.ExecuteNotify{newNotify(3, other, contract)})
+	eventStore.SaveContractEventIndex(math.MaxUint32, []*event.ExecuteNotify{newNotify(4, contract)})
+	assert.Nil(t, eventStore.CommitTo())
+
+	heights, txHashes, err := eventStore.GetEventNotifyTxsByContract(contract, 0, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 3}, heights)
+	assert.Equal(t, []common.Uint256{{1}, {3}}, txHashes)
+
+	heights, _, err = eventStore.GetEventNotifyTxsByContract(contract, 2, math.MaxUint32)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{3, math.MaxUint32}, heights)
+
+	heights, _, err = eventStore.GetEventNotifyTxsByContract(other, 3, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{3}, heights)
+}
+
+func TestAddressEventIndex(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	eventStore := &EventStore{store: store}
+
+	alice := common.AddressFromVmCode([]byte("alice"))
+	bob := common.AddressFromVmCode([]byte("bob"))
+	contract := common.AddressFromVmCode([]byte("contract"))
+	newNotify := func(txHash byte, states interface{}) *event.ExecuteNotify {
+		return &event.ExecuteNotify{
+			TxHash: common.Uint256{txHash},
+			Notify: []*event.NotifyEventInfo{{ContractAddress: contract, States: states}},
+		}
+	}
+	// native transfer notify base58 address, neovm notify hex address
+	eventStore.NewBatch()
+	eventStore.SaveAddressEventIndex(1, []*event.ExecuteNotify{
+		newNotify(1, []interface{}{"transfer", alice.ToBase58(), bob.ToBase58(), uint64(100)}),
+	})
+	eventStore.SaveAddressEventIndex(2, []*event.ExecuteNotify{
+		newNotify(2, []interface{}{"7472616e73666572", hex.EncodeToString(bob[:]), "00"}),
+		newNotify(3, "log"),
+	})
+	eventStore.SaveAddressEventIndex(3, []*event.ExecuteNotify{
+		newNotify(4, []interface{}{"transfer", alice.ToBase58(), alice.ToBase58(), uint64(1)}),
+	})
+	assert.Nil(t, eventStore.CommitTo())
+
+	heights, txHashes, err := eventStore.GetEventNotifyTxsByAddress(bob, 0, 10, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 2}, heights)
+	assert.Equal(t, []common.Uint256{{1}, {2}}, txHashes)
+
+	heights, _, err = eventStore.GetEventNotifyTxsByAddress(alice, 0, 10, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 3}, heights)
+
+	heights, _, err = eventStore.GetEventNotifyTxsByAddress(alice, 0, 10, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1}, heights)
+
+	heights, _, err = eventStore.GetEventNotifyTxsByAddress(bob, 2, 3, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{2}, heights)
+
+	filtered := FilterEventNotifyByAddress(newNotify(5, []interface{}{"transfer", alice.ToBase58()}), bob)
+	assert.Nil(t, filtered)
+}
+
+func TestEventNotifyPage(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	eventStore := &EventStore{store: store}
+	ledgerStore := &LedgerStoreImp{eventStore: eventStore}
+
+	contract := common.AddressFromVmCode([]byte("contract"))
+	eventStore.NewBatch()
+	notifies := make([]*event.ExecuteNotify, 0)
+	txHashes := make([]common.Uint256, 0)
+	for i := byte(1); i <= 5; i++ {
+		notify := &event.ExecuteNotify{
+			TxHash: common.Uint256{i},
+			Notify: []*event.NotifyEventInfo{{ContractAddress: contract, States: "log"}},
+		}
+		assert.Nil(t, eventStore.SaveEventNotifyByTx(notify.TxHash, notify))
+		notifies = append(notifies, notify)
+		txHashes = append(txHashes, notify.TxHash)
+	}
+	eventStore.SaveEventNotifyByBlock(1, txHashes[:3])
+	eventStore.SaveContractEventIndex(1, notifies[:3])
+	eventStore.SaveEventNotifyByBlock(2, txHashes[3:])
+	eventStore.SaveContractEventIndex(2, notifies[3:])
+	assert.Nil(t, eventStore.CommitTo())
+
+	page, err := ledgerStore.GetEventNotifyByBlockPage(1, "", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(page.Notifies))
+	assert.Equal(t, "2", page.Next)
+	page, err = ledgerStore.GetEventNotifyByBlockPage(1, page.Next, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, common.Uint256{3}, page.Notifies[0].TxHash)
+	assert.Equal(t, "", page.Next)
+	_, err = ledgerStore.GetEventNotifyByBlockPage(1, "x", 2)
+	assert.Equal(t, scom.ErrInvalidCursor, err)
+
+	result := make([]common.Uint256, 0)
+	cursor := ""
+	for i := 0; ; i++ {
+		page, err := ledgerStore.GetEventNotifyByContractPage(contract, 0, 10, cursor, 2)
+		assert.Nil(t, err)
+		for _, notify := range page.Notifies {
+			result = append(result, notify.TxHash)
+		}
+		if page.Next == "" {
+			assert.Equal(t, 2, i)
+			break
+		}
+		cursor = page.Next
+	}
+	assert.Equal(t, txHashes, result)
+
+	page, err = ledgerStore.GetEventNotifyByContractPage(contract, 0, 10, "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(page.Notifies))
+	assert.Equal(t, "", page.Next)
+	_, err = ledgerStore.GetEventNotifyByContractPage(contract, 0, 10, "00", 2)
+	assert.Equal(t, scom.ErrInvalidCursor, err)
+}
+
+func TestEventNotifyCompression(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	eventStore := &EventStore{store: store}
+
+	contract := common.AddressFromVmCode([]byte("contract"))
+	legacy := &event.ExecuteNotify{TxHash: common.Uint256{1}, State: event.CONTRACT_STATE_SUCCESS,
+		Notify: []*event.NotifyEventInfo{{ContractAddress: contract, States: "legacy"}}}
+	compressed := &event.ExecuteNotify{TxHash: common.Uint256{2}, State: event.CONTRACT_STATE_SUCCESS,
+		Notify: []*event.NotifyEventInfo{{ContractAddress: contract, States: "compressed"}}}
+	eventStore.NewBatch()
+	assert.Nil(t, eventStore.SaveEventNotifyByTx(legacy.TxHash, legacy))
+	eventStore.enableCompression = true
+	assert.Nil(t, eventStore.SaveEventNotifyByTx(compressed.TxHash, compressed))
+	assert.Nil(t, eventStore.CommitTo())
+
+	notify, err := eventStore.GetEventNotifyByTx(legacy.TxHash)
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy", notify.Notify[0].States)
+	notify, err = eventStore.GetEventNotifyByTx(compressed.TxHash)
+	assert.Nil(t, err)
+	assert.Equal(t, "compressed", notify.Notify[0].States)
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/smartcontract/event"
+)
+
+const EVENT_SUBSCRIPTION_BUFFER = 16 //Count of blocks buffered in the channel of event subscription
+
+// eventSubscriber read the events of blocks from event store and send them to subscriber, it waits for the next block
+// after the current block sent, so a slow subscriber does not block saving block
+type eventSubscriber struct {
+	id        uint64
+	ledger    *LedgerStoreImp
+	contracts []common.Address
+	next      uint32
+	events    chan *store.BlockEvents
+	wake      chan struct{}
+	quit      chan struct{}
+	quitOnce  sync.Once
+	done      chan struct{}
+	err       error
+}
+
+// SubscribeEvents stream the event notifies of blocks from fromHeight, the saved blocks are replayed first and then the
+// new blocks after they are committed. Only the events of contracts are streamed if contracts is not empty
+func (this *LedgerStoreImp) SubscribeEvents(fromHeight uint32, contracts []common.Address) (store.EventSubscription, error) {
+	if current := this.GetCurrentBlockHeight(); fromHeight > current+1 {
+		return nil, fmt.Errorf("from height %d is larger than next block height %d", fromHeight, current+1)
+	}
+	this.eventSubLock.Lock()
+	defer this.eventSubLock.Unlock()
+	if this.eventSubClosed {
+		return nil, fmt.Errorf("ledger is closed")
+	}
+	if this.eventSubs == nil {
+		this.eventSubs = make(map[uint64]*eventSubscriber)
+	}
+	this.eventSubNextId++
+	sub := &eventSubscriber{
+		id:        this.eventSubNextId,
+		ledger:    this,
+		contracts: contracts,
+		next:      fromHeight,
+		events:    make(chan *store.BlockEvents, EVENT_SUBSCRIPTION_BUFFER),
+		wake:      make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	this.eventSubs[sub.id] = sub
+	go sub.run()
+	return sub, nil
+}
+
+// notifyEventSubscribers wake the subscribers waiting for new block
+func (this *LedgerStoreImp) notifyEventSubscribers() {
+	this.eventSubLock.Lock()
+	defer this.eventSubLock.Unlock()
+	for _, sub := range this.eventSubs {
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// closeEventSubscribers stop all subscribers before the stores closed
+func (this *LedgerStoreImp) closeEventSubscribers() {
+	this.eventSubLock.Lock()
+	subs := this.eventSubs
+	this.eventSubs = nil
+	this.eventSubClosed = true
+	this.eventSubLock.Unlock()
+	for _, sub := range subs {
+		sub.stop()
+	}
+}
+
+// getBlockEvents return the event notifies of block, filtered by contracts if not empty
+func (this *LedgerStoreImp) getBlockEvents(height uint32, contracts []common.Address) (*store.BlockEvents, error) {
+	result := &store.BlockEvents{Height: height, Notifies: make([]*event.ExecuteNotify, 0)}
+	if len(contracts) > 0 {
+		bloom, err := this.eventStore.GetEventBloom(height)
+		if err != nil && err != scom.ErrNotFound {
+			return nil, err
+		}
+		if bloom != nil {
+			matched := false
+			for _, contract := range contracts {
+				if MatchEventBloom(bloom, contract, "") {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return result, nil
+			}
+		}
+	}
+	notifies, err := this.eventStore.GetEventNotifyByBlock(height)
+	if err == scom.ErrNotFound {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(contracts) == 0 {
+		result.Notifies = notifies
+		return result, nil
+	}
+	for _, notify := range notifies {
+		evts := make([]*event.NotifyEventInfo, 0)
+		for _, evt := range notify.Notify {
+			for _, contract := range contracts {
+				if evt.ContractAddress == contract {
+					evts = append(evts, evt)
+					break
+				}
+			}
+		}
+		if len(evts) > 0 {
+			filtered := *notify
+			filtered.Notify = evts
+			result.Notifies = append(result.Notifies, &filtered)
+		}
+	}
+	return result, nil
+}
+
+func (this *eventSubscriber) run() {
+	defer close(this.done)
+	defer close(this.events)
+	for {
+		for this.next <= this.ledger.GetCurrentBlockHeight() {
+			evts, err := this.ledger.getBlockEvents(this.next, this.contracts)
+			if err != nil {
+				this.err = fmt.Errorf("get events of height %d error %s", this.next, err)
+				return
+			}
+			select {
+			case this.events <- evts:
+			case <-this.quit:
+				return
+			}
+			this.next++
+		}
+		select {
+		case <-this.wake:
+		case <-this.quit:
+			return
+		}
+	}
+}
+
+func (this *eventSubscriber) stop() {
+	this.quitOnce.Do(func() {
+		close(this.quit)
+	})
+	<-this.done
+}
+
+func (this *eventSubscriber) Events() <-chan *store.BlockEvents {
+	return this.events
+}
+
+func (this *eventSubscriber) Unsubscribe() {
+	this.ledger.eventSubLock.Lock()
+	delete(this.ledger.eventSubs, this.id)
+	this.ledger.eventSubLock.Unlock()
+	this.stop()
+}
+
+func (this *eventSubscriber) Err() error {
+	select {
+	case <-this.done:
+		return this.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveBlockEvents(t *testing.T, sub store.EventSubscription) *store.BlockEvents {
+	select {
+	case evts := <-sub.Events():
+		return evts
+	case <-time.After(5 * time.Second):
+		t.Fatal("receive block events timeout")
+		return nil
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscription")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	newTestCheckpointBlock(t, ledgerStore, acc)
+	newTestCheckpointBlock(t, ledgerStore, acc)
+
+	contractA := common.Address{1}
+	contractB := common.Address{2}
+	txHash := common.Uint256{1}
+	notify := &event.ExecuteNotify{TxHash: txHash, State: event.CONTRACT_STATE_SUCCESS, Notify: []*event.NotifyEventInfo{
+		{ContractAddress: contractA, States: "a"},
+		{ContractAddress: contractB, States: "b"},
+	}}
+	ledgerStore.eventStore.NewBatch()
+	assert.Nil(t, ledgerStore.eventStore.SaveEventNotifyByTx(txHash, notify))
+	ledgerStore.eventStore.SaveEventNotifyByBlock(1, []common.Uint256{txHash})
+	ledgerStore.eventStore.SaveEventBloom(1, CreateEventBloom([]*event.ExecuteNotify{notify}))
+	assert.Nil(t, ledgerStore.eventStore.CommitTo())
+
+	_, err = ledgerStore.SubscribeEvents(4, nil)
+	assert.NotNil(t, err)
+
+	//the saved blocks are replayed before the new block
+	all, err := ledgerStore.SubscribeEvents(1, nil)
+	assert.Nil(t, err)
+	filtered, err := ledgerStore.SubscribeEvents(1, []common.Address{contractA})
+	assert.Nil(t, err)
+	evts := receiveBlockEvents(t, all)
+	assert.Equal(t, uint32(1), evts.Height)
+	assert.Equal(t, 1, len(evts.Notifies))
+	assert.Equal(t, 2, len(evts.Notifies[0].Notify))
+	evts = receiveBlockEvents(t, filtered)
+	assert.Equal(t, uint32(1), evts.Height)
+	assert.Equal(t, 1, len(evts.Notifies))
+	assert.Equal(t, 1, len(evts.Notifies[0].Notify))
+	assert.Equal(t, contractA, evts.Notifies[0].Notify[0].ContractAddress)
+	evts = receiveBlockEvents(t, filtered)
+	assert.Equal(t, uint32(2), evts.Height)
+	assert.Equal(t, 0, len(evts.Notifies))
+
+	newTestCheckpointBlock(t, ledgerStore, acc)
+	evts = receiveBlockEvents(t, filtered)
+	assert.Equal(t, uint32(3), evts.Height)
+	filtered.Unsubscribe()
+	_, ok := <-filtered.Events()
+	assert.False(t, ok)
+	assert.Nil(t, filtered.Err())
+
+	//the subscription is closed with ledger
+	assert.Nil(t, ledgerStore.Close())
+	for range all.Events() {
+	}
+	assert.Nil(t, all.Err())
+	_, err = ledgerStore.SubscribeEvents(1, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/merkle"
+)
+
+//EnableFullStateRoot start or stop maintaining the merkle patricia trie of all contract storage. When it is enabled
+//and the root at the current block is not saved, the trie is built from the current storage
+func (this *LedgerStoreImp) EnableFullStateRoot(enable bool) error {
+	if !enable {
+		atomic.StoreInt32(&this.fullStateRoot, 0)
+		return nil
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	err := this.initFullStateTree()
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&this.fullStateRoot, 1)
+	return nil
+}
+
+//initFullStateTree build the full state merkle patricia trie from the storage if the root at the current block of
+//state store is not saved
+func (this *LedgerStoreImp) initFullStateTree() error {
+	_, height, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	_, err = this.stateStore.GetFullStateRoot(height)
+	if err == nil {
+		return nil
+	}
+	if err != scom.ErrNotFound {
+		return fmt.Errorf("GetFullStateRoot error %s", err)
+	}
+	log.Infof("build full state trie at height %d", height)
+	tree, err := merkle.NewMerklePatriciaTrie(this.stateStore, common.UINT256_EMPTY)
+	if err != nil {
+		return err
+	}
+	iter := this.stateStore.store.NewIterator([]byte{byte(scom.ST_STORAGE)})
+	count := 0
+	for iter.Next() {
+		err = tree.Update(iter.Key()[1:], iter.Value())
+		if err != nil {
+			iter.Release()
+			return fmt.Errorf("update full state trie error %s", err)
+		}
+		count++
+	}
+	iter.Release()
+	if err = iter.Error(); err != nil {
+		return fmt.Errorf("iterate storage error %s", err)
+	}
+	root, err := tree.Commit()
+	if err != nil {
+		return fmt.Errorf("commit full state trie error %s", err)
+	}
+	this.stateStore.NewBatch()
+	this.stateStore.SaveFullStateNodes(tree.NewNodes())
+	this.stateStore.SaveFullStateRoot(height, root)
+	err = this.stateStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("stateStore.CommitTo error %s", err)
+	}
+	log.Infof("full state trie of %d keys at height %d, root %s", count, height, root.ToHexString())
+	return nil
+}
+
+//updateFullStateTree apply the storage updated in block to the full state merkle patricia trie of previous block, and
+//set the root and new trie nodes of result
+func (this *LedgerStoreImp) updateFullStateTree(height uint32, result *store.ExecuteResult) error {
+	if atomic.LoadInt32(&this.fullStateRoot) == 0 {
+		return nil
+	}
+	root := common.UINT256_EMPTY
+	if height != 0 {
+		var err error
+		root, err = this.stateStore.GetFullStateRoot(height - 1)
+		if err != nil {
+			return fmt.Errorf("GetFullStateRoot height:%d error %s", height-1, err)
+		}
+	}
+	tree, err := merkle.NewMerklePatriciaTrie(this.stateStore, root)
+	if err != nil {
+		return err
+	}
+	result.WriteSet.ForEach(func(key, val []byte) {
+		if err != nil || len(key) == 0 || key[0] != byte(scom.ST_STORAGE) {
+			return
+		}
+		err = tree.Update(key[1:], val)
+	})
+	if err != nil {
+		return fmt.Errorf("update full state trie error %s", err)
+	}
+	result.FullStateRoot, err = tree.Commit()
+	if err != nil {
+		return fmt.Errorf("commit full state trie error %s", err)
+	}
+	result.FullStateNodes = tree.NewNodes()
+	return nil
+}
+
+//GetFullStateProof return the full state root of block and the serialized merkle patricia proof of the storage key of
+//contract in it, it proves the value at the height or the exclusion of the key
+func (this *LedgerStoreImp) GetFullStateProof(height uint32, contract common.Address, key []byte) (common.Uint256,
+	[]byte, error) {
+	if height > this.GetCurrentBlockHeight() {
+		return common.UINT256_EMPTY, nil, fmt.Errorf("block height %d is not executed", height)
+	}
+	root, err := this.stateStore.GetFullStateRoot(height)
+	if err != nil {
+		return common.UINT256_EMPTY, nil, fmt.Errorf("GetFullStateRoot height:%d error %s", height, err)
+	}
+	tree, err := merkle.NewMerklePatriciaTrie(this.stateStore, root)
+	if err != nil {
+		return common.UINT256_EMPTY, nil, err
+	}
+	proof, err := tree.Prove(append(contract[:], key...))
+	if err != nil {
+		return common.UINT256_EMPTY, nil, fmt.Errorf("GetFullStateProof:%s", err)
+	}
+	sink := common.NewZeroCopySink(nil)
+	proof.Serialization(sink)
+	return root, sink.Bytes(), nil
+}
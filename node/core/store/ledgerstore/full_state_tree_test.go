@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/merkle"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFullStateRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullstate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	_, _, err = ledgerStore.GetFullStateProof(0, common.Address{}, nil)
+	assert.NotNil(t, err)
+
+	//the trie of genesis storage is built when enabled
+	assert.Nil(t, ledgerStore.EnableFullStateRoot(true))
+	root, err := ledgerStore.stateStore.GetFullStateRoot(0)
+	assert.Nil(t, err)
+	assert.NotEqual(t, common.UINT256_EMPTY, root)
+	iter := ledgerStore.stateStore.store.NewIterator([]byte{byte(scom.ST_STORAGE)})
+	assert.True(t, iter.Next())
+	key, value := append([]byte{}, iter.Key()[1:]...), append([]byte{}, iter.Value()...)
+	iter.Release()
+	contract, err := common.AddressParseFromBytes(key[:common.ADDR_LEN])
+	assert.Nil(t, err)
+
+	newTestCheckpointBlock(t, ledgerStore, acc)
+	proofRoot, data, err := ledgerStore.GetFullStateProof(1, contract, key[common.ADDR_LEN:])
+	assert.Nil(t, err)
+	assert.Equal(t, root, proofRoot)
+	proof := &merkle.MPTProof{}
+	assert.Nil(t, proof.Deserialization(common.NewZeroCopySource(data)))
+	assert.True(t, merkle.VerifyMPTProof(proofRoot, key, value, proof))
+	_, data, err = ledgerStore.GetFullStateProof(1, contract, []byte("absent"))
+	assert.Nil(t, err)
+	assert.Nil(t, proof.Deserialization(common.NewZeroCopySource(data)))
+	assert.True(t, merkle.VerifyMPTProof(proofRoot, append(contract[:], []byte("absent")...), nil, proof))
+	_, _, err = ledgerStore.GetFullStateProof(2, contract, nil)
+	assert.NotNil(t, err)
+
+	//the blocks saved when disabled have no root, and the trie is rebuilt when enabled again
+	assert.Nil(t, ledgerStore.EnableFullStateRoot(false))
+	newTestCheckpointBlock(t, ledgerStore, acc)
+	_, err = ledgerStore.stateStore.GetFullStateRoot(2)
+	assert.Equal(t, scom.ErrNotFound, err)
+	assert.Nil(t, ledgerStore.EnableFullStateRoot(true))
+	rebuilt, err := ledgerStore.stateStore.GetFullStateRoot(2)
+	assert.Nil(t, err)
+	assert.Equal(t, root, rebuilt)
+}
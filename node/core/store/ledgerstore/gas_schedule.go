@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/smartcontract/service/neovm"
+)
+
+//GasFork is the gas table entries overridden by a hard fork from the height
+type GasFork struct {
+	Name     string
+	Height   uint32
+	GasTable map[string]uint64
+}
+
+//NewGasSchedule return the gas forks of the chain spec hard forks sorted by height, nil if not set
+func NewGasSchedule(spec *config.ChainSpec) ([]*GasFork, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	forks := make([]*GasFork, 0, len(spec.HardForks))
+	for _, fork := range spec.HardForks {
+		if len(fork.GasTable) == 0 {
+			continue
+		}
+		for key := range fork.GasTable {
+			if _, ok := neovm.GAS_TABLE.Load(key); !ok {
+				return nil, fmt.Errorf("hard fork %s gas table has unknown key %s", fork.Name, key)
+			}
+		}
+		forks = append(forks, &GasFork{Name: fork.Name, Height: fork.Height, GasTable: fork.GasTable})
+	}
+	return forks, nil
+}
+
+//gasTableAt return the gas table of block at height, which is the neovm gas table refreshed by global params and
+//overridden by the hard forks up to the height
+func (this *LedgerStoreImp) gasTableAt(height uint32) map[string]uint64 {
+	gasTable := make(map[string]uint64)
+	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
+		gasTable[k.(string)] = value.(uint64)
+		return true
+	})
+	for _, fork := range this.gasSchedule {
+		if height < fork.Height {
+			break
+		}
+		for key, val := range fork.GasTable {
+			gasTable[key] = val
+		}
+	}
+	return gasTable
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/smartcontract/service/neovm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasSchedule(t *testing.T) {
+	forks, err := NewGasSchedule(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, forks)
+
+	spec := &config.ChainSpec{NetworkId: 1000, HardForks: []*config.ChainSpecHardFork{
+		{Name: "cheap-hash", Height: 100, GasTable: map[string]uint64{neovm.SHA256_NAME: 5}},
+		{Name: "feature-only", Height: 150, Features: []string{config.FEATURE_GAS_ROUND_TUNE}},
+		{Name: "storage", Height: 200, GasTable: map[string]uint64{neovm.SHA256_NAME: 7, neovm.STORAGE_PUT_NAME: 2000}},
+	}}
+	forks, err = NewGasSchedule(spec)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(forks))
+
+	ledgerStore := &LedgerStoreImp{gasSchedule: forks}
+	table := ledgerStore.gasTableAt(99)
+	assert.Equal(t, neovm.SHA256_GAS, table[neovm.SHA256_NAME])
+	assert.Equal(t, neovm.STORAGE_PUT_GAS, table[neovm.STORAGE_PUT_NAME])
+	table = ledgerStore.gasTableAt(100)
+	assert.Equal(t, uint64(5), table[neovm.SHA256_NAME])
+	assert.Equal(t, neovm.STORAGE_PUT_GAS, table[neovm.STORAGE_PUT_NAME])
+	table = ledgerStore.gasTableAt(200)
+	assert.Equal(t, uint64(7), table[neovm.SHA256_NAME])
+	assert.Equal(t, uint64(2000), table[neovm.STORAGE_PUT_NAME])
+	assert.Equal(t, neovm.HASH256_GAS, table[neovm.HASH256_NAME])
+
+	//the global gas table is not modified by hard forks
+	val, _ := neovm.GAS_TABLE.Load(neovm.SHA256_NAME)
+	assert.Equal(t, neovm.SHA256_GAS, val)
+
+	spec.HardForks[0].GasTable["Unknown.Gas"] = 1
+	_, err = NewGasSchedule(spec)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+)
+
+const (
+	GAS_STATS_WINDOW_SIZE = 4096          //Count of latest blocks whose gas statistics are kept in memory
+	GAS_STATS_MAX_RANGE   = uint32(100000) //Max count of blocks aggregated in one query
+)
+
+//gasStatsWindow keep the gas statistics of latest blocks, indexed by height % GAS_STATS_WINDOW_SIZE
+type gasStatsWindow struct {
+	lock  sync.RWMutex
+	stats []*store.GasStats
+}
+
+func newGasStatsWindow() *gasStatsWindow {
+	return &gasStatsWindow{
+		stats: make([]*store.GasStats, GAS_STATS_WINDOW_SIZE),
+	}
+}
+
+func (this *gasStatsWindow) add(stats *store.GasStats) {
+	if this == nil {
+		return
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.stats[stats.FromHeight%GAS_STATS_WINDOW_SIZE] = stats
+}
+
+//get return the gas statistics of block if in window, nil otherwise
+func (this *gasStatsWindow) get(height uint32) *store.GasStats {
+	if this == nil {
+		return nil
+	}
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	stats := this.stats[height%GAS_STATS_WINDOW_SIZE]
+	if stats == nil || stats.FromHeight != height {
+		return nil
+	}
+	return stats
+}
+
+//newBlockGasStats aggregate the gas and fee of transactions in block by their execute notifies
+func newBlockGasStats(block *types.Block, notifies []*event.ExecuteNotify) *store.GasStats {
+	height := block.Header.Height
+	stats := &store.GasStats{FromHeight: height, ToHeight: height, Blocks: 1}
+	gasConsumed := make(map[common.Uint256]*event.ExecuteNotify, len(notifies))
+	for _, notify := range notifies {
+		gasConsumed[notify.TxHash] = notify
+	}
+	for _, tx := range block.Transactions {
+		stats.TxCount++
+		notify := gasConsumed[tx.Hash()]
+		if notify == nil {
+			stats.FailedTxs++
+			continue
+		}
+		if notify.State != event.CONTRACT_STATE_SUCCESS {
+			stats.FailedTxs++
+		}
+		if notify.GasConsumed == 0 {
+			continue
+		}
+		stats.SysFee += notify.GasConsumed
+		if tx.GasPrice == 0 {
+			continue
+		}
+		stats.GasUsed += notify.GasConsumed / tx.GasPrice
+		if stats.MinGasPrice == 0 || tx.GasPrice < stats.MinGasPrice {
+			stats.MinGasPrice = tx.GasPrice
+		}
+		if tx.GasPrice > stats.MaxGasPrice {
+			stats.MaxGasPrice = tx.GasPrice
+		}
+	}
+	return stats
+}
+
+//mergeGasStats add the gas statistics of block to total
+func mergeGasStats(total, stats *store.GasStats) {
+	total.Blocks += stats.Blocks
+	total.TxCount += stats.TxCount
+	total.FailedTxs += stats.FailedTxs
+	total.GasUsed += stats.GasUsed
+	total.SysFee += stats.SysFee
+	if stats.MinGasPrice != 0 && (total.MinGasPrice == 0 || stats.MinGasPrice < total.MinGasPrice) {
+		total.MinGasPrice = stats.MinGasPrice
+	}
+	if stats.MaxGasPrice > total.MaxGasPrice {
+		total.MaxGasPrice = stats.MaxGasPrice
+	}
+}
+
+//GetGasStats return the gas and fee statistics aggregated over the blocks in [fromHeight, toHeight]. The blocks saved
+//before statistics are kept are not counted
+func (this *LedgerStoreImp) GetGasStats(fromHeight, toHeight uint32) (*store.GasStats, error) {
+	currHeight := this.GetCurrentBlockHeight()
+	if toHeight > currHeight {
+		toHeight = currHeight
+	}
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("invalid height range [%d, %d]", fromHeight, toHeight)
+	}
+	if toHeight-fromHeight >= GAS_STATS_MAX_RANGE {
+		return nil, fmt.Errorf("height range exceeds %d blocks", GAS_STATS_MAX_RANGE)
+	}
+	total := &store.GasStats{FromHeight: fromHeight, ToHeight: toHeight}
+	for height := fromHeight; ; height++ {
+		stats := this.gasStats.get(height)
+		if stats == nil {
+			var err error
+			stats, err = this.stateStore.GetGasStats(height)
+			if err != nil && err != scom.ErrNotFound {
+				return nil, fmt.Errorf("GetGasStats height %d error %s", height, err)
+			}
+		}
+		if stats != nil {
+			mergeGasStats(total, stats)
+		}
+		if height == toHeight {
+			break
+		}
+	}
+	return total, nil
+}
+
+//GetRecentGasStats return the gas and fee statistics aggregated over the latest blocks
+func (this *LedgerStoreImp) GetRecentGasStats(blocks uint32) (*store.GasStats, error) {
+	if blocks == 0 {
+		return nil, fmt.Errorf("block count should be positive")
+	}
+	currHeight := this.GetCurrentBlockHeight()
+	fromHeight := uint32(0)
+	if currHeight >= blocks {
+		fromHeight = currHeight - blocks + 1
+	}
+	return this.GetGasStats(fromHeight, currHeight)
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasStats(t *testing.T) {
+	charged := newInvokeTransaction(500, 20000, []byte{1})
+	failed := newInvokeTransaction(1000, 20000, []byte{2})
+	missing := newInvokeTransaction(500, 20000, []byte{3})
+	free := newInvokeTransaction(0, 20000, []byte{4})
+	newBlock := func(height uint32) *types.Block {
+		return &types.Block{
+			Header:       &types.Header{Height: height},
+			Transactions: []*types.Transaction{charged, failed, missing, free},
+		}
+	}
+	notifies := []*event.ExecuteNotify{
+		{TxHash: charged.Hash(), State: event.CONTRACT_STATE_SUCCESS, GasConsumed: 500 * 20000},
+		{TxHash: failed.Hash(), State: event.CONTRACT_STATE_FAIL, GasConsumed: 1000 * 100},
+		{TxHash: free.Hash(), State: event.CONTRACT_STATE_SUCCESS},
+	}
+	stats := newBlockGasStats(newBlock(1), notifies)
+	assert.Equal(t, &store.GasStats{
+		FromHeight:  1,
+		ToHeight:    1,
+		Blocks:      1,
+		TxCount:     4,
+		FailedTxs:   2,
+		GasUsed:     20100,
+		SysFee:      500*20000 + 1000*100,
+		MinGasPrice: 500,
+		MaxGasPrice: 1000,
+	}, stats)
+
+	//stats of height 1 and 2 are read from store, and height 3 from window
+	stateStore := NewMemStateStore(0)
+	ledgerStore := &LedgerStoreImp{stateStore: stateStore, gasStats: newGasStatsWindow(), currBlockHeight: 3}
+	stateStore.NewBatch()
+	stateStore.SaveGasStats(stats)
+	stateStore.SaveGasStats(newBlockGasStats(newBlock(2), notifies[:1]))
+	assert.Nil(t, stateStore.CommitTo())
+	saved, err := stateStore.GetGasStats(1)
+	assert.Nil(t, err)
+	assert.Equal(t, stats, saved)
+	ledgerStore.gasStats.add(newBlockGasStats(newBlock(3), nil))
+
+	total, err := ledgerStore.GetGasStats(0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, &store.GasStats{
+		FromHeight:  0,
+		ToHeight:    3,
+		Blocks:      3,
+		TxCount:     12,
+		FailedTxs:   2 + 3 + 4,
+		GasUsed:     20100 + 20000,
+		SysFee:      2*500*20000 + 1000*100,
+		MinGasPrice: 500,
+		MaxGasPrice: 1000,
+	}, total)
+
+	recent, err := ledgerStore.GetRecentGasStats(2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), recent.FromHeight)
+	assert.Equal(t, uint32(2), recent.Blocks)
+	assert.Equal(t, uint64(20000), recent.GasUsed)
+
+	_, err = ledgerStore.GetGasStats(3, 2)
+	assert.NotNil(t, err)
+	_, err = ledgerStore.GetRecentGasStats(0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	scom "github.com/ontio/layer2/node/core/store/common"
+)
+
+//IntegrityResult is the result of startup integrity check, CorruptHeight and Reason are set only if Corrupted.
+//The blocks below CorruptHeight are verified, VerifiedHeight is CorruptHeight-1 or the block height if not corrupted
+type IntegrityResult struct {
+	StartHeight    uint32
+	BlockHeight    uint32
+	EventHeight    uint32
+	StateHeight    uint32
+	VerifiedHeight uint32
+	Corrupted      bool
+	CorruptHeight  uint32
+	Reason         string
+}
+
+func (this *IntegrityResult) corrupt(height uint32, format string, args ...interface{}) *IntegrityResult {
+	this.Corrupted = true
+	this.CorruptHeight = height
+	if height > 0 {
+		this.VerifiedHeight = height - 1
+	}
+	this.Reason = fmt.Sprintf(format, args...)
+	return this
+}
+
+//Repairable return whether the corruption is above the executed state, so that the ledger is repaired by truncating
+//the blocks from the corrupted height, which are synced again and executed as usual
+func (this *IntegrityResult) Repairable() bool {
+	return this.Corrupted && this.CorruptHeight > this.StateHeight
+}
+
+//CheckIntegrity verify the latest blocks of block store. The hash index, header and transactions of each block are
+//loaded, the block hash, height, previous block hash and transactions root are checked, and the state merkle root and
+//account root of the executed blocks are read. The block merkle tree is checked against the block root of the last
+//executed block, and the current heights of stores against state <= event <= block of the commit order.
+//The blocks executed but not stored, which are before the checkpoint of fast sync, are skipped
+func (this *LedgerStoreImp) CheckIntegrity(blocks uint32) (*IntegrityResult, error) {
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	_, eventHeight, err := this.eventStore.GetCurrentBlock()
+	if err == scom.ErrNotFound {
+		eventHeight = stateHeight
+	} else if err != nil {
+		return nil, fmt.Errorf("eventStore.GetCurrentBlock error %s", err)
+	}
+	_, blockHeight, err := this.blockStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("blockStore.GetCurrentBlock error %s", err)
+	}
+	result := &IntegrityResult{
+		BlockHeight:    blockHeight,
+		EventHeight:    eventHeight,
+		StateHeight:    stateHeight,
+		VerifiedHeight: blockHeight,
+	}
+	if blocks == 0 {
+		return result, nil
+	}
+	if stateHeight > eventHeight {
+		return result.corrupt(eventHeight+1, "state height %d is above event height %d", stateHeight, eventHeight), nil
+	}
+	if eventHeight > blockHeight {
+		return result.corrupt(blockHeight+1, "event height %d is above block height %d", eventHeight, blockHeight), nil
+	}
+	if blockHeight >= blocks {
+		result.StartHeight = blockHeight - blocks + 1
+	}
+
+	prevHash := common.UINT256_EMPTY
+	if result.StartHeight > 0 {
+		prevHash, err = this.blockStore.GetBlockHash(result.StartHeight - 1)
+		if err != nil {
+			return result.corrupt(result.StartHeight-1, "block hash index error %s", err), nil
+		}
+	}
+	stateBlockRoot := common.UINT256_EMPTY
+	for height := result.StartHeight; height <= blockHeight; height++ {
+		blockHash, err := this.blockStore.GetBlockHash(height)
+		if err != nil {
+			return result.corrupt(height, "block hash index error %s", err), nil
+		}
+		block, err := this.blockStore.GetBlock(blockHash)
+		if err == scom.ErrNotFound && height <= stateHeight {
+			prevHash = blockHash
+			continue
+		}
+		if err != nil {
+			return result.corrupt(height, "load block %s error %s", blockHash.ToHexString(), err), nil
+		}
+		if hash := block.Hash(); hash != blockHash {
+			return result.corrupt(height, "block hash %s, expected %s", hash.ToHexString(), blockHash.ToHexString()), nil
+		}
+		if block.Header.Height != height {
+			return result.corrupt(height, "block height %d, expected %d", block.Header.Height, height), nil
+		}
+		if height > 0 && block.Header.PrevBlockHash != prevHash {
+			return result.corrupt(height, "previous block hash %s, expected %s", block.Header.PrevBlockHash.ToHexString(),
+				prevHash.ToHexString()), nil
+		}
+		txHashes := make([]common.Uint256, 0, len(block.Transactions))
+		for _, tx := range block.Transactions {
+			txHashes = append(txHashes, tx.Hash())
+		}
+		if txRoot := common.ComputeMerkleRoot(txHashes); txRoot != block.Header.TransactionsRoot {
+			return result.corrupt(height, "transactions root %s, expected %s", block.Header.TransactionsRoot.ToHexString(),
+				txRoot.ToHexString()), nil
+		}
+		if height <= stateHeight {
+			_, err = this.stateStore.GetStateMerkleRoot(height)
+			if err != nil && err != scom.ErrNotFound {
+				return result.corrupt(height, "state merkle root error %s", err), nil
+			}
+			_, err = this.stateStore.GetAccountTreeRoot(height)
+			if err != nil && err != scom.ErrNotFound {
+				return result.corrupt(height, "account root error %s", err), nil
+			}
+		}
+		if height == stateHeight {
+			stateBlockRoot = block.Header.BlockRoot
+		}
+		prevHash = blockHash
+	}
+	if stateHeight > 0 && stateBlockRoot != common.UINT256_EMPTY {
+		if root := this.stateStore.merkleTree.Root(); root != stateBlockRoot {
+			return result.corrupt(stateHeight, "block merkle tree root %s, expected %s", root.ToHexString(),
+				stateBlockRoot.ToHexString()), nil
+		}
+	}
+	return result, nil
+}
+
+//TruncateBlocks remove the blocks above height from block store, and move back the current block of event store if it
+//is above height. Only the blocks not executed are truncated, the state of executed blocks is never rolled back
+func (this *LedgerStoreImp) TruncateBlocks(height uint32) error {
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if height < stateHeight {
+		return fmt.Errorf("cannot truncate blocks to height %d below executed height %d", height, stateHeight)
+	}
+	blockHash, err := this.blockStore.GetBlockHash(height)
+	if err != nil {
+		return fmt.Errorf("GetBlockHash height:%d error %s", height, err)
+	}
+	_, blockHeight, err := this.blockStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("blockStore.GetCurrentBlock error %s", err)
+	}
+	//event store is committed before block store is truncated, so state <= event <= block holds on crash
+	_, eventHeight, err := this.eventStore.GetCurrentBlock()
+	if err != nil && err != scom.ErrNotFound {
+		return fmt.Errorf("eventStore.GetCurrentBlock error %s", err)
+	}
+	if err == nil && eventHeight > height {
+		this.eventStore.NewBatch()
+		this.eventStore.SaveCurrentBlock(height, blockHash)
+		err = this.eventStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("eventStore.CommitTo error %s", err)
+		}
+	}
+
+	this.blockStore.NewBatch()
+	for h := height + 1; h <= blockHeight; h++ {
+		this.blockStore.deleteBlock(h)
+	}
+	err = this.blockStore.deleteHeaderIndexListAbove(height)
+	if err != nil {
+		return err
+	}
+	err = this.blockStore.SaveCurrentBlock(height, blockHash)
+	if err != nil {
+		return fmt.Errorf("SaveCurrentBlock error %s", err)
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	return nil
+}
+
+//checkIntegrity run the integrity check at startup, and repair the ledger by truncating the corrupted blocks if
+//auto repair is enabled. The corruption of executed blocks is not repairable, the ledger should be restored from a
+//backup or by checkpoint fast sync
+func (this *LedgerStoreImp) checkIntegrity() error {
+	blocks := uint32(config.DefConfig.Common.IntegrityCheckBlocks)
+	if blocks == 0 {
+		return nil
+	}
+	result, err := this.CheckIntegrity(blocks)
+	if err != nil {
+		return err
+	}
+	if !result.Corrupted {
+		log.Infof("integrity check of blocks %d-%d passed", result.StartHeight, result.BlockHeight)
+		return nil
+	}
+	log.Errorf("integrity check found corrupted block %d: %s", result.CorruptHeight, result.Reason)
+	if !result.Repairable() {
+		return fmt.Errorf("block %d is corrupted at or below executed height %d: %s, restore the ledger from a backup "+
+			"or by checkpoint fast sync", result.CorruptHeight, result.StateHeight, result.Reason)
+	}
+	if !config.DefConfig.Common.EnableAutoRepair {
+		return fmt.Errorf("block %d is corrupted: %s, start without --disable-auto-repair to truncate the blocks to "+
+			"height %d", result.CorruptHeight, result.Reason, result.VerifiedHeight)
+	}
+	err = this.TruncateBlocks(result.VerifiedHeight)
+	if err != nil {
+		return fmt.Errorf("TruncateBlocks error %s", err)
+	}
+	log.Warnf("repaired ledger by truncating blocks %d-%d, the blocks are synced again", result.CorruptHeight,
+		result.BlockHeight)
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	newStore := func() *leveldbstore.LevelDBStore {
+		store, err := leveldbstore.NewMemLevelDBStore()
+		assert.Nil(t, err)
+		return store
+	}
+	blockStore := &BlockStore{store: newStore()}
+	stateStore := &StateStore{store: newStore(), merkleTree: merkle.NewTree(0, nil, nil)}
+	eventStore := &EventStore{store: newStore()}
+	ledgerStore := &LedgerStoreImp{blockStore: blockStore, stateStore: stateStore, eventStore: eventStore}
+
+	blockTree := merkle.NewTree(0, nil, nil)
+	hashes := make([]common.Uint256, 0)
+	blockStore.NewBatch()
+	for height := uint32(0); height <= 5; height++ {
+		header := &types.Header{Height: height, Timestamp: height + 1}
+		if height > 0 {
+			header.PrevBlockHash = hashes[height-1]
+		}
+		block := &types.Block{Header: header, Transactions: []*types.Transaction{}}
+		block.RebuildMerkleRoot()
+		blockTree.AppendHash(header.TransactionsRoot)
+		header.BlockRoot = blockTree.Root()
+		if height <= 2 {
+			stateStore.merkleTree.AppendHash(header.TransactionsRoot)
+		}
+		assert.Nil(t, blockStore.SaveBlock(block))
+		blockStore.SaveBlockHash(height, block.Hash())
+		hashes = append(hashes, block.Hash())
+	}
+	blockStore.SaveHeaderIndexList(0, hashes[:4])
+	assert.Nil(t, blockStore.SaveCurrentBlock(5, hashes[5]))
+	assert.Nil(t, blockStore.CommitTo())
+	stateStore.NewBatch()
+	assert.Nil(t, stateStore.SaveCurrentBlock(2, hashes[2]))
+	assert.Nil(t, stateStore.CommitTo())
+	eventStore.NewBatch()
+	eventStore.SaveCurrentBlock(3, hashes[3])
+	assert.Nil(t, eventStore.CommitTo())
+
+	result, err := ledgerStore.CheckIntegrity(100)
+	assert.Nil(t, err)
+	assert.False(t, result.Corrupted)
+	assert.Equal(t, uint32(5), result.VerifiedHeight)
+
+	//corrupted block above executed height is repaired by truncating
+	assert.Nil(t, blockStore.store.Put(blockStore.getHeaderKey(hashes[4]), []byte{1, 2, 3}))
+	result, err = ledgerStore.CheckIntegrity(100)
+	assert.Nil(t, err)
+	assert.True(t, result.Corrupted)
+	assert.Equal(t, uint32(4), result.CorruptHeight)
+	assert.Equal(t, uint32(3), result.VerifiedHeight)
+	assert.True(t, result.Repairable())
+
+	assert.Nil(t, ledgerStore.TruncateBlocks(result.VerifiedHeight))
+	hash, height, err := blockStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), height)
+	assert.Equal(t, hashes[3], hash)
+	_, err = blockStore.GetBlockHash(4)
+	assert.Equal(t, scom.ErrNotFound, err)
+	_, err = blockStore.GetBlock(hashes[5])
+	assert.Equal(t, scom.ErrNotFound, err)
+	count, err := blockStore.GetHeaderIndexCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), count)
+	result, err = ledgerStore.CheckIntegrity(100)
+	assert.Nil(t, err)
+	assert.False(t, result.Corrupted)
+
+	//the header index list containing truncated heights is deleted, event store is moved back
+	assert.Nil(t, ledgerStore.TruncateBlocks(2))
+	count, err = blockStore.GetHeaderIndexCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), count)
+	_, height, err = eventStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), height)
+
+	//corruption of executed blocks is not repairable
+	assert.NotNil(t, ledgerStore.TruncateBlocks(1))
+	stateTree := stateStore.merkleTree
+	stateStore.merkleTree = merkle.NewTree(0, nil, nil)
+	result, err = ledgerStore.CheckIntegrity(100)
+	assert.Nil(t, err)
+	assert.True(t, result.Corrupted)
+	assert.Equal(t, uint32(2), result.CorruptHeight)
+	assert.False(t, result.Repairable())
+	stateStore.merkleTree = stateTree
+
+	assert.Nil(t, blockStore.store.Put(blockStore.getHeaderKey(hashes[1]), []byte{1, 2, 3}))
+	result, err = ledgerStore.CheckIntegrity(100)
+	assert.Nil(t, err)
+	assert.True(t, result.Corrupted)
+	assert.Equal(t, uint32(1), result.CorruptHeight)
+	assert.False(t, result.Repairable())
+	result, err = ledgerStore.CheckIntegrity(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), result.StartHeight)
+	assert.False(t, result.Corrupted)
+}
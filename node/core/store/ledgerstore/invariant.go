@@ -0,0 +1,407 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/ontio/layer2/node/smartcontract/service/native/ont"
+	"github.com/ontio/layer2/node/smartcontract/service/native/utils"
+)
+
+const (
+	INVARIANT_MAX_VIOLATIONS = 100 //Max count of recent violations kept in invariant status
+
+	INVARIANT_SUPPLY = "supply" //Sum of balances of token equals genesis supply plus deposits minted minus withdraws burned
+	INVARIANT_HEIGHT = "height" //Current heights of stores never decrease, and state <= event <= block by commit order
+	INVARIANT_HEADER = "header" //Header links to previous header, and is signed by the next bookkeeper of previous header
+)
+
+//tokenSupply is the genesis supply of token, and the amount minted by deposits and burned by withdraws after genesis
+type tokenSupply struct {
+	base   uint64
+	minted uint64
+	burned uint64
+}
+
+//invariantChecker check the chain-wide invariants in background. Blocks are checked incrementally in height order,
+//the token supply is checked against the balances in a snapshot of state store at the end of every round.
+type invariantChecker struct {
+	ledger        *LedgerStoreImp
+	interval      time.Duration
+	checkedHeight uint32
+	prevHeader    *types.Header
+	storeHeights  [3]uint32 //Last seen current heights of state, event and block store
+	supplies      map[common.Address]*tokenSupply
+	exit          chan struct{}
+	done          chan struct{}
+}
+
+//StartInvariantChecker start the invariant checker which checks the new blocks every interval
+func (this *LedgerStoreImp) StartInvariantChecker(interval time.Duration) {
+	this.invariantLock.Lock()
+	defer this.invariantLock.Unlock()
+	if this.invariantChecker != nil {
+		return
+	}
+	this.invariantChecker = &invariantChecker{
+		ledger:   this,
+		interval: interval,
+		exit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	this.invariantStatus.Running = true
+	go this.invariantChecker.loop()
+	log.Infof("check invariants every %s", interval)
+}
+
+//stopInvariantChecker stop the invariant checker and wait the running round
+func (this *LedgerStoreImp) stopInvariantChecker() {
+	this.invariantLock.Lock()
+	checker := this.invariantChecker
+	this.invariantStatus.Running = false
+	this.invariantLock.Unlock()
+	if checker != nil {
+		close(checker.exit)
+		<-checker.done
+	}
+}
+
+//GetInvariantStatus return the progress, counters and recent violations of invariant checker
+func (this *LedgerStoreImp) GetInvariantStatus() store.InvariantStatus {
+	this.invariantLock.RLock()
+	defer this.invariantLock.RUnlock()
+	status := this.invariantStatus
+	status.Violations = append([]store.InvariantViolation{}, this.invariantStatus.Violations...)
+	return status
+}
+
+func (this *LedgerStoreImp) addInvariantViolation(invariant string, height uint32, detail string) {
+	log.Errorf("invariant %s violated at height %d: %s", invariant, height, detail)
+	this.invariantLock.Lock()
+	defer this.invariantLock.Unlock()
+	this.invariantStatus.ViolationCount++
+	violations := append(this.invariantStatus.Violations, store.InvariantViolation{
+		Invariant: invariant,
+		Height:    height,
+		Time:      uint32(time.Now().Unix()),
+		Detail:    detail,
+	})
+	if len(violations) > INVARIANT_MAX_VIOLATIONS {
+		violations = violations[len(violations)-INVARIANT_MAX_VIOLATIONS:]
+	}
+	this.invariantStatus.Violations = violations
+}
+
+func (this *LedgerStoreImp) updateInvariantStatus(checkedHeight uint32, roundFinished bool) {
+	this.invariantLock.Lock()
+	defer this.invariantLock.Unlock()
+	this.invariantStatus.CheckedHeight = checkedHeight
+	if roundFinished {
+		this.invariantStatus.Rounds++
+		this.invariantStatus.LastCheckTime = uint32(time.Now().Unix())
+	}
+}
+
+func (this *invariantChecker) loop() {
+	defer close(this.done)
+	ticker := time.NewTicker(this.interval)
+	defer ticker.Stop()
+	for {
+		if err := this.round(); err != nil {
+			log.Warnf("check invariants error %s", err)
+		}
+		select {
+		case <-this.exit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (this *invariantChecker) exited() bool {
+	select {
+	case <-this.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+//round check the heights of stores, the blocks committed since last round and the token supply.
+//The heights are read in the reverse order of block commit, so state <= event <= block always holds.
+func (this *invariantChecker) round() error {
+	if this.prevHeader == nil {
+		err := this.init()
+		if err != nil {
+			return err
+		}
+	}
+	snapshot, err := this.ledger.stateStore.store.NewSnapshot()
+	if err != nil {
+		return fmt.Errorf("state store snapshot error %s", err)
+	}
+	defer snapshot.Release()
+	stateHeight, err := snapshotCurrentHeight(snapshot)
+	if err != nil {
+		return err
+	}
+	_, eventHeight, err := this.ledger.eventStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("eventStore.GetCurrentBlock error %s", err)
+	}
+	_, blockHeight, err := this.ledger.blockStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("blockStore.GetCurrentBlock error %s", err)
+	}
+	this.checkStoreHeights([3]uint32{stateHeight, eventHeight, blockHeight})
+
+	for height := this.checkedHeight + 1; height <= stateHeight; height++ {
+		if this.exited() {
+			return nil
+		}
+		err = this.checkBlock(height)
+		if err != nil {
+			return err
+		}
+		this.checkedHeight = height
+		this.ledger.updateInvariantStatus(height, false)
+	}
+	if config.DefConfig.Common.EnableEventLog {
+		err = this.checkSupplies(snapshot, stateHeight)
+		if err != nil {
+			return err
+		}
+	}
+	this.ledger.updateInvariantStatus(this.checkedHeight, true)
+	return nil
+}
+
+//init load the genesis header and the genesis supply of tokens
+func (this *invariantChecker) init() error {
+	header, err := this.ledger.GetHeaderByHeight(0)
+	if err != nil {
+		return fmt.Errorf("get genesis header error %s", err)
+	}
+	this.supplies = make(map[common.Address]*tokenSupply)
+	for _, contract := range []common.Address{utils.OntContractAddress, utils.OngContractAddress} {
+		item, err := this.ledger.stateStore.GetStorageState(&states.StorageKey{
+			ContractAddress: contract,
+			Key:             []byte(ont.TOTAL_SUPPLY_NAME),
+		})
+		if err != nil {
+			return fmt.Errorf("get total supply of %s error %s", contract.ToHexString(), err)
+		}
+		base, eof := common.NewZeroCopySource(item.Value).NextUint64()
+		if eof {
+			return fmt.Errorf("total supply of %s error %s", contract.ToHexString(), common.ErrIrregularData)
+		}
+		this.supplies[contract] = &tokenSupply{base: base}
+	}
+	this.prevHeader = header
+	return nil
+}
+
+func (this *invariantChecker) checkStoreHeights(heights [3]uint32) {
+	names := [3]string{"state", "event", "block"}
+	for i := range heights {
+		if heights[i] < this.storeHeights[i] {
+			this.ledger.addInvariantViolation(INVARIANT_HEIGHT, heights[i],
+				fmt.Sprintf("%s store height decreased from %d", names[i], this.storeHeights[i]))
+		}
+		if i > 0 && heights[i-1] > heights[i] {
+			this.ledger.addInvariantViolation(INVARIANT_HEIGHT, heights[i-1],
+				fmt.Sprintf("%s store height is above %s store height %d", names[i-1], names[i], heights[i]))
+		}
+	}
+	this.storeHeights = heights
+}
+
+//checkBlock check the header continuity of block, and count the deposits minted and the withdraws burned in it
+func (this *invariantChecker) checkBlock(height uint32) error {
+	header, err := this.ledger.GetHeaderByHeight(height)
+	if err != nil {
+		return fmt.Errorf("get header of height %d error %s", height, err)
+	}
+	prevHash := this.prevHeader.Hash()
+	if header.PrevBlockHash != prevHash {
+		this.ledger.addInvariantViolation(INVARIANT_HEADER, height,
+			fmt.Sprintf("prev block hash %s, want %s", header.PrevBlockHash.ToHexString(), prevHash.ToHexString()))
+	}
+	if header.Timestamp <= this.prevHeader.Timestamp {
+		this.ledger.addInvariantViolation(INVARIANT_HEADER, height,
+			fmt.Sprintf("timestamp %d is not after %d", header.Timestamp, this.prevHeader.Timestamp))
+	}
+	address, err := types.AddressFromBookkeepers(header.Bookkeepers)
+	if err != nil || address != this.prevHeader.NextBookkeeper {
+		this.ledger.addInvariantViolation(INVARIANT_HEADER, height,
+			fmt.Sprintf("bookkeepers are not the next bookkeeper %s", this.prevHeader.NextBookkeeper.ToBase58()))
+	}
+	this.prevHeader = header
+
+	if !config.DefConfig.Common.EnableEventLog {
+		return nil
+	}
+	txHashes, err := this.ledger.eventStore.GetEventNotifyTxsByBlock(height)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return nil
+		}
+		return fmt.Errorf("get event notify of height %d error %s", height, err)
+	}
+	for _, txHash := range txHashes {
+		notify, err := this.getExactEventNotify(txHash)
+		if err != nil {
+			return fmt.Errorf("get event notify of tx %s error %s", txHash.ToHexString(), err)
+		}
+		if notify.State != event.CONTRACT_STATE_SUCCESS {
+			continue
+		}
+		for _, info := range notify.Notify {
+			supply, ok := this.supplies[info.ContractAddress]
+			if !ok {
+				continue
+			}
+			from, to, amount, ok := parseTransferStates(info.States)
+			if !ok {
+				continue
+			}
+			if from == common.ADDRESS_EMPTY {
+				supply.minted += amount
+			}
+			if to == common.ADDRESS_EMPTY {
+				supply.burned += amount
+			}
+		}
+	}
+	return nil
+}
+
+//getExactEventNotify read the event notify of transaction with json numbers, so the uint64 amount is not rounded by float64
+func (this *invariantChecker) getExactEventNotify(txHash common.Uint256) (*event.ExecuteNotify, error) {
+	data, err := this.ledger.eventStore.store.Get(genEventNotifyByTxKey(txHash))
+	if err != nil {
+		return nil, err
+	}
+	data, err = scom.DecompressValue(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	notify := &event.ExecuteNotify{}
+	err = decoder.Decode(notify)
+	if err != nil {
+		return nil, fmt.Errorf("json decode error %s", err)
+	}
+	return notify, nil
+}
+
+//parseTransferStates return the from, to and amount of the ont or ong transfer notify states
+func parseTransferStates(notifyStates interface{}) (common.Address, common.Address, uint64, bool) {
+	list, ok := notifyStates.([]interface{})
+	if !ok || len(list) != 4 || list[0] != ont.TRANSFER_NAME {
+		return common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 0, false
+	}
+	fromStr, ok1 := list[1].(string)
+	toStr, ok2 := list[2].(string)
+	number, ok3 := list[3].(json.Number)
+	if !ok1 || !ok2 || !ok3 {
+		return common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 0, false
+	}
+	from, err := common.AddressFromBase58(fromStr)
+	if err != nil {
+		return common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 0, false
+	}
+	to, err := common.AddressFromBase58(toStr)
+	if err != nil {
+		return common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 0, false
+	}
+	amount, err := strconv.ParseUint(number.String(), 10, 64)
+	if err != nil {
+		return common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 0, false
+	}
+	return from, to, amount, true
+}
+
+//checkSupplies check the sum of token balances in the state snapshot equals the genesis supply plus minted minus burned
+func (this *invariantChecker) checkSupplies(snapshot scom.StoreSnapshot, height uint32) error {
+	for contract, supply := range this.supplies {
+		balance, err := sumTokenBalances(snapshot, contract)
+		if err != nil {
+			return err
+		}
+		if supply.base+supply.minted != balance+supply.burned {
+			this.ledger.addInvariantViolation(INVARIANT_SUPPLY, height,
+				fmt.Sprintf("token %s balances %d, want genesis %d + minted %d - burned %d", contract.ToHexString(),
+					balance, supply.base, supply.minted, supply.burned))
+		}
+	}
+	return nil
+}
+
+//sumTokenBalances sum the balances of ont or ong, which are the storage keys of contract address followed by account address
+func sumTokenBalances(snapshot scom.StoreSnapshot, contract common.Address) (uint64, error) {
+	prefix := append([]byte{byte(scom.ST_STORAGE)}, contract[:]...)
+	iter := snapshot.NewIterator(prefix)
+	defer iter.Release()
+	sum := uint64(0)
+	for iter.Next() {
+		if len(iter.Key()) != len(prefix)+common.ADDR_LEN {
+			continue
+		}
+		value, err := states.GetValueFromRawStorageItem(iter.Value())
+		if err != nil {
+			return 0, fmt.Errorf("balance storage item error %s", err)
+		}
+		balance, eof := common.NewZeroCopySource(value).NextUint64()
+		if eof {
+			return 0, fmt.Errorf("balance storage value error %s", common.ErrIrregularData)
+		}
+		sum += balance
+	}
+	return sum, iter.Error()
+}
+
+//snapshotCurrentHeight return the current block height saved in the state store snapshot
+func snapshotCurrentHeight(snapshot scom.StoreSnapshot) (uint32, error) {
+	iter := snapshot.NewIterator([]byte{byte(scom.SYS_CURRENT_BLOCK)})
+	defer iter.Release()
+	if !iter.Next() {
+		return 0, fmt.Errorf("current block of state store snapshot %s", scom.ErrNotFound)
+	}
+	source := common.NewZeroCopySource(iter.Value())
+	source.Skip(common.UINT256_SIZE)
+	height, eof := source.NextUint32()
+	if eof {
+		return 0, fmt.Errorf("current block of state store snapshot %s", common.ErrIrregularData)
+	}
+	return height, nil
+}
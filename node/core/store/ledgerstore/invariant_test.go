@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/states"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/smartcontract/service/native/ont"
+	"github.com/ontio/layer2/node/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSumTokenBalances(t *testing.T) {
+	db := NewMemStateStore(0)
+	contract := utils.OntContractAddress
+	balance := func(addr common.Address, value uint64) {
+		key := append([]byte{byte(scom.ST_STORAGE)}, ont.GenBalanceKey(contract, addr)...)
+		db.store.BatchPut(key, utils.GenUInt64StorageItem(value).ToArray())
+	}
+	db.NewBatch()
+	balance(common.Address{1}, 100)
+	balance(common.Address{2}, 200)
+	balance(utils.OngContractAddress, 300)
+	supplyKey := append([]byte{byte(scom.ST_STORAGE)}, ont.GenTotalSupplyKey(contract)...)
+	db.store.BatchPut(supplyKey, utils.GenUInt64StorageItem(1000).ToArray())
+	assert.Nil(t, db.SaveCurrentBlock(7, common.Uint256{1}))
+	assert.Nil(t, db.CommitTo())
+
+	snapshot, err := db.store.NewSnapshot()
+	assert.Nil(t, err)
+	defer snapshot.Release()
+	db.NewBatch()
+	balance(common.Address{3}, 400)
+	assert.Nil(t, db.SaveCurrentBlock(8, common.Uint256{2}))
+	assert.Nil(t, db.CommitTo())
+
+	sum, err := sumTokenBalances(snapshot, contract)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(600), sum)
+	height, err := snapshotCurrentHeight(snapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(7), height)
+
+	item, err := db.GetStorageState(&states.StorageKey{ContractAddress: contract, Key: []byte(ont.TOTAL_SUPPLY_NAME)})
+	assert.Nil(t, err)
+	assert.Equal(t, utils.GenUInt64StorageItem(1000).Value, item.Value)
+}
+
+func TestParseTransferStates(t *testing.T) {
+	to := common.Address{1}
+	var notifyStates interface{}
+	data := `["transfer","` + common.ADDRESS_EMPTY.ToBase58() + `","` + to.ToBase58() + `",1000000000000000001]`
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	assert.Nil(t, decoder.Decode(&notifyStates))
+	from, addr, amount, ok := parseTransferStates(notifyStates)
+	assert.True(t, ok)
+	assert.Equal(t, common.ADDRESS_EMPTY, from)
+	assert.Equal(t, to, addr)
+	assert.Equal(t, uint64(1000000000000000001), amount)
+
+	_, _, _, ok = parseTransferStates([]interface{}{"approve", "a", "b", json.Number("1")})
+	assert.False(t, ok)
+}
+
+func TestInvariantViolations(t *testing.T) {
+	ledgerStore := &LedgerStoreImp{}
+	checker := &invariantChecker{ledger: ledgerStore}
+	checker.checkStoreHeights([3]uint32{5, 5, 6})
+	assert.Equal(t, uint64(0), ledgerStore.GetInvariantStatus().ViolationCount)
+
+	checker.checkStoreHeights([3]uint32{7, 6, 6})
+	status := ledgerStore.GetInvariantStatus()
+	assert.Equal(t, uint64(1), status.ViolationCount)
+	assert.Equal(t, INVARIANT_HEIGHT, status.Violations[0].Invariant)
+
+	checker.checkStoreHeights([3]uint32{4, 4, 4})
+	assert.Equal(t, uint64(4), ledgerStore.GetInvariantStatus().ViolationCount)
+
+	for i := 0; i < INVARIANT_MAX_VIOLATIONS; i++ {
+		ledgerStore.addInvariantViolation(INVARIANT_SUPPLY, uint32(i), "test")
+	}
+	status = ledgerStore.GetInvariantStatus()
+	assert.Equal(t, uint64(4+INVARIANT_MAX_VIOLATIONS), status.ViolationCount)
+	assert.Equal(t, INVARIANT_MAX_VIOLATIONS, len(status.Violations))
+	assert.Equal(t, uint32(INVARIANT_MAX_VIOLATIONS-1), status.Violations[INVARIANT_MAX_VIOLATIONS-1].Height)
+}
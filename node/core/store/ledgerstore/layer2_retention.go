@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"fmt"
+
+	"github.com/ontio/layer2/node/common/log"
+)
+
+const LAYER2_PRUNE_BATCH = uint32(1000) //Max count of heights pruned in one batch
+
+//SetLayer2Retention keep the layer2 state messages and account state leaves of the latest retention blocks, and of the
+//checkpoint heights which are multiple of checkpointInterval for withdraw proofs. The older ones are pruned now and
+//after each block saved, zero retention keeps all of them
+func (this *LedgerStoreImp) SetLayer2Retention(retention, checkpointInterval uint32) error {
+	this.layer2PruneLock.Lock()
+	defer this.layer2PruneLock.Unlock()
+	this.layer2Retention = retention
+	this.layer2Checkpoint = checkpointInterval
+	if retention == 0 {
+		return nil
+	}
+	log.Infof("layer2 state retention %d blocks, checkpoint interval %d, pruned to height %d", retention,
+		checkpointInterval, this.layer2PrunedHeight)
+	return this.pruneLayer2States(this.GetCurrentBlockHeight())
+}
+
+//pruneLayer2States prune the layer2 states out of retention at current block height, the caller should hold layer2PruneLock
+func (this *LedgerStoreImp) pruneLayer2States(currentHeight uint32) error {
+	if this.layer2Retention == 0 || currentHeight <= this.layer2Retention {
+		return nil
+	}
+	target := currentHeight - this.layer2Retention
+	for this.layer2PrunedHeight < target {
+		start := this.layer2PrunedHeight + 1
+		end := target
+		if end-start >= LAYER2_PRUNE_BATCH {
+			end = start + LAYER2_PRUNE_BATCH - 1
+		}
+		this.stateStore.NewBatch()
+		this.layer2Store.NewBatch()
+		for height := start; height <= end; height++ {
+			if this.isLayer2Checkpoint(height) {
+				continue
+			}
+			this.stateStore.DeleteLayer2States(height)
+			this.layer2Store.DeleteLayer2State(height)
+		}
+		//the pruned height is saved after the states deleted, so the interrupted pruning is redone at next start
+		err := this.stateStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("stateStore.CommitTo error %s", err)
+		}
+		this.layer2Store.SavePrunedHeight(end)
+		err = this.layer2Store.CommitTo()
+		if err != nil {
+			return fmt.Errorf("layer2Store.CommitTo error %s", err)
+		}
+		this.layer2PrunedHeight = end
+		log.Debugf("pruned layer2 states from height %d to %d", start, end)
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) isLayer2Checkpoint(height uint32) bool {
+	return this.layer2Checkpoint != 0 && height%this.layer2Checkpoint == 0
+}
+
+//isLayer2StatePruned return whether the missing layer2 state of height has been pruned
+func (this *LedgerStoreImp) isLayer2StatePruned(height uint32) bool {
+	this.layer2PruneLock.RLock()
+	defer this.layer2PruneLock.RUnlock()
+	return height != 0 && height <= this.layer2PrunedHeight
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayer2Retention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retention")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	hashes := make([]common.Uint256, 0, 2500)
+	for i := 0; i < 2500; i++ {
+		hashes = append(hashes, common.Uint256{byte(i), byte(i >> 8)})
+	}
+	ledgerStore := newTestBackupLedger(t, dir, hashes)
+	defer closeTestBackupLedger(ledgerStore)
+	ledgerStore.stateStore.NewBatch()
+	for i := range hashes {
+		height := uint32(i)
+		assert.Nil(t, ledgerStore.layer2Store.SaveMsgToLayer2Store(&types.Layer2State{Height: height}))
+		assert.Nil(t, ledgerStore.stateStore.SaveLayer2States(height, []common.Uint256{{1}}))
+		ledgerStore.stateStore.SaveLayer2AccountStates(height, []*store.AccountState{{Value: []byte{1}}})
+	}
+	assert.Nil(t, ledgerStore.stateStore.CommitTo())
+
+	assert.Nil(t, ledgerStore.SetLayer2Retention(0, 100))
+	state, err := ledgerStore.GetLayer2State(1)
+	assert.Nil(t, err)
+	assert.NotNil(t, state)
+
+	assert.Nil(t, ledgerStore.SetLayer2Retention(100, 1000))
+	prunedHeight, err := ledgerStore.layer2Store.GetPrunedHeight()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2399), prunedHeight)
+	for _, height := range []uint32{0, 1000, 2000, 2400, 2499} {
+		state, err = ledgerStore.GetLayer2State(height)
+		assert.Nil(t, err)
+		assert.Equal(t, height, state.Height)
+		states, err := ledgerStore.GetLayer2AccountStates(height)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(states.Leaves))
+		assert.Equal(t, 1, len(states.Accounts))
+	}
+	for _, height := range []uint32{1, 999, 1001, 2399} {
+		_, err = ledgerStore.GetLayer2State(height)
+		assert.NotNil(t, err)
+		_, err = ledgerStore.GetLayer2AccountStates(height)
+		assert.NotNil(t, err)
+	}
+
+	//the pruning continues with new blocks
+	ledgerStore.layer2PruneLock.Lock()
+	assert.Nil(t, ledgerStore.pruneLayer2States(2500))
+	ledgerStore.layer2PruneLock.Unlock()
+	_, err = ledgerStore.GetLayer2State(2400)
+	assert.NotNil(t, err)
+	state, err = ledgerStore.GetLayer2State(2401)
+	assert.Nil(t, err)
+	assert.NotNil(t, state)
+}
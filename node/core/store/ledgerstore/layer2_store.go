@@ -24,6 +24,7 @@ import (
 	scom "github.com/ontio/layer2/node/core/store/common"
 	"github.com/ontio/layer2/node/core/store/leveldbstore"
 	"github.com/ontio/layer2/node/core/types"
+	"io"
 	"os"
 )
 
@@ -77,6 +78,70 @@ func (this *Layer2Store) GetLayer2State(height uint32) (*types.Layer2State, erro
 	return msg, nil
 }
 
+//GetLayer2States return the layer2 state messages in [fromHeight, toHeight] in height order, the heights without message
+//are skipped
+func (this *Layer2Store) GetLayer2States(fromHeight, toHeight uint32) ([]*types.Layer2State, error) {
+	msgs := make([]*types.Layer2State, 0)
+	for height := fromHeight; height <= toHeight; height++ {
+		msg, err := this.GetLayer2State(height)
+		if err != nil {
+			return nil, fmt.Errorf("GetLayer2State %d error %s", height, err)
+		}
+		if msg != nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+//NewBatch start a commit batch
+func (this *Layer2Store) NewBatch() {
+	this.store.NewBatch()
+}
+
+//DeleteLayer2State delete the layer2 state message of height in batch
+func (this *Layer2Store) DeleteLayer2State(height uint32) {
+	this.store.BatchDelete(this.genLayer2StateKey(height))
+}
+
+//SavePrunedHeight save the height to which the layer2 states are pruned in batch
+func (this *Layer2Store) SavePrunedHeight(height uint32) {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(height)
+	this.store.BatchPut([]byte{byte(scom.SYS_LAYER2_PRUNED_HEIGHT)}, sink.Bytes())
+}
+
+//GetPrunedHeight return the height to which the layer2 states are pruned, 0 if never pruned
+func (this *Layer2Store) GetPrunedHeight() (uint32, error) {
+	data, err := this.store.Get([]byte{byte(scom.SYS_LAYER2_PRUNED_HEIGHT)})
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(data).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+//CommitTo commit the batch to store
+func (this *Layer2Store) CommitTo() error {
+	return this.store.BatchCommit()
+}
+
+//Close layer2 store
+func (this *Layer2Store) Close() error {
+	return this.store.Close()
+}
+
+//Compact layer2 store
+func (this *Layer2Store) Compact() error {
+	return this.store.Compact()
+}
+
 func (this *Layer2Store) genLayer2StateKey(height uint32) []byte {
 	temp := make([]byte, 5)
 	temp[0] = byte(scom.SYS_CROSS_CHAIN_MSG)
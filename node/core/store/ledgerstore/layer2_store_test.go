@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLayer2States(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layer2")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ledgerStore := newTestBackupLedger(t, dir, []common.Uint256{{1}, {2}, {3}, {4}, {5}})
+	defer closeTestBackupLedger(ledgerStore)
+	for _, height := range []uint32{1, 2, 4} {
+		assert.Nil(t, ledgerStore.layer2Store.SaveMsgToLayer2Store(&types.Layer2State{Height: height}))
+	}
+
+	heightsOf := func(msgs []*types.Layer2State) []uint32 {
+		heights := make([]uint32, 0, len(msgs))
+		for _, msg := range msgs {
+			heights = append(heights, msg.Height)
+		}
+		return heights
+	}
+	msgs, err := ledgerStore.GetLayer2States(0, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 2}, heightsOf(msgs))
+	msgs, err = ledgerStore.GetLayer2States(2, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{2, 4}, heightsOf(msgs))
+	msgs, err = ledgerStore.GetLayer2States(10, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(msgs))
+	_, err = ledgerStore.GetLayer2States(3, 2)
+	assert.NotNil(t, err)
+}
@@ -21,17 +21,17 @@ package ledgerstore
 import (
 	"bytes"
 	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"github.com/ontio/layer2/node/merkle"
 	types2 "github.com/ontio/layer2/node/vm/neovm/types"
 	"hash"
 	"math"
 	"os"
-	"sort"
+	"runtime"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/golang-lru"
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/common/config"
@@ -41,6 +41,7 @@ import (
 	"github.com/ontio/layer2/node/core/states"
 	"github.com/ontio/layer2/node/core/store"
 	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
 	"github.com/ontio/layer2/node/core/store/overlaydb"
 	"github.com/ontio/layer2/node/core/types"
 	"github.com/ontio/layer2/node/errors"
@@ -55,7 +56,11 @@ import (
 
 const (
 	SYSTEM_VERSION          = byte(1)      //Version of ledger store
-	HEADER_INDEX_BATCH_SIZE = uint32(2000) //Bath size of saving header index
+	HEADER_INDEX_BATCH_SIZE = uint32(2000) //Default bath size of saving header index
+	HEADER_INDEX_CACHE_SIZE = 4096         //Count of latest accessed header index cached in memory
+
+	PRE_EXEC_BATCH_TX_TIMEOUT      = 5 * time.Second  //Time limit of each transaction in pre-execution batch
+	PRE_EXEC_BATCH_TX_MEMORY_LIMIT = 64 * 1024 * 1024 //State cache bytes limit of each transaction in pre-execution batch
 )
 
 var (
@@ -67,9 +72,11 @@ var (
 )
 
 type PrexecuteParam struct {
-	JitMode    bool
-	WasmFactor uint64
-	MinGas     bool
+	JitMode     bool
+	WasmFactor  uint64
+	MinGas      bool
+	Timeout     time.Duration //Time limit of execution, 0 means no limit
+	MemoryLimit int           //State cache bytes limit of execution, 0 means no limit
 }
 
 //LedgerStoreImp is main store struct fo ledger
@@ -81,25 +88,84 @@ type LedgerStoreImp struct {
 	storedIndexCount     uint32                           //record the count of have saved block index
 	currBlockHeight      uint32                           //Current block height
 	currBlockHash        common.Uint256                   //Current block hash
-	headerIndex          map[uint32]common.Uint256        //Header index, Mapping header height => block hash
+	headerHeight         uint32                           //Current header height
+	headerHash           common.Uint256                   //Current header hash
+	headerCache          *lru.Cache                       //Header index cache, Mapping header height => block hash
+	headerIndexBatchSize uint32                           //Batch size of saving header index
 	savingBlockSemaphore chan bool
 	closing              bool
 	lock                 sync.RWMutex
 	stateHashCheckHeight uint32
+	compactLock          sync.Mutex                       //Serialize compaction, backup and close
+	compactClosed        bool                             //Reject compaction after ledger closed
+	compactExit          chan struct{}                    //Stop compaction scheduler, nil if not started
+	recoverLock          sync.RWMutex
+	recoverStatus        store.RecoverStatus              //Progress of the recovery at startup
+	invariantLock        sync.RWMutex
+	invariantStatus      store.InvariantStatus            //Progress and violations of invariant checker
+	invariantChecker     *invariantChecker                //Nil if invariant checker not started
+	layer2PruneLock      sync.RWMutex
+	layer2Retention      uint32                           //Count of latest blocks whose layer2 states are kept, 0 to keep all
+	layer2Checkpoint     uint32                           //Layer2 states of heights which are multiple of it are always kept
+	layer2PrunedHeight   uint32                           //Layer2 states up to the height are pruned except checkpoints
+	stateHistoryStart    uint32                           //First block height whose state history is saved, 0 if disabled
+	snapshotDir          string                           //Dir of state snapshots written at checkpoints, empty if disabled
+	snapshotWriting      int32                            //1 if a checkpoint snapshot is being written
+	eventSubLock         sync.Mutex
+	eventSubs            map[uint64]*eventSubscriber      //Event subscriptions by id
+	eventSubNextId       uint64
+	eventSubClosed       bool                             //Reject event subscription after ledger closed
+	metrics              *ledgerMetrics
+	accountRules         []*AccountRootRule               //Rules of storage writes which feed the account state root
+	accountTreeHeight    uint32                           //First block whose account state root is the sparse merkle tree root
+	gasSchedule          []*GasFork                       //Gas table overridden by hard forks, sorted by height
+	fullStateRoot        int32                            //1 if the full state merkle patricia trie is maintained
+	preExecCache         *lru.Cache                       //Pre-execution results on the current block, Mapping preExecCacheKey => result
+	gasStats             *gasStatsWindow                  //Gas statistics of latest blocks
+	readOnly             bool                             //Ledger is a read-only view of the data dir written by other process
+	readOnlyStores       []*leveldbstore.LevelDBStore     //Read-only views of leveldb refreshed together
+	refreshExit          chan struct{}                    //Stop refreshing read-only views, nil if not started
+	reEncryptExit        chan struct{}                    //Stop re-encrypting store values, nil if not started
 }
 
 //NewLedgerStore return LedgerStoreImp instance
 func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, error) {
+	headerCache, err := lru.New(HEADER_INDEX_CACHE_SIZE)
+	if err != nil {
+		return nil, fmt.Errorf("NewHeaderCache error %s", err)
+	}
+	preExecCache, err := lru.New(PRE_EXEC_CACHE_SIZE)
+	if err != nil {
+		return nil, fmt.Errorf("NewPreExecCache error %s", err)
+	}
 	ledgerStore := &LedgerStoreImp{
-		headerIndex:          make(map[uint32]common.Uint256),
+		headerCache:          headerCache,
+		preExecCache:         preExecCache,
+		headerIndexBatchSize: HEADER_INDEX_BATCH_SIZE,
 		savingBlockSemaphore: make(chan bool, 1),
 		stateHashCheckHeight: stateHashHeight,
+		metrics:              newLedgerMetrics(),
+		gasStats:             newGasStatsWindow(),
+	}
+	if size := config.DefConfig.Common.HeaderIndexBatchSize; size > 0 {
+		ledgerStore.headerIndexBatchSize = uint32(size)
+	}
+	ledgerStore.accountRules, err = NewAccountRootRules(config.DefConfig.ChainSpec)
+	if err != nil {
+		return nil, fmt.Errorf("NewAccountRootRules error %s", err)
+	}
+	ledgerStore.accountTreeHeight = accountTreeActivation(config.DefConfig.ChainSpec)
+	ledgerStore.gasSchedule, err = NewGasSchedule(config.DefConfig.ChainSpec)
+	if err != nil {
+		return nil, fmt.Errorf("NewGasSchedule error %s", err)
 	}
 
 	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), true)
 	if err != nil {
 		return nil, fmt.Errorf("NewBlockStore error %s", err)
 	}
+	blockStore.enableTxIndex = config.DefConfig.Common.EnableTxIndex
+	blockStore.enableCompression = config.DefConfig.Common.EnableCompression
 	ledgerStore.blockStore = blockStore
 
 	layer2Store, err := NewLayer2Store(dataDir)
@@ -120,13 +186,29 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, er
 	if err != nil {
 		return nil, fmt.Errorf("NewEventStore error %s", err)
 	}
+	eventState.enableCompression = config.DefConfig.Common.EnableCompression
 	ledgerStore.eventStore = eventState
 
+	if config.DefConfig.Common.CompactWindow != "" {
+		window, err := ParseCompactWindow(config.DefConfig.Common.CompactWindow)
+		if err != nil {
+			return nil, err
+		}
+		ledgerStore.startCompactScheduler(window)
+	}
+	ledgerStore.startReEncryption()
+	if events.DefBlockFeed != nil {
+		events.DefBlockFeed.SetLoader(ledgerStore.GetBlockByHeight)
+	}
+	ledgerStore.registerGauges()
 	return ledgerStore, nil
 }
 
 //InitLedgerStoreWithGenesisBlock init the ledger store with genesis block. It's the first operation after NewLedgerStore.
 func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.Block, defaultBookkeeper []keypair.PublicKey) error {
+	if this.readOnly {
+		return this.initReadOnly(genesisBlock)
+	}
 	hasInit, err := this.hasAlreadyInitGenesisBlock()
 	if err != nil {
 		return fmt.Errorf("hasAlreadyInit error %s", err)
@@ -198,7 +280,11 @@ func (this *LedgerStoreImp) initGenesisBlock() error {
 }
 
 func (this *LedgerStoreImp) init() error {
-	err := this.loadCurrentBlock()
+	err := this.checkIntegrity()
+	if err != nil {
+		return fmt.Errorf("checkIntegrity error %s", err)
+	}
+	err = this.loadCurrentBlock()
 	if err != nil {
 		return fmt.Errorf("loadCurrentBlock error %s", err)
 	}
@@ -206,6 +292,14 @@ func (this *LedgerStoreImp) init() error {
 	if err != nil {
 		return fmt.Errorf("loadHeaderIndexList error %s", err)
 	}
+	this.layer2PrunedHeight, err = this.layer2Store.GetPrunedHeight()
+	if err != nil {
+		return fmt.Errorf("layer2Store.GetPrunedHeight error %s", err)
+	}
+	err = this.initAccountTree()
+	if err != nil {
+		return fmt.Errorf("initAccountTree error %s", err)
+	}
 	err = this.recoverStore()
 	if err != nil {
 		return fmt.Errorf("recoverStore error %s", err)
@@ -224,14 +318,14 @@ func (this *LedgerStoreImp) loadCurrentBlock() error {
 	return nil
 }
 
+//loadHeaderIndexList load the current header and the block hashes not saved in header index list yet. Other block
+//hashes are read from block store when queried
 func (this *LedgerStoreImp) loadHeaderIndexList() error {
 	currBlockHeight := this.GetCurrentBlockHeight()
-	headerIndex, err := this.blockStore.GetHeaderIndexList()
+	storeIndexCount, err := this.blockStore.GetHeaderIndexCount()
 	if err != nil {
 		return fmt.Errorf("LoadHeaderIndexList error %s", err)
 	}
-	storeIndexCount := uint32(len(headerIndex))
-	this.headerIndex = headerIndex
 	this.storedIndexCount = storeIndexCount
 
 	for i := storeIndexCount; i <= currBlockHeight; i++ {
@@ -243,46 +337,7 @@ func (this *LedgerStoreImp) loadHeaderIndexList() error {
 		if blockHash == common.UINT256_EMPTY {
 			return fmt.Errorf("LoadBlockHash height %d hash nil", height)
 		}
-		this.headerIndex[height] = blockHash
-	}
-	return nil
-}
-
-func (this *LedgerStoreImp) recoverStore() error {
-	blockHeight := this.GetCurrentBlockHeight()
-
-	_, stateHeight, err := this.stateStore.GetCurrentBlock()
-	if err != nil {
-		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
-	}
-	for i := stateHeight; i < blockHeight; i++ {
-		blockHash, err := this.blockStore.GetBlockHash(i)
-		if err != nil {
-			return fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", i, err)
-		}
-		block, err := this.blockStore.GetBlock(blockHash)
-		if err != nil {
-			return fmt.Errorf("blockStore.GetBlock height:%d error:%s", i, err)
-		}
-		this.eventStore.NewBatch()
-		this.stateStore.NewBatch()
-		result, err := this.executeBlock(block)
-		if err != nil {
-			return err
-		}
-		err = this.saveBlockToStateStore(block, result)
-		if err != nil {
-			return fmt.Errorf("save to state store height:%d error:%s", i, err)
-		}
-		this.saveBlockToEventStore(block)
-		err = this.eventStore.CommitTo()
-		if err != nil {
-			return fmt.Errorf("eventStore.CommitTo height:%d error %s", i, err)
-		}
-		err = this.stateStore.CommitTo()
-		if err != nil {
-			return fmt.Errorf("stateStore.CommitTo height:%d error %s", i, err)
-		}
+		this.setHeaderIndex(height, blockHash)
 	}
 	return nil
 }
@@ -290,16 +345,29 @@ func (this *LedgerStoreImp) recoverStore() error {
 func (this *LedgerStoreImp) setHeaderIndex(height uint32, blockHash common.Uint256) {
 	this.lock.Lock()
 	defer this.lock.Unlock()
-	this.headerIndex[height] = blockHash
+	this.headerCache.Add(height, blockHash)
+	if height >= this.headerHeight {
+		this.headerHeight = height
+		this.headerHash = blockHash
+	}
 }
 
+//getHeaderIndex return the block hash of height from header index cache, or from block store if not cached
 func (this *LedgerStoreImp) getHeaderIndex(height uint32) common.Uint256 {
 	this.lock.RLock()
-	defer this.lock.RUnlock()
-	blockHash, ok := this.headerIndex[height]
-	if !ok {
+	if height > this.headerHeight || this.headerHash == common.UINT256_EMPTY {
+		this.lock.RUnlock()
 		return common.Uint256{}
 	}
+	this.lock.RUnlock()
+	if blockHash, ok := this.headerCache.Get(height); ok {
+		return blockHash.(common.Uint256)
+	}
+	blockHash, err := this.blockStore.GetBlockHash(height)
+	if err != nil {
+		return common.Uint256{}
+	}
+	this.headerCache.Add(height, blockHash)
 	return blockHash
 }
 
@@ -308,22 +376,14 @@ func (this *LedgerStoreImp) getHeaderIndex(height uint32) common.Uint256 {
 func (this *LedgerStoreImp) GetCurrentHeaderHeight() uint32 {
 	this.lock.RLock()
 	defer this.lock.RUnlock()
-	size := len(this.headerIndex)
-	if size == 0 {
-		return 0
-	}
-	return uint32(size) - 1
+	return this.headerHeight
 }
 
 //GetCurrentHeaderHash return the current header hash. The current header means the latest header.
 func (this *LedgerStoreImp) GetCurrentHeaderHash() common.Uint256 {
 	this.lock.RLock()
 	defer this.lock.RUnlock()
-	size := len(this.headerIndex)
-	if size == 0 {
-		return common.Uint256{}
-	}
-	return this.headerIndex[uint32(size)-1]
+	return this.headerHash
 }
 
 func (this *LedgerStoreImp) setCurrentBlock(height uint32, blockHash common.Uint256) {
@@ -369,7 +429,11 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header) (error) {
 	if prevHeader == nil {
 		return fmt.Errorf("cannot find pre header by blockHash %s", prevHeaderHash.ToHexString())
 	}
+	return verifyHeaderWithPrev(header, prevHeader)
+}
 
+//verifyHeaderWithPrev verify the height, timestamp and bookkeeper signature of header against its prev header
+func verifyHeaderWithPrev(header, prevHeader *types.Header) error {
 	if prevHeader.Height+1 != header.Height {
 		return fmt.Errorf("block height is incorrect")
 	}
@@ -396,7 +460,7 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header) (error) {
 	return nil
 }
 
-func (this *LedgerStoreImp) verifyLayer2State(layer2State *types.Layer2State, bookkeepers []keypair.PublicKey) error {
+func verifyLayer2State(layer2State *types.Layer2State, bookkeepers []keypair.PublicKey) error {
 	hash := layer2State.Hash()
 	m := len(bookkeepers) - (len(bookkeepers)-1)/3
 	err := signature.VerifyMultiSignature(hash[:], bookkeepers, m, layer2State.SigData)
@@ -412,6 +476,9 @@ func (this *LedgerStoreImp) GetStateMerkleRoot(height uint32) (common.Uint256, e
 }
 
 func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.ExecuteResult, err error) {
+	if this.readOnly {
+		return result, ErrReadOnlyLedger
+	}
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
 	currBlockHeight := this.GetCurrentBlockHeight()
@@ -431,6 +498,9 @@ func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.Execu
 }
 
 func (this *LedgerStoreImp) SubmitBlock(block *types.Block, layer2State *types.Layer2State, result store.ExecuteResult) error {
+	if this.readOnly {
+		return ErrReadOnlyLedger
+	}
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
 	if this.closing {
@@ -466,7 +536,7 @@ func (this *LedgerStoreImp) SubmitBlock(block *types.Block, layer2State *types.L
 			return fmt.Errorf("layer2 state root compare fail, expected:%x actual:%x", ccMsg.StatesRoot, root)
 		}
 		*/
-		if err := this.verifyLayer2State(layer2State, block.Header.Bookkeepers); err != nil {
+		if err := verifyLayer2State(layer2State, block.Header.Bookkeepers); err != nil {
 			return fmt.Errorf("verifyLayer2State error: %s", err)
 		}
 	}
@@ -478,7 +548,7 @@ func (this *LedgerStoreImp) SubmitBlock(block *types.Block, layer2State *types.L
 	return nil
 }
 
-func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
+func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block, sysFee common.Fixed64) error {
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
 
@@ -492,7 +562,7 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 		return fmt.Errorf("SaveCurrentBlock error %s", err)
 	}
 	this.blockStore.SaveBlockHash(blockHeight, blockHash)
-	err = this.blockStore.SaveBlock(block)
+	err = this.blockStore.SaveBlockWithSysFee(block, sysFee)
 	if err != nil {
 		return fmt.Errorf("SaveBlock height %d hash %s error %s", blockHeight, blockHash.ToHexString(), err)
 	}
@@ -500,6 +570,7 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 }
 
 func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.ExecuteResult, err error) {
+	start := time.Now()
 	overlay := this.stateStore.NewOverlayDB()
 	if block.Header.Height != 0 {
 		config := &smartcontract.Config{
@@ -513,14 +584,7 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 			return
 		}
 	}
-	gasTable := make(map[string]uint64)
-	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
-		key := k.(string)
-		val := value.(uint64)
-		gasTable[key] = val
-
-		return true
-	})
+	gasTable := this.gasTableAt(block.Header.Height)
 
 	cache := storage.NewCacheDB(overlay)
 	for _, tx := range block.Transactions {
@@ -548,73 +612,20 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 	} else {
 		result.MerkleRoot = this.stateStore.GetStateMerkleRootWithNewHash(result.Hash)
 	}
-	result.UpdatedAccountStateRoot, result.UpdatedAccountState = this.calculateChangeStateRoot(cache)
-	log.Infof("New state root: %s", result.UpdatedAccountStateRoot.ToHexString())
-	return
-}
-
-type KeyState struct {
-	Key      []byte
-	Value    []byte
-}
-
-type KeyStateSlice []*KeyState
-func (this KeyStateSlice) Len() int {
-	return len(this)
-}
-func (this KeyStateSlice) Swap(i, j int) {
-	key := this[i]
-	this[i] = this[j]
-	this[j] = key
-}
-func (this KeyStateSlice) Less(i, j int) bool {
-	return bytes.Compare(this[i].Key, this[j].Key) > 0
-}
-
-func (this *LedgerStoreImp) isAccountUpdateStore(key, val []byte) bool {
-	// [ST_STORAGE:ContractAddr:UserAddr] = 1 + 20 + 20
-	if len(key) != 41 {
-		return false
+	if block.Header.Height < this.accountTreeHeight {
+		result.UpdatedAccountStateRoot, result.UpdatedAccountState, result.UpdatedAccounts = calculateChangeStateRoot(cache)
 	}
-	return true
-}
-
-func (this *LedgerStoreImp) calculateChangeStateRoot(cache *storage.CacheDB) (common.Uint256, []common.Uint256) {
-	memdb := cache.GetMemDb()
-	states := make(map[string]*KeyState, 0)
-	memdb.ForEach(func(key, val []byte) {
-		if this.isAccountUpdateStore(key, val) == false {
-			return
-		}
-		accountAddr, _ := common.AddressParseFromBytes(key[common.ADDR_LEN+1:])
-		item, ok := states[hex.EncodeToString(accountAddr[:])]
-		if !ok {
-			states[hex.EncodeToString(accountAddr[:])] = &KeyState{
-				Key: accountAddr[:],
-				Value: val,
-			}
-		} else {
-			item.Value = append(item.Value, val...)
-		}
-	})
-	stateSlice := make([]*KeyState, 0)
-	for _, value := range states {
-		stateSlice = append(stateSlice, value)
-	}
-	sort.Sort(KeyStateSlice(stateSlice))
-	hashs := make([]common.Uint256, 0)
-	for _, item := range states {
-		state := sha256.New()
-		var result common.Uint256
-		state.Write(item.Value)
-		state.Sum(result[:0])
-		hashs = append(hashs, result)
-	}
-	if len(hashs) == 0 {
-		return common.UINT256_EMPTY, nil
-	} else {
-		return merkle.TreeHasher{}.HashFullTreeWithLeafHash(hashs), hashs
+	err = this.updateAccountTree(block.Header.Height, overlay, &result)
+	if err != nil {
+		return
+	}
+	err = this.updateFullStateTree(block.Header.Height, &result)
+	if err != nil {
+		return
 	}
+	log.Infof("New state root: %s", result.UpdatedAccountStateRoot.ToHexString())
+	this.metrics.updateExecuteMetrics(start, overlay, &result)
+	return
 }
 
 func calculateTotalStateHash(overlay *overlaydb.OverlayDB) (result common.Uint256, err error) {
@@ -654,6 +665,12 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 	for _, notify := range result.Notify {
 		SaveNotify(this.eventStore, notify.TxHash, notify)
 	}
+	this.saveReceipts(block, result.Notify)
+	if config.DefConfig.Common.EnableEventLog {
+		this.eventStore.SaveEventBloom(blockHeight, CreateEventBloom(result.Notify))
+		this.eventStore.SaveContractEventIndex(blockHeight, result.Notify)
+		this.eventStore.SaveAddressEventIndex(blockHeight, result.Notify)
+	}
 
 	err := this.stateStore.AddStateMerkleTreeRoot(blockHeight, result.Hash)
 	if err != nil {
@@ -674,9 +691,23 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 	if err != nil {
 		return fmt.Errorf("SaveLayer2States error %s", err)
 	}
+	this.stateStore.SaveLayer2AccountStates(blockHeight, result.UpdatedAccounts)
+	this.stateStore.SaveStateTreeNodes(result.StateTreeNodes)
+	if blockHeight >= this.accountTreeHeight {
+		this.stateStore.SaveAccountTreeRoot(blockHeight, result.UpdatedAccountStateRoot)
+	}
+	this.stateStore.SaveStorageTreeRoot(blockHeight, result.StorageStateRoot)
+	if result.FullStateNodes != nil {
+		this.stateStore.SaveFullStateNodes(result.FullStateNodes)
+		this.stateStore.SaveFullStateRoot(blockHeight, result.FullStateRoot)
+	}
 
 	log.Debugf("the state transition hash of block %d is:%s", blockHeight, result.Hash.ToHexString())
 
+	err = this.saveStateHistory(blockHeight, result)
+	if err != nil {
+		return fmt.Errorf("saveStateHistory error %s", err)
+	}
 	result.WriteSet.ForEach(func(key, val []byte) {
 		if len(val) == 0 {
 			this.stateStore.BatchDeleteRawKey(key)
@@ -734,37 +765,62 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, layer2Msg *types.Lay
 			block.Header.Height, blockRoot.ToHexString(), block.Header.BlockRoot.ToHexString())
 	}
 
+	start := time.Now()
 	this.blockStore.NewBatch()
 	this.stateStore.NewBatch()
 	this.eventStore.NewBatch()
-	err := this.saveBlockToBlockStore(block)
+	gasStats := newBlockGasStats(block, result.Notify)
+	err := this.saveBlockToBlockStore(block, common.Fixed64(gasStats.SysFee))
 	if err != nil {
 		return fmt.Errorf("save to block store height:%d error:%s", blockHeight, err)
 	}
+	commitStart := time.Now()
 	err = this.layer2Store.SaveMsgToLayer2Store(layer2Msg)
 	if err != nil {
 		return fmt.Errorf("save to msg layer2 state store height:%d error:%s", blockHeight, err)
 	}
+	this.metrics.updateCommitTime(DBDirLayer2, commitStart)
 	err = this.saveBlockToStateStore(block, result)
 	if err != nil {
 		return fmt.Errorf("save to state store height:%d error:%s", blockHeight, err)
 	}
+	this.stateStore.SaveGasStats(gasStats)
 	this.saveBlockToEventStore(block)
+	commitStart = time.Now()
 	err = this.blockStore.CommitTo()
 	if err != nil {
 		return fmt.Errorf("blockStore.CommitTo height:%d error %s", blockHeight, err)
 	}
+	this.metrics.updateCommitTime(DBDirBlock, commitStart)
 	// event store is idempotent to re-save when in recovering process, so save first before stateStore
+	commitStart = time.Now()
 	err = this.eventStore.CommitTo()
 	if err != nil {
 		return fmt.Errorf("eventStore.CommitTo height:%d error %s", blockHeight, err)
 	}
+	this.metrics.updateCommitTime(DBDirEvent, commitStart)
+	commitStart = time.Now()
 	err = this.stateStore.CommitTo()
 	if err != nil {
 		return fmt.Errorf("stateStore.CommitTo height:%d error %s", blockHeight, err)
 	}
+	this.metrics.updateCommitTime(DBDirState, commitStart)
 	this.setCurrentBlock(blockHeight, blockHash)
+	this.purgePreExecCache()
+	this.gasStats.add(gasStats)
+	this.metrics.updateSubmitTime(start)
+	this.notifyEventSubscribers()
+
+	this.layer2PruneLock.Lock()
+	err = this.pruneLayer2States(blockHeight)
+	this.layer2PruneLock.Unlock()
+	if err != nil {
+		log.Errorf("prune layer2 states at height %d error %s", blockHeight, err)
+	}
 
+	if events.DefBlockFeed != nil {
+		events.DefBlockFeed.Publish(block)
+	}
 	if events.DefActorPublisher != nil {
 		events.DefActorPublisher.Publish(
 			message.TOPIC_SAVE_BLOCK_COMPLETE,
@@ -805,22 +861,25 @@ func (this *LedgerStoreImp) saveHeaderIndexList() error {
 	this.lock.RLock()
 	storeCount := this.storedIndexCount
 	currHeight := this.currBlockHeight
-	if currHeight-storeCount < HEADER_INDEX_BATCH_SIZE {
-		this.lock.RUnlock()
+	batchSize := this.headerIndexBatchSize
+	this.lock.RUnlock()
+	if currHeight < storeCount || currHeight-storeCount < batchSize {
 		return nil
 	}
 
-	headerList := make([]common.Uint256, HEADER_INDEX_BATCH_SIZE)
-	for i := uint32(0); i < HEADER_INDEX_BATCH_SIZE; i++ {
+	headerList := make([]common.Uint256, batchSize)
+	for i := uint32(0); i < batchSize; i++ {
 		height := storeCount + i
-		headerList[i] = this.headerIndex[height]
+		headerList[i] = this.getHeaderIndex(height)
+		if headerList[i] == common.UINT256_EMPTY {
+			return fmt.Errorf("header index of height %d not found", height)
+		}
 	}
-	this.lock.RUnlock()
 
 	this.blockStore.SaveHeaderIndexList(storeCount, headerList)
 
 	this.lock.Lock()
-	this.storedIndexCount += HEADER_INDEX_BATCH_SIZE
+	this.storedIndexCount += batchSize
 	this.lock.Unlock()
 	return nil
 }
@@ -856,19 +915,57 @@ func (this *LedgerStoreImp) GetBlockRootWithNewTxRoots(startHeight uint32, txRoo
 }
 
 func (this *LedgerStoreImp) GetLayer2State(height uint32) (*types.Layer2State, error) {
-	return this.layer2Store.GetLayer2State(height)
+	state, err := this.layer2Store.GetLayer2State(height)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil && this.isLayer2StatePruned(height) {
+		return nil, fmt.Errorf("layer2 state of height %d is pruned", height)
+	}
+	return state, nil
 }
 
-func (this *LedgerStoreImp) GetLayer2StateProof(height uint32, key []byte) ([]byte, error) {
-	hashs, err := this.stateStore.GetLayer2States(height)
-	if err != nil {
-		return nil, fmt.Errorf("GetLayer2StateProof:%s", err)
+//GetLayer2States return the layer2 state messages in [fromHeight, toHeight], the range is cut off at current block height
+//and the heights without message or pruned are skipped
+func (this *LedgerStoreImp) GetLayer2States(fromHeight, toHeight uint32) ([]*types.Layer2State, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height %d is larger than to height %d", fromHeight, toHeight)
 	}
-	path, err := merkle.MerkleLeafPath(key, hashs)
-	if err != nil {
-		return nil, err
+	currentHeight := this.GetCurrentBlockHeight()
+	if toHeight > currentHeight {
+		toHeight = currentHeight
+	}
+	if fromHeight > toHeight {
+		return []*types.Layer2State{}, nil
+	}
+	return this.layer2Store.GetLayer2States(fromHeight, toHeight)
+}
+
+//GetLayer2AccountStates return the root, leaves and accounts of UpdatedAccountStateRoot of block
+func (this *LedgerStoreImp) GetLayer2AccountStates(height uint32) (*store.Layer2AccountStates, error) {
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d is not executed", height)
 	}
-	return path, nil
+	leaves, err := this.stateStore.GetLayer2States(height)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetLayer2States error %s", err)
+	}
+	root, err := this.accountStateRoot(height)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("accountStateRoot error %s", err)
+	}
+	accounts, err := this.stateStore.GetLayer2AccountStates(height)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetLayer2AccountStates error %s", err)
+	}
+	if len(leaves) == 0 && len(accounts) == 0 && this.isLayer2StatePruned(height) {
+		return nil, fmt.Errorf("layer2 account states of height %d are pruned", height)
+	}
+	return &store.Layer2AccountStates{
+		Root:     root,
+		Leaves:   leaves,
+		Accounts: accounts,
+	}, nil
 }
 
 //GetBlockHash return the block hash by block height
@@ -916,6 +1013,29 @@ func (this *LedgerStoreImp) GetBlockByHeight(height uint32) (*types.Block, error
 	return this.GetBlockByHash(blockHash)
 }
 
+//GetBlocksByHeightRange return blocks in [start, end], the range is cut off at current block height
+func (this *LedgerStoreImp) GetBlocksByHeightRange(start, end uint32) ([]*types.Block, error) {
+	if start > end {
+		return nil, fmt.Errorf("start height %d is larger than end height %d", start, end)
+	}
+	currentHeight := this.GetCurrentBlockHeight()
+	if end > currentHeight {
+		end = currentHeight
+	}
+	blocks := make([]*types.Block, 0)
+	for height := start; height <= end; height++ {
+		block, err := this.GetBlockByHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("GetBlockByHeight %d error %s", height, err)
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block at height %d not found", height)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
 //GetBookkeeperState return the bookkeeper state. Wrap function of StateStore.GetBookkeeperState
 func (this *LedgerStoreImp) GetBookkeeperState() (*states.BookkeeperState, error) {
 	return this.stateStore.GetBookkeeperState()
@@ -931,6 +1051,11 @@ func (this *LedgerStoreImp) GetContractState(contractHash common.Address) (*payl
 	return this.stateStore.GetContractState(contractHash)
 }
 
+//GetStateCacheStats return the counters of state read cache. Wrap function of StateStore.GetStateCacheStats
+func (this *LedgerStoreImp) GetStateCacheStats() store.StateCacheStats {
+	return this.stateStore.GetStateCacheStats()
+}
+
 //GetStorageItem return the storage value of the key in smart contract. Wrap function of StateStore.GetStorageState
 func (this *LedgerStoreImp) GetStorageItem(key *states.StorageKey) (*states.StorageItem, error) {
 	return this.stateStore.GetStorageState(key)
@@ -946,21 +1071,226 @@ func (this *LedgerStoreImp) GetEventNotifyByBlock(height uint32) ([]*event.Execu
 	return this.eventStore.GetEventNotifyByBlock(height)
 }
 
-//PreExecuteContract return the result of smart contract execution without commit to store
+//GetEventNotifyByBlockFilter return the event notify of contract with topic in block, empty topic match all topics.
+//Block is skipped by event bloom filter if it has no matched event
+func (this *LedgerStoreImp) GetEventNotifyByBlockFilter(height uint32, contract common.Address, topic string) ([]*event.ExecuteNotify, error) {
+	bloom, err := this.eventStore.GetEventBloom(height)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, err
+	}
+	// block saved without bloom filter need full scan
+	if bloom != nil && !MatchEventBloom(bloom, contract, topic) {
+		return nil, nil
+	}
+	notifies, err := this.eventStore.GetEventNotifyByBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*event.ExecuteNotify, 0)
+	for _, notify := range notifies {
+		if filtered := FilterEventNotify(notify, contract, topic); filtered != nil {
+			result = append(result, filtered)
+		}
+	}
+	return result, nil
+}
+
+//GetEventNotifyByContract return the event notify of contract in [fromHeight, toHeight] by the contract index of EventStore
+func (this *LedgerStoreImp) GetEventNotifyByContract(contract common.Address, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height %d is larger than to height %d", fromHeight, toHeight)
+	}
+	_, txHashes, err := this.eventStore.GetEventNotifyTxsByContract(contract, fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*event.ExecuteNotify, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		notify, err := this.eventStore.GetEventNotifyByTx(txHash)
+		if err != nil {
+			return nil, fmt.Errorf("GetEventNotifyByTx %s error %s", txHash.ToHexString(), err)
+		}
+		if filtered := FilterEventNotify(notify, contract, ""); filtered != nil {
+			result = append(result, filtered)
+		}
+	}
+	return result, nil
+}
+
+//GetEventNotifyByAddress return at most limit event notify which have address in states in [fromHeight, toHeight]
+//by the address index of EventStore, limit 0 means no limit
+func (this *LedgerStoreImp) GetEventNotifyByAddress(addr common.Address, fromHeight, toHeight uint32, limit uint32) ([]*event.ExecuteNotify, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height %d is larger than to height %d", fromHeight, toHeight)
+	}
+	_, txHashes, err := this.eventStore.GetEventNotifyTxsByAddress(addr, fromHeight, toHeight, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*event.ExecuteNotify, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		notify, err := this.eventStore.GetEventNotifyByTx(txHash)
+		if err != nil {
+			return nil, fmt.Errorf("GetEventNotifyByTx %s error %s", txHash.ToHexString(), err)
+		}
+		if filtered := FilterEventNotifyByAddress(notify, addr); filtered != nil {
+			result = append(result, filtered)
+		}
+	}
+	return result, nil
+}
+
+//GetTransactionsByAddress return at most limit transactions of payer or signer address in [fromHeight, toHeight] after the cursor
+//by the transaction index of BlockStore, limit 0 means no limit
+func (this *LedgerStoreImp) GetTransactionsByAddress(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.AddressTxPage, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height %d is larger than to height %d", fromHeight, toHeight)
+	}
+	after, err := ParseAddressTxCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	queryLimit := limit
+	if limit != 0 {
+		// one more to know whether there is next page
+		queryLimit = limit + 1
+	}
+	positions, txHashes, err := this.blockStore.GetTransactionsByAddress(addr, fromHeight, toHeight, after, queryLimit)
+	if err != nil {
+		return nil, err
+	}
+	page := &store.AddressTxPage{}
+	if limit != 0 && uint32(len(txHashes)) > limit {
+		positions, txHashes = positions[:limit], txHashes[:limit]
+		page.Next = positions[limit-1].String()
+	}
+	page.TxHashes = txHashes
+	page.Heights = make([]uint32, 0, len(positions))
+	for _, pos := range positions {
+		page.Heights = append(page.Heights, pos.Height)
+	}
+	return page, nil
+}
+
+//GetEventNotifyByBlockPage return at most limit event notify of block after the cursor, limit 0 means no limit.
+//The cursor of block is the offset of transactions which have event notify in block, empty cursor start from the first one
+func (this *LedgerStoreImp) GetEventNotifyByBlockPage(height uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	offset := uint64(0)
+	if cursor != "" {
+		var err error
+		offset, err = strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			return nil, scom.ErrInvalidCursor
+		}
+	}
+	notifies, total, err := this.eventStore.GetEventNotifyByBlockPage(height, uint32(offset), limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &store.EventNotifyPage{Notifies: notifies}
+	if limit != 0 && uint64(limit)+offset < uint64(total) {
+		page.Next = strconv.FormatUint(offset+uint64(limit), 10)
+	}
+	return page, nil
+}
+
+//GetEventNotifyByContractPage return at most limit event notify of contract in [fromHeight, toHeight] after the cursor,
+//limit 0 means no limit
+func (this *LedgerStoreImp) GetEventNotifyByContractPage(contract common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return this.getEventNotifyPageByIndex(fromHeight, toHeight, cursor, limit, this.eventStore.GetEventNotifyTxsByContractAfter,
+		contract, func(notify *event.ExecuteNotify) *event.ExecuteNotify {
+			return FilterEventNotify(notify, contract, "")
+		})
+}
+
+//GetEventNotifyByAddressPage return at most limit event notify of account address in [fromHeight, toHeight] after the cursor,
+//limit 0 means no limit
+func (this *LedgerStoreImp) GetEventNotifyByAddressPage(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return this.getEventNotifyPageByIndex(fromHeight, toHeight, cursor, limit, this.eventStore.GetEventNotifyTxsByAddressAfter,
+		addr, func(notify *event.ExecuteNotify) *event.ExecuteNotify {
+			return FilterEventNotifyByAddress(notify, addr)
+		})
+}
+
+func (this *LedgerStoreImp) getEventNotifyPageByIndex(fromHeight, toHeight uint32, cursor string, limit uint32,
+	getTxs func(common.Address, uint32, uint32, *EventIndexCursor, uint32) ([]uint32, []common.Uint256, error),
+	addr common.Address, filter func(*event.ExecuteNotify) *event.ExecuteNotify) (*store.EventNotifyPage, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height %d is larger than to height %d", fromHeight, toHeight)
+	}
+	after, err := ParseEventIndexCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	queryLimit := limit
+	if limit != 0 {
+		// one more to know whether there is next page
+		queryLimit = limit + 1
+	}
+	heights, txHashes, err := getTxs(addr, fromHeight, toHeight, after, queryLimit)
+	if err != nil {
+		return nil, err
+	}
+	page := &store.EventNotifyPage{}
+	if limit != 0 && uint32(len(txHashes)) > limit {
+		heights, txHashes = heights[:limit], txHashes[:limit]
+		last := &EventIndexCursor{Height: heights[limit-1], TxHash: txHashes[limit-1]}
+		page.Next = last.String()
+	}
+	page.Notifies = make([]*event.ExecuteNotify, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		notify, err := this.eventStore.GetEventNotifyByTx(txHash)
+		if err != nil {
+			return nil, fmt.Errorf("GetEventNotifyByTx %s error %s", txHash.ToHexString(), err)
+		}
+		if filtered := filter(notify); filtered != nil {
+			page.Notifies = append(page.Notifies, filtered)
+		}
+	}
+	return page, nil
+}
+
+//PreExecuteContractBatch return the results of smart contract executions without commit to store. The transactions are
+//executed concurrently by a worker pool, each with PRE_EXEC_BATCH_TX_TIMEOUT and PRE_EXEC_BATCH_TX_MEMORY_LIMIT, and
+//the error of the first failed transaction is returned. Atomic mode keeps blocks from saving during the executions.
+//The results are cached as PreExecuteContract
 func (this *LedgerStoreImp) PreExecuteContractBatch(txes []*types.Transaction, atomic bool) ([]*sstate.PreExecResult, uint32, error) {
 	if atomic {
 		this.getSavingBlockLock()
 		defer this.releaseSavingBlockLock()
 	}
 	height := this.GetCurrentBlockHeight()
-	results := make([]*sstate.PreExecResult, 0, len(txes))
-	for _, tx := range txes {
-		res, err := this.PreExecuteContract(tx)
+	param := PrexecuteParam{
+		MinGas:      true,
+		Timeout:     PRE_EXEC_BATCH_TX_TIMEOUT,
+		MemoryLimit: PRE_EXEC_BATCH_TX_MEMORY_LIMIT,
+	}
+	results := make([]*sstate.PreExecResult, len(txes))
+	errs := make([]error, len(txes))
+	workers := runtime.NumCPU()
+	if workers > len(txes) {
+		workers = len(txes)
+	}
+	indexes := make(chan int, len(txes))
+	for i := range txes {
+		indexes <- i
+	}
+	close(indexes)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index], errs[index] = this.preExecuteCached(txes[index], param)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
 			return nil, height, err
 		}
-
-		results = append(results, res)
 	}
 
 	return results, height, nil
@@ -982,21 +1312,17 @@ func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, p
 		Tx:        tx,
 		BlockHash: this.GetBlockHash(height),
 	}
+	var deadline time.Time
+	if preParam.Timeout > 0 {
+		deadline = time.Now().Add(preParam.Timeout)
+	}
 
 	overlay := this.stateStore.NewOverlayDB()
 	cache := storage.NewCacheDB(overlay)
-	gasTable := make(map[string]uint64)
-	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
-		key := k.(string)
-		val := value.(uint64)
-		if key == config.WASM_GAS_FACTOR && preParam.WasmFactor != 0 {
-			gasTable[key] = preParam.WasmFactor
-		} else {
-			gasTable[key] = val
-		}
-
-		return true
-	})
+	gasTable := this.gasTableAt(height + 1)
+	if preParam.WasmFactor != 0 {
+		gasTable[config.WASM_GAS_FACTOR] = preParam.WasmFactor
+	}
 
 	if tx.TxType == types.InvokeNeo {
 		invoke := tx.Payload.(*payload.InvokeCode)
@@ -1010,6 +1336,8 @@ func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, p
 			WasmExecStep: config.DEFAULT_WASM_MAX_STEPCOUNT,
 			JitMode:      preParam.JitMode,
 			PreExec:      true,
+			Deadline:     deadline,
+			MemoryLimit:  preParam.MemoryLimit,
 		}
 		//start the smart contract executive function
 		engine, _ := sc.NewExecuteEngine(invoke.Code, tx.TxType)
@@ -1060,7 +1388,8 @@ func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, p
 	}
 }
 
-//PreExecuteContract return the result of smart contract execution without commit to store
+//PreExecuteContract return the result of smart contract execution without commit to store, the successful results are
+//cached until next block saved
 func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.PreExecResult, error) {
 	param := PrexecuteParam{
 		JitMode:    false,
@@ -1068,7 +1397,7 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 		MinGas:     true,
 	}
 
-	return this.PreExecuteContractWithParam(tx, param)
+	return this.preExecuteCached(tx, param)
 }
 
 //Close ledger store.
@@ -1079,6 +1408,21 @@ func (this *LedgerStoreImp) Close() error {
 
 	this.closing = true
 
+	this.stopInvariantChecker()
+	this.closeEventSubscribers()
+	if this.compactExit != nil {
+		close(this.compactExit)
+	}
+	if this.refreshExit != nil {
+		close(this.refreshExit)
+	}
+	if this.reEncryptExit != nil {
+		close(this.reEncryptExit)
+	}
+	this.compactLock.Lock()
+	defer this.compactLock.Unlock()
+	this.compactClosed = true
+
 	err := this.blockStore.Close()
 	if err != nil {
 		return fmt.Errorf("blockStore close error %s", err)
@@ -1091,5 +1435,9 @@ func (this *LedgerStoreImp) Close() error {
 	if err != nil {
 		return fmt.Errorf("stateStore close error %s", err)
 	}
+	err = this.layer2Store.Close()
+	if err != nil {
+		return fmt.Errorf("layer2Store close error %s", err)
+	}
 	return nil
 }
@@ -0,0 +1,258 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/merkle"
+)
+
+const (
+	DBDirLight = "light"
+)
+
+// LightStore save the headers and layer2 state messages of blocks for the header-only light node, the transactions are
+// not executed. Headers are verified by the bookkeepers of prev header and the block root, layer2 states are verified
+// by the bookkeepers of the header of same height
+type LightStore struct {
+	dbDir             string
+	store             *leveldbstore.LevelDBStore
+	lock              sync.RWMutex
+	currHeader        *types.Header             //Header of current height, nil before genesis saved
+	merkleTree        *merkle.CompactMerkleTree //Merkle tree of transaction roots to verify block root of header
+	accountTreeHeight uint32                    //First block whose layer2 state root is the account tree root
+}
+
+// NewLightStore return the light store in dataDir
+func NewLightStore(dataDir string) (*LightStore, error) {
+	dbDir := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirLight)
+	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	if err != nil {
+		return nil, fmt.Errorf("NewLightStore error %s", err)
+	}
+	this := &LightStore{
+		dbDir:             dbDir,
+		store:             store,
+		merkleTree:        merkle.NewTree(0, nil, nil),
+		accountTreeHeight: accountTreeActivation(config.DefConfig.ChainSpec),
+	}
+	data, err := store.Get([]byte{byte(scom.SYS_CURRENT_BLOCK)})
+	if err == scom.ErrNotFound {
+		return this, nil
+	}
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("get current height error %s", err)
+	}
+	height, eof := common.NewZeroCopySource(data).NextUint32()
+	if eof {
+		store.Close()
+		return nil, fmt.Errorf("get current height error %s", io.ErrUnexpectedEOF)
+	}
+	this.currHeader, err = this.GetHeaderByHeight(height)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("get current header error %s", err)
+	}
+	data, err = store.Get([]byte{byte(scom.SYS_BLOCK_MERKLE_TREE)})
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("get block merkle tree error %s", err)
+	}
+	treeSize, hashes, err := parseMerkleTree(data)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("parse block merkle tree error %s", err)
+	}
+	this.merkleTree = merkle.NewTree(treeSize, hashes, nil)
+	return this, nil
+}
+
+// InitWithGenesisBlock save the header of genesis block to empty store, or check the saved genesis header
+func (this *LightStore) InitWithGenesisBlock(genesisBlock *types.Block) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.currHeader != nil {
+		header, err := this.GetHeaderByHeight(0)
+		if err != nil {
+			return fmt.Errorf("get genesis header error %s", err)
+		}
+		genesisHash, savedHash := genesisBlock.Hash(), header.Hash()
+		if savedHash != genesisHash {
+			return fmt.Errorf("genesis block hash %s not equal saved %s", genesisHash.ToHexString(),
+				savedHash.ToHexString())
+		}
+		return nil
+	}
+	return this.saveHeader(genesisBlock.Header, nil)
+}
+
+// AddHeader verify and save the next header and its layer2 state, layer2State may be nil if the block has not
+func (this *LightStore) AddHeader(header *types.Header, layer2State *types.Layer2State) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.currHeader == nil {
+		return fmt.Errorf("light store not initialized with genesis block")
+	}
+	if header.Height != this.currHeader.Height+1 {
+		return fmt.Errorf("header height %d not equal next height %d", header.Height, this.currHeader.Height+1)
+	}
+	if header.PrevBlockHash != this.currHeader.Hash() {
+		return fmt.Errorf("prev block hash of header height %d is incorrect", header.Height)
+	}
+	err := verifyHeaderWithPrev(header, this.currHeader)
+	if err != nil {
+		return fmt.Errorf("verify header height %d error %s", header.Height, err)
+	}
+	blockRoot := this.merkleTree.GetRootWithNewLeaf(header.TransactionsRoot)
+	if blockRoot != header.BlockRoot {
+		return fmt.Errorf("wrong block root at height:%d, expected:%s, got:%s", header.Height,
+			blockRoot.ToHexString(), header.BlockRoot.ToHexString())
+	}
+	if layer2State != nil {
+		if layer2State.Height != header.Height {
+			return fmt.Errorf("layer2 state height %d not equal header height %d", layer2State.Height, header.Height)
+		}
+		if layer2State.Version != types.CURR_LAYER2_STATE_VERSION {
+			return fmt.Errorf("error layer2 state version excepted:%d actual:%d", types.CURR_LAYER2_STATE_VERSION,
+				layer2State.Version)
+		}
+		err = verifyLayer2State(layer2State, header.Bookkeepers)
+		if err != nil {
+			return fmt.Errorf("verify layer2 state height %d error %s", header.Height, err)
+		}
+	}
+	return this.saveHeader(header, layer2State)
+}
+
+// saveHeader save the verified header and layer2 state as current, the caller should hold the lock
+func (this *LightStore) saveHeader(header *types.Header, layer2State *types.Layer2State) error {
+	this.store.NewBatch()
+	sink := common.NewZeroCopySink(nil)
+	header.Serialization(sink)
+	this.store.BatchPut(genLightHeightKey(scom.DATA_HEADER, header.Height), sink.Bytes())
+	if layer2State != nil {
+		sink = common.NewZeroCopySink(nil)
+		layer2State.Serialization(sink)
+		this.store.BatchPut(genLightHeightKey(scom.SYS_CROSS_CHAIN_MSG, header.Height), sink.Bytes())
+	}
+	tree := merkle.NewTree(this.merkleTree.TreeSize(), this.merkleTree.Hashes(), nil)
+	tree.AppendHash(header.TransactionsRoot)
+	hashes := tree.Hashes()
+	sink = common.NewZeroCopySink(make([]byte, 0, 4+len(hashes)*common.UINT256_SIZE))
+	sink.WriteUint32(tree.TreeSize())
+	for _, hash := range hashes {
+		sink.WriteHash(hash)
+	}
+	this.store.BatchPut([]byte{byte(scom.SYS_BLOCK_MERKLE_TREE)}, sink.Bytes())
+	sink = common.NewZeroCopySink(nil)
+	sink.WriteUint32(header.Height)
+	this.store.BatchPut([]byte{byte(scom.SYS_CURRENT_BLOCK)}, sink.Bytes())
+	err := this.store.BatchCommit()
+	if err != nil {
+		return fmt.Errorf("save header height %d error %s", header.Height, err)
+	}
+	this.currHeader = header
+	this.merkleTree = tree
+	return nil
+}
+
+// GetCurrentHeight return the height of latest saved header
+func (this *LightStore) GetCurrentHeight() uint32 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	if this.currHeader == nil {
+		return 0
+	}
+	return this.currHeader.Height
+}
+
+// GetHeaderByHeight return the header of height, ErrNotFound if not saved
+func (this *LightStore) GetHeaderByHeight(height uint32) (*types.Header, error) {
+	data, err := this.store.Get(genLightHeightKey(scom.DATA_HEADER, height))
+	if err != nil {
+		return nil, err
+	}
+	return types.HeaderFromRawBytes(data)
+}
+
+// GetLayer2State return the layer2 state of height, nil if the block has no layer2 state
+func (this *LightStore) GetLayer2State(height uint32) (*types.Layer2State, error) {
+	data, err := this.store.Get(genLightHeightKey(scom.SYS_CROSS_CHAIN_MSG, height))
+	if err == scom.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := new(types.Layer2State)
+	err = state.Deserialization(common.NewZeroCopySource(data))
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// VerifyLayer2StateProof verify the serialized sparse merkle proof of account key and value returned by
+// getlayer2stateproof against the signed layer2 state root of height, nil value verifies the key is absent. Below the
+// account tree height the proof is the legacy merkle path of the leaf data key, and value is not used
+func (this *LightStore) VerifyLayer2StateProof(height uint32, key, value, proof []byte) (bool, error) {
+	state, err := this.GetLayer2State(height)
+	if err != nil {
+		return false, err
+	}
+	if state == nil {
+		return false, fmt.Errorf("no layer2 state at height %d", height)
+	}
+	if height < this.accountTreeHeight {
+		data, err := merkle.MerkleProve(proof, state.StatesRoot)
+		if err != nil {
+			return false, nil
+		}
+		return bytes.Equal(data, key), nil
+	}
+	smtProof := new(merkle.SMTProof)
+	err = smtProof.Deserialization(common.NewZeroCopySource(proof))
+	if err != nil {
+		return false, fmt.Errorf("deserialize proof error %s", err)
+	}
+	return merkle.VerifySMTProof(state.StatesRoot, key, value, smtProof), nil
+}
+
+func (this *LightStore) Close() error {
+	return this.store.Close()
+}
+
+func genLightHeightKey(prefix scom.DataEntryPrefix, height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(prefix)
+	binary.BigEndian.PutUint32(key[1:], height)
+	return key
+}
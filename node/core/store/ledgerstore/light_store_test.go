@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/signature"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLightStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "light")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(filepath.Join(dir, "full"), 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	blocks := make([]*types.Block, 0)
+	layer2States := make([]*types.Layer2State, 0)
+	for i := 0; i < 3; i++ {
+		block, layer2State := newTestCheckpointBlock(t, ledgerStore, acc)
+		blocks = append(blocks, block)
+		layer2States = append(layer2States, layer2State)
+	}
+
+	lightDir := filepath.Join(dir, "light")
+	lightStore, err := NewLightStore(lightDir)
+	assert.Nil(t, err)
+	assert.NotNil(t, lightStore.AddHeader(blocks[0].Header, layer2States[0]))
+	assert.Nil(t, lightStore.InitWithGenesisBlock(genesisBlock))
+	assert.NotNil(t, lightStore.AddHeader(blocks[1].Header, layer2States[1]))
+
+	//the header and layer2 state not signed by bookkeepers are rejected
+	other := account.NewAccount("")
+	header := *blocks[0].Header
+	hash := header.Hash()
+	sig, err := signature.Sign(other, hash[:])
+	assert.Nil(t, err)
+	header.SigData = [][]byte{sig}
+	assert.NotNil(t, lightStore.AddHeader(&header, layer2States[0]))
+	layer2State := *layer2States[0]
+	hash = layer2State.Hash()
+	sig, err = signature.Sign(other, hash[:])
+	assert.Nil(t, err)
+	layer2State.SigData = [][]byte{sig}
+	assert.NotNil(t, lightStore.AddHeader(blocks[0].Header, &layer2State))
+	assert.Equal(t, uint32(0), lightStore.GetCurrentHeight())
+
+	for i, block := range blocks {
+		assert.Nil(t, lightStore.AddHeader(block.Header, layer2States[i]))
+	}
+	assert.Nil(t, lightStore.Close())
+
+	lightStore, err = NewLightStore(lightDir)
+	assert.Nil(t, err)
+	defer lightStore.Close()
+	assert.Nil(t, lightStore.InitWithGenesisBlock(genesisBlock))
+	assert.Equal(t, uint32(3), lightStore.GetCurrentHeight())
+	for i, block := range blocks {
+		header, err := lightStore.GetHeaderByHeight(block.Header.Height)
+		assert.Nil(t, err)
+		assert.Equal(t, block.Hash(), header.Hash())
+		state, err := lightStore.GetLayer2State(block.Header.Height)
+		assert.Nil(t, err)
+		assert.Equal(t, layer2States[i].Hash(), state.Hash())
+	}
+
+	//the account proof of full node is verified against the signed layer2 state root
+	key := append(acc.Address[:], other.Address[:]...)
+	proof, err := ledgerStore.GetLayer2StateProof(3, key)
+	assert.Nil(t, err)
+	valid, err := lightStore.VerifyLayer2StateProof(3, key, nil, proof)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	valid, err = lightStore.VerifyLayer2StateProof(3, key, []byte{1}, proof)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+	_, err = lightStore.VerifyLayer2StateProof(4, key, nil, proof)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/layer2/node/core/store/overlaydb"
+)
+
+//MetricsRegistry is the registry of ledger store metrics, which is served in prometheus format by the node started
+//with --metrics. The metrics are no-op unless metrics.Enabled is set before the ledger store is created
+var MetricsRegistry = metrics.NewRegistry()
+
+//ledgerMetrics is the timers and counters updated when block is executed and saved
+type ledgerMetrics struct {
+	executeTimer  metrics.Timer
+	submitTimer   metrics.Timer
+	commitTimers  map[string]metrics.Timer //Commit time of each store by store dir name
+	writeSetKeys  metrics.Histogram
+	writeSetBytes metrics.Histogram
+	overlayHits   metrics.Counter
+	overlayMisses metrics.Counter
+	preExecHits   metrics.Counter
+	preExecMisses metrics.Counter
+}
+
+func newLedgerMetrics() *ledgerMetrics {
+	newHistogram := func(name string) metrics.Histogram {
+		return metrics.GetOrRegisterHistogram(name, MetricsRegistry, metrics.NewExpDecaySample(1028, 0.015))
+	}
+	result := &ledgerMetrics{
+		executeTimer:  metrics.GetOrRegisterTimer("ledger/block/execute", MetricsRegistry),
+		submitTimer:   metrics.GetOrRegisterTimer("ledger/block/submit", MetricsRegistry),
+		commitTimers:  make(map[string]metrics.Timer),
+		writeSetKeys:  newHistogram("ledger/block/writeset/keys"),
+		writeSetBytes: newHistogram("ledger/block/writeset/bytes"),
+		overlayHits:   metrics.GetOrRegisterCounter("ledger/overlay/hits", MetricsRegistry),
+		overlayMisses: metrics.GetOrRegisterCounter("ledger/overlay/misses", MetricsRegistry),
+		preExecHits:   metrics.GetOrRegisterCounter("ledger/preexec/cache/hits", MetricsRegistry),
+		preExecMisses: metrics.GetOrRegisterCounter("ledger/preexec/cache/misses", MetricsRegistry),
+	}
+	for _, name := range []string{DBDirBlock, DBDirState, DBDirEvent, DBDirLayer2} {
+		result.commitTimers[name] = metrics.GetOrRegisterTimer("ledger/commit/"+name, MetricsRegistry)
+	}
+	return result
+}
+
+//updateExecuteMetrics record the execution time, write set and overlay reads of block
+func (this *ledgerMetrics) updateExecuteMetrics(start time.Time, overlay *overlaydb.OverlayDB, result *store.ExecuteResult) {
+	if this == nil {
+		return
+	}
+	this.executeTimer.UpdateSince(start)
+	hits, misses := overlay.CacheStats()
+	this.overlayHits.Inc(int64(hits))
+	this.overlayMisses.Inc(int64(misses))
+	if result.WriteSet != nil {
+		this.writeSetKeys.Update(int64(result.WriteSet.Len()))
+		this.writeSetBytes.Update(int64(result.WriteSet.Size()))
+	}
+}
+
+//updateCommitTime record the commit time of store named by its dir
+func (this *ledgerMetrics) updateCommitTime(name string, start time.Time) {
+	if this == nil {
+		return
+	}
+	this.commitTimers[name].UpdateSince(start)
+}
+
+//updateSubmitTime record the time of saving block to all stores
+func (this *ledgerMetrics) updateSubmitTime(start time.Time) {
+	if this == nil {
+		return
+	}
+	this.submitTimer.UpdateSince(start)
+}
+
+//updatePreExecCache count the hit or miss of pre-execution result cache
+func (this *ledgerMetrics) updatePreExecCache(hit bool) {
+	if this == nil {
+		return
+	}
+	if hit {
+		this.preExecHits.Inc(1)
+	} else {
+		this.preExecMisses.Inc(1)
+	}
+}
+
+//registerGauges register the gauges of heights, db sizes and state cache of the ledger store, which are read when
+//the metrics are collected. The gauges of the latest created ledger store are registered
+func (this *LedgerStoreImp) registerGauges() {
+	gauges := map[string]func() int64{
+		"ledger/height/current": func() int64 { return int64(this.GetCurrentBlockHeight()) },
+		"ledger/height/header":  func() int64 { return int64(this.GetCurrentHeaderHeight()) },
+		"ledger/height/block": func() int64 {
+			_, height, _ := this.blockStore.GetCurrentBlock()
+			return int64(height)
+		},
+		"ledger/height/state": func() int64 {
+			_, height, _ := this.stateStore.GetCurrentBlock()
+			return int64(height)
+		},
+		"ledger/height/event": func() int64 {
+			_, height, _ := this.eventStore.GetCurrentBlock()
+			return int64(height)
+		},
+		"ledger/statecache/size":   func() int64 { return int64(this.GetStateCacheStats().Size) },
+		"ledger/statecache/hits":   func() int64 { return int64(this.GetStateCacheStats().Hits) },
+		"ledger/statecache/misses": func() int64 { return int64(this.GetStateCacheStats().Misses) },
+	}
+	for name, dir := range map[string]string{
+		DBDirBlock:  this.blockStore.dbDir,
+		DBDirState:  this.stateStore.dbDir,
+		DBDirEvent:  this.eventStore.dbDir,
+		DBDirLayer2: this.layer2Store.dbDir,
+	} {
+		dir := dir
+		gauges["ledger/dbsize/"+name] = func() int64 { return dirSize(dir) }
+	}
+	for name, f := range gauges {
+		MetricsRegistry.Unregister(name)
+		metrics.NewRegisteredFunctionalGauge(name, MetricsRegistry, f)
+	}
+}
+
+//dirSize return the total size of files in dir
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
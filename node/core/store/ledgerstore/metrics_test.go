@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgerMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+	MetricsRegistry.UnregisterAll()
+	defer MetricsRegistry.UnregisterAll()
+
+	dir, err := ioutil.TempDir("", "metrics")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	newTestCheckpointBlock(t, ledgerStore, acc)
+
+	assert.Equal(t, int64(2), MetricsRegistry.Get("ledger/block/execute").(metrics.Timer).Count())
+	assert.Equal(t, int64(2), MetricsRegistry.Get("ledger/block/submit").(metrics.Timer).Count())
+	for _, name := range []string{DBDirBlock, DBDirState, DBDirEvent, DBDirLayer2} {
+		assert.Equal(t, int64(2), MetricsRegistry.Get("ledger/commit/"+name).(metrics.Timer).Count())
+		assert.True(t, MetricsRegistry.Get("ledger/dbsize/"+name).(metrics.Gauge).Value() > 0)
+	}
+	assert.Equal(t, int64(2), MetricsRegistry.Get("ledger/block/writeset/keys").(metrics.Histogram).Count())
+	assert.Equal(t, int64(1), MetricsRegistry.Get("ledger/height/current").(metrics.Gauge).Value())
+	assert.Equal(t, int64(1), MetricsRegistry.Get("ledger/height/state").(metrics.Gauge).Value())
+
+	// the state cache stats are served by the gauges
+	ledgerStore.GetContractState(common.ADDRESS_EMPTY)
+	stats := ledgerStore.GetStateCacheStats()
+	assert.True(t, stats.Misses > 0)
+	assert.Equal(t, int64(stats.Size), MetricsRegistry.Get("ledger/statecache/size").(metrics.Gauge).Value())
+	assert.Equal(t, int64(stats.Hits), MetricsRegistry.Get("ledger/statecache/hits").(metrics.Gauge).Value())
+	assert.Equal(t, int64(stats.Misses), MetricsRegistry.Get("ledger/statecache/misses").(metrics.Gauge).Value())
+}
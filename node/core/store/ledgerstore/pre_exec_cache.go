@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"crypto/sha256"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/types"
+	sstate "github.com/ontio/layer2/node/smartcontract/states"
+)
+
+const PRE_EXEC_CACHE_SIZE = 4096 //Count of pre-execution results cached for the current block
+
+//preExecCacheKey identify the pre-execution of a transaction on the state of a block
+type preExecCacheKey struct {
+	blockHash common.Uint256 //Hash of the current block whose state the transaction is executed on
+	txKey     common.Uint256 //Hash of the transaction type, payer, signer addresses and payload
+}
+
+//preExecTxKey return the hash of the fields of transaction which the pre-execution depends on, so the same call with
+//other nonce, gas price or gas limit has the same key
+func preExecTxKey(tx *types.Transaction) common.Uint256 {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteByte(byte(tx.TxType))
+	sink.WriteAddress(tx.Payer)
+	addrs := tx.GetSignatureAddresses()
+	sink.WriteVarUint(uint64(len(addrs)))
+	for _, addr := range addrs {
+		sink.WriteAddress(addr)
+	}
+	if tx.Payload != nil {
+		tx.Payload.Serialization(sink)
+	}
+	return sha256.Sum256(sink.Bytes())
+}
+
+//preExecuteCached return the cached result of the default pre-execution of tx on the current block, and pre-execute it
+//with param if not cached. Only the successful results are cached
+func (this *LedgerStoreImp) preExecuteCached(tx *types.Transaction, param PrexecuteParam) (*sstate.PreExecResult, error) {
+	if this.preExecCache == nil {
+		return this.PreExecuteContractWithParam(tx, param)
+	}
+	key := preExecCacheKey{blockHash: this.GetCurrentBlockHash(), txKey: preExecTxKey(tx)}
+	if value, ok := this.preExecCache.Get(key); ok {
+		this.metrics.updatePreExecCache(true)
+		result := *value.(*sstate.PreExecResult)
+		return &result, nil
+	}
+	this.metrics.updatePreExecCache(false)
+	result, err := this.PreExecuteContractWithParam(tx, param)
+	if err != nil {
+		return result, err
+	}
+	//the cached one is not shared with the caller
+	cached := *result
+	this.preExecCache.Add(key, &cached)
+	return result, nil
+}
+
+//purgePreExecCache drop the pre-execution results of the previous blocks after a new block is saved
+func (this *LedgerStoreImp) purgePreExecCache() {
+	if this.preExecCache != nil {
+		this.preExecCache.Purge()
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/payload"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/ontio/layer2/node/vm/neovm"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestInvokeTx(code []byte) *types.Transaction {
+	return &types.Transaction{TxType: types.InvokeNeo, Payload: &payload.InvokeCode{Code: code}}
+}
+
+func TestPreExecuteContractBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preexec")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+
+	txes := make([]*types.Transaction, 0)
+	for i := 1; i <= 16; i++ {
+		txes = append(txes, newTestInvokeTx([]byte{byte(neovm.PUSH1) + byte(i-1)}))
+	}
+	for _, atomic := range []bool{false, true} {
+		results, height, err := ledgerStore.PreExecuteContractBatch(txes, atomic)
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(0), height)
+		assert.Equal(t, len(txes), len(results))
+		for i, result := range results {
+			assert.Equal(t, event.CONTRACT_STATE_SUCCESS, result.State)
+			single, err := ledgerStore.PreExecuteContract(txes[i])
+			assert.Nil(t, err)
+			assert.Equal(t, single.Result, result.Result)
+		}
+	}
+
+	//the error of failed transaction fails the batch
+	loop := newTestInvokeTx([]byte{byte(neovm.JMP), 0x00, 0x00})
+	_, _, err = ledgerStore.PreExecuteContractBatch(append(txes, loop), false)
+	assert.NotNil(t, err)
+
+	_, err = ledgerStore.PreExecuteContractWithParam(loop, PrexecuteParam{Timeout: time.Nanosecond})
+	assert.Equal(t, smartcontract.ErrPreExecTimeout, err)
+}
+
+func TestPreExecuteContractCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preexeccache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+
+	tx := newTestInvokeTx([]byte{byte(neovm.PUSH2)})
+	result, err := ledgerStore.PreExecuteContract(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, ledgerStore.preExecCache.Len())
+	//the same call with other nonce hits the cache
+	other := newTestInvokeTx([]byte{byte(neovm.PUSH2)})
+	other.Nonce = 1
+	cached, err := ledgerStore.PreExecuteContract(other)
+	assert.Nil(t, err)
+	assert.Equal(t, result, cached)
+	assert.Equal(t, 1, ledgerStore.preExecCache.Len())
+	_, err = ledgerStore.PreExecuteContract(newTestInvokeTx([]byte{byte(neovm.PUSH3)}))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ledgerStore.preExecCache.Len())
+	//the failed result is not cached
+	_, err = ledgerStore.PreExecuteContract(newTestInvokeTx([]byte{byte(neovm.JMP), 0x00, 0x00}))
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, ledgerStore.preExecCache.Len())
+
+	//the cache is invalidated by new block
+	newTestCheckpointBlock(t, ledgerStore, acc)
+	assert.Equal(t, 0, ledgerStore.preExecCache.Len())
+	result, err = ledgerStore.PreExecuteContract(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, cached, result)
+	assert.Equal(t, 1, ledgerStore.preExecCache.Len())
+}
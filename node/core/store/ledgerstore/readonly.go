@@ -0,0 +1,252 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/errors"
+)
+
+var ErrReadOnlyLedger = errors.NewErr("ledger is read-only")
+
+//NewReadOnlyLedgerStore return a LedgerStoreImp which reads the data dir written by other node process at the same
+//time. The stores are read from point-in-time views created under viewRoot, and refreshed every refreshInterval to
+//follow the blocks saved by the writer, 0 to refresh only by Refresh. Executing and submitting block are rejected
+func NewReadOnlyLedgerStore(dataDir, viewRoot string, stateHashHeight uint32, refreshInterval time.Duration) (*LedgerStoreImp, error) {
+	headerCache, err := lru.New(HEADER_INDEX_CACHE_SIZE)
+	if err != nil {
+		return nil, fmt.Errorf("NewHeaderCache error %s", err)
+	}
+	preExecCache, err := lru.New(PRE_EXEC_CACHE_SIZE)
+	if err != nil {
+		return nil, fmt.Errorf("NewPreExecCache error %s", err)
+	}
+	ledgerStore := &LedgerStoreImp{
+		headerCache:          headerCache,
+		preExecCache:         preExecCache,
+		headerIndexBatchSize: HEADER_INDEX_BATCH_SIZE,
+		savingBlockSemaphore: make(chan bool, 1),
+		stateHashCheckHeight: stateHashHeight,
+		metrics:              newLedgerMetrics(),
+		gasStats:             newGasStatsWindow(),
+		readOnly:             true,
+	}
+	ledgerStore.accountRules, err = NewAccountRootRules(config.DefConfig.ChainSpec)
+	if err != nil {
+		return nil, fmt.Errorf("NewAccountRootRules error %s", err)
+	}
+	ledgerStore.accountTreeHeight = accountTreeActivation(config.DefConfig.ChainSpec)
+	ledgerStore.gasSchedule, err = NewGasSchedule(config.DefConfig.ChainSpec)
+	if err != nil {
+		return nil, fmt.Errorf("NewGasSchedule error %s", err)
+	}
+	cipher, err := storeCipher()
+	if err != nil {
+		return nil, err
+	}
+	// state store is committed last at saving block, open it first so other views are not older than it
+	dbPath := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirState)
+	stateDB, err := ledgerStore.openReadOnlyStore(dbPath, viewRoot)
+	if err != nil {
+		return nil, fmt.Errorf("open state store error %s", err)
+	}
+	stateDB.SetCipher(cipher)
+	ledgerStore.stateStore = &StateStore{
+		dbDir:                dbPath,
+		store:                stateDB,
+		merklePath:           fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), MerkleTreeStorePath),
+		stateHashCheckHeight: stateHashHeight,
+		readOnly:             true,
+	}
+	if size := config.DefConfig.Common.StateCacheSize; size > 0 {
+		ledgerStore.stateStore.cache, err = NewStateCache(int(size))
+		if err != nil {
+			ledgerStore.closeReadOnlyStores()
+			return nil, err
+		}
+	}
+
+	dbPath = fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock)
+	blockDB, err := ledgerStore.openReadOnlyStore(dbPath, viewRoot)
+	if err != nil {
+		return nil, fmt.Errorf("open block store error %s", err)
+	}
+	blockDB.SetCipher(cipher)
+	blockCache, err := NewBlockCache()
+	if err != nil {
+		ledgerStore.closeReadOnlyStores()
+		return nil, fmt.Errorf("NewBlockCache error %s", err)
+	}
+	ledgerStore.blockStore = &BlockStore{
+		dbDir:             dbPath,
+		enableCache:       true,
+		store:             blockDB,
+		cache:             blockCache,
+		enableTxIndex:     config.DefConfig.Common.EnableTxIndex,
+		enableCompression: config.DefConfig.Common.EnableCompression,
+	}
+
+	dbPath = fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirEvent)
+	eventDB, err := ledgerStore.openReadOnlyStore(dbPath, viewRoot)
+	if err != nil {
+		return nil, fmt.Errorf("open event store error %s", err)
+	}
+	eventDB.SetCipher(cipher)
+	ledgerStore.eventStore = &EventStore{
+		dbDir:             dbPath,
+		store:             eventDB,
+		enableCompression: config.DefConfig.Common.EnableCompression,
+	}
+
+	dbPath = fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirLayer2)
+	layer2DB, err := ledgerStore.openReadOnlyStore(dbPath, viewRoot)
+	if err != nil {
+		return nil, fmt.Errorf("open layer2 store error %s", err)
+	}
+	ledgerStore.layer2Store = &Layer2Store{
+		dbDir: dbPath,
+		store: layer2DB,
+	}
+	if refreshInterval > 0 {
+		ledgerStore.refreshExit = make(chan struct{})
+		go ledgerStore.refreshLoop(refreshInterval, ledgerStore.refreshExit)
+	}
+	ledgerStore.registerGauges()
+	return ledgerStore, nil
+}
+
+//openReadOnlyStore open the read-only view of leveldb, the views opened before are closed if failed
+func (this *LedgerStoreImp) openReadOnlyStore(dbPath, viewRoot string) (*leveldbstore.LevelDBStore, error) {
+	db, err := leveldbstore.NewReadOnlyLevelDBStore(dbPath, viewRoot)
+	if err != nil {
+		this.closeReadOnlyStores()
+		return nil, err
+	}
+	this.readOnlyStores = append(this.readOnlyStores, db)
+	return db, nil
+}
+
+func (this *LedgerStoreImp) closeReadOnlyStores() {
+	for _, db := range this.readOnlyStores {
+		db.Close()
+	}
+	this.readOnlyStores = nil
+}
+
+//IsReadOnly return whether the ledger is a read-only view of the data dir written by other process
+func (this *LedgerStoreImp) IsReadOnly() bool {
+	return this.readOnly
+}
+
+//initReadOnly check the genesis block saved by the writer and load the current view
+func (this *LedgerStoreImp) initReadOnly(genesisBlock *types.Block) error {
+	hasInit, err := this.hasAlreadyInitGenesisBlock()
+	if err != nil {
+		return fmt.Errorf("hasAlreadyInit error %s", err)
+	}
+	if !hasInit {
+		return fmt.Errorf("ledger is not initialized by the writer yet")
+	}
+	exist, err := this.blockStore.ContainBlock(genesisBlock.Hash())
+	if err != nil {
+		return fmt.Errorf("HashBlockExist error %s", err)
+	}
+	if !exist {
+		return fmt.Errorf("GenesisBlock arenot init correctly")
+	}
+	return this.loadReadOnlyView()
+}
+
+//loadReadOnlyView load the current block and merkle trees from the views of stores. The current block is the one
+//of state store, as the other stores may have saved later blocks
+func (this *LedgerStoreImp) loadReadOnlyView() error {
+	err := this.stateStore.reload()
+	if err != nil {
+		return fmt.Errorf("stateStore.reload error %s", err)
+	}
+	blockHash, blockHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	this.setCurrentBlock(blockHeight, blockHash)
+	err = this.loadHeaderIndexList()
+	if err != nil {
+		return fmt.Errorf("loadHeaderIndexList error %s", err)
+	}
+	this.setHeaderIndex(blockHeight, blockHash)
+	prunedHeight, err := this.layer2Store.GetPrunedHeight()
+	if err != nil {
+		return fmt.Errorf("layer2Store.GetPrunedHeight error %s", err)
+	}
+	this.layer2PruneLock.Lock()
+	this.layer2PrunedHeight = prunedHeight
+	this.layer2PruneLock.Unlock()
+	this.purgePreExecCache()
+	return nil
+}
+
+//Refresh update the read-only ledger to the latest blocks saved by the writer
+func (this *LedgerStoreImp) Refresh() error {
+	if !this.readOnly {
+		return fmt.Errorf("ledger is not read-only")
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	if this.closing {
+		return errors.NewErr("refresh error: ledger is closing")
+	}
+	for _, db := range this.readOnlyStores {
+		err := db.Refresh()
+		if err != nil {
+			return fmt.Errorf("refresh store error %s", err)
+		}
+	}
+	currBlockHeight := this.GetCurrentBlockHeight()
+	err := this.loadReadOnlyView()
+	if err != nil {
+		return err
+	}
+	if this.GetCurrentBlockHeight() > currBlockHeight {
+		this.notifyEventSubscribers()
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) refreshLoop(interval time.Duration, exit chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+			if err := this.Refresh(); err != nil {
+				log.Errorf("refresh read-only ledger error %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyLedgerStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readonly")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	dataDir := filepath.Join(dir, "data")
+	writer, err := NewLedgerStore(dataDir, 0)
+	assert.Nil(t, err)
+	defer writer.Close()
+	assert.Nil(t, writer.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	block, _ := newTestCheckpointBlock(t, writer, acc)
+
+	replica, err := NewReadOnlyLedgerStore(dataDir, dir, 0, 0)
+	assert.Nil(t, err)
+	defer replica.Close()
+	assert.True(t, replica.IsReadOnly())
+	assert.Nil(t, replica.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	assert.Equal(t, uint32(1), replica.GetCurrentBlockHeight())
+	assert.Equal(t, writer.GetBlockHash(1), replica.GetBlockHash(1))
+
+	_, err = replica.ExecuteBlock(block)
+	assert.Equal(t, ErrReadOnlyLedger, err)
+	assert.Equal(t, ErrReadOnlyLedger, replica.SubmitBlock(block, nil, store.ExecuteResult{}))
+
+	//the blocks saved by writer are read after refresh
+	newTestCheckpointBlock(t, writer, acc)
+	newTestCheckpointBlock(t, writer, acc)
+	assert.Equal(t, uint32(1), replica.GetCurrentBlockHeight())
+	assert.Nil(t, replica.Refresh())
+	assert.Equal(t, uint32(3), replica.GetCurrentBlockHeight())
+	for height := uint32(0); height <= 3; height++ {
+		assert.Equal(t, writer.GetBlockHash(height), replica.GetBlockHash(height))
+		root, err := writer.GetStateMerkleRoot(height)
+		assert.Nil(t, err)
+		replicaRoot, err := replica.GetStateMerkleRoot(height)
+		assert.Nil(t, err)
+		assert.Equal(t, root, replicaRoot)
+	}
+	proof, err := writer.GetMerkleProof(1, 3)
+	assert.Nil(t, err)
+	replicaProof, err := replica.GetMerkleProof(1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, proof, replicaProof)
+	layer2State, err := replica.GetLayer2State(3)
+	assert.Nil(t, err)
+	assert.NotNil(t, layer2State)
+
+	//the writer ledger is not read-only
+	assert.False(t, writer.IsReadOnly())
+	assert.NotNil(t, writer.Refresh())
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+)
+
+//saveReceipts save the receipts of transactions in block. Receipts are saved even if the event log is disabled
+func (this *LedgerStoreImp) saveReceipts(block *types.Block, notifies []*event.ExecuteNotify) {
+	byTx := make(map[common.Uint256]*event.ExecuteNotify, len(notifies))
+	for _, notify := range notifies {
+		byTx[notify.TxHash] = notify
+	}
+	for i, tx := range block.Transactions {
+		notify := byTx[tx.Hash()]
+		if notify == nil {
+			continue
+		}
+		this.eventStore.SaveReceipt(newReceipt(block.Header.Height, uint32(i), notify))
+	}
+}
+
+func newReceipt(height, txIndex uint32, notify *event.ExecuteNotify) *types.Receipt {
+	return &types.Receipt{
+		TxHash:      notify.TxHash,
+		Height:      height,
+		TxIndex:     txIndex,
+		State:       notify.State,
+		GasConsumed: notify.GasConsumed,
+		NotifyCount: uint32(len(notify.Notify)),
+		Bloom:       CreateEventBloom([]*event.ExecuteNotify{notify}),
+	}
+}
+
+//GetReceipt return the receipt of transaction, ErrNotFound if the transaction is not saved or saved before receipts
+//are kept
+func (this *LedgerStoreImp) GetReceipt(txHash common.Uint256) (*types.Receipt, error) {
+	return this.eventStore.GetReceipt(txHash)
+}
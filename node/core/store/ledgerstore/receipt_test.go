@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceipt(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	ledgerStore := &LedgerStoreImp{eventStore: &EventStore{store: store}}
+
+	silent := newInvokeTransaction(500, 20000, []byte{1})
+	missing := newInvokeTransaction(500, 20000, []byte{2})
+	noisy := newInvokeTransaction(500, 20000, []byte{3})
+	block := &types.Block{
+		Header:       &types.Header{Height: 5},
+		Transactions: []*types.Transaction{silent, missing, noisy},
+	}
+	contract := common.AddressFromVmCode([]byte("contract"))
+	notifies := []*event.ExecuteNotify{
+		{TxHash: noisy.Hash(), State: event.CONTRACT_STATE_SUCCESS, GasConsumed: 500 * 30,
+			Notify: []*event.NotifyEventInfo{{ContractAddress: contract, States: []interface{}{"transfer"}}}},
+		{TxHash: silent.Hash(), State: event.CONTRACT_STATE_FAIL, GasConsumed: 500 * 20000},
+	}
+	ledgerStore.eventStore.NewBatch()
+	ledgerStore.saveReceipts(block, notifies)
+	assert.Nil(t, ledgerStore.eventStore.CommitTo())
+
+	receipt, err := ledgerStore.GetReceipt(silent.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, &types.Receipt{
+		TxHash:      silent.Hash(),
+		Height:      5,
+		TxIndex:     0,
+		State:       event.CONTRACT_STATE_FAIL,
+		GasConsumed: 500 * 20000,
+	}, receipt)
+
+	receipt, err = ledgerStore.GetReceipt(noisy.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), receipt.TxIndex)
+	assert.Equal(t, event.CONTRACT_STATE_SUCCESS, receipt.State)
+	assert.Equal(t, uint64(500*30), receipt.GasConsumed)
+	assert.Equal(t, uint32(1), receipt.NotifyCount)
+	assert.True(t, MatchEventBloom(&receipt.Bloom, contract, ""))
+	assert.True(t, MatchEventBloom(&receipt.Bloom, contract, "transfer"))
+	assert.False(t, MatchEventBloom(&receipt.Bloom, contract, "approve"))
+
+	_, err = ledgerStore.GetReceipt(missing.Hash())
+	assert.Equal(t, scom.ErrNotFound, err)
+
+	source := common.NewZeroCopySource(common.SerializeToBytes(receipt))
+	decoded := &types.Receipt{}
+	assert.Nil(t, decoded.Deserialization(source))
+	assert.Equal(t, receipt, decoded)
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/signature"
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/layer2/node/core/types"
+)
+
+const (
+	RECOVER_READ_AHEAD   = 256              //Max count of blocks loaded and verified ahead of execution during recovery
+	RECOVER_LOG_INTERVAL = 10 * time.Second //Interval of recovery progress log
+)
+
+//recoverTask is one block of recovery pipeline, done is closed after verification finished
+type recoverTask struct {
+	height uint32
+	block  *types.Block
+	err    error
+	done   chan struct{}
+}
+
+//recoverStore re-execute the blocks which are saved to block store but not executed to state store.
+//Blocks are read ahead and verified by a worker pool, and executed sequentially in height order.
+//Execution of block reads the committed state of previous block, so state store and event store are
+//committed per block, and event store is always committed before state store.
+//The recovery progress is saved with the state of every block, so the recovery interrupted by crash
+//is resumed from the last executed block with its original start height and start time.
+func (this *LedgerStoreImp) recoverStore() error {
+	blockHeight := this.GetCurrentBlockHeight()
+
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	progress, err := this.stateStore.GetRecoverProgress()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetRecoverProgress error %s", err)
+	}
+	status := store.RecoverStatus{
+		Recovering:    stateHeight < blockHeight,
+		StartHeight:   stateHeight,
+		ResumeHeight:  stateHeight,
+		CurrentHeight: stateHeight,
+		TargetHeight:  blockHeight,
+		StartTime:     uint32(time.Now().Unix()),
+	}
+	if progress != nil && progress.StartHeight <= stateHeight {
+		status.StartHeight = progress.StartHeight
+		status.StartTime = progress.StartTime
+	}
+	this.setRecoverStatus(status)
+	if stateHeight >= blockHeight {
+		if progress != nil {
+			this.stateStore.NewBatch()
+			this.stateStore.DeleteRecoverProgress()
+			err = this.stateStore.CommitTo()
+			if err != nil {
+				return fmt.Errorf("stateStore.CommitTo error %s", err)
+			}
+		}
+		this.finishRecoverStatus()
+		return nil
+	}
+	if status.StartHeight < stateHeight {
+		log.Infof("resume recover store from height %d to %d, started from height %d at %s", stateHeight,
+			blockHeight, status.StartHeight, time.Unix(int64(status.StartTime), 0).Format(time.RFC3339))
+	} else {
+		log.Infof("recover store from height %d to %d", stateHeight, blockHeight)
+	}
+
+	exit := make(chan struct{})
+	defer close(exit)
+	tasks := this.startRecoverPipeline(stateHeight, blockHeight, exit)
+
+	start := time.Now()
+	lastLog := start
+	for i := stateHeight; i < blockHeight; i++ {
+		task := <-tasks
+		<-task.done
+		if task.err != nil {
+			return task.err
+		}
+		block := task.block
+		this.eventStore.NewBatch()
+		this.stateStore.NewBatch()
+		result, err := this.executeBlock(block)
+		if err != nil {
+			return err
+		}
+		err = this.saveBlockToStateStore(block, result)
+		if err != nil {
+			return fmt.Errorf("save to state store height:%d error:%s", i, err)
+		}
+		if i+1 < blockHeight {
+			this.stateStore.SaveRecoverProgress(&status)
+		} else {
+			this.stateStore.DeleteRecoverProgress()
+		}
+		this.saveBlockToEventStore(block)
+		err = this.eventStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("eventStore.CommitTo height:%d error %s", i, err)
+		}
+		err = this.stateStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("stateStore.CommitTo height:%d error %s", i, err)
+		}
+
+		now := time.Now()
+		done := i + 1 - stateHeight
+		speed := float64(done) / now.Sub(start).Seconds()
+		left := time.Duration(float64(blockHeight-i-1)/speed) * time.Second
+		this.updateRecoverHeight(i+1, uint32(now.Add(left).Unix()))
+		log.Debugf("recover store block %d/%d, %d txs, about %s left", i+1, blockHeight, len(block.Transactions), left)
+		if now.Sub(lastLog) >= RECOVER_LOG_INTERVAL {
+			lastLog = now
+			log.Infof("recover store height %d/%d, %.1f blocks/s, about %s left", i+1, blockHeight, speed, left)
+		}
+	}
+	this.finishRecoverStatus()
+	log.Infof("recover store to height %d finished in %s", blockHeight, time.Since(start))
+	return nil
+}
+
+//startRecoverPipeline load blocks of [start, end) in order and verify them by a worker pool.
+//The returned channel delivers the tasks in height order, at most RECOVER_READ_AHEAD ahead of execution.
+func (this *LedgerStoreImp) startRecoverPipeline(start, end uint32, exit chan struct{}) chan *recoverTask {
+	ordered := make(chan *recoverTask, RECOVER_READ_AHEAD)
+	verifying := make(chan *recoverTask, RECOVER_READ_AHEAD)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		go func() {
+			for task := range verifying {
+				task.err = this.verifyRecoverBlock(task.block)
+				close(task.done)
+			}
+		}()
+	}
+	go func() {
+		defer close(verifying)
+		for height := start; height < end; height++ {
+			task := &recoverTask{height: height, done: make(chan struct{})}
+			task.block, task.err = loadBlockByHeight(this.blockStore, height)
+			select {
+			case ordered <- task:
+			case <-exit:
+				return
+			}
+			if task.err != nil {
+				close(task.done)
+				return
+			}
+			select {
+			case verifying <- task:
+			case <-exit:
+				return
+			}
+		}
+	}()
+	return ordered
+}
+
+//verifyRecoverBlock verify the header and transaction signatures of block read from block store
+func (this *LedgerStoreImp) verifyRecoverBlock(block *types.Block) error {
+	height := block.Header.Height
+	if height == 0 {
+		return nil
+	}
+	err := this.verifyHeader(block.Header)
+	if err != nil {
+		return fmt.Errorf("verifyHeader height:%d error %s", height, err)
+	}
+	for _, tx := range block.Transactions {
+		err = verifyTransactionSigs(tx)
+		if err != nil {
+			txHash := tx.Hash()
+			return fmt.Errorf("verify transaction %s height:%d error %s", txHash.ToHexString(), height, err)
+		}
+	}
+	return nil
+}
+
+func verifyTransactionSigs(tx *types.Transaction) error {
+	hash := tx.Hash()
+	for _, sigData := range tx.Sigs {
+		sig, err := sigData.GetSig()
+		if err != nil {
+			return err
+		}
+		m := int(sig.M)
+		if len(sig.PubKeys) == 0 || len(sig.SigData) < m || m > len(sig.PubKeys) || m <= 0 {
+			return fmt.Errorf("wrong tx sig param length")
+		}
+		if len(sig.PubKeys) == 1 {
+			err = signature.Verify(sig.PubKeys[0], hash[:], sig.SigData[0])
+		} else {
+			err = signature.VerifyMultiSignature(hash[:], sig.PubKeys, m, sig.SigData)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) setRecoverStatus(status store.RecoverStatus) {
+	this.recoverLock.Lock()
+	defer this.recoverLock.Unlock()
+	this.recoverStatus = status
+}
+
+func (this *LedgerStoreImp) updateRecoverHeight(height uint32, estimatedTime uint32) {
+	this.recoverLock.Lock()
+	defer this.recoverLock.Unlock()
+	this.recoverStatus.CurrentHeight = height
+	this.recoverStatus.EstimatedTime = estimatedTime
+}
+
+func (this *LedgerStoreImp) finishRecoverStatus() {
+	this.recoverLock.Lock()
+	defer this.recoverLock.Unlock()
+	this.recoverStatus.Recovering = false
+	this.recoverStatus.EndTime = uint32(time.Now().Unix())
+	this.recoverStatus.EstimatedTime = this.recoverStatus.EndTime
+}
+
+//GetRecoverStatus return the progress of the recovery at startup
+func (this *LedgerStoreImp) GetRecoverStatus() store.RecoverStatus {
+	this.recoverLock.RLock()
+	defer this.recoverLock.RUnlock()
+	return this.recoverStatus
+}
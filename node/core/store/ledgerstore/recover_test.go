@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/signature"
+	"github.com/ontio/layer2/node/core/store"
+	"github.com/ontio/layer2/node/core/store/leveldbstore"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPipeline(t *testing.T) {
+	memStore, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	blockStore := &BlockStore{store: memStore}
+	ledgerStore := &LedgerStoreImp{blockStore: blockStore}
+
+	acc := account.NewAccount("")
+	other := account.NewAccount("")
+	bookkeeper, err := types.AddressFromBookkeepers([]keypair.PublicKey{acc.PublicKey})
+	assert.Nil(t, err)
+
+	blockStore.NewBatch()
+	prevHash := common.Uint256{}
+	for height := uint32(0); height < 5; height++ {
+		header := &types.Header{
+			PrevBlockHash:  prevHash,
+			Height:         height,
+			Timestamp:      height + 1,
+			NextBookkeeper: bookkeeper,
+			Bookkeepers:    []keypair.PublicKey{acc.PublicKey},
+		}
+		signer := acc
+		if height == 4 {
+			signer = other
+		}
+		hash := header.Hash()
+		sig, err := signature.Sign(signer, hash[:])
+		assert.Nil(t, err)
+		header.SigData = [][]byte{sig}
+		block := &types.Block{Header: header, Transactions: []*types.Transaction{}}
+		assert.Nil(t, blockStore.SaveBlock(block))
+		blockStore.SaveBlockHash(height, block.Hash())
+		prevHash = block.Hash()
+	}
+	assert.Nil(t, blockStore.CommitTo())
+
+	exit := make(chan struct{})
+	defer close(exit)
+	tasks := ledgerStore.startRecoverPipeline(1, 5, exit)
+	for height := uint32(1); height < 5; height++ {
+		task := <-tasks
+		<-task.done
+		assert.Equal(t, height, task.height)
+		assert.Equal(t, height, task.block.Header.Height)
+		if height == 4 {
+			assert.NotNil(t, task.err)
+		} else {
+			assert.Nil(t, task.err)
+		}
+	}
+}
+
+func TestRecoverStatus(t *testing.T) {
+	ledgerStore := &LedgerStoreImp{}
+	ledgerStore.setRecoverStatus(store.RecoverStatus{Recovering: true, StartHeight: 10, CurrentHeight: 10, TargetHeight: 20})
+	ledgerStore.updateRecoverHeight(15, 100)
+	status := ledgerStore.GetRecoverStatus()
+	assert.True(t, status.Recovering)
+	assert.Equal(t, uint32(15), status.CurrentHeight)
+	assert.Equal(t, uint32(100), status.EstimatedTime)
+
+	ledgerStore.finishRecoverStatus()
+	status = ledgerStore.GetRecoverStatus()
+	assert.False(t, status.Recovering)
+	assert.NotEqual(t, uint32(0), status.EndTime)
+}
+
+func TestRecoverProgress(t *testing.T) {
+	db := NewMemStateStore(0)
+	progress, err := db.GetRecoverProgress()
+	assert.Nil(t, err)
+	assert.Nil(t, progress)
+
+	db.NewBatch()
+	db.SaveRecoverProgress(&store.RecoverStatus{StartHeight: 10, CurrentHeight: 12, TargetHeight: 20, StartTime: 1000})
+	assert.Nil(t, db.CommitTo())
+	progress, err = db.GetRecoverProgress()
+	assert.Nil(t, err)
+	assert.Equal(t, &store.RecoverStatus{StartHeight: 10, TargetHeight: 20, StartTime: 1000}, progress)
+
+	db.NewBatch()
+	db.DeleteRecoverProgress()
+	assert.Nil(t, db.CommitTo())
+	progress, err = db.GetRecoverProgress()
+	assert.Nil(t, err)
+	assert.Nil(t, progress)
+}
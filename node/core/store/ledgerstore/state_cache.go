@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/ontio/layer2/node/core/store"
+)
+
+//StateCache with raw value of hot state keys, like contract storage and contract code. The generation changes when
+//the committed keys are removed, so a value read from the store before the commit is not cached after it
+type StateCache struct {
+	cache      *lru.ARCCache
+	hits       uint64
+	misses     uint64
+	lock       sync.Mutex
+	generation uint64
+}
+
+//NewStateCache return StateCache instance
+func NewStateCache(size int) (*StateCache, error) {
+	cache, err := lru.NewARC(size)
+	if err != nil {
+		return nil, fmt.Errorf("NewARC state error %s", err)
+	}
+	return &StateCache{
+		cache: cache,
+	}, nil
+}
+
+//Get return the raw value of key from cache
+func (this *StateCache) Get(key []byte) ([]byte, bool) {
+	value, ok := this.cache.Get(string(key))
+	if !ok {
+		atomic.AddUint64(&this.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&this.hits, 1)
+	return value.([]byte), true
+}
+
+//Add raw value of key to cache
+func (this *StateCache) Add(key, value []byte) {
+	this.cache.Add(string(key), value)
+}
+
+//Generation return the generation to read the store at, which is passed to AddSince
+func (this *StateCache) Generation() uint64 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.generation
+}
+
+//AddSince add raw value of key read from the store at generation, the value is dropped if keys were committed since
+func (this *StateCache) AddSince(key, value []byte, generation uint64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.generation != generation {
+		return
+	}
+	this.cache.Add(string(key), value)
+}
+
+//RemoveCommitted remove the keys committed to the store and start a new generation
+func (this *StateCache) RemoveCommitted(keys [][]byte) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, key := range keys {
+		this.cache.Remove(string(key))
+	}
+	this.generation++
+}
+
+//Remove key from cache
+func (this *StateCache) Remove(key []byte) {
+	this.cache.Remove(string(key))
+}
+
+//Purge remove all keys from cache and start a new generation
+func (this *StateCache) Purge() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.cache.Purge()
+	this.generation++
+}
+
+//Stats return the hit and miss counters of cache, which are served as the ledger/statecache gauges of MetricsRegistry
+func (this *StateCache) Stats() store.StateCacheStats {
+	return store.StateCacheStats{
+		Size:   uint64(this.cache.Len()),
+		Hits:   atomic.LoadUint64(&this.hits),
+		Misses: atomic.LoadUint64(&this.misses),
+	}
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/smartcontract/service/native/utils"
+)
+
+//EnableStateHistory save the values of storage keys before each block and the state diff of block from next block, so
+//the storage at the heights after the history started can be queried. Disabling the history clears its start height
+func (this *LedgerStoreImp) EnableStateHistory(enable bool) error {
+	start, err := this.stateStore.GetStateHistoryStart()
+	if err != nil {
+		return fmt.Errorf("GetStateHistoryStart error %s", err)
+	}
+	if !enable {
+		atomic.StoreUint32(&this.stateHistoryStart, 0)
+		if start != 0 {
+			log.Infof("state history from height %d is disabled", start)
+			return this.stateStore.DeleteStateHistoryStart()
+		}
+		return nil
+	}
+	if start == 0 {
+		start = this.GetCurrentBlockHeight() + 1
+		err = this.stateStore.SaveStateHistoryStart(start)
+		if err != nil {
+			return fmt.Errorf("SaveStateHistoryStart error %s", err)
+		}
+	}
+	atomic.StoreUint32(&this.stateHistoryStart, start)
+	log.Infof("state history is available from height %d", start-1)
+	return nil
+}
+
+//saveStateHistory save the values before block of the storage keys in write set and the state diff of block to state
+//store batch
+func (this *LedgerStoreImp) saveStateHistory(height uint32, result store.ExecuteResult) error {
+	if atomic.LoadUint32(&this.stateHistoryStart) == 0 {
+		return nil
+	}
+	var err error
+	changes := make([]*store.StateChange, 0)
+	result.WriteSet.ForEach(func(key, val []byte) {
+		if err != nil || len(key) == 0 {
+			return
+		}
+		var old []byte
+		old, err = this.stateStore.GetRawValue(key)
+		if err != nil {
+			return
+		}
+		if key[0] == byte(scom.ST_STORAGE) {
+			this.stateStore.SaveStateHistory(height, key, old)
+		}
+		changes = append(changes, &store.StateChange{Key: key, OldValue: old, NewValue: val})
+	})
+	if err != nil {
+		return err
+	}
+	this.stateStore.SaveStateDiff(height, changes)
+	return nil
+}
+
+//GetStateDiff return the state keys updated by block of height and their values before and after block, the height
+//should not be earlier than the state history started
+func (this *LedgerStoreImp) GetStateDiff(height uint32) ([]*store.StateChange, error) {
+	start := atomic.LoadUint32(&this.stateHistoryStart)
+	if start == 0 {
+		return nil, fmt.Errorf("state history is not enabled")
+	}
+	if height < start {
+		return nil, fmt.Errorf("state diff is available from height %d", start)
+	}
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d is not executed", height)
+	}
+	return this.stateStore.GetStateDiff(height)
+}
+
+//GetStorageItemAt return the storage item after block of height executed, the height should not be earlier than the
+//block before state history started
+func (this *LedgerStoreImp) GetStorageItemAt(key *states.StorageKey, height uint32) (*states.StorageItem, error) {
+	start := atomic.LoadUint32(&this.stateHistoryStart)
+	if start == 0 {
+		return nil, fmt.Errorf("state history is not enabled")
+	}
+	if height+1 < start {
+		return nil, fmt.Errorf("state history is available from height %d", start-1)
+	}
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d is not executed", height)
+	}
+	//read current value first, a block saved after it with the key updated is found in history
+	current, err := this.stateStore.GetStorageState(key)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, err
+	}
+	storeKey, err := this.stateStore.getStorageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	value, found, err := this.stateStore.GetStateHistory(storeKey, height)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateHistory error %s", err)
+	}
+	if !found {
+		if current == nil {
+			return nil, scom.ErrNotFound
+		}
+		return current, nil
+	}
+	if len(value) == 0 {
+		return nil, scom.ErrNotFound
+	}
+	item := new(states.StorageItem)
+	err = item.Deserialization(common.NewZeroCopySource(value))
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+//GetBalanceAt return the ont and ong balance of address after block of height executed
+func (this *LedgerStoreImp) GetBalanceAt(addr common.Address, height uint32) (uint64, uint64, error) {
+	balances := make([]uint64, 0, 2)
+	for _, contract := range []common.Address{utils.OntContractAddress, utils.OngContractAddress} {
+		item, err := this.GetStorageItemAt(&states.StorageKey{ContractAddress: contract, Key: addr[:]}, height)
+		if err != nil && err != scom.ErrNotFound {
+			return 0, 0, err
+		}
+		balance := uint64(0)
+		if item != nil {
+			var eof bool
+			balance, eof = common.NewZeroCopySource(item.Value).NextUint64()
+			if eof {
+				return 0, 0, fmt.Errorf("balance storage value error %s", common.ErrIrregularData)
+			}
+		}
+		balances = append(balances, balance)
+	}
+	return balances[0], balances[1], nil
+}
+
+//IterateStorage return the storage entries of contract with the key prefix after block of height executed, in key
+//order. The current storage is rolled back by the state diffs of blocks after height, so the height should not be
+//earlier than the block before state history started
+func (this *LedgerStoreImp) IterateStorage(contract common.Address, prefix []byte, height uint32) ([]*store.StorageEntry, error) {
+	start := atomic.LoadUint32(&this.stateHistoryStart)
+	if start == 0 {
+		return nil, fmt.Errorf("state history is not enabled")
+	}
+	if height+1 < start {
+		return nil, fmt.Errorf("state history is available from height %d", start-1)
+	}
+	if height > this.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("block height %d is not executed", height)
+	}
+	storePrefix, err := this.stateStore.getStorageKey(&states.StorageKey{ContractAddress: contract, Key: prefix})
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte)
+	iter := this.stateStore.store.NewIterator(storePrefix)
+	for iter.Next() {
+		values[string(iter.Key())] = append([]byte{}, iter.Value()...)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	//state diff is committed with the storage, so the diffs read after iterator cover the blocks it has seen
+	current := this.GetCurrentBlockHeight()
+	rolled := make(map[string]bool)
+	for h := height + 1; h > height; h++ {
+		changes, err := this.stateStore.GetStateDiff(h)
+		if err == scom.ErrNotFound && h > current {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GetStateDiff height %d error %s", h, err)
+		}
+		for _, change := range changes {
+			key := string(change.Key)
+			if rolled[key] || !bytes.HasPrefix(change.Key, storePrefix) {
+				continue
+			}
+			rolled[key] = true
+			if len(change.OldValue) == 0 {
+				delete(values, key)
+			} else {
+				values[key] = change.OldValue
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]*store.StorageEntry, 0, len(keys))
+	for _, key := range keys {
+		item := new(states.StorageItem)
+		err = item.Deserialization(common.NewZeroCopySource(values[key]))
+		if err != nil {
+			return nil, fmt.Errorf("storage item %x error %s", key, err)
+		}
+		entries = append(entries, &store.StorageEntry{Key: []byte(key[1+common.ADDR_LEN:]), Value: item.Value})
+	}
+	return entries, nil
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/smartcontract/service/native/utils"
+	"github.com/ontio/layer2/node/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateHistory(t *testing.T) {
+	db := NewMemStateStore(0)
+	ledgerStore := &LedgerStoreImp{stateStore: db}
+	addr := common.Address{1}
+	balance := func(v uint64) []byte {
+		sink := common.NewZeroCopySink(nil)
+		sink.WriteUint64(v)
+		return sink.Bytes()
+	}
+	saveBlock := func(height uint32, update func(cache *storage.CacheDB)) {
+		overlay := db.NewOverlayDB()
+		cache := storage.NewCacheDB(overlay)
+		update(cache)
+		cache.Commit()
+		result := store.ExecuteResult{WriteSet: overlay.GetWriteSet()}
+		db.NewBatch()
+		assert.Nil(t, ledgerStore.saveStateHistory(height, result))
+		result.WriteSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+		ledgerStore.currBlockHeight = height
+	}
+	putItem := func(cache *storage.CacheDB, contract common.Address, key, value []byte) {
+		sink := common.NewZeroCopySink(nil)
+		(&states.StorageItem{Value: value}).Serialization(sink)
+		cache.Put(append(contract[:], key...), sink.Bytes())
+	}
+
+	//the blocks before history enabled are not saved
+	saveBlock(1, func(cache *storage.CacheDB) {
+		putItem(cache, utils.OntContractAddress, addr[:], balance(100))
+	})
+	_, err := ledgerStore.GetStorageItemAt(&states.StorageKey{ContractAddress: utils.OntContractAddress, Key: addr[:]}, 1)
+	assert.NotNil(t, err)
+	assert.Nil(t, ledgerStore.EnableStateHistory(true))
+
+	saveBlock(2, func(cache *storage.CacheDB) {
+		putItem(cache, utils.OntContractAddress, addr[:], balance(80))
+		putItem(cache, utils.OngContractAddress, addr[:], balance(5))
+	})
+	saveBlock(3, func(cache *storage.CacheDB) {
+		putItem(cache, utils.OngContractAddress, addr[:], balance(7))
+	})
+	saveBlock(4, func(cache *storage.CacheDB) {
+		cache.Delete(append(utils.OntContractAddress[:], addr[:]...))
+	})
+
+	for _, c := range []struct {
+		height uint32
+		ont    uint64
+		ong    uint64
+	}{{1, 100, 0}, {2, 80, 5}, {3, 80, 7}, {4, 0, 7}} {
+		ont, ong, err := ledgerStore.GetBalanceAt(addr, c.height)
+		assert.Nil(t, err)
+		assert.Equal(t, c.ont, ont, "height %d", c.height)
+		assert.Equal(t, c.ong, ong, "height %d", c.height)
+	}
+	_, err = ledgerStore.GetStorageItemAt(&states.StorageKey{ContractAddress: utils.OntContractAddress, Key: addr[:]}, 4)
+	assert.Equal(t, scom.ErrNotFound, err)
+
+	item := func(value []byte) []byte {
+		sink := common.NewZeroCopySink(nil)
+		(&states.StorageItem{Value: value}).Serialization(sink)
+		return sink.Bytes()
+	}
+	ontKey := append([]byte{byte(scom.ST_STORAGE)}, append(utils.OntContractAddress[:], addr[:]...)...)
+	ongKey := append([]byte{byte(scom.ST_STORAGE)}, append(utils.OngContractAddress[:], addr[:]...)...)
+	changes, err := ledgerStore.GetStateDiff(2)
+	assert.Nil(t, err)
+	assert.Equal(t, []*store.StateChange{
+		{Key: ontKey, OldValue: item(balance(100)), NewValue: item(balance(80))},
+		{Key: ongKey, OldValue: nil, NewValue: item(balance(5))},
+	}, changes)
+	changes, err = ledgerStore.GetStateDiff(4)
+	assert.Nil(t, err)
+	assert.Equal(t, []*store.StateChange{{Key: ontKey, OldValue: item(balance(80)), NewValue: nil}}, changes)
+	_, err = ledgerStore.GetStateDiff(1)
+	assert.NotNil(t, err)
+	_, err = ledgerStore.GetStateDiff(5)
+	assert.NotNil(t, err)
+	_, _, err = ledgerStore.GetBalanceAt(addr, 0)
+	assert.NotNil(t, err)
+	_, _, err = ledgerStore.GetBalanceAt(addr, 5)
+	assert.NotNil(t, err)
+
+	//the history is restarted after disabled
+	assert.Nil(t, ledgerStore.EnableStateHistory(false))
+	_, _, err = ledgerStore.GetBalanceAt(addr, 4)
+	assert.NotNil(t, err)
+	assert.Nil(t, ledgerStore.EnableStateHistory(true))
+	_, _, err = ledgerStore.GetBalanceAt(addr, 3)
+	assert.NotNil(t, err)
+	_, _, err = ledgerStore.GetBalanceAt(addr, 4)
+	assert.Nil(t, err)
+}
+
+func TestIterateStorage(t *testing.T) {
+	db := NewMemStateStore(0)
+	ledgerStore := &LedgerStoreImp{stateStore: db}
+	contract := common.AddressFromVmCode([]byte("contract"))
+	saveBlock := func(height uint32, update func(cache *storage.CacheDB)) {
+		overlay := db.NewOverlayDB()
+		cache := storage.NewCacheDB(overlay)
+		update(cache)
+		cache.Commit()
+		result := store.ExecuteResult{WriteSet: overlay.GetWriteSet()}
+		db.NewBatch()
+		assert.Nil(t, ledgerStore.saveStateHistory(height, result))
+		result.WriteSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+		ledgerStore.currBlockHeight = height
+	}
+	putItem := func(cache *storage.CacheDB, key, value []byte) {
+		sink := common.NewZeroCopySink(nil)
+		(&states.StorageItem{Value: value}).Serialization(sink)
+		cache.Put(append(contract[:], key...), sink.Bytes())
+	}
+	keyA, keyB, other := []byte{1, 0xa}, []byte{1, 0xb}, []byte{2, 0xa}
+
+	saveBlock(1, func(cache *storage.CacheDB) {
+		putItem(cache, keyA, []byte("a1"))
+		putItem(cache, other, []byte("o1"))
+	})
+	_, err := ledgerStore.IterateStorage(contract, []byte{1}, 1)
+	assert.NotNil(t, err)
+	assert.Nil(t, ledgerStore.EnableStateHistory(true))
+	saveBlock(2, func(cache *storage.CacheDB) {
+		putItem(cache, keyB, []byte("b2"))
+	})
+	saveBlock(3, func(cache *storage.CacheDB) {
+		putItem(cache, keyA, []byte("a3"))
+		cache.Delete(append(contract[:], keyB...))
+	})
+	saveBlock(4, func(cache *storage.CacheDB) {
+		putItem(cache, keyB, []byte("b4"))
+		putItem(cache, keyA, []byte("a4"))
+	})
+
+	for _, c := range []struct {
+		height  uint32
+		entries []*store.StorageEntry
+	}{
+		{1, []*store.StorageEntry{{Key: keyA, Value: []byte("a1")}}},
+		{2, []*store.StorageEntry{{Key: keyA, Value: []byte("a1")}, {Key: keyB, Value: []byte("b2")}}},
+		{3, []*store.StorageEntry{{Key: keyA, Value: []byte("a3")}}},
+		{4, []*store.StorageEntry{{Key: keyA, Value: []byte("a4")}, {Key: keyB, Value: []byte("b4")}}},
+	} {
+		entries, err := ledgerStore.IterateStorage(contract, []byte{1}, c.height)
+		assert.Nil(t, err)
+		assert.Equal(t, c.entries, entries, "height %d", c.height)
+	}
+	entries, err := ledgerStore.IterateStorage(contract, nil, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, &store.StorageEntry{Key: other, Value: []byte("o1")}, entries[2])
+	entries, err = ledgerStore.IterateStorage(common.Address{}, nil, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	_, err = ledgerStore.IterateStorage(contract, nil, 0)
+	assert.NotNil(t, err)
+	_, err = ledgerStore.IterateStorage(contract, nil, 5)
+	assert.NotNil(t, err)
+}
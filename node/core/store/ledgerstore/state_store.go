@@ -23,15 +23,19 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/ontio/layer2/node/common"
+	sysconfig "github.com/ontio/layer2/node/common/config"
 	"github.com/ontio/layer2/node/common/log"
 	"github.com/ontio/layer2/node/common/serialization"
 	"github.com/ontio/layer2/node/core/payload"
 	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
 	scom "github.com/ontio/layer2/node/core/store/common"
 	"github.com/ontio/layer2/node/core/store/leveldbstore"
 	"github.com/ontio/layer2/node/core/store/overlaydb"
+	"github.com/ontio/layer2/node/core/types"
 	"github.com/ontio/layer2/node/merkle"
 )
 
@@ -48,12 +52,16 @@ type StateStore struct {
 	deltaMerkleTree      *merkle.CompactMerkleTree //Merkle tree of delta state root
 	merkleHashStore      merkle.HashStore
 	stateHashCheckHeight uint32
+	cache                *StateCache      //Read cache of contract state and storage, nil if disabled
+	dirtyKeys            [][]byte         //Keys written in current batch, removed from cache after commit
+	readOnly             bool             //Store is a view of the state written by other process
+	retiredHashStore     merkle.HashStore //Hash store before last reload, closed at next reload
 }
 
 //NewStateStore return state store instance
 func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*StateStore, error) {
 	var err error
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := openLevelDBStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +71,12 @@ func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*Stat
 		merklePath:           merklePath,
 		stateHashCheckHeight: stateHashCheckHeight,
 	}
+	if size := sysconfig.DefConfig.Common.StateCacheSize; size > 0 {
+		stateStore.cache, err = NewStateCache(int(size))
+		if err != nil {
+			return nil, err
+		}
+	}
 	_, height, err := stateStore.GetCurrentBlock()
 	if err != nil && err != scom.ErrNotFound {
 		return nil, fmt.Errorf("GetCurrentBlock error %s", err)
@@ -90,14 +104,52 @@ func NewMemStateStore(stateHashHeight uint32) *StateStore {
 //NewBatch start new commit batch
 func (self *StateStore) NewBatch() {
 	self.store.NewBatch()
+	self.dirtyKeys = nil
 }
 
 func (self *StateStore) BatchPutRawKeyVal(key, val []byte) {
 	self.store.BatchPut(key, val)
+	self.invalidCache(key)
 }
 
 func (self *StateStore) BatchDeleteRawKey(key []byte) {
 	self.store.BatchDelete(key)
+	self.invalidCache(key)
+}
+
+func (self *StateStore) invalidCache(key []byte) {
+	if self.cache == nil {
+		return
+	}
+	self.cache.Remove(key)
+	self.dirtyKeys = append(self.dirtyKeys, key)
+}
+
+//getCached return the value of key, read from cache first
+func (self *StateStore) getCached(key []byte) ([]byte, error) {
+	if self.cache == nil {
+		return self.store.Get(key)
+	}
+	if value, ok := self.cache.Get(key); ok {
+		return value, nil
+	}
+	// the value read before a commit may be stale once the commit removed the key, so it is only cached when no
+	// commit happened during the read
+	generation := self.cache.Generation()
+	value, err := self.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	self.cache.AddSince(key, value, generation)
+	return value, nil
+}
+
+//GetStateCacheStats return the hit and miss counters of read cache
+func (self *StateStore) GetStateCacheStats() store.StateCacheStats {
+	if self.cache == nil {
+		return store.StateCacheStats{}
+	}
+	return self.cache.Stats()
 }
 
 func (self *StateStore) init(currBlockHeight uint32) error {
@@ -108,7 +160,11 @@ func (self *StateStore) init(currBlockHeight uint32) error {
 	if treeSize > 0 && treeSize != currBlockHeight+1 {
 		return fmt.Errorf("merkle tree size is inconsistent with blockheight: %d", currBlockHeight+1)
 	}
-	self.merkleHashStore, err = merkle.NewFileHashStore(self.merklePath, treeSize)
+	if self.readOnly {
+		self.merkleHashStore, err = merkle.NewReadOnlyFileHashStore(self.merklePath, treeSize)
+	} else {
+		self.merkleHashStore, err = merkle.NewFileHashStore(self.merklePath, treeSize)
+	}
 	if err != nil {
 		log.Warn("merkle store is inconsistent with ChainStore. persistence will be disabled")
 	}
@@ -127,6 +183,28 @@ func (self *StateStore) init(currBlockHeight uint32) error {
 	return nil
 }
 
+//reload init the merkle trees and drop the cache after the read-only view of store refreshed. The hash store of
+//previous view is kept until next reload for the readers still using it
+func (self *StateStore) reload() error {
+	_, height, err := self.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("GetCurrentBlock error %s", err)
+	}
+	retired := self.merkleHashStore
+	err = self.init(height)
+	if err != nil {
+		return err
+	}
+	if self.retiredHashStore != nil {
+		self.retiredHashStore.Close()
+	}
+	self.retiredHashStore = retired
+	if self.cache != nil {
+		self.cache.Purge()
+	}
+	return nil
+}
+
 //GetStateMerkleTree return merkle tree size an tree node
 func (self *StateStore) GetStateMerkleTree() (uint32, []common.Uint256, error) {
 	key := self.genStateMerkleTreeKey()
@@ -143,6 +221,11 @@ func (self *StateStore) getMerkleTree(key []byte) (uint32, []common.Uint256, err
 	if err != nil {
 		return 0, nil, err
 	}
+	return parseMerkleTree(data)
+}
+
+//parseMerkleTree return the tree size and tree nodes of merkle tree value in store
+func parseMerkleTree(data []byte) (uint32, []common.Uint256, error) {
 	value := bytes.NewBuffer(data)
 	treeSize, err := serialization.ReadUint32(value)
 	if err != nil {
@@ -232,9 +315,22 @@ func (self *StateStore) NewOverlayDB() *overlaydb.OverlayDB {
 	return overlaydb.NewOverlayDB(self.store)
 }
 
-//CommitTo commit state batch to state store
+//CommitTo commit state batch to state store. The merkle hashes of the batch are written before the batch,
+//the hash file ahead of the stored tree size is overwritten at next start
 func (self *StateStore) CommitTo() error {
-	return self.store.BatchCommit()
+	if self.merkleHashStore != nil {
+		err := self.merkleHashStore.Flush()
+		if err != nil {
+			return fmt.Errorf("merkleHashStore.Flush error %s", err)
+		}
+	}
+	err := self.store.BatchCommit()
+	if self.cache != nil {
+		// value may be cached again before the batch committed, so remove it after commit
+		self.cache.RemoveCommitted(self.dirtyKeys)
+		self.dirtyKeys = nil
+	}
+	return err
 }
 
 //GetContractState return contract by contract address
@@ -244,7 +340,7 @@ func (self *StateStore) GetContractState(contractHash common.Address) (*payload.
 		return nil, err
 	}
 
-	value, err := self.store.Get(key)
+	value, err := self.getCached(key)
 	if err != nil {
 		return nil, err
 	}
@@ -296,7 +392,7 @@ func (self *StateStore) GetStorageState(key *states.StorageKey) (*states.Storage
 		return nil, err
 	}
 
-	data, err := self.store.Get(storeKey)
+	data, err := self.getCached(storeKey)
 	if err != nil {
 		return nil, err
 	}
@@ -401,15 +497,26 @@ func (self *StateStore) ClearAll() error {
 		self.store.NewBatch() // reset the batch
 		return err
 	}
+	if self.cache != nil {
+		self.cache.Purge()
+	}
 	return self.store.BatchCommit()
 }
 
 //Close state store
 func (self *StateStore) Close() error {
 	self.merkleHashStore.Close()
+	if self.retiredHashStore != nil {
+		self.retiredHashStore.Close()
+	}
 	return self.store.Close()
 }
 
+//Compact state store
+func (self *StateStore) Compact() error {
+	return self.store.Compact()
+}
+
 func (self *StateStore) GetLayer2States(height uint32) ([]common.Uint256, error) {
 	key := self.genLayer2StatesKey(height)
 	data, err := self.store.Get(key)
@@ -429,17 +536,6 @@ func (self *StateStore) GetLayer2States(height uint32) ([]common.Uint256, error)
 	return hashes, nil
 }
 
-func (self *StateStore) GetLayer2StatesRoot(height uint32) (common.Uint256, error) {
-	states, err := self.GetLayer2States(height)
-	if err != nil && err != scom.ErrNotFound {
-		return common.UINT256_EMPTY, err
-	}
-	if err == scom.ErrNotFound {
-		return common.UINT256_EMPTY, nil
-	}
-	return merkle.TreeHasher{}.HashFullTreeWithLeafHash(states), nil
-}
-
 func (self *StateStore) SaveLayer2States(height uint32, layer2States []common.Uint256) error {
 	//save cross states hash
 	if len(layer2States) == 0 {
@@ -454,6 +550,406 @@ func (self *StateStore) SaveLayer2States(height uint32, layer2States []common.Ui
 	return nil
 }
 
+//DeleteLayer2States delete the account state leaves and accounts of block in batch
+func (self *StateStore) DeleteLayer2States(height uint32) {
+	self.store.BatchDelete(self.genLayer2StatesKey(height))
+	self.store.BatchDelete(self.genLayer2AccountStatesKey(height))
+}
+
+//SaveLayer2AccountStates save the accounts and values which are the preimage of layer2 states hash
+func (self *StateStore) SaveLayer2AccountStates(height uint32, accounts []*store.AccountState) {
+	if len(accounts) == 0 {
+		return
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarUint(uint64(len(accounts)))
+	for _, account := range accounts {
+		sink.WriteAddress(account.Contract)
+		sink.WriteAddress(account.Address)
+		sink.WriteVarBytes(account.Value)
+	}
+	self.store.BatchPut(self.genLayer2AccountStatesKey(height), sink.Bytes())
+}
+
+//GetLayer2AccountStates return the accounts and values which are the preimage of layer2 states hash
+func (self *StateStore) GetLayer2AccountStates(height uint32) ([]*store.AccountState, error) {
+	data, err := self.store.Get(self.genLayer2AccountStatesKey(height))
+	if err != nil {
+		return nil, err
+	}
+	source := common.NewZeroCopySource(data)
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	accounts := make([]*store.AccountState, 0, n)
+	for i := uint64(0); i < n; i++ {
+		account := &store.AccountState{}
+		account.Contract, eof = source.NextAddress()
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+		account.Address, eof = source.NextAddress()
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+		account.Value, _, irregular, eof = source.NextVarBytes()
+		if irregular {
+			return nil, common.ErrIrregularData
+		}
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+//SaveRecoverProgress persist the start height, target height and start time of the recovery in batch,
+//so the recovery interrupted by crash can be resumed with its original progress
+func (self *StateStore) SaveRecoverProgress(status *store.RecoverStatus) {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(status.StartHeight)
+	sink.WriteUint32(status.TargetHeight)
+	sink.WriteUint32(status.StartTime)
+	self.store.BatchPut(self.getRecoverProgressKey(), sink.Bytes())
+}
+
+//GetRecoverProgress return the progress of the unfinished recovery, nil if there is no unfinished recovery
+func (self *StateStore) GetRecoverProgress() (*store.RecoverStatus, error) {
+	data, err := self.store.Get(self.getRecoverProgressKey())
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	status := &store.RecoverStatus{}
+	source := common.NewZeroCopySource(data)
+	var eof bool
+	status.StartHeight, eof = source.NextUint32()
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	status.TargetHeight, eof = source.NextUint32()
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	status.StartTime, eof = source.NextUint32()
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return status, nil
+}
+
+//DeleteRecoverProgress delete the progress of the finished recovery in batch
+func (self *StateStore) DeleteRecoverProgress() {
+	self.store.BatchDelete(self.getRecoverProgressKey())
+}
+
+//GetSMTNode return the node of account state sparse merkle tree by node hash
+func (self *StateStore) GetSMTNode(hash common.Uint256) ([]byte, error) {
+	return self.store.Get(append([]byte{byte(scom.SYS_ACCOUNT_TREE_NODE)}, hash[:]...))
+}
+
+//SaveStateTreeNodes save the new nodes of account state and storage state sparse merkle trees in batch
+func (self *StateStore) SaveStateTreeNodes(nodes map[common.Uint256][]byte) {
+	for hash, node := range nodes {
+		self.store.BatchPut(append([]byte{byte(scom.SYS_ACCOUNT_TREE_NODE)}, hash[:]...), node)
+	}
+}
+
+//SaveAccountTreeRoot save the root of account state sparse merkle tree of block in batch
+func (self *StateStore) SaveAccountTreeRoot(height uint32, root common.Uint256) {
+	self.store.BatchPut(self.genAccountTreeRootKey(height), root[:])
+}
+
+//GetAccountTreeRoot return the root of account state sparse merkle tree after block executed
+func (self *StateStore) GetAccountTreeRoot(height uint32) (common.Uint256, error) {
+	data, err := self.store.Get(self.genAccountTreeRootKey(height))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return common.Uint256ParseFromBytes(data)
+}
+
+//SaveStorageTreeRoot save the root of storage state sparse merkle tree of block in batch
+func (self *StateStore) SaveStorageTreeRoot(height uint32, root common.Uint256) {
+	self.store.BatchPut(self.genStorageTreeRootKey(height), root[:])
+}
+
+//GetStorageTreeRoot return the root of storage state sparse merkle tree after block executed
+func (self *StateStore) GetStorageTreeRoot(height uint32) (common.Uint256, error) {
+	data, err := self.store.Get(self.genStorageTreeRootKey(height))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return common.Uint256ParseFromBytes(data)
+}
+
+func (self *StateStore) genStorageTreeRootKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_STORAGE_TREE_ROOT)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+func (self *StateStore) genAccountTreeRootKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_ACCOUNT_TREE_ROOT)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//GetMPTNode return the node of full state merkle patricia trie by node hash
+func (self *StateStore) GetMPTNode(hash common.Uint256) ([]byte, error) {
+	return self.store.Get(append([]byte{byte(scom.SYS_FULL_STATE_NODE)}, hash[:]...))
+}
+
+//SaveFullStateNodes save the new nodes of full state merkle patricia trie in batch
+func (self *StateStore) SaveFullStateNodes(nodes map[common.Uint256][]byte) {
+	for hash, node := range nodes {
+		self.store.BatchPut(append([]byte{byte(scom.SYS_FULL_STATE_NODE)}, hash[:]...), node)
+	}
+}
+
+//SaveFullStateRoot save the root of full state merkle patricia trie of block in batch
+func (self *StateStore) SaveFullStateRoot(height uint32, root common.Uint256) {
+	self.store.BatchPut(self.genFullStateRootKey(height), root[:])
+}
+
+//GetFullStateRoot return the root of full state merkle patricia trie after block executed
+func (self *StateStore) GetFullStateRoot(height uint32) (common.Uint256, error) {
+	data, err := self.store.Get(self.genFullStateRootKey(height))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return common.Uint256ParseFromBytes(data)
+}
+
+func (self *StateStore) genFullStateRootKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_FULL_STATE_ROOT)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//SaveGasStats persist the gas statistics of block in batch
+func (self *StateStore) SaveGasStats(stats *store.GasStats) {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint64(stats.TxCount)
+	sink.WriteUint64(stats.FailedTxs)
+	sink.WriteUint64(stats.GasUsed)
+	sink.WriteUint64(stats.SysFee)
+	sink.WriteUint64(stats.MinGasPrice)
+	sink.WriteUint64(stats.MaxGasPrice)
+	self.store.BatchPut(self.genGasStatsKey(stats.FromHeight), sink.Bytes())
+}
+
+//GetGasStats return the gas statistics of block, ErrNotFound for the blocks saved before statistics are kept
+func (self *StateStore) GetGasStats(height uint32) (*store.GasStats, error) {
+	data, err := self.store.Get(self.genGasStatsKey(height))
+	if err != nil {
+		return nil, err
+	}
+	stats := &store.GasStats{FromHeight: height, ToHeight: height, Blocks: 1}
+	source := common.NewZeroCopySource(data)
+	for _, value := range []*uint64{&stats.TxCount, &stats.FailedTxs, &stats.GasUsed, &stats.SysFee,
+		&stats.MinGasPrice, &stats.MaxGasPrice} {
+		var eof bool
+		*value, eof = source.NextUint64()
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+	return stats, nil
+}
+
+func (self *StateStore) genGasStatsKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_GAS_STATS)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//GetRawValue return the value of state key in store, nil if the key is absent
+func (self *StateStore) GetRawValue(key []byte) ([]byte, error) {
+	value, err := self.store.Get(key)
+	if err != nil && err != scom.ErrNotFound {
+		return nil, err
+	}
+	return value, nil
+}
+
+//SaveStateHistory save the value of storage key before block in batch. The empty value means the key did not exist
+func (self *StateStore) SaveStateHistory(height uint32, key, value []byte) {
+	self.store.BatchPut(self.genStateHistoryKey(key, height), value)
+}
+
+//GetStateHistory return the value of storage key before the first block after height which updated it, found is false
+//if the key is not updated after height
+func (self *StateStore) GetStateHistory(key []byte, height uint32) (value []byte, found bool, err error) {
+	if height == math.MaxUint32 {
+		return nil, false, nil
+	}
+	iter := self.store.NewRangeIterator(self.genStateHistoryKey(key, height+1),
+		self.genStateHistoryKey(key, math.MaxUint32))
+	defer iter.Release()
+	if iter.Next() {
+		return append([]byte{}, iter.Value()...), true, nil
+	}
+	return nil, false, iter.Error()
+}
+
+//SaveStateHistoryStart save the first block height whose state history is saved
+func (self *StateStore) SaveStateHistoryStart(height uint32) error {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(height)
+	return self.store.Put([]byte{byte(scom.SYS_STATE_HISTORY_START)}, sink.Bytes())
+}
+
+//DeleteStateHistoryStart delete the start height after state history disabled, so the history is restarted when enabled
+func (self *StateStore) DeleteStateHistoryStart() error {
+	return self.store.Delete([]byte{byte(scom.SYS_STATE_HISTORY_START)})
+}
+
+//GetStateHistoryStart return the first block height whose state history is saved, 0 if state history is not enabled
+func (self *StateStore) GetStateHistoryStart() (uint32, error) {
+	data, err := self.store.Get([]byte{byte(scom.SYS_STATE_HISTORY_START)})
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(data).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+//SaveStateDiff save the state keys updated by block and their values before and after block in batch
+func (self *StateStore) SaveStateDiff(height uint32, changes []*store.StateChange) {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarUint(uint64(len(changes)))
+	for _, change := range changes {
+		sink.WriteVarBytes(change.Key)
+		sink.WriteVarBytes(change.OldValue)
+		sink.WriteVarBytes(change.NewValue)
+	}
+	self.store.BatchPut(self.genStateDiffKey(height), sink.Bytes())
+}
+
+//GetStateDiff return the state keys updated by block and their values before and after block
+func (self *StateStore) GetStateDiff(height uint32) ([]*store.StateChange, error) {
+	data, err := self.store.Get(self.genStateDiffKey(height))
+	if err != nil {
+		return nil, err
+	}
+	source := common.NewZeroCopySource(data)
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	changes := make([]*store.StateChange, 0, n)
+	for i := uint64(0); i < n; i++ {
+		change := &store.StateChange{}
+		for _, value := range []*[]byte{&change.Key, &change.OldValue, &change.NewValue} {
+			*value, _, irregular, eof = source.NextVarBytes()
+			if irregular {
+				return nil, common.ErrIrregularData
+			}
+			if eof {
+				return nil, io.ErrUnexpectedEOF
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func (self *StateStore) genStateDiffKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_STATE_DIFF)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//SaveCheckpoint save the checkpoint and set it as the latest checkpoint
+func (self *StateStore) SaveCheckpoint(checkpoint *types.Checkpoint) error {
+	sink := common.NewZeroCopySink(nil)
+	checkpoint.Serialization(sink)
+	self.store.NewBatch()
+	self.store.BatchPut(self.genCheckpointKey(checkpoint.Height), sink.Bytes())
+	sink.Reset()
+	sink.WriteUint32(checkpoint.Height)
+	self.store.BatchPut([]byte{byte(scom.SYS_LATEST_CHECKPOINT)}, sink.Bytes())
+	return self.store.BatchCommit()
+}
+
+//GetCheckpoint return the checkpoint of height
+func (self *StateStore) GetCheckpoint(height uint32) (*types.Checkpoint, error) {
+	data, err := self.store.Get(self.genCheckpointKey(height))
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(types.Checkpoint)
+	err = checkpoint.Deserialization(common.NewZeroCopySource(data))
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+//GetLatestCheckpointHeight return the height of the latest checkpoint, ErrNotFound if no checkpoint saved
+func (self *StateStore) GetLatestCheckpointHeight() (uint32, error) {
+	data, err := self.store.Get([]byte{byte(scom.SYS_LATEST_CHECKPOINT)})
+	if err != nil {
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(data).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+func (self *StateStore) genCheckpointKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_CHECKPOINT)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//genStateHistoryKey return the key of storage key at height, the height is big endian so the history is in height order
+func (self *StateStore) genStateHistoryKey(key []byte, height uint32) []byte {
+	sink := common.NewZeroCopySink(make([]byte, 0, len(key)+10))
+	sink.WriteByte(byte(scom.SYS_STATE_HISTORY))
+	sink.WriteVarBytes(key)
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, height)
+	sink.WriteBytes(buf)
+	return sink.Bytes()
+}
+
+func (self *StateStore) getRecoverProgressKey() []byte {
+	return []byte{byte(scom.SYS_RECOVER_PROGRESS)}
+}
+
+func (self *StateStore) genLayer2AccountStatesKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_LAYER2_ACCOUNT_STATES)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
 func (self *StateStore) genLayer2StatesKey(height uint32) []byte {
 	key := make([]byte, 5)
 	key[0] = byte(scom.SYS_CURRENT_LAYER2_STATES)
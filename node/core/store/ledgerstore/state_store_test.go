@@ -19,11 +19,17 @@
 package ledgerstore
 
 import (
+	"crypto/sha256"
 	"math/rand"
+	"sync"
 	"testing"
 
 	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
 	"github.com/ontio/layer2/node/merkle"
+	"github.com/ontio/layer2/node/smartcontract/storage"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -71,3 +77,352 @@ func TestStateMerkleRoot(t *testing.T) {
 	}
 
 }
+
+func TestStateCache(t *testing.T) {
+	db := NewMemStateStore(0)
+	cache, err := NewStateCache(10)
+	assert.Nil(t, err)
+	db.cache = cache
+
+	putItem := func(key *states.StorageKey, value []byte) {
+		storeKey, _ := db.getStorageKey(key)
+		sink := common.NewZeroCopySink(nil)
+		item := &states.StorageItem{Value: value}
+		item.Serialization(sink)
+		db.NewBatch()
+		db.BatchPutRawKeyVal(storeKey, sink.Bytes())
+		err := db.CommitTo()
+		assert.Nil(t, err)
+	}
+
+	key := &states.StorageKey{ContractAddress: common.ADDRESS_EMPTY, Key: []byte("key")}
+	putItem(key, []byte("v1"))
+	item, err := db.GetStorageState(key)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), item.Value)
+	item, err = db.GetStorageState(key)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), item.Value)
+	stats := db.GetStateCacheStats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+
+	putItem(key, []byte("v2"))
+	item, err = db.GetStorageState(key)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v2"), item.Value)
+	assert.Equal(t, uint64(2), db.GetStateCacheStats().Misses)
+}
+
+func TestStateCacheStaleRead(t *testing.T) {
+	cache, err := NewStateCache(10)
+	assert.Nil(t, err)
+	key := []byte("key")
+
+	// the value read before the commit is not cached after the commit removed the key
+	generation := cache.Generation()
+	cache.RemoveCommitted([][]byte{key})
+	cache.AddSince(key, []byte("v1"), generation)
+	_, ok := cache.Get(key)
+	assert.False(t, ok)
+	cache.AddSince(key, []byte("v2"), cache.Generation())
+	value, ok := cache.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v2"), value)
+
+	generation = cache.Generation()
+	cache.Purge()
+	cache.AddSince(key, []byte("v2"), generation)
+	_, ok = cache.Get(key)
+	assert.False(t, ok)
+}
+
+//pausedGetStore pause the Get after the value is read until resume is closed, once a token is sent to pause
+type pausedGetStore struct {
+	scom.PersistStore
+	pause  chan struct{}
+	read   chan struct{}
+	resume chan struct{}
+}
+
+func (this *pausedGetStore) Get(key []byte) ([]byte, error) {
+	value, err := this.PersistStore.Get(key)
+	select {
+	case <-this.pause:
+		close(this.read)
+		<-this.resume
+	default:
+	}
+	return value, err
+}
+
+func TestStateCacheConcurrentCommit(t *testing.T) {
+	db := NewMemStateStore(0)
+	cache, err := NewStateCache(10)
+	assert.Nil(t, err)
+	db.cache = cache
+	paused := &pausedGetStore{PersistStore: db.store, pause: make(chan struct{}, 1), read: make(chan struct{}),
+		resume: make(chan struct{})}
+	db.store = paused
+
+	key := &states.StorageKey{ContractAddress: common.ADDRESS_EMPTY, Key: []byte("key")}
+	storeKey, _ := db.getStorageKey(key)
+	putItem := func(value []byte) {
+		sink := common.NewZeroCopySink(nil)
+		item := &states.StorageItem{Value: value}
+		item.Serialization(sink)
+		db.NewBatch()
+		db.BatchPutRawKeyVal(storeKey, sink.Bytes())
+		assert.Nil(t, db.CommitTo())
+	}
+	putItem([]byte("v1"))
+
+	// the reader gets v1 from the store, then v2 is committed before the reader fills the cache
+	paused.pause <- struct{}{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		item, err := db.GetStorageState(key)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("v1"), item.Value)
+	}()
+	<-paused.read
+	putItem([]byte("v2"))
+	close(paused.resume)
+	wg.Wait()
+
+	item, err := db.GetStorageState(key)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v2"), item.Value)
+}
+
+func TestLayer2AccountStates(t *testing.T) {
+	db := NewMemStateStore(0)
+	ledgerStore := &LedgerStoreImp{stateStore: db}
+	contract1, contract2 := common.Address{1}, common.Address{2}
+	user1, user2 := common.Address{1}, common.Address{2}
+	key := func(contract, user common.Address) []byte {
+		return append(contract[:], user[:]...)
+	}
+	execute := func(height uint32, update func(cache *storage.CacheDB)) store.ExecuteResult {
+		overlay := db.NewOverlayDB()
+		cache := storage.NewCacheDB(overlay)
+		update(cache)
+		cache.Commit()
+		result := store.ExecuteResult{WriteSet: overlay.GetWriteSet()}
+		assert.Nil(t, ledgerStore.updateAccountTree(height, overlay, &result))
+		db.NewBatch()
+		assert.Nil(t, db.SaveLayer2States(height, result.UpdatedAccountState))
+		db.SaveLayer2AccountStates(height, result.UpdatedAccounts)
+		db.SaveStateTreeNodes(result.StateTreeNodes)
+		db.SaveAccountTreeRoot(height, result.UpdatedAccountStateRoot)
+		db.SaveStorageTreeRoot(height, result.StorageStateRoot)
+		result.WriteSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+		return result
+	}
+
+	result := execute(0, func(cache *storage.CacheDB) {
+		cache.Put(key(contract2, user1), []byte("c2u1"))
+		cache.Put(key(contract1, user2), []byte("c1u2"))
+		cache.Put(key(contract1, user1), []byte("c1u1"))
+		cache.Put(append(contract1[:], []byte("totalSupply")...), []byte("value"))
+	})
+	assert.Equal(t, []*store.AccountState{
+		{Contract: contract1, Address: user1, Value: []byte("c1u1")},
+		{Contract: contract1, Address: user2, Value: []byte("c1u2")},
+		{Contract: contract2, Address: user1, Value: []byte("c2u1")},
+	}, result.UpdatedAccounts)
+	assert.Equal(t, []common.Uint256{
+		merkle.SMTLeafHash(key(contract1, user1), []byte("c1u1")),
+		merkle.SMTLeafHash(key(contract1, user2), []byte("c1u2")),
+		merkle.SMTLeafHash(key(contract2, user1), []byte("c2u1")),
+	}, result.UpdatedAccountState)
+	saved, err := db.GetLayer2AccountStates(0)
+	assert.Nil(t, err)
+	assert.Equal(t, result.UpdatedAccounts, saved)
+	_, err = db.GetLayer2AccountStates(1)
+	assert.NotNil(t, err)
+
+	//the root depends on the account values only
+	tree := merkle.NewSparseMerkleTree(db, common.UINT256_EMPTY)
+	assert.Nil(t, tree.Update(key(contract1, user2), []byte("c1u2")))
+	assert.Nil(t, tree.Update(key(contract2, user1), []byte("c2u1")))
+	assert.Nil(t, tree.Update(key(contract1, user1), []byte("c1u1")))
+	assert.Equal(t, tree.Root(), result.UpdatedAccountStateRoot)
+
+	result = execute(1, func(cache *storage.CacheDB) {
+		cache.Put(key(contract1, user1), []byte("c1u1 v2"))
+		cache.Delete(key(contract2, user1))
+	})
+	assert.Equal(t, []common.Uint256{
+		merkle.SMTLeafHash(key(contract1, user1), []byte("c1u1 v2")),
+		common.UINT256_EMPTY,
+	}, result.UpdatedAccountState)
+	root, err := db.GetAccountTreeRoot(1)
+	assert.Nil(t, err)
+	assert.Equal(t, result.UpdatedAccountStateRoot, root)
+
+	for _, c := range []struct {
+		key   []byte
+		value []byte
+	}{
+		{key(contract1, user1), []byte("c1u1 v2")},
+		{key(contract1, user2), []byte("c1u2")},
+		{key(contract2, user1), nil},
+		{key(contract2, user2), nil},
+	} {
+		data, err := ledgerStore.GetLayer2StateProof(1, c.key)
+		assert.Nil(t, err)
+		proof := &merkle.SMTProof{}
+		assert.Nil(t, proof.Deserialization(common.NewZeroCopySource(data)))
+		assert.True(t, merkle.VerifySMTProof(root, c.key, c.value, proof))
+	}
+
+	//the storage state tree has all storage keys, including the ones not in account state tree
+	ledgerStore.currBlockHeight = 1
+	storageRoot, data, err := ledgerStore.GetStorageStateProof(1, contract1, []byte("totalSupply"))
+	assert.Nil(t, err)
+	assert.Equal(t, result.StorageStateRoot, storageRoot)
+	assert.NotEqual(t, root, storageRoot)
+	proof := &merkle.SMTProof{}
+	assert.Nil(t, proof.Deserialization(common.NewZeroCopySource(data)))
+	assert.True(t, merkle.VerifySMTProof(storageRoot, append(contract1[:], []byte("totalSupply")...), []byte("value"), proof))
+	assert.False(t, merkle.VerifySMTProof(root, append(contract1[:], []byte("totalSupply")...), []byte("value"), proof))
+	_, data, err = ledgerStore.GetStorageStateProof(1, contract1, user1[:])
+	assert.Nil(t, err)
+	assert.Nil(t, proof.Deserialization(common.NewZeroCopySource(data)))
+	assert.True(t, merkle.VerifySMTProof(storageRoot, key(contract1, user1), []byte("c1u1 v2"), proof))
+	_, _, err = ledgerStore.GetStorageStateProof(2, contract1, user1[:])
+	assert.NotNil(t, err)
+
+	//the snapshot replays the updated accounts to the accounts in tree at height
+	snapshot, err := ledgerStore.GetLayer2AccountSnapshot(1)
+	assert.Nil(t, err)
+	assert.Equal(t, root, snapshot.Root)
+	assert.Equal(t, [][]byte{key(contract1, user1), key(contract1, user2)}, snapshot.Keys)
+	assert.Equal(t, []*store.AccountState{
+		{Contract: contract1, Address: user1, Value: []byte("c1u1 v2")},
+		{Contract: contract1, Address: user2, Value: []byte("c1u2")},
+	}, snapshot.Accounts)
+	snapshot, err = ledgerStore.GetLayer2AccountSnapshot(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(snapshot.Accounts))
+	assert.Equal(t, []byte("c1u1"), snapshot.Accounts[0].Value)
+	_, err = ledgerStore.GetLayer2AccountSnapshot(2)
+	assert.NotNil(t, err)
+}
+
+func TestAccountTreeHeight(t *testing.T) {
+	db := NewMemStateStore(0)
+	ledgerStore := &LedgerStoreImp{stateStore: db, accountTreeHeight: 2}
+	contract1, contract2 := common.Address{1}, common.Address{2}
+	user1, user2 := common.Address{1}, common.Address{2}
+	key := func(contract, user common.Address) []byte {
+		return append(contract[:], user[:]...)
+	}
+	execute := func(height uint32, update func(cache *storage.CacheDB)) store.ExecuteResult {
+		overlay := db.NewOverlayDB()
+		cache := storage.NewCacheDB(overlay)
+		update(cache)
+		cache.Commit()
+		result := store.ExecuteResult{WriteSet: overlay.GetWriteSet()}
+		if height < ledgerStore.accountTreeHeight {
+			result.UpdatedAccountStateRoot, result.UpdatedAccountState, result.UpdatedAccounts = calculateChangeStateRoot(cache)
+		}
+		assert.Nil(t, ledgerStore.updateAccountTree(height, overlay, &result))
+		db.NewBatch()
+		assert.Nil(t, db.SaveLayer2States(height, result.UpdatedAccountState))
+		db.SaveLayer2AccountStates(height, result.UpdatedAccounts)
+		db.SaveStateTreeNodes(result.StateTreeNodes)
+		if height >= ledgerStore.accountTreeHeight {
+			db.SaveAccountTreeRoot(height, result.UpdatedAccountStateRoot)
+		}
+		db.SaveStorageTreeRoot(height, result.StorageStateRoot)
+		result.WriteSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+		ledgerStore.currBlockHeight = height
+		return result
+	}
+
+	//the blocks below the height have the legacy root of the values of updated accounts in address descending order
+	result := execute(0, func(cache *storage.CacheDB) {
+		cache.Put(key(contract1, user1), []byte("\x00c1u1"))
+		cache.Put(key(contract1, user2), []byte("c1u2"))
+	})
+	leaves := []common.Uint256{sha256.Sum256([]byte("c1u2")), sha256.Sum256([]byte("\x00c1u1"))}
+	assert.Equal(t, leaves, result.UpdatedAccountState)
+	assert.Equal(t, merkle.TreeHasher{}.HashFullTreeWithLeafHash(leaves), result.UpdatedAccountStateRoot)
+	assert.Equal(t, []*store.AccountState{
+		{Address: user2, Value: []byte("c1u2")},
+		{Address: user1, Value: []byte("\x00c1u1")},
+	}, result.UpdatedAccounts)
+	_, err := db.GetAccountTreeRoot(0)
+	assert.Equal(t, scom.ErrNotFound, err)
+	root, err := ledgerStore.accountStateRoot(0)
+	assert.Nil(t, err)
+	assert.Equal(t, result.UpdatedAccountStateRoot, root)
+	states, err := ledgerStore.GetLayer2AccountStates(0)
+	assert.Nil(t, err)
+	assert.Equal(t, root, states.Root)
+
+	//the legacy proof is the merkle path of the leaf data
+	proof, err := ledgerStore.GetLayer2StateProof(0, []byte("c1u1"))
+	assert.Nil(t, err)
+	data, err := merkle.MerkleProve(proof, root)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("c1u1"), data)
+
+	result = execute(1, func(cache *storage.CacheDB) {
+		cache.Put(key(contract2, user1), []byte("c2u1"))
+	})
+	assert.Equal(t, []common.Uint256{sha256.Sum256([]byte("c2u1"))}, result.UpdatedAccountState)
+	_, err = ledgerStore.GetLayer2AccountSnapshot(1)
+	assert.NotNil(t, err)
+
+	//the block at the height builds the tree of all the accounts
+	result = execute(2, func(cache *storage.CacheDB) {
+		cache.Put(key(contract1, user1), []byte("c1u1 v2"))
+	})
+	tree := merkle.NewSparseMerkleTree(db, common.UINT256_EMPTY)
+	assert.Nil(t, tree.Update(key(contract1, user1), []byte("c1u1 v2")))
+	assert.Nil(t, tree.Update(key(contract1, user2), []byte("c1u2")))
+	assert.Nil(t, tree.Update(key(contract2, user1), []byte("c2u1")))
+	assert.Equal(t, tree.Root(), result.UpdatedAccountStateRoot)
+	assert.Equal(t, []*store.AccountState{
+		{Contract: contract1, Address: user1, Value: []byte("c1u1 v2")},
+		{Contract: contract1, Address: user2, Value: []byte("c1u2")},
+		{Contract: contract2, Address: user1, Value: []byte("c2u1")},
+	}, result.UpdatedAccounts)
+
+	result = execute(3, func(cache *storage.CacheDB) {
+		cache.Delete(key(contract1, user2))
+	})
+	assert.Equal(t, []common.Uint256{common.UINT256_EMPTY}, result.UpdatedAccountState)
+	root, err = ledgerStore.accountStateRoot(3)
+	assert.Nil(t, err)
+	assert.Equal(t, result.UpdatedAccountStateRoot, root)
+	data, err = ledgerStore.GetLayer2StateProof(3, key(contract2, user1))
+	assert.Nil(t, err)
+	smtProof := &merkle.SMTProof{}
+	assert.Nil(t, smtProof.Deserialization(common.NewZeroCopySource(data)))
+	assert.True(t, merkle.VerifySMTProof(root, key(contract2, user1), []byte("c2u1"), smtProof))
+
+	snapshot, err := ledgerStore.GetLayer2AccountSnapshot(3)
+	assert.Nil(t, err)
+	assert.Equal(t, root, snapshot.Root)
+	assert.Equal(t, [][]byte{key(contract1, user1), key(contract2, user1)}, snapshot.Keys)
+}
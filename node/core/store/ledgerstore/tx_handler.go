@@ -43,8 +43,7 @@ import (
 )
 
 func tuneGasFeeByHeight(height uint32, gas uint64, gasRound uint64, curBalance uint64) uint64 {
-	gasTuneheight := sysconfig.GetGasRoundTuneHeight(sysconfig.NETWORK_ID_SOLO_NET)
-	if height > gasTuneheight {
+	if sysconfig.IsFeatureActive(sysconfig.FEATURE_GAS_ROUND_TUNE, height) {
 		t := (gas + gasRound - 1) / gasRound
 		if gas > math.MaxUint64-gasRound {
 			return curBalance
@@ -0,0 +1,230 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/common/log"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+//VerifyResult is the result of chain verification, DivergedHeight and Reason are set only if Diverged
+type VerifyResult struct {
+	StartHeight    uint32
+	EndHeight      uint32
+	Diverged       bool
+	DivergedHeight uint32
+	Reason         string
+}
+
+func (this *VerifyResult) diverge(height uint32, format string, args ...interface{}) *VerifyResult {
+	this.Diverged = true
+	this.DivergedHeight = height
+	this.Reason = fmt.Sprintf(format, args...)
+	return this
+}
+
+//ChainVerifier re-execute the blocks of a source data dir by a replay ledger, and compare the recomputed state
+//merkle roots, updated account states and block roots against the values stored in source
+type ChainVerifier struct {
+	blockStore *BlockStore
+	stateStore *StateStore
+	ledger     *LedgerStoreImp
+}
+
+//NewChainVerifier open the block store and state store in sourceDir to verify by the replay ledger
+func NewChainVerifier(sourceDir string, ledger *LedgerStoreImp) (*ChainVerifier, error) {
+	blockStore, err := openBackupBlockStore(filepath.Join(sourceDir, DBDirBlock))
+	if err != nil {
+		return nil, err
+	}
+	hashCheckHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
+	stateStore, err := NewStateStore(filepath.Join(sourceDir, DBDirState), filepath.Join(sourceDir, MerkleTreeStorePath),
+		hashCheckHeight)
+	if err != nil {
+		blockStore.Close()
+		return nil, fmt.Errorf("open source state store error %s", err)
+	}
+	return &ChainVerifier{
+		blockStore: blockStore,
+		stateStore: stateStore,
+		ledger:     ledger,
+	}, nil
+}
+
+//Verify replay the blocks after the current height of replay ledger to endHeight, or to the last executed block of
+//source if endHeight is 0, and stop at the first divergent height
+func (this *ChainVerifier) Verify(endHeight uint32) (*VerifyResult, error) {
+	_, sourceHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("source state store GetCurrentBlock error %s", err)
+	}
+	if endHeight == 0 || endHeight > sourceHeight {
+		endHeight = sourceHeight
+	}
+	startHeight := this.ledger.GetCurrentBlockHeight()
+	result := &VerifyResult{StartHeight: startHeight, EndHeight: startHeight}
+
+	sourceHash, err := this.blockStore.GetBlockHash(startHeight)
+	if err != nil {
+		return nil, fmt.Errorf("source GetBlockHash height:%d error %s", startHeight, err)
+	}
+	if currHash := this.ledger.GetCurrentBlockHash(); currHash != sourceHash {
+		return result.diverge(startHeight, "block hash %s, expected %s", sourceHash.ToHexString(), currHash.ToHexString()), nil
+	}
+
+	start := time.Now()
+	lastLog := start
+	for height := startHeight + 1; height <= endHeight; height++ {
+		block, err := loadBlockByHeight(this.blockStore, height)
+		if err != nil {
+			return nil, err
+		}
+		blockRoot := this.ledger.GetBlockRootWithNewTxRoots(height, []common.Uint256{block.Header.TransactionsRoot})
+		if blockRoot != block.Header.BlockRoot {
+			return result.diverge(height, "block root %s, expected %s", block.Header.BlockRoot.ToHexString(),
+				blockRoot.ToHexString()), nil
+		}
+		execResult, err := this.ledger.ExecuteBlock(block)
+		if err != nil {
+			return result.diverge(height, "execute block error %s", err), nil
+		}
+		stateRoot, err := this.stateStore.GetStateMerkleRoot(height)
+		if err != nil && err != scom.ErrNotFound {
+			return nil, fmt.Errorf("source GetStateMerkleRoot height:%d error %s", height, err)
+		}
+		if stateRoot != execResult.MerkleRoot {
+			return result.diverge(height, "state merkle root %s, expected %s", stateRoot.ToHexString(),
+				execResult.MerkleRoot.ToHexString()), nil
+		}
+		//the blocks below the account tree height have the legacy root of the updated account states
+		if height < this.ledger.accountTreeHeight {
+			accountStates, err := this.stateStore.GetLayer2States(height)
+			if err != nil && err != scom.ErrNotFound {
+				return nil, fmt.Errorf("source GetLayer2States height:%d error %s", height, err)
+			}
+			if !sameStateLeaves(accountStates, execResult.UpdatedAccountState) {
+				accountRoot := legacyAccountStateRoot(accountStates)
+				return result.diverge(height, "updated account state root %s, expected %s", accountRoot.ToHexString(),
+					execResult.UpdatedAccountStateRoot.ToHexString()), nil
+			}
+		} else {
+			accountRoot, err := this.stateStore.GetAccountTreeRoot(height)
+			if err == scom.ErrNotFound {
+				return result.diverge(height, "account state root not found, expected %s",
+					execResult.UpdatedAccountStateRoot.ToHexString()), nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("source GetAccountTreeRoot height:%d error %s", height, err)
+			}
+			if accountRoot != execResult.UpdatedAccountStateRoot {
+				return result.diverge(height, "account state root %s, expected %s", accountRoot.ToHexString(),
+					execResult.UpdatedAccountStateRoot.ToHexString()), nil
+			}
+		}
+		err = this.ledger.SubmitBlock(block, nil, execResult)
+		if err != nil {
+			return result.diverge(height, "submit block error %s", err), nil
+		}
+		result.EndHeight = height
+
+		if now := time.Now(); now.Sub(lastLog) >= RECOVER_LOG_INTERVAL {
+			lastLog = now
+			log.Infof("verify chain height %d/%d", height, endHeight)
+		}
+	}
+	log.Infof("verify chain from height %d to %d cost %s", startHeight, result.EndHeight, time.Since(start))
+	return result, nil
+}
+
+//Close the source stores
+func (this *ChainVerifier) Close() {
+	this.blockStore.Close()
+	this.stateStore.Close()
+}
+
+//VerifyChain replay the chain in sourceDir from genesis, or from the backup in checkpointDir, in a new ledger in workDir
+func VerifyChain(sourceDir, workDir, checkpointDir string, genesisBlock *types.Block, bookkeepers []keypair.PublicKey,
+	endHeight uint32) (*VerifyResult, error) {
+	if checkpointDir != "" {
+		err := RestoreBackup(checkpointDir, workDir)
+		if err != nil {
+			return nil, fmt.Errorf("restore checkpoint error %s", err)
+		}
+	}
+	hashCheckHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
+	ledger, err := NewLedgerStore(workDir, hashCheckHeight)
+	if err != nil {
+		return nil, err
+	}
+	defer ledger.Close()
+	err = ledger.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := NewChainVerifier(sourceDir, ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer verifier.Close()
+	return verifier.Verify(endHeight)
+}
+
+func loadBlockByHeight(blockStore *BlockStore, height uint32) (*types.Block, error) {
+	blockHash, err := blockStore.GetBlockHash(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockHash height:%d error %s", height, err)
+	}
+	block, err := blockStore.GetBlock(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlock height:%d error %s", height, err)
+	}
+	return block, nil
+}
+
+//sameStateLeaves compare the account state leaves regardless of order, the leaves of blocks saved by old version
+//with several accounts are not in a stable order
+func sameStateLeaves(a, b []common.Uint256) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortLeaves := func(leaves []common.Uint256) []common.Uint256 {
+		sorted := append([]common.Uint256{}, leaves...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+		})
+		return sorted
+	}
+	sa, sb := sortLeaves(a), sortLeaves(b)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
+}
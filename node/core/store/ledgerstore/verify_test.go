@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameStateLeaves(t *testing.T) {
+	a := []common.Uint256{{1}, {2}, {3}}
+	assert.True(t, sameStateLeaves(a, []common.Uint256{{3}, {1}, {2}}))
+	assert.True(t, sameStateLeaves(nil, []common.Uint256{}))
+	assert.False(t, sameStateLeaves(a, []common.Uint256{{1}, {2}}))
+	assert.False(t, sameStateLeaves(a, []common.Uint256{{1}, {2}, {4}}))
+	assert.Equal(t, []common.Uint256{{1}, {2}, {3}}, a)
+}
+
+func TestChainVerifierStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "source")
+	closeTestBackupLedger(newTestBackupLedger(t, sourceDir, []common.Uint256{{1}, {2}}))
+
+	replay := &LedgerStoreImp{currBlockHeight: 1, currBlockHash: common.Uint256{2}}
+	verifier, err := NewChainVerifier(sourceDir, replay)
+	assert.Nil(t, err)
+	defer verifier.Close()
+	result, err := verifier.Verify(0)
+	assert.Nil(t, err)
+	assert.False(t, result.Diverged)
+	assert.Equal(t, uint32(1), result.EndHeight)
+
+	replay.currBlockHash = common.Uint256{3}
+	result, err = verifier.Verify(0)
+	assert.Nil(t, err)
+	assert.True(t, result.Diverged)
+	assert.Equal(t, uint32(1), result.DivergedHeight)
+}
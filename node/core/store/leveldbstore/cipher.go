@@ -0,0 +1,305 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package leveldbstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+
+	"github.com/ontio/layer2/node/core/store/common"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+//ENCRYPTED_VALUE_MAGIC mark the value encrypted by AES-GCM, followed by the big endian key id, the nonce and the sealed
+//value. The values without magic are plaintext written before encryption enabled
+var ENCRYPTED_VALUE_MAGIC = []byte{'A', 'E', 'S', 0xff}
+
+const encryptedHeaderLen = 4 + 4 //magic + key id
+
+//ENCRYPTION_STATE_KEY is the metadata of store recorded once all values are encrypted, the value is the active key id
+var ENCRYPTION_STATE_KEY = []byte{byte(common.SYS_ENCRYPTION_STATE)}
+
+//ValueCipher encrypt the values of leveldb by AES-GCM with the active key, the leveldb key is the additional data so
+//a value can not be moved to other key. The retired keys are kept to decrypt the values not re-encrypted yet
+type ValueCipher struct {
+	activeId uint32
+	aeads    map[uint32]cipher.AEAD
+}
+
+//NewValueCipher return the cipher of AES keys by id, the key should be 16, 24 or 32 bytes
+func NewValueCipher(keys map[uint32][]byte, activeId uint32) (*ValueCipher, error) {
+	if _, ok := keys[activeId]; !ok {
+		return nil, fmt.Errorf("active key %d is not found", activeId)
+	}
+	aeads := make(map[uint32]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d error %s", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %d error %s", id, err)
+		}
+		aeads[id] = aead
+	}
+	return &ValueCipher{activeId: activeId, aeads: aeads}, nil
+}
+
+type encryptKeyFile struct {
+	ActiveKey uint32
+	Keys      []*encryptKeyEntry
+}
+
+//encryptKeyEntry is the hex Key, or the name of environment variable holding the hex key set by the key management
+//service
+type encryptKeyEntry struct {
+	Id  uint32
+	Key string `json:",omitempty"`
+	Env string `json:",omitempty"`
+}
+
+//LoadValueCipher read the cipher from the json key file
+func LoadValueCipher(file string) (*ValueCipher, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read key file %s error %s", file, err)
+	}
+	keyFile := &encryptKeyFile{}
+	err = json.Unmarshal(data, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal key file %s error %s", file, err)
+	}
+	keys := make(map[uint32][]byte, len(keyFile.Keys))
+	for _, entry := range keyFile.Keys {
+		if _, ok := keys[entry.Id]; ok {
+			return nil, fmt.Errorf("key %d is duplicated", entry.Id)
+		}
+		str := entry.Key
+		if entry.Env != "" {
+			str = os.Getenv(entry.Env)
+		}
+		key, err := hex.DecodeString(str)
+		if err != nil || len(key) == 0 {
+			return nil, fmt.Errorf("key %d is not hex", entry.Id)
+		}
+		keys[entry.Id] = key
+	}
+	return NewValueCipher(keys, keyFile.ActiveKey)
+}
+
+//ActiveKeyId return the id of key used to encrypt
+func (this *ValueCipher) ActiveKeyId() uint32 {
+	return this.activeId
+}
+
+//Encrypt return the value encrypted by the active key
+func (this *ValueCipher) Encrypt(key, value []byte) []byte {
+	aead := this.aeads[this.activeId]
+	buf := make([]byte, encryptedHeaderLen+aead.NonceSize(), encryptedHeaderLen+aead.NonceSize()+len(value)+aead.Overhead())
+	copy(buf, ENCRYPTED_VALUE_MAGIC)
+	binary.BigEndian.PutUint32(buf[len(ENCRYPTED_VALUE_MAGIC):], this.activeId)
+	nonce := buf[encryptedHeaderLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Errorf("read random nonce error %s", err))
+	}
+	return aead.Seal(buf, nonce, value, key)
+}
+
+//Decrypt return the plaintext of value, value without magic is returned as it is. The store rejects the values without
+//magic once all values are re-encrypted
+func (this *ValueCipher) Decrypt(key, value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, ENCRYPTED_VALUE_MAGIC) {
+		return value, nil
+	}
+	if len(value) < encryptedHeaderLen {
+		return nil, fmt.Errorf("encrypted value is too short")
+	}
+	id := binary.BigEndian.Uint32(value[len(ENCRYPTED_VALUE_MAGIC):])
+	aead, ok := this.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("value encrypted by unknown key %d", id)
+	}
+	if len(value) < encryptedHeaderLen+aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is too short")
+	}
+	nonce := value[encryptedHeaderLen : encryptedHeaderLen+aead.NonceSize()]
+	data, err := aead.Open(nil, nonce, value[encryptedHeaderLen+aead.NonceSize():], key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt value by key %d error %s", id, err)
+	}
+	return data, nil
+}
+
+//IsActive return whether the value is encrypted by the active key
+func (this *ValueCipher) IsActive(value []byte) bool {
+	return len(value) >= encryptedHeaderLen && bytes.HasPrefix(value, ENCRYPTED_VALUE_MAGIC) &&
+		binary.BigEndian.Uint32(value[len(ENCRYPTED_VALUE_MAGIC):]) == this.activeId
+}
+
+//MarkEncrypted record in the metadata of store that all values are encrypted, which is called after the values are
+//re-encrypted from the first key. The plaintext values read after it are rejected as corrupted or injected
+func (self *LevelDBStore) MarkEncrypted() error {
+	if self.cipher == nil {
+		return fmt.Errorf("store is not encrypted")
+	}
+	if self.view != nil {
+		return fmt.Errorf("store is read-only")
+	}
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, self.cipher.ActiveKeyId())
+	if err := self.Put(ENCRYPTION_STATE_KEY, value); err != nil {
+		return err
+	}
+	self.setEncrypted(true)
+	return nil
+}
+
+//IsEncrypted return whether all values of store are recorded as encrypted
+func (self *LevelDBStore) IsEncrypted() bool {
+	return self.isEncrypted()
+}
+
+func (self *LevelDBStore) isEncrypted() bool {
+	return atomic.LoadUint32(&self.encrypted) == 1
+}
+
+func (self *LevelDBStore) setEncrypted(encrypted bool) {
+	value := uint32(0)
+	if encrypted {
+		value = 1
+	}
+	atomic.StoreUint32(&self.encrypted, value)
+}
+
+//decrypt return the plaintext of value, the plaintext value is rejected if the store is encrypted
+func (self *LevelDBStore) decrypt(key, value []byte) ([]byte, error) {
+	return decryptValue(self.cipher, key, value, self.isEncrypted())
+}
+
+func decryptValue(cipher *ValueCipher, key, value []byte, encrypted bool) ([]byte, error) {
+	if encrypted && !bytes.HasPrefix(value, ENCRYPTED_VALUE_MAGIC) {
+		return nil, fmt.Errorf("value of key %x is not encrypted in the encrypted store", key)
+	}
+	return cipher.Decrypt(key, value)
+}
+
+//ReEncrypt rewrite the values from key start which are plaintext or encrypted by retired keys with the active key. At
+//most limit values are scanned in one call under the write lock, so it does not overwrite the concurrent writes. It
+//returns the key to continue, nil if all values are scanned, and the count of values rewritten
+func (self *LevelDBStore) ReEncrypt(start []byte, limit int) ([]byte, int, error) {
+	if self.cipher == nil {
+		return nil, 0, fmt.Errorf("store is not encrypted")
+	}
+	if self.view != nil {
+		return nil, 0, fmt.Errorf("store is read-only")
+	}
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+	iter := self.db.NewIterator(&util.Range{Start: start}, nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	var next []byte
+	for scanned := 0; iter.Next(); scanned++ {
+		if scanned == limit {
+			next = append([]byte{}, iter.Key()...)
+			break
+		}
+		if self.cipher.IsActive(iter.Value()) {
+			continue
+		}
+		value, err := self.decrypt(iter.Key(), iter.Value())
+		if err != nil {
+			return nil, 0, fmt.Errorf("key %x error %s", iter.Key(), err)
+		}
+		key := append([]byte{}, iter.Key()...)
+		batch.Put(key, self.cipher.Encrypt(key, value))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, 0, err
+	}
+	if batch.Len() > 0 {
+		if err := self.db.Write(batch, nil); err != nil {
+			return nil, 0, err
+		}
+	}
+	return next, batch.Len(), nil
+}
+
+//decryptIterator return the plaintext values of iterator, a value failed to decrypt stops the iteration with error
+type decryptIterator struct {
+	common.StoreIterator
+	cipher    *ValueCipher
+	encrypted bool
+	value     []byte
+	err       error
+}
+
+func (this *decryptIterator) decrypt(ok bool) bool {
+	if !ok {
+		this.value = nil
+		return false
+	}
+	this.value, this.err = decryptValue(this.cipher, this.StoreIterator.Key(), this.StoreIterator.Value(), this.encrypted)
+	return this.err == nil
+}
+
+func (this *decryptIterator) Next() bool {
+	if this.err != nil {
+		return false
+	}
+	return this.decrypt(this.StoreIterator.Next())
+}
+
+func (this *decryptIterator) First() bool {
+	if this.err != nil {
+		return false
+	}
+	return this.decrypt(this.StoreIterator.First())
+}
+
+func (this *decryptIterator) Value() []byte {
+	return this.value
+}
+
+func (this *decryptIterator) Error() error {
+	if this.err != nil {
+		return this.err
+	}
+	return this.StoreIterator.Error()
+}
+
+//decryptIter wrap the iterator to decrypt values, iterator is returned as it is if cipher is nil. The plaintext values
+//stop the iteration with error if the store is encrypted
+func decryptIter(iter common.StoreIterator, cipher *ValueCipher, encrypted bool) common.StoreIterator {
+	if cipher == nil {
+		return iter
+	}
+	return &decryptIterator{StoreIterator: iter, cipher: cipher, encrypted: encrypted}
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package leveldbstore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueCipher(t *testing.T) {
+	key1, key2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16)
+	store, err := NewMemLevelDBStore()
+	assert.Nil(t, err)
+	defer store.Close()
+
+	//values written before encryption enabled are read as plaintext
+	store.NewBatch()
+	for i := 0; i < 5; i++ {
+		store.BatchPut([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+	assert.Nil(t, store.BatchCommit())
+	cipher1, err := NewValueCipher(map[uint32][]byte{1: key1}, 1)
+	assert.Nil(t, err)
+	store.SetCipher(cipher1)
+	assert.Nil(t, store.Put([]byte("key5"), []byte("value5")))
+	raw, err := store.db.Get([]byte("key5"), nil)
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(raw, ENCRYPTED_VALUE_MAGIC))
+	assert.False(t, bytes.Contains(raw, []byte("value5")))
+
+	checkValues := func() {
+		for i := 0; i < 6; i++ {
+			value, err := store.Get([]byte(fmt.Sprintf("key%d", i)))
+			assert.Nil(t, err)
+			assert.Equal(t, []byte(fmt.Sprintf("value%d", i)), value)
+		}
+		iter := store.NewIterator([]byte("key"))
+		count := 0
+		for iter.Next() {
+			assert.Equal(t, []byte(fmt.Sprintf("value%d", count)), iter.Value())
+			count++
+		}
+		iter.Release()
+		assert.Nil(t, iter.Error())
+		assert.Equal(t, 6, count)
+	}
+	checkValues()
+	snapshot, err := store.NewSnapshot()
+	assert.Nil(t, err)
+	iter := snapshot.NewIterator([]byte("key5"))
+	assert.True(t, iter.Next())
+	assert.Equal(t, []byte("value5"), iter.Value())
+	iter.Release()
+	snapshot.Release()
+
+	reEncrypt := func() int {
+		var next []byte
+		total := 0
+		for {
+			var count int
+			next, count, err = store.ReEncrypt(next, 2)
+			assert.Nil(t, err)
+			total += count
+			if next == nil {
+				return total
+			}
+		}
+	}
+	assert.Equal(t, 5, reEncrypt())
+	assert.Equal(t, 0, reEncrypt())
+	checkValues()
+
+	//rotate to key 2, the values of key 1 are readable until re-encrypted
+	cipher2, err := NewValueCipher(map[uint32][]byte{1: key1, 2: key2}, 2)
+	assert.Nil(t, err)
+	store.SetCipher(cipher2)
+	checkValues()
+	assert.Equal(t, 6, reEncrypt())
+	store.SetCipher(&ValueCipher{activeId: 2, aeads: map[uint32]cipher.AEAD{2: cipher2.aeads[2]}})
+	checkValues()
+
+	//the value can not be moved to other key
+	raw, err = store.db.Get([]byte("key1"), nil)
+	assert.Nil(t, err)
+	assert.Nil(t, store.db.Put([]byte("key2"), raw, nil))
+	_, err = store.Get([]byte("key2"))
+	assert.NotNil(t, err)
+	iter = store.NewIterator([]byte("key"))
+	for iter.Next() {
+	}
+	assert.NotNil(t, iter.Error())
+	iter.Release()
+
+	_, err = NewValueCipher(map[uint32][]byte{1: key1}, 2)
+	assert.NotNil(t, err)
+	_, err = NewValueCipher(map[uint32][]byte{1: key1[:10]}, 1)
+	assert.NotNil(t, err)
+}
+
+func TestMarkEncrypted(t *testing.T) {
+	store, err := NewMemLevelDBStore()
+	assert.Nil(t, err)
+	defer store.Close()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, store.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+	cipher1, err := NewValueCipher(map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}, 1)
+	assert.Nil(t, err)
+	store.SetCipher(cipher1)
+	assert.False(t, store.IsEncrypted())
+	next, count, err := store.ReEncrypt(nil, 10)
+	assert.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, 3, count)
+	assert.Nil(t, store.MarkEncrypted())
+	assert.True(t, store.IsEncrypted())
+	value, err := store.Get([]byte("key1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	//the plaintext value is rejected by get, iterators and re-encryption
+	assert.Nil(t, store.db.Put([]byte("key3"), []byte("value3"), nil))
+	_, err = store.Get([]byte("key3"))
+	assert.NotNil(t, err)
+	iter := store.NewIterator([]byte("key"))
+	for iter.Next() {
+	}
+	assert.NotNil(t, iter.Error())
+	iter.Release()
+	snapshot, err := store.NewSnapshot()
+	assert.Nil(t, err)
+	iter = snapshot.NewIterator([]byte("key3"))
+	assert.False(t, iter.Next())
+	assert.NotNil(t, iter.Error())
+	iter.Release()
+	snapshot.Release()
+	_, _, err = store.ReEncrypt(nil, 10)
+	assert.NotNil(t, err)
+
+	//the record is kept when the store is opened again, and removed when encryption is disabled
+	store.SetCipher(cipher1)
+	assert.True(t, store.IsEncrypted())
+	store.SetCipher(nil)
+	has, err := store.db.Has(ENCRYPTION_STATE_KEY, nil)
+	assert.Nil(t, err)
+	assert.False(t, has)
+	store.SetCipher(cipher1)
+	assert.False(t, store.IsEncrypted())
+	value, err = store.Get([]byte("key3"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value3"), value)
+
+	store.SetCipher(nil)
+	assert.NotNil(t, store.MarkEncrypted())
+}
+
+func TestLoadValueCipher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cipher")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys.json")
+	os.Setenv("TEST_STORE_KEY", fmt.Sprintf("%x", bytes.Repeat([]byte{2}, 32)))
+	defer os.Unsetenv("TEST_STORE_KEY")
+	data := fmt.Sprintf(`{"ActiveKey":2,"Keys":[{"Id":1,"Key":"%x"},{"Id":2,"Env":"TEST_STORE_KEY"}]}`,
+		bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, ioutil.WriteFile(file, []byte(data), 0600))
+	cipher, err := LoadValueCipher(file)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), cipher.ActiveKeyId())
+	assert.Equal(t, 2, len(cipher.aeads))
+
+	os.Unsetenv("TEST_STORE_KEY")
+	_, err = LoadValueCipher(file)
+	assert.NotNil(t, err)
+}
@@ -19,6 +19,8 @@
 package leveldbstore
 
 import (
+	"sync"
+
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ontio/layer2/node/core/store/common"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -31,8 +33,13 @@ import (
 
 //LevelDB store
 type LevelDBStore struct {
-	db    *leveldb.DB // LevelDB instance
-	batch *leveldb.Batch
+	db        *leveldb.DB // LevelDB instance
+	batch     *leveldb.Batch
+	view      *levelDBView // View of leveldb written by other process, nil if the store is writable
+	lock      sync.RWMutex // Lock of db swapped by refreshing view
+	cipher    *ValueCipher // Encrypt the values written, nil if encryption is disabled
+	encrypted uint32       // Set if all values are encrypted, the plaintext values are rejected
+	writeLock sync.Mutex   // Serialize the writes with re-encryption
 }
 
 // used to compute the size of bloom filter bits array .
@@ -92,31 +99,70 @@ func NewMemLevelDBStore() (*LevelDBStore, error) {
 	}, nil
 }
 
+//getDb return the leveldb instance, which is swapped by Refresh if the store is read-only
+func (self *LevelDBStore) getDb() *leveldb.DB {
+	if self.view == nil {
+		return self.db
+	}
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.db
+}
+
+//SetCipher encrypt the values written and decrypt the values read by cipher, it should be set before the store is used.
+//The plaintext values are rejected if the store is recorded as encrypted, and the record is removed if encryption is
+//disabled, as the values are written in plaintext then
+func (self *LevelDBStore) SetCipher(cipher *ValueCipher) {
+	self.cipher = cipher
+	encrypted, _ := self.getDb().Has(ENCRYPTION_STATE_KEY, nil)
+	if encrypted && cipher == nil && self.view == nil {
+		self.writeLock.Lock()
+		self.getDb().Delete(ENCRYPTION_STATE_KEY, nil)
+		self.writeLock.Unlock()
+	}
+	self.setEncrypted(encrypted && cipher != nil)
+}
+
+//Cipher return the cipher of values, nil if encryption is disabled
+func (self *LevelDBStore) Cipher() *ValueCipher {
+	return self.cipher
+}
+
 //Put a key-value pair to leveldb
 func (self *LevelDBStore) Put(key []byte, value []byte) error {
-	return self.db.Put(key, value, nil)
+	if self.cipher != nil {
+		value = self.cipher.Encrypt(key, value)
+	}
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+	return self.getDb().Put(key, value, nil)
 }
 
 //Get the value of a key from leveldb
 func (self *LevelDBStore) Get(key []byte) ([]byte, error) {
-	dat, err := self.db.Get(key, nil)
+	dat, err := self.getDb().Get(key, nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
 			return nil, common.ErrNotFound
 		}
 		return nil, err
 	}
+	if self.cipher != nil {
+		return self.decrypt(key, dat)
+	}
 	return dat, nil
 }
 
 //Has return whether the key is exist in leveldb
 func (self *LevelDBStore) Has(key []byte) (bool, error) {
-	return self.db.Has(key, nil)
+	return self.getDb().Has(key, nil)
 }
 
 //Delete the the in leveldb
 func (self *LevelDBStore) Delete(key []byte) error {
-	return self.db.Delete(key, nil)
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+	return self.getDb().Delete(key, nil)
 }
 
 //NewBatch start commit batch
@@ -126,6 +172,9 @@ func (self *LevelDBStore) NewBatch() {
 
 //BatchPut put a key-value pair to leveldb batch
 func (self *LevelDBStore) BatchPut(key []byte, value []byte) {
+	if self.cipher != nil {
+		value = self.cipher.Encrypt(key, value)
+	}
 	self.batch.Put(key, value)
 }
 
@@ -136,7 +185,9 @@ func (self *LevelDBStore) BatchDelete(key []byte) {
 
 //BatchCommit commit batch to leveldb
 func (self *LevelDBStore) BatchCommit() error {
-	err := self.db.Write(self.batch, nil)
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+	err := self.getDb().Write(self.batch, nil)
 	if err != nil {
 		return err
 	}
@@ -146,14 +197,53 @@ func (self *LevelDBStore) BatchCommit() error {
 
 //Close leveldb
 func (self *LevelDBStore) Close() error {
+	if self.view != nil {
+		return self.closeView()
+	}
 	err := self.db.Close()
 	return err
 }
 
+//Compact the whole key range of leveldb
+func (self *LevelDBStore) Compact() error {
+	return self.getDb().CompactRange(util.Range{})
+}
+
+//LevelDBSnapshot is a read-only snapshot of leveldb
+type LevelDBSnapshot struct {
+	snapshot  *leveldb.Snapshot
+	cipher    *ValueCipher
+	encrypted bool
+}
+
+//NewSnapshot return the snapshot of current leveldb, the snapshot should be released after use
+func (self *LevelDBStore) NewSnapshot() (common.StoreSnapshot, error) {
+	snapshot, err := self.getDb().GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBSnapshot{snapshot: snapshot, cipher: self.cipher, encrypted: self.isEncrypted()}, nil
+}
+
+//NewIterator return a iterator of snapshot with the key prefix
+func (self *LevelDBSnapshot) NewIterator(prefix []byte) common.StoreIterator {
+	return decryptIter(self.snapshot.NewIterator(util.BytesPrefix(prefix), nil), self.cipher, self.encrypted)
+}
+
+//Release snapshot
+func (self *LevelDBSnapshot) Release() {
+	self.snapshot.Release()
+}
+
 //NewIterator return a iterator of leveldb with the key prefix
 func (self *LevelDBStore) NewIterator(prefix []byte) common.StoreIterator {
 
-	iter := self.db.NewIterator(util.BytesPrefix(prefix), nil)
+	iter := self.getDb().NewIterator(util.BytesPrefix(prefix), nil)
+
+	return decryptIter(iter, self.cipher, self.isEncrypted())
+}
 
-	return iter
+//NewRangeIterator return a iterator of leveldb with the key in [start, limit)
+func (self *LevelDBStore) NewRangeIterator(start, limit []byte) common.StoreIterator {
+	return decryptIter(self.getDb().NewIterator(&util.Range{Start: start, Limit: limit}, nil), self.cipher, self.isEncrypted())
 }
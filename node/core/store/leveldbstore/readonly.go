@@ -0,0 +1,222 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package leveldbstore
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+//READ_ONLY_VIEW_RETRIES is the times of copying the view of leveldb which is changed during the copy
+const READ_ONLY_VIEW_RETRIES = 5
+
+//levelDBView is a point-in-time copy of leveldb written by other process
+type levelDBView struct {
+	file       string      //Dir of the leveldb
+	root       string      //Dir where the view dirs are created, system temp dir if empty
+	dir        string      //Dir of current view
+	retired    *leveldb.DB //Previous view kept until next refresh for the iterators and snapshots on it
+	retiredDir string
+}
+
+//NewReadOnlyLevelDBStore return a read-only LevelDBStore of the leveldb in file, which may be opened and written by
+//other process at the same time. The store reads a point-in-time view of the leveldb created in a temporary dir under
+//viewRoot, and Refresh update the view. The table files are hard linked to the view as leveldb never modifies them,
+//or copied if viewRoot is on other file system, and the manifest and journal files are copied
+func NewReadOnlyLevelDBStore(file, viewRoot string) (*LevelDBStore, error) {
+	view := &levelDBView{file: file, root: viewRoot}
+	db, dir, err := view.open()
+	if err != nil {
+		return nil, err
+	}
+	view.dir = dir
+	return &LevelDBStore{
+		db:   db,
+		view: view,
+	}, nil
+}
+
+//Refresh update the read-only store to the latest view of leveldb. The previous view is closed at next refresh, so the
+//iterators and snapshots should be released before that
+func (self *LevelDBStore) Refresh() error {
+	if self.view == nil {
+		return fmt.Errorf("leveldb store is not read-only")
+	}
+	db, dir, err := self.view.open()
+	if err != nil {
+		return err
+	}
+	self.lock.Lock()
+	retired, retiredDir := self.view.retired, self.view.retiredDir
+	self.view.retired, self.view.retiredDir = self.db, self.view.dir
+	self.db, self.view.dir = db, dir
+	self.lock.Unlock()
+	closeLevelDBView(retired, retiredDir)
+	//the writer may have finished re-encryption since the last view
+	if self.cipher != nil {
+		encrypted, _ := db.Has(ENCRYPTION_STATE_KEY, nil)
+		self.setEncrypted(encrypted)
+	}
+	return nil
+}
+
+func (self *LevelDBStore) closeView() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	closeLevelDBView(self.view.retired, self.view.retiredDir)
+	self.view.retired, self.view.retiredDir = nil, ""
+	err := self.db.Close()
+	os.RemoveAll(self.view.dir)
+	return err
+}
+
+func closeLevelDBView(db *leveldb.DB, dir string) {
+	if db != nil {
+		db.Close()
+	}
+	if dir != "" {
+		os.RemoveAll(dir)
+	}
+}
+
+//open copy the view of leveldb to a new dir and open it read-only
+func (self *levelDBView) open() (*leveldb.DB, string, error) {
+	var err error
+	for i := 0; i < READ_ONLY_VIEW_RETRIES; i++ {
+		var dir string
+		dir, err = ioutil.TempDir(self.root, filepath.Base(self.file)+"-view")
+		if err != nil {
+			return nil, "", err
+		}
+		err = copyLevelDBView(self.file, dir)
+		if err == nil {
+			var db *leveldb.DB
+			db, err = leveldb.OpenFile(dir, &opt.Options{
+				ReadOnly:       true,
+				ErrorIfMissing: true,
+				Filter:         filter.NewBloomFilter(BITSPERKEY),
+			})
+			if err == nil {
+				return db, dir, nil
+			}
+		}
+		os.RemoveAll(dir)
+	}
+	return nil, "", fmt.Errorf("open view of %s error %s", self.file, err)
+}
+
+//copyLevelDBView copy the leveldb in src to dst. The table files which are referred by the manifest are linked before
+//the journal files are copied, and the manifest is copied to the size at the beginning. The copy is consistent if the
+//manifest is not changed during the copy, because leveldb only appends to the journals, and the view may miss the
+//writes at the end of journals which is same as an earlier view
+func copyLevelDBView(src, dst string) error {
+	current, err := ioutil.ReadFile(filepath.Join(src, "CURRENT"))
+	if err != nil {
+		return err
+	}
+	manifest := strings.TrimSpace(string(current))
+	info, err := os.Stat(filepath.Join(src, manifest))
+	if err != nil {
+		return err
+	}
+	names, err := listDir(src)
+	if err != nil {
+		return err
+	}
+	journals := make([]string, 0)
+	for _, name := range names {
+		switch filepath.Ext(name) {
+		case ".ldb", ".sst":
+			err = os.Link(filepath.Join(src, name), filepath.Join(dst, name))
+			if err != nil && !os.IsNotExist(err) {
+				err = copyFile(filepath.Join(src, name), filepath.Join(dst, name), -1)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		case ".log":
+			journals = append(journals, name)
+		}
+	}
+	//the older journals are copied before the newer ones
+	sort.Strings(journals)
+	for _, name := range journals {
+		err = copyFile(filepath.Join(src, name), filepath.Join(dst, name), -1)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	err = copyFile(filepath.Join(src, manifest), filepath.Join(dst, manifest), info.Size())
+	if err != nil {
+		return err
+	}
+	latest, err := ioutil.ReadFile(filepath.Join(src, "CURRENT"))
+	if err != nil {
+		return err
+	}
+	latestInfo, err := os.Stat(filepath.Join(src, manifest))
+	if err != nil {
+		return err
+	}
+	if string(latest) != string(current) || latestInfo.Size() != info.Size() {
+		return fmt.Errorf("manifest of %s is changed during copy", src)
+	}
+	return ioutil.WriteFile(filepath.Join(dst, "CURRENT"), current, 0644)
+}
+
+func listDir(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+//copyFile copy size bytes of src to dst, or the whole file if size is negative
+func copyFile(src, dst string, size int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		_, err = io.Copy(out, in)
+	} else {
+		_, err = io.CopyN(out, in, size)
+	}
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
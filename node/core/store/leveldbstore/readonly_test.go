@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package leveldbstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyLevelDBStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readonly")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	viewRoot := filepath.Join(dir, "views")
+	assert.Nil(t, os.Mkdir(viewRoot, 0755))
+
+	writer, err := NewLevelDBStore(filepath.Join(dir, "db"))
+	assert.Nil(t, err)
+	defer writer.Close()
+	assert.Nil(t, writer.Put([]byte("foo"), []byte("bar")))
+
+	//the leveldb opened by writer is read by the view
+	reader, err := NewReadOnlyLevelDBStore(filepath.Join(dir, "db"), viewRoot)
+	assert.Nil(t, err)
+	value, err := reader.Get([]byte("foo"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("bar"), value)
+	assert.NotNil(t, reader.Put([]byte("foo"), []byte("baz")))
+
+	writer.NewBatch()
+	for i := 0; i < 1000; i++ {
+		writer.BatchPut([]byte(fmt.Sprintf("key%d", i)), make([]byte, 1024))
+	}
+	assert.Nil(t, writer.BatchCommit())
+	assert.Nil(t, writer.Compact())
+	assert.Nil(t, writer.Delete([]byte("foo")))
+	_, err = reader.Get([]byte("key1"))
+	assert.NotNil(t, err)
+
+	//the view is updated by refresh, and the iterator on previous view is usable until next refresh
+	iter := reader.NewIterator([]byte("foo"))
+	assert.Nil(t, reader.Refresh())
+	assert.True(t, iter.Next())
+	iter.Release()
+	_, err = reader.Get([]byte("foo"))
+	assert.NotNil(t, err)
+	value, err = reader.Get([]byte("key999"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1024, len(value))
+	assert.Nil(t, reader.Refresh())
+	views, err := ioutil.ReadDir(viewRoot)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(views))
+
+	assert.Nil(t, reader.Close())
+	views, err = ioutil.ReadDir(viewRoot)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(views))
+	assert.NotNil(t, writer.Refresh())
+}
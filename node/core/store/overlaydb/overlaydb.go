@@ -27,9 +27,11 @@ import (
 )
 
 type OverlayDB struct {
-	store common.PersistStore
-	memdb *MemDB
-	dbErr error
+	store  common.PersistStore
+	memdb  *MemDB
+	dbErr  error
+	hits   uint64 //Count of reads served by the write set
+	misses uint64 //Count of reads from the backend store
 }
 
 const initCap = 4 * 1024
@@ -59,8 +61,10 @@ func (self *OverlayDB) Get(key []byte) (value []byte, err error) {
 	var unknown bool
 	value, unknown = self.memdb.Get(key)
 	if unknown == false {
+		self.hits++
 		return value, nil
 	}
+	self.misses++
 
 	value, err = self.store.Get(key)
 	if err != nil {
@@ -92,6 +96,11 @@ func (self *OverlayDB) CommitTo() {
 	})
 }
 
+//CacheStats return the count of reads served by the write set and read from the backend store
+func (self *OverlayDB) CacheStats() (hits, misses uint64) {
+	return self.hits, self.misses
+}
+
 func (self *OverlayDB) GetWriteSet() *MemDB {
 	return self.memdb
 }
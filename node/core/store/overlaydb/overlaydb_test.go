@@ -102,3 +102,22 @@ func BenchmarkOverlayDBRandomPut(b *testing.B) {
 	}
 
 }
+
+func TestOverlayDBCacheStats(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	assert.Nil(t, store.Put([]byte("stored"), []byte("val")))
+
+	overlay := NewOverlayDB(store)
+	overlay.Put([]byte("written"), []byte("val"))
+	_, err = overlay.Get([]byte("written"))
+	assert.Nil(t, err)
+	_, err = overlay.Get([]byte("stored"))
+	assert.Nil(t, err)
+	_, err = overlay.Get([]byte("missing"))
+	assert.Nil(t, err)
+
+	hits, misses := overlay.CacheStats()
+	assert.Equal(t, uint64(1), hits)
+	assert.Equal(t, uint64(2), misses)
+}
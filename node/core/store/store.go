@@ -19,6 +19,8 @@
 package store
 
 import (
+	"time"
+
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/core/payload"
@@ -34,14 +36,142 @@ type ExecuteResult struct {
 	Hash            common.Uint256
 	MerkleRoot      common.Uint256
 	UpdatedAccountState     []common.Uint256
+	UpdatedAccounts         []*AccountState
 	UpdatedAccountStateRoot common.Uint256
+	StorageStateRoot        common.Uint256
+	StateTreeNodes        map[common.Uint256][]byte
+	FullStateRoot         common.Uint256            //Root of all storage, empty if full state root is disabled
+	FullStateNodes        map[common.Uint256][]byte
 	Notify          []*event.ExecuteNotify
 }
 
+//AccountState is the updated storage value of account of contract in block, the key of account in the sparse merkle
+//tree of UpdatedAccountStateRoot is Contract and Address, and empty Value means the account is deleted
+type AccountState struct {
+	Contract common.Address
+	Address  common.Address
+	Value    []byte
+}
+
+//StorageEntry is a storage key of contract and its value
+type StorageEntry struct {
+	Key   []byte //Storage key without contract address
+	Value []byte
+}
+
+//StateChange is the value of state key before and after block executed, empty value means the key is absent
+type StateChange struct {
+	Key      []byte
+	OldValue []byte
+	NewValue []byte
+}
+
+//Layer2AccountStates is the sparse merkle tree leaves of the accounts updated in block in key order, Accounts is the
+//preimage of the leaves and nil for the blocks saved before accounts are stored
+type Layer2AccountStates struct {
+	Root     common.Uint256
+	Leaves   []common.Uint256
+	Accounts []*AccountState
+}
+
+//Layer2AccountSnapshot is all the accounts in the account state tree of block in tree key order, Keys are the tree keys
+//of Accounts, which are contract address, key prefix of account root rule and account address
+type Layer2AccountSnapshot struct {
+	Height   uint32
+	Root     common.Uint256
+	Keys     [][]byte
+	Accounts []*AccountState
+}
+
+//StateCacheStats is the counters of state store read cache
+type StateCacheStats struct {
+	Size   uint64
+	Hits   uint64
+	Misses uint64
+}
+
+//EventNotifyPage is one page of event notify query, Next is the continuation token of next page and empty at the last page
+type EventNotifyPage struct {
+	Notifies []*event.ExecuteNotify
+	Next     string
+}
+
+//BlockEvents is the event notifies of a committed block streamed by EventSubscription, Notifies is empty if the block
+//has no matched event so that subscribers can save the height they have processed
+type BlockEvents struct {
+	Height   uint32
+	Notifies []*event.ExecuteNotify
+}
+
+//EventSubscription stream the event notifies of committed blocks in height order. The channel of Events is closed
+//after unsubscribed, ledger closed or reading events failed, and Err return the error after the channel closed
+type EventSubscription interface {
+	Events() <-chan *BlockEvents
+	Unsubscribe()
+	Err() error
+}
+
+//AddressTxPage is one page of transactions of address, Next is the continuation token of next page and empty at the last page
+type AddressTxPage struct {
+	Heights  []uint32
+	TxHashes []common.Uint256
+	Next     string
+}
+
+//RecoverStatus is the progress of re-executing saved blocks to state store at startup, time in unix seconds
+//ResumeHeight is the height this run starts from, it is above StartHeight if an interrupted recovery is resumed
+type RecoverStatus struct {
+	Recovering    bool
+	StartHeight   uint32
+	ResumeHeight  uint32
+	CurrentHeight uint32
+	TargetHeight  uint32
+	StartTime     uint32
+	EndTime       uint32
+	EstimatedTime uint32 //Estimated end time of the recovery
+}
+
+//InvariantViolation is one failed check of invariant checker, time in unix seconds
+type InvariantViolation struct {
+	Invariant string
+	Height    uint32
+	Time      uint32
+	Detail    string
+}
+
+//InvariantStatus is the progress and the counters of invariant checker, Violations keeps the recent violations
+type InvariantStatus struct {
+	Running        bool
+	CheckedHeight  uint32
+	Rounds         uint64
+	ViolationCount uint64
+	LastCheckTime  uint32
+	Violations     []InvariantViolation
+}
+
+//GasStats is the gas and fee statistics of the blocks in [FromHeight, ToHeight], Blocks is the count of blocks
+//having statistics. SysFee is the fee charged to payers, and GasUsed is SysFee divided by gas price of each transaction,
+//so the transactions of zero gas price are not counted in GasUsed. Gas prices are of the charged transactions
+type GasStats struct {
+	FromHeight  uint32
+	ToHeight    uint32
+	Blocks      uint32
+	TxCount     uint64
+	FailedTxs   uint64
+	GasUsed     uint64
+	SysFee      uint64
+	MinGasPrice uint64
+	MaxGasPrice uint64
+}
+
 // LedgerStore provides func with store package.
 type LedgerStore interface {
 	InitLedgerStoreWithGenesisBlock(genesisblock *types.Block, defaultBookkeeper []keypair.PublicKey) error
 	Close() error
+	CompactStores() error
+	Backup(dir string) error
+	ExportBlocks(path string, from, to uint32) error
+	ImportBlocks(path string) error
 	ExecuteBlock(b *types.Block) (ExecuteResult, error)                                       // called by consensus
 	SubmitBlock(b *types.Block, crossChainMsg *types.Layer2State, exec ExecuteResult) error // called by consensus
 	GetStateMerkleRoot(height uint32) (result common.Uint256, err error)
@@ -55,7 +185,9 @@ type LedgerStore interface {
 	GetHeaderByHeight(height uint32) (*types.Header, error)
 	GetBlockByHash(blockHash common.Uint256) (*types.Block, error)
 	GetBlockByHeight(height uint32) (*types.Block, error)
+	GetBlocksByHeightRange(start, end uint32) ([]*types.Block, error)
 	GetTransaction(txHash common.Uint256) (*types.Transaction, uint32, error)
+	GetTransactionsByAddress(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*AddressTxPage, error)
 	IsContainBlock(blockHash common.Uint256) (bool, error)
 	IsContainTransaction(txHash common.Uint256) (bool, error)
 	GetBlockRootWithNewTxRoots(startHeight uint32, txRoots []common.Uint256) common.Uint256
@@ -67,7 +199,39 @@ type LedgerStore interface {
 	PreExecuteContractBatch(txes []*types.Transaction, atomic bool) ([]*cstates.PreExecResult, uint32, error)
 	GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error)
 	GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error)
+	GetEventNotifyByBlockFilter(height uint32, contract common.Address, topic string) ([]*event.ExecuteNotify, error)
+	GetEventNotifyByContract(contract common.Address, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error)
+	GetEventNotifyByAddress(addr common.Address, fromHeight, toHeight uint32, limit uint32) ([]*event.ExecuteNotify, error)
+	GetEventNotifyByBlockPage(height uint32, cursor string, limit uint32) (*EventNotifyPage, error)
+	GetEventNotifyByContractPage(contract common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*EventNotifyPage, error)
+	GetEventNotifyByAddressPage(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*EventNotifyPage, error)
+	SubscribeEvents(fromHeight uint32, contracts []common.Address) (EventSubscription, error)
 	//layer2 state states root
 	GetLayer2State(height uint32) (*types.Layer2State, error)
+	GetLayer2States(fromHeight, toHeight uint32) ([]*types.Layer2State, error)
 	GetLayer2StateProof(height uint32, key []byte) ([]byte, error)
+	GetStorageStateProof(height uint32, contract common.Address, key []byte) (common.Uint256, []byte, error)
+	GetFullStateProof(height uint32, contract common.Address, key []byte) (common.Uint256, []byte, error)
+	GetLayer2AccountStates(height uint32) (*Layer2AccountStates, error)
+	GetLayer2AccountSnapshot(height uint32) (*Layer2AccountSnapshot, error)
+	GetStateCacheStats() StateCacheStats
+	GetRecoverStatus() RecoverStatus
+	StartInvariantChecker(interval time.Duration)
+	GetInvariantStatus() InvariantStatus
+	SetLayer2Retention(retention, checkpointInterval uint32) error
+	EnableStateHistory(enable bool) error
+	EnableFullStateRoot(enable bool) error
+	GetStorageItemAt(key *states.StorageKey, height uint32) (*states.StorageItem, error)
+	IterateStorage(contract common.Address, prefix []byte, height uint32) ([]*StorageEntry, error)
+	GetBalanceAt(addr common.Address, height uint32) (uint64, uint64, error)
+	GetStateDiff(height uint32) ([]*StateChange, error)
+	SetCheckpointSnapshotDir(dir string) error
+	NewCheckpoint(height uint32) (*types.Checkpoint, error)
+	SaveCheckpoint(checkpoint *types.Checkpoint) error
+	GetCheckpoint(height uint32) (*types.Checkpoint, error)
+	GetReceipt(txHash common.Uint256) (*types.Receipt, error)
+	GetGasStats(fromHeight, toHeight uint32) (*GasStats, error)
+	GetRecentGasStats(blocks uint32) (*GasStats, error)
+	IsReadOnly() bool
+	Refresh() error
 }
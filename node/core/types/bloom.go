@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+const (
+	BLOOM_BYTE_LENGTH = 256 //Bloom filter size of block events
+	BLOOM_BIT_LENGTH  = 8 * BLOOM_BYTE_LENGTH
+	BLOOM_HASH_COUNT  = 3 //Bits set for every item
+)
+
+//Bloom filter of (contract address, event topic) of all events in a block
+type Bloom [BLOOM_BYTE_LENGTH]byte
+
+//BytesToBloom return bloom of the serialized bytes
+func BytesToBloom(data []byte) (Bloom, error) {
+	var bloom Bloom
+	if len(data) != BLOOM_BYTE_LENGTH {
+		return bloom, fmt.Errorf("bloom length %d error", len(data))
+	}
+	copy(bloom[:], data)
+	return bloom, nil
+}
+
+//Add item to bloom filter
+func (this *Bloom) Add(item []byte) {
+	for _, bit := range bloomBits(item) {
+		this[BLOOM_BYTE_LENGTH-1-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+//Test return false if item is definitely not in bloom filter
+func (this *Bloom) Test(item []byte) bool {
+	for _, bit := range bloomBits(item) {
+		if this[BLOOM_BYTE_LENGTH-1-bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func bloomBits(item []byte) [BLOOM_HASH_COUNT]uint {
+	var bits [BLOOM_HASH_COUNT]uint
+	hash := sha256.Sum256(item)
+	for i := 0; i < BLOOM_HASH_COUNT; i++ {
+		bits[i] = (uint(hash[2*i])<<8 | uint(hash[2*i+1])) % BLOOM_BIT_LENGTH
+	}
+	return bits
+}
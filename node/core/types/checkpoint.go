@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package types
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ontio/layer2/node/common"
+)
+
+const (
+	CURR_CHECKPOINT_VERSION = 1
+)
+
+//Checkpoint is the block and state roots at a checkpoint height signed by bookkeepers, a node can sync state at the
+//checkpoint and replay the blocks after it
+type Checkpoint struct {
+	Version          byte
+	Height           uint32
+	BlockHash        common.Uint256
+	BlockRoot        common.Uint256 //Root of block merkle tree up to the height
+	StateRoot        common.Uint256 //Root of state merkle tree up to the height
+	AccountStateRoot common.Uint256 //Root of account state sparse merkle tree, which is the root of layer2 state
+	StorageStateRoot common.Uint256 //Root of storage state sparse merkle tree
+	ContractHash     common.Uint256 //Hash of the bookkeeper and contract state entries in key order, from version 1
+
+	SigData [][]byte
+
+	hash *common.Uint256
+}
+
+func (this *Checkpoint) serializationUnsigned(sink *common.ZeroCopySink) {
+	sink.WriteByte(this.Version)
+	sink.WriteUint32(this.Height)
+	sink.WriteHash(this.BlockHash)
+	sink.WriteHash(this.BlockRoot)
+	sink.WriteHash(this.StateRoot)
+	sink.WriteHash(this.AccountStateRoot)
+	sink.WriteHash(this.StorageStateRoot)
+	if this.Version >= 1 {
+		sink.WriteHash(this.ContractHash)
+	}
+}
+
+func (this *Checkpoint) Serialization(sink *common.ZeroCopySink) {
+	this.serializationUnsigned(sink)
+	sink.WriteVarUint(uint64(len(this.SigData)))
+	for _, sig := range this.SigData {
+		sink.WriteVarBytes(sig)
+	}
+}
+
+func (this *Checkpoint) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Version, eof = source.NextByte()
+	if eof {
+		return fmt.Errorf("Checkpoint, deserialization read version error")
+	}
+	this.Height, eof = source.NextUint32()
+	if eof {
+		return fmt.Errorf("Checkpoint, deserialization read height error")
+	}
+	for _, hash := range []*common.Uint256{&this.BlockHash, &this.BlockRoot, &this.StateRoot, &this.AccountStateRoot,
+		&this.StorageStateRoot} {
+		*hash, eof = source.NextHash()
+		if eof {
+			return fmt.Errorf("Checkpoint, deserialization read root error")
+		}
+	}
+	if this.Version >= 1 {
+		this.ContractHash, eof = source.NextHash()
+		if eof {
+			return fmt.Errorf("Checkpoint, deserialization read contract hash error")
+		}
+	}
+	sigLen, _, irr, eof := source.NextVarUint()
+	if irr || eof {
+		return fmt.Errorf("Checkpoint, deserialization read sigData length error")
+	}
+	sigData := make([][]byte, 0)
+	for i := uint64(0); i < sigLen; i++ {
+		v, _, irr, eof := source.NextVarBytes()
+		if irr || eof {
+			return fmt.Errorf("Checkpoint, deserialization read sigData value error")
+		}
+		sigData = append(sigData, v)
+	}
+	this.SigData = sigData
+	return nil
+}
+
+func (this *Checkpoint) Hash() common.Uint256 {
+	if this.hash != nil {
+		return *this.hash
+	}
+	sink := common.NewZeroCopySink(nil)
+	this.serializationUnsigned(sink)
+	temp := sha256.Sum256(sink.Bytes())
+	hash := common.Uint256(sha256.Sum256(temp[:]))
+	this.hash = &hash
+	return hash
+}
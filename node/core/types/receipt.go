@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/ontio/layer2/node/common"
+)
+
+//Receipt is the execution result of a transaction saved at commit. State is the contract state of execute notify,
+//GasConsumed is the fee charged to payer, and Bloom is the bloom filter of the events of the transaction
+type Receipt struct {
+	TxHash      common.Uint256
+	Height      uint32
+	TxIndex     uint32 //Index of transaction in block
+	State       byte
+	GasConsumed uint64
+	NotifyCount uint32
+	Bloom       Bloom
+}
+
+func (this *Receipt) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteHash(this.TxHash)
+	sink.WriteUint32(this.Height)
+	sink.WriteUint32(this.TxIndex)
+	sink.WriteByte(this.State)
+	sink.WriteUint64(this.GasConsumed)
+	sink.WriteUint32(this.NotifyCount)
+	//the bloom of transaction without event is omitted
+	if this.NotifyCount == 0 {
+		sink.WriteVarBytes(nil)
+	} else {
+		sink.WriteVarBytes(this.Bloom[:])
+	}
+}
+
+func (this *Receipt) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.TxHash, eof = source.NextHash()
+	if eof {
+		return fmt.Errorf("Receipt, deserialization read tx hash error")
+	}
+	this.Height, eof = source.NextUint32()
+	if eof {
+		return fmt.Errorf("Receipt, deserialization read height error")
+	}
+	this.TxIndex, eof = source.NextUint32()
+	if eof {
+		return fmt.Errorf("Receipt, deserialization read tx index error")
+	}
+	this.State, eof = source.NextByte()
+	if eof {
+		return fmt.Errorf("Receipt, deserialization read state error")
+	}
+	this.GasConsumed, eof = source.NextUint64()
+	if eof {
+		return fmt.Errorf("Receipt, deserialization read gas consumed error")
+	}
+	this.NotifyCount, eof = source.NextUint32()
+	if eof {
+		return fmt.Errorf("Receipt, deserialization read notify count error")
+	}
+	data, _, irregular, eof := source.NextVarBytes()
+	if irregular || eof {
+		return fmt.Errorf("Receipt, deserialization read bloom error")
+	}
+	if len(data) == 0 {
+		this.Bloom = Bloom{}
+		return nil
+	}
+	bloom, err := BytesToBloom(data)
+	if err != nil {
+		return fmt.Errorf("Receipt, deserialization %s", err)
+	}
+	this.Bloom = bloom
+	return nil
+}
@@ -29,6 +29,7 @@ var DefEvtHub *eventhub.EventHub
 var DefPublisherPID *actor.PID
 var DefActorPublisher *ActorPublisher
 var defPublisherProps *actor.Props
+var DefBlockFeed *BlockFeed
 
 func Init() {
 	DefEvtHub = eventhub.GlobalEventHub
@@ -39,6 +40,7 @@ func Init() {
 		panic(fmt.Errorf("DefPublisherPID SpawnNamed error:%s", err))
 	}
 	DefActorPublisher = NewActorPublisher(DefPublisherPID)
+	DefBlockFeed = NewBlockFeed()
 }
 
 func NewActorPublisher(publisher *actor.PID, evtHub ...*eventhub.EventHub) *ActorPublisher {
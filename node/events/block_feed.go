@@ -0,0 +1,330 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package events
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/types"
+)
+
+//OverflowPolicy is how a block subscription handles the published blocks when it lags more than its buffer size
+type OverflowPolicy byte
+
+const (
+	POLICY_CATCH_UP    OverflowPolicy = iota //Deliver every block, the blocks out of feed cache are loaded by the block loader
+	POLICY_DROP_OLDEST                       //Skip the oldest blocks, so the subscriber lags at most buffer size blocks
+	POLICY_BACKPRESSURE                      //Wait at publishing until the subscriber catches up, at most BLOCK_FEED_BACKPRESSURE_TIMEOUT
+)
+
+const (
+	BLOCK_FEED_CACHE_SIZE           = 256             //Count of latest published blocks kept in memory for lagging subscribers
+	BLOCK_FEED_DEFAULT_BUFFER_SIZE  = 64              //Default count of blocks a subscriber may lag before its policy applies
+	BLOCK_FEED_BACKPRESSURE_TIMEOUT = 3 * time.Second //Max waiting time of publishing for each backpressure subscriber
+)
+
+//BlockLoader return the saved block of height, used to replay blocks not in feed cache
+type BlockLoader func(height uint32) (*types.Block, error)
+
+//BlockEvent is a block delivered to subscriber in height order
+type BlockEvent struct {
+	Block  *types.Block
+	Missed uint32 //Count of blocks skipped right before the block
+}
+
+type BlockSubscriptionConfig struct {
+	Name       string         //Name of subscriber in logs
+	BufferSize int            //Count of blocks the subscriber may lag, BLOCK_FEED_DEFAULT_BUFFER_SIZE if 0
+	Policy     OverflowPolicy //Handling of blocks when the subscriber lags more than BufferSize
+	Replay     bool           //Deliver from FromHeight, otherwise from the next published block
+	FromHeight uint32
+}
+
+//BlockFeed publish the saved blocks to typed subscriptions. Publishing only records the block and wakes the
+//subscriptions, each subscription delivers the blocks in its own goroutine, so a slow subscriber does not stall the
+//block saving, and the blocks it lags are delivered later or reported as missed by its policy
+type BlockFeed struct {
+	lock    sync.Mutex
+	height  uint32                        //Height of last published block
+	started bool                          //Whether any block published
+	recent  []*types.Block                //Latest published blocks, indexed by height % BLOCK_FEED_CACHE_SIZE
+	loader  BlockLoader                   //Nil if blocks out of cache can not be replayed
+	subs    map[uint64]*BlockSubscription //Subscriptions by id
+	nextId  uint64
+	closed  bool
+}
+
+func NewBlockFeed() *BlockFeed {
+	return &BlockFeed{
+		recent: make([]*types.Block, BLOCK_FEED_CACHE_SIZE),
+		subs:   make(map[uint64]*BlockSubscription),
+	}
+}
+
+//SetLoader set the loader of saved blocks, which replays the blocks evicted from feed cache
+func (this *BlockFeed) SetLoader(loader BlockLoader) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.loader = loader
+}
+
+//Height return the height of last published block, false if no block published
+func (this *BlockFeed) Height() (uint32, bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.height, this.started
+}
+
+//Subscribe start a subscription of published blocks. The subscription should be closed when not used
+func (this *BlockFeed) Subscribe(config BlockSubscriptionConfig) (*BlockSubscription, error) {
+	if config.BufferSize <= 0 {
+		config.BufferSize = BLOCK_FEED_DEFAULT_BUFFER_SIZE
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return nil, fmt.Errorf("block feed is closed")
+	}
+	this.nextId++
+	sub := &BlockSubscription{
+		feed:     this,
+		id:       this.nextId,
+		config:   config,
+		wake:     make(chan struct{}, 1),
+		progress: make(chan struct{}, 1),
+		events:   make(chan *BlockEvent),
+		exit:     make(chan struct{}),
+	}
+	if config.Replay {
+		sub.next = config.FromHeight
+	} else if this.started {
+		sub.next = this.height + 1
+	} else {
+		sub.waitFirst = true
+	}
+	this.subs[sub.id] = sub
+	go sub.loop()
+	return sub, nil
+}
+
+//Publish record the saved block and wake the subscriptions. It only waits for the lagging subscriptions of
+//POLICY_BACKPRESSURE, at most BLOCK_FEED_BACKPRESSURE_TIMEOUT for each
+func (this *BlockFeed) Publish(block *types.Block) {
+	height := block.Header.Height
+	this.lock.Lock()
+	if this.closed {
+		this.lock.Unlock()
+		return
+	}
+	this.recent[height%BLOCK_FEED_CACHE_SIZE] = block
+	this.height, this.started = height, true
+	var lagging []*BlockSubscription
+	for _, sub := range this.subs {
+		if sub.waitFirst {
+			sub.next, sub.waitFirst = height, false
+		}
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+		if sub.config.Policy == POLICY_BACKPRESSURE && sub.lag(height) > sub.config.BufferSize {
+			lagging = append(lagging, sub)
+		}
+	}
+	this.lock.Unlock()
+	for _, sub := range lagging {
+		sub.waitProgress(height)
+	}
+}
+
+//Close close all subscriptions and reject later ones
+func (this *BlockFeed) Close() {
+	this.lock.Lock()
+	this.closed = true
+	subs := this.subs
+	this.subs = make(map[uint64]*BlockSubscription)
+	this.lock.Unlock()
+	for _, sub := range subs {
+		sub.stop()
+	}
+}
+
+//cachedBlock return the published block of height if still in cache, the caller should hold lock
+func (this *BlockFeed) cachedBlock(height uint32) *types.Block {
+	block := this.recent[height%BLOCK_FEED_CACHE_SIZE]
+	if block == nil || block.Header.Height != height {
+		return nil
+	}
+	return block
+}
+
+//BlockSubscription deliver the published blocks to Events in height order
+type BlockSubscription struct {
+	feed      *BlockFeed
+	id        uint64
+	config    BlockSubscriptionConfig
+	next      uint32        //Height of next block to deliver, guarded by feed lock
+	waitFirst bool          //Start from the next published block, guarded by feed lock
+	missed    uint32        //Count of skipped blocks not reported yet, guarded by feed lock
+	total     uint64        //Count of all skipped blocks
+	wake      chan struct{} //Signaled when block published
+	progress  chan struct{} //Signaled when block delivered
+	events    chan *BlockEvent
+	exit      chan struct{}
+	closeOnce sync.Once
+}
+
+//Events return the channel of delivered blocks, which is closed after the subscription closed
+func (this *BlockSubscription) Events() <-chan *BlockEvent {
+	return this.events
+}
+
+//Missed return the count of blocks skipped by the subscription
+func (this *BlockSubscription) Missed() uint64 {
+	return atomic.LoadUint64(&this.total)
+}
+
+//Close stop the subscription
+func (this *BlockSubscription) Close() {
+	this.feed.lock.Lock()
+	delete(this.feed.subs, this.id)
+	this.feed.lock.Unlock()
+	this.stop()
+}
+
+func (this *BlockSubscription) stop() {
+	this.closeOnce.Do(func() {
+		close(this.exit)
+	})
+}
+
+//lag return the count of published blocks not delivered, the caller should hold feed lock
+func (this *BlockSubscription) lag(height uint32) int {
+	if this.waitFirst || this.next > height {
+		return 0
+	}
+	return int(height - this.next + 1)
+}
+
+func (this *BlockSubscription) waitProgress(height uint32) {
+	timer := time.NewTimer(BLOCK_FEED_BACKPRESSURE_TIMEOUT)
+	defer timer.Stop()
+	for {
+		this.feed.lock.Lock()
+		lag := this.lag(height)
+		this.feed.lock.Unlock()
+		if lag <= this.config.BufferSize {
+			return
+		}
+		select {
+		case <-this.progress:
+		case <-this.exit:
+			return
+		case <-timer.C:
+			log.Warnf("block subscription %s lags %d blocks at height %d, stop waiting", this.config.Name, lag, height)
+			return
+		}
+	}
+}
+
+//skip mark the blocks before height as missed, the caller should hold feed lock
+func (this *BlockSubscription) skip(height uint32) {
+	if height <= this.next {
+		return
+	}
+	count := height - this.next
+	this.missed += count
+	atomic.AddUint64(&this.total, uint64(count))
+	this.next = height
+}
+
+//nextEvent return the next block to deliver, false if the subscription has delivered all published blocks
+func (this *BlockSubscription) nextEvent() (*BlockEvent, bool) {
+	feed := this.feed
+	for {
+		feed.lock.Lock()
+		if !feed.started || this.waitFirst || this.next > feed.height {
+			feed.lock.Unlock()
+			return nil, false
+		}
+		if this.config.Policy == POLICY_DROP_OLDEST && this.lag(feed.height) > this.config.BufferSize {
+			this.skip(feed.height - uint32(this.config.BufferSize) + 1)
+		}
+		height := this.next
+		block := feed.cachedBlock(height)
+		loader := feed.loader
+		feed.lock.Unlock()
+
+		var err error
+		if block == nil && loader != nil {
+			block, err = loader(height)
+		}
+		feed.lock.Lock()
+		if this.next != height {
+			feed.lock.Unlock()
+			continue
+		}
+		if block == nil {
+			// without loader, skip to the oldest cached block at once
+			oldest := height + 1
+			if loader == nil && feed.height >= BLOCK_FEED_CACHE_SIZE && feed.height-BLOCK_FEED_CACHE_SIZE+1 > oldest {
+				oldest = feed.height - BLOCK_FEED_CACHE_SIZE + 1
+			}
+			log.Warnf("block subscription %s skip blocks [%d, %d), error: %v", this.config.Name, height, oldest, err)
+			this.skip(oldest)
+			feed.lock.Unlock()
+			continue
+		}
+		evt := &BlockEvent{Block: block, Missed: this.missed}
+		this.missed = 0
+		feed.lock.Unlock()
+		return evt, true
+	}
+}
+
+func (this *BlockSubscription) loop() {
+	defer close(this.events)
+	for {
+		evt, ok := this.nextEvent()
+		if !ok {
+			select {
+			case <-this.wake:
+				continue
+			case <-this.exit:
+				return
+			}
+		}
+		select {
+		case this.events <- evt:
+		case <-this.exit:
+			return
+		}
+		this.feed.lock.Lock()
+		this.next = evt.Block.Header.Height + 1
+		this.feed.lock.Unlock()
+		select {
+		case this.progress <- struct{}{}:
+		default:
+		}
+	}
+}
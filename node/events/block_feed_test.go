@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ontio/layer2/node/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFeedBlock(height uint32) *types.Block {
+	return &types.Block{Header: &types.Header{Height: height}}
+}
+
+func receiveBlockEvent(t *testing.T, sub *BlockSubscription) *BlockEvent {
+	select {
+	case evt := <-sub.Events():
+		return evt
+	case <-time.After(time.Second):
+		t.Fatal("receive block event timeout")
+	}
+	return nil
+}
+
+func TestBlockFeedReplay(t *testing.T) {
+	feed := NewBlockFeed()
+	defer feed.Close()
+	feed.SetLoader(func(height uint32) (*types.Block, error) {
+		return newTestFeedBlock(height), nil
+	})
+	live, err := feed.Subscribe(BlockSubscriptionConfig{Name: "live"})
+	assert.Nil(t, err)
+	for height := uint32(0); height < BLOCK_FEED_CACHE_SIZE+10; height++ {
+		feed.Publish(newTestFeedBlock(height))
+	}
+	for height := uint32(0); height < BLOCK_FEED_CACHE_SIZE+10; height++ {
+		evt := receiveBlockEvent(t, live)
+		assert.Equal(t, height, evt.Block.Header.Height)
+		assert.Equal(t, uint32(0), evt.Missed)
+	}
+
+	//blocks evicted from cache are loaded, the block failed to load is reported as missed
+	feed.SetLoader(func(height uint32) (*types.Block, error) {
+		if height == 3 {
+			return nil, fmt.Errorf("not found")
+		}
+		return newTestFeedBlock(height), nil
+	})
+	replay, err := feed.Subscribe(BlockSubscriptionConfig{Name: "replay", Replay: true, FromHeight: 2})
+	assert.Nil(t, err)
+	evt := receiveBlockEvent(t, replay)
+	assert.Equal(t, uint32(2), evt.Block.Header.Height)
+	evt = receiveBlockEvent(t, replay)
+	assert.Equal(t, uint32(4), evt.Block.Header.Height)
+	assert.Equal(t, uint32(1), evt.Missed)
+	assert.Equal(t, uint64(1), replay.Missed())
+
+	replay.Close()
+	for range replay.Events() {
+	}
+}
+
+func TestBlockFeedDropOldest(t *testing.T) {
+	feed := NewBlockFeed()
+	defer feed.Close()
+	sub, err := feed.Subscribe(BlockSubscriptionConfig{Name: "drop", BufferSize: 2, Policy: POLICY_DROP_OLDEST})
+	assert.Nil(t, err)
+	for height := uint32(0); height < 10; height++ {
+		feed.Publish(newTestFeedBlock(height))
+	}
+	//a block may be taken before later blocks published
+	evt := receiveBlockEvent(t, sub)
+	for evt.Block.Header.Height < 8 {
+		evt = receiveBlockEvent(t, sub)
+	}
+	assert.Equal(t, uint32(8), evt.Block.Header.Height)
+	assert.True(t, sub.Missed() > 0)
+	evt = receiveBlockEvent(t, sub)
+	assert.Equal(t, uint32(9), evt.Block.Header.Height)
+	assert.Equal(t, uint32(0), evt.Missed)
+}
+
+func TestBlockFeedBackpressure(t *testing.T) {
+	feed := NewBlockFeed()
+	defer feed.Close()
+	sub, err := feed.Subscribe(BlockSubscriptionConfig{Name: "slow", BufferSize: 1, Policy: POLICY_BACKPRESSURE})
+	assert.Nil(t, err)
+	feed.Publish(newTestFeedBlock(0))
+	published := make(chan struct{})
+	go func() {
+		feed.Publish(newTestFeedBlock(1))
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("publish should wait for lagging subscriber")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, uint32(0), receiveBlockEvent(t, sub).Block.Header.Height)
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish should continue after subscriber received")
+	}
+	assert.Equal(t, uint32(1), receiveBlockEvent(t, sub).Block.Header.Height)
+
+	feed.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	_, err = feed.Subscribe(BlockSubscriptionConfig{})
+	assert.NotNil(t, err)
+}
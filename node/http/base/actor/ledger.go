@@ -22,6 +22,7 @@ import (
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/core/ledger"
 	"github.com/ontio/layer2/node/core/payload"
+	"github.com/ontio/layer2/node/core/store"
 	"github.com/ontio/layer2/node/core/types"
 	"github.com/ontio/layer2/node/smartcontract/event"
 	cstate "github.com/ontio/layer2/node/smartcontract/states"
@@ -42,6 +43,31 @@ func GetBlockByHeight(height uint32) (*types.Block, error) {
 	return ledger.DefLedger.GetBlockByHeight(height)
 }
 
+//CompactStores of ledger
+func CompactStores() error {
+	return ledger.DefLedger.CompactStores()
+}
+
+//BackupLedger of ledger
+func BackupLedger(dir string) error {
+	return ledger.DefLedger.Backup(dir)
+}
+
+//GetRecoverStatus from ledger
+func GetRecoverStatus() store.RecoverStatus {
+	return ledger.DefLedger.GetRecoverStatus()
+}
+
+//GetInvariantStatus from ledger
+func GetInvariantStatus() store.InvariantStatus {
+	return ledger.DefLedger.GetInvariantStatus()
+}
+
+//GetBlocksByHeightRange from ledger
+func GetBlocksByHeightRange(start, end uint32) ([]*types.Block, error) {
+	return ledger.DefLedger.GetBlocksByHeightRange(start, end)
+}
+
 //GetBlockHashFromStore from ledger
 func GetBlockHashFromStore(height uint32) common.Uint256 {
 	return ledger.DefLedger.GetBlockHash(height)
@@ -72,6 +98,46 @@ func GetStorageItem(address common.Address, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetStorageItem(address, key)
 }
 
+//GetStorageItemAt from ledger
+func GetStorageItemAt(address common.Address, key []byte, height uint32) ([]byte, error) {
+	return ledger.DefLedger.GetStorageItemAt(address, key, height)
+}
+
+//IterateStorage from ledger
+func IterateStorage(contract common.Address, prefix []byte, height uint32) ([]*store.StorageEntry, error) {
+	return ledger.DefLedger.IterateStorage(contract, prefix, height)
+}
+
+//GetBalanceAt from ledger
+func GetBalanceAt(addr common.Address, height uint32) (uint64, uint64, error) {
+	return ledger.DefLedger.GetBalanceAt(addr, height)
+}
+
+//GetStateDiff from ledger
+func GetStateDiff(height uint32) ([]*store.StateChange, error) {
+	return ledger.DefLedger.GetStateDiff(height)
+}
+
+//GetReceipt from ledger
+func GetReceipt(txHash common.Uint256) (*types.Receipt, error) {
+	return ledger.DefLedger.GetReceipt(txHash)
+}
+
+//GetGasStats from ledger
+func GetGasStats(fromHeight, toHeight uint32) (*store.GasStats, error) {
+	return ledger.DefLedger.GetGasStats(fromHeight, toHeight)
+}
+
+//GetRecentGasStats from ledger
+func GetRecentGasStats(blocks uint32) (*store.GasStats, error) {
+	return ledger.DefLedger.GetRecentGasStats(blocks)
+}
+
+//GetCheckpoint from ledger
+func GetCheckpoint(height uint32) (*types.Checkpoint, error) {
+	return ledger.DefLedger.GetCheckpoint(height)
+}
+
 //GetContractStateFromStore from ledger
 func GetContractStateFromStore(hash common.Address) (*payload.DeployCode, error) {
 	hash = updateNativeSCAddr(hash)
@@ -103,6 +169,36 @@ func GetEventNotifyByHeight(height uint32) ([]*event.ExecuteNotify, error) {
 	return ledger.DefLedger.GetEventNotifyByBlock(height)
 }
 
+//GetEventNotifyByContract from ledger
+func GetEventNotifyByContract(contract common.Address, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error) {
+	return ledger.DefLedger.GetEventNotifyByContract(contract, fromHeight, toHeight)
+}
+
+//GetEventNotifyByAddress from ledger
+func GetEventNotifyByAddress(addr common.Address, fromHeight, toHeight uint32, limit uint32) ([]*event.ExecuteNotify, error) {
+	return ledger.DefLedger.GetEventNotifyByAddress(addr, fromHeight, toHeight, limit)
+}
+
+//GetTransactionsByAddress from ledger
+func GetTransactionsByAddress(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.AddressTxPage, error) {
+	return ledger.DefLedger.GetTransactionsByAddress(addr, fromHeight, toHeight, cursor, limit)
+}
+
+//GetEventNotifyByHeightPage from ledger
+func GetEventNotifyByHeightPage(height uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return ledger.DefLedger.GetEventNotifyByBlockPage(height, cursor, limit)
+}
+
+//GetEventNotifyByContractPage from ledger
+func GetEventNotifyByContractPage(contract common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return ledger.DefLedger.GetEventNotifyByContractPage(contract, fromHeight, toHeight, cursor, limit)
+}
+
+//GetEventNotifyByAddressPage from ledger
+func GetEventNotifyByAddressPage(addr common.Address, fromHeight, toHeight uint32, cursor string, limit uint32) (*store.EventNotifyPage, error) {
+	return ledger.DefLedger.GetEventNotifyByAddressPage(addr, fromHeight, toHeight, cursor, limit)
+}
+
 //GetMerkleProof from ledger
 func GetMerkleProof(proofHeight uint32, rootHeight uint32) ([]common.Uint256, error) {
 	return ledger.DefLedger.GetMerkleProof(proofHeight, rootHeight)
@@ -112,6 +208,26 @@ func GetLayer2State(height uint32) (*types.Layer2State, error) {
 	return ledger.DefLedger.GetLayer2State(height)
 }
 
+func GetStorageStateProof(height uint32, contract common.Address, key []byte) (common.Uint256, []byte, error) {
+	return ledger.DefLedger.GetStorageStateProof(height, contract, key)
+}
+
+func GetFullStateProof(height uint32, contract common.Address, key []byte) (common.Uint256, []byte, error) {
+	return ledger.DefLedger.GetFullStateProof(height, contract, key)
+}
+
+func GetLayer2States(fromHeight, toHeight uint32) ([]*types.Layer2State, error) {
+	return ledger.DefLedger.GetLayer2States(fromHeight, toHeight)
+}
+
 func GetLayer2StateProof(height uint32, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetLayer2StateProof(height, key)
 }
+
+func GetLayer2AccountStates(height uint32) (*store.Layer2AccountStates, error) {
+	return ledger.DefLedger.GetLayer2AccountStates(height)
+}
+
+func GetLayer2AccountSnapshot(height uint32) (*store.Layer2AccountSnapshot, error) {
+	return ledger.DefLedger.GetLayer2AccountSnapshot(height)
+}
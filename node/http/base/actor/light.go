@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package actor
+
+import (
+	"github.com/ontio/layer2/node/core/ledger"
+	"github.com/ontio/layer2/node/core/types"
+)
+
+//GetLightCurrentHeight from light ledger
+func GetLightCurrentHeight() uint32 {
+	return ledger.DefLightLedger.GetCurrentHeight()
+}
+
+//GetLightHeaderByHeight from light ledger
+func GetLightHeaderByHeight(height uint32) (*types.Header, error) {
+	return ledger.DefLightLedger.GetHeaderByHeight(height)
+}
+
+//GetLightLayer2State from light ledger
+func GetLightLayer2State(height uint32) (*types.Layer2State, error) {
+	return ledger.DefLightLedger.GetLayer2State(height)
+}
+
+//VerifyLightLayer2StateProof from light ledger
+func VerifyLightLayer2StateProof(height uint32, key, value, proof []byte) (bool, error) {
+	return ledger.DefLightLedger.VerifyLayer2StateProof(height, key, value, proof)
+}
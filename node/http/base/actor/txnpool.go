@@ -35,6 +35,9 @@ var txnPid *actor.PID
 var txnPoolPid *actor.PID
 var DisableSyncVerifyTx = false
 
+//errTxPoolNotStarted is returned when the node runs without transaction pool, like the read-only replica
+var errTxPoolNotStarted = errors.New("transaction pool is not started")
+
 func SetTxPid(actr *actor.PID) {
 	txnPid = actr
 }
@@ -44,6 +47,9 @@ func SetTxnPoolPid(actr *actor.PID) {
 
 //append transaction to pool to txpool actor
 func AppendTxToPool(txn *types.Transaction) (ontErrors.ErrCode, string) {
+	if txnPid == nil {
+		return ontErrors.ErrUnknown, errTxPoolNotStarted.Error()
+	}
 	if DisableSyncVerifyTx {
 		txReq := &tcomn.TxReq{txn, tcomn.HttpSender, nil}
 		txnPid.Tell(txReq)
@@ -65,6 +71,9 @@ func AppendTxToPool(txn *types.Transaction) (ontErrors.ErrCode, string) {
 
 //GetTxsFromPool from txpool actor
 func GetTxsFromPool(byCount bool) map[common.Uint256]*types.Transaction {
+	if txnPoolPid == nil {
+		return nil
+	}
 	future := txnPoolPid.RequestFuture(&tcomn.GetTxnPoolReq{ByCount: byCount}, REQ_TIMEOUT*time.Second)
 	result, err := future.Result()
 	if err != nil {
@@ -85,6 +94,9 @@ func GetTxsFromPool(byCount bool) map[common.Uint256]*types.Transaction {
 
 //GetTxFromPool from txpool actor
 func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
+	if txnPid == nil {
+		return tcomn.TXEntry{}, errTxPoolNotStarted
+	}
 
 	future := txnPid.RequestFuture(&tcomn.GetTxnReq{hash}, REQ_TIMEOUT*time.Second)
 	result, err := future.Result()
@@ -116,6 +128,9 @@ func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
 
 //GetTxnCount from txpool actor
 func GetTxnCount() ([]uint32, error) {
+	if txnPid == nil {
+		return []uint32{}, errTxPoolNotStarted
+	}
 	future := txnPid.RequestFuture(&tcomn.GetTxnCountReq{}, REQ_TIMEOUT*time.Second)
 	result, err := future.Result()
 	if err != nil {
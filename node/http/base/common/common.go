@@ -33,6 +33,9 @@ import (
 	"github.com/ontio/layer2/node/common/log"
 	"github.com/ontio/layer2/node/core/ledger"
 	"github.com/ontio/layer2/node/core/payload"
+	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
 	"github.com/ontio/layer2/node/core/types"
 	cutils "github.com/ontio/layer2/node/core/utils"
 	ontErrors "github.com/ontio/layer2/node/errors"
@@ -47,6 +50,18 @@ import (
 const MAX_SEARCH_HEIGHT uint32 = 100
 const MAX_REQUEST_BODY_SIZE = 1 << 20
 
+//MAX_PAGE_LIMIT is the max count of items in one page of paged query
+const MAX_PAGE_LIMIT uint32 = 1000
+
+//MAX_BLOCK_RANGE is the max count of blocks returned by one range query
+const MAX_BLOCK_RANGE uint32 = 100
+
+//MAX_LAYER2_STATE_RANGE is the max count of heights of layer2 state messages returned by one range query
+const MAX_LAYER2_STATE_RANGE uint32 = 1000
+
+//MAX_HEADER_RANGE is the max count of block headers returned by one range query
+const MAX_HEADER_RANGE uint32 = 1000
+
 type BalanceOfRsp struct {
 	Ont    string `json:"ont"`
 	Ong    string `json:"ong"`
@@ -75,6 +90,32 @@ type ExecuteNotify struct {
 	Notify      []NotifyEventInfo
 }
 
+//EventNotifyPage is the response of paged event query, pass Next as cursor to get the next page, empty Next means the last page
+type EventNotifyPage struct {
+	Notifies []*ExecuteNotify
+	Next     string
+}
+
+//AddressTxPage is the response of paged transaction query of address, empty Next means the last page
+type AddressTxPage struct {
+	Transactions []*AddressTx
+	Next         string
+}
+
+type AddressTx struct {
+	TxHash string
+	Height uint32
+}
+
+//FeatureInfo is the state of feature at the queried height
+type FeatureInfo struct {
+	Name             string
+	Description      string
+	ActivationHeight uint32
+	Disabled         bool
+	Active           bool
+}
+
 type PreExecuteResult struct {
 	State  byte
 	Gas    uint64
@@ -108,9 +149,99 @@ type Sig struct {
 	SigData []string
 }
 
+//Layer2StateProof is the proof in hex, Root is the storage state root of block for the proof of contract storage key
 type Layer2StateProof struct {
 	Type      string
 	AuditPath string
+	Root      string `json:",omitempty"`
+}
+
+//Layer2AccountStatesInfo is the account state root of block and the leaves of updated accounts, Accounts are returned in
+//verbose mode
+type Layer2AccountStatesInfo struct {
+	Height   uint32
+	Root     string
+	Leaves   []string
+	Accounts []*Layer2AccountInfo `json:",omitempty"`
+}
+
+//Layer2AccountInfo is the updated account of contract and its value in hex, Leaf is the sparse merkle tree leaf of the
+//account and empty hash for deleted account
+type Layer2AccountInfo struct {
+	Contract string
+	Address  string
+	Value    string
+	Leaf     string
+}
+
+//Layer2AccountSnapshotInfo is all the accounts in the account state tree of block with their proofs against Root
+type Layer2AccountSnapshotInfo struct {
+	Height   uint32
+	Root     string
+	Accounts []*Layer2SnapshotAccountInfo
+}
+
+//Layer2SnapshotAccountInfo is an account in the account state tree, Key is the tree key in hex and Proof is the
+//serialized sparse merkle proof of its value
+type Layer2SnapshotAccountInfo struct {
+	Contract string
+	Address  string
+	Key      string
+	Value    string
+	Proof    string
+}
+
+//StateDiff is the state keys updated by block in key order
+type StateDiff struct {
+	Height  uint32
+	Changes []*StateChangeInfo
+}
+
+//StateChangeInfo is the value of state key in hex before and after block, Contract and Key are decoded from the key by
+//its Type, storage values are decoded to the value of storage item and empty value means the key is absent
+type StateChangeInfo struct {
+	Type     string
+	Contract string `json:",omitempty"`
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+//StorageSnapshot is the storage entries of contract with the key prefix after block of height, keys and values are hex
+type StorageSnapshot struct {
+	Contract string
+	Height   uint32
+	Entries  []*StorageEntryInfo
+}
+
+type StorageEntryInfo struct {
+	Key   string
+	Value string
+}
+
+//CheckpointInfo is the checkpoint signed by bookkeepers, Checkpoint is the serialized checkpoint in hex used by
+//fast sync to verify the state snapshot
+type CheckpointInfo struct {
+	Height           uint32
+	BlockHash        string
+	BlockRoot        string
+	StateRoot        string
+	AccountStateRoot string
+	StorageStateRoot string
+	ContractHash     string
+	Checkpoint       string
+}
+
+//ReceiptInfo is the execution result of transaction, Bloom is the hex bloom filter of its events and empty if the
+//transaction has no event
+type ReceiptInfo struct {
+	TxHash      string
+	Height      uint32
+	TxIndex     uint32
+	State       byte
+	GasConsumed uint64
+	NotifyCount uint32
+	Bloom       string
 }
 
 type Transactions struct {
@@ -196,6 +327,112 @@ func GetExecuteNotify(obj *event.ExecuteNotify) (map[string]bool, ExecuteNotify)
 	return contractAddrs, ExecuteNotify{txhash, obj.State, obj.GasConsumed, evts}
 }
 
+//GetEventNotifyPage convert the event page of ledger to response
+func GetEventNotifyPage(page *store.EventNotifyPage) *EventNotifyPage {
+	notifies := make([]*ExecuteNotify, 0, len(page.Notifies))
+	for _, obj := range page.Notifies {
+		_, notify := GetExecuteNotify(obj)
+		notifies = append(notifies, &notify)
+	}
+	return &EventNotifyPage{Notifies: notifies, Next: page.Next}
+}
+
+//GetAddressTxPage convert the transaction page of ledger to response
+func GetAddressTxPage(page *store.AddressTxPage) *AddressTxPage {
+	txs := make([]*AddressTx, 0, len(page.TxHashes))
+	for i, txHash := range page.TxHashes {
+		txs = append(txs, &AddressTx{TxHash: txHash.ToHexString(), Height: page.Heights[i]})
+	}
+	return &AddressTxPage{Transactions: txs, Next: page.Next}
+}
+
+//GetCheckpointInfo convert the checkpoint to response
+func GetCheckpointInfo(checkpoint *types.Checkpoint) *CheckpointInfo {
+	sink := common.NewZeroCopySink(nil)
+	checkpoint.Serialization(sink)
+	return &CheckpointInfo{
+		Height:           checkpoint.Height,
+		BlockHash:        checkpoint.BlockHash.ToHexString(),
+		BlockRoot:        checkpoint.BlockRoot.ToHexString(),
+		StateRoot:        checkpoint.StateRoot.ToHexString(),
+		AccountStateRoot: checkpoint.AccountStateRoot.ToHexString(),
+		StorageStateRoot: checkpoint.StorageStateRoot.ToHexString(),
+		ContractHash:     checkpoint.ContractHash.ToHexString(),
+		Checkpoint:       common.ToHexString(sink.Bytes()),
+	}
+}
+
+//GetReceiptInfo convert the receipt to response
+func GetReceiptInfo(receipt *types.Receipt) *ReceiptInfo {
+	info := &ReceiptInfo{
+		TxHash:      receipt.TxHash.ToHexString(),
+		Height:      receipt.Height,
+		TxIndex:     receipt.TxIndex,
+		State:       receipt.State,
+		GasConsumed: receipt.GasConsumed,
+		NotifyCount: receipt.NotifyCount,
+	}
+	if receipt.NotifyCount > 0 {
+		info.Bloom = common.ToHexString(receipt.Bloom[:])
+	}
+	return info
+}
+
+//GetStorageSnapshot convert the storage entries of contract at height to response
+func GetStorageSnapshot(contract common.Address, height uint32, entries []*store.StorageEntry) *StorageSnapshot {
+	infos := make([]*StorageEntryInfo, 0, len(entries))
+	for _, entry := range entries {
+		infos = append(infos, &StorageEntryInfo{
+			Key:   common.ToHexString(entry.Key),
+			Value: common.ToHexString(entry.Value),
+		})
+	}
+	return &StorageSnapshot{Contract: contract.ToHexString(), Height: height, Entries: infos}
+}
+
+//GetStateDiff convert the state changes of block to response
+func GetStateDiff(height uint32, changes []*store.StateChange) *StateDiff {
+	infos := make([]*StateChangeInfo, 0, len(changes))
+	for _, change := range changes {
+		info := &StateChangeInfo{Type: "Unknown", Key: common.ToHexString(change.Key)}
+		decodeValue := func(value []byte) string { return common.ToHexString(value) }
+		switch {
+		case len(change.Key) > common.ADDR_LEN && change.Key[0] == byte(scom.ST_STORAGE):
+			contract, _ := common.AddressParseFromBytes(change.Key[1 : 1+common.ADDR_LEN])
+			info.Type = "Storage"
+			info.Contract = contract.ToHexString()
+			info.Key = common.ToHexString(change.Key[1+common.ADDR_LEN:])
+			decodeValue = func(value []byte) string {
+				item := new(states.StorageItem)
+				if len(value) == 0 || item.Deserialization(common.NewZeroCopySource(value)) != nil {
+					return common.ToHexString(value)
+				}
+				return common.ToHexString(item.Value)
+			}
+		case len(change.Key) == 1+common.ADDR_LEN && change.Key[0] == byte(scom.ST_CONTRACT):
+			contract, _ := common.AddressParseFromBytes(change.Key[1:])
+			info.Type = "Contract"
+			info.Contract = contract.ToHexString()
+			info.Key = ""
+		case len(change.Key) > 0 && change.Key[0] == byte(scom.ST_BOOKKEEPER):
+			info.Type = "Bookkeeper"
+			info.Key = common.ToHexString(change.Key[1:])
+		}
+		info.OldValue = decodeValue(change.OldValue)
+		info.NewValue = decodeValue(change.NewValue)
+		infos = append(infos, info)
+	}
+	return &StateDiff{Height: height, Changes: infos}
+}
+
+//GetPageLimit return the limit of paged query, 0 or larger than MAX_PAGE_LIMIT is MAX_PAGE_LIMIT
+func GetPageLimit(limit uint32) uint32 {
+	if limit == 0 || limit > MAX_PAGE_LIMIT {
+		return MAX_PAGE_LIMIT
+	}
+	return limit
+}
+
 func ConvertPreExecuteResult(obj *cstate.PreExecResult) PreExecuteResult {
 	evts := []NotifyEventInfo{}
 	for _, v := range obj.Notify {
@@ -0,0 +1,47 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package common
+
+import (
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/states"
+	"github.com/ontio/layer2/node/core/store"
+	scom "github.com/ontio/layer2/node/core/store/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStateDiff(t *testing.T) {
+	contract := common.Address{1}
+	sink := common.NewZeroCopySink(nil)
+	(&states.StorageItem{Value: []byte{2}}).Serialization(sink)
+	changes := []*store.StateChange{
+		{Key: append(append([]byte{byte(scom.ST_STORAGE)}, contract[:]...), 3), NewValue: sink.Bytes()},
+		{Key: append([]byte{byte(scom.ST_CONTRACT)}, contract[:]...), OldValue: []byte{4}},
+		{Key: []byte{byte(scom.ST_BOOKKEEPER), 5}, NewValue: []byte{6}},
+		{Key: []byte{byte(scom.ST_VOTE)}},
+	}
+	diff := GetStateDiff(7, changes)
+	assert.Equal(t, &StateDiff{Height: 7, Changes: []*StateChangeInfo{
+		{Type: "Storage", Contract: contract.ToHexString(), Key: "03", NewValue: "02"},
+		{Type: "Contract", Contract: contract.ToHexString(), OldValue: "04"},
+		{Type: "Bookkeeper", Key: "05", NewValue: "06"},
+		{Type: "Unknown", Key: "08"},
+	}}, diff)
+}
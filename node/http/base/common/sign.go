@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/core/signature"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+//ResponseSignature prove the result of response is given by the node at the height
+type ResponseSignature struct {
+	Height    uint32
+	Hash      string //sha256 of json encoded result
+	PublicKey string
+	Signature string
+}
+
+var responseSigner struct {
+	sync.RWMutex
+	account *account.Account
+}
+
+//SetResponseSigner set the account to sign response, nil account disable the signature
+func SetResponseSigner(acc *account.Account) {
+	responseSigner.Lock()
+	defer responseSigner.Unlock()
+	responseSigner.account = acc
+}
+
+//SignResult sign the hash of json encoded result and height, return nil if no signer
+func SignResult(result interface{}, height uint32) (*ResponseSignature, error) {
+	responseSigner.RLock()
+	acc := responseSigner.account
+	responseSigner.RUnlock()
+	if acc == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error %s", err)
+	}
+	hash := sha256.Sum256(data)
+	sig, err := signature.Sign(acc, responseSignData(hash, height))
+	if err != nil {
+		return nil, fmt.Errorf("signature.Sign error %s", err)
+	}
+	return &ResponseSignature{
+		Height:    height,
+		Hash:      hex.EncodeToString(hash[:]),
+		PublicKey: hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey)),
+		Signature: hex.EncodeToString(sig),
+	}, nil
+}
+
+//VerifyResultSignature check the signature of raw json result in response, and the signer is pubKey
+func VerifyResultSignature(result json.RawMessage, sig *ResponseSignature, pubKey keypair.PublicKey) error {
+	hash := sha256.Sum256(result)
+	if hex.EncodeToString(hash[:]) != sig.Hash {
+		return fmt.Errorf("result hash mismatch")
+	}
+	if sig.PublicKey != hex.EncodeToString(keypair.SerializePublicKey(pubKey)) {
+		return fmt.Errorf("signer public key mismatch")
+	}
+	data, err := hex.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("decode signature error %s", err)
+	}
+	return signature.Verify(pubKey, responseSignData(hash, sig.Height), data)
+}
+
+func responseSignData(hash [sha256.Size]byte, height uint32) []byte {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteBytes(hash[:])
+	sink.WriteUint32(height)
+	return sink.Bytes()
+}
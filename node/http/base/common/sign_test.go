@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignResult(t *testing.T) {
+	result := MerkleProof{"MerkleProof", "root", 1, "blockroot", 2, []string{"a", "b"}}
+	sig, err := SignResult(result, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, sig)
+
+	acc := account.NewAccount("")
+	SetResponseSigner(acc)
+	defer SetResponseSigner(nil)
+	sig, err = SignResult(result, 2)
+	assert.Nil(t, err)
+	data, err := json.Marshal(map[string]interface{}{"result": result, "signature": sig})
+	assert.Nil(t, err)
+
+	resp := &struct {
+		Result    json.RawMessage
+		Signature *ResponseSignature
+	}{}
+	assert.Nil(t, json.Unmarshal(data, resp))
+	assert.Nil(t, VerifyResultSignature(resp.Result, resp.Signature, acc.PublicKey))
+	assert.NotNil(t, VerifyResultSignature(resp.Result, resp.Signature, account.NewAccount("").PublicKey))
+
+	tampered := json.RawMessage(`{"Type":"MerkleProof","TransactionsRoot":"fake"}`)
+	assert.NotNil(t, VerifyResultSignature(tampered, resp.Signature, acc.PublicKey))
+	resp.Signature.Height = 3
+	assert.NotNil(t, VerifyResultSignature(resp.Result, resp.Signature, acc.PublicKey))
+}
@@ -49,7 +49,7 @@ func GetNodeVersion(cmd map[string]interface{}) map[string]interface{} {
 // get networkid
 func GetNetworkId(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
-	resp["Result"] = config.NETWORK_ID_SOLO_NET
+	resp["Result"] = config.DefConfig.GetNetworkId()
 	return resp
 }
 
@@ -289,6 +289,21 @@ func GetSmartCodeEventTxsByHeight(cmd map[string]interface{}) map[string]interfa
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	index := uint32(height)
+	if cursor, ok := cmd["Cursor"].(string); ok {
+		limit, err := getPageLimit(cmd)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		page, err := bactor.GetEventNotifyByHeightPage(index, cursor, limit)
+		if err != nil {
+			if scom.ErrNotFound == err {
+				return ResponsePack(berr.SUCCESS)
+			}
+			return pageErrorPack(err)
+		}
+		resp["Result"] = bcomn.GetEventNotifyPage(page)
+		return resp
+	}
 	eventInfos, err := bactor.GetEventNotifyByHeight(index)
 	if err != nil {
 		if scom.ErrNotFound == err {
@@ -336,6 +351,178 @@ func GetSmartCodeEventByTxHash(cmd map[string]interface{}) map[string]interface{
 	return resp
 }
 
+//get all smartcontract event of contract in height range
+func GetSmartCodeEventByContract(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+
+	resp := ResponsePack(berr.SUCCESS)
+
+	str, ok := cmd["Addr"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	from, ok1 := cmd["From"].(string)
+	to, ok2 := cmd["To"].(string)
+	if !ok1 || !ok2 {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	fromHeight, err := strconv.ParseUint(from, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	toHeight, err := strconv.ParseUint(to, 10, 32)
+	if err != nil || toHeight < fromHeight {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	if cursor, ok := cmd["Cursor"].(string); ok {
+		limit, err := getPageLimit(cmd)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		page, err := bactor.GetEventNotifyByContractPage(address, uint32(fromHeight), uint32(toHeight), cursor, limit)
+		if err != nil {
+			return pageErrorPack(err)
+		}
+		resp["Result"] = bcomn.GetEventNotifyPage(page)
+		return resp
+	}
+	eventInfos, err := bactor.GetEventNotifyByContract(address, uint32(fromHeight), uint32(toHeight))
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
+	for _, eventInfo := range eventInfos {
+		_, notify := bcomn.GetExecuteNotify(eventInfo)
+		eInfos = append(eInfos, &notify)
+	}
+	resp["Result"] = eInfos
+	return resp
+}
+
+//get at most limit smartcontract event of account address in height range, limit 0 means no limit
+func GetSmartCodeEventByAddress(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+
+	resp := ResponsePack(berr.SUCCESS)
+
+	str, ok := cmd["Addr"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	from, ok1 := cmd["From"].(string)
+	to, ok2 := cmd["To"].(string)
+	limitStr, ok3 := cmd["Limit"].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	fromHeight, err := strconv.ParseUint(from, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	toHeight, err := strconv.ParseUint(to, 10, 32)
+	if err != nil || toHeight < fromHeight {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	limit, err := strconv.ParseUint(limitStr, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	if cursor, ok := cmd["Cursor"].(string); ok {
+		page, err := bactor.GetEventNotifyByAddressPage(address, uint32(fromHeight), uint32(toHeight), cursor,
+			bcomn.GetPageLimit(uint32(limit)))
+		if err != nil {
+			return pageErrorPack(err)
+		}
+		resp["Result"] = bcomn.GetEventNotifyPage(page)
+		return resp
+	}
+	eventInfos, err := bactor.GetEventNotifyByAddress(address, uint32(fromHeight), uint32(toHeight), uint32(limit))
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
+	for _, eventInfo := range eventInfos {
+		_, notify := bcomn.GetExecuteNotify(eventInfo)
+		eInfos = append(eInfos, &notify)
+	}
+	resp["Result"] = eInfos
+	return resp
+}
+
+//get at most limit transactions of payer or signer address in height range after the cursor
+func GetTransactionsByAddress(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableTxIndex {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+
+	resp := ResponsePack(berr.SUCCESS)
+
+	str, ok := cmd["Addr"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	from, ok1 := cmd["From"].(string)
+	to, ok2 := cmd["To"].(string)
+	if !ok1 || !ok2 {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	fromHeight, err := strconv.ParseUint(from, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	toHeight, err := strconv.ParseUint(to, 10, 32)
+	if err != nil || toHeight < fromHeight {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	limit, err := getPageLimit(cmd)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	cursor, _ := cmd["Cursor"].(string)
+	page, err := bactor.GetTransactionsByAddress(address, uint32(fromHeight), uint32(toHeight), cursor, limit)
+	if err != nil {
+		return pageErrorPack(err)
+	}
+	resp["Result"] = bcomn.GetAddressTxPage(page)
+	return resp
+}
+
+//getPageLimit return the limit of paged query, empty limit is the max page limit
+func getPageLimit(cmd map[string]interface{}) (uint32, error) {
+	str, _ := cmd["Limit"].(string)
+	if str == "" {
+		return bcomn.MAX_PAGE_LIMIT, nil
+	}
+	limit, err := strconv.ParseUint(str, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return bcomn.GetPageLimit(uint32(limit)), nil
+}
+
+func pageErrorPack(err error) map[string]interface{} {
+	if err == scom.ErrInvalidCursor {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	return ResponsePack(berr.INTERNAL_ERROR)
+}
+
 //get contract state
 func GetContractState(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -20,6 +20,7 @@ package rpc
 
 import (
 	"encoding/hex"
+	"fmt"
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/common/config"
 	"github.com/ontio/layer2/node/common/log"
@@ -85,6 +86,66 @@ func GetBlock(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(block.ToArray()))
 }
 
+//get blocks in height range [start, end], at most MAX_BLOCK_RANGE blocks in one call
+// A JSON example for getblocksbyheightrange method as following:
+//   {"jsonrpc": "2.0", "method": "getblocksbyheightrange", "params": [1, 100, 1], "id": 0}
+func GetBlocksByHeightRange(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	start, ok1 := params[0].(float64)
+	end, ok2 := params[1].(float64)
+	if !ok1 || !ok2 || start < 0 || end < start || end-start >= float64(bcomn.MAX_BLOCK_RANGE) {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	blocks, err := bactor.GetBlocksByHeightRange(uint32(start), uint32(end))
+	if err != nil {
+		return responsePack(berr.UNKNOWN_BLOCK, "unknown block")
+	}
+	if len(params) >= 3 {
+		switch (params[2]).(type) {
+		case float64:
+			json := uint32(params[2].(float64))
+			if json == 1 {
+				infos := make([]interface{}, 0, len(blocks))
+				for _, block := range blocks {
+					infos = append(infos, bcomn.GetBlockInfo(block))
+				}
+				return responseSuccess(infos)
+			}
+		default:
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	raws := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		raws = append(raws, common.ToHexString(block.ToArray()))
+	}
+	return responseSuccess(raws)
+}
+
+//get raw block headers in height range [start, end], at most MAX_HEADER_RANGE headers in one call. The range stops
+//before the first header not saved
+func GetBlockHeaders(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	start, ok1 := params[0].(float64)
+	end, ok2 := params[1].(float64)
+	if !ok1 || !ok2 || start < 0 || end < start || end-start >= float64(bcomn.MAX_HEADER_RANGE) {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	raws := make([]string, 0, uint32(end-start)+1)
+	for height := uint32(start); height <= uint32(end); height++ {
+		header, err := bactor.GetHeaderByHeight(height)
+		if err != nil || header == nil {
+			break
+		}
+		raws = append(raws, common.ToHexString(header.ToArray()))
+	}
+	return responseSuccess(raws)
+}
+
 //get block height
 func GetBlockCount(params []interface{}) map[string]interface{} {
 	height := bactor.GetCurrentBlockHeight()
@@ -244,6 +305,77 @@ func GetStorage(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(value))
 }
 
+//get the storage value of contract after block of height executed, it requires the node started with state history
+// A JSON example for getstorageat method as following:
+//   {"jsonrpc": "2.0", "method": "getstorageat", "params": ["code hash", "key", 100], "id": 0}
+func GetStorageAt(params []interface{}) map[string]interface{} {
+	if len(params) < 3 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok = params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[2].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	value, err := bactor.GetStorageItemAt(address, key, uint32(height))
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responseSuccess(nil)
+		}
+		log.Errorf("GetStorageAt, bactor.GetStorageItemAt error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(common.ToHexString(value))
+}
+
+//get all storage entries of contract with the key prefix after block of height, the prefix may be empty
+func GetStoragesAt(params []interface{}) map[string]interface{} {
+	if len(params) < 3 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok = params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	prefix, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[2].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	entries, err := bactor.IterateStorage(address, prefix, uint32(height))
+	if err != nil {
+		log.Errorf("GetStoragesAt, bactor.IterateStorage error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(bcomn.GetStorageSnapshot(address, uint32(height), entries))
+}
+
 //send raw transaction
 // A JSON example for sendrawtransaction method as following:
 //   {"jsonrpc": "2.0", "method": "sendrawtransaction", "params": ["raw transactioin in hex"], "id": 0}
@@ -296,6 +428,147 @@ func GetNodeVersion(params []interface{}) map[string]interface{} {
 	return responseSuccess(config.Version)
 }
 
+//get all event notify of contract in height range,
+//with limit and cursor params return one page of them, the first page use empty cursor
+func GetSmartCodeEventByContract(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return responsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 3 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	fromHeight, ok1 := params[1].(float64)
+	toHeight, ok2 := params[2].(float64)
+	if !ok1 || !ok2 || fromHeight < 0 || toHeight < fromHeight {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if len(params) >= 5 {
+		limit, ok1 := params[3].(float64)
+		cursor, ok2 := params[4].(string)
+		if !ok1 || !ok2 || limit < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		page, err := bactor.GetEventNotifyByContractPage(address, uint32(fromHeight), uint32(toHeight), cursor,
+			bcomn.GetPageLimit(uint32(limit)))
+		if err != nil {
+			if err == scom.ErrInvalidCursor {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			return responsePack(berr.INTERNAL_ERROR, "")
+		}
+		return responseSuccess(bcomn.GetEventNotifyPage(page))
+	}
+	eventInfos, err := bactor.GetEventNotifyByContract(address, uint32(fromHeight), uint32(toHeight))
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
+	for _, eventInfo := range eventInfos {
+		_, notify := bcomn.GetExecuteNotify(eventInfo)
+		eInfos = append(eInfos, &notify)
+	}
+	return responseSuccess(eInfos)
+}
+
+//get at most limit event notify of account address in height range, limit 0 means no limit,
+//with cursor param return one page of them, the first page use empty cursor
+func GetSmartCodeEventByAddress(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return responsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 4 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	fromHeight, ok1 := params[1].(float64)
+	toHeight, ok2 := params[2].(float64)
+	limit, ok3 := params[3].(float64)
+	if !ok1 || !ok2 || !ok3 || fromHeight < 0 || toHeight < fromHeight || limit < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if len(params) >= 5 {
+		cursor, ok := params[4].(string)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		page, err := bactor.GetEventNotifyByAddressPage(address, uint32(fromHeight), uint32(toHeight), cursor,
+			bcomn.GetPageLimit(uint32(limit)))
+		if err != nil {
+			if err == scom.ErrInvalidCursor {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			return responsePack(berr.INTERNAL_ERROR, "")
+		}
+		return responseSuccess(bcomn.GetEventNotifyPage(page))
+	}
+	eventInfos, err := bactor.GetEventNotifyByAddress(address, uint32(fromHeight), uint32(toHeight), uint32(limit))
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
+	for _, eventInfo := range eventInfos {
+		_, notify := bcomn.GetExecuteNotify(eventInfo)
+		eInfos = append(eInfos, &notify)
+	}
+	return responseSuccess(eInfos)
+}
+
+//get at most limit transactions of payer or signer address in height range after the cursor,
+//the first page use empty cursor
+func GetTransactionsByAddress(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableTxIndex {
+		return responsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 4 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	fromHeight, ok1 := params[1].(float64)
+	toHeight, ok2 := params[2].(float64)
+	limit, ok3 := params[3].(float64)
+	if !ok1 || !ok2 || !ok3 || fromHeight < 0 || toHeight < fromHeight || limit < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	cursor := ""
+	if len(params) >= 5 {
+		cursor, ok = params[4].(string)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	page, err := bactor.GetTransactionsByAddress(address, uint32(fromHeight), uint32(toHeight), cursor,
+		bcomn.GetPageLimit(uint32(limit)))
+	if err != nil {
+		if err == scom.ErrInvalidCursor {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(bcomn.GetAddressTxPage(page))
+}
+
 //get contract state
 func GetContractState(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -333,7 +606,7 @@ func GetContractState(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(sink.Bytes()))
 }
 
-//get smartconstract event
+//get smartconstract event, by block height with limit and cursor params return one page of events
 func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 	if !config.DefConfig.Common.EnableEventLog {
 		return responsePack(berr.INVALID_METHOD, "")
@@ -346,6 +619,24 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 	// block height
 	case float64:
 		height := uint32(params[0].(float64))
+		if len(params) >= 3 {
+			limit, ok1 := params[1].(float64)
+			cursor, ok2 := params[2].(string)
+			if !ok1 || !ok2 || limit < 0 {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			page, err := bactor.GetEventNotifyByHeightPage(height, cursor, bcomn.GetPageLimit(uint32(limit)))
+			if err != nil {
+				if err == scom.ErrNotFound {
+					return responseSuccess(nil)
+				}
+				if err == scom.ErrInvalidCursor {
+					return responsePack(berr.INVALID_PARAMS, "")
+				}
+				return responsePack(berr.INTERNAL_ERROR, "")
+			}
+			return responseSuccess(bcomn.GetEventNotifyPage(page))
+		}
 		eventInfos, err := bactor.GetEventNotifyByHeight(height)
 		if err != nil {
 			if err == scom.ErrNotFound {
@@ -406,6 +697,30 @@ func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 	return responsePack(berr.INVALID_PARAMS, "")
 }
 
+//get the receipt of transaction, which is the execution state, gas consumed, notify count and event bloom
+func GetReceipt(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	receipt, err := bactor.GetReceipt(hash)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responseSuccess(nil)
+		}
+		log.Errorf("GetReceipt, bactor.GetReceipt error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(bcomn.GetReceiptInfo(receipt))
+}
+
 //get balance of address
 func GetBalance(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -426,6 +741,112 @@ func GetBalance(params []interface{}) map[string]interface{} {
 	return responseSuccess(rsp)
 }
 
+//get the ont and ong balance of address after block of height executed, it requires the node started with state history
+func GetBalanceAt(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	addrBase58, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := common.AddressFromBase58(addrBase58)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[1].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	ont, ong, err := bactor.GetBalanceAt(address, uint32(height))
+	if err != nil {
+		log.Errorf("GetBalanceAt, bactor.GetBalanceAt error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(&bcomn.BalanceOfRsp{
+		Ont:    fmt.Sprintf("%d", ont),
+		Ong:    fmt.Sprintf("%d", ong),
+		Height: fmt.Sprintf("%d", uint32(height)),
+	})
+}
+
+//get the state keys updated by block of height and their values before and after block, it requires the node started
+//with state history
+func GetStateDiff(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[0].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	changes, err := bactor.GetStateDiff(uint32(height))
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responseSuccess(nil)
+		}
+		log.Errorf("GetStateDiff, bactor.GetStateDiff error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(bcomn.GetStateDiff(uint32(height), changes))
+}
+
+//get the gas and fee statistics aggregated over the blocks in height range [fromHeight, toHeight]
+func GetGasStats(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	fromHeight, ok1 := params[0].(float64)
+	toHeight, ok2 := params[1].(float64)
+	if !ok1 || !ok2 || fromHeight < 0 || toHeight < fromHeight {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	stats, err := bactor.GetGasStats(uint32(fromHeight), uint32(toHeight))
+	if err != nil {
+		log.Errorf("GetGasStats, bactor.GetGasStats error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(stats)
+}
+
+//get the gas and fee statistics aggregated over the latest blocks
+func GetRecentGasStats(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	blocks, ok := params[0].(float64)
+	if !ok || blocks < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	stats, err := bactor.GetRecentGasStats(uint32(blocks))
+	if err != nil {
+		log.Errorf("GetRecentGasStats, bactor.GetRecentGasStats error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(stats)
+}
+
+//get the checkpoint of height signed by bookkeepers, the latest checkpoint if height is not given
+func GetCheckpoint(params []interface{}) map[string]interface{} {
+	var height float64
+	if len(params) > 0 {
+		var ok bool
+		height, ok = params[0].(float64)
+		if !ok || height < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	checkpoint, err := bactor.GetCheckpoint(uint32(height))
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responseSuccess(nil)
+		}
+		log.Errorf("GetCheckpoint, bactor.GetCheckpoint error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(bcomn.GetCheckpointInfo(checkpoint))
+}
+
 //get allowance
 func GetAllowance(params []interface{}) map[string]interface{} {
 	if len(params) < 3 {
@@ -493,8 +914,8 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 	for _, v := range proof {
 		hashes = append(hashes, v.ToHexString())
 	}
-	return responseSuccess(bcomn.MerkleProof{"MerkleProof", header.TransactionsRoot.ToHexString(), height,
-		curHeader.BlockRoot.ToHexString(), curHeight, hashes})
+	return responseSignedSuccess(bcomn.MerkleProof{"MerkleProof", header.TransactionsRoot.ToHexString(), height,
+		curHeader.BlockRoot.ToHexString(), curHeight, hashes}, curHeight)
 }
 
 //get block transactions by height
@@ -528,6 +949,34 @@ func GetGasPrice(params []interface{}) map[string]interface{} {
 	return responseSuccess(result)
 }
 
+//get the registered features and whether they are active at height, default is the current block height
+func GetFeatures(params []interface{}) map[string]interface{} {
+	height := bactor.GetCurrentBlockHeight()
+	if len(params) >= 1 {
+		h, ok := params[0].(float64)
+		if !ok || h < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		height = uint32(h)
+	}
+	infos := make([]*bcomn.FeatureInfo, 0)
+	for _, feature := range config.GetFeatures() {
+		infos = append(infos, &bcomn.FeatureInfo{
+			Name:             feature.Name,
+			Description:      feature.Description,
+			ActivationHeight: feature.ActivationHeight,
+			Disabled:         feature.Disabled,
+			Active:           feature.IsActive(height),
+		})
+	}
+	return responseSuccess(infos)
+}
+
+//GetRecoverStatus return the progress of re-executing saved blocks at startup
+func GetRecoverStatus(params []interface{}) map[string]interface{} {
+	return responseSuccess(bactor.GetRecoverStatus())
+}
+
 // get unbound ong of address
 func GetUnboundOng(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -588,12 +1037,125 @@ func GetLayer2State(params []interface{}) map[string]interface{} {
 		log.Errorf("GetLayer2State, get block by height from db error:%s", err)
 		return responsePack(berr.INTERNAL_ERROR, "")
 	}
-	return responseSuccess(bcomn.TransferLayer2State(msg, header.Bookkeepers))
+	return responseSignedSuccess(bcomn.TransferLayer2State(msg, header.Bookkeepers), uint32(height))
+}
+
+//get layer2 messages in height range [fromHeight, toHeight], at most MAX_LAYER2_STATE_RANGE heights in one call.
+//The heights without message are skipped, and the range stops before the block whose next header is not saved
+func GetLayer2States(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	fromHeight, ok1 := params[0].(float64)
+	toHeight, ok2 := params[1].(float64)
+	if !ok1 || !ok2 || fromHeight < 0 || toHeight < fromHeight ||
+		toHeight-fromHeight >= float64(bcomn.MAX_LAYER2_STATE_RANGE) {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	msgs, err := bactor.GetLayer2States(uint32(fromHeight), uint32(toHeight))
+	if err != nil {
+		log.Errorf("GetLayer2States, get layer2 state msgs from db error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	states := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		header, err := bactor.GetHeaderByHeight(msg.Height + 1)
+		if err != nil || header == nil {
+			break
+		}
+		states = append(states, bcomn.TransferLayer2State(msg, header.Bookkeepers))
+	}
+	return responseSignedSuccess(states, uint32(toHeight))
+}
+
+//get the leaves of updated account state root of block, and the account entries in verbose mode
+func GetLayer2AccountStates(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[0].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	verbose := false
+	if len(params) >= 2 {
+		v, ok := params[1].(float64)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		verbose = v == 1
+	}
+	states, err := bactor.GetLayer2AccountStates(uint32(height))
+	if err != nil {
+		log.Errorf("GetLayer2AccountStates, bactor.GetLayer2AccountStates error:%s", err)
+		return responsePack(berr.UNKNOWN_BLOCK, "")
+	}
+	info := &bcomn.Layer2AccountStatesInfo{
+		Height: uint32(height),
+		Root:   states.Root.ToHexString(),
+		Leaves: make([]string, 0, len(states.Leaves)),
+	}
+	for _, leaf := range states.Leaves {
+		info.Leaves = append(info.Leaves, leaf.ToHexString())
+	}
+	if verbose {
+		info.Accounts = make([]*bcomn.Layer2AccountInfo, 0, len(states.Accounts))
+		//the leaf is the sparse merkle tree leaf of the account, or the legacy leaf below the account tree height
+		for i, account := range states.Accounts {
+			leaf := common.UINT256_EMPTY
+			if i < len(states.Leaves) {
+				leaf = states.Leaves[i]
+			}
+			info.Accounts = append(info.Accounts, &bcomn.Layer2AccountInfo{
+				Contract: account.Contract.ToHexString(),
+				Address:  account.Address.ToBase58(),
+				Value:    common.ToHexString(account.Value),
+				Leaf:     leaf.ToHexString(),
+			})
+		}
+	}
+	return responseSignedSuccess(info, uint32(height))
+}
+
+//get all the accounts in the account state tree of block and their proofs, for users to withdraw from the committed root
+func GetLayer2AccountSnapshot(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[0].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	snapshot, err := bactor.GetLayer2AccountSnapshot(uint32(height))
+	if err != nil {
+		log.Errorf("GetLayer2AccountSnapshot, bactor.GetLayer2AccountSnapshot error:%s", err)
+		return responsePack(berr.UNKNOWN_BLOCK, "")
+	}
+	info := &bcomn.Layer2AccountSnapshotInfo{
+		Height:   snapshot.Height,
+		Root:     snapshot.Root.ToHexString(),
+		Accounts: make([]*bcomn.Layer2SnapshotAccountInfo, 0, len(snapshot.Accounts)),
+	}
+	for i, account := range snapshot.Accounts {
+		proof, err := bactor.GetLayer2StateProof(snapshot.Height, snapshot.Keys[i])
+		if err != nil {
+			log.Errorf("GetLayer2AccountSnapshot, bactor.GetLayer2StateProof error:%s", err)
+			return responsePack(berr.INTERNAL_ERROR, "")
+		}
+		info.Accounts = append(info.Accounts, &bcomn.Layer2SnapshotAccountInfo{
+			Contract: account.Contract.ToHexString(),
+			Address:  account.Address.ToBase58(),
+			Key:      common.ToHexString(snapshot.Keys[i]),
+			Value:    common.ToHexString(account.Value),
+			Proof:    common.ToHexString(proof),
+		})
+	}
+	return responseSignedSuccess(info, snapshot.Height)
 }
 
 //get layer2 state proof
 func GetLayer2StateProof(params []interface{}) map[string]interface{} {
-	if len(params) < 1 {
+	if len(params) < 2 {
 		return responsePack(berr.INVALID_PARAMS, nil)
 	}
 	height, ok := params[0].(float64)
@@ -604,6 +1166,9 @@ func GetLayer2StateProof(params []interface{}) map[string]interface{} {
 	if !ok {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
+	if len(params) >= 3 {
+		return getStorageStateProof(uint32(height), str, params[2])
+	}
 	key, err := hex.DecodeString(str)
 	if err != nil {
 		return responsePack(berr.INVALID_PARAMS, "")
@@ -613,5 +1178,69 @@ func GetLayer2StateProof(params []interface{}) map[string]interface{} {
 		log.Errorf("GetLayer2StateProof, bactor.GetLayer2StateProof error:%s", err)
 		return responsePack(berr.INTERNAL_ERROR, "")
 	}
-	return responseSuccess(bcomn.Layer2StateProof{"Layer2StateProof", hex.EncodeToString(proof)})
+	return responseSignedSuccess(bcomn.Layer2StateProof{Type: "Layer2StateProof", AuditPath: hex.EncodeToString(proof)},
+		uint32(height))
+}
+
+//get the proof of storage key of contract in the full state merkle patricia trie root of block
+func GetFullStateProof(params []interface{}) map[string]interface{} {
+	if len(params) < 3 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	height, ok := params[0].(float64)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	contractStr, ok := params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	contract, err := common.AddressFromHexString(contractStr)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok := params[2].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	root, proof, err := bactor.GetFullStateProof(uint32(height), contract, key)
+	if err != nil {
+		log.Errorf("GetFullStateProof, bactor.GetFullStateProof error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSignedSuccess(bcomn.Layer2StateProof{
+		Type:      "FullStateProof",
+		AuditPath: hex.EncodeToString(proof),
+		Root:      root.ToHexString(),
+	}, uint32(height))
+}
+
+//get the proof of storage key of contract in the storage state root of block
+func getStorageStateProof(height uint32, contractStr string, keyParam interface{}) map[string]interface{} {
+	contract, err := common.AddressFromHexString(contractStr)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok := keyParam.(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	root, proof, err := bactor.GetStorageStateProof(height, contract, key)
+	if err != nil {
+		log.Errorf("GetLayer2StateProof, bactor.GetStorageStateProof error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSignedSuccess(bcomn.Layer2StateProof{
+		Type:      "StorageStateProof",
+		AuditPath: hex.EncodeToString(proof),
+		Root:      root.ToHexString(),
+	}, height)
 }
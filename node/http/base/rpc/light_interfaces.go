@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rpc
+
+import (
+	"encoding/hex"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/log"
+	bactor "github.com/ontio/layer2/node/http/base/actor"
+	bcomn "github.com/ontio/layer2/node/http/base/common"
+	berr "github.com/ontio/layer2/node/http/base/error"
+)
+
+//get header height of light node
+func GetLightBlockCount(params []interface{}) map[string]interface{} {
+	return responseSuccess(bactor.GetLightCurrentHeight() + 1)
+}
+
+//get block hash by height from light node
+func GetLightBlockHash(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	height, ok := params[0].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	header, err := bactor.GetLightHeaderByHeight(uint32(height))
+	if err != nil {
+		return responsePack(berr.UNKNOWN_BLOCK, "")
+	}
+	hash := header.Hash()
+	return responseSuccess(hash.ToHexString())
+}
+
+//get raw block headers in height range [start, end] from light node, in the same format as full node
+func GetLightBlockHeaders(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	start, ok1 := params[0].(float64)
+	end, ok2 := params[1].(float64)
+	if !ok1 || !ok2 || start < 0 || end < start || end-start >= float64(bcomn.MAX_HEADER_RANGE) {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	raws := make([]string, 0, uint32(end-start)+1)
+	for height := uint32(start); height <= uint32(end); height++ {
+		header, err := bactor.GetLightHeaderByHeight(height)
+		if err != nil {
+			break
+		}
+		raws = append(raws, common.ToHexString(header.ToArray()))
+	}
+	return responseSuccess(raws)
+}
+
+//get layer2 message by height from light node, in the same format as full node
+func GetLightLayer2State(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[0].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	msg, err := bactor.GetLightLayer2State(uint32(height))
+	if err != nil {
+		log.Errorf("GetLightLayer2State, get layer2 state msg from db error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	header, err := bactor.GetLightHeaderByHeight(uint32(height) + 1)
+	if err != nil {
+		log.Errorf("GetLightLayer2State, get header by height from db error:%s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(bcomn.TransferLayer2State(msg, header.Bookkeepers))
+}
+
+//verify the audit path of getlayer2stateproof against the layer2 state root of height saved by light node. The params
+//are height, the hex of account key, account value and audit path, empty value verifies the key is absent
+func VerifyLightLayer2StateProof(params []interface{}) map[string]interface{} {
+	if len(params) < 4 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height, ok := params[0].(float64)
+	if !ok || height < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	var args [3][]byte
+	for i := range args {
+		str, ok := params[i+1].(string)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		data, err := hex.DecodeString(str)
+		if err != nil {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		args[i] = data
+	}
+	key, value, proof := args[0], args[1], args[2]
+	if len(value) == 0 {
+		value = nil
+	}
+	valid, err := bactor.VerifyLightLayer2StateProof(uint32(height), key, value, proof)
+	if err != nil {
+		log.Errorf("VerifyLightLayer2StateProof, verify proof error:%s", err)
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(valid)
+}
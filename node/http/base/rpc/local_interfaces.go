@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 
 	"github.com/ontio/layer2/node/common/log"
+	bactor "github.com/ontio/layer2/node/http/base/actor"
 	berr "github.com/ontio/layer2/node/http/base/error"
 )
 
@@ -53,3 +54,33 @@ func SetDebugInfo(params []interface{}) map[string]interface{} {
 	}
 	return responsePack(berr.SUCCESS, true)
 }
+
+//backup the ledger to the directory without stopping the node
+func BackupLedger(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	dir, ok := params[0].(string)
+	if !ok || dir == "" {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if err := bactor.BackupLedger(dir); err != nil {
+		log.Errorf("BackupLedger error %s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//compact the store databases of ledger, it may take minutes for large database
+func CompactStores(params []interface{}) map[string]interface{} {
+	if err := bactor.CompactStores(); err != nil {
+		log.Errorf("CompactStores error %s", err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//get the progress, counters and recent violations of invariant checker
+func GetInvariantStatus(params []interface{}) map[string]interface{} {
+	return responseSuccess(bactor.GetInvariantStatus())
+}
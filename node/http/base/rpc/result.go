@@ -19,6 +19,8 @@
 package rpc
 
 import (
+	"github.com/ontio/layer2/node/common/log"
+	bcomn "github.com/ontio/layer2/node/http/base/common"
 	Err "github.com/ontio/layer2/node/http/base/error"
 )
 
@@ -33,3 +35,17 @@ func responsePack(errcode int64, result interface{}) map[string]interface{} {
 	}
 	return resp
 }
+
+//responseSignedSuccess attach the signature of result at height if the node sign response
+func responseSignedSuccess(result interface{}, height uint32) map[string]interface{} {
+	resp := responseSuccess(result)
+	sig, err := bcomn.SignResult(result, height)
+	if err != nil {
+		log.Errorf("sign response error %s", err)
+		return responsePack(Err.INTERNAL_ERROR, "")
+	}
+	if sig != nil {
+		resp["signature"] = sig
+	}
+	return resp
+}
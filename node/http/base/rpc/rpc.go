@@ -113,13 +113,17 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 	function, ok := mainMux.m[method]
 	if ok {
 		response := function(request["params"].([]interface{}))
-		data, err := json.Marshal(map[string]interface{}{
+		message := map[string]interface{}{
 			"jsonrpc": "2.0",
 			"error":   response["error"],
 			"desc":    response["desc"],
 			"result":  response["result"],
 			"id":      request["id"],
-		})
+		}
+		if sig, ok := response["signature"]; ok {
+			message["signature"] = sig
+		}
+		data, err := json.Marshal(message)
 		if err != nil {
 			log.Error("HTTP JSON RPC Handle - json.Marshal: ", err)
 			return
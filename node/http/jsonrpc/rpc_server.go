@@ -35,31 +35,69 @@ func StartRPCServer() error {
 
 	rpc.HandleFunc("getbestblockhash", rpc.GetBestBlockHash)
 	rpc.HandleFunc("getblock", rpc.GetBlock)
+	rpc.HandleFunc("getblocksbyheightrange", rpc.GetBlocksByHeightRange)
+	rpc.HandleFunc("getblockheaders", rpc.GetBlockHeaders)
 	rpc.HandleFunc("getblockcount", rpc.GetBlockCount)
 	rpc.HandleFunc("getblockhash", rpc.GetBlockHash)
 	//HandleFunc("getrawmempool", GetRawMemPool)
 
 	rpc.HandleFunc("getrawtransaction", rpc.GetRawTransaction)
+	rpc.HandleFunc("gettransactionsbyaddress", rpc.GetTransactionsByAddress)
 	rpc.HandleFunc("sendrawtransaction", rpc.SendRawTransaction)
 	rpc.HandleFunc("getstorage", rpc.GetStorage)
+	rpc.HandleFunc("getstorageat", rpc.GetStorageAt)
+	rpc.HandleFunc("getstoragesat", rpc.GetStoragesAt)
 	rpc.HandleFunc("getversion", rpc.GetNodeVersion)
 
 	rpc.HandleFunc("getcontractstate", rpc.GetContractState)
 	rpc.HandleFunc("getmempooltxcount", rpc.GetMemPoolTxCount)
 	rpc.HandleFunc("getmempooltxstate", rpc.GetMemPoolTxState)
 	rpc.HandleFunc("getsmartcodeevent", rpc.GetSmartCodeEvent)
+	rpc.HandleFunc("getsmartcodeeventbycontract", rpc.GetSmartCodeEventByContract)
+	rpc.HandleFunc("getsmartcodeeventbyaddress", rpc.GetSmartCodeEventByAddress)
 	rpc.HandleFunc("getblockheightbytxhash", rpc.GetBlockHeightByTxHash)
+	rpc.HandleFunc("getreceipt", rpc.GetReceipt)
 
 	rpc.HandleFunc("getbalance", rpc.GetBalance)
+	rpc.HandleFunc("getbalanceat", rpc.GetBalanceAt)
+	rpc.HandleFunc("getstatediff", rpc.GetStateDiff)
+	rpc.HandleFunc("getcheckpoint", rpc.GetCheckpoint)
 	rpc.HandleFunc("getallowance", rpc.GetAllowance)
 	rpc.HandleFunc("getmerkleproof", rpc.GetMerkleProof)
 	rpc.HandleFunc("getblocktxsbyheight", rpc.GetBlockTxsByHeight)
 	rpc.HandleFunc("getgasprice", rpc.GetGasPrice)
+	rpc.HandleFunc("getgasstats", rpc.GetGasStats)
+	rpc.HandleFunc("getrecentgasstats", rpc.GetRecentGasStats)
+	rpc.HandleFunc("getfeatures", rpc.GetFeatures)
+	rpc.HandleFunc("getrecoverstatus", rpc.GetRecoverStatus)
 	rpc.HandleFunc("getunboundong", rpc.GetUnboundOng)
 	rpc.HandleFunc("getgrantong", rpc.GetGrantOng)
 
 	rpc.HandleFunc("getlayer2state", rpc.GetLayer2State)
+	rpc.HandleFunc("getlayer2states", rpc.GetLayer2States)
 	rpc.HandleFunc("getlayer2stateproof", rpc.GetLayer2StateProof)
+	rpc.HandleFunc("getfullstateproof", rpc.GetFullStateProof)
+	rpc.HandleFunc("getlayer2accountstates", rpc.GetLayer2AccountStates)
+	rpc.HandleFunc("getlayer2accountsnapshot", rpc.GetLayer2AccountSnapshot)
+
+	err := http.ListenAndServe(":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpJsonPort)), nil)
+	if err != nil {
+		return fmt.Errorf("ListenAndServe error:%s", err)
+	}
+	return nil
+}
+
+//StartLightRPCServer start the json rpc server of header-only light node, which serves the header and layer2 state
+//queries and proof verification
+func StartLightRPCServer() error {
+	log.Debug()
+	http.HandleFunc("/", rpc.Handle)
+
+	rpc.HandleFunc("getblockcount", rpc.GetLightBlockCount)
+	rpc.HandleFunc("getblockhash", rpc.GetLightBlockHash)
+	rpc.HandleFunc("getblockheaders", rpc.GetLightBlockHeaders)
+	rpc.HandleFunc("getlayer2state", rpc.GetLightLayer2State)
+	rpc.HandleFunc("verifylayer2stateproof", rpc.VerifyLightLayer2StateProof)
 
 	err := http.ListenAndServe(":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpJsonPort)), nil)
 	if err != nil {
@@ -0,0 +1,210 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package lightsync provides the syncer of header-only light node, which fetches the block headers and layer2 states
+// from the json rpc of upstream node and saves them to light ledger after verification
+package lightsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/log"
+	"github.com/ontio/layer2/node/core/ledger"
+	"github.com/ontio/layer2/node/core/types"
+	bcomn "github.com/ontio/layer2/node/http/base/common"
+	berr "github.com/ontio/layer2/node/http/base/error"
+)
+
+type response struct {
+	Error  int64           `json:"error"`
+	Desc   string          `json:"desc"`
+	Result json.RawMessage `json:"result"`
+}
+
+type Syncer struct {
+	upstream     string
+	ledger       *ledger.LightLedger
+	pollInterval time.Duration
+	client       *http.Client
+	exit         chan struct{}
+	done         chan struct{}
+}
+
+//NewSyncer return syncer of light ledger from the upstream json rpc address, which is polled every pollInterval
+func NewSyncer(upstream string, ldg *ledger.LightLedger, pollInterval time.Duration) *Syncer {
+	return &Syncer{
+		upstream:     upstream,
+		ledger:       ldg,
+		pollInterval: pollInterval,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		exit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+//Start sync in background until closed
+func (this *Syncer) Start() {
+	go this.loop()
+}
+
+//Close stop the sync and wait for the running round
+func (this *Syncer) Close() {
+	close(this.exit)
+	<-this.done
+}
+
+func (this *Syncer) loop() {
+	defer close(this.done)
+	ticker := time.NewTicker(this.pollInterval)
+	defer ticker.Stop()
+	for {
+		err := this.SyncOnce()
+		if err != nil {
+			log.Warnf("light sync error %s", err)
+		}
+		select {
+		case <-this.exit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//SyncOnce sync the headers up to the block before upstream head. The layer2 state of block is served by upstream
+//after the next header saved, so the head block is synced at next round
+func (this *Syncer) SyncOnce() error {
+	var count uint32
+	err := this.call("getblockcount", &count)
+	if err != nil {
+		return err
+	}
+	if count < 2 {
+		return nil
+	}
+	target := count - 2
+	for from := this.ledger.GetCurrentHeight() + 1; from <= target; {
+		to := target
+		if to-from >= bcomn.MAX_HEADER_RANGE {
+			to = from + bcomn.MAX_HEADER_RANGE - 1
+		}
+		headers, err := this.getHeaders(from, to)
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			return fmt.Errorf("upstream has no header at height %d", from)
+		}
+		states, err := this.getLayer2States(from, to)
+		if err != nil {
+			return err
+		}
+		for _, header := range headers {
+			err = this.ledger.AddHeader(header, states[header.Height])
+			if err != nil {
+				return err
+			}
+		}
+		from += uint32(len(headers))
+		log.Debugf("light sync to height %d", from-1)
+	}
+	return nil
+}
+
+func (this *Syncer) getHeaders(from, to uint32) ([]*types.Header, error) {
+	var raws []string
+	err := this.call("getblockheaders", &raws, from, to)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*types.Header, 0, len(raws))
+	for _, raw := range raws {
+		data, err := common.HexToBytes(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decode header error %s", err)
+		}
+		header, err := types.HeaderFromRawBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("deserialize header error %s", err)
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+//getLayer2States return the layer2 states in height range by height, the bookkeepers attached by upstream are ignored
+//since the state is verified by the bookkeepers of header
+func (this *Syncer) getLayer2States(from, to uint32) (map[uint32]*types.Layer2State, error) {
+	var raws []string
+	err := this.call("getlayer2states", &raws, from, to)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[uint32]*types.Layer2State, len(raws))
+	for _, raw := range raws {
+		data, err := common.HexToBytes(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decode layer2 state error %s", err)
+		}
+		state := new(types.Layer2State)
+		err = state.Deserialization(common.NewZeroCopySource(data))
+		if err != nil {
+			return nil, fmt.Errorf("deserialize layer2 state error %s", err)
+		}
+		states[state.Height] = state
+	}
+	return states, nil
+}
+
+func (this *Syncer) call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return err
+	}
+	httpResp, err := this.client.Post(this.upstream, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	resp := &response{}
+	err = json.Unmarshal(body, resp)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal response error %s", err)
+	}
+	if resp.Error != berr.SUCCESS {
+		return fmt.Errorf("%s error %d %s", method, resp.Error, resp.Desc)
+	}
+	return json.Unmarshal(resp.Result, result)
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package lightsync
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ontio/layer2/node/account"
+	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
+	"github.com/ontio/layer2/node/core/genesis"
+	"github.com/ontio/layer2/node/core/ledger"
+	"github.com/ontio/layer2/node/core/signature"
+	"github.com/ontio/layer2/node/core/types"
+	bcomn "github.com/ontio/layer2/node/http/base/common"
+	"github.com/ontio/layer2/node/merkle"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+type testChain struct {
+	lock    sync.Mutex
+	acc     *account.Account
+	tree    *merkle.CompactMerkleTree
+	headers []*types.Header
+	states  []*types.Layer2State
+}
+
+func (this *testChain) addHeader(t *testing.T, signer *account.Account) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	prev := this.headers[len(this.headers)-1]
+	txRoot := common.ComputeMerkleRoot(nil)
+	header := &types.Header{
+		PrevBlockHash:    prev.Hash(),
+		TransactionsRoot: txRoot,
+		BlockRoot:        this.tree.GetRootWithNewLeaf(txRoot),
+		Timestamp:        prev.Timestamp + 1,
+		Height:           prev.Height + 1,
+		NextBookkeeper:   prev.NextBookkeeper,
+		Bookkeepers:      []keypair.PublicKey{this.acc.PublicKey},
+	}
+	hash := header.Hash()
+	sig, err := signature.Sign(signer, hash[:])
+	assert.Nil(t, err)
+	header.SigData = [][]byte{sig}
+	state := &types.Layer2State{Height: header.Height, StatesRoot: header.Hash()}
+	hash = state.Hash()
+	sig, err = signature.Sign(this.acc, hash[:])
+	assert.Nil(t, err)
+	state.SigData = [][]byte{sig}
+	this.tree.AppendHash(txRoot)
+	this.headers = append(this.headers, header)
+	this.states = append(this.states, state)
+}
+
+func (this *testChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	req := &struct {
+		Method string
+		Params []uint32
+	}{}
+	json.NewDecoder(r.Body).Decode(req)
+	var result interface{}
+	switch req.Method {
+	case "getblockcount":
+		result = len(this.headers)
+	case "getblockheaders":
+		raws := []string{}
+		for height := req.Params[0]; height <= req.Params[1] && height < uint32(len(this.headers)); height++ {
+			raws = append(raws, common.ToHexString(this.headers[height].ToArray()))
+		}
+		result = raws
+	case "getlayer2states":
+		raws := []string{}
+		for height := req.Params[0]; height <= req.Params[1] && height+1 < uint32(len(this.headers)); height++ {
+			raws = append(raws, bcomn.TransferLayer2State(this.states[height], this.headers[height+1].Bookkeepers))
+		}
+		result = raws
+	}
+	data, _ := json.Marshal(map[string]interface{}{"error": 0, "desc": "SUCCESS", "result": result})
+	w.Write(data)
+}
+
+func TestSyncer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lightsync")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	solo := config.DefConfig.Genesis.SOLO.Bookkeepers
+	config.DefConfig.Genesis.SOLO.Bookkeepers = []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}
+	defer func() { config.DefConfig.Genesis.SOLO.Bookkeepers = solo }()
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	chain := &testChain{
+		acc:     acc,
+		tree:    merkle.NewTree(0, nil, nil),
+		headers: []*types.Header{genesisBlock.Header},
+		states:  []*types.Layer2State{nil},
+	}
+	chain.tree.AppendHash(genesisBlock.Header.TransactionsRoot)
+	for i := 0; i < 4; i++ {
+		chain.addHeader(t, acc)
+	}
+	upstream := httptest.NewServer(chain)
+	defer upstream.Close()
+
+	ldg, err := ledger.NewLightLedger(dir)
+	assert.Nil(t, err)
+	defer ldg.Close()
+	assert.Nil(t, ldg.Init(genesisBlock))
+	syncer := NewSyncer(upstream.URL, ldg, time.Second)
+
+	//the head block is synced after its next header
+	assert.Nil(t, syncer.SyncOnce())
+	assert.Equal(t, uint32(3), ldg.GetCurrentHeight())
+	chain.addHeader(t, acc)
+	assert.Nil(t, syncer.SyncOnce())
+	assert.Equal(t, uint32(4), ldg.GetCurrentHeight())
+	state, err := ldg.GetLayer2State(4)
+	assert.Nil(t, err)
+	assert.Equal(t, chain.states[4].Hash(), state.Hash())
+
+	//the header not signed by bookkeepers stops the sync
+	chain.addHeader(t, account.NewAccount(""))
+	chain.addHeader(t, acc)
+	assert.NotNil(t, syncer.SyncOnce())
+	assert.Equal(t, uint32(5), ldg.GetCurrentHeight())
+}
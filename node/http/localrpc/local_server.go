@@ -39,6 +39,9 @@ func StartLocalServer() error {
 	http.HandleFunc(LOCAL_DIR, rpc.Handle)
 
 	rpc.HandleFunc("setdebuginfo", rpc.SetDebugInfo)
+	rpc.HandleFunc("compactstores", rpc.CompactStores)
+	rpc.HandleFunc("backupledger", rpc.BackupLedger)
+	rpc.HandleFunc("getinvariantstatus", rpc.GetInvariantStatus)
 
 	// TODO: only listen to local host
 	err := http.ListenAndServe(LOCAL_HOST+":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpLocalPort)), nil)
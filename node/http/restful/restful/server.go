@@ -64,6 +64,9 @@ const (
 	GET_CONTRACT_STATE    = "/api/v1/contract/:hash"
 	GET_SMTCOCE_EVT_TXS   = "/api/v1/smartcode/event/transactions/:height"
 	GET_SMTCOCE_EVTS      = "/api/v1/smartcode/event/txhash/:hash"
+	GET_CONTRACT_EVTS     = "/api/v1/smartcode/event/contract/:addr/:from/:to"
+	GET_ADDRESS_EVTS      = "/api/v1/smartcode/event/address/:addr/:from/:to/:limit"
+	GET_ADDRESS_TXS       = "/api/v1/address/transactions/:addr/:from/:to"
 	GET_BLK_HGT_BY_TXHASH = "/api/v1/block/height/txhash/:hash"
 	GET_MERKLE_PROOF      = "/api/v1/merkleproof/:hash"
 	GET_GAS_PRICE         = "/api/v1/gasprice"
@@ -140,6 +143,9 @@ func (this *restServer) registryMethod() {
 		GET_CONTRACT_STATE:    {name: "getcontract", handler: rest.GetContractState},
 		GET_SMTCOCE_EVT_TXS:   {name: "getsmartcodeeventbyheight", handler: rest.GetSmartCodeEventTxsByHeight},
 		GET_SMTCOCE_EVTS:      {name: "getsmartcodeeventbyhash", handler: rest.GetSmartCodeEventByTxHash},
+		GET_CONTRACT_EVTS:     {name: "getsmartcodeeventbycontract", handler: rest.GetSmartCodeEventByContract},
+		GET_ADDRESS_EVTS:      {name: "getsmartcodeeventbyaddress", handler: rest.GetSmartCodeEventByAddress},
+		GET_ADDRESS_TXS:       {name: "gettransactionsbyaddress", handler: rest.GetTransactionsByAddress},
 		GET_BLK_HGT_BY_TXHASH: {name: "getblockheightbytxhash", handler: rest.GetBlockHeightByTxHash},
 		GET_STORAGE:           {name: "getstorage", handler: rest.GetStorage},
 		GET_BALANCE:           {name: "getbalance", handler: rest.GetBalance},
@@ -178,6 +184,12 @@ func (this *restServer) getPath(url string) string {
 		return GET_SMTCOCE_EVT_TXS
 	} else if strings.Contains(url, strings.TrimRight(GET_SMTCOCE_EVTS, ":hash")) {
 		return GET_SMTCOCE_EVTS
+	} else if strings.Contains(url, strings.TrimRight(GET_CONTRACT_EVTS, ":addr/:from/:to")) {
+		return GET_CONTRACT_EVTS
+	} else if strings.Contains(url, strings.TrimRight(GET_ADDRESS_EVTS, ":addr/:from/:to/:limit")) {
+		return GET_ADDRESS_EVTS
+	} else if strings.Contains(url, strings.TrimRight(GET_ADDRESS_TXS, ":addr/:from/:to")) {
+		return GET_ADDRESS_TXS
 	} else if strings.Contains(url, strings.TrimRight(GET_BLK_HGT_BY_TXHASH, ":hash")) {
 		return GET_BLK_HGT_BY_TXHASH
 	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE, ":hash/:key")) {
@@ -198,6 +210,14 @@ func (this *restServer) getPath(url string) string {
 	return url
 }
 
+//setEventPageParams set the limit and cursor query of paged event query, request without cursor is not paged
+func setEventPageParams(r *http.Request, req map[string]interface{}) {
+	query := r.URL.Query()
+	if cursor, ok := query["cursor"]; ok {
+		req["Cursor"], req["Limit"] = cursor[0], query.Get("limit")
+	}
+}
+
 //get request params
 func (this *restServer) getParams(r *http.Request, url string, req map[string]interface{}) map[string]interface{} {
 	switch url {
@@ -220,8 +240,24 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 		req["Hash"], req["Key"] = getParam(r, "hash"), getParam(r, "key")
 	case GET_SMTCOCE_EVT_TXS:
 		req["Height"] = getParam(r, "height")
+		setEventPageParams(r, req)
 	case GET_SMTCOCE_EVTS:
 		req["Hash"] = getParam(r, "hash")
+	case GET_CONTRACT_EVTS:
+		req["Addr"] = getParam(r, "addr")
+		req["From"], req["To"] = getParam(r, "from"), getParam(r, "to")
+		setEventPageParams(r, req)
+	case GET_ADDRESS_EVTS:
+		req["Addr"] = getParam(r, "addr")
+		req["From"], req["To"] = getParam(r, "from"), getParam(r, "to")
+		req["Limit"] = getParam(r, "limit")
+		if cursor, ok := r.URL.Query()["cursor"]; ok {
+			req["Cursor"] = cursor[0]
+		}
+	case GET_ADDRESS_TXS:
+		req["Addr"] = getParam(r, "addr")
+		req["From"], req["To"] = getParam(r, "from"), getParam(r, "to")
+		req["Limit"], req["Cursor"] = r.FormValue("limit"), r.FormValue("cursor")
 	case GET_BLK_HGT_BY_TXHASH:
 		req["Hash"] = getParam(r, "hash")
 	case GET_BALANCE:
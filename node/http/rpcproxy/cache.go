@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rpcproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+//cache scope of rpc response
+const (
+	SCOPE_NONE      = iota //always forward to upstream
+	SCOPE_HEAD             //valid until the next block
+	SCOPE_IMMUTABLE        //never change once available
+)
+
+//Response is the cached json rpc response without request id
+type Response struct {
+	Error  int64           `json:"error"`
+	Desc   string          `json:"desc"`
+	Result    json.RawMessage `json:"result"`
+	Signature json.RawMessage `json:"signature,omitempty"`
+}
+
+//CacheStats is the counters of response cache
+type CacheStats struct {
+	Height    uint32
+	Immutable int
+	Head      int
+	Hits      uint64
+	Misses    uint64
+}
+
+//ResponseCache keep immutable responses until evicted, and head responses until the block height changed
+type ResponseCache struct {
+	lock      sync.RWMutex
+	immutable *lru.ARCCache
+	head      *lru.Cache
+	height    uint32
+	epoch     uint64
+	hits      uint64
+	misses    uint64
+}
+
+//NewResponseCache return ResponseCache with at most size responses of each scope
+func NewResponseCache(size int) (*ResponseCache, error) {
+	immutable, err := lru.NewARC(size)
+	if err != nil {
+		return nil, fmt.Errorf("NewARC error %s", err)
+	}
+	head, err := lru.New(size)
+	if err != nil {
+		return nil, fmt.Errorf("lru.New error %s", err)
+	}
+	return &ResponseCache{immutable: immutable, head: head}, nil
+}
+
+//Get return the cached response of key
+func (this *ResponseCache) Get(key string) (*Response, bool) {
+	value, ok := this.immutable.Get(key)
+	if !ok {
+		this.lock.RLock()
+		value, ok = this.head.Get(key)
+		this.lock.RUnlock()
+	}
+	if !ok {
+		atomic.AddUint64(&this.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&this.hits, 1)
+	return value.(*Response), true
+}
+
+//Epoch return the block height and the epoch which increase at every height change,
+//response fetched in an old epoch is dropped by Add
+func (this *ResponseCache) Epoch() (uint32, uint64) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.height, this.epoch
+}
+
+//Add response of the scope fetched in epoch to cache
+func (this *ResponseCache) Add(key string, resp *Response, scope int, epoch uint64) {
+	switch scope {
+	case SCOPE_IMMUTABLE:
+		this.immutable.Add(key, resp)
+	case SCOPE_HEAD:
+		this.lock.Lock()
+		if epoch == this.epoch {
+			this.head.Add(key, resp)
+		}
+		this.lock.Unlock()
+	}
+}
+
+//SetHeight purge the head responses if height changed
+func (this *ResponseCache) SetHeight(height uint32) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if height == this.height && this.epoch != 0 {
+		return false
+	}
+	this.height = height
+	this.epoch++
+	this.head.Purge()
+	return true
+}
+
+//Stats return the counters of cache
+func (this *ResponseCache) Stats() CacheStats {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return CacheStats{
+		Height:    this.height,
+		Immutable: this.immutable.Len(),
+		Head:      this.head.Len(),
+		Hits:      atomic.LoadUint64(&this.hits),
+		Misses:    atomic.LoadUint64(&this.misses),
+	}
+}
@@ -0,0 +1,353 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package rpcproxy provides a read-only json rpc proxy which caches the responses of the upstream node
+package rpcproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ontio/layer2/node/common/log"
+	bcomn "github.com/ontio/layer2/node/http/base/common"
+	berr "github.com/ontio/layer2/node/http/base/error"
+)
+
+//methodScopes is the cache scope of successful response of the json rpc methods served by the proxy, methods not
+//listed are rejected. Responses of immutable methods are only cached when the result is not empty, since the block
+//or transaction may be not available yet
+var methodScopes = map[string]int{
+	"getblock":                    SCOPE_IMMUTABLE,
+	"getblockhash":                SCOPE_IMMUTABLE,
+	"getblocksbyheightrange":      SCOPE_IMMUTABLE,
+	"getblockheaders":             SCOPE_IMMUTABLE,
+	"getblocktxsbyheight":         SCOPE_IMMUTABLE,
+	"getrawtransaction":           SCOPE_IMMUTABLE,
+	"getblockheightbytxhash":      SCOPE_IMMUTABLE,
+	"getreceipt":                  SCOPE_IMMUTABLE,
+	"getsmartcodeevent":           SCOPE_IMMUTABLE,
+	"getlayer2state":              SCOPE_IMMUTABLE,
+	"getlayer2states":             SCOPE_IMMUTABLE,
+	"getstorageat":                SCOPE_IMMUTABLE,
+	"getstoragesat":               SCOPE_IMMUTABLE,
+	"getbalanceat":                SCOPE_IMMUTABLE,
+	"getstatediff":                SCOPE_IMMUTABLE,
+	"getgasstats":                 SCOPE_IMMUTABLE,
+	"getlayer2stateproof":         SCOPE_IMMUTABLE,
+	"getfullstateproof":           SCOPE_IMMUTABLE,
+	"getlayer2accountstates":      SCOPE_IMMUTABLE,
+	"getlayer2accountsnapshot":    SCOPE_IMMUTABLE,
+	"getsmartcodeeventbycontract": SCOPE_IMMUTABLE,
+	"getsmartcodeeventbyaddress":  SCOPE_IMMUTABLE,
+	"gettransactionsbyaddress":    SCOPE_IMMUTABLE,
+	"getblockcount":               SCOPE_HEAD,
+	"getbestblockhash":            SCOPE_HEAD,
+	"getstorage":                  SCOPE_HEAD,
+	"getcontractstate":            SCOPE_HEAD,
+	"getbalance":                  SCOPE_HEAD,
+	"getallowance":                SCOPE_HEAD,
+	"getmerkleproof":              SCOPE_HEAD,
+	"getgasprice":                 SCOPE_HEAD,
+	"getrecentgasstats":           SCOPE_HEAD,
+	"getfeatures":                 SCOPE_HEAD,
+	"getrecoverstatus":            SCOPE_HEAD,
+	"getunboundong":               SCOPE_HEAD,
+	"getgrantong":                 SCOPE_HEAD,
+	"getversion":                  SCOPE_HEAD,
+	"getcheckpoint":               SCOPE_HEAD,
+	"getmempooltxcount":           SCOPE_NONE,
+	"getmempooltxstate":           SCOPE_NONE,
+	"getrawmempool":               SCOPE_NONE,
+	"verifylayer2stateproof":      SCOPE_NONE,
+}
+
+type request struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	Id     interface{}   `json:"id"`
+}
+
+type ProxyServer struct {
+	upstream     string
+	pollInterval time.Duration
+	cache        *ResponseCache
+	client       *http.Client
+	httpSvr      *http.Server
+	exit         chan struct{}
+}
+
+//NewProxyServer return proxy of the upstream json rpc address, cache at most cacheSize responses of each scope
+func NewProxyServer(upstream string, cacheSize int, pollInterval time.Duration) (*ProxyServer, error) {
+	cache, err := NewResponseCache(cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ProxyServer{
+		upstream:     upstream,
+		pollInterval: pollInterval,
+		cache:        cache,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		exit:         make(chan struct{}),
+	}, nil
+}
+
+//Start serve the proxy on address, block until the server closed
+func (this *ProxyServer) Start(address string) error {
+	err := this.updateHeight()
+	if err != nil {
+		return fmt.Errorf("get upstream block height error %s", err)
+	}
+	go this.pollHeight()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", this.Handle)
+	mux.HandleFunc("/stats", this.HandleStats)
+	this.httpSvr = &http.Server{Addr: address, Handler: mux}
+	err = this.httpSvr.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("ListenAndServe error %s", err)
+	}
+	return nil
+}
+
+func (this *ProxyServer) Close() {
+	close(this.exit)
+	if this.httpSvr != nil {
+		err := this.httpSvr.Close()
+		if err != nil {
+			log.Errorf("proxy server close error %s", err)
+		}
+	}
+}
+
+//pollHeight purge the head responses when upstream block height changed
+func (this *ProxyServer) pollHeight() {
+	ticker := time.NewTicker(this.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this.exit:
+			return
+		case <-ticker.C:
+			err := this.updateHeight()
+			if err != nil {
+				log.Warnf("proxy update block height error %s", err)
+			}
+		}
+	}
+}
+
+func (this *ProxyServer) updateHeight() error {
+	resp, err := this.call(&request{Method: "getblockcount", Params: []interface{}{}, Id: 1})
+	if err != nil {
+		return err
+	}
+	if resp.Error != berr.SUCCESS {
+		return fmt.Errorf("getblockcount error %d %s", resp.Error, resp.Desc)
+	}
+	var count uint32
+	err = json.Unmarshal(resp.Result, &count)
+	if err != nil || count == 0 {
+		return fmt.Errorf("invalid block count %s", resp.Result)
+	}
+	if this.cache.SetHeight(count - 1) {
+		log.Debugf("proxy block height %d", count-1)
+	}
+	return nil
+}
+
+//Handle serve the json rpc request from cache or upstream
+func (this *ProxyServer) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("content-type", "application/json;charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if r.Method != http.MethodPost || r.Body == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	defer r.Body.Close()
+	req := &request{}
+	err := json.NewDecoder(io.LimitReader(r.Body, bcomn.MAX_REQUEST_BODY_SIZE)).Decode(req)
+	if err != nil || req.Method == "" {
+		log.Debugf("proxy decode request error %v", err)
+		writeResponse(w, req.Id, &Response{Error: berr.INVALID_PARAMS, Desc: berr.ErrMap[berr.INVALID_PARAMS]})
+		return
+	}
+	scope, allowed := methodScopes[req.Method]
+	if !allowed {
+		writeResponse(w, req.Id, &Response{Error: berr.INVALID_METHOD, Desc: "read-only proxy"})
+		return
+	}
+	if req.Params == nil {
+		req.Params = []interface{}{}
+	}
+	key, err := cacheKey(req)
+	if err != nil {
+		writeResponse(w, req.Id, &Response{Error: berr.INVALID_PARAMS, Desc: berr.ErrMap[berr.INVALID_PARAMS]})
+		return
+	}
+	cacheable := scope != SCOPE_NONE
+	if cacheable {
+		if resp, ok := this.cache.Get(key); ok {
+			writeResponse(w, req.Id, resp)
+			return
+		}
+	}
+	height, epoch := this.cache.Epoch()
+	resp, err := this.call(req)
+	if err != nil {
+		log.Warnf("proxy call %s error %s", req.Method, err)
+		writeResponse(w, req.Id, &Response{Error: berr.INTERNAL_ERROR, Desc: berr.ErrMap[berr.INTERNAL_ERROR]})
+		return
+	}
+	if cacheable {
+		this.cache.Add(key, resp, responseScope(scope, req, resp, height), epoch)
+	}
+	writeResponse(w, req.Id, resp)
+}
+
+//HandleStats return the cache stats
+func (this *ProxyServer) HandleStats(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(this.cache.Stats())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json;charset=utf-8")
+	w.Write(data)
+}
+
+//call forward the request to upstream
+func (this *ProxyServer) call(req *request) (*Response, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  req.Method,
+		"params":  req.Params,
+		"id":      req.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := this.client.Post(this.upstream, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp := &Response{}
+	err = json.Unmarshal(body, resp)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal response error %s", err)
+	}
+	return resp, nil
+}
+
+//responseScope downgrade the scope of method by the response. Failed response is not cached, empty result
+//of immutable method may be available at the next block, and event or transaction query to unreached height
+//will get more later
+func responseScope(scope int, req *request, resp *Response, height uint32) int {
+	if resp.Error != berr.SUCCESS {
+		return SCOPE_NONE
+	}
+	if scope != SCOPE_IMMUTABLE {
+		return scope
+	}
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return SCOPE_HEAD
+	}
+	switch req.Method {
+	case "getsmartcodeeventbycontract", "getsmartcodeeventbyaddress", "gettransactionsbyaddress":
+		if len(req.Params) < 3 {
+			return SCOPE_NONE
+		}
+		toHeight, ok := req.Params[2].(float64)
+		if !ok || toHeight > float64(height) {
+			return SCOPE_HEAD
+		}
+	case "getblocksbyheightrange", "getblockheaders", "getgasstats":
+		if len(req.Params) < 2 {
+			return SCOPE_NONE
+		}
+		end, ok := req.Params[1].(float64)
+		if !ok || end > float64(height) {
+			return SCOPE_HEAD
+		}
+	case "getstorageat", "getstoragesat", "getbalanceat", "getstatediff":
+		//the state at height is immutable once the block is saved
+		last := len(req.Params) - 1
+		if last < 0 {
+			return SCOPE_NONE
+		}
+		atHeight, ok := req.Params[last].(float64)
+		if !ok || atHeight > float64(height) {
+			return SCOPE_HEAD
+		}
+	case "getlayer2states":
+		//the message of height is returned with the bookkeepers of next header
+		if len(req.Params) < 2 {
+			return SCOPE_NONE
+		}
+		toHeight, ok := req.Params[1].(float64)
+		if !ok || toHeight >= float64(height) {
+			return SCOPE_HEAD
+		}
+	}
+	return SCOPE_IMMUTABLE
+}
+
+func cacheKey(req *request) (string, error) {
+	params, err := json.Marshal(req.Params)
+	if err != nil {
+		return "", err
+	}
+	return req.Method + string(params), nil
+}
+
+func writeResponse(w http.ResponseWriter, id interface{}, resp *Response) {
+	result := resp.Result
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+	message := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"error":   resp.Error,
+		"desc":    resp.Desc,
+		"result":  result,
+		"id":      id,
+	}
+	if len(resp.Signature) != 0 {
+		message["signature"] = resp.Signature
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Errorf("proxy json.Marshal response error %s", err)
+		return
+	}
+	w.Write(data)
+}
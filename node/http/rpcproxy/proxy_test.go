@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rpcproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	berr "github.com/ontio/layer2/node/http/base/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyCache(t *testing.T) {
+	var count uint32 = 10
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		json.NewDecoder(r.Body).Decode(req)
+		if req.Method != "getblockcount" {
+			atomic.AddInt32(&calls, 1)
+		}
+		var result interface{}
+		switch req.Method {
+		case "getblockcount":
+			result = atomic.LoadUint32(&count)
+		case "getblock":
+			if req.Params[0].(float64) < float64(atomic.LoadUint32(&count)) {
+				result = "block"
+			}
+		case "getbalance":
+			result = atomic.LoadUint32(&count)
+		case "getsmartcodeeventbycontract":
+			result = []string{}
+		}
+		data, _ := json.Marshal(map[string]interface{}{"error": 0, "desc": "SUCCESS", "result": result, "id": req.Id})
+		w.Write(data)
+	}))
+	defer upstream.Close()
+
+	proxy, err := NewProxyServer(upstream.URL, 100, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, proxy.updateHeight())
+	call := func(method string, params ...interface{}) *Response {
+		data, _ := json.Marshal(&request{Method: method, Params: params, Id: 7})
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data)))
+		resp := &Response{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), resp))
+		return resp
+	}
+
+	// immutable block is cached, unavailable block is not cached after the next block
+	assert.Equal(t, `"block"`, string(call("getblock", 9).Result))
+	assert.Equal(t, `"block"`, string(call("getblock", 9).Result))
+	assert.Equal(t, "null", string(call("getblock", 10).Result))
+	assert.Equal(t, "null", string(call("getblock", 10).Result))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	assert.Equal(t, "10", string(call("getbalance", "addr").Result))
+	call("getsmartcodeeventbycontract", "addr", 0, 9)
+	call("getsmartcodeeventbycontract", "addr", 0, 20)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	atomic.StoreUint32(&count, 11)
+	assert.Nil(t, proxy.updateHeight())
+	assert.Equal(t, `"block"`, string(call("getblock", 10).Result))
+	assert.Equal(t, "11", string(call("getbalance", "addr").Result))
+	call("getblock", 9)
+	call("getsmartcodeeventbycontract", "addr", 0, 9)
+	call("getsmartcodeeventbycontract", "addr", 0, 20)
+	assert.Equal(t, int32(8), atomic.LoadInt32(&calls))
+
+	// methods not listed are rejected without calling upstream, uncached methods are always forwarded
+	assert.Equal(t, berr.INVALID_METHOD, call("sendrawtransaction", "00").Error)
+	assert.Equal(t, berr.INVALID_METHOD, call("unknownmethod").Error)
+	assert.Equal(t, int32(8), atomic.LoadInt32(&calls))
+	call("getmempooltxcount")
+	call("getmempooltxcount")
+	assert.Equal(t, int32(10), atomic.LoadInt32(&calls))
+
+	stats := proxy.cache.Stats()
+	assert.Equal(t, uint32(10), stats.Height)
+	assert.Equal(t, 3, stats.Immutable)
+}
@@ -20,6 +20,8 @@
 package websocket
 
 import (
+	"context"
+
 	"github.com/ontio/layer2/node/common"
 	cfg "github.com/ontio/layer2/node/common/config"
 	"github.com/ontio/layer2/node/common/log"
@@ -35,20 +37,27 @@ import (
 
 var ws *websocket.WsServer
 
+//sendFunc deliver the message to subscribers, used by both broadcast and replay
+type sendFunc func(contractAddrs map[string]bool, sub int, cur *websocket.Cursor, resp map[string]interface{})
+
 func StartServer() {
 	bactor.SubscribeEvent(message.TOPIC_SAVE_BLOCK_COMPLETE, sendBlock2WSclient)
 	bactor.SubscribeEvent(message.TOPIC_SMART_CODE_EVENT, pushSmartCodeEvent)
 	go func() {
 		ws = websocket.InitWsServer()
+		ws.SetReplayHandler(replaySubscription)
 		ws.Start()
 	}()
 }
 func sendBlock2WSclient(v interface{}) {
-	if cfg.DefConfig.Ws.HttpWsPort != 0 {
-		go func() {
-			pushBlock(v)
-			pushBlockTransactions(v)
-		}()
+	if cfg.DefConfig.Ws.HttpWsPort != 0 && ws != nil {
+		block, ok := v.(types.Block)
+		if !ok {
+			return
+		}
+		ws.Dispatch(func() {
+			pushBlockMessages(&block, ws.BroadcastToSubscribers)
+		})
 	}
 }
 func Stop() {
@@ -60,6 +69,7 @@ func Stop() {
 func ReStartServer() {
 	if ws == nil {
 		ws = websocket.InitWsServer()
+		ws.SetReplayHandler(replaySubscription)
 		ws.Start()
 		return
 	}
@@ -75,54 +85,85 @@ func pushSmartCodeEvent(v interface{}) {
 		log.Errorf("[PushSmartCodeEvent]", "SmartCodeEvent err")
 		return
 	}
-	go func() {
+	ws.Dispatch(func() {
 		switch object := rs.Result.(type) {
 		case *event.LogEventArgs:
 			contractAddrs, evts := bcomn.GetLogEvent(object)
-			pushEvent(contractAddrs, rs.TxHash.ToHexString(), rs.Error, rs.Action, evts)
+			resp := eventResp(rs.Error, rs.Action, evts)
+			ws.PushTxResult(contractAddrs, rs.TxHash.ToHexString(), resp)
+			ws.BroadcastToSubscribers(contractAddrs, websocket.WSTOPIC_EVENT, nil, resp)
 		case *event.ExecuteNotify:
+			// notify of committed block is broadcast with the block messages to keep them in order
 			contractAddrs, notify := bcomn.GetExecuteNotify(object)
-			pushEvent(contractAddrs, rs.TxHash.ToHexString(), rs.Error, rs.Action, notify)
+			ws.PushTxResult(contractAddrs, rs.TxHash.ToHexString(), eventResp(rs.Error, rs.Action, notify))
 		default:
 		}
-	}()
+	})
 }
 
-func pushEvent(contractAddrs map[string]bool, txHash string, errcode int64, action string, result interface{}) {
-	if ws != nil {
-		resp := rest.ResponsePack(Err.SUCCESS)
-		resp["Result"] = result
-		resp["Error"] = errcode
-		resp["Action"] = action
-		resp["Desc"] = Err.ErrMap[resp["Error"].(int64)]
-		ws.PushTxResult(contractAddrs, txHash, resp)
-		ws.BroadcastToSubscribers(contractAddrs, websocket.WSTOPIC_EVENT, resp)
-	}
+func eventResp(errcode int64, action string, result interface{}) map[string]interface{} {
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Result"] = result
+	resp["Error"] = errcode
+	resp["Action"] = action
+	resp["Desc"] = Err.ErrMap[resp["Error"].(int64)]
+	return resp
 }
 
-func pushBlock(v interface{}) {
-	if ws == nil {
-		return
-	}
+func withCursor(resp map[string]interface{}, cur *websocket.Cursor) map[string]interface{} {
+	resp["Cursor"] = cur
+	return resp
+}
+
+//pushBlockMessages send the block, tx hashes and notify events of the block in cursor order
+func pushBlockMessages(block *types.Block, send sendFunc) {
+	height := block.Header.Height
+	cur := &websocket.Cursor{Height: height, Seq: websocket.SEQ_RAW_BLOCK}
 	resp := rest.ResponsePack(Err.SUCCESS)
-	if block, ok := v.(types.Block); ok {
-		resp["Action"] = "sendrawblock"
-		resp["Result"] = common.ToHexString(block.ToArray())
-		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_RAW_BLOCK, resp)
+	resp["Action"] = "sendrawblock"
+	resp["Result"] = common.ToHexString(block.ToArray())
+	send(nil, websocket.WSTOPIC_RAW_BLOCK, cur, withCursor(resp, cur))
+
+	cur = &websocket.Cursor{Height: height, Seq: websocket.SEQ_JSON_BLOCK}
+	resp = rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendjsonblock"
+	resp["Result"] = bcomn.GetBlockInfo(block)
+	send(nil, websocket.WSTOPIC_JSON_BLOCK, cur, withCursor(resp, cur))
+
+	cur = &websocket.Cursor{Height: height, Seq: websocket.SEQ_TXHASHS}
+	resp = rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendblocktxhashs"
+	resp["Result"] = bcomn.GetBlockTransactions(block)
+	send(nil, websocket.WSTOPIC_TXHASHS, cur, withCursor(resp, cur))
 
-		resp["Action"] = "sendjsonblock"
-		resp["Result"] = bcomn.GetBlockInfo(&block)
-		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_JSON_BLOCK, resp)
+	notifies, err := bactor.GetEventNotifyByHeight(height)
+	if err != nil {
+		log.Errorf("[pushBlockMessages] get event notify of height %d error %s", height, err)
+		return
+	}
+	for i, notify := range notifies {
+		cur = &websocket.Cursor{Height: height, Seq: websocket.SEQ_EVENT_BASE + uint32(i)}
+		contractAddrs, evts := bcomn.GetExecuteNotify(notify)
+		resp = eventResp(Err.SUCCESS, event.EVENT_NOTIFY, evts)
+		send(contractAddrs, websocket.WSTOPIC_EVENT, cur, withCursor(resp, cur))
 	}
 }
-func pushBlockTransactions(v interface{}) {
-	if ws == nil {
-		return
+
+//replaySubscription push the messages of committed blocks after the cursor to the session until ctx is canceled
+func replaySubscription(ctx context.Context, sessionId string, from websocket.Cursor) {
+	send := func(contractAddrs map[string]bool, sub int, cur *websocket.Cursor, resp map[string]interface{}) {
+		ws.SendToSubscriber(sessionId, contractAddrs, sub, cur, resp)
 	}
-	resp := rest.ResponsePack(Err.SUCCESS)
-	if block, ok := v.(types.Block); ok {
-		resp["Result"] = bcomn.GetBlockTransactions(&block)
-		resp["Action"] = "sendblocktxhashs"
-		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_TXHASHS, resp)
+	current := bactor.GetCurrentBlockHeight()
+	for height := from.Height; height <= current; height++ {
+		if ctx.Err() != nil {
+			return
+		}
+		block, err := bactor.GetBlockByHeight(height)
+		if err != nil || block == nil {
+			log.Errorf("[replaySubscription] get block of height %d error %v", height, err)
+			return
+		}
+		pushBlockMessages(block, send)
 	}
 }
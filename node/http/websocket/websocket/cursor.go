@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package websocket
+
+//sequence of every pushed message inside one block, events follow the block messages in tx order
+const (
+	SEQ_RAW_BLOCK  uint32 = 0
+	SEQ_JSON_BLOCK uint32 = 1
+	SEQ_TXHASHS    uint32 = 2
+	SEQ_EVENT_BASE uint32 = 3
+)
+
+//max blocks can be replayed for one subscribe request
+const MAX_REPLAY_BLOCKS uint32 = 10000
+
+//Cursor identify a pushed message by block height and sequence in the block
+type Cursor struct {
+	Height uint32 `json:"Height"`
+	Seq    uint32 `json:"Seq"`
+}
+
+//After return whether the cursor is later than other
+func (self Cursor) After(other Cursor) bool {
+	if self.Height != other.Height {
+		return self.Height > other.Height
+	}
+	return self.Seq > other.Seq
+}
@@ -23,6 +23,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"math"
 	"net"
 	"net/http"
 	"strconv"
@@ -33,6 +34,7 @@ import (
 	"github.com/ontio/layer2/node/common"
 	cfg "github.com/ontio/layer2/node/common/config"
 	"github.com/ontio/layer2/node/common/log"
+	bactor "github.com/ontio/layer2/node/http/base/actor"
 	Err "github.com/ontio/layer2/node/http/base/error"
 	"github.com/ontio/layer2/node/http/base/rest"
 	"github.com/ontio/layer2/node/http/websocket/session"
@@ -45,10 +47,17 @@ const (
 	WSTOPIC_TXHASHS    = 4
 )
 
+const PUSH_QUEUE_SIZE = 1024
+
+//max live pushes held for one subscriber during the replay, the replay catches up from the cursor after they are
+//dropped
+const MAX_PENDING_PUSHES = 1024
+
 type handler func(map[string]interface{}) map[string]interface{}
 type Handler struct {
-	handler  handler
-	pushFlag bool
+	handler   handler
+	pushFlag  bool
+	afterSend func(map[string]interface{}) //called after the response has been sent to client
 }
 
 //subscribe event for client
@@ -58,7 +67,55 @@ type subscribe struct {
 	SubscribeJsonBlock    bool     `json:"SubscribeJsonBlock"`
 	SubscribeRawBlock     bool     `json:"SubscribeRawBlock"`
 	SubscribeBlockTxHashs bool     `json:"SubscribeBlockTxHashs"`
+	Cursor                *Cursor  `json:"Cursor,omitempty"` //last delivered message, nil if nothing delivered
+	replayFrom            *Cursor
+	replaying             bool //live pushes are held in pending until the replay finished
+	replayId              uint64
+	cancelReplay          context.CancelFunc
+	pending               []pendingPush
+	pendingDropped        bool //pending overflowed, the replay runs again from the cursor instead of flushing it
+}
+
+//pendingPush is a live message held back while the subscription is replaying
+type pendingPush struct {
+	contractAddrs map[string]bool
+	sub           int
+	cur           Cursor
+	data          []byte
+}
+
+//match return whether the message of topic should be sent to the subscriber
+func (self *subscribe) match(contractAddrs map[string]bool, sub int) bool {
+	switch sub {
+	case WSTOPIC_JSON_BLOCK:
+		return self.SubscribeJsonBlock
+	case WSTOPIC_RAW_BLOCK:
+		return self.SubscribeRawBlock
+	case WSTOPIC_TXHASHS:
+		return self.SubscribeBlockTxHashs
+	case WSTOPIC_EVENT:
+		if !self.SubscribeEvent {
+			return false
+		}
+		if len(self.ContractsFilter) == 0 {
+			return true
+		}
+		for _, addr := range self.ContractsFilter {
+			if contractAddrs[addr] {
+				return true
+			}
+		}
+	}
+	return false
 }
+
+//currentBlockHeight return the height of the ledger, which the replay cursor is checked against
+var currentBlockHeight = bactor.GetCurrentBlockHeight
+
+//ReplayHandler push the messages after cursor to the session, it returns early when ctx is canceled by the
+//close or the re-subscribe of the session
+type ReplayHandler func(ctx context.Context, sessionId string, from Cursor)
+
 type WsServer struct {
 	sync.RWMutex
 	Upgrader     websocket.Upgrader
@@ -68,6 +125,9 @@ type WsServer struct {
 	ActionMap    map[string]Handler   //handler functions
 	TxHashMap    map[string]string    //key: txHash   value:sessionid
 	SubscribeMap map[string]subscribe //key: sessionId   value:subscribeInfo
+	pushQueue    chan func()          //push tasks run one by one to keep messages in order
+	replay       ReplayHandler
+	replayCount  uint64
 }
 
 //init websocket server
@@ -77,10 +137,28 @@ func InitWsServer() *WsServer {
 		SessionList:  session.NewSessionList(),
 		TxHashMap:    make(map[string]string),
 		SubscribeMap: make(map[string]subscribe),
+		pushQueue:    make(chan func(), PUSH_QUEUE_SIZE),
 	}
+	go ws.dispatchLoop()
 	return ws
 }
 
+//SetReplayHandler set the handler used to resume subscription from client cursor
+func (self *WsServer) SetReplayHandler(replay ReplayHandler) {
+	self.replay = replay
+}
+
+//Dispatch queue the push task, tasks are executed in the order they are dispatched
+func (self *WsServer) Dispatch(task func()) {
+	self.pushQueue <- task
+}
+
+func (self *WsServer) dispatchLoop() {
+	for task := range self.pushQueue {
+		task()
+	}
+}
+
 //start websocket server
 func (self *WsServer) Start() error {
 	wsPort := int(cfg.DefConfig.Ws.HttpWsPort)
@@ -165,6 +243,26 @@ func (self *WsServer) registryMethod() {
 				}
 			}
 		}
+		if _, ok := cmd["FromHeight"]; ok {
+			from, ok := parseReplayCursor(cmd, currentBlockHeight)
+			if !ok {
+				resp = rest.ResponsePack(Err.INVALID_PARAMS)
+				resp["Action"] = "subscribe"
+				return resp
+			}
+			// the replay of the former subscribe is replaced, the pushes it held are kept and filtered by the cursor
+			if sub.cancelReplay != nil {
+				sub.cancelReplay()
+				sub.cancelReplay = nil
+			}
+			sub.replayId = 0
+			// client has seen the message at cursor, so only later messages will be delivered.
+			// live pushes are held until the replay finished, otherwise they would move the
+			// cursor past the messages being replayed
+			sub.Cursor = from
+			sub.replayFrom = from
+			sub.replaying = true
+		}
 		self.SubscribeMap[sessionId] = sub
 
 		resp["Action"] = "subscribe"
@@ -190,7 +288,7 @@ func (self *WsServer) registryMethod() {
 		"gettransaction":            {handler: rest.GetTransactionByHash},
 		"sendrawtransaction":        {handler: rest.SendRawTransaction, pushFlag: true},
 		"heartbeat":                 {handler: heartbeat},
-		"subscribe":                 {handler: subscribe},
+		"subscribe":                 {handler: subscribe, afterSend: self.replaySubscription},
 		"getstorage":                {handler: rest.GetStorage},
 		"getallowance":              {handler: rest.GetAllowance},
 		"getmerkleproof":            {handler: rest.GetMerkleProof},
@@ -207,6 +305,105 @@ func (self *WsServer) registryMethod() {
 	self.ActionMap = actionMap
 }
 
+//parseReplayCursor return the cursor of FromHeight and FromSeq, which must be uint32 values and at most
+//MAX_REPLAY_BLOCKS behind the current height
+func parseReplayCursor(cmd map[string]interface{}, currentHeight func() uint32) (*Cursor, bool) {
+	height, ok := cmd["FromHeight"].(float64)
+	if !ok || !isUint32(height) {
+		return nil, false
+	}
+	from := &Cursor{Height: uint32(height)}
+	if value, ok := cmd["FromSeq"]; ok {
+		seq, ok := value.(float64)
+		if !ok || !isUint32(seq) {
+			return nil, false
+		}
+		from.Seq = uint32(seq)
+	}
+	if current := currentHeight(); current > from.Height && current-from.Height > MAX_REPLAY_BLOCKS {
+		return nil, false
+	}
+	return from, true
+}
+
+func isUint32(value float64) bool {
+	return value >= 0 && value <= math.MaxUint32 && value == math.Trunc(value)
+}
+
+//replaySubscription push the messages client missed after the subscribe response
+func (self *WsServer) replaySubscription(cmd map[string]interface{}) {
+	sessionId, _ := cmd["SessionId"].(string)
+	self.Lock()
+	defer self.Unlock()
+	sub, ok := self.SubscribeMap[sessionId]
+	if !ok || sub.replayFrom == nil {
+		return
+	}
+	from := *sub.replayFrom
+	sub.replayFrom = nil
+	self.SubscribeMap[sessionId] = sub
+	self.startReplay(sessionId, from)
+}
+
+//startReplay run the replay of the session from cursor, the caller must hold the lock
+func (self *WsServer) startReplay(sessionId string, from Cursor) {
+	if self.replay == nil {
+		self.flushPending(sessionId)
+		return
+	}
+	self.replayCount++
+	id := self.replayCount
+	ctx, cancel := context.WithCancel(context.Background())
+	v := self.SubscribeMap[sessionId]
+	v.replayId = id
+	v.cancelReplay = cancel
+	self.SubscribeMap[sessionId] = v
+	// replay may read thousands of blocks, run it aside so other subscribers are not stalled
+	go func() {
+		defer cancel()
+		self.replay(ctx, sessionId, from)
+		self.finishReplay(sessionId, id)
+	}()
+}
+
+//finishReplay flush the live pushes held during the replay of id, only those after the last replayed
+//message are delivered. If the pushes were dropped, the replay runs again from the cursor to catch up
+func (self *WsServer) finishReplay(sessionId string, id uint64) {
+	self.Lock()
+	defer self.Unlock()
+	v, ok := self.SubscribeMap[sessionId]
+	if !ok || !v.replaying || v.replayId != id {
+		return
+	}
+	v.cancelReplay = nil
+	if v.pendingDropped {
+		v.pendingDropped = false
+		v.pending = nil
+		self.SubscribeMap[sessionId] = v
+		self.startReplay(sessionId, *v.Cursor)
+		return
+	}
+	self.SubscribeMap[sessionId] = v
+	self.flushPending(sessionId)
+}
+
+//flushPending end the replay of the session and send the held pushes, the caller must hold the lock
+func (self *WsServer) flushPending(sessionId string) {
+	v, ok := self.SubscribeMap[sessionId]
+	if !ok || !v.replaying {
+		return
+	}
+	pending := v.pending
+	v.replaying = false
+	v.pending = nil
+	v.pendingDropped = false
+	self.SubscribeMap[sessionId] = v
+	for _, p := range pending {
+		cur := p.cur
+		self.sendToSubscriber(sessionId, p.contractAddrs, p.sub, &cur, p.data, false)
+	}
+}
+
 func (self *WsServer) Stop() {
 	if self.server != nil {
 		self.server.Shutdown(context.Background())
@@ -352,6 +549,9 @@ func (self *WsServer) OnDataHandle(curSession *session.Session, bysMsg []byte, r
 		}
 	}
 	curSession.Send(marshalResp(resp))
+	if action.afterSend != nil {
+		action.afterSend(req)
+	}
 
 	return true
 }
@@ -372,6 +572,9 @@ func (self *WsServer) deleteTxHashes(sessionId string) {
 func (self *WsServer) deleteSubscribe(sessionId string) {
 	self.Lock()
 	defer self.Unlock()
+	if sub, ok := self.SubscribeMap[sessionId]; ok && sub.cancelReplay != nil {
+		sub.cancelReplay()
+	}
 	delete(self.SubscribeMap, sessionId)
 }
 
@@ -411,35 +614,57 @@ func (self *WsServer) PushTxResult(contractAddrs map[string]bool, txHashStr stri
 		s.Send(marshalResp(resp))
 	}
 }
-func (self *WsServer) BroadcastToSubscribers(contractAddrs map[string]bool, sub int, resp map[string]interface{}) {
+//BroadcastToSubscribers send the message to all matched subscribers. Message with cursor is delivered
+//at most once to every subscriber and only if it is later than the last delivered one
+func (self *WsServer) BroadcastToSubscribers(contractAddrs map[string]bool, sub int, cur *Cursor, resp map[string]interface{}) {
 	// broadcast SubscribeMap
 	self.Lock()
 	defer self.Unlock()
 	data := marshalResp(resp)
-	for sid, v := range self.SubscribeMap {
-		s := self.SessionList.GetSessionById(sid)
-		if s == nil {
-			continue
+	for sid := range self.SubscribeMap {
+		self.sendToSubscriber(sid, contractAddrs, sub, cur, data, false)
+	}
+}
+
+//SendToSubscriber send the replayed message to one subscriber, use the same rules as BroadcastToSubscribers
+//but is not held back by the replay of the subscriber
+func (self *WsServer) SendToSubscriber(sessionId string, contractAddrs map[string]bool, sub int, cur *Cursor, resp map[string]interface{}) {
+	self.Lock()
+	defer self.Unlock()
+	self.sendToSubscriber(sessionId, contractAddrs, sub, cur, marshalResp(resp), true)
+}
+
+func (self *WsServer) sendToSubscriber(sessionId string, contractAddrs map[string]bool, sub int, cur *Cursor, data []byte, replay bool) {
+	v, ok := self.SubscribeMap[sessionId]
+	if !ok || !v.match(contractAddrs, sub) {
+		return
+	}
+	s := self.SessionList.GetSessionById(sessionId)
+	if s == nil {
+		return
+	}
+	if cur != nil && v.replaying && !replay {
+		if v.pendingDropped {
+			return
 		}
-		if sub == WSTOPIC_JSON_BLOCK && v.SubscribeJsonBlock {
-			s.Send(data)
-		} else if sub == WSTOPIC_RAW_BLOCK && v.SubscribeRawBlock {
-			s.Send(data)
-		} else if sub == WSTOPIC_TXHASHS && v.SubscribeBlockTxHashs {
-			s.Send(data)
-		} else if sub == WSTOPIC_EVENT && v.SubscribeEvent {
-			if len(v.ContractsFilter) == 0 {
-				s.Send(data)
-				continue
-			}
-			for _, addr := range v.ContractsFilter {
-				if contractAddrs[addr] {
-					s.Send(data)
-					break
-				}
-			}
+		if len(v.pending) >= MAX_PENDING_PUSHES {
+			v.pending = nil
+			v.pendingDropped = true
+		} else {
+			v.pending = append(v.pending, pendingPush{contractAddrs: contractAddrs, sub: sub, cur: *cur, data: data})
+		}
+		self.SubscribeMap[sessionId] = v
+		return
+	}
+	if cur != nil {
+		if v.Cursor != nil && !cur.After(*v.Cursor) {
+			return
 		}
+		c := *cur
+		v.Cursor = &c
+		self.SubscribeMap[sessionId] = v
 	}
+	s.Send(data)
 }
 
 func (self *WsServer) initTlsListen() (net.Listener, error) {
@@ -0,0 +1,289 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	Err "github.com/ontio/layer2/node/http/base/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func blockResp(cur Cursor) map[string]interface{} {
+	return map[string]interface{}{"Error": Err.SUCCESS, "Action": "sendjsonblock", "Cursor": cur}
+}
+
+//dialSession connect a client to the server and return it with the id of its session
+func dialSession(t *testing.T, ws *WsServer) (*websocket.Conn, string) {
+	sessions := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s, _ := ws.SessionList.NewSession(conn)
+		sessions <- s.GetSessionId()
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, <-sessions
+}
+
+//readCursors read the cursors of count block messages
+func readCursors(t *testing.T, conn *websocket.Conn, count int) []Cursor {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	cursors := make([]Cursor, 0, count)
+	for i := 0; i < count; i++ {
+		_, data, err := conn.ReadMessage()
+		if !assert.Nil(t, err) {
+			break
+		}
+		resp := struct{ Cursor Cursor }{}
+		assert.Nil(t, json.Unmarshal(data, &resp))
+		cursors = append(cursors, resp.Cursor)
+	}
+	return cursors
+}
+
+//blockCursors return the cursors of the json blocks from start to end
+func blockCursors(start, end uint32) []Cursor {
+	cursors := make([]Cursor, 0)
+	for height := start; height <= end; height++ {
+		cursors = append(cursors, Cursor{Height: height, Seq: SEQ_JSON_BLOCK})
+	}
+	return cursors
+}
+
+//broadcastBlocks push the json blocks from start to end by the dispatch loop
+func broadcastBlocks(t *testing.T, ws *WsServer, start, end uint32) {
+	done := make(chan struct{})
+	ws.Dispatch(func() {
+		for height := start; height <= end; height++ {
+			cur := &Cursor{Height: height, Seq: SEQ_JSON_BLOCK}
+			ws.BroadcastToSubscribers(nil, WSTOPIC_JSON_BLOCK, cur, blockResp(*cur))
+		}
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatch loop stalled by replay")
+	}
+}
+
+func TestReplayRacingLivePush(t *testing.T) {
+	ws := InitWsServer()
+	conn, sessionId := dialSession(t, ws)
+
+	// client has seen (1,1), the node is at height 3 and block 4 arrives during the replay
+	from := Cursor{Height: 1, Seq: SEQ_JSON_BLOCK}
+	ws.SubscribeMap[sessionId] = subscribe{SubscribeJsonBlock: true, Cursor: &from, replayFrom: &from, replaying: true}
+	livePushed := make(chan struct{})
+	ws.SetReplayHandler(func(ctx context.Context, sid string, from Cursor) {
+		<-livePushed
+		for height := from.Height; height <= 3; height++ {
+			cur := &Cursor{Height: height, Seq: SEQ_JSON_BLOCK}
+			ws.SendToSubscriber(sid, nil, WSTOPIC_JSON_BLOCK, cur, blockResp(*cur))
+		}
+	})
+	ws.replaySubscription(map[string]interface{}{"SessionId": sessionId})
+
+	// the dispatch loop keeps running while the replay is blocked
+	for _, height := range []uint32{3, 4} {
+		cur := &Cursor{Height: height, Seq: SEQ_JSON_BLOCK}
+		done := make(chan struct{})
+		ws.Dispatch(func() {
+			ws.BroadcastToSubscribers(nil, WSTOPIC_JSON_BLOCK, cur, blockResp(*cur))
+			close(done)
+		})
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("dispatch loop stalled by replay")
+		}
+	}
+	close(livePushed)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, height := range []uint32{2, 3, 4} {
+		_, data, err := conn.ReadMessage()
+		assert.Nil(t, err)
+		resp := struct{ Cursor Cursor }{}
+		assert.Nil(t, json.Unmarshal(data, &resp))
+		assert.Equal(t, Cursor{Height: height, Seq: SEQ_JSON_BLOCK}, resp.Cursor)
+	}
+	ws.Lock()
+	sub := ws.SubscribeMap[sessionId]
+	ws.Unlock()
+	assert.False(t, sub.replaying)
+	assert.Equal(t, Cursor{Height: 4, Seq: SEQ_JSON_BLOCK}, *sub.Cursor)
+
+	// nothing else is delivered
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err := conn.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestParseReplayCursor(t *testing.T) {
+	current := func() uint32 { return MAX_REPLAY_BLOCKS + 10 }
+	cases := []struct {
+		cmd  map[string]interface{}
+		want *Cursor
+	}{
+		{map[string]interface{}{"FromHeight": float64(10), "FromSeq": float64(2)}, &Cursor{Height: 10, Seq: 2}},
+		{map[string]interface{}{"FromHeight": float64(20)}, &Cursor{Height: 20}},
+		// the cursor ahead of the ledger replays nothing
+		{map[string]interface{}{"FromHeight": float64(math.MaxUint32)}, &Cursor{Height: math.MaxUint32}},
+		{map[string]interface{}{"FromHeight": float64(9)}, nil},
+		{map[string]interface{}{"FromHeight": float64(-1)}, nil},
+		{map[string]interface{}{"FromHeight": float64(math.MaxUint32 + 1)}, nil},
+		{map[string]interface{}{"FromHeight": 10.5}, nil},
+		{map[string]interface{}{"FromHeight": "10"}, nil},
+		{map[string]interface{}{"FromHeight": float64(10), "FromSeq": float64(-1)}, nil},
+		{map[string]interface{}{"FromHeight": float64(10), "FromSeq": "1"}, nil},
+	}
+	for _, c := range cases {
+		from, ok := parseReplayCursor(c.cmd, current)
+		assert.Equal(t, c.want != nil, ok, "%v", c.cmd)
+		assert.Equal(t, c.want, from, "%v", c.cmd)
+	}
+}
+
+func TestReplayCanceledBySessionClose(t *testing.T) {
+	ws := InitWsServer()
+	_, sessionId := dialSession(t, ws)
+	from := Cursor{Height: 1, Seq: SEQ_JSON_BLOCK}
+	ws.SubscribeMap[sessionId] = subscribe{SubscribeJsonBlock: true, Cursor: &from, replayFrom: &from, replaying: true}
+	canceled := make(chan struct{})
+	ws.SetReplayHandler(func(ctx context.Context, sid string, from Cursor) {
+		<-ctx.Done()
+		close(canceled)
+	})
+	ws.replaySubscription(map[string]interface{}{"SessionId": sessionId})
+
+	ws.deleteSubscribe(sessionId)
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay is not canceled by the session close")
+	}
+}
+
+func TestReplayReplacedByResubscribe(t *testing.T) {
+	ws := InitWsServer()
+	ws.registryMethod()
+	conn, sessionId := dialSession(t, ws)
+	height := currentBlockHeight
+	currentBlockHeight = func() uint32 { return 3 }
+	defer func() { currentBlockHeight = height }()
+
+	started, canceled := make(chan struct{}), make(chan struct{})
+	var replays int32
+	ws.SetReplayHandler(func(ctx context.Context, sid string, from Cursor) {
+		if atomic.AddInt32(&replays, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			close(canceled)
+			return
+		}
+		for height := from.Height; height <= 3; height++ {
+			cur := &Cursor{Height: height, Seq: SEQ_JSON_BLOCK}
+			ws.SendToSubscriber(sid, nil, WSTOPIC_JSON_BLOCK, cur, blockResp(*cur))
+		}
+	})
+	subscribe := func() {
+		cmd := map[string]interface{}{"SessionId": sessionId, "SubscribeJsonBlock": true, "FromHeight": float64(1),
+			"FromSeq": float64(SEQ_JSON_BLOCK)}
+		action := ws.ActionMap["subscribe"]
+		assert.Equal(t, Err.SUCCESS, action.handler(cmd)["Error"])
+		action.afterSend(cmd)
+	}
+	subscribe()
+	<-started
+	broadcastBlocks(t, ws, 4, 4)
+	// the pushes held by the first replay are delivered after the second one
+	subscribe()
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay is not canceled by the re-subscribe")
+	}
+	assert.Equal(t, blockCursors(2, 4), readCursors(t, conn, 3))
+	ws.Lock()
+	sub := ws.SubscribeMap[sessionId]
+	ws.Unlock()
+	assert.False(t, sub.replaying)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&replays))
+}
+
+func TestReplayCatchUpAfterPendingDropped(t *testing.T) {
+	ws := InitWsServer()
+	conn, sessionId := dialSession(t, ws)
+	from := Cursor{Height: 1, Seq: SEQ_JSON_BLOCK}
+	ws.SubscribeMap[sessionId] = subscribe{SubscribeJsonBlock: true, Cursor: &from, replayFrom: &from, replaying: true}
+
+	// the live pushes of blocks 4 to 4+MAX_PENDING_PUSHES overflow while the first replay up to block 3 is blocked
+	last := uint32(4 + MAX_PENDING_PUSHES)
+	livePushed := make(chan struct{})
+	replays := make(chan Cursor, 2)
+	ws.SetReplayHandler(func(ctx context.Context, sid string, from Cursor) {
+		// the first replay ends at the current block before the live pushes, however late it is scheduled
+		end := last
+		if len(replays) == 0 {
+			end = 3
+			<-livePushed
+		}
+		replays <- from
+		for height := from.Height; height <= end; height++ {
+			cur := &Cursor{Height: height, Seq: SEQ_JSON_BLOCK}
+			ws.SendToSubscriber(sid, nil, WSTOPIC_JSON_BLOCK, cur, blockResp(*cur))
+		}
+	})
+	ws.replaySubscription(map[string]interface{}{"SessionId": sessionId})
+	broadcastBlocks(t, ws, 4, last)
+	ws.Lock()
+	sub := ws.SubscribeMap[sessionId]
+	ws.Unlock()
+	assert.True(t, sub.pendingDropped)
+	assert.Nil(t, sub.pending)
+	close(livePushed)
+
+	// the second replay catches up from the last replayed block
+	received := make(chan []Cursor)
+	go func() { received <- readCursors(t, conn, int(last-1)) }()
+	assert.Equal(t, blockCursors(2, last), <-received)
+	assert.Equal(t, from, <-replays)
+	assert.Equal(t, Cursor{Height: 3, Seq: SEQ_JSON_BLOCK}, <-replays)
+	ws.Lock()
+	sub = ws.SubscribeMap[sessionId]
+	ws.Unlock()
+	assert.False(t, sub.replaying)
+	assert.False(t, sub.pendingDropped)
+}
@@ -21,13 +21,17 @@ package main
 import (
 	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"runtime"
 	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/fdlimit"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
 	"github.com/ontio/ontology-crypto/keypair"
 	alog "github.com/ontio/ontology-eventbus/log"
 	"github.com/ontio/layer2/node/account"
@@ -40,10 +44,13 @@ import (
 	"github.com/ontio/layer2/node/consensus"
 	"github.com/ontio/layer2/node/core/genesis"
 	"github.com/ontio/layer2/node/core/ledger"
+	"github.com/ontio/layer2/node/core/store/ledgerstore"
 	"github.com/ontio/layer2/node/events"
 	bactor "github.com/ontio/layer2/node/http/base/actor"
 	hserver "github.com/ontio/layer2/node/http/base/actor"
+	bcomn "github.com/ontio/layer2/node/http/base/common"
 	"github.com/ontio/layer2/node/http/jsonrpc"
+	"github.com/ontio/layer2/node/http/lightsync"
 	"github.com/ontio/layer2/node/http/localrpc"
 	"github.com/ontio/layer2/node/http/restful"
 	"github.com/ontio/layer2/node/http/websocket"
@@ -68,6 +75,9 @@ func setupAPP() *cli.App {
 		cmd.ContractCommand,
 		cmd.ImportCommand,
 		cmd.ExportCommand,
+		cmd.RestoreCommand,
+		cmd.FastSyncCommand,
+		cmd.VerifyCommand,
 		cmd.TxCommond,
 		cmd.SigTxCommand,
 		cmd.MultiSigAddrCommand,
@@ -78,10 +88,32 @@ func setupAPP() *cli.App {
 	app.Flags = []cli.Flag{
 		//common setting
 		utils.ConfigFlag,
+		utils.ChainSpecFlag,
 		utils.LogLevelFlag,
 		utils.DisableLogFileFlag,
 		utils.DisableEventLogFlag,
+		utils.EnableTxIndexFlag,
+		utils.EnableCompressionFlag,
+		utils.CompactWindowFlag,
 		utils.DataDirFlag,
+		utils.StateCacheSizeFlag,
+		utils.HeaderIndexBatchSizeFlag,
+		utils.InvariantCheckIntervalFlag,
+		utils.Layer2RetentionFlag,
+		utils.Layer2CheckpointIntervalFlag,
+		utils.EnableStateHistoryFlag,
+		utils.EnableFullStateRootFlag,
+		utils.CheckpointSnapshotDirFlag,
+		utils.EncryptKeyFileFlag,
+		utils.RecoverOnlyFlag,
+		utils.IntegrityCheckBlocksFlag,
+		utils.DisableAutoRepairFlag,
+		utils.LightUpstreamFlag,
+		utils.ReadOnlyFlag,
+		utils.ReadOnlyViewDirFlag,
+		utils.ReadOnlyRefreshFlag,
+		utils.MetricsFlag,
+		utils.MetricsPortFlag,
 		//account setting
 		utils.WalletFileFlag,
 		utils.AccountAddressFlag,
@@ -111,6 +143,7 @@ func setupAPP() *cli.App {
 		//rpc setting
 		utils.RPCDisabledFlag,
 		utils.RPCPortFlag,
+		utils.RPCSignProofFlag,
 		utils.RPCLocalEnableFlag,
 		utils.RPCLocalProtFlag,
 		//rest setting
@@ -147,17 +180,31 @@ func startOntology(ctx *cli.Context) {
 		log.Errorf("initConfig error: %s", err)
 		return
 	}
+	if upstream := ctx.GlobalString(utils.GetFlagName(utils.LightUpstreamFlag)); upstream != "" {
+		startLightNode(ctx, upstream)
+		return
+	}
+	if ctx.GlobalBool(utils.GetFlagName(utils.ReadOnlyFlag)) {
+		startReadOnlyNode(ctx)
+		return
+	}
 	acc, err := initAccount(ctx)
 	if err != nil {
 		log.Errorf("initWallet error: %s", err)
 		return
 	}
-	stateHashHeight := config.GetStateHashCheckHeight(config.NETWORK_ID_SOLO_NET)
+	initMetrics(ctx)
+	stateHashHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
 	ldg, err := initLedger(ctx, stateHashHeight)
 	if err != nil {
 		log.Errorf("%s", err)
 		return
 	}
+	if ctx.GlobalBool(utils.GetFlagName(utils.RecoverOnlyFlag)) {
+		log.Infof("recover only mode, ledger recovered to height %d, closing ledger...", ldg.GetCurrentBlockHeight())
+		ldg.Close()
+		return
+	}
 	txpool, err := initTxPool(ctx)
 	if err != nil {
 		log.Errorf("initTxPool error: %s", err)
@@ -168,7 +215,79 @@ func startOntology(ctx *cli.Context) {
 		log.Errorf("initConsensus error: %s", err)
 		return
 	}
-	err = initRpc(ctx)
+	err = initRpc(ctx, acc)
+	if err != nil {
+		log.Errorf("initRpc error: %s", err)
+		return
+	}
+	err = initLocalRpc(ctx)
+	if err != nil {
+		log.Errorf("initLocalRpc error: %s", err)
+		return
+	}
+	initRestful(ctx)
+	initWs(ctx)
+
+	go logCurrBlockHeight()
+	waitToExit(ldg)
+}
+
+//startLightNode run the header-only light node, which only saves and verifies the block headers and layer2 states
+//synced from upstream without executing transactions
+func startLightNode(ctx *cli.Context, upstream string) {
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		log.Errorf("GetBookkeepers error: %s", err)
+		return
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		log.Errorf("genesisBlock error %s", err)
+		return
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.NETWORK_NAME_SOLO_NET)
+	ledger.DefLightLedger, err = ledger.NewLightLedger(dbDir)
+	if err != nil {
+		log.Errorf("NewLightLedger error: %s", err)
+		return
+	}
+	err = ledger.DefLightLedger.Init(genesisBlock)
+	if err != nil {
+		log.Errorf("Init light ledger error: %s", err)
+		ledger.DefLightLedger.Close()
+		return
+	}
+	syncer := lightsync.NewSyncer(upstream, ledger.DefLightLedger, config.DEFAULT_GEN_BLOCK_TIME*time.Second)
+	syncer.Start()
+	log.Infof("Light node syncing from %s, current header height %d", upstream, ledger.DefLightLedger.GetCurrentHeight())
+	if config.DefConfig.Rpc.EnableHttpJsonRpc {
+		go func() {
+			err := jsonrpc.StartLightRPCServer()
+			if err != nil {
+				log.Errorf("StartLightRPCServer error: %s", err)
+			}
+		}()
+	}
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	sig := <-sc
+	log.Infof("Ontology received exit signal: %v.", sig.String())
+	log.Infof("closing light ledger...")
+	syncer.Close()
+	ledger.DefLightLedger.Close()
+}
+
+//startReadOnlyNode run the read-only replica, which serves the queries from the data dir written by other node
+//process, without transaction pool and consensus
+func startReadOnlyNode(ctx *cli.Context) {
+	initMetrics(ctx)
+	ldg, err := initReadOnlyLedger(ctx)
+	if err != nil {
+		log.Errorf("%s", err)
+		return
+	}
+	err = initRpc(ctx, nil)
 	if err != nil {
 		log.Errorf("initRpc error: %s", err)
 		return
@@ -185,6 +304,35 @@ func startOntology(ctx *cli.Context) {
 	waitToExit(ldg)
 }
 
+func initReadOnlyLedger(ctx *cli.Context) (*ledger.Ledger, error) {
+	events.Init() //Init event hub
+
+	var err error
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.NETWORK_NAME_SOLO_NET)
+	viewDir := ctx.GlobalString(utils.GetFlagName(utils.ReadOnlyViewDirFlag))
+	refresh := ctx.GlobalUint(utils.GetFlagName(utils.ReadOnlyRefreshFlag))
+	stateHashHeight := config.GetFeatureActivationHeight(config.FEATURE_STATE_HASH_CHECK)
+	ledger.DefLedger, err = ledger.NewReadOnlyLedger(dbDir, viewDir, stateHashHeight, time.Duration(refresh)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("NewReadOnlyLedger error: %s", err)
+	}
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeepers error: %s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		return nil, fmt.Errorf("genesisBlock error %s", err)
+	}
+	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	if err != nil {
+		ledger.DefLedger.Close()
+		return nil, fmt.Errorf("Init ledger error: %s", err)
+	}
+	log.Infof("Read-only ledger init success, current block height %d", ledger.DefLedger.GetCurrentBlockHeight())
+	return ledger.DefLedger, nil
+}
+
 func initLog(ctx *cli.Context) {
 	//init log module
 	logLevel := ctx.GlobalInt(utils.GetFlagName(utils.LogLevelFlag))
@@ -228,7 +376,12 @@ func initAccount(ctx *cli.Context) (*account.Account, error) {
 
 	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
 		curPk := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
-		config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
+		bookkeepers := config.DefConfig.Genesis.SOLO.Bookkeepers
+		if len(bookkeepers) == 0 {
+			config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
+		} else if !strings.EqualFold(bookkeepers[0], curPk) {
+			return nil, fmt.Errorf("account %s is not the bookkeeper of chain spec", acc.Address.ToBase58())
+		}
 	}
 
 	log.Infof("Account init success")
@@ -257,6 +410,26 @@ func initLedger(ctx *cli.Context, stateHashHeight uint32) (*ledger.Ledger, error
 	if err != nil {
 		return nil, fmt.Errorf("Init ledger error: %s", err)
 	}
+	if interval := config.DefConfig.Common.InvariantCheckInterval; interval > 0 {
+		ledger.DefLedger.StartInvariantChecker(time.Duration(interval) * time.Second)
+	}
+	err = ledger.DefLedger.SetLayer2Retention(uint32(config.DefConfig.Common.Layer2Retention),
+		uint32(config.DefConfig.Common.Layer2CheckpointInterval))
+	if err != nil {
+		return nil, fmt.Errorf("SetLayer2Retention error: %s", err)
+	}
+	err = ledger.DefLedger.EnableStateHistory(config.DefConfig.Common.EnableStateHistory)
+	if err != nil {
+		return nil, fmt.Errorf("EnableStateHistory error: %s", err)
+	}
+	err = ledger.DefLedger.EnableFullStateRoot(config.DefConfig.Common.EnableFullStateRoot)
+	if err != nil {
+		return nil, fmt.Errorf("EnableFullStateRoot error: %s", err)
+	}
+	err = ledger.DefLedger.SetCheckpointSnapshotDir(config.DefConfig.Common.CheckpointSnapshotDir)
+	if err != nil {
+		return nil, fmt.Errorf("SetCheckpointSnapshotDir error: %s", err)
+	}
 
 	log.Infof("Ledger init success")
 	return ledger.DefLedger, nil
@@ -300,10 +473,13 @@ func initConsensus(ctx *cli.Context, txpoolSvr *proc.TXPoolServer, acc *account.
 	return consensusService, nil
 }
 
-func initRpc(ctx *cli.Context) error {
+func initRpc(ctx *cli.Context, acc *account.Account) error {
 	if !config.DefConfig.Rpc.EnableHttpJsonRpc {
 		return nil
 	}
+	if config.DefConfig.Rpc.SignProof {
+		bcomn.SetResponseSigner(acc)
+	}
 	var err error
 	exitCh := make(chan interface{}, 0)
 	go func() {
@@ -349,6 +525,26 @@ func initLocalRpc(ctx *cli.Context) error {
 	return nil
 }
 
+//initMetrics enable the metrics and serve the ledger metrics in prometheus format. It must be called before the
+//ledger is created, or the metrics are no-op
+func initMetrics(ctx *cli.Context) {
+	if !ctx.GlobalBool(utils.GetFlagName(utils.MetricsFlag)) {
+		return
+	}
+	metrics.Enabled = true
+	port := ctx.GlobalUint(utils.GetFlagName(utils.MetricsPortFlag))
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler(ledgerstore.MetricsRegistry))
+	go func() {
+		err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+		if err != nil {
+			log.Errorf("metrics server error: %s", err)
+		}
+	}()
+
+	log.Infof("Metrics server init success on port %d", port)
+}
+
 func initRestful(ctx *cli.Context) {
 	if !config.DefConfig.Restful.EnableHttpRestful {
 		return
@@ -20,8 +20,8 @@ package merkle
 
 import (
 	"errors"
-	"io"
 	"os"
+	"sync"
 
 	"github.com/ontio/layer2/node/common"
 )
@@ -29,36 +29,63 @@ import (
 // HashStore is an interface for persist hash
 type HashStore interface {
 	Append(hash []common.Uint256) error
-	Flush() error
+	Flush() error //Persist the appended hashes, called once per block commit
 	Close()
 	GetHash(pos uint32) (common.Uint256, error)
 }
 
+var errReadOnlyHashStore = errors.New("hash store is read-only")
+
+const MERKLE_TAIL_CACHE_SIZE = 4096 //Count of the last stored hashes cached in memory, which cover the rightmost path
+
+//fileHashStore buffer the appended hashes in memory and write them to file in one batch at Flush,
+//the last hashes are kept in memory, so the proofs of recent blocks do not read the file
 type fileHashStore struct {
 	file_name string
 	file      *os.File
+	lock      sync.RWMutex
+	size      uint32           //count of hashes written to file
+	pending   []common.Uint256 //hashes appended but not written
+	tail      []common.Uint256 //last hashes including pending ones, tail[i] is at position tailStart+i
+	tailStart uint32
+	readOnly  bool //File is written by other process, Append and Flush are not allowed
 }
 
 // NewFileHashStore returns a HashStore implement in file
 func NewFileHashStore(name string, tree_size uint32) (HashStore, error) {
-	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0755)
+	return openFileHashStore(name, tree_size, false)
+}
+
+// NewReadOnlyFileHashStore returns a HashStore of the file written by other process, the file may have more hashes
+// than tree_size
+func NewReadOnlyFileHashStore(name string, tree_size uint32) (HashStore, error) {
+	return openFileHashStore(name, tree_size, true)
+}
+
+func openFileHashStore(name string, tree_size uint32, readOnly bool) (HashStore, error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(name, flag, 0755)
 	if err != nil {
 		return nil, err
 	}
 	store := &fileHashStore{
 		file_name: name,
 		file:      f,
+		readOnly:  readOnly,
 	}
 
 	err = store.checkConsistence(tree_size)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
 
 	num_hashes := getStoredHashNum(tree_size)
-	size := int64(num_hashes) * int64(common.UINT256_SIZE)
-
-	_, err = store.file.Seek(size, io.SeekStart)
+	store.size = uint32(num_hashes)
+	err = store.loadTail()
 	if err != nil {
 		return nil, err
 	}
@@ -88,23 +115,73 @@ func (self *fileHashStore) checkConsistence(tree_size uint32) error {
 	return nil
 }
 
+//loadTail read the last MERKLE_TAIL_CACHE_SIZE hashes of file to memory
+func (self *fileHashStore) loadTail() error {
+	count := self.size
+	if count > MERKLE_TAIL_CACHE_SIZE {
+		count = MERKLE_TAIL_CACHE_SIZE
+	}
+	self.tailStart = self.size - count
+	buf := make([]byte, int(count)*common.UINT256_SIZE)
+	_, err := self.file.ReadAt(buf, int64(self.tailStart)*int64(common.UINT256_SIZE))
+	if err != nil {
+		return err
+	}
+	self.tail = make([]common.Uint256, count, MERKLE_TAIL_CACHE_SIZE)
+	for i := range self.tail {
+		copy(self.tail[i][:], buf[i*common.UINT256_SIZE:])
+	}
+	return nil
+}
+
+//Append buffer the hashes, they are written to file at Flush
 func (self *fileHashStore) Append(hash []common.Uint256) error {
 	if self == nil {
 		return nil
 	}
-	buf := make([]byte, 0, len(hash)*common.UINT256_SIZE)
-	for _, h := range hash {
-		buf = append(buf, h[:]...)
+	if self.readOnly {
+		return errReadOnlyHashStore
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.pending = append(self.pending, hash...)
+	self.tail = append(self.tail, hash...)
+	if len(self.tail) > 2*MERKLE_TAIL_CACHE_SIZE && len(self.pending) <= MERKLE_TAIL_CACHE_SIZE {
+		drop := len(self.tail) - MERKLE_TAIL_CACHE_SIZE
+		self.tail = append(make([]common.Uint256, 0, 2*MERKLE_TAIL_CACHE_SIZE), self.tail[drop:]...)
+		self.tailStart += uint32(drop)
 	}
-	_, err := self.file.Write(buf)
-	return err
+	return nil
 }
 
+//Flush write the pending hashes to file in one batch and sync the file
 func (self *fileHashStore) Flush() error {
 	if self == nil {
 		return nil
 	}
-	return self.file.Sync()
+	if self.readOnly {
+		return errReadOnlyHashStore
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if len(self.pending) == 0 {
+		return nil
+	}
+	buf := make([]byte, 0, len(self.pending)*common.UINT256_SIZE)
+	for _, h := range self.pending {
+		buf = append(buf, h[:]...)
+	}
+	_, err := self.file.WriteAt(buf, int64(self.size)*int64(common.UINT256_SIZE))
+	if err != nil {
+		return err
+	}
+	err = self.file.Sync()
+	if err != nil {
+		return err
+	}
+	self.size += uint32(len(self.pending))
+	self.pending = self.pending[:0]
+	return nil
 }
 
 func (self *fileHashStore) Close() {
@@ -118,6 +195,11 @@ func (self *fileHashStore) GetHash(pos uint32) (common.Uint256, error) {
 	if self == nil {
 		return EMPTY_HASH, errors.New("FileHashstore is nil")
 	}
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if pos >= self.tailStart && pos-self.tailStart < uint32(len(self.tail)) {
+		return self.tail[pos-self.tailStart], nil
+	}
 	hash := EMPTY_HASH
 	_, err := self.file.ReadAt(hash[:], int64(pos)*int64(common.UINT256_SIZE))
 	if err != nil {
@@ -154,7 +154,8 @@ func (self *CompactMerkleTree) Append(leafv []byte) []common.Uint256 {
 	return self.AppendHash(leaf)
 }
 
-// AppendHash appends a leaf hash to the merkle tree and returns the audit path
+// AppendHash appends a leaf hash to the merkle tree and returns the audit path,
+// the hashes are persisted when the hash store is flushed
 func (self *CompactMerkleTree) AppendHash(leaf common.Uint256) []common.Uint256 {
 	size := len(self.hashes)
 	auditPath := make([]common.Uint256, size, size)
@@ -174,7 +175,6 @@ func (self *CompactMerkleTree) AppendHash(leaf common.Uint256) []common.Uint256
 	}
 	if self.hashStore != nil {
 		self.hashStore.Append(storehashes)
-		self.hashStore.Flush()
 	}
 	self.treeSize += 1
 	self.hashes = self.hashes[0:size]
@@ -21,6 +21,7 @@ package merkle
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/ontio/layer2/node/common"
@@ -306,3 +307,34 @@ func TestAudit(t *testing.T) {
 		assert.Equal(t, []byte(fmt.Sprintf("%d", i)), value)
 	}
 }
+
+func TestFileHashStoreFlush(t *testing.T) {
+	name := "flushtree.db"
+	defer os.Remove(name)
+	store, err := NewFileHashStore(name, 0)
+	assert.Nil(t, err)
+	tree := NewTree(0, nil, store)
+	N := uint32(3 * MERKLE_TAIL_CACHE_SIZE)
+	for i := uint32(0); i < N; i++ {
+		tree.Append([]byte(fmt.Sprintf("leaf %d", i)))
+		if i%100 == 0 {
+			assert.Nil(t, store.Flush())
+		}
+	}
+	root := tree.Root()
+	proof, err := tree.InclusionProof(1, N)
+	assert.Nil(t, err)
+	consistency := tree.ConsistencyProof(N-10, N)
+	assert.Nil(t, store.Flush())
+	store.Close()
+
+	store, err = NewFileHashStore(name, N)
+	assert.Nil(t, err)
+	defer store.Close()
+	tree2 := NewTree(N, tree.Hashes(), store)
+	assert.Equal(t, root, tree2.Root())
+	proof2, err := tree2.InclusionProof(1, N)
+	assert.Nil(t, err)
+	assert.Equal(t, proof, proof2)
+	assert.Equal(t, consistency, tree2.ConsistencyProof(N-10, N))
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ontio/layer2/node/common"
+)
+
+//The merkle patricia trie is the ethereum trie keyed by the raw key. Nodes are keyed by the keccak256 of the rlp
+//encoded node, and the root of empty trie is zero.
+const MPT_MAX_PROOF_NODES = 2 * 256
+
+var mptEmptyRoot = ethcommon.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+//MPTNodeStore return the persisted node of merkle patricia trie by node hash
+type MPTNodeStore interface {
+	GetMPTNode(hash common.Uint256) ([]byte, error)
+}
+
+//mptNodeDB keep the new nodes in memory and read the persisted nodes from store
+type mptNodeDB struct {
+	*memorydb.Database
+	store MPTNodeStore
+}
+
+func (self *mptNodeDB) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	return err == nil, nil
+}
+
+func (self *mptNodeDB) Get(key []byte) ([]byte, error) {
+	if node, err := self.Database.Get(key); err == nil {
+		return node, nil
+	}
+	hash, err := common.Uint256ParseFromBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	return self.store.GetMPTNode(hash)
+}
+
+//MerklePatriciaTrie update the trie from root, the new nodes are kept in memory until they are persisted by caller
+type MerklePatriciaTrie struct {
+	db     *mptNodeDB
+	trieDB *trie.Database
+	trie   *trie.Trie
+}
+
+//NewMerklePatriciaTrie return the trie of root whose nodes are in store
+func NewMerklePatriciaTrie(store MPTNodeStore, root common.Uint256) (*MerklePatriciaTrie, error) {
+	db := &mptNodeDB{Database: memorydb.New(), store: store}
+	trieDB := trie.NewDatabase(db)
+	t, err := trie.New(ethcommon.Hash(root), trieDB)
+	if err != nil {
+		return nil, fmt.Errorf("open trie of root %s error %s", root.ToHexString(), err)
+	}
+	return &MerklePatriciaTrie{db: db, trieDB: trieDB, trie: t}, nil
+}
+
+//Update set the value of key, empty value deletes the key. The value is copied since the trie keeps it until commit
+func (self *MerklePatriciaTrie) Update(key, value []byte) error {
+	if len(value) == 0 {
+		return self.trie.TryDelete(key)
+	}
+	return self.trie.TryUpdate(key, append([]byte{}, value...))
+}
+
+//Commit hash the updated nodes and return the new root, the new nodes are returned by NewNodes
+func (self *MerklePatriciaTrie) Commit() (common.Uint256, error) {
+	root, err := self.trie.Commit(nil)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if root == mptEmptyRoot {
+		return common.UINT256_EMPTY, nil
+	}
+	err = self.trieDB.Commit(root, false)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return common.Uint256(root), nil
+}
+
+//NewNodes return the nodes committed since the trie is opened
+func (self *MerklePatriciaTrie) NewNodes() map[common.Uint256][]byte {
+	nodes := make(map[common.Uint256][]byte)
+	iter := self.db.Database.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		hash, err := common.Uint256ParseFromBytes(iter.Key())
+		if err != nil {
+			continue
+		}
+		nodes[hash] = append([]byte{}, iter.Value()...)
+	}
+	return nodes
+}
+
+//MPTProof is the nodes on the path of key from root, which proves the value of key or that the key is absent
+type MPTProof struct {
+	Nodes [][]byte
+}
+
+//Prove return the proof of key in the root the trie is opened with or committed
+func (self *MerklePatriciaTrie) Prove(key []byte) (*MPTProof, error) {
+	proofDB := memorydb.New()
+	err := self.trie.Prove(key, 0, proofDB)
+	if err != nil {
+		return nil, err
+	}
+	proof := &MPTProof{}
+	iter := proofDB.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		proof.Nodes = append(proof.Nodes, append([]byte{}, iter.Value()...))
+	}
+	return proof, nil
+}
+
+//VerifyMPTProof verify the proof of key and value against root, nil value verifies the key is not in trie
+func VerifyMPTProof(root common.Uint256, key, value []byte, proof *MPTProof) bool {
+	if root == common.UINT256_EMPTY {
+		return value == nil
+	}
+	proofDB := memorydb.New()
+	for _, node := range proof.Nodes {
+		proofDB.Put(crypto.Keccak256(node), node)
+	}
+	val, _, err := trie.VerifyProof(ethcommon.Hash(root), key, proofDB)
+	if err != nil {
+		return false
+	}
+	if value == nil {
+		return val == nil
+	}
+	return bytes.Equal(val, value)
+}
+
+func (self *MPTProof) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(self.Nodes)))
+	for _, node := range self.Nodes {
+		sink.WriteVarBytes(node)
+	}
+}
+
+func (self *MPTProof) Deserialization(source *common.ZeroCopySource) error {
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if n > MPT_MAX_PROOF_NODES {
+		return errors.New("too many nodes of mpt proof")
+	}
+	self.Nodes = make([][]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		node, _, irregular, eof := source.NextVarBytes()
+		if irregular {
+			return common.ErrIrregularData
+		}
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.Nodes = append(self.Nodes, node)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type memMPTNodeStore map[common.Uint256][]byte
+
+func (self memMPTNodeStore) GetMPTNode(hash common.Uint256) ([]byte, error) {
+	node, ok := self[hash]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return node, nil
+}
+
+func TestMerklePatriciaTrie(t *testing.T) {
+	store := make(memMPTNodeStore)
+	tree, err := NewMerklePatriciaTrie(store, common.UINT256_EMPTY)
+	assert.Nil(t, err)
+	root, err := tree.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, common.UINT256_EMPTY, root)
+	assert.True(t, VerifyMPTProof(root, []byte{1}, nil, &MPTProof{}))
+
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, tree.Update([]byte{byte(i), 1}, []byte{byte(i), 2}))
+	}
+	root, err = tree.Commit()
+	assert.Nil(t, err)
+	for hash, node := range tree.NewNodes() {
+		store[hash] = node
+	}
+
+	//same key set has same root regardless of update order
+	other, err := NewMerklePatriciaTrie(make(memMPTNodeStore), common.UINT256_EMPTY)
+	assert.Nil(t, err)
+	for i := 99; i >= 0; i-- {
+		assert.Nil(t, other.Update([]byte{byte(i), 1}, []byte{byte(i), 2}))
+	}
+	otherRoot, err := other.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, root, otherRoot)
+
+	//reload trie from store and update incrementally
+	tree, err = NewMerklePatriciaTrie(store, root)
+	assert.Nil(t, err)
+	proof, err := tree.Prove([]byte{1, 1})
+	assert.Nil(t, err)
+	sink := common.NewZeroCopySink(nil)
+	proof.Serialization(sink)
+	decoded := &MPTProof{}
+	assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.True(t, VerifyMPTProof(root, []byte{1, 1}, []byte{1, 2}, decoded))
+	assert.False(t, VerifyMPTProof(root, []byte{1, 1}, []byte{1, 3}, decoded))
+	assert.False(t, VerifyMPTProof(root, []byte{1, 1}, nil, decoded))
+
+	proof, err = tree.Prove([]byte{1, 2})
+	assert.Nil(t, err)
+	assert.True(t, VerifyMPTProof(root, []byte{1, 2}, nil, proof))
+
+	assert.Nil(t, tree.Update([]byte{1, 1}, nil))
+	assert.Nil(t, tree.Update([]byte{200, 1}, []byte{200}))
+	newRoot, err := tree.Commit()
+	assert.Nil(t, err)
+	assert.NotEqual(t, root, newRoot)
+	for hash, node := range tree.NewNodes() {
+		store[hash] = node
+	}
+	tree, err = NewMerklePatriciaTrie(store, newRoot)
+	assert.Nil(t, err)
+	proof, err = tree.Prove([]byte{1, 1})
+	assert.Nil(t, err)
+	assert.True(t, VerifyMPTProof(newRoot, []byte{1, 1}, nil, proof))
+	proof, err = tree.Prove([]byte{200, 1})
+	assert.Nil(t, err)
+	assert.True(t, VerifyMPTProof(newRoot, []byte{200, 1}, []byte{200}, proof))
+}
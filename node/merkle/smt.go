@@ -0,0 +1,350 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ontio/layer2/node/common"
+)
+
+//The sparse merkle tree is keyed by the sha256 of key, so the depth is 256. Empty subtree hash is zero, and a subtree
+//with one leaf is the leaf itself, so the tree of a key set is unique and only non-empty nodes are stored.
+const (
+	SMT_DEPTH       = 256
+	SMT_LEAF_PREFIX = byte(0x00) //leaf node: prefix, path, value hash
+	SMT_NODE_PREFIX = byte(0x01) //internal node: prefix, left hash, right hash
+	SMT_NODE_SIZE   = 1 + 2*common.UINT256_SIZE
+)
+
+//SMTNodeStore return the persisted node of sparse merkle tree by node hash
+type SMTNodeStore interface {
+	GetSMTNode(hash common.Uint256) ([]byte, error)
+}
+
+//SparseMerkleTree update the tree from root, the new nodes are kept in memory until they are persisted by caller
+type SparseMerkleTree struct {
+	store SMTNodeStore
+	root  common.Uint256
+	nodes map[common.Uint256][]byte
+}
+
+//NewSparseMerkleTree return the tree of root whose nodes are in store
+func NewSparseMerkleTree(store SMTNodeStore, root common.Uint256) *SparseMerkleTree {
+	return &SparseMerkleTree{
+		store: store,
+		root:  root,
+		nodes: make(map[common.Uint256][]byte),
+	}
+}
+
+//Root return the current root of tree
+func (self *SparseMerkleTree) Root() common.Uint256 {
+	return self.root
+}
+
+//SMTPath return the path of key in tree
+func SMTPath(key []byte) common.Uint256 {
+	return sha256.Sum256(key)
+}
+
+//SMTLeafHash return the hash of leaf of key and value
+func SMTLeafHash(key, value []byte) common.Uint256 {
+	return smtLeafHash(SMTPath(key), sha256.Sum256(value))
+}
+
+func smtLeafHash(path, valueHash common.Uint256) common.Uint256 {
+	return sha256.Sum256(encodeSMTNode(SMT_LEAF_PREFIX, path, valueHash))
+}
+
+func smtNodeHash(left, right common.Uint256) common.Uint256 {
+	return sha256.Sum256(encodeSMTNode(SMT_NODE_PREFIX, left, right))
+}
+
+func encodeSMTNode(prefix byte, a, b common.Uint256) []byte {
+	buf := make([]byte, 0, SMT_NODE_SIZE)
+	buf = append(buf, prefix)
+	buf = append(buf, a[:]...)
+	return append(buf, b[:]...)
+}
+
+//smtBit return the bit of path at depth, from the most significant bit of first byte
+func smtBit(path common.Uint256, depth int) byte {
+	return (path[depth/8] >> uint(7-depth%8)) & 1
+}
+
+//Update set the value of key, empty value deletes the key
+func (self *SparseMerkleTree) Update(key, value []byte) error {
+	path := SMTPath(key)
+	leaf := common.UINT256_EMPTY
+	if len(value) != 0 {
+		leaf = self.putNode(SMT_LEAF_PREFIX, path, sha256.Sum256(value))
+	}
+	root, err := self.update(self.root, 0, path, leaf)
+	if err != nil {
+		return err
+	}
+	self.root = root
+	return nil
+}
+
+//update replace the leaf of path in subtree at depth with leaf, empty leaf deletes the path
+func (self *SparseMerkleTree) update(hash common.Uint256, depth int, path, leaf common.Uint256) (common.Uint256, error) {
+	if hash == common.UINT256_EMPTY {
+		return leaf, nil
+	}
+	node, err := self.getNode(hash)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	a, b := nodeChildren(node)
+	if node[0] == SMT_LEAF_PREFIX {
+		if a == path || leaf == common.UINT256_EMPTY {
+			if a == path {
+				return leaf, nil
+			}
+			return hash, nil
+		}
+		return self.merge(hash, a, leaf, path, depth)
+	}
+	if smtBit(path, depth) == 0 {
+		a, err = self.update(a, depth+1, path, leaf)
+	} else {
+		b, err = self.update(b, depth+1, path, leaf)
+	}
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return self.joinChildren(a, b)
+}
+
+//joinChildren return the node of children, the subtree with one leaf collapses to the leaf
+func (self *SparseMerkleTree) joinChildren(left, right common.Uint256) (common.Uint256, error) {
+	if left == common.UINT256_EMPTY || right == common.UINT256_EMPTY {
+		child := left
+		if child == common.UINT256_EMPTY {
+			child = right
+		}
+		if child == common.UINT256_EMPTY {
+			return child, nil
+		}
+		node, err := self.getNode(child)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		if node[0] == SMT_LEAF_PREFIX {
+			return child, nil
+		}
+	}
+	return self.putNode(SMT_NODE_PREFIX, left, right), nil
+}
+
+//merge return the subtree at depth of two leaves of different paths
+func (self *SparseMerkleTree) merge(leafA, pathA, leafB, pathB common.Uint256, depth int) (common.Uint256, error) {
+	if depth >= SMT_DEPTH {
+		return common.UINT256_EMPTY, fmt.Errorf("merge leaves of same path %s", pathA.ToHexString())
+	}
+	bitA, bitB := smtBit(pathA, depth), smtBit(pathB, depth)
+	if bitA != bitB {
+		if bitA == 0 {
+			return self.putNode(SMT_NODE_PREFIX, leafA, leafB), nil
+		}
+		return self.putNode(SMT_NODE_PREFIX, leafB, leafA), nil
+	}
+	child, err := self.merge(leafA, pathA, leafB, pathB, depth+1)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if bitA == 0 {
+		return self.putNode(SMT_NODE_PREFIX, child, common.UINT256_EMPTY), nil
+	}
+	return self.putNode(SMT_NODE_PREFIX, common.UINT256_EMPTY, child), nil
+}
+
+func (self *SparseMerkleTree) putNode(prefix byte, a, b common.Uint256) common.Uint256 {
+	node := encodeSMTNode(prefix, a, b)
+	hash := common.Uint256(sha256.Sum256(node))
+	self.nodes[hash] = node
+	return hash
+}
+
+func (self *SparseMerkleTree) getNode(hash common.Uint256) ([]byte, error) {
+	if node, ok := self.nodes[hash]; ok {
+		return node, nil
+	}
+	node, err := self.store.GetSMTNode(hash)
+	if err != nil {
+		return nil, fmt.Errorf("get smt node %s error %s", hash.ToHexString(), err)
+	}
+	if len(node) != SMT_NODE_SIZE || (node[0] != SMT_LEAF_PREFIX && node[0] != SMT_NODE_PREFIX) {
+		return nil, fmt.Errorf("smt node %s is broken", hash.ToHexString())
+	}
+	return node, nil
+}
+
+func nodeChildren(node []byte) (common.Uint256, common.Uint256) {
+	var a, b common.Uint256
+	copy(a[:], node[1:1+common.UINT256_SIZE])
+	copy(b[:], node[1+common.UINT256_SIZE:])
+	return a, b
+}
+
+//NewNodes return the nodes created by updates and reachable from current root, which need to be persisted
+func (self *SparseMerkleTree) NewNodes() map[common.Uint256][]byte {
+	nodes := make(map[common.Uint256][]byte)
+	var collect func(hash common.Uint256)
+	collect = func(hash common.Uint256) {
+		node, ok := self.nodes[hash]
+		if !ok {
+			return
+		}
+		nodes[hash] = node
+		if node[0] == SMT_NODE_PREFIX {
+			a, b := nodeChildren(node)
+			collect(a)
+			collect(b)
+		}
+	}
+	collect(self.root)
+	return nodes
+}
+
+//SMTProof is the inclusion or exclusion proof of key. Siblings are from root to the end of path, which is empty
+//or a leaf. The leaf is the key itself for inclusion proof, or another key in the subtree for exclusion proof.
+type SMTProof struct {
+	Siblings      []common.Uint256
+	HasLeaf       bool
+	LeafPath      common.Uint256
+	LeafValueHash common.Uint256
+}
+
+//Prove return the proof of key in current root
+func (self *SparseMerkleTree) Prove(key []byte) (*SMTProof, error) {
+	path := SMTPath(key)
+	proof := &SMTProof{}
+	hash := self.root
+	for depth := 0; hash != common.UINT256_EMPTY; depth++ {
+		node, err := self.getNode(hash)
+		if err != nil {
+			return nil, err
+		}
+		a, b := nodeChildren(node)
+		if node[0] == SMT_LEAF_PREFIX {
+			proof.HasLeaf = true
+			proof.LeafPath = a
+			proof.LeafValueHash = b
+			break
+		}
+		if smtBit(path, depth) == 0 {
+			proof.Siblings = append(proof.Siblings, b)
+			hash = a
+		} else {
+			proof.Siblings = append(proof.Siblings, a)
+			hash = b
+		}
+	}
+	return proof, nil
+}
+
+//VerifySMTProof verify the proof of key and value against root, nil value verifies the key is not in tree
+func VerifySMTProof(root common.Uint256, key, value []byte, proof *SMTProof) bool {
+	path := SMTPath(key)
+	depth := len(proof.Siblings)
+	if depth > SMT_DEPTH {
+		return false
+	}
+	hash := common.UINT256_EMPTY
+	if value != nil {
+		if !proof.HasLeaf || proof.LeafPath != path || proof.LeafValueHash != sha256.Sum256(value) {
+			return false
+		}
+		hash = smtLeafHash(path, proof.LeafValueHash)
+	} else if proof.HasLeaf {
+		if proof.LeafPath == path {
+			return false
+		}
+		for i := 0; i < depth; i++ {
+			if smtBit(proof.LeafPath, i) != smtBit(path, i) {
+				return false
+			}
+		}
+		hash = smtLeafHash(proof.LeafPath, proof.LeafValueHash)
+	}
+	for i := depth - 1; i >= 0; i-- {
+		if smtBit(path, i) == 0 {
+			hash = smtNodeHash(hash, proof.Siblings[i])
+		} else {
+			hash = smtNodeHash(proof.Siblings[i], hash)
+		}
+	}
+	return hash == root
+}
+
+func (self *SMTProof) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(self.Siblings)))
+	for _, sibling := range self.Siblings {
+		sink.WriteHash(sibling)
+	}
+	sink.WriteBool(self.HasLeaf)
+	if self.HasLeaf {
+		sink.WriteHash(self.LeafPath)
+		sink.WriteHash(self.LeafValueHash)
+	}
+}
+
+func (self *SMTProof) Deserialization(source *common.ZeroCopySource) error {
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if n > SMT_DEPTH {
+		return errors.New("too many siblings of smt proof")
+	}
+	self.Siblings = make([]common.Uint256, 0, n)
+	for i := uint64(0); i < n; i++ {
+		sibling, eof := source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.Siblings = append(self.Siblings, sibling)
+	}
+	self.HasLeaf, irregular, eof = source.NextBool()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if self.HasLeaf {
+		self.LeafPath, eof = source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.LeafValueHash, eof = source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ontio/layer2/node/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type memSMTNodeStore map[common.Uint256][]byte
+
+func (self memSMTNodeStore) GetSMTNode(hash common.Uint256) ([]byte, error) {
+	node, ok := self[hash]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return node, nil
+}
+
+func TestSparseMerkleTree(t *testing.T) {
+	store := make(memSMTNodeStore)
+	tree := NewSparseMerkleTree(store, common.UINT256_EMPTY)
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, tree.Update([]byte{byte(i)}, []byte{byte(i), 1}))
+	}
+	for hash, node := range tree.NewNodes() {
+		store[hash] = node
+	}
+	root := tree.Root()
+
+	//same key set has same root regardless of update order
+	other := NewSparseMerkleTree(make(memSMTNodeStore), common.UINT256_EMPTY)
+	for i := 99; i >= 0; i-- {
+		assert.Nil(t, other.Update([]byte{byte(i)}, []byte{byte(i), 1}))
+	}
+	assert.Equal(t, root, other.Root())
+
+	//reload tree from store
+	tree = NewSparseMerkleTree(store, root)
+	for i := 0; i < 100; i++ {
+		proof, err := tree.Prove([]byte{byte(i)})
+		assert.Nil(t, err)
+		sink := common.NewZeroCopySink(nil)
+		proof.Serialization(sink)
+		decoded := &SMTProof{}
+		assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+		assert.True(t, VerifySMTProof(root, []byte{byte(i)}, []byte{byte(i), 1}, decoded))
+		assert.False(t, VerifySMTProof(root, []byte{byte(i)}, []byte{byte(i), 2}, decoded))
+		assert.False(t, VerifySMTProof(root, []byte{byte(i)}, nil, decoded))
+	}
+	for i := 100; i < 200; i++ {
+		proof, err := tree.Prove([]byte{byte(i)})
+		assert.Nil(t, err)
+		assert.True(t, VerifySMTProof(root, []byte{byte(i)}, nil, proof))
+		assert.False(t, VerifySMTProof(root, []byte{byte(i)}, []byte{1}, proof))
+	}
+
+	//deleting keys collapses the tree back
+	for i := 50; i < 100; i++ {
+		assert.Nil(t, tree.Update([]byte{byte(i)}, nil))
+	}
+	half := NewSparseMerkleTree(make(memSMTNodeStore), common.UINT256_EMPTY)
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, half.Update([]byte{byte(i)}, []byte{byte(i), 1}))
+	}
+	assert.Equal(t, half.Root(), tree.Root())
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, tree.Update([]byte{byte(i)}, nil))
+	}
+	assert.Equal(t, common.UINT256_EMPTY, tree.Root())
+}
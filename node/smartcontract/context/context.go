@@ -41,6 +41,7 @@ type ContextRef interface {
 	NewExecuteEngine(code []byte, txtype types.TransactionType) (Engine, error)
 	CheckUseGas(gas uint64) bool
 	CheckExecStep() bool
+	CheckPreExecLimit() error
 	GetCallerAddress() []common.Address
 	SetInternalErr()
 	IsInternalErr() bool
@@ -70,13 +70,17 @@ func (this *NativeService) Register(methodName string, handler Handler) {
 func (this *NativeService) Invoke() ([]byte, error) {
 	contract := this.InvokeParam
 	services, ok := Contracts[contract.Address]
-	operatorPublicKeyBytes,_ := hex.DecodeString(config.DefConfig.Genesis.SOLO.Bookkeepers[0])
-	operatorPublicKey,_ := keypair.DeserializePublicKey(operatorPublicKeyBytes)
-	operatorAddress := types.AddressFromPubKey(operatorPublicKey)
 	player := this.Tx.Payer.ToBase58()
-	//log.Infof("player: %s, operator: %s", player, operatorAddress.ToBase58())
-	if player == operatorAddress.ToBase58() {
-		this.Operator = true
+	if spec := config.DefConfig.ChainSpec; spec != nil && len(spec.Operators) > 0 {
+		this.Operator = spec.IsOperator(player, this.Height)
+	} else {
+		operatorPublicKeyBytes,_ := hex.DecodeString(config.DefConfig.Genesis.SOLO.Bookkeepers[0])
+		operatorPublicKey,_ := keypair.DeserializePublicKey(operatorPublicKeyBytes)
+		operatorAddress := types.AddressFromPubKey(operatorPublicKey)
+		//log.Infof("player: %s, operator: %s", player, operatorAddress.ToBase58())
+		if player == operatorAddress.ToBase58() {
+			this.Operator = true
+		}
 	}
 	this.MinOngLimit = config.DefConfig.Common.MinOngLimit
 	if !ok {
@@ -21,9 +21,11 @@ package ont
 import (
 	"fmt"
 	"github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/node/common/config"
 	"github.com/ontio/layer2/node/common/constants"
 	"github.com/ontio/layer2/node/common/log"
 	"github.com/ontio/layer2/node/errors"
+	"github.com/ontio/layer2/node/smartcontract/event"
 	"github.com/ontio/layer2/node/smartcontract/service/native"
 	"github.com/ontio/layer2/node/smartcontract/service/native/utils"
 	"math/big"
@@ -49,6 +51,8 @@ func RegisterOntContract(native *native.NativeService) {
 	native.Register(TOTALSUPPLY_NAME, OntTotalSupply)
 	native.Register(BALANCEOF_NAME, OntBalanceOf)
 	native.Register(ALLOWANCE_NAME, OntAllowance)
+	native.Register(FREEZE_NAME, OntFreeze)
+	native.Register(ISFROZEN_NAME, OntIsFrozen)
 }
 
 func OntInit(native *native.NativeService) ([]byte, error) {
@@ -211,6 +215,52 @@ func OntAllowance(native *native.NativeService) ([]byte, error) {
 	return GetBalanceValue(native, APPROVE_FLAG)
 }
 
+//OntFreeze freeze the ONT and ONG of address for its forced exit on L1, only the operator can freeze. The frozen
+//address can still receive but not send, and is never unfrozen as its balance is paid on L1
+func OntFreeze(native *native.NativeService) ([]byte, error) {
+	if !native.Operator {
+		return utils.BYTE_FALSE, errors.NewErr("[OntFreeze] only operator can freeze!")
+	}
+	source := common.NewZeroCopySource(native.Input)
+	addr, err := utils.DecodeAddress(source)
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "[OntFreeze] get address error!")
+	}
+	frozen, err := IsFrozen(native, addr)
+	if err != nil {
+		return utils.BYTE_FALSE, err
+	}
+	if frozen {
+		return utils.BYTE_TRUE, nil
+	}
+	native.CacheDB.Put(GenFrozenKey(addr), utils.GenUInt32StorageItem(1).ToArray())
+	if config.DefConfig.Common.EnableEventLog {
+		native.Notifications = append(native.Notifications,
+			&event.NotifyEventInfo{
+				ContractAddress: native.ContextRef.CurrentContext().ContractAddress,
+				States:          []interface{}{FREEZE_NAME, addr.ToBase58()},
+			})
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+//OntIsFrozen return true if the address is frozen
+func OntIsFrozen(native *native.NativeService) ([]byte, error) {
+	source := common.NewZeroCopySource(native.Input)
+	addr, err := utils.DecodeAddress(source)
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "[OntIsFrozen] get address error!")
+	}
+	frozen, err := IsFrozen(native, addr)
+	if err != nil {
+		return utils.BYTE_FALSE, err
+	}
+	if frozen {
+		return utils.BYTE_TRUE, nil
+	}
+	return utils.BYTE_FALSE, nil
+}
+
 func GetBalanceValue(native *native.NativeService, flag byte) ([]byte, error) {
 	source := common.NewZeroCopySource(native.Input)
 	from, err := utils.DecodeAddress(source)
@@ -42,6 +42,9 @@ const (
 	TOTALSUPPLY_NAME    = "totalSupply"
 	BALANCEOF_NAME      = "balanceOf"
 	ALLOWANCE_NAME      = "allowance"
+	FREEZE_NAME         = "freeze"
+	ISFROZEN_NAME       = "isFrozen"
+	FROZEN_NAME         = "frozen"
 )
 
 func AddNotifications(native *native.NativeService, contract common.Address, state *State) {
@@ -64,6 +67,21 @@ func GenTotalSupplyKey(contract common.Address) []byte {
 	return append(contract[:], TOTAL_SUPPLY_NAME...)
 }
 
+//GenFrozenKey return the key of the frozen flag of address, which is kept by the ONT contract for both ONT and ONG
+func GenFrozenKey(addr common.Address) []byte {
+	temp := append(utils.OntContractAddress[:], FROZEN_NAME...)
+	return append(temp, addr[:]...)
+}
+
+//IsFrozen return true if the address is frozen by the operator for the forced exit on L1
+func IsFrozen(native *native.NativeService, addr common.Address) (bool, error) {
+	flag, err := utils.GetStorageUInt32(native, GenFrozenKey(addr))
+	if err != nil {
+		return false, err
+	}
+	return flag != 0, nil
+}
+
 func GenBalanceKey(contract, addr common.Address) []byte {
 	return append(contract[:], addr[:]...)
 }
@@ -79,6 +97,9 @@ func Transfer(native *native.NativeService, contract common.Address, state *Stat
 		if !native.ContextRef.CheckWitness(state.From) {
 			return 0, 0, errors.NewErr("authentication failed!")
 		}
+		if err := checkNotFrozen(native, state.From); err != nil {
+			return 0, 0, err
+		}
 	}
 
 	//
@@ -118,6 +139,9 @@ func TransferedFrom(native *native.NativeService, currentContract common.Address
 		if native.ContextRef.CheckWitness(state.Sender) == false {
 			return 0, 0, errors.NewErr("authentication failed!")
 		}
+		if err := checkNotFrozen(native, state.From); err != nil {
+			return 0, 0, err
+		}
 	}
 	isLayer2Withdraw := IsLayer2Addr(state.To)
 
@@ -144,6 +168,17 @@ func TransferedFrom(native *native.NativeService, currentContract common.Address
 	return fromBalance, toBalance, nil
 }
 
+func checkNotFrozen(native *native.NativeService, addr common.Address) error {
+	frozen, err := IsFrozen(native, addr)
+	if err != nil {
+		return err
+	}
+	if frozen {
+		return fmt.Errorf("[Transfer] account %s is frozen", addr.ToBase58())
+	}
+	return nil
+}
+
 func IsLayer2Addr(addr common.Address) bool {
 	if addr.ToHexString() == common.ADDRESS_EMPTY.ToHexString() {
 		return true
@@ -134,8 +134,13 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 	var gasTable [256]uint64
 	for {
 		//check the execution step count
-		if this.PreExec && !this.ContextRef.CheckExecStep() {
-			return nil, VM_EXEC_STEP_EXCEED
+		if this.PreExec {
+			if !this.ContextRef.CheckExecStep() {
+				return nil, VM_EXEC_STEP_EXCEED
+			}
+			if err := this.ContextRef.CheckPreExecLimit(); err != nil {
+				return nil, err
+			}
 		}
 		if this.Engine.Context == nil {
 			break
@@ -20,6 +20,7 @@ package smartcontract
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ontio/layer2/node/common"
 	"github.com/ontio/layer2/node/common/config"
@@ -34,7 +35,13 @@ import (
 )
 
 const (
-	MAX_EXECUTE_ENGINE = 128
+	MAX_EXECUTE_ENGINE         = 128
+	PRE_EXEC_LIMIT_CHECK_STEPS = 256 //Count of execution steps between pre-execution deadline and memory checks
+)
+
+var (
+	ErrPreExecTimeout        = errors.New("pre-execution exceeded the deadline")
+	ErrPreExecMemoryExceeded = errors.New("pre-execution exceeded the state cache memory limit")
 )
 
 // SmartContract describe smart contract execute engine
@@ -50,6 +57,8 @@ type SmartContract struct {
 	WasmExecStep  uint64
 	JitMode       bool
 	PreExec       bool
+	Deadline      time.Time // deadline of pre-execution, zero means no deadline
+	MemoryLimit   int       // limit of state cache bytes of pre-execution, zero means no limit
 	internelErr   bool
 }
 
@@ -110,6 +119,20 @@ func (this *SmartContract) CheckExecStep() bool {
 	return true
 }
 
+// CheckPreExecLimit check the deadline and state cache size of pre-execution every PRE_EXEC_LIMIT_CHECK_STEPS steps
+func (this *SmartContract) CheckPreExecLimit() error {
+	if this.ExecStep%PRE_EXEC_LIMIT_CHECK_STEPS != 0 {
+		return nil
+	}
+	if !this.Deadline.IsZero() && time.Now().After(this.Deadline) {
+		return ErrPreExecTimeout
+	}
+	if this.MemoryLimit > 0 && this.CacheDB.GetMemDb().Size() > this.MemoryLimit {
+		return ErrPreExecMemoryExceeded
+	}
+	return nil
+}
+
 func (this *SmartContract) CheckUseGas(gas uint64) bool {
 	if this.Gas < gas {
 		return false
@@ -127,9 +150,9 @@ func (this *SmartContract) checkContexts() bool {
 
 func NewVmFeatureFlag(blockHeight uint32) vm.VmFeatureFlag {
 	var feature vm.VmFeatureFlag
-	enableHeight := config.GetOpcodeUpdateCheckHeight(config.NETWORK_ID_SOLO_NET)
-	feature.DisableHasKey = blockHeight <= enableHeight
-	feature.AllowReaderEOF = blockHeight <= enableHeight
+	active := config.IsFeatureActive(config.FEATURE_OPCODE_HASKEY, blockHeight)
+	feature.DisableHasKey = !active
+	feature.AllowReaderEOF = !active
 
 	return feature
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/ontio/layer2/node/core/ledger"
 	tx "github.com/ontio/layer2/node/core/types"
 	"github.com/ontio/layer2/node/errors"
+	"github.com/ontio/layer2/node/events"
 	httpcom "github.com/ontio/layer2/node/http/base/common"
 	params "github.com/ontio/layer2/node/smartcontract/service/native/global_params"
 	nutils "github.com/ontio/layer2/node/smartcontract/service/native/utils"
@@ -85,6 +86,7 @@ type TXPoolServer struct {
 	gasPrice              uint64                              // Gas price to enforce for acceptance into the pool
 	disablePreExec        bool                                // Disbale PreExecute a transaction
 	disableBroadcastNetTx bool                                // Disable broadcast tx from network
+	blockSub              *events.BlockSubscription           // The subscription of saved blocks, nil if not subscribed
 }
 
 // NewTxPoolServer creates a new tx pool server to schedule workers to
@@ -472,6 +474,9 @@ func (s *TXPoolServer) getNextValidatorPID(key types.VerifyType) *actor.PID {
 
 // Stop stops server and workers.
 func (s *TXPoolServer) Stop() {
+	if s.blockSub != nil {
+		s.blockSub.Close()
+	}
 	for _, v := range s.actors {
 		v.Stop()
 	}
@@ -558,6 +563,29 @@ func (s *TXPoolServer) cleanTransactionList(txs []*tx.Transaction, height uint32
 	}
 }
 
+// SubscribeBlocks cleans the tx pool with the blocks saved to ledger. The
+// blocks are received in order from the feed without passing the bounded
+// actor mailbox, so no block is dropped when the pool is busy.
+func (s *TXPoolServer) SubscribeBlocks(feed *events.BlockFeed) error {
+	sub, err := feed.Subscribe(events.BlockSubscriptionConfig{
+		Name:   "txpool",
+		Policy: events.POLICY_CATCH_UP,
+	})
+	if err != nil {
+		return err
+	}
+	s.blockSub = sub
+	go func() {
+		for evt := range sub.Events() {
+			if evt.Missed > 0 {
+				log.Warnf("txpool missed %d blocks before block %d", evt.Missed, evt.Block.Header.Height)
+			}
+			s.cleanTransactionList(evt.Block.Transactions, evt.Block.Header.Height)
+		}
+	}()
+	return nil
+}
+
 // delTransaction deletes a transaction in the tx pool.
 func (s *TXPoolServer) delTransaction(t *tx.Transaction) {
 	s.txPool.DelTxList(t)
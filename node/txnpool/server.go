@@ -83,7 +83,14 @@ func StartTxnPoolServer(disablePreExec, disableBroadcastNetTx bool) (*tp.TXPoolS
 	s.RegisterActor(tc.TxActor, txPid)
 
 	// Subscribe the block complete event
-	var sub = events.NewActorSubscriber(txPoolPid)
-	sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	if events.DefBlockFeed != nil {
+		err = s.SubscribeBlocks(events.DefBlockFeed)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var sub = events.NewActorSubscriber(txPoolPid)
+		sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	}
 	return s, nil
 }
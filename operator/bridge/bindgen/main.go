@@ -0,0 +1,312 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// bindgen generate the method and event tables of the bridge package from the layer2 contracts, the abi of the evm
+// target is read from contract/Layer2.sol and the params are checked against contract/layer2.py of the neovm target,
+// so both bindings follow the deployed contracts. Run by go generate in the bridge package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const CONTRACT_NAME = "Layer2"
+
+var (
+	solFile = flag.String("sol", "../../contract/Layer2.sol", "solidity contract of the evm target")
+	pyFile  = flag.String("py", "../../contract/layer2.py", "python contract of the neovm target")
+	outFile = flag.String("out", "contract_gen.go", "generated file of the bridge package")
+	methods = flag.String("methods", "", "comma separated functions of the contract bound by the bridge")
+	events  = flag.String("events", "", "comma separated events of the evm contract decoded by the bridge")
+)
+
+// the contracts carry no go license header, so the header of the package is written before the generated notice
+const licenseHeader = `/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */`
+
+const generatedNotice = "// Code generated by bindgen from contract/Layer2.sol and contract/layer2.py. DO NOT EDIT.\n\n"
+
+// the solidity types of the params and the ParamType of the bridge they are mapped to
+var solTypes = map[string]string{
+	"uint256":   "TYPE_UINT",
+	"address":   "TYPE_ADDRESS",
+	"bytes":     "TYPE_BYTES",
+	"string":    "TYPE_STRING",
+	"uint256[]": "TYPE_UINT_ARRAY",
+	"address[]": "TYPE_ADDRESS_ARRAY",
+	"bytes[]":   "TYPE_BYTES_ARRAY",
+}
+
+var (
+	solContract = regexp.MustCompile(`contract\s+` + CONTRACT_NAME + `\b[^{]*\{`)
+	solFunction = regexp.MustCompile(`function\s+(\w+)\s*\(([^)]*)\)([^{;]*)[{;]`)
+	solReturns  = regexp.MustCompile(`returns\s*\(([^)]*)\)`)
+	solEvent    = regexp.MustCompile(`event\s+(\w+)\s*\(([^)]*)\)\s*;`)
+	pyFunction  = regexp.MustCompile(`(?m)^def\s+(\w+)\s*\(([^)]*)\)\s*:`)
+)
+
+type param struct {
+	Name string
+	Type string
+}
+
+// the function of the contract, the params are parsed when it is bound
+type function struct {
+	Params   string
+	Returns  string
+	ReadOnly bool
+}
+
+// parseParams parse the param list of solidity, the names of the params are required if named is true
+func parseParams(list string, named bool) ([]param, error) {
+	params := make([]param, 0)
+	if strings.TrimSpace(list) == "" {
+		return params, nil
+	}
+	for _, item := range strings.Split(list, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty param in (%s)", list)
+		}
+		typ, ok := solTypes[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("param type %s is not supported by the bridge", fields[0])
+		}
+		name := ""
+		if last := fields[len(fields)-1]; len(fields) > 1 && last != "memory" && last != "calldata" {
+			name = last
+		}
+		if named && name == "" {
+			return nil, fmt.Errorf("param %s has no name", strings.TrimSpace(item))
+		}
+		params = append(params, param{Name: name, Type: typ})
+	}
+	return params, nil
+}
+
+// parseSolidity return the public functions and the param lists of the events of the layer2 contract
+func parseSolidity(src []byte) (map[string]*function, map[string]string, error) {
+	loc := solContract.FindIndex(src)
+	if loc == nil {
+		return nil, nil, fmt.Errorf("contract %s is not found", CONTRACT_NAME)
+	}
+	body := string(src[loc[1]:])
+	functions := make(map[string]*function)
+	for _, match := range solFunction.FindAllStringSubmatch(body, -1) {
+		modifiers := strings.Fields(match[3])
+		if !hasField(modifiers, "public") && !hasField(modifiers, "external") {
+			continue
+		}
+		f := &function{Params: match[2], ReadOnly: hasField(modifiers, "view") || hasField(modifiers, "pure")}
+		if returns := solReturns.FindStringSubmatch(match[3]); returns != nil {
+			f.Returns = returns[1]
+		}
+		functions[match[1]] = f
+	}
+	events := make(map[string]string)
+	for _, match := range solEvent.FindAllStringSubmatch(body, -1) {
+		events[match[1]] = match[2]
+	}
+	return functions, events, nil
+}
+
+// parsePython return the param names of the functions of the neovm contract
+func parsePython(src []byte) map[string][]string {
+	functions := make(map[string][]string)
+	for _, match := range pyFunction.FindAllStringSubmatch(string(src), -1) {
+		names := make([]string, 0)
+		for _, name := range strings.Split(match[2], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		functions[match[1]] = names
+	}
+	return functions
+}
+
+func hasField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// constName return the METHOD_ constant of the method, as depositNFT to METHOD_DEPOSIT_NFT
+func constName(name string) string {
+	runes := []rune(name)
+	buf := new(bytes.Buffer)
+	buf.WriteString("METHOD_")
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+			buf.WriteByte('_')
+		}
+		buf.WriteRune(unicode.ToUpper(r))
+	}
+	return buf.String()
+}
+
+func writeParams(buf *bytes.Buffer, field string, params []param) {
+	if len(params) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "%s: []Param{\n", field)
+	for _, p := range params {
+		if p.Name == "" {
+			fmt.Fprintf(buf, "{Type: %s},\n", p.Type)
+		} else {
+			fmt.Fprintf(buf, "{Name: %q, Type: %s},\n", p.Name, p.Type)
+		}
+	}
+	buf.WriteString("},\n")
+}
+
+func splitList(list string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// generate return the source of the method and event tables of the bridge package
+func generate(sol []byte, py []byte, methods []string, events []string) ([]byte, error) {
+	functions, solEvents, err := parseSolidity(sol)
+	if err != nil {
+		return nil, err
+	}
+	pyFunctions := parsePython(py)
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no method is bound")
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(licenseHeader + "\n\n")
+	buf.WriteString(generatedNotice)
+	buf.WriteString("package bridge\n\nconst (\n")
+	for _, name := range methods {
+		fmt.Fprintf(buf, "%s = %q\n", constName(name), name)
+	}
+	buf.WriteString(")\n\n// the interface of the layer2 bridge contract on L1, bindings of all targets are built from it\n")
+	buf.WriteString("var Methods = []Method{\n")
+	for _, name := range methods {
+		f, ok := functions[name]
+		if !ok {
+			return nil, fmt.Errorf("function %s is not a public function of contract %s", name, CONTRACT_NAME)
+		}
+		params, err := parseParams(f.Params, true)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %s", name, err)
+		}
+		// the outputs of the transactions are not read by the bridge, only the outputs of the view functions
+		var outputs []param
+		if f.ReadOnly {
+			outputs, err = parseParams(f.Returns, false)
+			if err != nil {
+				return nil, fmt.Errorf("function %s returns: %s", name, err)
+			}
+		}
+		pyParams, ok := pyFunctions[name]
+		if !ok {
+			return nil, fmt.Errorf("function %s is not defined by the neovm contract", name)
+		}
+		if len(pyParams) != len(params) {
+			return nil, fmt.Errorf("function %s has %d params on evm, %d on neovm", name, len(params), len(pyParams))
+		}
+		for i, p := range params {
+			if pyParams[i] != p.Name {
+				return nil, fmt.Errorf("function %s param %d is %s on evm, %s on neovm", name, i, p.Name, pyParams[i])
+			}
+		}
+		fmt.Fprintf(buf, "{\nName: %s,\n", constName(name))
+		writeParams(buf, "Params", params)
+		writeParams(buf, "Outputs", outputs)
+		if f.ReadOnly {
+			buf.WriteString("ReadOnly: true,\n")
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n\n// the events of the bridge contract decoded from the logs of the evm target\n")
+	buf.WriteString("var evmEvents = []Method{\n")
+	for _, name := range events {
+		list, ok := solEvents[name]
+		if !ok {
+			return nil, fmt.Errorf("event %s is not defined by contract %s", name, CONTRACT_NAME)
+		}
+		params, err := parseParams(list, true)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %s", name, err)
+		}
+		fmt.Fprintf(buf, "{\nName: %q,\n", name)
+		writeParams(buf, "Params", params)
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	flag.Parse()
+	sol, err := ioutil.ReadFile(*solFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read %s error: %s\n", *solFile, err)
+		os.Exit(1)
+	}
+	py, err := ioutil.ReadFile(*pyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read %s error: %s\n", *pyFile, err)
+		os.Exit(1)
+	}
+	out, err := generate(sol, py, splitList(*methods), splitList(*events))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generate from %s error: %s\n", *solFile, err)
+		os.Exit(1)
+	}
+	err = ioutil.WriteFile(*outFile, out, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write %s error: %s\n", *outFile, err)
+		os.Exit(1)
+	}
+}
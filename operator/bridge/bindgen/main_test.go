@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// generateArgs return the methods and events of the go:generate line of the bridge package
+func generateArgs(t *testing.T) ([]string, []string) {
+	file, err := os.Open("../bridge.go")
+	if err != nil {
+		t.Fatalf("open bridge.go err: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "//go:generate go run ./bindgen ") {
+			continue
+		}
+		flags := flag.NewFlagSet("bindgen", flag.ContinueOnError)
+		methods := flags.String("methods", "", "")
+		events := flags.String("events", "", "")
+		if err := flags.Parse(strings.Fields(line)[4:]); err != nil {
+			t.Fatalf("parse go:generate line err: %v", err)
+		}
+		return splitList(*methods), splitList(*events)
+	}
+	t.Fatalf("go:generate line of bindgen is not found in bridge.go")
+	return nil, nil
+}
+
+// the generated tables of the bridge are the same as the contracts, run go generate in the bridge package if the
+// contracts change
+func TestGeneratedBindings(t *testing.T) {
+	sol, err := ioutil.ReadFile("../../../contract/Layer2.sol")
+	if err != nil {
+		t.Fatalf("read solidity contract err: %v", err)
+	}
+	py, err := ioutil.ReadFile("../../../contract/layer2.py")
+	if err != nil {
+		t.Fatalf("read python contract err: %v", err)
+	}
+	methods, events := generateArgs(t)
+	want, err := generate(sol, py, methods, events)
+	if err != nil {
+		t.Fatalf("generate err: %v", err)
+	}
+	got, err := ioutil.ReadFile("../contract_gen.go")
+	if err != nil {
+		t.Fatalf("read generated bindings err: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("contract_gen.go is out of date with the contracts, run go generate")
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	const sol = `contract Layer2 {
+    event DepositEvent(uint256 id, address player);
+    event BadEvent(uint256);
+    function deposit(address player, uint256 amount) public returns (bool) {}
+    function withdraw(uint256 withdrawId) public returns (bool) {}
+    function setLimit(uint8 limit) public returns (bool) {}
+    function refund(uint256 id) internal returns (bool) {}
+}`
+	const py = `def deposit(player, amount):
+def withdraw(id):
+def setLimit(limit):
+def refund(id):
+`
+	cases := []struct {
+		name    string
+		sol     string
+		methods []string
+		events  []string
+	}{
+		{"no contract", "contract Other {}", []string{"deposit"}, nil},
+		{"no method", sol, nil, nil},
+		{"unknown method", sol, []string{"transfer"}, nil},
+		{"internal method", sol, []string{"refund"}, nil},
+		{"unsupported type", sol, []string{"setLimit"}, nil},
+		{"param differ from neovm", sol, []string{"withdraw"}, nil},
+		{"not on neovm", strings.Replace(sol, "deposit(", "depositAll(", 1), []string{"depositAll"}, nil},
+		{"unknown event", sol, []string{"deposit"}, []string{"ExitEvent"}},
+		{"unnamed event param", sol, []string{"deposit"}, []string{"BadEvent"}},
+	}
+	for _, c := range cases {
+		if _, err := generate([]byte(c.sol), []byte(py), c.methods, c.events); err == nil {
+			t.Errorf("%s: bindings are generated", c.name)
+		}
+	}
+	if _, err := generate([]byte(sol), []byte(py), []string{"deposit"}, []string{"DepositEvent"}); err != nil {
+		t.Errorf("generate err: %v", err)
+	}
+}
+
+func TestConstName(t *testing.T) {
+	cases := map[string]string{
+		"deposit":              "METHOD_DEPOSIT",
+		"depositNFT":           "METHOD_DEPOSIT_NFT",
+		"getStateRootByHeight": "METHOD_GET_STATE_ROOT_BY_HEIGHT",
+		"NFTDeposit":           "METHOD_NFT_DEPOSIT",
+	}
+	for name, want := range cases {
+		if got := constName(name); got != want {
+			t.Errorf("const of %s is %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"fmt"
+)
+
+// the method tables are generated from the layer2 contracts, the abi of contract/Layer2.sol and the params of
+// contract/layer2.py, so the bindings of both targets follow the deployed contracts
+//go:generate go run ./bindgen -methods deposit,depositNFT,withdraw,updateState,setToken,refundDeposit,challengeState,requestExit,challengeExit,finalizeExit,startMassExit,claimMassExit,registerOperator,switchOperator,cancelOperator,getStateRootByHeight,getCurrentHeight,getMassExitHeight,getOperator -events DepositEvent,NFTDepositEvent,RevertStateEvent,ExitRequestEvent
+
+// the notify names of the neovm contract which are not methods of the bridge
+const (
+	METHOD_UPDATE_DEPOSIT_STATE = "updateDepositState"
+	METHOD_REVERT_STATE         = "revertState"
+)
+
+// the type of the method param, mapped to the type of every target vm
+type ParamType string
+
+const (
+	TYPE_BYTES         ParamType = "bytes"
+	TYPE_STRING        ParamType = "string"
+	TYPE_UINT          ParamType = "uint"
+	TYPE_ADDRESS       ParamType = "address"
+	TYPE_UINT_ARRAY    ParamType = "uint[]"
+	TYPE_ADDRESS_ARRAY ParamType = "address[]"
+	TYPE_BYTES_ARRAY   ParamType = "bytes[]"
+)
+
+type Param struct {
+	Name string
+	Type ParamType
+}
+
+type Method struct {
+	Name     string
+	Params   []Param
+	Outputs  []Param
+	ReadOnly bool
+}
+
+func GetMethod(name string) (*Method, error) {
+	for i := range Methods {
+		if Methods[i].Name == name {
+			return &Methods[i], nil
+		}
+	}
+	return nil, fmt.Errorf("bridge method %s is not defined", name)
+}
+
+type DepositParam struct {
+	Player       []byte
+	Amount       uint64
+	AssetAddress []byte
+}
+
+// the standard of the non-fungible token deposited by depositNFT
+const (
+	STANDARD_OEP5 = 5
+	STANDARD_OEP8 = 8
+)
+
+// the OEP-5 token is deposited by the amount 1
+type DepositNFTParam struct {
+	Player       []byte
+	AssetAddress []byte
+	TokenId      []byte
+	Amount       uint64
+	Standard     uint64
+}
+
+type WithdrawParam struct {
+	WithdrawId uint64
+}
+
+type UpdateStateParam struct {
+	StateRootHash   string
+	Height          uint32
+	Version         string
+	DepositIds      []uint64
+	WithdrawAmounts []uint64
+	ToAddresses     [][]byte
+	AssetAddresses  [][]byte
+	// the token id of the non-fungible withdraw, empty for the fungible one
+	TokenIds        [][]byte
+	// the layer2 token contract of the withdraw, the leaf of the withdraw is the account of the layer2 token contract
+	// and the to address in the state root
+	Layer2Assets    [][]byte
+	// the sparse merkle proof of the leaf of the withdraw in the state root, built by EncodeWithdrawProof
+	WithdrawProofs  [][]byte
+}
+
+type SetTokenParam struct {
+	AssetAddress []byte
+	Enabled      bool
+}
+
+type RefundDepositParam struct {
+	DepositId uint64
+}
+
+// the fraud proof of the commit of Height, Layer2State is the serialized layer2 state of Height signed by the layer2
+// bookkeepers, whose state root differs from the committed one
+type ChallengeStateParam struct {
+	Height      uint32
+	Layer2State []byte
+}
+
+// the forced exit of the balance of Player in the layer2 state root of the committed height, Value is the serialized
+// storage item of the layer2 account and Proof is built by EncodeWithdrawProof
+type RequestExitParam struct {
+	Player       []byte
+	AssetAddress []byte
+	Value        []byte
+	Proof        []byte
+}
+
+// the lower balance of the player of the exit in the layer2 state root of Height committed after the exit
+type ChallengeExitParam struct {
+	ExitId uint64
+	Height uint32
+	Value  []byte
+	Proof  []byte
+}
+
+type FinalizeExitParam struct {
+	ExitId uint64
+}
+
+// the balance of Player in the layer2 state root of the committed height, claimed once the mass exit is started
+type ClaimMassExitParam struct {
+	Player       []byte
+	AssetAddress []byte
+	Value        []byte
+	Proof        []byte
+}
+
+// the rotation of the operator key to NewOperator, the transactions of the operator are signed by both keys in the
+// TransitionPeriod blocks, and the new key can switch after them
+type RegisterOperatorParam struct {
+	NewOperator      []byte
+	TransitionPeriod uint64
+}
+
+// the operator of the contract, NewOperator is empty if no rotation is pending
+type OperatorState struct {
+	Operator    []byte
+	NewOperator []byte
+	SwitchBlock uint64
+}
+
+type StateRoot struct {
+	StateRootHash string
+	Height        uint64
+	Version       string
+}
+
+type DepositEvent struct {
+	// the version of the event schema
+	Version      int
+	ID           uint64
+	Player       []byte
+	Amount       uint64
+	Height       uint64
+	Status       uint64
+	AssetAddress string
+	// the hex of the token id, empty for the fungible deposit
+	TokenId      string
+}
+
+type ExitEvent struct {
+	Version      int
+	ID           uint64
+	Player       []byte
+	AssetAddress string
+	Amount       uint64
+	// the layer2 height of the state root proving the balance
+	Height       uint64
+	// the L1 block of the exit request, the exit can be finalized after the challenge period since it
+	BlockHeight  uint64
+}
+
+// Bridge is the typed binding of the bridge contract
+type Bridge interface {
+	// build the invoke params of methods
+	DepositParams(param *DepositParam) ([]interface{}, error)
+	DepositNFTParams(param *DepositNFTParam) ([]interface{}, error)
+	WithdrawParams(param *WithdrawParam) ([]interface{}, error)
+	UpdateStateParams(param *UpdateStateParam) ([]interface{}, error)
+	SetTokenParams(param *SetTokenParam) ([]interface{}, error)
+	RefundDepositParams(param *RefundDepositParam) ([]interface{}, error)
+	ChallengeStateParams(param *ChallengeStateParam) ([]interface{}, error)
+	RequestExitParams(param *RequestExitParam) ([]interface{}, error)
+	ChallengeExitParams(param *ChallengeExitParam) ([]interface{}, error)
+	FinalizeExitParams(param *FinalizeExitParam) ([]interface{}, error)
+	StartMassExitParams() ([]interface{}, error)
+	ClaimMassExitParams(param *ClaimMassExitParam) ([]interface{}, error)
+	GetStateRootByHeightParams(height uint64) ([]interface{}, error)
+	GetCurrentHeightParams() ([]interface{}, error)
+	GetMassExitHeightParams() ([]interface{}, error)
+	RegisterOperatorParams(param *RegisterOperatorParam) ([]interface{}, error)
+	SwitchOperatorParams() ([]interface{}, error)
+	CancelOperatorParams() ([]interface{}, error)
+	GetOperatorParams() ([]interface{}, error)
+	// parse the results and events of contract
+	ParseStateRoot(result interface{}) (*StateRoot, error)
+	ParseCurrentHeight(result interface{}) (uint64, error)
+	ParseMassExitHeight(result interface{}) (uint64, error)
+	ParseOperator(result interface{}) (*OperatorState, error)
+	EventName(states interface{}) (string, error)
+	ParseDepositEvent(states interface{}) (*DepositEvent, error)
+	ParseExitEvent(states interface{}) (*ExitEvent, error)
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"fmt"
+)
+
+// the schema of the states of an event of the bridge contract, following the event name. A change of the states is a
+// new version of the event, the versions emitted by the contracts deployed are all kept to decode the old blocks
+type EventSchema struct {
+	Event   string
+	Version int
+	States  []Param
+}
+
+var EventSchemas = []EventSchema{
+	{
+		Event:   METHOD_DEPOSIT,
+		Version: 1,
+		States: []Param{
+			{Name: "id", Type: TYPE_UINT},
+			{Name: "player", Type: TYPE_ADDRESS},
+			{Name: "amount", Type: TYPE_UINT},
+			{Name: "height", Type: TYPE_UINT},
+			{Name: "status", Type: TYPE_UINT},
+			{Name: "assetAddress", Type: TYPE_BYTES},
+		},
+	},
+	{
+		Event:   METHOD_DEPOSIT_NFT,
+		Version: 1,
+		States: []Param{
+			{Name: "id", Type: TYPE_UINT},
+			{Name: "player", Type: TYPE_ADDRESS},
+			{Name: "amount", Type: TYPE_UINT},
+			{Name: "height", Type: TYPE_UINT},
+			{Name: "status", Type: TYPE_UINT},
+			{Name: "assetAddress", Type: TYPE_BYTES},
+			{Name: "tokenId", Type: TYPE_BYTES},
+		},
+	},
+	{
+		Event:   METHOD_REQUEST_EXIT,
+		Version: 1,
+		States: []Param{
+			{Name: "id", Type: TYPE_UINT},
+			{Name: "player", Type: TYPE_ADDRESS},
+			{Name: "assetAddress", Type: TYPE_BYTES},
+			{Name: "amount", Type: TYPE_UINT},
+			{Name: "height", Type: TYPE_UINT},
+			{Name: "blockHeight", Type: TYPE_UINT},
+		},
+	},
+}
+
+// DecodeError is the error of an event of the bridge contract, or a notify of a bridged token, not matching its schema
+type DecodeError struct {
+	Event  string
+	Reason string
+}
+
+func (this *DecodeError) Error() string {
+	if this.Event == "" {
+		return fmt.Sprintf("decode event error: %s", this.Reason)
+	}
+	return fmt.Sprintf("decode %s event error: %s", this.Event, this.Reason)
+}
+
+func decodeError(event string, format string, args ...interface{}) error {
+	return &DecodeError{Event: event, Reason: fmt.Sprintf(format, args...)}
+}
+
+// the schema of the event of the states, the version is identified by the number of the states
+func eventSchema(event string, states []interface{}) (*EventSchema, error) {
+	found := false
+	for i := range EventSchemas {
+		if EventSchemas[i].Event != event {
+			continue
+		}
+		found = true
+		if len(EventSchemas[i].States)+1 == len(states) {
+			return &EventSchemas[i], nil
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("event %s has no schema", event)
+	}
+	return nil, decodeError(event, "no version of %d states", len(states)-1)
+}
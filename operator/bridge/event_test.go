@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func depositStates() []interface{} {
+	return []interface{}{neovmHex(METHOD_DEPOSIT), neovmUint(7), hex.EncodeToString(testPlayer[:]), neovmUint(1000),
+		neovmUint(20), neovmUint(1), "0000000000000000000000000000000000000001"}
+}
+
+func TestNeoVMParseDepositEvent(t *testing.T) {
+	b := NewNeoVMBridge()
+	event, err := b.ParseDepositEvent(depositStates())
+	if err != nil {
+		t.Fatalf("parse deposit err: %v", err)
+	}
+	want := &DepositEvent{Version: 1, ID: 7, Player: testPlayer[:], Amount: 1000, Height: 20, Status: 1,
+		AssetAddress: "0000000000000000000000000000000000000001"}
+	if !reflect.DeepEqual(event, want) {
+		t.Errorf("deposit event %+v, want %+v", event, want)
+	}
+
+	states := append(depositStates(), "0a")
+	states[0] = neovmHex(METHOD_DEPOSIT_NFT)
+	event, err = b.ParseDepositEvent(states)
+	if err != nil {
+		t.Fatalf("parse nft deposit err: %v", err)
+	}
+	if event.TokenId != "0a" || event.ID != 7 {
+		t.Errorf("nft deposit event %+v", event)
+	}
+}
+
+func TestNeoVMParseExitEvent(t *testing.T) {
+	states := []interface{}{neovmHex(METHOD_REQUEST_EXIT), neovmUint(3), hex.EncodeToString(testPlayer[:]), "0a0b",
+		neovmUint(500), neovmUint(40), neovmUint(90)}
+	event, err := NewNeoVMBridge().ParseExitEvent(states)
+	if err != nil {
+		t.Fatalf("parse exit err: %v", err)
+	}
+	want := &ExitEvent{Version: 1, ID: 3, Player: testPlayer[:], AssetAddress: "0a0b", Amount: 500, Height: 40, BlockHeight: 90}
+	if !reflect.DeepEqual(event, want) {
+		t.Errorf("exit event %+v, want %+v", event, want)
+	}
+}
+
+func TestNeoVMDecodeError(t *testing.T) {
+	b := NewNeoVMBridge()
+	mutate := func(i int, value interface{}) []interface{} {
+		states := depositStates()
+		states[i] = value
+		return states
+	}
+	cases := []struct {
+		name   string
+		states interface{}
+	}{
+		{"not array", "deposit"},
+		{"name not hex", mutate(0, "deposit")},
+		{"unknown version", depositStates()[:6]},
+		{"id not string", mutate(1, 7)},
+		{"id not hex", mutate(1, "zz")},
+		{"negative amount", mutate(3, "ff")},
+		{"amount over uint64", mutate(3, "010203040506070809")},
+		{"player not address", mutate(2, "0102")},
+	}
+	for _, c := range cases {
+		_, err := b.ParseDepositEvent(c.states)
+		if _, ok := err.(*DecodeError); !ok {
+			t.Errorf("%s: err %v, want DecodeError", c.name, err)
+		}
+	}
+	// the event of another name is not decoded as deposit
+	states := depositStates()
+	states[0] = neovmHex(METHOD_REQUEST_EXIT)
+	if _, err := b.ParseDepositEvent(states); err == nil {
+		t.Errorf("exit event is decoded as deposit")
+	}
+}
+
+func TestEventSchemaVersion(t *testing.T) {
+	schema, err := eventSchema(METHOD_DEPOSIT, depositStates())
+	if err != nil || schema.Event != METHOD_DEPOSIT || schema.Version != 1 {
+		t.Errorf("schema %+v, %v", schema, err)
+	}
+	if _, err = eventSchema("unknown", depositStates()); err == nil {
+		t.Errorf("event without schema is decoded")
+	}
+	if _, err = eventSchema(METHOD_DEPOSIT, depositStates()[:3]); err == nil {
+		t.Errorf("states of no version are decoded")
+	}
+}
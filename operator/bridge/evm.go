@@ -0,0 +1,506 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethereum_common "github.com/ethereum/go-ethereum/common"
+)
+
+var evmTypes = map[ParamType]string{
+	TYPE_BYTES:         "bytes",
+	TYPE_STRING:        "string",
+	TYPE_UINT:          "uint256",
+	TYPE_ADDRESS:       "address",
+	TYPE_UINT_ARRAY:    "uint256[]",
+	TYPE_ADDRESS_ARRAY: "address[]",
+	TYPE_BYTES_ARRAY:   "bytes[]",
+}
+
+type evmABIParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type evmABIEntry struct {
+	Type            string        `json:"type"`
+	Name            string        `json:"name"`
+	Inputs          []evmABIParam `json:"inputs"`
+	Outputs         []evmABIParam `json:"outputs,omitempty"`
+	StateMutability string        `json:"stateMutability,omitempty"`
+}
+
+// the events of the bridge contract on evm, solidity does not allow the event named as the function, so the
+// deposit events are named DepositEvent and NFTDepositEvent
+const (
+	EVM_EVENT_DEPOSIT     = "DepositEvent"
+	EVM_EVENT_DEPOSIT_NFT = "NFTDepositEvent"
+	EVM_EVENT_REVERT      = "RevertStateEvent"
+	EVM_EVENT_EXIT        = "ExitRequestEvent"
+)
+
+// EVMABI return the abi json of bridge contract for evm target
+func EVMABI() (string, error) {
+	entries := make([]evmABIEntry, 0, len(Methods) + len(evmEvents))
+	for _, method := range Methods {
+		inputs, err := evmABIParams(method.Params)
+		if err != nil {
+			return "", err
+		}
+		outputs, err := evmABIParams(method.Outputs)
+		if err != nil {
+			return "", err
+		}
+		mutability := "nonpayable"
+		if method.ReadOnly {
+			mutability = "view"
+		}
+		entries = append(entries, evmABIEntry{
+			Type:            "function",
+			Name:            method.Name,
+			Inputs:          inputs,
+			Outputs:         outputs,
+			StateMutability: mutability,
+		})
+	}
+	for _, event := range evmEvents {
+		inputs, err := evmABIParams(event.Params)
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, evmABIEntry{
+			Type:   "event",
+			Name:   event.Name,
+			Inputs: inputs,
+		})
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func evmABIParams(params []Param) ([]evmABIParam, error) {
+	result := make([]evmABIParam, 0, len(params))
+	for _, param := range params {
+		typ, ok := evmTypes[param.Type]
+		if !ok {
+			return nil, fmt.Errorf("param type %s is not supported by evm", param.Type)
+		}
+		result = append(result, evmABIParam{Name: param.Name, Type: typ})
+	}
+	return result, nil
+}
+
+// EVMBridge is the binding of the bridge contract deployed on evm chain.
+// The params are [method, args...], which are packed to the call data by the abi of EVMABI
+type EVMBridge struct {
+	abi abi.ABI
+}
+
+func NewEVMBridge() (*EVMBridge, error) {
+	abiJson, err := EVMABI()
+	if err != nil {
+		return nil, err
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		return nil, fmt.Errorf("parse layer2 contract abi err: %v", err)
+	}
+	return &EVMBridge{abi: contractAbi}, nil
+}
+
+// Pack return the name of the method and the call data of the params [method, args...]
+func (this *EVMBridge) Pack(params []interface{}) (string, []byte, error) {
+	if len(params) == 0 {
+		return "", nil, fmt.Errorf("bridge params is empty")
+	}
+	name, ok := params[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("bridge method is not string")
+	}
+	data, err := this.abi.Pack(name, params[1:]...)
+	if err != nil {
+		return "", nil, fmt.Errorf("pack params of %s err: %v", name, err)
+	}
+	return name, data, nil
+}
+
+// UnpackOutputs return the outputs of the method, which are parsed by ParseStateRoot, ParseCurrentHeight and so on
+func (this *EVMBridge) UnpackOutputs(name string, output []byte) ([]interface{}, error) {
+	method, ok := this.abi.Methods[name]
+	if !ok {
+		return nil, fmt.Errorf("bridge method %s is not defined", name)
+	}
+	if len(output) == 0 {
+		return nil, fmt.Errorf("result of contract is not found")
+	}
+	return method.Outputs.UnpackValues(output)
+}
+
+// DecodeLog return the states of the log of the bridge contract, which are the event name and the non-indexed args of
+// the log. The states are nil if the log is not an event of the bridge contract
+func (this *EVMBridge) DecodeLog(topics []ethereum_common.Hash, data []byte) ([]interface{}, error) {
+	if len(topics) == 0 {
+		return nil, nil
+	}
+	event, err := this.abi.EventByID(topics[0])
+	if err != nil {
+		return nil, nil
+	}
+	values, err := event.Inputs.UnpackValues(data)
+	if err != nil {
+		return nil, decodeError(event.Name, "unpack log err: %v", err)
+	}
+	return append([]interface{}{event.Name}, values...), nil
+}
+
+func (this *EVMBridge) invokeParams(name string, args ...interface{}) ([]interface{}, error) {
+	method, err := GetMethod(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(method.Params) != len(args) {
+		return nil, fmt.Errorf("bridge method %s need %d params, got %d", name, len(method.Params), len(args))
+	}
+	return append([]interface{}{name}, args...), nil
+}
+
+func evmAddress(addr []byte) (ethereum_common.Address, error) {
+	var result ethereum_common.Address
+	if len(addr) != len(result) {
+		return result, fmt.Errorf("invalid address length %d", len(addr))
+	}
+	copy(result[:], addr)
+	return result, nil
+}
+
+func evmUints(values []uint64) []*big.Int {
+	result := make([]*big.Int, 0, len(values))
+	for _, value := range values {
+		result = append(result, new(big.Int).SetUint64(value))
+	}
+	return result
+}
+
+func (this *EVMBridge) DepositParams(param *DepositParam) ([]interface{}, error) {
+	player, err := evmAddress(param.Player)
+	if err != nil {
+		return nil, err
+	}
+	return this.invokeParams(METHOD_DEPOSIT, player, new(big.Int).SetUint64(param.Amount), param.AssetAddress)
+}
+
+func (this *EVMBridge) DepositNFTParams(param *DepositNFTParam) ([]interface{}, error) {
+	player, err := evmAddress(param.Player)
+	if err != nil {
+		return nil, err
+	}
+	return this.invokeParams(METHOD_DEPOSIT_NFT, player, param.AssetAddress, param.TokenId,
+		new(big.Int).SetUint64(param.Amount), new(big.Int).SetUint64(param.Standard))
+}
+
+func (this *EVMBridge) WithdrawParams(param *WithdrawParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_WITHDRAW, new(big.Int).SetUint64(param.WithdrawId))
+}
+
+func (this *EVMBridge) UpdateStateParams(param *UpdateStateParam) ([]interface{}, error) {
+	if len(param.WithdrawAmounts) != len(param.ToAddresses) || len(param.WithdrawAmounts) != len(param.AssetAddresses) ||
+		len(param.WithdrawAmounts) != len(param.TokenIds) || len(param.WithdrawAmounts) != len(param.Layer2Assets) ||
+		len(param.WithdrawAmounts) != len(param.WithdrawProofs) {
+		return nil, fmt.Errorf("withdraw amounts, to addresses, asset addresses, token ids and proofs must have the same length")
+	}
+	toAddresses := make([]ethereum_common.Address, 0, len(param.ToAddresses))
+	for _, to := range param.ToAddresses {
+		addr, err := evmAddress(to)
+		if err != nil {
+			return nil, err
+		}
+		toAddresses = append(toAddresses, addr)
+	}
+	return this.invokeParams(METHOD_UPDATE_STATE, param.StateRootHash, new(big.Int).SetUint64(uint64(param.Height)),
+		param.Version, evmUints(param.DepositIds), evmUints(param.WithdrawAmounts), toAddresses, param.AssetAddresses, param.TokenIds,
+		param.Layer2Assets, param.WithdrawProofs)
+}
+
+func (this *EVMBridge) SetTokenParams(param *SetTokenParam) ([]interface{}, error) {
+	enabled := int64(2)
+	if param.Enabled {
+		enabled = 1
+	}
+	return this.invokeParams(METHOD_SET_TOKEN, param.AssetAddress, big.NewInt(enabled))
+}
+
+func (this *EVMBridge) RefundDepositParams(param *RefundDepositParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_REFUND_DEPOSIT, new(big.Int).SetUint64(param.DepositId))
+}
+
+func (this *EVMBridge) ChallengeStateParams(param *ChallengeStateParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_CHALLENGE_STATE, new(big.Int).SetUint64(uint64(param.Height)), param.Layer2State)
+}
+
+func (this *EVMBridge) RequestExitParams(param *RequestExitParam) ([]interface{}, error) {
+	player, err := evmAddress(param.Player)
+	if err != nil {
+		return nil, err
+	}
+	return this.invokeParams(METHOD_REQUEST_EXIT, player, param.AssetAddress, param.Value, param.Proof)
+}
+
+func (this *EVMBridge) ChallengeExitParams(param *ChallengeExitParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_CHALLENGE_EXIT, new(big.Int).SetUint64(param.ExitId),
+		new(big.Int).SetUint64(uint64(param.Height)), param.Value, param.Proof)
+}
+
+func (this *EVMBridge) FinalizeExitParams(param *FinalizeExitParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_FINALIZE_EXIT, new(big.Int).SetUint64(param.ExitId))
+}
+
+func (this *EVMBridge) StartMassExitParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_START_MASS_EXIT)
+}
+
+func (this *EVMBridge) ClaimMassExitParams(param *ClaimMassExitParam) ([]interface{}, error) {
+	player, err := evmAddress(param.Player)
+	if err != nil {
+		return nil, err
+	}
+	return this.invokeParams(METHOD_CLAIM_MASS_EXIT, player, param.AssetAddress, param.Value, param.Proof)
+}
+
+func (this *EVMBridge) GetStateRootByHeightParams(height uint64) ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_STATE_ROOT_BY_HEIGHT, new(big.Int).SetUint64(height))
+}
+
+func (this *EVMBridge) GetCurrentHeightParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_CURRENT_HEIGHT)
+}
+
+func (this *EVMBridge) GetMassExitHeightParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_MASS_EXIT_HEIGHT)
+}
+
+// result is the unpacked outputs of getStateRootByHeight
+func (this *EVMBridge) RegisterOperatorParams(param *RegisterOperatorParam) ([]interface{}, error) {
+	newOperator, err := evmAddress(param.NewOperator)
+	if err != nil {
+		return nil, err
+	}
+	return this.invokeParams(METHOD_REGISTER_OPERATOR, newOperator, new(big.Int).SetUint64(param.TransitionPeriod))
+}
+
+func (this *EVMBridge) SwitchOperatorParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_SWITCH_OPERATOR)
+}
+
+func (this *EVMBridge) CancelOperatorParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_CANCEL_OPERATOR)
+}
+
+func (this *EVMBridge) GetOperatorParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_OPERATOR)
+}
+
+func (this *EVMBridge) ParseStateRoot(result interface{}) (*StateRoot, error) {
+	outputs, ok := result.([]interface{})
+	if !ok || len(outputs) != 3 {
+		return nil, fmt.Errorf("state root not found")
+	}
+	stateRootHash, ok1 := outputs[0].(string)
+	height, ok2 := outputs[1].(*big.Int)
+	version, ok3 := outputs[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("invalid state root outputs")
+	}
+	return &StateRoot{
+		StateRootHash: stateRootHash,
+		Height:        height.Uint64(),
+		Version:       version,
+	}, nil
+}
+
+// result is the unpacked outputs of getCurrentHeight
+func (this *EVMBridge) ParseCurrentHeight(result interface{}) (uint64, error) {
+	outputs, ok := result.([]interface{})
+	if !ok || len(outputs) != 1 {
+		return 0, fmt.Errorf("current height not found")
+	}
+	height, ok := outputs[0].(*big.Int)
+	if !ok {
+		return 0, fmt.Errorf("invalid current height outputs")
+	}
+	return height.Uint64(), nil
+}
+
+// result is the unpacked outputs of getMassExitHeight
+func (this *EVMBridge) ParseMassExitHeight(result interface{}) (uint64, error) {
+	outputs, ok := result.([]interface{})
+	if !ok || len(outputs) != 1 {
+		return 0, fmt.Errorf("mass exit height not found")
+	}
+	height, ok := outputs[0].(*big.Int)
+	if !ok {
+		return 0, fmt.Errorf("invalid mass exit height outputs")
+	}
+	return height.Uint64(), nil
+}
+
+// states is [event name, the unpacked non-indexed args of the log...]
+func (this *EVMBridge) EventName(states interface{}) (string, error) {
+	items, ok := states.([]interface{})
+	if !ok || len(items) == 0 {
+		return "", decodeError("", "states is not array")
+	}
+	name, ok := items[0].(string)
+	if !ok {
+		return "", decodeError("", "name is not string")
+	}
+	switch name {
+	case EVM_EVENT_DEPOSIT:
+		return METHOD_DEPOSIT, nil
+	case EVM_EVENT_DEPOSIT_NFT:
+		return METHOD_DEPOSIT_NFT, nil
+	case EVM_EVENT_REVERT:
+		return METHOD_REVERT_STATE, nil
+	case EVM_EVENT_EXIT:
+		return METHOD_REQUEST_EXIT, nil
+	}
+	return name, nil
+}
+
+// the zero address of the new operator is returned as empty, as no rotation is pending
+func (this *EVMBridge) ParseOperator(result interface{}) (*OperatorState, error) {
+	outputs, ok := result.([]interface{})
+	if !ok || len(outputs) != 3 {
+		return nil, fmt.Errorf("operator not found")
+	}
+	operator, ok1 := outputs[0].(interface{ Bytes() []byte })
+	newOperator, ok2 := outputs[1].(interface{ Bytes() []byte })
+	switchBlock, ok3 := outputs[2].(*big.Int)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("invalid operator outputs")
+	}
+	state := &OperatorState{
+		Operator:    operator.Bytes(),
+		SwitchBlock: switchBlock.Uint64(),
+	}
+	if new(big.Int).SetBytes(newOperator.Bytes()).Sign() != 0 {
+		state.NewOperator = newOperator.Bytes()
+	}
+	return state, nil
+}
+
+// deposit event: [DepositEvent, id, player, amount, height, status, assetAddress]
+// nft deposit event: [NFTDepositEvent, id, player, amount, height, status, assetAddress, tokenId]
+func (this *EVMBridge) ParseDepositEvent(states interface{}) (*DepositEvent, error) {
+	name, err := this.EventName(states)
+	if err != nil {
+		return nil, err
+	}
+	if name != METHOD_DEPOSIT && name != METHOD_DEPOSIT_NFT {
+		return nil, fmt.Errorf("event %s is not deposit", name)
+	}
+	items := states.([]interface{})
+	schema, err := eventSchema(name, items)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint64, 0, 4)
+	for _, i := range []int{1, 3, 4, 5} {
+		value, ok := items[i].(*big.Int)
+		if !ok || !value.IsUint64() {
+			return nil, decodeError(name, "state %d is not uint", i)
+		}
+		values = append(values, value.Uint64())
+	}
+	// the address is unpacked as the address type of the evm client
+	player, ok := items[2].(interface{ Bytes() []byte })
+	if !ok || len(player.Bytes()) != 20 {
+		return nil, decodeError(name, "player is not address")
+	}
+	assetAddress, ok := items[6].([]byte)
+	if !ok {
+		return nil, decodeError(name, "asset address is not bytes")
+	}
+	event := &DepositEvent{
+		Version:      schema.Version,
+		ID:           values[0],
+		Player:       player.Bytes(),
+		Amount:       values[1],
+		Height:       values[2],
+		Status:       values[3],
+		AssetAddress: hex.EncodeToString(assetAddress),
+	}
+	if name == METHOD_DEPOSIT_NFT {
+		tokenId, ok := items[7].([]byte)
+		if !ok || len(tokenId) == 0 {
+			return nil, decodeError(name, "token id is not bytes")
+		}
+		event.TokenId = hex.EncodeToString(tokenId)
+	}
+	return event, nil
+}
+
+// exit event: [ExitRequestEvent, id, player, assetAddress, amount, height, blockHeight]
+func (this *EVMBridge) ParseExitEvent(states interface{}) (*ExitEvent, error) {
+	name, err := this.EventName(states)
+	if err != nil {
+		return nil, err
+	}
+	if name != METHOD_REQUEST_EXIT {
+		return nil, fmt.Errorf("event %s is not exit", name)
+	}
+	items := states.([]interface{})
+	schema, err := eventSchema(name, items)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint64, 0, 4)
+	for _, i := range []int{1, 4, 5, 6} {
+		value, ok := items[i].(*big.Int)
+		if !ok || !value.IsUint64() {
+			return nil, decodeError(name, "state %d is not uint", i)
+		}
+		values = append(values, value.Uint64())
+	}
+	player, ok := items[2].(interface{ Bytes() []byte })
+	if !ok || len(player.Bytes()) != 20 {
+		return nil, decodeError(name, "player is not address")
+	}
+	assetAddress, ok := items[3].([]byte)
+	if !ok {
+		return nil, decodeError(name, "asset address is not bytes")
+	}
+	return &ExitEvent{
+		Version:      schema.Version,
+		ID:           values[0],
+		Player:       player.Bytes(),
+		AssetAddress: hex.EncodeToString(assetAddress),
+		Amount:       values[1],
+		Height:       values[2],
+		BlockHeight:  values[3],
+	}, nil
+}
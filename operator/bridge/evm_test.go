@@ -0,0 +1,264 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	ethereum_common "github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	testEVMPlayer = ethereum_common.Address{1, 2, 3}
+	testEVMAsset  = []byte{0xa, 0xb}
+)
+
+func newTestEVMBridge(t *testing.T) *EVMBridge {
+	b, err := NewEVMBridge()
+	if err != nil {
+		t.Fatalf("new evm bridge err: %v", err)
+	}
+	return b
+}
+
+// the params of every method of the bridge are packed by the abi and unpacked to the same args
+func TestEVMPackRoundTrip(t *testing.T) {
+	b := newTestEVMBridge(t)
+	build := map[string]func() ([]interface{}, error){
+		METHOD_DEPOSIT: func() ([]interface{}, error) {
+			return b.DepositParams(&DepositParam{Player: testEVMPlayer[:], Amount: 1000, AssetAddress: testEVMAsset})
+		},
+		METHOD_DEPOSIT_NFT: func() ([]interface{}, error) {
+			return b.DepositNFTParams(&DepositNFTParam{Player: testEVMPlayer[:], AssetAddress: testEVMAsset, TokenId: []byte{7},
+				Amount: 1, Standard: STANDARD_OEP5})
+		},
+		METHOD_WITHDRAW: func() ([]interface{}, error) {
+			return b.WithdrawParams(&WithdrawParam{WithdrawId: 3})
+		},
+		METHOD_UPDATE_STATE: func() ([]interface{}, error) {
+			return b.UpdateStateParams(&UpdateStateParam{StateRootHash: "root", Height: 12, Version: "1",
+				DepositIds: []uint64{1, 2}, WithdrawAmounts: []uint64{300}, ToAddresses: [][]byte{testEVMPlayer[:]},
+				AssetAddresses: [][]byte{testEVMAsset}, TokenIds: [][]byte{{}}, Layer2Assets: [][]byte{{0xc}},
+				WithdrawProofs: [][]byte{{0xd, 0xe}}})
+		},
+		METHOD_SET_TOKEN: func() ([]interface{}, error) {
+			return b.SetTokenParams(&SetTokenParam{AssetAddress: testEVMAsset, Enabled: true})
+		},
+		METHOD_REFUND_DEPOSIT: func() ([]interface{}, error) {
+			return b.RefundDepositParams(&RefundDepositParam{DepositId: 9})
+		},
+		METHOD_CHALLENGE_STATE: func() ([]interface{}, error) {
+			return b.ChallengeStateParams(&ChallengeStateParam{Height: 12, Layer2State: []byte{1}})
+		},
+		METHOD_REQUEST_EXIT: func() ([]interface{}, error) {
+			return b.RequestExitParams(&RequestExitParam{Player: testEVMPlayer[:], AssetAddress: testEVMAsset, Value: []byte{1}, Proof: []byte{2}})
+		},
+		METHOD_CHALLENGE_EXIT: func() ([]interface{}, error) {
+			return b.ChallengeExitParams(&ChallengeExitParam{ExitId: 4, Height: 13, Value: []byte{1}, Proof: []byte{2}})
+		},
+		METHOD_FINALIZE_EXIT: func() ([]interface{}, error) {
+			return b.FinalizeExitParams(&FinalizeExitParam{ExitId: 4})
+		},
+		METHOD_START_MASS_EXIT: b.StartMassExitParams,
+		METHOD_CLAIM_MASS_EXIT: func() ([]interface{}, error) {
+			return b.ClaimMassExitParams(&ClaimMassExitParam{Player: testEVMPlayer[:], AssetAddress: testEVMAsset, Value: []byte{1}, Proof: []byte{2}})
+		},
+		METHOD_REGISTER_OPERATOR: func() ([]interface{}, error) {
+			return b.RegisterOperatorParams(&RegisterOperatorParam{NewOperator: testEVMPlayer[:], TransitionPeriod: 100})
+		},
+		METHOD_SWITCH_OPERATOR: b.SwitchOperatorParams,
+		METHOD_CANCEL_OPERATOR: b.CancelOperatorParams,
+		METHOD_GET_STATE_ROOT_BY_HEIGHT: func() ([]interface{}, error) {
+			return b.GetStateRootByHeightParams(12)
+		},
+		METHOD_GET_CURRENT_HEIGHT:   b.GetCurrentHeightParams,
+		METHOD_GET_MASS_EXIT_HEIGHT: b.GetMassExitHeightParams,
+		METHOD_GET_OPERATOR:         b.GetOperatorParams,
+	}
+	for _, method := range Methods {
+		f, ok := build[method.Name]
+		if !ok {
+			t.Errorf("method %s has no binding", method.Name)
+			continue
+		}
+		params, err := f()
+		if err != nil {
+			t.Fatalf("%s: build params err: %v", method.Name, err)
+		}
+		name, data, err := b.Pack(params)
+		if err != nil {
+			t.Fatalf("%s: pack err: %v", method.Name, err)
+		}
+		abiMethod, err := b.abi.MethodById(data[:4])
+		if err != nil || name != method.Name || abiMethod.Name != method.Name {
+			t.Fatalf("%s: packed as %s, %v", method.Name, name, err)
+		}
+		args, err := abiMethod.Inputs.UnpackValues(data[4:])
+		if err != nil {
+			t.Fatalf("%s: unpack err: %v", method.Name, err)
+		}
+		if len(args) != len(params)-1 || (len(args) > 0 && !reflect.DeepEqual(args, params[1:])) {
+			t.Errorf("%s: unpacked %v, want %v", method.Name, args, params[1:])
+		}
+	}
+}
+
+func TestEVMPackError(t *testing.T) {
+	b := newTestEVMBridge(t)
+	cases := [][]interface{}{
+		nil,
+		{1},
+		{"unknown"},
+		{METHOD_WITHDRAW, "not uint"},
+	}
+	for _, params := range cases {
+		if _, _, err := b.Pack(params); err == nil {
+			t.Errorf("params %v is packed", params)
+		}
+	}
+	if _, err := b.DepositParams(&DepositParam{Player: []byte{1}}); err == nil {
+		t.Errorf("invalid player address is accepted")
+	}
+}
+
+// the args of the events packed by the abi are decoded and parsed as the log of the contract
+func TestEVMDecodeLogRoundTrip(t *testing.T) {
+	b := newTestEVMBridge(t)
+	log := func(name string, args ...interface{}) ([]ethereum_common.Hash, []byte) {
+		event := b.abi.Events[name]
+		data, err := event.Inputs.Pack(args...)
+		if err != nil {
+			t.Fatalf("pack event %s err: %v", name, err)
+		}
+		return []ethereum_common.Hash{event.ID()}, data
+	}
+	uint256 := func(value uint64) *big.Int {
+		return new(big.Int).SetUint64(value)
+	}
+
+	states, err := b.DecodeLog(log(EVM_EVENT_DEPOSIT, uint256(7), testEVMPlayer, uint256(1000), uint256(20), uint256(1), testEVMAsset))
+	if err != nil {
+		t.Fatalf("decode deposit log err: %v", err)
+	}
+	deposit, err := b.ParseDepositEvent(states)
+	if err != nil {
+		t.Fatalf("parse deposit err: %v", err)
+	}
+	want := &DepositEvent{Version: 1, ID: 7, Player: testEVMPlayer[:], Amount: 1000, Height: 20, Status: 1, AssetAddress: "0a0b"}
+	if !reflect.DeepEqual(deposit, want) {
+		t.Errorf("deposit event %+v, want %+v", deposit, want)
+	}
+
+	states, err = b.DecodeLog(log(EVM_EVENT_DEPOSIT_NFT, uint256(8), testEVMPlayer, uint256(1), uint256(21), uint256(1), testEVMAsset, []byte{0xf}))
+	if err != nil {
+		t.Fatalf("decode nft deposit log err: %v", err)
+	}
+	deposit, err = b.ParseDepositEvent(states)
+	if err != nil {
+		t.Fatalf("parse nft deposit err: %v", err)
+	}
+	want = &DepositEvent{Version: 1, ID: 8, Player: testEVMPlayer[:], Amount: 1, Height: 21, Status: 1, AssetAddress: "0a0b", TokenId: "0f"}
+	if !reflect.DeepEqual(deposit, want) {
+		t.Errorf("nft deposit event %+v, want %+v", deposit, want)
+	}
+
+	states, err = b.DecodeLog(log(EVM_EVENT_EXIT, uint256(3), testEVMPlayer, testEVMAsset, uint256(500), uint256(40), uint256(90)))
+	if err != nil {
+		t.Fatalf("decode exit log err: %v", err)
+	}
+	exit, err := b.ParseExitEvent(states)
+	if err != nil {
+		t.Fatalf("parse exit err: %v", err)
+	}
+	wantExit := &ExitEvent{Version: 1, ID: 3, Player: testEVMPlayer[:], AssetAddress: "0a0b", Amount: 500, Height: 40, BlockHeight: 90}
+	if !reflect.DeepEqual(exit, wantExit) {
+		t.Errorf("exit event %+v, want %+v", exit, wantExit)
+	}
+
+	states, err = b.DecodeLog(log(EVM_EVENT_REVERT, uint256(30), uint256(20)))
+	if err != nil {
+		t.Fatalf("decode revert log err: %v", err)
+	}
+	if name, err := b.EventName(states); err != nil || name != METHOD_REVERT_STATE {
+		t.Errorf("revert event name %s, %v", name, err)
+	}
+}
+
+func TestEVMDecodeLogError(t *testing.T) {
+	b := newTestEVMBridge(t)
+	// the logs of other events are skipped
+	states, err := b.DecodeLog([]ethereum_common.Hash{{1}}, nil)
+	if states != nil || err != nil {
+		t.Errorf("unknown log decoded as %v, %v", states, err)
+	}
+	states, err = b.DecodeLog(nil, nil)
+	if states != nil || err != nil {
+		t.Errorf("anonymous log decoded as %v, %v", states, err)
+	}
+	topics := []ethereum_common.Hash{b.abi.Events[EVM_EVENT_DEPOSIT].ID()}
+	if _, err = b.DecodeLog(topics, []byte{1, 2, 3}); err == nil {
+		t.Errorf("truncated log is decoded")
+	} else if _, ok := err.(*DecodeError); !ok {
+		t.Errorf("err %v, want DecodeError", err)
+	}
+}
+
+// the outputs packed by the abi are unpacked and parsed as the result of the contract
+func TestEVMParseOutputs(t *testing.T) {
+	b := newTestEVMBridge(t)
+	pack := func(name string, values ...interface{}) []interface{} {
+		data, err := b.abi.Methods[name].Outputs.Pack(values...)
+		if err != nil {
+			t.Fatalf("pack outputs of %s err: %v", name, err)
+		}
+		outputs, err := b.UnpackOutputs(name, data)
+		if err != nil {
+			t.Fatalf("unpack outputs of %s err: %v", name, err)
+		}
+		return outputs
+	}
+
+	stateRoot, err := b.ParseStateRoot(pack(METHOD_GET_STATE_ROOT_BY_HEIGHT, "root", big.NewInt(12), "1"))
+	want := &StateRoot{StateRootHash: "root", Height: 12, Version: "1"}
+	if err != nil || !reflect.DeepEqual(stateRoot, want) {
+		t.Errorf("state root %+v, %v, want %+v", stateRoot, err, want)
+	}
+	height, err := b.ParseCurrentHeight(pack(METHOD_GET_CURRENT_HEIGHT, big.NewInt(15)))
+	if err != nil || height != 15 {
+		t.Errorf("current height %d, %v", height, err)
+	}
+	height, err = b.ParseMassExitHeight(pack(METHOD_GET_MASS_EXIT_HEIGHT, big.NewInt(0)))
+	if err != nil || height != 0 {
+		t.Errorf("mass exit height %d, %v", height, err)
+	}
+	operator, err := b.ParseOperator(pack(METHOD_GET_OPERATOR, testEVMPlayer, ethereum_common.Address{}, big.NewInt(0)))
+	wantOperator := &OperatorState{Operator: testEVMPlayer[:]}
+	if err != nil || !reflect.DeepEqual(operator, wantOperator) {
+		t.Errorf("operator %+v, %v, want %+v", operator, err, wantOperator)
+	}
+
+	if _, err = b.UnpackOutputs(METHOD_GET_CURRENT_HEIGHT, nil); err == nil {
+		t.Errorf("empty output is unpacked")
+	}
+	if _, err = b.UnpackOutputs("unknown", []byte{1}); err == nil {
+		t.Errorf("output of unknown method is unpacked")
+	}
+}
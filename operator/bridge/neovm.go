@@ -0,0 +1,417 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	ontology_sdk_common "github.com/ontio/ontology-go-sdk/common"
+	ontology_common "github.com/ontio/ontology/common"
+)
+
+// NeoVMBridge is the binding of the bridge contract deployed on ontology neovm
+type NeoVMBridge struct {
+}
+
+func NewNeoVMBridge() *NeoVMBridge {
+	return &NeoVMBridge{}
+}
+
+func (this *NeoVMBridge) invokeParams(name string, args ...interface{}) ([]interface{}, error) {
+	method, err := GetMethod(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(method.Params) != len(args) {
+		return nil, fmt.Errorf("bridge method %s need %d params, got %d", name, len(method.Params), len(args))
+	}
+	return []interface{}{name, args}, nil
+}
+
+func (this *NeoVMBridge) DepositParams(param *DepositParam) ([]interface{}, error) {
+	player, err := ontology_common.AddressParseFromBytes(param.Player)
+	if err != nil {
+		return nil, fmt.Errorf("invalid player address: %s", err)
+	}
+	return this.invokeParams(METHOD_DEPOSIT, player, param.Amount, param.AssetAddress)
+}
+
+func (this *NeoVMBridge) DepositNFTParams(param *DepositNFTParam) ([]interface{}, error) {
+	player, err := ontology_common.AddressParseFromBytes(param.Player)
+	if err != nil {
+		return nil, fmt.Errorf("invalid player address: %s", err)
+	}
+	return this.invokeParams(METHOD_DEPOSIT_NFT, player, param.AssetAddress, param.TokenId, param.Amount, param.Standard)
+}
+
+func (this *NeoVMBridge) WithdrawParams(param *WithdrawParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_WITHDRAW, param.WithdrawId)
+}
+
+func (this *NeoVMBridge) UpdateStateParams(param *UpdateStateParam) ([]interface{}, error) {
+	if len(param.WithdrawAmounts) != len(param.ToAddresses) || len(param.WithdrawAmounts) != len(param.AssetAddresses) ||
+		len(param.WithdrawAmounts) != len(param.TokenIds) || len(param.WithdrawAmounts) != len(param.Layer2Assets) ||
+		len(param.WithdrawAmounts) != len(param.WithdrawProofs) {
+		return nil, fmt.Errorf("withdraw amounts, to addresses, asset addresses, token ids and proofs must have the same length")
+	}
+	toAddresses := make([]ontology_common.Address, 0, len(param.ToAddresses))
+	for _, to := range param.ToAddresses {
+		addr, err := ontology_common.AddressParseFromBytes(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid withdraw address: %s", err)
+		}
+		toAddresses = append(toAddresses, addr)
+	}
+	depositIds := param.DepositIds
+	if depositIds == nil {
+		depositIds = make([]uint64, 0)
+	}
+	withdrawAmounts := param.WithdrawAmounts
+	if withdrawAmounts == nil {
+		withdrawAmounts = make([]uint64, 0)
+	}
+	assetAddresses := param.AssetAddresses
+	if assetAddresses == nil {
+		assetAddresses = make([][]byte, 0)
+	}
+	tokenIds := param.TokenIds
+	if tokenIds == nil {
+		tokenIds = make([][]byte, 0)
+	}
+	layer2Assets := param.Layer2Assets
+	if layer2Assets == nil {
+		layer2Assets = make([][]byte, 0)
+	}
+	withdrawProofs := param.WithdrawProofs
+	if withdrawProofs == nil {
+		withdrawProofs = make([][]byte, 0)
+	}
+	return this.invokeParams(METHOD_UPDATE_STATE, param.StateRootHash, param.Height, param.Version,
+		depositIds, withdrawAmounts, toAddresses, assetAddresses, tokenIds, layer2Assets, withdrawProofs)
+}
+
+// the token is enabled by 1 and paused by 2, as the empty storage of neovm reads as 0
+func (this *NeoVMBridge) SetTokenParams(param *SetTokenParam) ([]interface{}, error) {
+	enabled := uint64(2)
+	if param.Enabled {
+		enabled = 1
+	}
+	return this.invokeParams(METHOD_SET_TOKEN, param.AssetAddress, enabled)
+}
+
+func (this *NeoVMBridge) RefundDepositParams(param *RefundDepositParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_REFUND_DEPOSIT, param.DepositId)
+}
+
+func (this *NeoVMBridge) ChallengeStateParams(param *ChallengeStateParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_CHALLENGE_STATE, param.Height, param.Layer2State)
+}
+
+func (this *NeoVMBridge) RequestExitParams(param *RequestExitParam) ([]interface{}, error) {
+	player, err := ontology_common.AddressParseFromBytes(param.Player)
+	if err != nil {
+		return nil, fmt.Errorf("invalid player address: %s", err)
+	}
+	return this.invokeParams(METHOD_REQUEST_EXIT, player, param.AssetAddress, param.Value, param.Proof)
+}
+
+func (this *NeoVMBridge) ChallengeExitParams(param *ChallengeExitParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_CHALLENGE_EXIT, param.ExitId, param.Height, param.Value, param.Proof)
+}
+
+func (this *NeoVMBridge) FinalizeExitParams(param *FinalizeExitParam) ([]interface{}, error) {
+	return this.invokeParams(METHOD_FINALIZE_EXIT, param.ExitId)
+}
+
+func (this *NeoVMBridge) StartMassExitParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_START_MASS_EXIT)
+}
+
+func (this *NeoVMBridge) ClaimMassExitParams(param *ClaimMassExitParam) ([]interface{}, error) {
+	player, err := ontology_common.AddressParseFromBytes(param.Player)
+	if err != nil {
+		return nil, fmt.Errorf("invalid player address: %s", err)
+	}
+	return this.invokeParams(METHOD_CLAIM_MASS_EXIT, player, param.AssetAddress, param.Value, param.Proof)
+}
+
+func (this *NeoVMBridge) GetStateRootByHeightParams(height uint64) ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_STATE_ROOT_BY_HEIGHT, height)
+}
+
+func (this *NeoVMBridge) GetCurrentHeightParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_CURRENT_HEIGHT)
+}
+
+func (this *NeoVMBridge) GetMassExitHeightParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_MASS_EXIT_HEIGHT)
+}
+
+func (this *NeoVMBridge) RegisterOperatorParams(param *RegisterOperatorParam) ([]interface{}, error) {
+	newOperator, err := ontology_common.AddressParseFromBytes(param.NewOperator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator address: %s", err)
+	}
+	return this.invokeParams(METHOD_REGISTER_OPERATOR, newOperator, param.TransitionPeriod)
+}
+
+func (this *NeoVMBridge) SwitchOperatorParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_SWITCH_OPERATOR)
+}
+
+func (this *NeoVMBridge) CancelOperatorParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_CANCEL_OPERATOR)
+}
+
+func (this *NeoVMBridge) GetOperatorParams() ([]interface{}, error) {
+	return this.invokeParams(METHOD_GET_OPERATOR)
+}
+
+func (this *NeoVMBridge) ParseStateRoot(result interface{}) (*StateRoot, error) {
+	item, ok := result.(*ontology_sdk_common.ResultItem)
+	if !ok || item == nil {
+		return nil, fmt.Errorf("state root result is not neovm result")
+	}
+	data, err := item.ToArray()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 3 {
+		return nil, fmt.Errorf("state root not found")
+	}
+	stateRootHash, err := data[0].ToString()
+	if err != nil {
+		return nil, fmt.Errorf("parse state root hash error: %s", err)
+	}
+	height, err := data[1].ToInteger()
+	if err != nil {
+		return nil, fmt.Errorf("parse state root height error: %s", err)
+	}
+	version, err := data[2].ToString()
+	if err != nil {
+		return nil, fmt.Errorf("parse state root version error: %s", err)
+	}
+	return &StateRoot{
+		StateRootHash: stateRootHash,
+		Height:        height.Uint64(),
+		Version:       version,
+	}, nil
+}
+
+func (this *NeoVMBridge) ParseCurrentHeight(result interface{}) (uint64, error) {
+	item, ok := result.(*ontology_sdk_common.ResultItem)
+	if !ok || item == nil {
+		return 0, fmt.Errorf("current height result is not neovm result")
+	}
+	height, err := item.ToInteger()
+	if err != nil {
+		return 0, fmt.Errorf("parse current height error: %s", err)
+	}
+	return height.Uint64(), nil
+}
+
+func (this *NeoVMBridge) ParseMassExitHeight(result interface{}) (uint64, error) {
+	item, ok := result.(*ontology_sdk_common.ResultItem)
+	if !ok || item == nil {
+		return 0, fmt.Errorf("mass exit height result is not neovm result")
+	}
+	height, err := item.ToInteger()
+	if err != nil {
+		return 0, fmt.Errorf("parse mass exit height error: %s", err)
+	}
+	return height.Uint64(), nil
+}
+
+func (this *NeoVMBridge) EventName(states interface{}) (string, error) {
+	items, ok := states.([]interface{})
+	if !ok || len(items) == 0 {
+		return "", decodeError("", "states is not array")
+	}
+	name, err := hexItem("", items[0])
+	if err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+// deposit event: [deposit, id, player, amount, height, status, assetAddress]
+// nft deposit event: [depositNFT, id, player, amount, height, status, assetAddress, tokenId]
+// the operator result: [operator, newOperator, switchBlock], the new operator and the switch block are empty if no
+// rotation is pending
+func (this *NeoVMBridge) ParseOperator(result interface{}) (*OperatorState, error) {
+	item, ok := result.(*ontology_sdk_common.ResultItem)
+	if !ok || item == nil {
+		return nil, fmt.Errorf("operator result is not neovm result")
+	}
+	data, err := item.ToArray()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 3 {
+		return nil, fmt.Errorf("operator not found")
+	}
+	operator, err := data[0].ToByteArray()
+	if err != nil {
+		return nil, fmt.Errorf("parse operator error: %s", err)
+	}
+	newOperator, err := data[1].ToByteArray()
+	if err != nil {
+		return nil, fmt.Errorf("parse new operator error: %s", err)
+	}
+	switchBlock, err := data[2].ToInteger()
+	if err != nil {
+		return nil, fmt.Errorf("parse operator switch block error: %s", err)
+	}
+	return &OperatorState{
+		Operator:    operator,
+		NewOperator: newOperator,
+		SwitchBlock: switchBlock.Uint64(),
+	}, nil
+}
+
+func (this *NeoVMBridge) ParseDepositEvent(states interface{}) (*DepositEvent, error) {
+	name, err := this.EventName(states)
+	if err != nil {
+		return nil, err
+	}
+	if name != METHOD_DEPOSIT && name != METHOD_DEPOSIT_NFT {
+		return nil, fmt.Errorf("event %s is not deposit", name)
+	}
+	items := states.([]interface{})
+	schema, err := eventSchema(name, items)
+	if err != nil {
+		return nil, err
+	}
+	err = checkNeoVMStates(schema, items)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, len(items)-1)
+	for _, item := range items[1:6] {
+		value, err := hexItem(name, item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	assetAddress, ok := items[6].(string)
+	if !ok {
+		return nil, decodeError(name, "asset address is not string")
+	}
+	event := &DepositEvent{
+		Version:      schema.Version,
+		ID:           bytesToUint64(values[0]),
+		Player:       values[1],
+		Amount:       bytesToUint64(values[2]),
+		Height:       bytesToUint64(values[3]),
+		Status:       bytesToUint64(values[4]),
+		AssetAddress: assetAddress,
+	}
+	if name == METHOD_DEPOSIT_NFT {
+		tokenId, ok := items[7].(string)
+		if !ok || tokenId == "" {
+			return nil, decodeError(name, "token id is not string")
+		}
+		event.TokenId = tokenId
+	}
+	return event, nil
+}
+
+// exit event: [requestExit, id, player, assetAddress, amount, height, blockHeight]
+func (this *NeoVMBridge) ParseExitEvent(states interface{}) (*ExitEvent, error) {
+	name, err := this.EventName(states)
+	if err != nil {
+		return nil, err
+	}
+	if name != METHOD_REQUEST_EXIT {
+		return nil, fmt.Errorf("event %s is not exit", name)
+	}
+	items := states.([]interface{})
+	schema, err := eventSchema(name, items)
+	if err != nil {
+		return nil, err
+	}
+	err = checkNeoVMStates(schema, items)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, len(items)-1)
+	for _, item := range items[1:] {
+		value, err := hexItem(name, item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return &ExitEvent{
+		Version:      schema.Version,
+		ID:           bytesToUint64(values[0]),
+		Player:       values[1],
+		AssetAddress: hex.EncodeToString(values[2]),
+		Amount:       bytesToUint64(values[3]),
+		Height:       bytesToUint64(values[4]),
+		BlockHeight:  bytesToUint64(values[5]),
+	}, nil
+}
+
+// check the states by the types of the schema, the states are the hex of the neovm values
+func checkNeoVMStates(schema *EventSchema, items []interface{}) error {
+	for i, param := range schema.States {
+		value, err := hexItem(schema.Event, items[i+1])
+		if err != nil {
+			return err
+		}
+		switch param.Type {
+		case TYPE_UINT:
+			// neovm integer is signed
+			if len(value) > 8 || (len(value) > 0 && value[len(value)-1]&0x80 != 0) {
+				return decodeError(schema.Event, "%s is not uint64", param.Name)
+			}
+		case TYPE_ADDRESS:
+			if len(value) != ontology_common.ADDR_LEN {
+				return decodeError(schema.Event, "%s is not address", param.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func hexItem(event string, item interface{}) ([]byte, error) {
+	value, ok := item.(string)
+	if !ok {
+		return nil, decodeError(event, "state is not string")
+	}
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, decodeError(event, "state is not hex: %s", err)
+	}
+	return data, nil
+}
+
+// neovm integer is little endian
+func bytesToUint64(bys []byte) uint64 {
+	data := make([]byte, 8)
+	copy(data, bys)
+	var value int64
+	binary.Read(bytes.NewBuffer(data), binary.LittleEndian, &value)
+	return uint64(value)
+}
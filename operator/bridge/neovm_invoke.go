@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	ontology_common "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/vm/neovm"
+)
+
+// NeoVMInvocation is a neovm contract call decoded from the invoke code built by the sdk
+type NeoVMInvocation struct {
+	Contract ontology_common.Address
+	Method   string
+	Args     []interface{}
+}
+
+// DecodeNeoVMInvocation decode the code of [method, [args...]] params, only the push and pack
+// opcodes emitted by the sdk param builder are supported. The items of Args are []byte,
+// *big.Int or []interface{}, which are converted by NeoVMItemToInt and NeoVMItemToString.
+func DecodeNeoVMInvocation(code []byte) (*NeoVMInvocation, error) {
+	stack := make([]interface{}, 0)
+	pop := func() (interface{}, error) {
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("stack is empty")
+		}
+		item := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return item, nil
+	}
+	offset := 0
+	readBytes := func(n int) ([]byte, error) {
+		if n < 0 || offset+n > len(code) {
+			return nil, fmt.Errorf("unexpected end of code")
+		}
+		data := code[offset : offset+n]
+		offset += n
+		return data, nil
+	}
+	for offset < len(code) {
+		op := neovm.OpCode(code[offset])
+		offset++
+		switch {
+		case op == neovm.PUSH0:
+			stack = append(stack, []byte{})
+		case op >= neovm.PUSHBYTES1 && op <= neovm.PUSHBYTES75:
+			data, err := readBytes(int(op))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, data)
+		case op == neovm.PUSHDATA1 || op == neovm.PUSHDATA2 || op == neovm.PUSHDATA4:
+			size := map[neovm.OpCode]int{neovm.PUSHDATA1: 1, neovm.PUSHDATA2: 2, neovm.PUSHDATA4: 4}[op]
+			lenData, err := readBytes(size)
+			if err != nil {
+				return nil, err
+			}
+			buf := make([]byte, 4)
+			copy(buf, lenData)
+			data, err := readBytes(int(binary.LittleEndian.Uint32(buf)))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, data)
+		case op == neovm.PUSHM1 || (op >= neovm.PUSH1 && op <= neovm.PUSH16):
+			stack = append(stack, big.NewInt(int64(op)-int64(neovm.PUSH1)+1))
+		case op == neovm.PACK:
+			item, err := pop()
+			if err != nil {
+				return nil, err
+			}
+			count := NeoVMItemToInt(item)
+			if !count.IsInt64() || count.Int64() < 0 || count.Int64() > int64(len(stack)) {
+				return nil, fmt.Errorf("invalid pack count %s", count)
+			}
+			array := make([]interface{}, 0, count.Int64())
+			for i := int64(0); i < count.Int64(); i++ {
+				item, _ := pop()
+				array = append(array, item)
+			}
+			stack = append(stack, array)
+		case op == neovm.APPCALL:
+			contract, err := readBytes(ontology_common.ADDR_LEN)
+			if err != nil {
+				return nil, err
+			}
+			if offset != len(code) {
+				return nil, fmt.Errorf("code after appcall is not supported")
+			}
+			if len(stack) != 2 {
+				return nil, fmt.Errorf("appcall need method and args, got %d items", len(stack))
+			}
+			method, ok := stack[1].([]byte)
+			if !ok {
+				return nil, fmt.Errorf("method is not bytes")
+			}
+			args, ok := stack[0].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("args is not array")
+			}
+			invoke := &NeoVMInvocation{Method: string(method), Args: args}
+			copy(invoke.Contract[:], contract)
+			return invoke, nil
+		default:
+			return nil, fmt.Errorf("unsupported opcode %x", byte(op))
+		}
+	}
+	return nil, fmt.Errorf("appcall not found")
+}
+
+// NeoVMItemToInt convert the stack item to integer as neovm does
+func NeoVMItemToInt(item interface{}) *big.Int {
+	switch v := item.(type) {
+	case *big.Int:
+		return v
+	case []byte:
+		return ontology_common.BigIntFromNeoBytes(v)
+	}
+	return big.NewInt(0)
+}
+
+// NeoVMItemToString convert the stack item to string as neovm does
+func NeoVMItemToString(item interface{}) string {
+	switch v := item.(type) {
+	case []byte:
+		return string(v)
+	case *big.Int:
+		return string(ontology_common.BigIntToNeoBytes(v))
+	}
+	return ""
+}
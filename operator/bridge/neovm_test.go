@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	ontology_common "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/utils"
+)
+
+var testContract = ontology_common.Address{9, 8, 7}
+
+var testPlayer = ontology_common.Address{1, 2, 3}
+
+func neovmHex(value string) string {
+	return hex.EncodeToString([]byte(value))
+}
+
+func neovmUint(value uint64) string {
+	return hex.EncodeToString(ontology_common.BigIntToNeoBytes(new(big.Int).SetUint64(value)))
+}
+
+// checkNeoVMArg compare the arg decoded from the invoke code with the param of the binding
+func checkNeoVMArg(t *testing.T, method string, want interface{}, got interface{}) {
+	switch w := want.(type) {
+	case uint64:
+		if NeoVMItemToInt(got).Uint64() != w {
+			t.Errorf("%s: arg %v, want %d", method, got, w)
+		}
+	case uint32:
+		if NeoVMItemToInt(got).Uint64() != uint64(w) {
+			t.Errorf("%s: arg %v, want %d", method, got, w)
+		}
+	case string:
+		if NeoVMItemToString(got) != w {
+			t.Errorf("%s: arg %v, want %s", method, got, w)
+		}
+	case []byte:
+		if data, ok := got.([]byte); !ok || !bytes.Equal(data, w) {
+			t.Errorf("%s: arg %v, want %x", method, got, w)
+		}
+	case ontology_common.Address:
+		if data, ok := got.([]byte); !ok || !bytes.Equal(data, w[:]) {
+			t.Errorf("%s: arg %v, want %s", method, got, w.ToHexString())
+		}
+	default:
+		items, ok := got.([]interface{})
+		value := reflect.ValueOf(want)
+		if !ok || value.Kind() != reflect.Slice || value.Len() != len(items) {
+			t.Errorf("%s: arg %v, want %v", method, got, want)
+			return
+		}
+		for i := range items {
+			checkNeoVMArg(t, method, value.Index(i).Interface(), items[i])
+		}
+	}
+}
+
+// the params of the bridge are built to the invoke code by the sdk and decoded to the same args
+func TestNeoVMInvokeRoundTrip(t *testing.T) {
+	b := NewNeoVMBridge()
+	build := map[string]func() ([]interface{}, error){
+		METHOD_DEPOSIT: func() ([]interface{}, error) {
+			return b.DepositParams(&DepositParam{Player: testPlayer[:], Amount: 1000, AssetAddress: []byte{0xa}})
+		},
+		METHOD_DEPOSIT_NFT: func() ([]interface{}, error) {
+			return b.DepositNFTParams(&DepositNFTParam{Player: testPlayer[:], AssetAddress: []byte{0xa}, TokenId: []byte{7},
+				Amount: 1, Standard: STANDARD_OEP8})
+		},
+		METHOD_UPDATE_STATE: func() ([]interface{}, error) {
+			return b.UpdateStateParams(&UpdateStateParam{StateRootHash: "root", Height: 12, Version: "1",
+				DepositIds: []uint64{1, 200000}, WithdrawAmounts: []uint64{300}, ToAddresses: [][]byte{testPlayer[:]},
+				AssetAddresses: [][]byte{{0xa}}, TokenIds: [][]byte{{7}}, Layer2Assets: [][]byte{{0xc}},
+				WithdrawProofs: [][]byte{{0xd, 0xe}}})
+		},
+		METHOD_SET_TOKEN: func() ([]interface{}, error) {
+			return b.SetTokenParams(&SetTokenParam{AssetAddress: []byte{0xa}, Enabled: false})
+		},
+		METHOD_CHALLENGE_EXIT: func() ([]interface{}, error) {
+			return b.ChallengeExitParams(&ChallengeExitParam{ExitId: 4, Height: 13, Value: []byte{1}, Proof: []byte{2}})
+		},
+		METHOD_REGISTER_OPERATOR: func() ([]interface{}, error) {
+			return b.RegisterOperatorParams(&RegisterOperatorParam{NewOperator: testPlayer[:], TransitionPeriod: 100})
+		},
+		METHOD_GET_STATE_ROOT_BY_HEIGHT: func() ([]interface{}, error) {
+			return b.GetStateRootByHeightParams(1 << 40)
+		},
+		METHOD_GET_CURRENT_HEIGHT: b.GetCurrentHeightParams,
+	}
+	for name, f := range build {
+		params, err := f()
+		if err != nil {
+			t.Fatalf("%s: build params err: %v", name, err)
+		}
+		code, err := utils.BuildNeoVMInvokeCode(testContract, params)
+		if err != nil {
+			t.Fatalf("%s: build invoke code err: %v", name, err)
+		}
+		invoke, err := DecodeNeoVMInvocation(code)
+		if err != nil {
+			t.Fatalf("%s: decode invoke code err: %v", name, err)
+		}
+		if invoke.Method != name || invoke.Contract != testContract {
+			t.Errorf("%s: decoded call of %s on %s", name, invoke.Method, invoke.Contract.ToHexString())
+		}
+		checkNeoVMArg(t, name, params[1], invoke.Args)
+	}
+}
+
+func TestDecodeNeoVMInvocationError(t *testing.T) {
+	code, err := utils.BuildNeoVMInvokeCode(testContract, []interface{}{METHOD_WITHDRAW, []interface{}{uint64(3)}})
+	if err != nil {
+		t.Fatalf("build invoke code err: %v", err)
+	}
+	cases := map[string][]byte{
+		"empty":            {},
+		"truncated":        code[:len(code)-3],
+		"code after call":  append(append([]byte{}, code...), 0x00),
+		"unknown opcode":   {0xff},
+		"pack without len": {0xc1},
+	}
+	for name, c := range cases {
+		if _, err := DecodeNeoVMInvocation(c); err == nil {
+			t.Errorf("%s: code %x is decoded", name, c)
+		}
+	}
+}
+
+// the deposit event encoded as the notify of the neovm contract is parsed to the same event
+func TestNeoVMDepositEventRoundTrip(t *testing.T) {
+	b := NewNeoVMBridge()
+	events := []*DepositEvent{
+		{Version: 1, ID: 0, Player: testPlayer[:], Amount: 1, Height: 1, Status: 0, AssetAddress: "0a"},
+		{Version: 1, ID: 1 << 62, Player: testPlayer[:], Amount: ^uint64(0) >> 1, Height: 300000, Status: 2,
+			AssetAddress: "0000000000000000000000000000000000000002"},
+		{Version: 1, ID: 8, Player: testPlayer[:], Amount: 1, Height: 21, Status: 1, AssetAddress: "0a0b", TokenId: "0f"},
+	}
+	for _, want := range events {
+		name := METHOD_DEPOSIT
+		if want.TokenId != "" {
+			name = METHOD_DEPOSIT_NFT
+		}
+		states := []interface{}{neovmHex(name), neovmUint(want.ID), hex.EncodeToString(want.Player), neovmUint(want.Amount),
+			neovmUint(want.Height), neovmUint(want.Status), want.AssetAddress}
+		if want.TokenId != "" {
+			states = append(states, want.TokenId)
+		}
+		event, err := b.ParseDepositEvent(states)
+		if err != nil {
+			t.Fatalf("parse deposit %+v err: %v", want, err)
+		}
+		if !reflect.DeepEqual(event, want) {
+			t.Errorf("deposit event %+v, want %+v", event, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// the end of the path of the withdraw proof in the sparse merkle tree of the layer2 account states
+const (
+	PROOF_EMPTY      = 0 // the path ends in an empty subtree, the account is absent
+	PROOF_LEAF       = 1 // the path ends at the leaf of the account
+	PROOF_OTHER_LEAF = 2 // the path ends at the leaf of another account, the account is absent
+
+	SMT_DEPTH       = 256
+	SMT_LEAF_PREFIX = byte(0x00)
+	SMT_NODE_PREFIX = byte(0x01)
+)
+
+// SMTProof is the proof returned by getlayer2stateproof of the layer2 node. Siblings are from the root to the end of
+// the path of the key
+type SMTProof struct {
+	Siblings      [][32]byte
+	HasLeaf       bool
+	LeafPath      [32]byte
+	LeafValueHash [32]byte
+}
+
+// ParseSMTProof parse the serialized proof of the layer2 node: varuint count, siblings, has leaf, leaf path, value hash
+func ParseSMTProof(data []byte) (*SMTProof, error) {
+	reader := bytes.NewReader(data)
+	count, err := readVarUint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if count > SMT_DEPTH {
+		return nil, fmt.Errorf("too many siblings of smt proof")
+	}
+	proof := &SMTProof{Siblings: make([][32]byte, count)}
+	for i := range proof.Siblings {
+		if _, err := reader.Read(proof.Siblings[i][:]); err != nil {
+			return nil, fmt.Errorf("read sibling of smt proof: %s", err)
+		}
+	}
+	hasLeaf, err := reader.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read leaf flag of smt proof: %s", err)
+	}
+	proof.HasLeaf = hasLeaf == 1
+	if proof.HasLeaf {
+		if n, _ := reader.Read(proof.LeafPath[:]); n != 32 {
+			return nil, fmt.Errorf("read leaf path of smt proof failed")
+		}
+		if n, _ := reader.Read(proof.LeafValueHash[:]); n != 32 {
+			return nil, fmt.Errorf("read leaf value hash of smt proof failed")
+		}
+	}
+	return proof, nil
+}
+
+func readVarUint(reader *bytes.Reader) (uint64, error) {
+	prefix, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	size := 0
+	switch prefix {
+	case 0xFD:
+		size = 2
+	case 0xFE:
+		size = 4
+	case 0xFF:
+		size = 8
+	default:
+		return uint64(prefix), nil
+	}
+	data := make([]byte, 8)
+	if n, _ := reader.Read(data[:size]); n != size {
+		return 0, fmt.Errorf("read varuint failed")
+	}
+	return binary.LittleEndian.Uint64(data), nil
+}
+
+// EncodeWithdrawProof encode the proof of key for the layer2 contract: [end type, leaf path and value hash of
+// another account | value hash of the account, siblings...]
+func EncodeWithdrawProof(key []byte, proof *SMTProof) []byte {
+	path := sha256.Sum256(key)
+	data := make([]byte, 0, 65 + 32 * len(proof.Siblings))
+	if !proof.HasLeaf {
+		data = append(data, PROOF_EMPTY)
+	} else if proof.LeafPath == path {
+		data = append(data, PROOF_LEAF)
+		data = append(data, proof.LeafValueHash[:]...)
+	} else {
+		data = append(data, PROOF_OTHER_LEAF)
+		data = append(data, proof.LeafPath[:]...)
+		data = append(data, proof.LeafValueHash[:]...)
+	}
+	for _, sibling := range proof.Siblings {
+		data = append(data, sibling[:]...)
+	}
+	return data
+}
+
+// VerifyWithdrawProof verify the encoded proof of key against the state root, as the layer2 contract does
+func VerifyWithdrawProof(root [32]byte, key []byte, data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	path := sha256.Sum256(key)
+	var hash [32]byte
+	offset := 1
+	switch data[0] {
+	case PROOF_EMPTY:
+	case PROOF_LEAF:
+		if len(data) < 33 {
+			return false
+		}
+		hash = smtHash(SMT_LEAF_PREFIX, path[:], data[1:33])
+		offset = 33
+	case PROOF_OTHER_LEAF:
+		if len(data) < 65 || bytes.Equal(data[1:33], path[:]) {
+			return false
+		}
+		hash = smtHash(SMT_LEAF_PREFIX, data[1:33], data[33:65])
+		offset = 65
+	default:
+		return false
+	}
+	if (len(data) - offset) % 32 != 0 {
+		return false
+	}
+	depth := (len(data) - offset) / 32
+	if depth > SMT_DEPTH {
+		return false
+	}
+	for i := 0; i < depth && data[0] == PROOF_OTHER_LEAF; i++ {
+		if smtBit(data[1:33], i) != smtBit(path[:], i) {
+			return false
+		}
+	}
+	for i := depth - 1; i >= 0; i-- {
+		sibling := data[offset + 32 * i : offset + 32 * (i + 1)]
+		if smtBit(path[:], i) == 0 {
+			hash = smtHash(SMT_NODE_PREFIX, hash[:], sibling)
+		} else {
+			hash = smtHash(SMT_NODE_PREFIX, sibling, hash[:])
+		}
+	}
+	return hash == root
+}
+
+func smtHash(prefix byte, a, b []byte) [32]byte {
+	data := make([]byte, 0, 65)
+	data = append(data, prefix)
+	data = append(data, a...)
+	return sha256.Sum256(append(data, b...))
+}
+
+func smtBit(path []byte, depth int) byte {
+	return (path[depth / 8] >> uint(7 - depth % 8)) & 1
+}
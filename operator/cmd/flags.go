@@ -23,6 +23,7 @@ import (
 	"strings"
 
 	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/e2e"
 	"github.com/urfave/cli"
 )
 
@@ -57,6 +58,23 @@ var (
 		Value: config.DEFAULT_CONFIG_FILE_NAME,
 	}
 
+	ChainSpecFlag = cli.StringFlag{
+		Name:  "chainspec",
+		Usage: "Chain spec `<file>` shared with the layer2 node, overrides the ChainSpec of the config file",
+		Value: "",
+	}
+
+	LeaderLockFlag = cli.StringFlag{
+		Name:  "leaderlock",
+		Usage: "Elect the leader of the operators sharing the database by the mysql lock `<name>`, overrides the LeaderLock of the config file",
+		Value: "",
+	}
+
+	DryRunFlag = cli.BoolFlag{
+		Name:  "dryrun",
+		Usage: "Parse both chains and record the deposit mints and state commits in the database without signing or sending any transaction, overrides the DryRun of the config file",
+	}
+
 	EthStartFlag = cli.Uint64Flag{
 		Name:  "ethereum",
 		Usage: "eth start block height ",
@@ -74,6 +92,50 @@ var (
 		Usage: "multichain start block height ",
 		Value: uint64(0),
 	}
+	E2EConfigFlag = cli.StringFlag{
+		Name:  "e2econfig",
+		Usage: "End-to-end test environment config file `<path>`",
+		Value: e2e.DEFAULT_E2E_CONFIG_FILE,
+	}
+
+	ScenarioFlag = cli.StringFlag{
+		Name:  "scenario",
+		Usage: "Run the scenario file or all scenarios of the directory `<path>`",
+		Value: "",
+	}
+
+	MassExitHeightFlag = cli.UintFlag{
+		Name:  "height",
+		Usage: "Export the accounts in the layer2 state root of the committed `<height>`",
+		Value: 0,
+	}
+
+	MassExitOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Write the mass exit snapshot to the json `<file>`",
+		Value: "massexit.json",
+	}
+
+	DeadLetterIDFlag = cli.Uint64Flag{
+		Name:  "id",
+		Usage: "Retry the job of the dead letter `<id>`",
+		Value: 0,
+	}
+	CommitHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "The commit job of the layer2 `<height>`",
+		Value: 0,
+	}
+	CommitLimitFlag = cli.IntFlag{
+		Name:  "limit",
+		Usage: "List at most `<count>` unconfirmed or failed commits and commit jobs, or actions of the dry run",
+		Value: 20,
+	}
+	TransitionPeriodFlag = cli.Uint64Flag{
+		Name:  "period",
+		Usage: "Sign by both keys in the transition period of `<blocks>` on L1 before the new key can switch",
+		Value: 0,
+	}
 	//EncryptFlag = cli.StringFlag{
 	//	Name:  "encrypt",
 	//	Usage: "encrypt string `pwd`",
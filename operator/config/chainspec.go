@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// the ChainSpec types are generated from the chain spec of the layer2 node, as the operator pins a node version
+// without it, so the node and the operator read the same file format
+//go:generate go run ./chainspecgen
+
+func LoadChainSpec(fileName string) (*ChainSpec, error) {
+	data, err := ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	spec := &ChainSpec{}
+	err = json.Unmarshal(data, spec)
+	if err != nil {
+		return nil, fmt.Errorf("LoadChainSpec: unmarshal %s error %s", fileName, err)
+	}
+	if spec.NetworkId == 0 {
+		return nil, fmt.Errorf("LoadChainSpec: %s has no NetworkId", fileName)
+	}
+	if spec.Bridge != nil && spec.Bridge.Layer2ContractAddress != "" && !isHexAddress(spec.Bridge.Layer2ContractAddress) {
+		return nil, fmt.Errorf("LoadChainSpec: Layer2ContractAddress %s is not a hex address", spec.Bridge.Layer2ContractAddress)
+	}
+	for _, token := range spec.Tokens {
+		if token.Name == "" || !isHexAddress(token.Address) {
+			return nil, fmt.Errorf("LoadChainSpec: token %s address %s is invalid", token.Name, token.Address)
+		}
+	}
+	return spec, nil
+}
+
+// Apply fills the bridge and layer2 params of the service config from the chain spec.
+func (this *ChainSpec) Apply(servConfig *ServiceConfig) {
+	if this.Bridge != nil && servConfig.OntologyConfig != nil {
+		if this.Bridge.OntologyRestURL != "" {
+			servConfig.OntologyConfig.RestURL = this.Bridge.OntologyRestURL
+		}
+		if this.Bridge.Layer2ContractAddress != "" {
+			servConfig.OntologyConfig.Layer2ContractAddress = this.Bridge.Layer2ContractAddress
+		}
+		if this.Bridge.GasPrice > 0 {
+			servConfig.OntologyConfig.GasPrice = this.Bridge.GasPrice
+		}
+		if this.Bridge.GasLimit > 0 {
+			servConfig.OntologyConfig.GasLimit = this.Bridge.GasLimit
+		}
+	}
+	// the layer2 node rejects transactions below its minimum gas limit
+	if this.Params != nil && servConfig.Layer2Config != nil && servConfig.Layer2Config.GasLimit < this.Params.GasLimit {
+		servConfig.Layer2Config.GasLimit = this.Params.GasLimit
+	}
+	// the registered tokens not configured are bridged to the same address on layer2
+	for _, token := range this.Tokens {
+		configured := false
+		for _, tokenConfig := range servConfig.Tokens {
+			if tokenConfig.Address == token.Address {
+				configured = true
+				break
+			}
+		}
+		if !configured {
+			servConfig.Tokens = append(servConfig.Tokens, &TokenConfig{Name: token.Name, Address: token.Address, Layer2Address: token.Address, Decimals: token.Decimals})
+		}
+	}
+	servConfig.Spec = this
+}
+
+// GetTokenByAddress returns the registered token of the hex address, nil if not registered.
+func (this *ChainSpec) GetTokenByAddress(address string) *ChainSpecToken {
+	for _, token := range this.Tokens {
+		if token.Address == address {
+			return token
+		}
+	}
+	return nil
+}
+
+func isHexAddress(address string) bool {
+	data, err := hex.DecodeString(address)
+	return err == nil && len(data) == 20
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// chainspecgen generate the chain spec types of the operator config from the chain spec of the layer2 node, as the
+// operator pins a node version without it. Run by go generate in the operator config package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const TYPE_PREFIX = "ChainSpec"
+
+var (
+	srcFile = flag.String("src", "../../node/common/config/chainspec.go", "chain spec file of the layer2 node")
+	outFile = flag.String("out", "chainspec_types.go", "generated file of the operator config")
+)
+
+// the license header of the source is kept, followed by the generated notice
+const generatedNotice = "// Code generated by chainspecgen from node/common/config/chainspec.go. DO NOT EDIT.\n\n"
+
+// generate return the source of the ChainSpec type declarations of src, in package config
+func generate(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "chainspec.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if len(file.Comments) > 0 && file.Comments[0].Pos() < file.Package {
+		buf.WriteString(string(src[:fset.Position(file.Comments[0].End()).Offset]) + "\n\n")
+	}
+	buf.WriteString(generatedNotice)
+	buf.WriteString("package config\n")
+	found := false
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if !strings.HasPrefix(spec.(*ast.TypeSpec).Name.Name, TYPE_PREFIX) {
+				return nil, fmt.Errorf("type %s is not a chain spec type", spec.(*ast.TypeSpec).Name.Name)
+			}
+		}
+		buf.WriteString("\n")
+		err = printer.Fprint(buf, fset, &printer.CommentedNode{Node: genDecl, Comments: file.Comments})
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n")
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("no chain spec type found")
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	flag.Parse()
+	src, err := ioutil.ReadFile(*srcFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read %s error: %s\n", *srcFile, err)
+		os.Exit(1)
+	}
+	out, err := generate(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generate from %s error: %s\n", *srcFile, err)
+		os.Exit(1)
+	}
+	err = ioutil.WriteFile(*outFile, out, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write %s error: %s\n", *outFile, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// the generated types of the operator config are the same as the chain spec of the node, run go generate in the
+// operator config package if the node changes it
+func TestGeneratedChainSpec(t *testing.T) {
+	src, err := ioutil.ReadFile("../../../node/common/config/chainspec.go")
+	if err != nil {
+		t.Fatalf("read node chain spec err: %v", err)
+	}
+	want, err := generate(src)
+	if err != nil {
+		t.Fatalf("generate err: %v", err)
+	}
+	got, err := ioutil.ReadFile("../chainspec_types.go")
+	if err != nil {
+		t.Fatalf("read generated chain spec err: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("chainspec_types.go is out of date with the node chain spec, run go generate")
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	cases := map[string]string{
+		"not go":        "chain spec",
+		"no type":       "package config\n\nfunc f() {}\n",
+		"foreign type":  "package config\n\ntype Other struct{}\n",
+	}
+	for name, src := range cases {
+		if _, err := generate([]byte(src)); err == nil {
+			t.Errorf("%s: source is generated", name)
+		}
+	}
+}
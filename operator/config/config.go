@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"time"
 
 	"github.com/ontio/layer2/operator/log"
@@ -33,10 +34,49 @@ const (
 	ETH_MONITOR_INTERVAL     = 3 * time.Second
 	ONT_MONITOR_INTERVAL     = 3 * time.Second
 	KEY_UNLOCK_TIME          = 30 * time.Second
+	LEADER_ELECTION_INTERVAL = 1 * time.Second
+	MULTISIG_REQUEST_TIMEOUT = 5 * time.Second
+	SIGNER_REQUEST_TIMEOUT   = 5 * time.Second
+	CHALLENGE_CHECK_INTERVAL = 10 * time.Second
+	EXIT_CHECK_INTERVAL      = 10 * time.Second
+	MASS_EXIT_CHECK_INTERVAL = 60 * time.Second
+	DEPOSIT_REFUND_INTERVAL  = 60 * time.Second
+	HEALTH_CHECK_TIMEOUT     = 5 * time.Second
+	DEFAULT_SHUTDOWN_TIMEOUT = 60 * time.Second
+	SHUTDOWN_ABORT_TIMEOUT   = 5 * time.Second
+	NOTIFY_CHECK_INTERVAL    = 60 * time.Second
+	NOTIFY_REQUEST_TIMEOUT   = 10 * time.Second
+	TRACE_EXPORT_INTERVAL    = 5 * time.Second
+	TRACE_REQUEST_TIMEOUT    = 10 * time.Second
+
+	DEFAULT_ONT_GAS_PRICE     = 500
+	DEFAULT_COMMIT_GAS_LIMIT  = 6000000
+	DEFAULT_GAS_PRICE_BUMP    = 10
+
+	DEFAULT_RETRY_BASE_DELAY   = 1 * time.Second
+	DEFAULT_RETRY_MAX_DELAY    = 60 * time.Second
+	DEFAULT_RETRY_MAX_ATTEMPTS = 20
+
+	DEFAULT_SUBMIT_MAX_IN_FLIGHT = 4
+	DEFAULT_SUBMIT_INTERVAL      = 100 * time.Millisecond
+
+	DEFAULT_LAYER2_PARSE_CONCURRENCY = 4
+
+	DEFAULT_HEALTH_MAX_LAG     = 100
+
+	DEFAULT_NOTIFY_DEPOSIT_TIMEOUT = 30 * time.Minute
+	DEFAULT_NOTIFY_COOLDOWN        = 30 * time.Minute
+
+	DEFAULT_TRACE_SERVICE_NAME = "layer2-operator"
+
+	DEFAULT_TRAFFIC_INTERVAL        = 100 * time.Second
+	DEFAULT_TRAFFIC_DEPOSIT_AMOUNT  = 100000
+	DEFAULT_TRAFFIC_WITHDRAW_AMOUNT = 1000
 
 	ETH_USEFUL_BLOCK_NUM      = 3
 	ETH_PROOF_USERFUL_BLOCK   = 25
 	ONT_USEFUL_BLOCK_NUM      = 1
+	ONT_REORG_TRACK_BLOCKS    = 1000
 	ETH_CHAIN_ID               = 2
 	DEFAULT_CONFIG_FILE_NAME  = "./config.json"
 	Version                   = "1.0"
@@ -44,6 +84,19 @@ const (
 	DEFAULT_LOG_LEVEL = log.InfoLog
 )
 
+// the database drivers of DBConfig
+const (
+	DB_DRIVER_MYSQL    = "mysql"
+	DB_DRIVER_POSTGRES = "postgres"
+	DB_DRIVER_SQLITE   = "sqlite"
+)
+
+// the L1 chain the layer2 deposits from and commits to
+const (
+	L1_ONTOLOGY = "ontology"
+	L1_ETHEREUM = "ethereum"
+)
+
 //type ETH struct {
 //	Chain             string // eth or etc
 //	ChainId           uint64
@@ -53,33 +106,522 @@ const (
 //}
 
 type ServiceConfig struct {
+	ChainSpec              string `json:",omitempty"`
+	LeaderLock             string `json:",omitempty"`
+	DryRun                 bool   `json:",omitempty"`
+	ShutdownTimeout        uint64 `json:",omitempty"`
+	L1                     string `json:",omitempty"`
 	OntologyConfig         *OntologyConfig
+	EthereumConfig         *EthereumConfig `json:",omitempty"`
 	DBConfig               *DBConfig
 	Layer2Config           *Layer2Config
+	MultiSigConfig         *MultiSigConfig `json:",omitempty"`
+	ChallengerConfig       *ChallengerConfig `json:",omitempty"`
+	MassExitConfig         *MassExitConfig `json:",omitempty"`
+	WithdrawFees           []*WithdrawFeeConfig `json:",omitempty"`
+	RetryConfig            *RetryConfig `json:",omitempty"`
+	SubmitConfig           *SubmitConfig `json:",omitempty"`
+	Tokens                 []*TokenConfig `json:",omitempty"`
+	AssetConfig            *AssetConfig `json:",omitempty"`
+	AdminConfig            *AdminConfig `json:",omitempty"`
+	MetricsConfig          *MetricsConfig `json:",omitempty"`
+	HealthConfig           *HealthConfig `json:",omitempty"`
+	NotifyConfig           *NotifyConfig `json:",omitempty"`
+	LogConfig              *LogConfig `json:",omitempty"`
+	TracingConfig          *TracingConfig `json:",omitempty"`
+	TrafficConfig          *TrafficConfig `json:",omitempty"`
+	Spec                   *ChainSpec `json:"-"`
 }
 
+// the commit transactions are sent with GasPrice, or the gas price of the network if it is 0. Every time the node
+// rejects the commit transaction, the gas price is raised by GasPriceBump percent up to MaxGasPrice, it is never
+// raised if MaxGasPrice is 0. GasLimit is used when the commit fails in pre-execution. The blocks are pushed by the
+// websocket of WebSocketURL if it is set, and the node is polled while the websocket is disconnected. The commits are
+// paid by the accounts of Payers in rotation if it is set, they are signed by the operator account and the payer
 type OntologyConfig struct {
 	RestURL                 string
+	WebSocketURL            string `json:",omitempty"`
 	Layer2ContractAddress   string
 	WalletFile              string
 	WalletPwd               string
+	Signer                  *SignerConfig `json:",omitempty"`
+	NextAccount             *AccountConfig `json:",omitempty"`
+	Payers                  []*AccountConfig `json:",omitempty"`
 	GasPrice                uint64
 	GasLimit                uint64
+	MaxGasPrice             uint64 `json:",omitempty"`
+	GasPriceBump            uint64 `json:",omitempty"`
+	CommitBatchSize         int    `json:",omitempty"`
+	CommitBatchWindow       uint64 `json:",omitempty"`
+	DepositConfirmations    uint32 `json:",omitempty"`
 }
 
+// the layer2 contract is the solidity contract of contract/Layer2.sol, the transactions of the operator are signed by
+// the key of KeyStoreFile. The gas price suggested by the node is capped by MaxGasPrice
+type EthereumConfig struct {
+	RpcURL                  string
+	Layer2ContractAddress   string
+	KeyStoreFile            string
+	KeyStorePwd             string
+	ChainId                 uint64 `json:",omitempty"`
+	GasPrice                uint64 `json:",omitempty"`
+	GasLimit                uint64 `json:",omitempty"`
+	MaxGasPrice             uint64 `json:",omitempty"`
+	CommitBatchSize         int    `json:",omitempty"`
+	CommitBatchWindow       uint64 `json:",omitempty"`
+	DepositConfirmations    uint32 `json:",omitempty"`
+}
+
+// the blocks are pushed by the websocket of WebSocketURL if it is set, and the node is polled while the websocket is
+// disconnected. ParseConcurrency blocks are fetched from the node at the same time when the parser is behind, they are
+// still saved and committed in height order
 type Layer2Config struct {
 	RestURL                 string
+	WebSocketURL            string `json:",omitempty"`
 	WalletFile              string
 	WalletPwd               string
+	Signer                  *SignerConfig `json:",omitempty"`
+	NextAccount             *AccountConfig `json:",omitempty"`
 	GasPrice                uint64
 	GasLimit                uint64
+	DepositBatchSize        int    `json:",omitempty"`
+	DepositBatchWindow      uint64 `json:",omitempty"`
+	ParseConcurrency        int    `json:",omitempty"`
 }
 
+// the account is kept by the signing service of URL instead of the wallet file, WalletFile and WalletPwd are ignored
+// if it is set. KeyID is the key of the account in the service, Token is sent as the bearer token, and CAFile,
+// CertFile and KeyFile are the optional TLS files to verify the service and to authenticate the operator
+type SignerConfig struct {
+	URL      string
+	KeyID    string
+	Token    string `json:",omitempty"`
+	CAFile   string `json:",omitempty"`
+	CertFile string `json:",omitempty"`
+	KeyFile  string `json:",omitempty"`
+}
+
+// the next key of the operator account in the key rotation or a payer account, kept in WalletFile with WalletPwd or by
+// Signer as the account of the chain
+type AccountConfig struct {
+	WalletFile string
+	WalletPwd  string
+	Signer     *SignerConfig `json:",omitempty"`
+}
+
+// the OEP-4 token of Address on ontology is bridged to the OEP-4 contract of Layer2Address on layer2, ONT and ONG of
+// AssetConfig are always bridged to the layer2 native assets. The tokens are the initial entries of the token registry in the
+// database, which is changed by the admin api after
+type TokenConfig struct {
+	Name                    string
+	Address                 string
+	Layer2Address           string
+	Decimals                uint32
+	Standard                string `json:",omitempty"`
+}
+
+const (
+	NETWORK_MAINNET = "mainnet"
+	NETWORK_TESTNET = "testnet"
+	NETWORK_PRIVATE = "private"
+)
+
+// the native assets and the collector of the network. OntAddress and OngAddress are the hex addresses of ONT and ONG
+// in the bridge contract on L1, they are minted by the native contracts on layer2. Collector is the base58 layer2
+// address the deposits are minted from and the withdrawals are transferred to, it must be the collector of the layer2
+// node. The addresses not set are of the preset of Network, mainnet, testnet or private, mainnet if it is empty
+type AssetConfig struct {
+	Network                 string `json:",omitempty"`
+	OntAddress              string `json:",omitempty"`
+	OngAddress              string `json:",omitempty"`
+	Collector               string `json:",omitempty"`
+}
+
+// the presets of the networks, the native contracts of ontology and the empty address of layer2
+var NetworkAssets = map[string]AssetConfig{
+	NETWORK_MAINNET: {
+		Network:    NETWORK_MAINNET,
+		OntAddress: "0000000000000000000000000000000000000001",
+		OngAddress: "0000000000000000000000000000000000000002",
+		Collector:  "AFmseVrdL9f9oyCzZefL9tG6UbvhPbdYzM",
+	},
+	NETWORK_TESTNET: {
+		Network:    NETWORK_TESTNET,
+		OntAddress: "0000000000000000000000000000000000000001",
+		OngAddress: "0000000000000000000000000000000000000002",
+		Collector:  "AFmseVrdL9f9oyCzZefL9tG6UbvhPbdYzM",
+	},
+	NETWORK_PRIVATE: {
+		Network:    NETWORK_PRIVATE,
+		OntAddress: "0000000000000000000000000000000000000001",
+		OngAddress: "0000000000000000000000000000000000000002",
+		Collector:  "AFmseVrdL9f9oyCzZefL9tG6UbvhPbdYzM",
+	},
+}
+
+// Resolve return the assets of the preset of the network overridden by the addresses set, the config may be nil
+func (this *AssetConfig) Resolve() (*AssetConfig, error) {
+	network := NETWORK_MAINNET
+	if this != nil && this.Network != "" {
+		network = this.Network
+	}
+	preset, ok := NetworkAssets[network]
+	if !ok {
+		return nil, fmt.Errorf("network %s of AssetConfig is not mainnet, testnet or private", network)
+	}
+	assets := preset
+	if this != nil {
+		if this.OntAddress != "" {
+			assets.OntAddress = this.OntAddress
+		}
+		if this.OngAddress != "" {
+			assets.OngAddress = this.OngAddress
+		}
+		if this.Collector != "" {
+			assets.Collector = this.Collector
+		}
+	}
+	if !isHexAddress(assets.OntAddress) || !isHexAddress(assets.OngAddress) {
+		return nil, fmt.Errorf("ONT address %s or ONG address %s of AssetConfig is not a hex address", assets.OntAddress, assets.OngAddress)
+	}
+	if assets.OntAddress == assets.OngAddress {
+		return nil, fmt.Errorf("ONT and ONG of AssetConfig are the same address %s", assets.OntAddress)
+	}
+	return &assets, nil
+}
+
+// the admin api manages the token registry and refunds the rejected deposits, MirrorTokens also sets the changed
+// tokens to the layer2 contract. The requests must carry AuthToken as the bearer token if it is set
+type AdminConfig struct {
+	ListenAddr              string
+	MirrorTokens            bool
+	AuthToken               string `json:",omitempty"`
+}
+
+// the metrics of the operator are served in prometheus format at /metrics of ListenAddr
+type MetricsConfig struct {
+	ListenAddr              string
+}
+
+// the liveness and readiness are served at /healthz and /readyz of ListenAddr, the operator is not healthy if the
+// parse height lags more than MaxL1Lag blocks behind the confirmed L1 height or MaxLayer2Lag blocks behind layer2
+type HealthConfig struct {
+	ListenAddr              string
+	MaxL1Lag                uint32 `json:",omitempty"`
+	MaxLayer2Lag            uint32 `json:",omitempty"`
+}
+
+func (this *HealthConfig) MaxL1LagOrDefault() uint32 {
+	if this.MaxL1Lag == 0 {
+		return DEFAULT_HEALTH_MAX_LAG
+	}
+	return this.MaxL1Lag
+}
+
+func (this *HealthConfig) MaxLayer2LagOrDefault() uint32 {
+	if this.MaxLayer2Lag == 0 {
+		return DEFAULT_HEALTH_MAX_LAG
+	}
+	return this.MaxLayer2Lag
+}
+
+// the spans of the deposits and withdrawals are exported in OTLP/HTTP json to Endpoint of the opentelemetry
+// collector, like http://localhost:4318/v1/traces, as the service ServiceName. Headers are added to the export
+// requests, like the api key of the tracing backend
+type TracingConfig struct {
+	Endpoint                string
+	ServiceName             string `json:",omitempty"`
+	Headers                 map[string]string `json:",omitempty"`
+}
+
+func (this *TracingConfig) ServiceNameOrDefault() string {
+	if this.ServiceName == "" {
+		return DEFAULT_TRACE_SERVICE_NAME
+	}
+	return this.ServiceName
+}
+
+// the fake deposits and withdrawals are generated on a test network to drive the pipeline, it must never be set on a
+// live network. Every Interval seconds, a deposit of DepositAmount of each of Tokens, ONT and ONG if empty, is credited
+// to the layer2 account of the operator without an event on L1. Once the deposits are minted, WithdrawAmount of each
+// token is withdrawn by the layer2 account. Rounds is the number of rounds, unlimited if 0
+type TrafficConfig struct {
+	Interval                uint64   `json:",omitempty"`
+	Tokens                  []string `json:",omitempty"`
+	DepositAmount           uint64   `json:",omitempty"`
+	WithdrawAmount          uint64   `json:",omitempty"`
+	Rounds                  int      `json:",omitempty"`
+}
+
+func (this *TrafficConfig) IntervalOrDefault() time.Duration {
+	if this.Interval == 0 {
+		return DEFAULT_TRAFFIC_INTERVAL
+	}
+	return time.Duration(this.Interval) * time.Second
+}
+
+func (this *TrafficConfig) DepositAmountOrDefault() uint64 {
+	if this.DepositAmount == 0 {
+		return DEFAULT_TRAFFIC_DEPOSIT_AMOUNT
+	}
+	return this.DepositAmount
+}
+
+func (this *TrafficConfig) WithdrawAmountOrDefault() uint64 {
+	if this.WithdrawAmount == 0 {
+		return DEFAULT_TRAFFIC_WITHDRAW_AMOUNT
+	}
+	return this.WithdrawAmount
+}
+
+// the log is written to stdout and the files in Path, in the Format of text or json. Level and ModuleLevels are the names
+// trace, debug, info, warn, error or fatal, Level replaces the level of the flag if set, and ModuleLevels set the level
+// of the modules, which are the prefixes of the messages like "challenger - ". A new file is started once the current
+// one exceeds MaxSize MB or is older than RotateInterval seconds, and at most MaxBackups old files are kept, all of
+// them if it is 0
+type LogConfig struct {
+	Format                  string `json:",omitempty"`
+	Level                   string `json:",omitempty"`
+	ModuleLevels            map[string]string `json:",omitempty"`
+	Path                    string `json:",omitempty"`
+	MaxSize                 int64  `json:",omitempty"`
+	RotateInterval          uint64 `json:",omitempty"`
+	MaxBackups              int    `json:",omitempty"`
+}
+
+//Options return the log options of the config, level is the level of the flag
+func (this *LogConfig) Options(level int) (*log.Options, error) {
+	opts := &log.Options{
+		Level:          level,
+		Format:         this.Format,
+		ModuleLevels:   make(map[string]int),
+		Path:           this.Path,
+		MaxSize:        this.MaxSize,
+		RotateInterval: time.Duration(this.RotateInterval) * time.Second,
+		MaxBackups:     this.MaxBackups,
+		Stdout:         true,
+	}
+	if this.Level != "" {
+		var err error
+		opts.Level, err = log.ParseLevel(this.Level)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for module, name := range this.ModuleLevels {
+		moduleLevel, err := log.ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %s", module, err)
+		}
+		opts.ModuleLevels[module] = moduleLevel
+	}
+	if opts.Path == "" {
+		opts.Path = log.PATH
+	}
+	return opts, nil
+}
+
+// the alerts of the bridge anomalies are sent to every configured sink, WebhookURL receives the alert in json and
+// SlackWebhookURL receives it as a slack message. A deposit not committed to layer2 in DepositTimeout seconds is stuck,
+// and the gas balance of the operator account on L1 is low below MinGasBalance, which is in the smallest unit of ONG
+// or in gwei on ethereum, it is not checked if 0. The ONG balance of the operator account on layer2 is low below
+// MinLayer2Balance, which is not checked if 0. The alert of the same anomaly is sent again after Cooldown seconds
+type NotifyConfig struct {
+	WebhookURL              string `json:",omitempty"`
+	SlackWebhookURL         string `json:",omitempty"`
+	Email                   *EmailConfig `json:",omitempty"`
+	DepositTimeout          uint64 `json:",omitempty"`
+	MinGasBalance           uint64 `json:",omitempty"`
+	MinLayer2Balance        uint64 `json:",omitempty"`
+	Cooldown                uint64 `json:",omitempty"`
+}
+
+// the alert email is sent from From to every address of To by the SMTP server of SMTPAddr, which is host:port. The
+// server authenticates by Username and Password if Username is set
+type EmailConfig struct {
+	SMTPAddr                string
+	Username                string `json:",omitempty"`
+	Password                string `json:",omitempty"`
+	From                    string
+	To                      []string
+}
+
+func (this *NotifyConfig) DepositTimeoutOrDefault() time.Duration {
+	if this.DepositTimeout == 0 {
+		return DEFAULT_NOTIFY_DEPOSIT_TIMEOUT
+	}
+	return time.Duration(this.DepositTimeout) * time.Second
+}
+
+func (this *NotifyConfig) CooldownOrDefault() time.Duration {
+	if this.Cooldown == 0 {
+		return DEFAULT_NOTIFY_COOLDOWN
+	}
+	return time.Duration(this.Cooldown) * time.Second
+}
+
+// the layer2 state is committed by the M-of-N multi-signature address of the operators
+type MultiSigConfig struct {
+	M                       uint16
+	PublicKeys              []string
+	Peers                   []string
+	ListenAddr              string
+	Proposer                bool
+}
+
+// the challenger compares the layer2 states committed to L1 from StartHeight with the signed layer2 states of the
+// layer2 node every Interval seconds, and challenges the commit whose state root differs. StartHeight is the L1
+// committed height at start by default
+type ChallengerConfig struct {
+	Interval                uint64 `json:",omitempty"`
+	StartHeight             uint32 `json:",omitempty"`
+}
+
+// once the mass exit is started on L1, the operator exports the snapshot of the accounts in the state root of the
+// committed height to ExportDir, so that every user can claim its balance on L1
+type MassExitConfig struct {
+	ExportDir               string
+}
+
+// the fee deducted from the withdraws of the fungible token of Address when they are committed to L1, Flat is in the
+// smallest unit of the token and Rate is in basis points of the amount. The operators of the multisig commit must set
+// the same fees
+type WithdrawFeeConfig struct {
+	Address                 string
+	Flat                    uint64 `json:",omitempty"`
+	Rate                    uint64 `json:",omitempty"`
+}
+
+// the failed work is retried after BaseDelay milliseconds, which is doubled on every attempt up to MaxDelay
+// milliseconds with a random jitter. A job failed MaxAttempts times is moved to the dead letter table
+type RetryConfig struct {
+	BaseDelay               uint64 `json:",omitempty"`
+	MaxDelay                uint64 `json:",omitempty"`
+	MaxAttempts             int    `json:",omitempty"`
+}
+
+// the L1 transactions of the operator are sent by the submission queue, the transactions of the same payer are sent
+// one by one so their nonces never conflict. At most MaxInFlight transactions of all payers are being sent at the same
+// time, and the submissions are at least Interval milliseconds apart
+type SubmitConfig struct {
+	MaxInFlight             int    `json:",omitempty"`
+	Interval                uint64 `json:",omitempty"`
+}
+
+// the database is mysql unless ProjectDBDriver is postgres or sqlite, ProjectDBSSLMode is the sslmode of postgres,
+// disable by default. sqlite only uses ProjectDBName, which is the database file
 type DBConfig struct {
+	ProjectDBDriver    string `json:",omitempty"`
 	ProjectDBUrl       string
 	ProjectDBUser      string
 	ProjectDBPassword  string
 	ProjectDBName      string
+	ProjectDBSSLMode   string `json:",omitempty"`
+}
+
+// IsEthereum return true if the L1 chain is ethereum, the default L1 chain is ontology
+func (this *ServiceConfig) IsEthereum() bool {
+	return this.L1 == L1_ETHEREUM
+}
+
+// ShutdownDuration return the time to drain the in-flight work on shutdown, ShutdownTimeout is in seconds
+func (this *ServiceConfig) ShutdownDuration() time.Duration {
+	if this.ShutdownTimeout == 0 {
+		return DEFAULT_SHUTDOWN_TIMEOUT
+	}
+	return time.Duration(this.ShutdownTimeout) * time.Second
+}
+
+// CommitBatch return the commit batch size and window of the L1 chain
+func (this *ServiceConfig) CommitBatch() (int, uint64) {
+	if this.IsEthereum() && this.EthereumConfig != nil {
+		return this.EthereumConfig.CommitBatchSize, this.EthereumConfig.CommitBatchWindow
+	} else if this.OntologyConfig != nil {
+		return this.OntologyConfig.CommitBatchSize, this.OntologyConfig.CommitBatchWindow
+	}
+	return 0, 0
+}
+
+// DepositConfirmations return the blocks on top of the L1 block before its deposits are parsed
+func (this *ServiceConfig) DepositConfirmations() uint32 {
+	if this.IsEthereum() && this.EthereumConfig != nil {
+		return this.EthereumConfig.DepositConfirmations
+	} else if this.OntologyConfig != nil {
+		return this.OntologyConfig.DepositConfirmations
+	}
+	return 0
+}
+
+// Account return the operator account of the wallet file or the signer
+func (this *OntologyConfig) Account() *AccountConfig {
+	return &AccountConfig{WalletFile: this.WalletFile, WalletPwd: this.WalletPwd, Signer: this.Signer}
+}
+
+// Account return the operator account of the wallet file or the signer
+func (this *Layer2Config) Account() *AccountConfig {
+	return &AccountConfig{WalletFile: this.WalletFile, WalletPwd: this.WalletPwd, Signer: this.Signer}
+}
+
+func (this *Layer2Config) ParseConcurrencyOrDefault() int {
+	if this.ParseConcurrency <= 0 {
+		return DEFAULT_LAYER2_PARSE_CONCURRENCY
+	}
+	return this.ParseConcurrency
+}
+
+// Reload return a copy of the config with the settings of next which take effect without restart: the node urls, the
+// deposit confirmations, the commit and deposit batches, the layer2 parse concurrency, the gas prices, the tokens and the withdraw fees. The names
+// of the other settings changed by next are returned, they take effect on restart
+func (this *ServiceConfig) Reload(next *ServiceConfig) (*ServiceConfig, []string) {
+	reloaded := *this
+	if this.OntologyConfig != nil && next.OntologyConfig != nil {
+		ontologyConfig := *this.OntologyConfig
+		ontologyConfig.RestURL = next.OntologyConfig.RestURL
+		ontologyConfig.GasPrice = next.OntologyConfig.GasPrice
+		ontologyConfig.GasLimit = next.OntologyConfig.GasLimit
+		ontologyConfig.MaxGasPrice = next.OntologyConfig.MaxGasPrice
+		ontologyConfig.GasPriceBump = next.OntologyConfig.GasPriceBump
+		ontologyConfig.CommitBatchSize = next.OntologyConfig.CommitBatchSize
+		ontologyConfig.CommitBatchWindow = next.OntologyConfig.CommitBatchWindow
+		ontologyConfig.DepositConfirmations = next.OntologyConfig.DepositConfirmations
+		reloaded.OntologyConfig = &ontologyConfig
+	}
+	if this.EthereumConfig != nil && next.EthereumConfig != nil {
+		ethereumConfig := *this.EthereumConfig
+		ethereumConfig.RpcURL = next.EthereumConfig.RpcURL
+		ethereumConfig.GasPrice = next.EthereumConfig.GasPrice
+		ethereumConfig.GasLimit = next.EthereumConfig.GasLimit
+		ethereumConfig.MaxGasPrice = next.EthereumConfig.MaxGasPrice
+		ethereumConfig.CommitBatchSize = next.EthereumConfig.CommitBatchSize
+		ethereumConfig.CommitBatchWindow = next.EthereumConfig.CommitBatchWindow
+		ethereumConfig.DepositConfirmations = next.EthereumConfig.DepositConfirmations
+		reloaded.EthereumConfig = &ethereumConfig
+	}
+	if this.Layer2Config != nil && next.Layer2Config != nil {
+		layer2Config := *this.Layer2Config
+		layer2Config.RestURL = next.Layer2Config.RestURL
+		layer2Config.GasPrice = next.Layer2Config.GasPrice
+		layer2Config.GasLimit = next.Layer2Config.GasLimit
+		layer2Config.DepositBatchSize = next.Layer2Config.DepositBatchSize
+		layer2Config.DepositBatchWindow = next.Layer2Config.DepositBatchWindow
+		layer2Config.ParseConcurrency = next.Layer2Config.ParseConcurrency
+		reloaded.Layer2Config = &layer2Config
+	}
+	reloaded.Tokens = next.Tokens
+	reloaded.WithdrawFees = next.WithdrawFees
+	restart := make([]string, 0)
+	current := reflect.ValueOf(reloaded)
+	changed := reflect.ValueOf(*next)
+	for i := 0; i < current.NumField(); i++ {
+		name := current.Type().Field(i).Name
+		if name == "Spec" {
+			continue
+		}
+		if !reflect.DeepEqual(current.Field(i).Interface(), changed.Field(i).Interface()) {
+			restart = append(restart, name)
+		}
+	}
+	return &reloaded, restart
 }
 
 func ReadFile(fileName string) ([]byte, error) {
@@ -112,6 +654,26 @@ func NewServiceConfig(configFilePath string) *ServiceConfig {
 		log.Errorf("NewServiceConfig: failed, err: %s", err)
 		return nil
 	}
+	if servConfig.ChainSpec != "" {
+		spec, err := LoadChainSpec(servConfig.ChainSpec)
+		if err != nil {
+			log.Errorf("NewServiceConfig: failed, err: %s", err)
+			return nil
+		}
+		spec.Apply(servConfig)
+	}
+	if servConfig.L1 != "" && servConfig.L1 != L1_ONTOLOGY && servConfig.L1 != L1_ETHEREUM {
+		log.Errorf("NewServiceConfig: failed, L1 %s is not supported", servConfig.L1)
+		return nil
+	}
+	if servConfig.IsEthereum() && servConfig.EthereumConfig == nil {
+		log.Errorf("NewServiceConfig: failed, EthereumConfig is required by the ethereum L1")
+		return nil
+	}
+	if !servConfig.IsEthereum() && servConfig.OntologyConfig == nil {
+		log.Errorf("NewServiceConfig: failed, OntologyConfig is required by the ontology L1")
+		return nil
+	}
 
 	return servConfig
 }
@@ -0,0 +1,322 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	ADMIN_TOKENS_PATH         = "/api/v1/tokens"
+	ADMIN_ADD_TOKEN_PATH      = "/api/v1/addtoken"
+	ADMIN_PAUSE_TOKEN_PATH    = "/api/v1/pausetoken"
+	ADMIN_ENABLE_TOKEN_PATH   = "/api/v1/enabletoken"
+	ADMIN_REFUND_DEPOSIT_PATH = "/api/v1/refunddeposit"
+	ADMIN_WITHDRAW_FEES_PATH  = "/api/v1/withdrawfees"
+	ADMIN_COMMIT_COSTS_PATH   = "/api/v1/commitcosts"
+	ADMIN_STATUS_PATH         = "/api/v1/status"
+	ADMIN_PAUSE_PATH          = "/api/v1/pause"
+	ADMIN_RESUME_PATH         = "/api/v1/resume"
+	ADMIN_PAUSES_PATH         = "/api/v1/pauses"
+	ADMIN_RELOAD_PATH         = "/api/v1/reload"
+	ADMIN_AUDIT_PATH          = "/api/v1/audit"
+	// the prefix of ListenAddr serving the admin api on the unix socket of the path
+	ADMIN_UNIX_PREFIX = "unix:"
+)
+
+type TokenRequest struct {
+	Name            string
+	Address         string
+	Layer2Address   string
+	Decimals        uint32
+	Standard        string
+}
+
+type RefundRequest struct {
+	ID              uint64
+}
+
+type AdminResponse struct {
+	Result          interface{}
+	Error           string
+}
+
+//startAdminServer serve the admin api of the token registry and the refund of the rejected deposits, it must only
+//listen on the address reachable by the admin
+func (this *Layer2Operator) startAdminServer() error {
+	adminConfig := this.config().AdminConfig
+	if adminConfig == nil || adminConfig.ListenAddr == "" {
+		return nil
+	}
+	listener, err := adminListener(adminConfig)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ADMIN_TOKENS_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		return this.tokens.list(), nil
+	}))
+	mux.HandleFunc(ADMIN_ADD_TOKEN_PATH, this.handleAdmin(this.adminAddToken))
+	mux.HandleFunc(ADMIN_PAUSE_TOKEN_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		return this.adminSetTokenEnabled(r, false)
+	}))
+	mux.HandleFunc(ADMIN_ENABLE_TOKEN_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		return this.adminSetTokenEnabled(r, true)
+	}))
+	mux.HandleFunc(ADMIN_REFUND_DEPOSIT_PATH, this.handleAdmin(this.adminRefundDeposit))
+	mux.HandleFunc(ADMIN_WITHDRAW_FEES_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		totals := LoadWithdrawFeeTotals()
+		if totals == nil {
+			return nil, fmt.Errorf("load withdraw fees failed")
+		}
+		return totals, nil
+	}))
+	mux.HandleFunc(ADMIN_COMMIT_COSTS_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		costs := LoadLayer2CommitCosts()
+		if costs == nil {
+			return nil, fmt.Errorf("load commit costs failed")
+		}
+		return costs, nil
+	}))
+	mux.HandleFunc(ADMIN_STATUS_PATH, this.handleAdmin(this.adminStatus))
+	mux.HandleFunc(ADMIN_PAUSE_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		return this.adminPause(r, true)
+	}))
+	mux.HandleFunc(ADMIN_RESUME_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		return this.adminPause(r, false)
+	}))
+	mux.HandleFunc(ADMIN_PAUSES_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		pauses := LoadPauses()
+		if pauses == nil {
+			return nil, fmt.Errorf("load pauses failed")
+		}
+		return pauses, nil
+	}))
+	mux.HandleFunc(ADMIN_RELOAD_PATH, this.handleAdmin(func(r *http.Request) (interface{}, error) {
+		if r.Method != http.MethodPost {
+			return nil, fmt.Errorf("method %s is not allowed", r.Method)
+		}
+		return this.Reload(adminInitiator(r))
+	}))
+	mux.HandleFunc(ADMIN_AUDIT_PATH, this.handleAdmin(this.adminAudit))
+	this.adminServer = &http.Server{Handler: mux}
+	go this.adminServer.Serve(listener)
+	log.Infof("admin - server started at %s", adminConfig.ListenAddr)
+	return nil
+}
+
+//adminListener listen on the unix socket or the tcp address of ListenAddr, the tcp address must be a loopback
+//address unless the requests are authenticated by AuthToken
+func adminListener(adminConfig *config.AdminConfig) (net.Listener, error) {
+	if strings.HasPrefix(adminConfig.ListenAddr, ADMIN_UNIX_PREFIX) {
+		path := strings.TrimPrefix(adminConfig.ListenAddr, ADMIN_UNIX_PREFIX)
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("admin listen %s error: %s", adminConfig.ListenAddr, err)
+		}
+		return listener, nil
+	}
+	if adminConfig.AuthToken == "" && !isLoopbackAddr(adminConfig.ListenAddr) {
+		return nil, fmt.Errorf("admin listen %s error: AuthToken is required unless the address is a loopback or unix socket address", adminConfig.ListenAddr)
+	}
+	listener, err := net.Listen("tcp", adminConfig.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("admin listen %s error: %s", adminConfig.ListenAddr, err)
+	}
+	return listener, nil
+}
+
+//isLoopbackAddr return true if the host of the address is localhost or a loopback ip, the empty host listens on all
+//the interfaces
+func isLoopbackAddr(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (this *Layer2Operator) handleAdmin(handle func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &AdminResponse{}
+		if !this.isAdminAuthorized(r) {
+			log.Errorf("admin - %s from %s is not authorized", r.URL.Path, r.RemoteAddr)
+			resp.Error = "not authorized"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		result, err := handle(r)
+		if err != nil {
+			log.Errorf("admin - %s err: %v", r.URL.Path, err)
+			resp.Error = err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			resp.Result = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+//isAdminAuthorized return true if the auth token is not set, which is only allowed on the loopback and unix socket
+//addresses, or the request carries it as the bearer token
+func (this *Layer2Operator) isAdminAuthorized(r *http.Request) bool {
+	authToken := this.config().AdminConfig.AuthToken
+	if authToken == "" {
+		return true
+	}
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(authToken)) == 1
+}
+
+func (this *Layer2Operator) adminAddToken(r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	req := &TokenRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %s", err)
+	}
+	token := &Token{
+		Name: req.Name,
+		Address: req.Address,
+		Layer2Address: req.Layer2Address,
+		Decimals: req.Decimals,
+		Enabled: true,
+		Standard: req.Standard,
+	}
+	err = this.tokens.add(token)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("admin - add token %s, address: %s, layer2 address: %s", token.Name, token.Address, token.Layer2Address)
+	result, err := this.mirrorToken(token.Address, true)
+	auditToken(r, result, err, "add token %s, address: %s, layer2 address: %s", token.Name, token.Address, token.Layer2Address)
+	return result, err
+}
+
+func (this *Layer2Operator) adminSetTokenEnabled(r *http.Request, enabled bool) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	req := &TokenRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %s", err)
+	}
+	err = this.tokens.setEnabled(req.Address, enabled)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("admin - set token %s enabled: %v", req.Address, enabled)
+	result, err := this.mirrorToken(req.Address, enabled)
+	auditToken(r, result, err, "set token %s enabled: %v", req.Address, enabled)
+	return result, err
+}
+
+//auditToken record the change of the token registry with the transaction mirroring it, the error of the mirror is
+//recorded as the change is saved already
+func auditToken(r *http.Request, mirror interface{}, err error, format string, args ...interface{}) {
+	txHash, _ := mirror.(string)
+	detail := fmt.Sprintf(format, args...)
+	if err != nil {
+		detail += fmt.Sprintf(", mirror err: %v", err)
+	}
+	audit(AUDIT_TOKEN, adminInitiator(r), txHash, "%s", detail)
+}
+
+//mirrorToken set the token to the registry of the layer2 contract if MirrorTokens, and return the transaction hash
+func (this *Layer2Operator) mirrorToken(address string, enabled bool) (interface{}, error) {
+	if !this.config().AdminConfig.MirrorTokens {
+		return nil, nil
+	}
+	assetAddress, _ := hex.DecodeString(address)
+	params, err := this.bridge.SetTokenParams(&bridge.SetTokenParam{AssetAddress: assetAddress, Enabled: enabled})
+	if err != nil {
+		return nil, err
+	}
+	return this.invokeLayer2Contract(params)
+}
+
+//adminRefundDeposit return the rejected deposit to the player on ontology
+func (this *Layer2Operator) adminRefundDeposit(r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	req := &RefundRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %s", err)
+	}
+	deposit := LoadDepositByID(req.ID)
+	if deposit == nil {
+		return nil, fmt.Errorf("deposit %d is not found", req.ID)
+	}
+	if deposit.State != DEPOSIT_REJECTED {
+		return nil, fmt.Errorf("deposit %d of state %d is not rejected", req.ID, deposit.State)
+	}
+	params, err := this.bridge.RefundDepositParams(&bridge.RefundDepositParam{DepositId: deposit.ID})
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := this.invokeLayer2Contract(params)
+	if err != nil {
+		return nil, err
+	}
+	audit(AUDIT_REFUND, adminInitiator(r), txHash, "refund rejected deposit %d", deposit.ID)
+	err = UpdateDepositRefund(deposit.ID, DEPOSIT_REFUNDED, txHash)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("admin - refund deposit %d, tx hash: %s", deposit.ID, txHash)
+	return txHash, nil
+}
+
+//invokeLayer2Contract send the transaction of the operator to the layer2 contract on L1, the multisig operator
+//address can not sign it alone, and no transaction is sent in the dry run
+func (this *Layer2Operator) invokeLayer2Contract(params []interface{}) (string, error) {
+	if this.multiSig != nil {
+		return "", fmt.Errorf("the operator address is the multisig address of the operators")
+	}
+	if this.dryRun() {
+		return "", errDryRun
+	}
+	return this.l1.Invoke(params)
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/operator/config"
+)
+
+func TestAdminListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		config  *config.AdminConfig
+		ok      bool
+	}{
+		{&config.AdminConfig{ListenAddr: "127.0.0.1:0"}, true},
+		{&config.AdminConfig{ListenAddr: "localhost:0"}, true},
+		{&config.AdminConfig{ListenAddr: "[::1]:0"}, true},
+		{&config.AdminConfig{ListenAddr: ADMIN_UNIX_PREFIX + filepath.Join(dir, "admin.sock")}, true},
+		{&config.AdminConfig{ListenAddr: ":0", AuthToken: "secret"}, true},
+		{&config.AdminConfig{ListenAddr: "0.0.0.0:0", AuthToken: "secret"}, true},
+		// the address reachable by others requires the auth token
+		{&config.AdminConfig{ListenAddr: ":0"}, false},
+		{&config.AdminConfig{ListenAddr: "0.0.0.0:0"}, false},
+		{&config.AdminConfig{ListenAddr: "10.0.0.1:0"}, false},
+		{&config.AdminConfig{ListenAddr: "127.0.0.1"}, false},
+	}
+	for _, c := range cases {
+		listener, err := adminListener(c.config)
+		if (err == nil) != c.ok {
+			t.Errorf("listen %s with auth token %q: err %v", c.config.ListenAddr, c.config.AuthToken, err)
+		}
+		if listener != nil {
+			listener.Close()
+		}
+	}
+}
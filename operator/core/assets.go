@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/config"
+)
+
+// the hex of the native contracts of ONT and ONG on layer2, which are fixed by the layer2 node
+var (
+	LAYER2_ONT_ADDRESS = hex.EncodeToString(layer2_sdk.ONT_CONTRACT_ADDRESS[:])
+	LAYER2_ONG_ADDRESS = hex.EncodeToString(layer2_sdk.ONG_CONTRACT_ADDRESS[:])
+)
+
+//nativeAssets is the addresses of ONT and ONG in the bridge contract on L1, and the layer2 address the deposits are
+//minted from and the withdrawals are transferred to
+type nativeAssets struct {
+	network      string
+	ont          string
+	ong          string
+	collector    layer2_common.Address
+}
+
+//assets is the native assets of the network of the operator, the default is the preset of mainnet
+var assets *nativeAssets
+
+func init() {
+	if err := SetAssets(nil); err != nil {
+		panic(err)
+	}
+}
+
+//SetAssets check the assets of the config and set them as the native assets of the operator, it is called at startup
+//before the tokens are registered
+func SetAssets(assetConfig *config.AssetConfig) error {
+	resolved, err := assetConfig.Resolve()
+	if err != nil {
+		return err
+	}
+	collector, err := layer2_common.AddressFromBase58(resolved.Collector)
+	if err != nil {
+		return fmt.Errorf("collector %s of AssetConfig is not a layer2 address: %s", resolved.Collector, err)
+	}
+	assets = &nativeAssets{network: resolved.Network, ont: resolved.OntAddress, ong: resolved.OngAddress, collector: collector}
+	return nil
+}
+
+//isNativeToken return whether the address is ONT or ONG, on L1 or on layer2
+func isNativeToken(address string) bool {
+	return address == assets.ont || address == assets.ong || address == LAYER2_ONT_ADDRESS || address == LAYER2_ONG_ADDRESS
+}
+
+//isOntToken return whether the token is ONT, the other native token is ONG
+func isOntToken(token *Token) bool {
+	return token.Address == assets.ont
+}
+
+//isLayer2Tx return whether the base58 layer2 address is the collector, the transfers from it are the mints of the
+//deposits and the transfers to it are the withdrawals
+func isLayer2Tx(addr string) bool {
+	address, err := layer2_common.AddressFromBase58(addr)
+	return err == nil && address == assets.collector
+}
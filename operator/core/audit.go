@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+// the external actions of the operator recorded in the audit log
+const (
+	AUDIT_MINT          = "mint"
+	AUDIT_COMMIT        = "commit"
+	AUDIT_REFUND        = "refund"
+	AUDIT_FREEZE        = "freeze"
+	AUDIT_CHALLENGE     = "challenge"
+	AUDIT_TOKEN         = "token"
+	AUDIT_PAUSE         = "pause"
+	AUDIT_RESUME        = "resume"
+	AUDIT_RELOAD        = "reload"
+	AUDIT_KEY_ROTATION  = "keyrotation"
+	AUDIT_DEAD_LETTER   = "deadletter"
+	AUDIT_COMMIT_ADMIN  = "commitadmin"
+)
+
+// the initiators of the actions besides the admin api, whose initiator is the remote address of the request
+const (
+	AUDIT_BY_OPERATOR = "operator"
+	AUDIT_BY_CLI      = "cli"
+	AUDIT_BY_SIGNAL   = "signal"
+
+	// the count of the latest audit entries in the status and the audit api by default
+	AUDIT_DEFAULT_LIMIT = 20
+)
+
+//audit append the action to the audit log, the action is done already, so the failure to record it is only logged
+func audit(action string, initiator string, txHash string, format string, args ...interface{}) {
+	entry := &AuditEntry{
+		Action:    action,
+		Initiator: initiator,
+		Detail:    fmt.Sprintf(format, args...),
+		TxHash:    txHash,
+		TT:        uint32(time.Now().Unix()),
+	}
+	err := SaveAuditEntry(entry)
+	if err != nil {
+		log.Errorf("audit - save %s by %s err: %v, detail: %s, tx hash: %s", action, initiator, err, entry.Detail, txHash)
+	}
+}
+
+//adminInitiator return the initiator of the admin request
+func adminInitiator(r *http.Request) string {
+	return "admin " + r.RemoteAddr
+}
+
+//adminAudit return the audit entries before the id of the before param, at most limit of them and the latest first
+func (this *Layer2Operator) adminAudit(r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	limit, err := queryLimit(r, AUDIT_DEFAULT_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+	before := uint64(0)
+	if value := r.URL.Query().Get("before"); value != "" {
+		before, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid before %s", value)
+		}
+	}
+	entries := LoadAuditLog(before, limit)
+	if entries == nil {
+		return nil, fmt.Errorf("load audit log failed")
+	}
+	return entries, nil
+}
+
+//queryLimit return the positive limit param of the request, or def if it is not set
+func queryLimit(r *http.Request, def int) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid limit %s", value)
+	}
+	return limit, nil
+}
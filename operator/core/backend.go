@@ -0,0 +1,557 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	ontology_sdk "github.com/ontio/ontology-go-sdk"
+	ontology_common "github.com/ontio/ontology/common"
+	ontology_types "github.com/ontio/ontology/core/types"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+//L1Event is the event of the layer2 contract in the L1 block, States is decoded by the bridge of the L1 chain.
+//NotifyIndex is the index of the event among the events of the contract in its transaction, so TxHash and NotifyIndex
+//identify the event in any block the transaction is packed in
+type L1Event struct {
+	TxHash      string
+	NotifyIndex uint32
+	States      interface{}
+}
+
+type L1Block struct {
+	Height    uint32
+	Hash      string
+	Timestamp uint32
+	Events    []*L1Event
+}
+
+//L1CommitResult is the result of the executed updateState transaction, Fee is the gas paid by the transaction in the
+//smallest unit of the L1 native asset
+type L1CommitResult struct {
+	Height    uint32
+	Success   bool
+	GasPrice  uint64
+	GasUsed   uint64
+	Fee       uint64
+}
+
+//L1Backend is the chain the layer2 deposits from and the layer2 states are committed to, the params of the layer2
+//contract are built by the bridge of the chain. The operator only talks to the L1 through it, so another L1 or a mock
+//chain is plugged in by NewLayer2OperatorWithBackend
+type L1Backend interface {
+	Name() string
+	Bridge() bridge.Bridge
+	GetHeight() (uint32, error)
+	BlockHash(height uint32) (string, error)
+	//GetEvents return the block of height with the events of the layer2 contract
+	GetEvents(height uint32) (*L1Block, error)
+	//Call pre-execute the layer2 contract, the result is parsed by the bridge
+	Call(params []interface{}) (interface{}, error)
+	//Invoke send the transaction of the layer2 contract, it fails if the transaction fails in pre-execution
+	Invoke(params []interface{}) (string, error)
+	//SubmitStateCommit send the updateState transaction until it is accepted by the L1 node
+	SubmitStateCommit(params []interface{}) (string, error)
+	//CheckCommit return nil if the updateState transaction is not executed yet
+	CheckCommit(txHash string) (*L1CommitResult, error)
+	//IsTxLost return true if the L1 node is reachable and the transaction is neither in a block nor in the pool
+	IsTxLost(txHash string) bool
+	//GasBalance return the balance of the operator account paying the gas, ONG in the smallest unit or ETH in gwei. The
+	//largest balance of the payers is returned if the commits are paid by the payer accounts
+	GasBalance() (uint64, error)
+	//CommitFee return the estimated fee of the updateState transaction of params at the initial gas price, in the unit
+	//of GasBalance
+	CommitFee(params []interface{}) (uint64, error)
+	//Reload apply the reloaded config, the node is connected again if its url is changed
+	Reload(servConfig *config.ServiceConfig) error
+	//NewBlocks return the channel of the heights of the blocks pushed by the node, nil if the node is polled
+	NewBlocks() <-chan uint32
+}
+
+//newL1Backend connect the L1 chain of the config, the ontology account is only loaded for the ontology L1
+func (this *Layer2Operator) newL1Backend() (L1Backend, error) {
+	if this.config().IsEthereum() {
+		return newEthereumBackend(this.config().EthereumConfig, this.retry, this.submit)
+	}
+	account, err := this.getOntologyAccount(this.config().OntologyConfig.Account())
+	if err != nil {
+		return nil, err
+	}
+	this.ontologyAccount = account
+	backend, err := newOntologyBackend(this.ontologySdk, account, this.config().OntologyConfig, this.retry, this.submit)
+	if err != nil {
+		return nil, err
+	}
+	if len(this.config().OntologyConfig.Payers) > 0 {
+		backend.payers, err = this.newPayerPool(this.config().OntologyConfig.Payers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return backend, nil
+}
+
+//newPayerPool load the payer accounts paying the commits in rotation, they are not supported by the multisig operators
+//whose commits are paid by the multisig address
+func (this *Layer2Operator) newPayerPool(accounts []*config.AccountConfig) (*payerPool, error) {
+	if this.config().MultiSigConfig != nil {
+		return nil, fmt.Errorf("payer accounts are not supported by the multisig operators")
+	}
+	payers := make([]*ontologySigner, 0, len(accounts))
+	loaded := make(map[ontology_common.Address]bool)
+	for _, account := range accounts {
+		payer, err := this.getOntologyAccount(account)
+		if err != nil {
+			return nil, err
+		}
+		if loaded[payer.Address] {
+			return nil, fmt.Errorf("payer account %s is duplicated", payer.Address.ToBase58())
+		}
+		loaded[payer.Address] = true
+		payers = append(payers, payer)
+	}
+	pool := newPayerPool(payers)
+	log.Infof("ontologyAccount - commits are paid by the payers in rotation: %s", strings.Join(pool.Addresses(), ", "))
+	return pool, nil
+}
+
+//ontologyBackend is the L1Backend of the NeoVM layer2 contract on ontology
+type ontologyBackend struct {
+	// the config and the sdk are replaced on reload
+	configValue atomic.Value
+	sdkValue    atomic.Value
+	retry     *retryPolicy
+	submit    *submitQueue
+	account   *ontologySigner
+	// the next key of the operator in the key rotation, the transactions are signed by both keys
+	cosigner  *ontologySigner
+	// the accounts paying the commits in rotation, the operator account pays if it is nil
+	payers    *payerPool
+	bridge    bridge.Bridge
+	contract  ontology_common.Address
+	// the contract address of the notify is the hex string of the address
+	contractHex string
+	// the blocks pushed by the websocket of the node, nil if WebSocketURL is not set
+	subscription *blockSubscription
+}
+
+func newOntologyBackend(sdk *ontology_sdk.OntologySdk, account *ontologySigner, cfg *config.OntologyConfig, retry *retryPolicy, submit *submitQueue) (*ontologyBackend, error) {
+	contractAddress := cfg.Layer2ContractAddress
+	contract, err := ontology_common.AddressFromHexString(contractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layer2 contract address %s: %s", contractAddress, err)
+	}
+	backend := &ontologyBackend{
+		retry:       retry,
+		submit:      submit,
+		account:     account,
+		bridge:      bridge.NewNeoVMBridge(),
+		contract:    contract,
+		contractHex: contractAddress,
+	}
+	backend.configValue.Store(cfg)
+	backend.sdkValue.Store(sdk)
+	if cfg.WebSocketURL != "" {
+		backend.subscription = newBlockSubscription(backend.Name(), cfg.WebSocketURL, newOntologySubscriber())
+	}
+	return backend, nil
+}
+
+func (this *ontologyBackend) config() *config.OntologyConfig {
+	return this.configValue.Load().(*config.OntologyConfig)
+}
+
+func (this *ontologyBackend) sdk() *ontology_sdk.OntologySdk {
+	return this.sdkValue.Load().(*ontology_sdk.OntologySdk)
+}
+
+//Reload replace the config by the reloaded one, the ontology node of the new RestURL must be reachable
+func (this *ontologyBackend) Reload(servConfig *config.ServiceConfig) error {
+	cfg := servConfig.OntologyConfig
+	if cfg.RestURL != this.config().RestURL {
+		sdk := ontology_sdk.NewOntologySdk()
+		sdk.NewRpcClient().SetAddress(cfg.RestURL)
+		_, err := sdk.GetCurrentBlockHeight()
+		if err != nil {
+			return fmt.Errorf("connect ontology node %s err: %v", cfg.RestURL, err)
+		}
+		this.sdkValue.Store(sdk)
+		log.Infof("reload - ontology node changed to %s", cfg.RestURL)
+	}
+	this.configValue.Store(cfg)
+	return nil
+}
+
+func (this *ontologyBackend) Name() string {
+	return "ontology"
+}
+
+func (this *ontologyBackend) NewBlocks() <-chan uint32 {
+	if this.subscription == nil {
+		return nil
+	}
+	return this.subscription.NewBlocks()
+}
+
+func (this *ontologyBackend) Bridge() bridge.Bridge {
+	return this.bridge
+}
+
+func (this *ontologyBackend) GetHeight() (uint32, error) {
+	return this.sdk().GetCurrentBlockHeight()
+}
+
+func (this *ontologyBackend) BlockHash(height uint32) (string, error) {
+	block, err := this.sdk().GetBlockByHeight(height)
+	if err != nil {
+		return "", err
+	}
+	blockHash := block.Hash()
+	return blockHash.ToHexString(), nil
+}
+
+func (this *ontologyBackend) GetEvents(height uint32) (*L1Block, error) {
+	var block *ontology_types.Block
+	if this.subscription != nil {
+		block, _ = this.subscription.Block(height).(*ontology_types.Block)
+	}
+	if block == nil {
+		var err error
+		block, err = this.sdk().GetBlockByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+	}
+	blockHash := block.Hash()
+	result := &L1Block{
+		Height:    height,
+		Hash:      blockHash.ToHexString(),
+		Timestamp: block.Header.Timestamp,
+		Events:    make([]*L1Event, 0),
+	}
+	// the empty block has no event
+	if len(block.Transactions) == 0 {
+		return result, nil
+	}
+	events, err := this.sdk().GetSmartContractEventByBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		notifyIndex := uint32(0)
+		for _, notify := range event.Notify {
+			if notify.ContractAddress != this.contractHex {
+				continue
+			}
+			result.Events = append(result.Events, &L1Event{
+				TxHash:      event.TxHash,
+				NotifyIndex: notifyIndex,
+				States:      notify.States,
+			})
+			notifyIndex ++
+		}
+	}
+	return result, nil
+}
+
+func (this *ontologyBackend) Call(params []interface{}) (interface{}, error) {
+	tx, err := this.sdk().NeoVM.NewNeoVMInvokeTransaction(0, 0, this.contract, params)
+	if err != nil {
+		return nil, fmt.Errorf("new transaction failed!")
+	}
+	result, err := this.sdk().PreExecTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || result.Result == nil {
+		return nil, fmt.Errorf("result of contract is not found")
+	}
+	return result.Result, nil
+}
+
+//preExec pre-execute the transaction signed by the operator, return the gas consumed
+func (this *ontologyBackend) preExec(params []interface{}) (uint64, error) {
+	tx, err := this.sdk().NeoVM.NewNeoVMInvokeTransaction(0, 0, this.contract, params)
+	if err != nil {
+		return 0, err
+	}
+	err = this.sign(tx)
+	if err != nil {
+		return 0, fmt.Errorf("sign transaction failed! err: %s", err.Error())
+	}
+	result, err := this.sdk().PreExecTransaction(tx)
+	if err != nil {
+		return 0, err
+	}
+	if result.State == 0 {
+		return 0, fmt.Errorf("transaction is failed in pre-execution")
+	}
+	return result.Gas, nil
+}
+
+func (this *ontologyBackend) Invoke(params []interface{}) (string, error) {
+	return this.submit.Submit(this.account.Address.ToBase58(), func() (string, error) {
+		gasLimit, err := this.preExec(params)
+		if err != nil {
+			return "", err
+		}
+		tx, err := this.sdk().NeoVM.NewNeoVMInvokeTransaction(this.gasPrice(), gasLimit, this.contract, params)
+		if err != nil {
+			return "", err
+		}
+		err = this.sign(tx)
+		if err != nil {
+			return "", err
+		}
+		txHash, err := this.sdk().SendTransaction(tx)
+		if err != nil {
+			return "", err
+		}
+		return txHash.ToHexString(), nil
+	})
+}
+
+//SubmitStateCommit send the commit paid by the operator account, or by the next payer in rotation whose balance covers
+//the estimated fee. The payer failing to send the commit is held, so the next commit is sent by another payer
+func (this *ontologyBackend) SubmitStateCommit(params []interface{}) (string, error) {
+	if this.payers == nil {
+		return this.commitSignedBy(this.account.Address.ToBase58(), params, this.sign)
+	}
+	payer, err := this.commitPayer(params)
+	if err != nil {
+		return "", err
+	}
+	txHash, err := this.commitSignedBy(payer.Address.ToBase58(), params, func(tx *ontology_types.MutableTransaction) error {
+		return this.signPaidBy(tx, payer)
+	})
+	if err != nil {
+		if err != errShutdown {
+			log.Warnf("payer %s failed to send the commit, hold it for %s", payer.Address.ToBase58(), PAYER_HOLD_TIME)
+			this.payers.Hold(payer)
+		}
+		return "", err
+	}
+	this.payers.Release(payer)
+	return txHash, nil
+}
+
+//commitPayer return the first payer in rotation whose balance covers the estimated fee of the commit, the commit is
+//deferred if no payer covers it
+func (this *ontologyBackend) commitPayer(params []interface{}) (*ontologySigner, error) {
+	fee, err := this.CommitFee(params)
+	if err != nil {
+		return nil, err
+	}
+	for _, payer := range this.payers.Rotate() {
+		balance, err := this.sdk().Native.Ong.BalanceOf(payer.Address)
+		if err != nil {
+			log.Warnf("get gas balance of payer %s err: %v", payer.Address.ToBase58(), err)
+			continue
+		}
+		if balance >= fee {
+			return payer, nil
+		}
+		log.Warnf("gas balance %d of payer %s can not cover the estimated commit fee %d", balance, payer.Address.ToBase58(), fee)
+	}
+	return nil, &waitError{fmt.Errorf("no payer can cover the estimated commit fee %d", fee)}
+}
+
+//sign set the operator the payer and sign the transaction, it is signed by the next key too in the key rotation
+func (this *ontologyBackend) sign(tx *ontology_types.MutableTransaction) error {
+	return this.signPaidBy(tx, this.account)
+}
+
+//signPaidBy set the payer and sign the transaction by the operator as sign, it is signed by the payer too if the payer
+//is another account
+func (this *ontologyBackend) signPaidBy(tx *ontology_types.MutableTransaction, payer *ontologySigner) error {
+	this.sdk().SetPayer(tx, payer.Address)
+	err := this.sdk().SignToTransaction(tx, this.account)
+	if err != nil {
+		return err
+	}
+	if this.cosigner != nil {
+		err = this.sdk().SignToTransaction(tx, this.cosigner)
+		if err != nil {
+			return err
+		}
+	}
+	if payer.Address != this.account.Address {
+		return this.sdk().SignToTransaction(tx, payer)
+	}
+	return nil
+}
+
+//gasPrice return the configured gas price, or the gas price of the network if it is not configured
+func (this *ontologyBackend) gasPrice() uint64 {
+	if this.config().GasPrice > 0 {
+		return this.config().GasPrice
+	}
+	params, err := this.sdk().Native.GlobalParams.GetGlobalParams([]string{"gasPrice"})
+	if err != nil {
+		log.Warnf("get gas price of ontology failed! err: %s, use the default gas price", err.Error())
+		return config.DEFAULT_ONT_GAS_PRICE
+	}
+	gasPrice, err := strconv.ParseUint(params["gasPrice"], 10, 64)
+	if err != nil || gasPrice == 0 {
+		return config.DEFAULT_ONT_GAS_PRICE
+	}
+	return gasPrice
+}
+
+//commitGasLimit return the gas limit of the updateState transaction by pre-execution, or the configured gas limit if it
+//fails in pre-execution
+func (this *ontologyBackend) commitGasLimit(params []interface{}) uint64 {
+	gasLimit, err := this.preExec(params)
+	if err != nil {
+		gasLimit = this.config().GasLimit
+		if gasLimit == 0 {
+			gasLimit = config.DEFAULT_COMMIT_GAS_LIMIT
+		}
+	}
+	return gasLimit
+}
+
+//commitSignedBy send the updateState transaction of the payer signed by sign, which is the multisig of the operators if
+//configured, the transaction is sent by the submission queue of the payer
+func (this *ontologyBackend) commitSignedBy(payer string, params []interface{}, sign func(tx *ontology_types.MutableTransaction) error) (string, error) {
+	return this.submit.Submit(payer, func() (string, error) {
+		return this.sendCommit(params, sign)
+	})
+}
+
+func (this *ontologyBackend) sendCommit(params []interface{}, sign func(tx *ontology_types.MutableTransaction) error) (string, error) {
+	gasLimit := this.commitGasLimit(params)
+	pricer := newGasPricer(this.gasPrice(), this.config().MaxGasPrice, this.config().GasPriceBump)
+	return this.sendUntilAccepted(pricer, func(gasPrice uint64) (*ontology_types.MutableTransaction, error) {
+		tx, err := this.sdk().NeoVM.NewNeoVMInvokeTransaction(gasPrice, gasLimit, this.contract, params)
+		if err != nil {
+			return nil, fmt.Errorf("new layer2 state commit transaction failed! err: %s", err.Error())
+		}
+		err = sign(tx)
+		if err != nil {
+			return nil, fmt.Errorf("sign layer2 state commit transaction failed! err: %s", err.Error())
+		}
+		return tx, nil
+	})
+}
+
+//sendUntilAccepted send the transaction again with the backoff of the retry policy until it is accepted by the ontology
+//node, the transaction is built and signed again with the raised gas price of pricer after every rejection
+func (this *ontologyBackend) sendUntilAccepted(pricer *gasPricer, build func(gasPrice uint64) (*ontology_types.MutableTransaction, error)) (string, error) {
+	tx, err := build(pricer.Price())
+	if err != nil {
+		return "", err
+	}
+	for attempt := 0; ; attempt ++ {
+		txHash, err := this.sdk().SendTransaction(tx)
+		if err == nil {
+			return txHash.ToHexString(), nil
+		}
+		if this.retry.Exhausted(attempt + 1) {
+			return "", fmt.Errorf("send layer2 state commit transaction failed %d times! err: %s", attempt + 1, err.Error())
+		}
+		log.Errorf("send layer2 state commit transaction with gas price %d failed! err: %s, try again......", pricer.Price(), err.Error())
+		if !this.retry.Wait(attempt) {
+			return "", errShutdown
+		}
+		if !pricer.Bump() {
+			continue
+		}
+		tx, err = build(pricer.Price())
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (this *ontologyBackend) CheckCommit(txHash string) (*L1CommitResult, error) {
+	event, err := this.sdk().GetSmartContractEvent(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, nil
+	}
+	height, err := this.sdk().GetBlockHeightByTxHash(txHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := this.sdk().GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	result := &L1CommitResult{
+		Height:   height,
+		Success:  event.State == 1,
+		GasPrice: tx.GasPrice,
+		Fee:      event.GasConsumed,
+	}
+	// the gas consumed of the event is the fee of the transaction
+	if tx.GasPrice > 0 {
+		result.GasUsed = event.GasConsumed / tx.GasPrice
+	}
+	return result, nil
+}
+
+func (this *ontologyBackend) GasBalance() (uint64, error) {
+	if this.payers == nil {
+		return this.sdk().Native.Ong.BalanceOf(this.account.Address)
+	}
+	var largest uint64
+	var lastErr error
+	reached := false
+	for _, payer := range this.payers.payers {
+		balance, err := this.sdk().Native.Ong.BalanceOf(payer.Address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reached = true
+		if balance > largest {
+			largest = balance
+		}
+	}
+	if !reached {
+		return 0, lastErr
+	}
+	return largest, nil
+}
+
+func (this *ontologyBackend) CommitFee(params []interface{}) (uint64, error) {
+	return this.gasPrice() * this.commitGasLimit(params), nil
+}
+
+func (this *ontologyBackend) IsTxLost(txHash string) bool {
+	if _, err := this.sdk().GetCurrentBlockHeight(); err != nil {
+		return false
+	}
+	if height, err := this.sdk().GetBlockHeightByTxHash(txHash); err == nil && height > 0 {
+		return false
+	}
+	if _, err := this.sdk().GetMemPoolTxState(txHash); err == nil {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,370 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	layer2_sdk_common "github.com/ontio/layer2/go-sdk/common"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	ontology_sdk_common "github.com/ontio/ontology-go-sdk/common"
+	ontology_common "github.com/ontio/ontology/common"
+)
+
+//mockL1Backend is the L1 of the NeoVM bridge contract in memory, the commits submitted are kept and executed with the
+//results set by the test
+type mockL1Backend struct {
+	bridge        *bridge.NeoVMBridge
+	height        uint32
+	hashes        map[uint32]string
+	blocks        map[uint32]*L1Block
+	stateRoots    map[uint64]*bridge.StateRoot
+	commitHeight  uint32
+	commits       [][]interface{}
+	results       map[string]*L1CommitResult
+	gasBalance    uint64
+	commitFee     uint64
+}
+
+func newMockL1Backend() *mockL1Backend {
+	return &mockL1Backend{
+		bridge:     bridge.NewNeoVMBridge(),
+		hashes:     make(map[uint32]string),
+		blocks:     make(map[uint32]*L1Block),
+		stateRoots: make(map[uint64]*bridge.StateRoot),
+		results:    make(map[string]*L1CommitResult),
+		gasBalance: 1000,
+		commitFee:  10,
+	}
+}
+
+//neovmResult return the pre-execute result of the json form, in which result is a hex string or array
+func neovmResult(result interface{}) (*ontology_sdk_common.ResultItem, error) {
+	data, err := json.Marshal(map[string]interface{}{"State": 1, "Gas": 0, "Result": result})
+	if err != nil {
+		return nil, err
+	}
+	preExec := &ontology_sdk_common.PreExecResult{}
+	err = json.Unmarshal(data, preExec)
+	if err != nil {
+		return nil, err
+	}
+	return preExec.Result, nil
+}
+
+func neovmInt(value uint64) string {
+	return hex.EncodeToString(ontology_common.BigIntToNeoBytes(new(big.Int).SetUint64(value)))
+}
+
+func (this *mockL1Backend) Name() string {
+	return "ontology"
+}
+
+func (this *mockL1Backend) Bridge() bridge.Bridge {
+	return this.bridge
+}
+
+func (this *mockL1Backend) GetHeight() (uint32, error) {
+	return this.height, nil
+}
+
+func (this *mockL1Backend) BlockHash(height uint32) (string, error) {
+	hash, ok := this.hashes[height]
+	if !ok {
+		return "", fmt.Errorf("block %d is not found", height)
+	}
+	return hash, nil
+}
+
+func (this *mockL1Backend) GetEvents(height uint32) (*L1Block, error) {
+	block, ok := this.blocks[height]
+	if !ok {
+		return &L1Block{Height: height, Hash: this.hashes[height]}, nil
+	}
+	return block, nil
+}
+
+func (this *mockL1Backend) Call(params []interface{}) (interface{}, error) {
+	switch params[0] {
+	case bridge.METHOD_GET_CURRENT_HEIGHT:
+		return neovmResult(neovmInt(uint64(this.commitHeight)))
+	case bridge.METHOD_GET_STATE_ROOT_BY_HEIGHT:
+		height := params[1].([]interface{})[0].(uint64)
+		stateRoot, ok := this.stateRoots[height]
+		if !ok {
+			return neovmResult("")
+		}
+		return neovmResult([]string{
+			hex.EncodeToString([]byte(stateRoot.StateRootHash)),
+			neovmInt(stateRoot.Height),
+			hex.EncodeToString([]byte(stateRoot.Version)),
+		})
+	}
+	return nil, fmt.Errorf("method %v is not supported by mock backend", params[0])
+}
+
+func (this *mockL1Backend) Invoke(params []interface{}) (string, error) {
+	return "", fmt.Errorf("invoke is not supported by mock backend")
+}
+
+func (this *mockL1Backend) SubmitStateCommit(params []interface{}) (string, error) {
+	this.commits = append(this.commits, params)
+	return fmt.Sprintf("commit-%d", len(this.commits)), nil
+}
+
+func (this *mockL1Backend) CheckCommit(txHash string) (*L1CommitResult, error) {
+	return this.results[txHash], nil
+}
+
+func (this *mockL1Backend) IsTxLost(txHash string) bool {
+	return false
+}
+
+func (this *mockL1Backend) GasBalance() (uint64, error) {
+	return this.gasBalance, nil
+}
+
+func (this *mockL1Backend) CommitFee(params []interface{}) (uint64, error) {
+	return this.commitFee, nil
+}
+
+func (this *mockL1Backend) Reload(servConfig *config.ServiceConfig) error {
+	return nil
+}
+
+func (this *mockL1Backend) NewBlocks() <-chan uint32 {
+	return nil
+}
+
+//newTestOperator return the leading operator on the mock L1 and the SQLite database of openTestDB
+func newTestOperator(t *testing.T, l1 *mockL1Backend) *Layer2Operator {
+	openTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	drain, abort := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	t.Cleanup(abort)
+	operator := &Layer2Operator{
+		bridge:          l1.Bridge(),
+		l1:              l1,
+		l1ChainInfo:     LoadChainInfo(l1.Name()),
+		layer2ChainInfo: LoadChainInfo("layer2"),
+		ctx:             ctx,
+		cancel:          cancel,
+		drain:           drain,
+		abort:           abort,
+		drained:         make(chan struct{}),
+		tokens:          newTokenRegistry(),
+		metrics:         newOperatorMetrics(false),
+		notifier:        newNotifier(),
+		tracer:          newTracer(),
+		standby:         newStandbyCache(),
+	}
+	if operator.l1ChainInfo == nil || operator.layer2ChainInfo == nil {
+		t.Fatalf("chain info is not found")
+	}
+	operator.configValue.Store(&config.ServiceConfig{})
+	operator.setLeading(true)
+	return operator
+}
+
+func commitJob(t *testing.T, height uint32, deposits ...uint64) *Job {
+	msg := &Layer2CommitMsg{
+		Layer2State: &layer2_sdk_common.Layer2State{Height: height, StatesRoot: layer2_common.Uint256{byte(height)}},
+		Deposits:    deposits,
+		WithDraws:   make([]*Withdraw, 0),
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Job{Kind: JOB_COMMIT, Key: uint64(height), Payload: string(payload)}
+}
+
+func saveTestDeposit(t *testing.T, deposit *Deposit) {
+	saved, err := SaveDeposit(deposit)
+	if err != nil || !saved {
+		t.Fatalf("save deposit %d: %v", deposit.ID, err)
+	}
+	if deposit.Layer2TxHash != "" {
+		err = UpdateDepositByID(deposit.ID, deposit.State, deposit.Layer2TxHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunCommitJob(t *testing.T) {
+	l1 := newMockL1Backend()
+	operator := newTestOperator(t, l1)
+	saveTestDeposit(t, &Deposit{TxHash: "deposit", ID: 1, State: DEPOSIT_FINISH})
+
+	err := operator.runCommitJob(commitJob(t, 5, 1))
+	if err != nil {
+		t.Fatalf("commit err: %v", err)
+	}
+	if len(l1.commits) != 1 || l1.commits[0][0] != bridge.METHOD_UPDATE_STATE {
+		t.Fatalf("commits submitted: %v", l1.commits)
+	}
+	if height := l1.commits[0][1].([]interface{})[1]; height != uint32(5) {
+		t.Errorf("commit height %v, want 5", height)
+	}
+	if deposit := LoadDepositByID(1); deposit.State != DEPOSIT_NOTIFY {
+		t.Errorf("deposit state %d, want %d", deposit.State, DEPOSIT_NOTIFY)
+	}
+	if unconfirmed := LoadLayer2Commit_Unconfirmed(); len(unconfirmed) != 1 || unconfirmed[0] != "commit-1" {
+		t.Errorf("unconfirmed commits %v, want commit-1", unconfirmed)
+	}
+
+	// the state committed before exit is recorded as finished instead of sent again
+	l1.stateRoots[6] = &bridge.StateRoot{StateRootHash: "root", Height: 6}
+	err = operator.runCommitJob(commitJob(t, 6))
+	if err != nil {
+		t.Fatalf("commit err: %v", err)
+	}
+	if len(l1.commits) != 1 {
+		t.Errorf("state committed before exit is sent again")
+	}
+	if height := GetLayer2CommitHeight(); height != 6 {
+		t.Errorf("finished commit height %d, want 6", height)
+	}
+
+	// the commit is deferred while the gas balance can not cover the fee
+	l1.gasBalance = 1
+	err = operator.runCommitJob(commitJob(t, 7))
+	if _, ok := err.(*waitError); !ok {
+		t.Fatalf("commit err %v, want wait error", err)
+	}
+	if len(l1.commits) != 1 {
+		t.Errorf("commit is sent without gas")
+	}
+}
+
+func TestRunCommitBatch(t *testing.T) {
+	l1 := newMockL1Backend()
+	operator := newTestOperator(t, l1)
+	l1.commitHeight = 3
+
+	jobs := []*Job{commitJob(t, 3), commitJob(t, 4), commitJob(t, 5)}
+	err := operator.runCommitBatch(jobs)
+	if err != nil {
+		t.Fatalf("commit err: %v", err)
+	}
+	// height 3 is on L1 already, 4 and 5 are merged into the commit of 5
+	if len(l1.commits) != 1 {
+		t.Fatalf("%d commits submitted, want 1", len(l1.commits))
+	}
+	if height := l1.commits[0][1].([]interface{})[1]; height != uint32(5) {
+		t.Errorf("commit height %v, want 5", height)
+	}
+	if height := GetLayer2CommitHeight(); height != 3 {
+		t.Errorf("finished commit height %d, want 3", height)
+	}
+}
+
+func TestCheckLayer2State(t *testing.T) {
+	l1 := newMockL1Backend()
+	operator := newTestOperator(t, l1)
+	for height := uint32(1); height <= 3; height ++ {
+		err := operator.runCommitJob(commitJob(t, height))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	operator.layer2ChainInfo.Height = 3
+	l1.results["commit-1"] = &L1CommitResult{Height: 10, Success: true, GasPrice: 500, GasUsed: 20, Fee: 10000}
+	l1.results["commit-2"] = &L1CommitResult{Height: 11, Success: false}
+	l1.results["commit-3"] = &L1CommitResult{Height: 11, Success: true}
+
+	operator.checkLayer2State()
+	if unconfirmed := LoadLayer2Commit_Unconfirmed(); len(unconfirmed) != 0 {
+		t.Errorf("commits %v are not confirmed", unconfirmed)
+	}
+	finished := LoadRecentLayer2Commits(LAYER2MSG_FINISH, 10)
+	if len(finished) != 2 || finished[1].TxHash != "commit-1" || finished[1].Fee != 10000 {
+		t.Errorf("finished commits %v", finished)
+	}
+	failed := LoadRecentLayer2Commits(LAYER2MSG_FAILED, 10)
+	if len(failed) != 1 || failed[0].TxHash != "commit-2" {
+		t.Errorf("failed commits %v", failed)
+	}
+	// the failed commit rewinds the parser to the commit finished before it
+	if operator.layer2ChainInfo.Height != 1 || !operator.needCheck {
+		t.Errorf("parser at %d is not rewound to the finished commit 1", operator.layer2ChainInfo.Height)
+	}
+}
+
+func TestCheckL1Reorg(t *testing.T) {
+	l1 := newMockL1Backend()
+	operator := newTestOperator(t, l1)
+	for height := uint32(1); height <= 5; height ++ {
+		hash := fmt.Sprintf("hash-%d", height)
+		l1.hashes[height] = hash
+		err := SaveOntologyBlock(height, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveTestDeposit(t, &Deposit{TxHash: "deposit-3", ID: 1, Height: 3, State: DEPOSIT_EVENT})
+	saveTestDeposit(t, &Deposit{TxHash: "deposit-4", ID: 2, Height: 4, State: DEPOSIT_EVENT})
+	saveTestDeposit(t, &Deposit{TxHash: "deposit-5", ID: 3, Height: 5, State: DEPOSIT_COMMIT, Layer2TxHash: "mint"})
+	operator.l1ChainInfo.Height = 5
+	operator.standby.putL1(5, &L1Block{Height: 5, Hash: "hash-5"})
+
+	// nothing is rewound while the parsed blocks are on the chain
+	l1.height = 5
+	operator.checkL1Reorg(l1.height)
+	if operator.l1ChainInfo.Height != 5 {
+		t.Fatalf("parser is rewound to %d without reorg", operator.l1ChainInfo.Height)
+	}
+
+	// blocks 4 and 5 are replaced
+	l1.hashes[4] = "hash-4b"
+	l1.hashes[5] = "hash-5b"
+	operator.checkL1Reorg(l1.height)
+	if operator.l1ChainInfo.Height != 3 {
+		t.Fatalf("parser is rewound to %d, want 3", operator.l1ChainInfo.Height)
+	}
+	if chain := LoadChainInfo(l1.Name()); chain.Height != 3 {
+		t.Errorf("saved parse height %d, want 3", chain.Height)
+	}
+	if LoadOntologyBlockHash(4) != "" || LoadOntologyBlockHash(3) != "hash-3" {
+		t.Errorf("parsed blocks above 3 are not deleted")
+	}
+	if deposit := LoadDepositByID(1); deposit.State != DEPOSIT_EVENT {
+		t.Errorf("deposit below the reorg is changed to %d", deposit.State)
+	}
+	if deposit := LoadDepositByID(2); deposit.State != DEPOSIT_REORGED {
+		t.Errorf("deposit not minted is %d, want reorged", deposit.State)
+	}
+	var quarantined int
+	err := DefDB.QueryRow("select count(*) from depositquarantine where id = ?", 3).Scan(&quarantined)
+	if err != nil || quarantined != 1 {
+		t.Errorf("minted deposit is not quarantined: %d, %v", quarantined, err)
+	}
+	if operator.standby.takeL1(5) != nil {
+		t.Errorf("block fetched when standby is kept after reorg")
+	}
+}
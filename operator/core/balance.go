@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+//checkGasBalance alert the gas balance of the operator account on L1 below min
+func (this *Layer2Operator) checkGasBalance(min uint64) {
+	balance, err := this.l1.GasBalance()
+	if err != nil {
+		log.Errorf("notify - get %s gas balance err: %v", this.l1.Name(), err)
+		return
+	}
+	this.metrics.gasBalance.Update(int64(balance))
+	if balance < min {
+		this.notify(ALERT_LOW_GAS_BALANCE, this.l1.Name(), "%s gas balance of the operator %d is below %d", this.l1.Name(), balance, min)
+	}
+}
+
+//checkLayer2Balance alert the ONG balance of the operator account on layer2 below min
+func (this *Layer2Operator) checkLayer2Balance(min uint64) {
+	if this.layer2Account == nil {
+		return
+	}
+	address := this.layer2Account.Address
+	balance, err := this.layer2Sdk().Native.Ong.BalanceOf(address)
+	if err != nil {
+		log.Errorf("notify - get layer2 balance err: %v", err)
+		return
+	}
+	this.metrics.layer2Balance.Update(int64(balance))
+	if balance < min {
+		this.notify(ALERT_LOW_LAYER2_BALANCE, address.ToBase58(), "layer2 balance of the operator %s %d is below %d", address.ToBase58(), balance, min)
+	}
+}
+
+//commitPayerBalance return the gas balance of the payer of the commit, which is the multisig address if configured
+func (this *Layer2Operator) commitPayerBalance() (uint64, error) {
+	if this.multiSig != nil {
+		return this.l1.(*ontologyBackend).sdk().Native.Ong.BalanceOf(this.multiSig.address)
+	}
+	return this.l1.GasBalance()
+}
+
+//checkCommitFunds return the wait error if the gas balance of the payer can not cover the estimated fee of the commit,
+//so the commit is deferred until the payer is funded instead of failing its attempts. The commit is not deferred if
+//the balance or the fee is unknown, it fails by itself if the payer is short of gas
+func (this *Layer2Operator) checkCommitFunds(msg *Layer2CommitMsg, params []interface{}) error {
+	balance, err := this.commitPayerBalance()
+	if err != nil {
+		log.Warnf("get %s gas balance of the commit payer err: %v", this.l1.Name(), err)
+		return nil
+	}
+	fee, err := this.l1.CommitFee(params)
+	if err != nil {
+		log.Warnf("estimate %s commit fee err: %v", this.l1.Name(), err)
+		return nil
+	}
+	if balance >= fee {
+		return nil
+	}
+	this.metrics.commitDeferred.Inc(1)
+	this.notify(ALERT_LOW_GAS_BALANCE, this.l1.Name(), "%s gas balance of the commit payer %d can not cover the estimated fee %d",
+		this.l1.Name(), balance, fee)
+	return &waitError{fmt.Errorf("commit of layer2 state of height %d is deferred, %s gas balance %d can not cover the estimated fee %d",
+		msg.Layer2State.Height, this.l1.Name(), balance, fee)}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	layer2_sdk_common "github.com/ontio/layer2/go-sdk/common"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"time"
+)
+
+//challengeLoop compare the layer2 states committed to L1 with the signed layer2 states of the layer2 node, which
+//executes every layer2 block itself, and challenge the commit whose state root differs. A commit is only challenged
+//in the challenge period of the contract, after which its withdraws are paid
+func (this *Layer2Operator) challengeLoop() {
+	log.Infof("start challengeLoop")
+	interval := config.CHALLENGE_CHECK_INTERVAL
+	if this.config().ChallengerConfig.Interval > 0 {
+		interval = time.Duration(this.config().ChallengerConfig.Interval) * time.Second
+	}
+	checked := this.config().ChallengerConfig.StartHeight
+	if checked == 0 {
+		committed, err := this.getCommittedHeight()
+		if err != nil {
+			log.Errorf("challenger - get committed height err: %v", err)
+		}
+		checked = committed
+	}
+	for true {
+		select {
+		case <- this.ctx.Done():
+			log.Infof("challengeLoop exit")
+			return
+		case <- time.After(interval):
+		}
+		height, err := this.checkCommittedStates(checked)
+		if err != nil {
+			log.Errorf("challenger - check committed states after %d err: %v", checked, err)
+		}
+		checked = height
+	}
+}
+
+//checkCommittedStates check the commits above height up to the L1 committed height, and return the last height
+//checked. The heights of an aggregated commit without state root on L1 are skipped
+func (this *Layer2Operator) checkCommittedStates(height uint32) (uint32, error) {
+	committed, err := this.getCommittedHeight()
+	if err != nil {
+		return height, err
+	}
+	if committed < height {
+		// the commits above are reverted, check them again when committed
+		return committed, nil
+	}
+	for height < committed {
+		params, err := this.bridge.GetStateRootByHeightParams(uint64(height + 1))
+		if err != nil {
+			return height, err
+		}
+		result, err := this.l1.Call(params)
+		if err != nil {
+			return height, err
+		}
+		stateRoot, err := this.bridge.ParseStateRoot(result)
+		if err == nil && stateRoot.Height == uint64(height + 1) {
+			err = this.checkCommittedState(stateRoot)
+			if err != nil {
+				return height, err
+			}
+		}
+		height ++
+	}
+	return height, nil
+}
+
+//checkCommittedState challenge the commit of stateRoot if the state root differs from the signed one of the layer2
+//node for the same height
+func (this *Layer2Operator) checkCommittedState(stateRoot *bridge.StateRoot) error {
+	height := uint32(stateRoot.Height)
+	layer2State, _, err := this.layer2Sdk().GetLayer2State(height)
+	if err != nil {
+		return fmt.Errorf("get layer2 state of height %d err: %v", height, err)
+	}
+	if layer2State.StatesRoot.ToHexString() == stateRoot.StateRootHash {
+		return nil
+	}
+	commitLog(this.l1.Name(), height, "").Warnf("challenger - state root %s committed at height %d differs from the layer2 state root %s",
+		stateRoot.StateRootHash, height, layer2State.StatesRoot.ToHexString())
+	this.notify(ALERT_STATE_MISMATCH, fmt.Sprintf("%d", height), "state root %s committed at height %d differs from the layer2 state root %s",
+		stateRoot.StateRootHash, height, layer2State.StatesRoot.ToHexString())
+	txHash, err := this.challengeState(layer2State)
+	if err != nil {
+		return fmt.Errorf("challenge commit of height %d err: %v", height, err)
+	}
+	commitLog(this.l1.Name(), height, txHash).Infof("challenger - challenge commit of height %d, tx: %s", height, txHash)
+	audit(AUDIT_CHALLENGE, AUDIT_BY_OPERATOR, txHash, "challenge commit of height %d by layer2 state root %s", height,
+		layer2State.StatesRoot.ToHexString())
+	return nil
+}
+
+//challengeState send the signed layer2 state as the fraud proof of the commit of the same height, the contract
+//reverts the commit and all the commits after it
+func (this *Layer2Operator) challengeState(layer2State *layer2_sdk_common.Layer2State) (string, error) {
+	sink := layer2_common.NewZeroCopySink(nil)
+	layer2State.Serialization(sink)
+	params, err := this.bridge.ChallengeStateParams(&bridge.ChallengeStateParam{
+		Height:      layer2State.Height,
+		Layer2State: sink.Bytes(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return this.l1.Invoke(params)
+}
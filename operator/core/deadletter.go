@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+//deadLetterJobs move the jobs failed attempts times to the dead letter table, they are not run again until retried by
+//the deadletter command
+func (this *Layer2Operator) deadLetterJobs(jobs []*Job, attempts int, err error) {
+	for _, job := range jobs {
+		log.Errorf("job %d of kind %d, key: %d, failed %d times, move to dead letter. err: %s", job.ID, job.Kind, job.Key, attempts, err.Error())
+		if job.Kind == JOB_COMMIT {
+			this.notify(ALERT_COMMIT_FAILED, fmt.Sprintf("%d", job.Key), "commit job %d failed %d times, err: %s", job.Key, attempts, err.Error())
+		} else if job.Kind == JOB_DEPOSIT {
+			this.notify(ALERT_DEPOSIT_FAILED, fmt.Sprintf("%d", job.Key), "deposit job %d failed %d times, err: %s", job.Key, attempts, err.Error())
+		}
+		letter := &DeadLetter{
+			Kind:     job.Kind,
+			Key:      job.Key,
+			Payload:  job.Payload,
+			Error:    err.Error(),
+			Attempts: attempts,
+			TT:       uint32(time.Now().Unix()),
+		}
+		for attempt := 0; SaveDeadLetter(letter, job.ID) != nil; attempt ++ {
+			log.Errorf("save dead letter of job %d failed, try again......", job.ID)
+			if !this.retry.Wait(attempt) {
+				return
+			}
+		}
+	}
+}
+
+//isJobKindBlocked return true if the jobs of kind are paused or can not run. The commits after a dead commit job would
+//skip the deposits and withdraws of it, so they wait for the dead commit job to be retried
+func (this *Layer2Operator) isJobKindBlocked(kind int) bool {
+	if kind == JOB_COMMIT && HasDeadJob(JOB_COMMIT) {
+		return true
+	}
+	return isPaused(kind)
+}
+
+//RetryDeadLetter set the job of the dead letter pending again and remove the dead letter
+func RetryDeadLetter(id uint64) error {
+	letter := LoadDeadLetter(id)
+	if letter == nil {
+		return fmt.Errorf("dead letter %d is not found", id)
+	}
+	job := LoadJob(letter.Kind, letter.Key)
+	if job == nil {
+		return fmt.Errorf("job of kind %d, key: %d, is not found", letter.Kind, letter.Key)
+	}
+	if job.State != JOB_DEAD {
+		return fmt.Errorf("job %d is not dead, state: %d", job.ID, job.State)
+	}
+	err := RequeueDeadLetter(id, job.ID)
+	if err != nil {
+		return err
+	}
+	audit(AUDIT_DEAD_LETTER, AUDIT_BY_CLI, "", "retry job %d of kind %d, key: %d", job.ID, job.Kind, job.Key)
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ontio/layer2/operator/config"
+)
+
+//Dialect is the SQL of the database the operator persists to. The statements of the operator are written in MySQL,
+//and rewritten by the dialect of the database when they are prepared, so the database layer is shared by the databases
+type Dialect interface {
+	//DriverName return the name of the registered sql driver of the database
+	DriverName() string
+	//DataSource return the data source name of the database of the config
+	DataSource(cfg *config.DBConfig) string
+	//Rewrite return the MySQL statement in the SQL of the database
+	Rewrite(query string) string
+	//Init prepare the connected database, the embedded database creates its tables
+	Init(db *sql.DB) error
+	//TryLockSQL, IsLockedSQL and UnlockSQL return the statements of the advisory lock of the leader election, they
+	//take the lock name and return 1 if succeeded
+	TryLockSQL() string
+	IsLockedSQL() string
+	UnlockSQL() string
+}
+
+// the dialect of the connected database, it is set by ConnectDB
+var DefDialect Dialect = &mysqlDialect{}
+
+//NewDialect return the dialect of the driver of DBConfig, the default driver is mysql
+func NewDialect(driver string) (Dialect, error) {
+	switch driver {
+	case "", config.DB_DRIVER_MYSQL:
+		return &mysqlDialect{}, nil
+	case config.DB_DRIVER_POSTGRES:
+		return &postgresDialect{}, nil
+	case config.DB_DRIVER_SQLITE:
+		return &sqliteDialect{}, nil
+	default:
+		return nil, fmt.Errorf("database driver %s is not supported", driver)
+	}
+}
+
+type mysqlDialect struct {
+}
+
+func (this *mysqlDialect) DriverName() string {
+	return DB_DRIVER_NAME
+}
+
+func (this *mysqlDialect) DataSource(cfg *config.DBConfig) string {
+	return cfg.ProjectDBUser +
+		":" + cfg.ProjectDBPassword +
+		"@tcp(" + cfg.ProjectDBUrl +
+		")/" + cfg.ProjectDBName +
+		"?charset=utf8"
+}
+
+func (this *mysqlDialect) Rewrite(query string) string {
+	return query
+}
+
+func (this *mysqlDialect) Init(db *sql.DB) error {
+	return nil
+}
+
+func (this *mysqlDialect) TryLockSQL() string {
+	return "select GET_LOCK(?, 0)"
+}
+
+func (this *mysqlDialect) IsLockedSQL() string {
+	return "select IS_USED_LOCK(?) = CONNECTION_ID()"
+}
+
+func (this *mysqlDialect) UnlockSQL() string {
+	return "select RELEASE_LOCK(?)"
+}
+
+var (
+	insertIgnore   = regexp.MustCompile(`(?i)^\s*insert\s+ignore\s+into`)
+	insertTable    = regexp.MustCompile(`(?i)^\s*insert\s+into\s+(\w+)`)
+	upsertUpdate   = regexp.MustCompile(`(?i)\s+on\s+duplicate\s+key\s+update\s+(.*)$`)
+	upsertValue    = regexp.MustCompile(`(?i)values\s*\(\s*(\w+)\s*\)`)
+	pgIfNull       = regexp.MustCompile(`(?i)\bifnull\(`)
+)
+
+// the conflict targets of the upserts by table, mysql updates the row of any unique key while postgres and sqlite
+// must be given the key, so a new upsert must add its table here
+var upsertConflictKeys = map[string]string{
+	"deposit":           "id",
+	"depositquarantine": "layer2txhash, id",
+	"job":               "kind, jobkey",
+	"ontologyblock":     "height",
+	"withdrawfee":       "txhash",
+	"pause":             "target",
+}
+
+//rewriteConflicts replace insert ignore and on duplicate key update of MySQL by the on conflict clause shared by
+//postgres and sqlite
+func rewriteConflicts(query string) string {
+	if insertIgnore.MatchString(query) {
+		return insertIgnore.ReplaceAllString(query, "insert into") + " on conflict do nothing"
+	}
+	match := upsertUpdate.FindStringSubmatchIndex(query)
+	if match == nil {
+		return query
+	}
+	table := insertTable.FindStringSubmatch(query)
+	if table == nil {
+		return query
+	}
+	key, ok := upsertConflictKeys[strings.ToLower(table[1])]
+	if !ok {
+		return query
+	}
+	update := upsertValue.ReplaceAllString(query[match[2]:match[3]], "excluded.$1")
+	return fmt.Sprintf("%s on conflict (%s) do update set %s", query[:match[0]], key, update)
+}
+
+//postgresDialect rewrite the MySQL statements of the operator for PostgreSQL, the tables are created by
+//docs/explorer_pg.sql
+type postgresDialect struct {
+	rewritten     sync.Map
+}
+
+func (this *postgresDialect) DriverName() string {
+	return DB_POSTGRES_DRIVER_NAME
+}
+
+func (this *postgresDialect) DataSource(cfg *config.DBConfig) string {
+	sslMode := cfg.ProjectDBSSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dataSource := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.ProjectDBUser, cfg.ProjectDBPassword),
+		Host:     cfg.ProjectDBUrl,
+		Path:     "/" + cfg.ProjectDBName,
+		RawQuery: "sslmode=" + url.QueryEscape(sslMode),
+	}
+	return dataSource.String()
+}
+
+//Rewrite quote the identifiers by double quotes, replace ifnull by coalesce, insert ignore and on duplicate key update
+//by on conflict, and number the placeholders
+func (this *postgresDialect) Rewrite(query string) string {
+	if rewritten, ok := this.rewritten.Load(query); ok {
+		return rewritten.(string)
+	}
+	result := strings.Replace(query, "`", `"`, -1)
+	result = pgIfNull.ReplaceAllString(result, "coalesce(")
+	result = rewriteConflicts(result)
+	var builder strings.Builder
+	placeholder := 0
+	for _, c := range result {
+		if c != '?' {
+			builder.WriteRune(c)
+			continue
+		}
+		placeholder ++
+		builder.WriteString("$" + strconv.Itoa(placeholder))
+	}
+	result = builder.String()
+	this.rewritten.Store(query, result)
+	return result
+}
+
+func (this *postgresDialect) Init(db *sql.DB) error {
+	return nil
+}
+
+// the lock is the advisory lock of the two keys 0 and the hash of the name, which is shown in pg_locks with objsubid 2
+
+func (this *postgresDialect) TryLockSQL() string {
+	return "select case when pg_try_advisory_lock(0, hashtext(?)) then 1 else 0 end"
+}
+
+func (this *postgresDialect) IsLockedSQL() string {
+	return "select count(*) from pg_locks where locktype = 'advisory' and granted and pid = pg_backend_pid() and classid = 0 and objid = hashtext(?)::oid and objsubid = 2"
+}
+
+func (this *postgresDialect) UnlockSQL() string {
+	return "select case when pg_advisory_unlock(0, hashtext(?)) then 1 else 0 end"
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+)
+
+func TestPostgresRewrite(t *testing.T) {
+	cases := []struct {
+		query  string
+		want   string
+	}{
+		{"select 1", "select 1"},
+		{"select ifnull(layer2txhash,''), IFNULL(tokenid, '') from deposit where id = ? and state = ?",
+			"select coalesce(layer2txhash,''), coalesce(tokenid, '') from deposit where id = $1 and state = $2"},
+		{"select `key` from job", `select "key" from job`},
+		{"insert ignore into deposit(id, txhash) values (?,?)", "insert into deposit(id, txhash) values ($1,$2) on conflict do nothing"},
+		{"insert into pause(target, tt) values (?,?) on duplicate key update tt = ?",
+			"insert into pause(target, tt) values ($1,$2) on conflict (target) do update set tt = $3"},
+	}
+	dialect := &postgresDialect{}
+	for _, c := range cases {
+		// the second rewrite is served by the cache
+		for i := 0; i < 2; i ++ {
+			if got := dialect.Rewrite(c.query); got != c.want {
+				t.Errorf("Rewrite(%q)\n got: %q\nwant: %q", c.query, got, c.want)
+			}
+		}
+	}
+}
+
+func TestMysqlRewrite(t *testing.T) {
+	query := "insert ignore into deposit(id) values (?)"
+	if got := (&mysqlDialect{}).Rewrite(query); got != query {
+		t.Errorf("mysql statement is rewritten to %q", got)
+	}
+}
+
+func TestRewriteConflicts(t *testing.T) {
+	cases := []struct {
+		query  string
+		want   string
+	}{
+		{"select id from deposit where id = ?", "select id from deposit where id = ?"},
+		{"insert into deposit(id) values (?)", "insert into deposit(id) values (?)"},
+		{"insert ignore into deposit(id, txhash) values (?,?)", "insert into deposit(id, txhash) values (?,?) on conflict do nothing"},
+		{"INSERT IGNORE INTO chain_info(name) values (?)", "insert into chain_info(name) values (?) on conflict do nothing"},
+		{"insert into pause(target, tt) values (?,?) on duplicate key update tt = ?",
+			"insert into pause(target, tt) values (?,?) on conflict (target) do update set tt = ?"},
+		{"insert into deposit(id, state) values (?,?) ON DUPLICATE KEY UPDATE state=VALUES(state)",
+			"insert into deposit(id, state) values (?,?) on conflict (id) do update set state=excluded.state"},
+		{"insert into job(kind, jobkey, state) values (?,?,?) on duplicate key update state = values(state), tt = values( tt )",
+			"insert into job(kind, jobkey, state) values (?,?,?) on conflict (kind, jobkey) do update set state = excluded.state, tt = excluded.tt"},
+		// the table without a conflict key is left as it is
+		{"insert into token(address) values (?) on duplicate key update name = ?",
+			"insert into token(address) values (?) on duplicate key update name = ?"},
+	}
+	for _, c := range cases {
+		if got := rewriteConflicts(c.query); got != c.want {
+			t.Errorf("rewriteConflicts(%q)\n got: %q\nwant: %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestSqliteRewrite(t *testing.T) {
+	cases := []struct {
+		query  string
+		want   string
+	}{
+		// sqlite has ifnull and the backtick quotes
+		{"select ifnull(layer2txhash,''), `key` from deposit where id = ?", "select ifnull(layer2txhash,''), `key` from deposit where id = ?"},
+		{"insert ignore into deposit(id, txhash) values (?,?)", "insert into deposit(id, txhash) values (?,?) on conflict do nothing"},
+		{"insert into pause(target, tt) values (?,?) on duplicate key update tt = ?",
+			"insert into pause(target, tt) values (?,?) on conflict (target) do update set tt = ?"},
+	}
+	dialect := &sqliteDialect{}
+	for _, c := range cases {
+		if got := dialect.Rewrite(c.query); got != c.want {
+			t.Errorf("Rewrite(%q)\n got: %q\nwant: %q", c.query, got, c.want)
+		}
+	}
+}
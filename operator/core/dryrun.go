@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	DRYRUN_MINT   = "mint"
+	DRYRUN_COMMIT = "commit"
+)
+
+// the transactions are refused in the dry run, like those of the admin api
+var errDryRun = errors.New("no transaction is sent in the dry run")
+
+//dryRunMint is the mint of the deposits the operator would send to layer2
+type dryRunMint struct {
+	Token      string
+	Deposits   []*Deposit
+}
+
+//dryRunCommit is the updateState the operator would send to L1 for the layer2 state of Height
+type dryRunCommit struct {
+	Height     uint32
+	FromHeight uint32
+	StateRoot  string
+	Deposits   []uint64
+	Withdraws  []*Withdraw
+	Params     []interface{}
+}
+
+//dryRun return true if the operator parses both chains without signing or sending any transaction
+func (this *Layer2Operator) dryRun() bool {
+	return this.config().DryRun
+}
+
+//recordDryRun save the action the operator would take in place of the transaction, the error of the database is
+//returned so the job is retried
+func (this *Layer2Operator) recordDryRun(kind string, subject string, detail interface{}) error {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	err = SaveDryRunAction(&DryRunAction{
+		Kind:    kind,
+		Subject: subject,
+		Detail:  string(data),
+		TT:      uint32(time.Now().Unix()),
+	})
+	if err != nil {
+		return fmt.Errorf("save dry run action %s of %s err: %v", kind, subject, err)
+	}
+	log.Infof("dryrun - would %s %s: %s", kind, subject, data)
+	return nil
+}
+
+//dryRunMintDeposits record the mint of the deposits instead of sending it, the deposits are left as they are
+func (this *Layer2Operator) dryRunMintDeposits(tokenAddress string, deposits []*Deposit) error {
+	ids := make([]string, 0, len(deposits))
+	for _, deposit := range deposits {
+		ids = append(ids, fmt.Sprintf("%d", deposit.ID))
+	}
+	return this.recordDryRun(DRYRUN_MINT, "deposit " + strings.Join(ids, ","), &dryRunMint{Token: tokenAddress, Deposits: deposits})
+}
+
+//dryRunCommitState record the updateState of msg instead of sending it, the commit is not saved so the deposits and
+//withdraws of msg are left as they are
+func (this *Layer2Operator) dryRunCommitState(msg *Layer2CommitMsg, params []interface{}) error {
+	return this.recordDryRun(DRYRUN_COMMIT, fmt.Sprintf("height %d", msg.Layer2State.Height), &dryRunCommit{
+		Height:     msg.Layer2State.Height,
+		FromHeight: msg.fromHeight(),
+		StateRoot:  msg.Layer2State.StatesRoot.ToHexString(),
+		Deposits:   msg.Deposits,
+		Withdraws:  msg.WithDraws,
+		Params:     params,
+	})
+}
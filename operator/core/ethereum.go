@@ -0,0 +1,371 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	ethereum_common "github.com/ethereum/go-ethereum/common"
+	ethereum_types "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	ethereum_params "github.com/ethereum/go-ethereum/params"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync/atomic"
+)
+
+//ethereumBackend is the L1Backend of the solidity layer2 contract on ethereum, the params of the bridge are packed by
+//the evm bridge
+type ethereumBackend struct {
+	// the config and the client are replaced on reload
+	configValue atomic.Value
+	clientValue atomic.Value
+	retry     *retryPolicy
+	submit    *submitQueue
+	bridge    *bridge.EVMBridge
+	contract  ethereum_common.Address
+	key       *ecdsa.PrivateKey
+	from      ethereum_common.Address
+	chainId   *big.Int
+}
+
+func newEthereumBackend(cfg *config.EthereumConfig, retry *retryPolicy, submit *submitQueue) (*ethereumBackend, error) {
+	if !ethereum_common.IsHexAddress(cfg.Layer2ContractAddress) {
+		return nil, fmt.Errorf("invalid layer2 contract address %s", cfg.Layer2ContractAddress)
+	}
+	evmBridge, err := bridge.NewEVMBridge()
+	if err != nil {
+		return nil, err
+	}
+	keyJson, err := ioutil.ReadFile(cfg.KeyStoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore file %s err: %v", cfg.KeyStoreFile, err)
+	}
+	key, err := keystore.DecryptKey(keyJson, cfg.KeyStorePwd)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keystore file %s err: %v", cfg.KeyStoreFile, err)
+	}
+	client, err := ethclient.Dial(cfg.RpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("dial ethereum node %s err: %v", cfg.RpcURL, err)
+	}
+	chainId := new(big.Int).SetUint64(cfg.ChainId)
+	if cfg.ChainId == 0 {
+		chainId, err = client.ChainID(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("get ethereum chain id err: %v", err)
+		}
+	}
+	log.Infof("ethereumAccount - eth account address: %s, chain id: %s", key.Address.Hex(), chainId.String())
+	backend := &ethereumBackend{
+		retry:    retry,
+		submit:   submit,
+		bridge:   evmBridge,
+		contract: ethereum_common.HexToAddress(cfg.Layer2ContractAddress),
+		key:      key.PrivateKey,
+		from:     key.Address,
+		chainId:  chainId,
+	}
+	backend.configValue.Store(cfg)
+	backend.clientValue.Store(client)
+	return backend, nil
+}
+
+func (this *ethereumBackend) config() *config.EthereumConfig {
+	return this.configValue.Load().(*config.EthereumConfig)
+}
+
+func (this *ethereumBackend) client() *ethclient.Client {
+	return this.clientValue.Load().(*ethclient.Client)
+}
+
+//Reload replace the config by the reloaded one, the ethereum node of the new RpcURL must be of the same chain id. The
+//old client is not closed, as the calls in flight may still use it
+func (this *ethereumBackend) Reload(servConfig *config.ServiceConfig) error {
+	cfg := servConfig.EthereumConfig
+	if cfg.RpcURL != this.config().RpcURL {
+		client, err := ethclient.Dial(cfg.RpcURL)
+		if err != nil {
+			return fmt.Errorf("dial ethereum node %s err: %v", cfg.RpcURL, err)
+		}
+		chainId, err := client.ChainID(context.Background())
+		if err != nil {
+			client.Close()
+			return fmt.Errorf("get ethereum chain id of %s err: %v", cfg.RpcURL, err)
+		}
+		if chainId.Cmp(this.chainId) != 0 {
+			client.Close()
+			return fmt.Errorf("ethereum node %s is of chain id %s, expect %s", cfg.RpcURL, chainId.String(), this.chainId.String())
+		}
+		this.clientValue.Store(client)
+		log.Infof("reload - ethereum node changed to %s", cfg.RpcURL)
+	}
+	this.configValue.Store(cfg)
+	return nil
+}
+
+func (this *ethereumBackend) Name() string {
+	return "ethereum"
+}
+
+func (this *ethereumBackend) Bridge() bridge.Bridge {
+	return this.bridge
+}
+
+func (this *ethereumBackend) GetHeight() (uint32, error) {
+	header, err := this.client().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(header.Number.Uint64()), nil
+}
+
+func (this *ethereumBackend) BlockHash(height uint32) (string, error) {
+	header, err := this.client().HeaderByNumber(context.Background(), new(big.Int).SetUint64(uint64(height)))
+	if err != nil {
+		return "", err
+	}
+	hash := header.Hash()
+	return hex.EncodeToString(hash[:]), nil
+}
+
+//NewBlocks return nil, the ethereum node is polled
+func (this *ethereumBackend) NewBlocks() <-chan uint32 {
+	return nil
+}
+
+//GetEvents filter the logs of the layer2 contract in the block, the states of the event are the event name and the
+//non-indexed args of the log
+func (this *ethereumBackend) GetEvents(height uint32) (*L1Block, error) {
+	number := new(big.Int).SetUint64(uint64(height))
+	header, err := this.client().HeaderByNumber(context.Background(), number)
+	if err != nil {
+		return nil, err
+	}
+	hash := header.Hash()
+	logs, err := this.client().FilterLogs(context.Background(), ethereum.FilterQuery{
+		BlockHash: &hash,
+		Addresses: []ethereum_common.Address{this.contract},
+	})
+	if err != nil {
+		return nil, err
+	}
+	block := &L1Block{
+		Height:    height,
+		Hash:      hex.EncodeToString(hash[:]),
+		Timestamp: uint32(header.Time),
+		Events:    make([]*L1Event, 0),
+	}
+	// the index of the log among the logs of the contract in its transaction
+	notifyIndexes := make(map[ethereum_common.Hash]uint32)
+	for _, item := range logs {
+		if item.Removed || len(item.Topics) == 0 {
+			continue
+		}
+		states, err := this.bridge.DecodeLog(item.Topics, item.Data)
+		if err != nil {
+			log.Errorf("decode log of tx: %s err: %v", item.TxHash.Hex(), err)
+			continue
+		}
+		if states == nil {
+			continue
+		}
+		notifyIndex := notifyIndexes[item.TxHash]
+		notifyIndexes[item.TxHash] = notifyIndex + 1
+		block.Events = append(block.Events, &L1Event{
+			TxHash:      hex.EncodeToString(item.TxHash[:]),
+			NotifyIndex: notifyIndex,
+			States:      states,
+		})
+	}
+	return block, nil
+}
+
+func (this *ethereumBackend) Call(params []interface{}) (interface{}, error) {
+	name, data, err := this.bridge.Pack(params)
+	if err != nil {
+		return nil, err
+	}
+	output, err := this.client().CallContract(context.Background(), ethereum.CallMsg{
+		From: this.from,
+		To:   &this.contract,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return this.bridge.UnpackOutputs(name, output)
+}
+
+//newTransaction build the transaction signed by the operator, the gas limit is estimated if it is not configured
+func (this *ethereumBackend) newTransaction(params []interface{}, estimate bool) (*ethereum_types.Transaction, error) {
+	_, data, err := this.bridge.Pack(params)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := this.client().PendingNonceAt(context.Background(), this.from)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, gasLimit, err := this.gas(data, estimate)
+	if err != nil {
+		return nil, err
+	}
+	tx := ethereum_types.NewTransaction(nonce, this.contract, big.NewInt(0), gasLimit, gasPrice, data)
+	return ethereum_types.SignTx(tx, ethereum_types.NewEIP155Signer(this.chainId), this.key)
+}
+
+//gas return the gas price and the gas limit of the transaction of data, the gas price is suggested by the node if it
+//is not configured, and the gas limit is estimated if it is not configured or estimate is set
+func (this *ethereumBackend) gas(data []byte, estimate bool) (*big.Int, uint64, error) {
+	var err error
+	gasPrice := new(big.Int).SetUint64(this.config().GasPrice)
+	if this.config().GasPrice == 0 {
+		gasPrice, err = this.client().SuggestGasPrice(context.Background())
+		if err != nil {
+			return nil, 0, err
+		}
+		if this.config().MaxGasPrice > 0 && gasPrice.Cmp(new(big.Int).SetUint64(this.config().MaxGasPrice)) > 0 {
+			gasPrice.SetUint64(this.config().MaxGasPrice)
+		}
+	}
+	gasLimit := this.config().GasLimit
+	if gasLimit == 0 || estimate {
+		estimated, err := this.client().EstimateGas(context.Background(), ethereum.CallMsg{
+			From: this.from,
+			To:   &this.contract,
+			Data: data,
+		})
+		if err != nil && estimate {
+			return nil, 0, fmt.Errorf("transaction is failed in pre-execution: %v", err)
+		} else if err != nil {
+			gasLimit = config.DEFAULT_COMMIT_GAS_LIMIT
+		} else if gasLimit == 0 {
+			gasLimit = estimated
+		}
+	}
+	return gasPrice, gasLimit, nil
+}
+
+//Invoke send the transaction by the submission queue, so the pending nonce is taken after the last transaction of the
+//operator is accepted by the node
+func (this *ethereumBackend) Invoke(params []interface{}) (string, error) {
+	return this.submit.Submit(this.from.Hex(), func() (string, error) {
+		tx, err := this.newTransaction(params, true)
+		if err != nil {
+			return "", err
+		}
+		err = this.client().SendTransaction(context.Background(), tx)
+		if err != nil {
+			return "", err
+		}
+		hash := tx.Hash()
+		return hex.EncodeToString(hash[:]), nil
+	})
+}
+
+func (this *ethereumBackend) SubmitStateCommit(params []interface{}) (string, error) {
+	return this.submit.Submit(this.from.Hex(), func() (string, error) {
+		return this.sendCommit(params)
+	})
+}
+
+func (this *ethereumBackend) sendCommit(params []interface{}) (string, error) {
+	tx, err := this.newTransaction(params, false)
+	if err != nil {
+		return "", fmt.Errorf("new layer2 state commit transaction failed! err: %s", err.Error())
+	}
+	for attempt := 0; ; attempt ++ {
+		err = this.client().SendTransaction(context.Background(), tx)
+		// the transaction sent before the error is known by the node
+		if err == nil || strings.Contains(err.Error(), "known") {
+			break
+		}
+		if this.retry.Exhausted(attempt + 1) {
+			return "", fmt.Errorf("send layer2 state commit transaction failed %d times! err: %s", attempt + 1, err.Error())
+		}
+		log.Errorf("send layer2 state commit transaction failed! err: %s, try again......", err.Error())
+		if !this.retry.Wait(attempt) {
+			return "", errShutdown
+		}
+	}
+	hash := tx.Hash()
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (this *ethereumBackend) CheckCommit(txHash string) (*L1CommitResult, error) {
+	receipt, err := this.client().TransactionReceipt(context.Background(), ethereum_common.HexToHash(txHash))
+	if err == ethereum.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx, _, err := this.client().TransactionByHash(context.Background(), ethereum_common.HexToHash(txHash))
+	if err != nil {
+		return nil, err
+	}
+	return &L1CommitResult{
+		Height:   uint32(receipt.BlockNumber.Uint64()),
+		Success:  receipt.Status == ethereum_types.ReceiptStatusSuccessful,
+		GasPrice: tx.GasPrice().Uint64(),
+		GasUsed:  receipt.GasUsed,
+		Fee:      new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed)).Uint64(),
+	}, nil
+}
+
+func (this *ethereumBackend) GasBalance() (uint64, error) {
+	balance, err := this.client().BalanceAt(context.Background(), this.from, nil)
+	if err != nil {
+		return 0, err
+	}
+	return new(big.Int).Div(balance, big.NewInt(ethereum_params.GWei)).Uint64(), nil
+}
+
+//CommitFee return the fee in gwei, which is rounded up
+func (this *ethereumBackend) CommitFee(params []interface{}) (uint64, error) {
+	_, data, err := this.bridge.Pack(params)
+	if err != nil {
+		return 0, err
+	}
+	gasPrice, gasLimit, err := this.gas(data, false)
+	if err != nil {
+		return 0, err
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+	fee.Add(fee, big.NewInt(ethereum_params.GWei - 1))
+	return fee.Div(fee, big.NewInt(ethereum_params.GWei)).Uint64(), nil
+}
+
+func (this *ethereumBackend) IsTxLost(txHash string) bool {
+	if _, err := this.GetHeight(); err != nil {
+		return false
+	}
+	_, _, err := this.client().TransactionByHash(context.Background(), ethereum_common.HexToHash(txHash))
+	return err == ethereum.NotFound
+}
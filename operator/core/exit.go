@@ -0,0 +1,204 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"time"
+)
+
+//exitLoop freeze the layer2 accounts of the exits requested on L1, and challenge the exit whose balance in the state
+//root committed after the freeze is lower than its amount, as the balance may be spent before the freeze
+func (this *Layer2Operator) exitLoop() {
+	log.Infof("start exitLoop")
+	for true {
+		select {
+		case <- this.ctx.Done():
+			log.Infof("exitLoop exit")
+			return
+		case <- time.After(config.EXIT_CHECK_INTERVAL):
+		}
+		if !this.isLeading() {
+			continue
+		}
+		for _, exit := range LoadExitsByState(EXIT_EVENT) {
+			txHash, err := this.freezeExitAccount(exit)
+			if err != nil {
+				log.Errorf("exit - freeze account of exit %d err: %v", exit.ID, err)
+				continue
+			}
+			err = UpdateExitByID(exit.ID, EXIT_FROZEN, txHash)
+			if err != nil {
+				log.Errorf("exit - update exit %d err: %v", exit.ID, err)
+			}
+		}
+		for _, exit := range LoadExitsByState(EXIT_FROZEN) {
+			checked, err := this.checkExit(exit)
+			if err != nil {
+				log.Errorf("exit - check exit %d err: %v", exit.ID, err)
+				continue
+			}
+			if !checked {
+				continue
+			}
+			err = UpdateExitByID(exit.ID, EXIT_CHECKED, exit.Layer2TxHash)
+			if err != nil {
+				log.Errorf("exit - update exit %d err: %v", exit.ID, err)
+			}
+		}
+	}
+}
+
+//freezeExitAccount freeze the ONT and ONG of the player of the exit on layer2 and return the hash of the freeze. The
+//other tokens can not be frozen, their exits are only checked against the next commit
+func (this *Layer2Operator) freezeExitAccount(exit *Exit) (string, error) {
+	if !isNativeToken(exit.TokenAddress) {
+		return "", nil
+	}
+	player, err := layer2_common.AddressFromBase58(exit.Player)
+	if err != nil {
+		return "", err
+	}
+	// the base fee of the node does not change with the latest blocks, so the freeze built again has the same hash
+	fee, err := this.layer2Sdk().BaseFee(1)
+	if err != nil {
+		return "", err
+	}
+	tx, err := this.layer2Sdk().Native.NewNativeInvokeTransaction(fee.GasPrice, fee.GasLimit, layer2_sdk.ONT_CONTRACT_VERSION,
+		layer2_sdk.ONT_CONTRACT_ADDRESS, "freeze", []interface{}{player[:]})
+	if err != nil {
+		return "", err
+	}
+	// the nonce is fixed by the exit id, so the freeze sent again after restart has the same hash
+	tx.Nonce = uint32(exit.ID)
+	this.layer2Sdk().SetPayer(tx, this.layer2Account.Address)
+	err = this.layer2Sdk().SignToTransaction(tx, this.layer2Account)
+	if err != nil {
+		return "", err
+	}
+	txHash := tx.Hash()
+	if _, err := this.layer2Sdk().GetBlockHeightByTxHash(txHash.ToHexString()); err == nil {
+		return txHash.ToHexString(), nil
+	}
+	_, err = this.layer2Sdk().SendTransaction(tx)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("exit - freeze %s for exit %d, tx hash: %s", exit.Player, exit.ID, txHash.ToHexString())
+	audit(AUDIT_FREEZE, AUDIT_BY_OPERATOR, txHash.ToHexString(), "freeze %s for exit %d", exit.Player, exit.ID)
+	return txHash.ToHexString(), nil
+}
+
+//checkExit compare the amount of the exit with the balance of its player in the state root committed after the freeze,
+//and challenge the exit if the balance is lower. It returns false if the freeze is not committed yet
+func (this *Layer2Operator) checkExit(exit *Exit) (bool, error) {
+	token := this.tokens.get(exit.TokenAddress)
+	if token == nil {
+		return false, fmt.Errorf("token %s is not bridged", exit.TokenAddress)
+	}
+	layer2Asset, err := hex.DecodeString(token.Layer2Address)
+	if err != nil {
+		return false, err
+	}
+	contract, err := layer2_common.AddressParseFromBytes(layer2Asset)
+	if err != nil {
+		return false, err
+	}
+	player, err := layer2_common.AddressFromBase58(exit.Player)
+	if err != nil {
+		return false, err
+	}
+	frozenHeight := exit.Layer2Height + 1
+	if exit.Layer2TxHash != "" {
+		frozenHeight, err = this.layer2Sdk().GetBlockHeightByTxHash(exit.Layer2TxHash)
+		if err != nil {
+			return false, nil
+		}
+	}
+	committed, err := this.getCommittedHeight()
+	if err != nil {
+		return false, err
+	}
+	if committed < frozenHeight {
+		return false, nil
+	}
+	layer2State, _, err := this.layer2Sdk().GetLayer2State(committed)
+	if err != nil {
+		return false, err
+	}
+	key := append(append([]byte{}, layer2Asset...), player[:]...)
+	proof, err := this.getLayer2StateProof(committed, key)
+	if err != nil {
+		return false, err
+	}
+	data := bridge.EncodeWithdrawProof(key, proof)
+	if !bridge.VerifyWithdrawProof([32]byte(layer2State.StatesRoot), key, data) {
+		return false, fmt.Errorf("proof of key %x does not match the layer2 state root of height %d", key, committed)
+	}
+	// the proof is of the committed height and the storage is the latest, they match if the account is not changed since
+	payload, err := this.layer2Sdk().GetStorage(contract.ToHexString(), player[:])
+	if err != nil {
+		return false, err
+	}
+	value := []byte{}
+	balance := uint64(0)
+	if data[0] == bridge.PROOF_LEAF {
+		sink := layer2_common.NewZeroCopySink(nil)
+		sink.WriteByte(0)
+		sink.WriteVarBytes(payload)
+		value = sink.Bytes()
+		valueHash := sha256.Sum256(value)
+		if !bytes.Equal(valueHash[:], data[1:33]) {
+			log.Warnf("exit - balance of %s is changed since height %d, check exit %d later", exit.Player, committed, exit.ID)
+			return false, nil
+		}
+		balance = layer2_common.BigIntFromNeoBytes(payload).Uint64()
+	}
+	if balance >= exit.Amount {
+		return true, nil
+	}
+	params, err := this.bridge.ChallengeExitParams(&bridge.ChallengeExitParam{
+		ExitId: exit.ID,
+		Height: committed,
+		Value:  value,
+		Proof:  data,
+	})
+	if err != nil {
+		return false, err
+	}
+	txHash, err := this.l1.Invoke(params)
+	if err != nil {
+		return false, err
+	}
+	log.Infof("exit - challenge exit %d of amount %d by balance %d at height %d, tx: %s", exit.ID, exit.Amount, balance,
+		committed, txHash)
+	audit(AUDIT_CHALLENGE, AUDIT_BY_OPERATOR, txHash, "challenge exit %d of amount %d by balance %d at height %d", exit.ID,
+		exit.Amount, balance, committed)
+	return true, nil
+}
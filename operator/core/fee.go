@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"github.com/ontio/layer2/operator/log"
+)
+
+const WITHDRAW_FEE_RATE_BASE = 10000
+
+//withdrawFee return the fee deducted from the withdraw by the configured fee of its token, the non-fungible withdraw
+//has no fee. The fee is capped so that the withdraw committed to L1 keeps at least 1 unit
+func (this *Layer2Operator) withdrawFee(withdraw *Withdraw) uint64 {
+	if withdraw.TokenId != "" || withdraw.Amount == 0 {
+		return 0
+	}
+	for _, feeConfig := range this.config().WithdrawFees {
+		if feeConfig.Address != withdraw.TokenAddress {
+			continue
+		}
+		amount := withdraw.Amount
+		// split the amount so that the rate does not overflow
+		fee := feeConfig.Flat + amount / WITHDRAW_FEE_RATE_BASE * feeConfig.Rate + amount % WITHDRAW_FEE_RATE_BASE * feeConfig.Rate / WITHDRAW_FEE_RATE_BASE
+		if fee < feeConfig.Flat || fee >= amount {
+			fee = amount - 1
+		}
+		return fee
+	}
+	return 0
+}
+
+//saveWithdrawFees record the fees of the withdraws of the commit msg of height
+func (this *Layer2Operator) saveWithdrawFees(msg *Layer2CommitMsg) {
+	for _, withdraw := range msg.WithDraws {
+		fee := this.withdrawFee(withdraw)
+		if fee == 0 {
+			continue
+		}
+		err := SaveWithdrawFee(&WithdrawFee{
+			TxHash: withdraw.TxHash,
+			TokenAddress: withdraw.TokenAddress,
+			Amount: withdraw.Amount,
+			Fee: fee,
+			Height: msg.Layer2State.Height,
+		})
+		if err != nil {
+			log.Errorf("save fee of withdraw %s err: %v", withdraw.TxHash, err)
+		}
+	}
+}
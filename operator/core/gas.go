@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"github.com/ontio/layer2/operator/config"
+)
+
+//gasPricer is the gas price strategy of the commit transactions, the initial gas price is the configured gas price or
+//the gas price of the network, and it is raised by bump percent on every retry until max
+type gasPricer struct {
+	price    uint64
+	max      uint64
+	bump     uint64
+}
+
+func newGasPricer(price uint64, max uint64, bump uint64) *gasPricer {
+	if bump == 0 {
+		bump = config.DEFAULT_GAS_PRICE_BUMP
+	}
+	if max > 0 && price > max {
+		price = max
+	}
+	return &gasPricer{
+		price: price,
+		max:   max,
+		bump:  bump,
+	}
+}
+
+//Price return the current gas price
+func (this *gasPricer) Price() uint64 {
+	return this.price
+}
+
+//Bump raise the gas price for the retry, return false if the gas price reaches the max already
+func (this *gasPricer) Bump() bool {
+	if this.price >= this.max {
+		return false
+	}
+	price := this.price + this.price * this.bump / 100
+	if price == this.price {
+		price ++
+	}
+	if price > this.max {
+		price = this.max
+	}
+	this.price = price
+	return true
+}
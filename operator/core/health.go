@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	HEALTH_LIVE_PATH  = "/healthz"
+	HEALTH_READY_PATH = "/readyz"
+
+	HEALTH_OK = "ok"
+)
+
+//HealthResponse is the result of every check, which is ok or the error of the check
+type HealthResponse struct {
+	Status          string
+	Checks          map[string]string
+}
+
+//healthCheck is a check of the operator, reachable is false if the check fails because a node is not reachable,
+//which does not fail the liveness
+type healthCheck struct {
+	name      string
+	check     func() (reachable bool, err error)
+}
+
+//startHealthServer serve the liveness and readiness of the operator
+func (this *Layer2Operator) startHealthServer() error {
+	healthConfig := this.config().HealthConfig
+	if healthConfig == nil || healthConfig.ListenAddr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", healthConfig.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("health listen %s error: %s", healthConfig.ListenAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(HEALTH_LIVE_PATH, this.handleHealth(true))
+	mux.HandleFunc(HEALTH_READY_PATH, this.handleHealth(false))
+	this.healthServer = &http.Server{Handler: mux}
+	go this.healthServer.Serve(listener)
+	log.Infof("health - server started at %s", healthConfig.ListenAddr)
+	return nil
+}
+
+//handleHealth run the checks, the liveness ignores the checks failed by an unreachable node, as restarting the
+//operator does not help
+func (this *Layer2Operator) handleHealth(live bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &HealthResponse{
+			Status: HEALTH_OK,
+			Checks: make(map[string]string),
+		}
+		if !live && this.stopping() {
+			resp.Status = "fail"
+			resp.Checks["shutdown"] = errShutdown.Error()
+		}
+		for _, check := range this.healthChecks() {
+			reachable, err := runHealthCheck(check)
+			if err == nil {
+				resp.Checks[check.name] = HEALTH_OK
+				continue
+			}
+			resp.Checks[check.name] = err.Error()
+			if !live || reachable {
+				resp.Status = "fail"
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != HEALTH_OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+//runHealthCheck run the check in HEALTH_CHECK_TIMEOUT, a check timed out is of an unreachable node
+func runHealthCheck(check *healthCheck) (bool, error) {
+	type result struct {
+		reachable bool
+		err       error
+	}
+	done := make(chan *result, 1)
+	go func() {
+		reachable, err := check.check()
+		done <- &result{reachable, err}
+	}()
+	select {
+	case res := <- done:
+		return res.reachable, res.err
+	case <- time.After(config.HEALTH_CHECK_TIMEOUT):
+		return false, fmt.Errorf("check timeout")
+	}
+}
+
+func (this *Layer2Operator) healthChecks() []*healthCheck {
+	return []*healthCheck{
+		{name: "db", check: func() (bool, error) {
+			return true, DefDB.Ping()
+		}},
+		{name: "wallet", check: func() (bool, error) {
+			if this.layer2Account == nil {
+				return true, fmt.Errorf("layer2 wallet is not unlocked")
+			}
+			if !this.config().IsEthereum() && this.ontologyAccount == nil {
+				return true, fmt.Errorf("ontology wallet is not unlocked")
+			}
+			return true, nil
+		}},
+		{name: this.l1.Name(), check: func() (bool, error) {
+			height, err := this.l1.GetHeight()
+			if err != nil {
+				return false, err
+			}
+			if !this.isLeading() {
+				return true, nil
+			}
+			lag := int64(this.l1ConfirmedHeight(height)) - int64(this.l1ChainInfo.Height)
+			if lag > int64(this.config().HealthConfig.MaxL1LagOrDefault()) {
+				return true, fmt.Errorf("parse height %d lags %d blocks behind the confirmed height", this.l1ChainInfo.Height, lag)
+			}
+			return true, nil
+		}},
+		{name: "layer2", check: func() (bool, error) {
+			height, err := this.layer2Sdk().GetCurrentBlockHeight()
+			if err != nil {
+				return false, err
+			}
+			// the layer2 is not parsed ahead of the commits, which are stopped by the pause or the dead commit job
+			if !this.isLeading() || this.isJobKindBlocked(JOB_COMMIT) {
+				return true, nil
+			}
+			lag := int64(height) - 1 - int64(this.layer2ChainInfo.Height)
+			if lag > int64(this.config().HealthConfig.MaxLayer2LagOrDefault()) {
+				return true, fmt.Errorf("parse height %d lags %d blocks behind the layer2 height", this.layer2ChainInfo.Height, lag)
+			}
+			return true, nil
+		}},
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/log"
+)
+
+//latestKeyRotation return the latest key rotation not cancelled, nil if there is none
+func latestKeyRotation() (*KeyRotation, error) {
+	rotations := LoadKeyRotations()
+	if rotations == nil {
+		return nil, fmt.Errorf("load key rotations failed")
+	}
+	for i := len(rotations) - 1; i >= 0; i-- {
+		if rotations[i].State != KEY_ROTATION_CANCELLED {
+			return rotations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+//applyKeyRotation sign the L1 transactions by the next ontology key too if it is configured, so the operator keeps
+//committing in the transition period of the rotation. Once the latest rotation is switched, the next keys are the
+//ontology account and the layer2 payer
+func (this *Layer2Operator) applyKeyRotation() error {
+	rotation, err := latestKeyRotation()
+	if err != nil {
+		return err
+	}
+	switched := rotation != nil && rotation.State == KEY_ROTATION_SWITCHED
+	if !this.config().IsEthereum() && this.config().OntologyConfig != nil {
+		nextAccount := this.config().OntologyConfig.NextAccount
+		if nextAccount == nil {
+			if rotation != nil && rotation.State == KEY_ROTATION_REGISTERED {
+				return fmt.Errorf("key rotation %d is registered, NextAccount of OntologyConfig is required to sign by the new key", rotation.ID)
+			}
+			if switched && this.ontologyAccount != nil && this.ontologyAccount.Address.ToBase58() == rotation.OntologyAddress {
+				return fmt.Errorf("ontology key %s is rotated to %s", rotation.OntologyAddress, rotation.NextOntologyAddress)
+			}
+		} else {
+			backend, ok := this.l1.(*ontologyBackend)
+			if !ok {
+				return fmt.Errorf("key rotation is only supported by the ontology L1")
+			}
+			if this.config().MultiSigConfig != nil {
+				return fmt.Errorf("key rotation is not supported by the multisig operators")
+			}
+			next, err := this.getOntologyAccount(nextAccount)
+			if err != nil {
+				return err
+			}
+			if switched && backend.account.Address.ToBase58() == rotation.OntologyAddress {
+				if next.Address.ToBase58() != rotation.NextOntologyAddress {
+					return fmt.Errorf("ontology key %s is rotated to %s, not the next account %s", rotation.OntologyAddress,
+						rotation.NextOntologyAddress, next.Address.ToBase58())
+				}
+				backend.account = next
+				this.ontologyAccount = next
+				log.Infof("key rotation %d is switched, ontology account: %s", rotation.ID, next.Address.ToBase58())
+			} else if next.Address != backend.account.Address {
+				backend.cosigner = next
+				log.Infof("ontology transactions are signed by the next key %s too", next.Address.ToBase58())
+			}
+		}
+	}
+	if switched && this.layer2Account.Address.ToBase58() == rotation.Layer2Address {
+		nextAccount := this.config().Layer2Config.NextAccount
+		if nextAccount == nil {
+			return fmt.Errorf("layer2 key %s is rotated to %s", rotation.Layer2Address, rotation.NextLayer2Address)
+		}
+		next, err := this.getLyer2Account(nextAccount)
+		if err != nil {
+			return err
+		}
+		if next.Address.ToBase58() != rotation.NextLayer2Address {
+			return fmt.Errorf("layer2 key %s is rotated to %s, not the next account %s", rotation.Layer2Address,
+				rotation.NextLayer2Address, next.Address.ToBase58())
+		}
+		this.layer2Account = next
+		log.Infof("key rotation %d is switched, layer2 payer: %s", rotation.ID, next.Address.ToBase58())
+	}
+	return nil
+}
+
+//keyRotationBackend return the ontology L1 signed by the operator account, and by the next key of the config if cosign
+func (this *Layer2Operator) keyRotationBackend(cosign bool) (*ontologyBackend, error) {
+	if this.config().IsEthereum() {
+		return nil, fmt.Errorf("key rotation is only supported by the ontology L1")
+	}
+	if this.config().MultiSigConfig != nil {
+		return nil, fmt.Errorf("key rotation is not supported by the multisig operators")
+	}
+	l1, err := this.newL1Backend()
+	if err != nil {
+		return nil, err
+	}
+	backend := l1.(*ontologyBackend)
+	if cosign {
+		nextAccount := this.config().OntologyConfig.NextAccount
+		if nextAccount == nil {
+			return nil, fmt.Errorf("NextAccount of OntologyConfig is required")
+		}
+		backend.cosigner, err = this.getOntologyAccount(nextAccount)
+		if err != nil {
+			return nil, err
+		}
+		if backend.cosigner.Address == backend.account.Address {
+			return nil, fmt.Errorf("next ontology account %s is the operator account", backend.account.Address.ToBase58())
+		}
+	}
+	return backend, nil
+}
+
+//OperatorState return the operator and the pending rotation of the layer2 contract
+func (this *Layer2Operator) OperatorState() (*bridge.OperatorState, error) {
+	backend, err := this.keyRotationBackend(false)
+	if err != nil {
+		return nil, err
+	}
+	return operatorState(backend)
+}
+
+func operatorState(l1 L1Backend) (*bridge.OperatorState, error) {
+	params, err := l1.Bridge().GetOperatorParams()
+	if err != nil {
+		return nil, err
+	}
+	result, err := l1.Call(params)
+	if err != nil {
+		return nil, err
+	}
+	return l1.Bridge().ParseOperator(result)
+}
+
+//RegisterKeyRotation register the next ontology key of the config as the new operator of the layer2 contract, signed by
+//both keys, and save the rotation to the next keys of the config. The new key can switch after transitionPeriod blocks
+func (this *Layer2Operator) RegisterKeyRotation(transitionPeriod uint64) (*KeyRotation, error) {
+	rotation, err := latestKeyRotation()
+	if err != nil {
+		return nil, err
+	}
+	if rotation != nil && rotation.State == KEY_ROTATION_REGISTERED {
+		return nil, fmt.Errorf("key rotation %d is registered, switch or cancel it first", rotation.ID)
+	}
+	backend, err := this.keyRotationBackend(true)
+	if err != nil {
+		return nil, err
+	}
+	if this.config().Layer2Config.NextAccount == nil {
+		return nil, fmt.Errorf("NextAccount of Layer2Config is required")
+	}
+	layer2Account, err := this.getLyer2Account(this.config().Layer2Config.Account())
+	if err != nil {
+		return nil, err
+	}
+	nextLayer2Account, err := this.getLyer2Account(this.config().Layer2Config.NextAccount)
+	if err != nil {
+		return nil, err
+	}
+	if nextLayer2Account.Address == layer2Account.Address {
+		return nil, fmt.Errorf("next layer2 account %s is the operator account", layer2Account.Address.ToBase58())
+	}
+	state, err := operatorState(backend)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(state.Operator, backend.account.Address[:]) {
+		return nil, fmt.Errorf("ontology account %s is not the operator of the layer2 contract", backend.account.Address.ToBase58())
+	}
+	if len(state.NewOperator) > 0 {
+		return nil, fmt.Errorf("layer2 contract is rotating to %x", state.NewOperator)
+	}
+	params, err := backend.Bridge().RegisterOperatorParams(&bridge.RegisterOperatorParam{
+		NewOperator:      backend.cosigner.Address[:],
+		TransitionPeriod: transitionPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := backend.Invoke(params)
+	if err != nil {
+		return nil, err
+	}
+	audit(AUDIT_KEY_ROTATION, AUDIT_BY_CLI, txHash, "register %s, transition period: %d", backend.cosigner.Address.ToBase58(),
+		transitionPeriod)
+	rotation = &KeyRotation{
+		State:               KEY_ROTATION_REGISTERED,
+		OntologyAddress:     backend.account.Address.ToBase58(),
+		NextOntologyAddress: backend.cosigner.Address.ToBase58(),
+		Layer2Address:       layer2Account.Address.ToBase58(),
+		NextLayer2Address:   nextLayer2Account.Address.ToBase58(),
+		TransitionPeriod:    transitionPeriod,
+		RegisterTxHash:      txHash,
+		TT:                  uint32(time.Now().Unix()),
+	}
+	err = SaveKeyRotation(rotation)
+	if err != nil {
+		return nil, fmt.Errorf("key rotation is registered by transaction %s, save it failed: %s", txHash, err)
+	}
+	log.Infof("key rotation - register %s, transaction: %s", rotation.NextOntologyAddress, txHash)
+	return rotation, nil
+}
+
+//SwitchKeyRotation switch the operator of the layer2 contract to the new key after the transition period, the layer2
+//payer is switched by the operators on restart
+func (this *Layer2Operator) SwitchKeyRotation() (*KeyRotation, error) {
+	rotation, err := latestKeyRotation()
+	if err != nil {
+		return nil, err
+	}
+	if rotation == nil || rotation.State != KEY_ROTATION_REGISTERED {
+		return nil, fmt.Errorf("no key rotation is registered")
+	}
+	backend, err := this.keyRotationBackend(true)
+	if err != nil {
+		return nil, err
+	}
+	if backend.cosigner.Address.ToBase58() != rotation.NextOntologyAddress {
+		return nil, fmt.Errorf("next ontology account %s is not the new key %s of key rotation %d",
+			backend.cosigner.Address.ToBase58(), rotation.NextOntologyAddress, rotation.ID)
+	}
+	state, err := operatorState(backend)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(state.NewOperator, backend.cosigner.Address[:]) {
+		return nil, fmt.Errorf("new key %s is not registered in the layer2 contract", rotation.NextOntologyAddress)
+	}
+	height, err := backend.GetHeight()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(height) < state.SwitchBlock {
+		return nil, fmt.Errorf("transition period ends at block %d, current block is %d", state.SwitchBlock, height)
+	}
+	params, err := backend.Bridge().SwitchOperatorParams()
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := backend.Invoke(params)
+	if err != nil {
+		return nil, err
+	}
+	audit(AUDIT_KEY_ROTATION, AUDIT_BY_CLI, txHash, "switch to %s", rotation.NextOntologyAddress)
+	err = UpdateKeyRotation(rotation.ID, KEY_ROTATION_SWITCHED, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("key rotation is switched by transaction %s, save it failed: %s", txHash, err)
+	}
+	rotation.State = KEY_ROTATION_SWITCHED
+	rotation.SwitchTxHash = txHash
+	log.Infof("key rotation - switch to %s, transaction: %s", rotation.NextOntologyAddress, txHash)
+	return rotation, nil
+}
+
+//CancelKeyRotation cancel the registered key rotation by the operator account, the next key is not needed
+func (this *Layer2Operator) CancelKeyRotation() (*KeyRotation, error) {
+	rotation, err := latestKeyRotation()
+	if err != nil {
+		return nil, err
+	}
+	if rotation == nil || rotation.State != KEY_ROTATION_REGISTERED {
+		return nil, fmt.Errorf("no key rotation is registered")
+	}
+	backend, err := this.keyRotationBackend(false)
+	if err != nil {
+		return nil, err
+	}
+	state, err := operatorState(backend)
+	if err != nil {
+		return nil, err
+	}
+	// the rotation is only cancelled in the database if its registration is not executed
+	txHash := ""
+	if len(state.NewOperator) > 0 {
+		params, err := backend.Bridge().CancelOperatorParams()
+		if err != nil {
+			return nil, err
+		}
+		txHash, err = backend.Invoke(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	audit(AUDIT_KEY_ROTATION, AUDIT_BY_CLI, txHash, "cancel %s", rotation.NextOntologyAddress)
+	err = UpdateKeyRotation(rotation.ID, KEY_ROTATION_CANCELLED, txHash)
+	if err != nil {
+		return nil, err
+	}
+	rotation.State = KEY_ROTATION_CANCELLED
+	rotation.SwitchTxHash = txHash
+	log.Infof("key rotation - cancel %s, transaction: %s", rotation.NextOntologyAddress, txHash)
+	return rotation, nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	layer2_sdk_common "github.com/ontio/layer2/go-sdk/common"
+	layer2_types "github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/operator/config"
+	"sync"
+)
+
+//layer2Block is a layer2 block fetched from the node with its events and state, err is set if it is not fetched
+type layer2Block struct {
+	height      uint32
+	block       *layer2_types.Block
+	events      []*layer2_sdk_common.SmartContactEvent
+	layer2State *layer2_sdk_common.Layer2State
+	err         error
+}
+
+//layer2ParseConcurrency return the max number of layer2 blocks fetched at the same time
+func (this *Layer2Operator) layer2ParseConcurrency() int {
+	if this.config().Layer2Config == nil {
+		return config.DEFAULT_LAYER2_PARSE_CONCURRENCY
+	}
+	return this.config().Layer2Config.ParseConcurrencyOrDefault()
+}
+
+//fetchLayer2Blocks fetch the blocks of count heights from height concurrently, the blocks are returned in height
+//order and end at the first one failed
+func (this *Layer2Operator) fetchLayer2Blocks(height uint32, count int) []*layer2Block {
+	blocks := make([]*layer2Block, count)
+	wg := sync.WaitGroup{}
+	for i := 0; i < count; i ++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blocks[i] = this.fetchLayer2Block(height + uint32(i))
+		}(i)
+	}
+	wg.Wait()
+	for i, block := range blocks {
+		if block.err != nil {
+			return blocks[:i + 1]
+		}
+	}
+	return blocks
+}
+
+//fetchLayer2Block fetch the block of height, the block pushed by the websocket is taken if there is one
+func (this *Layer2Operator) fetchLayer2Block(height uint32) *layer2Block {
+	fetched := &layer2Block{height: height}
+	if this.layer2Subscription != nil {
+		fetched.block, _ = this.layer2Subscription.Block(height).(*layer2_types.Block)
+	}
+	if fetched.block == nil {
+		fetched.block, fetched.err = this.layer2Sdk().GetBlockByHeight(height)
+		if fetched.err != nil {
+			return fetched
+		}
+	}
+	// the empty block has no event, but its state is still committed
+	if len(fetched.block.Transactions) > 0 {
+		fetched.events, fetched.err = this.layer2Sdk().GetSmartContractEventByBlock(height)
+		if fetched.err != nil {
+			return fetched
+		}
+	}
+	fetched.layer2State, _, _ = this.layer2Sdk().GetLayer2State(height)
+	return fetched
+}
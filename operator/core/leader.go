@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"context"
+	"database/sql"
+	"github.com/ontio/layer2/operator/log"
+	"sync"
+)
+
+//LeaderElector elect the leader of the operator instances sharing a database by the advisory lock of the database
+//dialect. The lock is held by a dedicated connection, and the database releases it when the session ends, so a standby
+//acquires it after the leader exits or loses the database
+type LeaderElector struct {
+	db       *sql.DB
+	name     string
+	conn     *sql.Conn
+	leader   bool
+	mu       sync.Mutex
+}
+
+func NewLeaderElector(db *sql.DB, name string) *LeaderElector {
+	return &LeaderElector{
+		db: db,
+		name: name,
+	}
+}
+
+//Campaign try to acquire the lock without waiting if standby, or check the lock is still held if leader, and return
+//whether this instance is the leader
+func (this *LeaderElector) Campaign() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	ctx := context.Background()
+	if this.conn == nil {
+		conn, err := this.db.Conn(ctx)
+		if err != nil {
+			log.Errorf("leader election - connect db err: %v", err)
+			return false
+		}
+		this.conn = conn
+	}
+	var held sql.NullInt64
+	var err error
+	if this.leader {
+		err = this.conn.QueryRowContext(ctx, DefDialect.IsLockedSQL(), this.name).Scan(&held)
+	} else {
+		err = this.conn.QueryRowContext(ctx, DefDialect.TryLockSQL(), this.name).Scan(&held)
+	}
+	if err != nil {
+		log.Errorf("leader election - query lock %s err: %v", this.name, err)
+		this.closeConn()
+		return false
+	}
+	this.leader = held.Valid && held.Int64 == 1
+	return this.leader
+}
+
+func (this *LeaderElector) IsLeader() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.leader
+}
+
+//Resign release the lock if leader and close the connection
+func (this *LeaderElector) Resign() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.conn == nil {
+		return
+	}
+	if this.leader {
+		var released sql.NullInt64
+		this.conn.QueryRowContext(context.Background(), DefDialect.UnlockSQL(), this.name).Scan(&released)
+	}
+	this.closeConn()
+}
+
+func (this *LeaderElector) closeConn() {
+	this.conn.Close()
+	this.conn = nil
+	this.leader = false
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+const LAYER2_SNAPSHOT_TIMEOUT = 10 * time.Minute
+
+//MassExitAccount is the balance of Player in the layer2 state root of the mass exit, which is claimed by claimMassExit
+//of the bridge contract with AssetAddress, Value and Proof
+type MassExitAccount struct {
+	Player          string
+	AssetAddress    string
+	Layer2Asset     string
+	Value           string
+	Proof           string
+}
+
+//MassExitSnapshot is the accounts of all the bridged fungible tokens in the layer2 state root of Height
+type MassExitSnapshot struct {
+	Height          uint32
+	StateRoot       string
+	Accounts        []*MassExitAccount
+}
+
+type layer2AccountSnapshot struct {
+	Height          uint32
+	Root            string
+	Accounts        []*layer2SnapshotAccount
+}
+
+type layer2SnapshotAccount struct {
+	Key             string
+	Value           string
+	Proof           string
+}
+
+//massExitLoop export the snapshot of the accounts in the state root of the committed height once the mass exit is
+//started on L1. The snapshot is exported again if the committed height changes, as a pending commit may be reverted
+func (this *Layer2Operator) massExitLoop() {
+	log.Infof("start massExitLoop")
+	exported := uint32(0)
+	for true {
+		select {
+		case <- this.ctx.Done():
+			log.Infof("massExitLoop exit")
+			return
+		case <- time.After(config.MASS_EXIT_CHECK_INTERVAL):
+		}
+		started, err := this.getMassExitHeight()
+		if err != nil {
+			log.Errorf("mass exit - get mass exit height err: %v", err)
+			continue
+		}
+		if started == 0 {
+			continue
+		}
+		height, err := this.getCommittedHeight()
+		if err != nil {
+			log.Errorf("mass exit - get committed height err: %v", err)
+			continue
+		}
+		if height == exported {
+			continue
+		}
+		err = this.exportMassExit(height)
+		if err != nil {
+			log.Errorf("mass exit - export snapshot of height %d err: %v", height, err)
+			continue
+		}
+		exported = height
+	}
+}
+
+//getMassExitHeight return the L1 block the mass exit is started at, or 0 if it is not started
+func (this *Layer2Operator) getMassExitHeight() (uint64, error) {
+	params, err := this.bridge.GetMassExitHeightParams()
+	if err != nil {
+		return 0, err
+	}
+	result, err := this.l1.Call(params)
+	if err != nil {
+		return 0, err
+	}
+	return this.bridge.ParseMassExitHeight(result)
+}
+
+//exportMassExit export the snapshot of the committed height to the export dir, after checking its state root against
+//the committed one
+func (this *Layer2Operator) exportMassExit(height uint32) error {
+	params, err := this.bridge.GetStateRootByHeightParams(uint64(height))
+	if err != nil {
+		return err
+	}
+	result, err := this.l1.Call(params)
+	if err != nil {
+		return err
+	}
+	stateRoot, err := this.bridge.ParseStateRoot(result)
+	if err != nil {
+		return err
+	}
+	snapshot, err := GetMassExitSnapshot(this.config().Layer2Config.RestURL, height, this.tokens.list())
+	if err != nil {
+		return err
+	}
+	if snapshot.StateRoot != stateRoot.StateRootHash {
+		return fmt.Errorf("state root %s of snapshot mismatch the committed state root %s", snapshot.StateRoot, stateRoot.StateRootHash)
+	}
+	path := filepath.Join(this.config().MassExitConfig.ExportDir, fmt.Sprintf("massexit_%d.json", height))
+	err = WriteMassExitSnapshot(path, snapshot)
+	if err != nil {
+		return err
+	}
+	log.Infof("mass exit - export %d accounts of height %d to %s", len(snapshot.Accounts), height, path)
+	return nil
+}
+
+//GetMassExitSnapshot get the accounts in the layer2 state root of height by getlayer2accountsnapshot of the layer2 node
+//of rpcURL, and build the claims of the accounts of the fungible tokens. The proof of every claim is verified against
+//the state root, so that the contract does not reject it
+func GetMassExitSnapshot(rpcURL string, height uint32, tokens []*Token) (*MassExitSnapshot, error) {
+	result, err := callLayer2Rpc(rpcURL, "getlayer2accountsnapshot", []interface{}{height}, LAYER2_SNAPSHOT_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	accountSnapshot := &layer2AccountSnapshot{}
+	if err := json.Unmarshal(result, accountSnapshot); err != nil {
+		return nil, fmt.Errorf("parse layer2 account snapshot failed! err: %s", err.Error())
+	}
+	root, err := layer2_common.Uint256FromHexString(accountSnapshot.Root)
+	if err != nil {
+		return nil, fmt.Errorf("parse layer2 account snapshot root failed! err: %s", err.Error())
+	}
+	layer2Tokens := make(map[string]*Token)
+	for _, token := range tokens {
+		if token.Standard == "" {
+			layer2Tokens[token.Layer2Address] = token
+		}
+	}
+	snapshot := &MassExitSnapshot{
+		Height:    accountSnapshot.Height,
+		StateRoot: accountSnapshot.Root,
+		Accounts:  make([]*MassExitAccount, 0),
+	}
+	for _, account := range accountSnapshot.Accounts {
+		key, err := hex.DecodeString(account.Key)
+		if err != nil {
+			return nil, fmt.Errorf("decode key %s failed! err: %s", account.Key, err.Error())
+		}
+		// the contract proves the account of the layer2 asset and the player only
+		if len(key) != 2 * layer2_common.ADDR_LEN {
+			continue
+		}
+		token, ok := layer2Tokens[hex.EncodeToString(key[:layer2_common.ADDR_LEN])]
+		if !ok {
+			continue
+		}
+		data, err := hex.DecodeString(account.Proof)
+		if err != nil {
+			return nil, fmt.Errorf("decode proof of key %s failed! err: %s", account.Key, err.Error())
+		}
+		proof, err := bridge.ParseSMTProof(data)
+		if err != nil {
+			return nil, err
+		}
+		claimProof := bridge.EncodeWithdrawProof(key, proof)
+		if !bridge.VerifyWithdrawProof([32]byte(root), key, claimProof) {
+			return nil, fmt.Errorf("proof of key %s does not match the layer2 state root of height %d", account.Key, accountSnapshot.Height)
+		}
+		player, err := layer2_common.AddressParseFromBytes(key[layer2_common.ADDR_LEN:])
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Accounts = append(snapshot.Accounts, &MassExitAccount{
+			Player:       player.ToBase58(),
+			AssetAddress: token.Address,
+			Layer2Asset:  token.Layer2Address,
+			Value:        account.Value,
+			Proof:        hex.EncodeToString(claimProof),
+		})
+	}
+	return snapshot, nil
+}
+
+//WriteMassExitSnapshot write the snapshot to the json file of path
+func WriteMassExitSnapshot(path string, snapshot *MassExitSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
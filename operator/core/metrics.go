@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	METRICS_PATH = "/metrics"
+	// the mysql, postgres and sqlite drivers timing the statements
+	DB_DRIVER_NAME          = "mysql-timed"
+	DB_POSTGRES_DRIVER_NAME = "postgres-timed"
+	DB_SQLITE_DRIVER_NAME   = "sqlite-timed"
+)
+
+//MetricsRegistry is the registry of the operator metrics, which is served in prometheus format if MetricsConfig is
+//set. The metrics are no-op unless metrics.Enabled is set before the operator is created
+var MetricsRegistry = metrics.NewRegistry()
+
+// the time of the statements of the database, it is set when the operator metrics are created
+var dbQueryTimer metrics.Timer = metrics.NilTimer{}
+
+func init() {
+	sql.Register(DB_DRIVER_NAME, &timedDriver{mysql.MySQLDriver{}, &mysqlDialect{}})
+	sql.Register(DB_POSTGRES_DRIVER_NAME, &timedDriver{&pq.Driver{}, &postgresDialect{}})
+	sql.Register(DB_SQLITE_DRIVER_NAME, &timedDriver{&sqlite3.SQLiteDriver{}, &sqliteDialect{}})
+}
+
+//operatorMetrics is the heights, counters and commit costs updated by the loops of the operator
+type operatorMetrics struct {
+	l1Height        metrics.Gauge
+	layer2Height    metrics.Gauge
+	commitSuccess   metrics.Counter
+	commitFailure   metrics.Counter
+	commitGasUsed   metrics.Histogram
+	commitFee       metrics.Histogram
+	retries         metrics.Counter
+	// the events of the bridge contract on L1 and the transfer notifies on layer2 not matching their schema
+	undecodedL1Events     metrics.Counter
+	undecodedLayer2Events metrics.Counter
+	// the balances of the operator accounts and the commits deferred for the gas balance short of the estimated fee
+	gasBalance      metrics.Gauge
+	layer2Balance   metrics.Gauge
+	commitDeferred  metrics.Counter
+}
+
+//newOperatorMetrics enable the metrics if enabled, the metrics created before are no-op
+func newOperatorMetrics(enabled bool) *operatorMetrics {
+	if enabled {
+		metrics.Enabled = true
+	}
+	newHistogram := func(name string) metrics.Histogram {
+		return metrics.GetOrRegisterHistogram(name, MetricsRegistry, metrics.NewExpDecaySample(1028, 0.015))
+	}
+	dbQueryTimer = metrics.GetOrRegisterTimer("db/query", MetricsRegistry)
+	return &operatorMetrics{
+		l1Height:      metrics.GetOrRegisterGauge("l1/height/chain", MetricsRegistry),
+		layer2Height:  metrics.GetOrRegisterGauge("layer2/height/chain", MetricsRegistry),
+		commitSuccess: metrics.GetOrRegisterCounter("commit/success", MetricsRegistry),
+		commitFailure: metrics.GetOrRegisterCounter("commit/failure", MetricsRegistry),
+		commitGasUsed: newHistogram("commit/gasused"),
+		commitFee:     newHistogram("commit/fee"),
+		retries:       metrics.GetOrRegisterCounter("retry/count", MetricsRegistry),
+		undecodedL1Events:     metrics.GetOrRegisterCounter("l1/event/undecodable", MetricsRegistry),
+		undecodedLayer2Events: metrics.GetOrRegisterCounter("layer2/event/undecodable", MetricsRegistry),
+		gasBalance:    metrics.GetOrRegisterGauge("l1/balance/gas", MetricsRegistry),
+		layer2Balance: metrics.GetOrRegisterGauge("layer2/balance", MetricsRegistry),
+		commitDeferred: metrics.GetOrRegisterCounter("commit/deferred", MetricsRegistry),
+	}
+}
+
+//updateCommitResult count the executed commit and record the gas it used
+func (this *operatorMetrics) updateCommitResult(result *L1CommitResult) {
+	if result.Success {
+		this.commitSuccess.Inc(1)
+	} else {
+		this.commitFailure.Inc(1)
+	}
+	this.commitGasUsed.Update(int64(result.GasUsed))
+	this.commitFee.Update(int64(result.Fee))
+}
+
+//registerGauges register the gauges of the parsed heights and the pending records, which are read when the metrics
+//are collected
+func (this *Layer2Operator) registerGauges() {
+	gauges := map[string]func() int64{
+		"l1/height/parsed":     func() int64 { return int64(this.l1ChainInfo.Height) },
+		"layer2/height/parsed": func() int64 { return int64(this.layer2ChainInfo.Height) },
+		"deposit/pending":      func() int64 { return CountDepositsByState(DEPOSIT_EVENT) },
+		"withdraw/pending":     func() int64 { return CountWithdrawsByState(WITHDRAW_INIT) },
+		"job/dead":             func() int64 { return CountJobsByState(JOB_DEAD) },
+	}
+	for name, f := range gauges {
+		MetricsRegistry.Unregister(name)
+		metrics.NewRegisteredFunctionalGauge(name, MetricsRegistry, f)
+	}
+}
+
+//startMetricsServer serve the metrics in prometheus format
+func (this *Layer2Operator) startMetricsServer() error {
+	metricsConfig := this.config().MetricsConfig
+	if metricsConfig == nil || metricsConfig.ListenAddr == "" {
+		return nil
+	}
+	this.registerGauges()
+	listener, err := net.Listen("tcp", metricsConfig.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("metrics listen %s error: %s", metricsConfig.ListenAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(METRICS_PATH, prometheus.Handler(MetricsRegistry))
+	this.metricsServer = &http.Server{Handler: mux}
+	go this.metricsServer.Serve(listener)
+	log.Infof("metrics - server started at %s", metricsConfig.ListenAddr)
+	return nil
+}
+
+//timedDriver is the database driver recording the time of the statements executed by the connections it opens, the
+//statements are rewritten by the dialect of the database
+type timedDriver struct {
+	driver.Driver
+	dialect    Dialect
+}
+
+func (this *timedDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := this.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{conn, this.dialect}, nil
+}
+
+type timedConn struct {
+	driver.Conn
+	dialect    Dialect
+}
+
+func (this *timedConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := this.Conn.Prepare(this.dialect.Rewrite(query))
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{stmt}, nil
+}
+
+//CheckNamedValue convert the args by the mysql connection, which accepts uint64 values
+func (this *timedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := this.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func (this *timedConn) Ping(ctx context.Context) error {
+	if pinger, ok := this.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (this *timedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := this.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+type timedStmt struct {
+	driver.Stmt
+}
+
+func (this *timedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	defer dbQueryTimer.UpdateSince(time.Now())
+	return this.Stmt.Exec(args)
+}
+
+func (this *timedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	defer dbQueryTimer.UpdateSince(time.Now())
+	return this.Stmt.Query(args)
+}
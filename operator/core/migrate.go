@@ -0,0 +1,570 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	// the lock taken by the operator migrating the schema, so the operators sharing the database migrate it once
+	MIGRATION_LOCK         = "layer2-migration"
+	MIGRATION_LOCK_TIMEOUT = 60 * time.Second
+)
+
+//Migration is a versioned change of the schema of the operator tables. The statements are written in MySQL and
+//rewritten by the dialect like the other statements, so they are limited to the SQL shared by the databases, a
+//statement which differs is given for the driver name of the dialect in Dialects
+type Migration struct {
+	Version     uint32
+	Description string
+	Statements  []string
+	Dialects    map[string][]string
+}
+
+func (this *Migration) statements(dialect Dialect) []string {
+	if statements, ok := this.Dialects[dialect.DriverName()]; ok {
+		return statements
+	}
+	return this.Statements
+}
+
+//Migrations is the schema migrations in version order. The scripts in docs and the SQLite schema are the tables of the
+//first release, the baseline of version 1, and are not changed any more, a change of the tables is a new migration
+//appended here
+var Migrations = []*Migration{
+	{
+		Version:     1,
+		Description: "baseline of the tables of docs/explorer.sql",
+	},
+	{
+		Version:     2,
+		Description: "quarantine the deposit mints not matching their deposits",
+		Statements: []string{
+			`create table depositquarantine (
+ layer2txhash VARCHAR(256) NOT NULL,
+ tt INTEGER DEFAULT 0,
+ height INTEGER DEFAULT 0,
+ id BIGINT DEFAULT 0,
+ txhash VARCHAR(256) DEFAULT '',
+ reason VARCHAR(1024) DEFAULT '',
+ PRIMARY KEY (layer2txhash)
+)`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "persist the deposit and commit work in the job table",
+		Statements: []string{
+			`create table job (
+ id BIGINT NOT NULL AUTO_INCREMENT,
+ kind INTEGER NOT NULL,
+ jobkey BIGINT NOT NULL,
+ state INTEGER DEFAULT 0,
+ payload TEXT NOT NULL,
+ tt INTEGER DEFAULT 0,
+ PRIMARY KEY (id),
+ UNIQUE (kind, jobkey)
+)`,
+			"create index job_kind_state on job (kind, state)",
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				`create table job (
+ id BIGSERIAL NOT NULL,
+ kind INTEGER NOT NULL,
+ jobkey BIGINT NOT NULL,
+ state INTEGER DEFAULT 0,
+ payload TEXT NOT NULL,
+ tt INTEGER DEFAULT 0,
+ PRIMARY KEY (id),
+ UNIQUE (kind, jobkey)
+)`,
+				"create index job_kind_state on job (kind, state)",
+			},
+			DB_SQLITE_DRIVER_NAME: {
+				`create table job (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ kind INTEGER NOT NULL,
+ jobkey BIGINT NOT NULL,
+ state INTEGER DEFAULT 0,
+ payload TEXT NOT NULL,
+ tt INTEGER DEFAULT 0,
+ UNIQUE (kind, jobkey)
+)`,
+				"create index job_kind_state on job (kind, state)",
+			},
+		},
+	},
+	{
+		Version:     4,
+		Description: "key layer2tx by the transfer and depositquarantine by the deposit",
+		Statements: []string{
+			"alter table layer2tx add column notifyindex INTEGER NOT NULL DEFAULT 0",
+			"alter table layer2tx drop primary key, add primary key (txhash, notifyindex)",
+			"alter table depositquarantine drop primary key, add primary key (layer2txhash, id)",
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				"alter table layer2tx add column notifyindex INTEGER NOT NULL DEFAULT 0",
+				"alter table layer2tx drop constraint layer2tx_pkey",
+				"alter table layer2tx add primary key (txhash, notifyindex)",
+				"alter table depositquarantine drop constraint depositquarantine_pkey",
+				"alter table depositquarantine add primary key (layer2txhash, id)",
+			},
+			// sqlite changes no primary key, the tables are copied to new ones
+			DB_SQLITE_DRIVER_NAME: {
+				`create table layer2tx_transfer (
+ txhash VARCHAR(256) NOT NULL,
+ state INTEGER NOT NULL,
+ tt INTEGER NOT NULL,
+ fee BIGINT NOT NULL,
+ height INTEGER NOT NULL,
+ fromaddress VARCHAR(256) NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ toaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ notifyindex INTEGER NOT NULL DEFAULT 0,
+ PRIMARY KEY (txhash, notifyindex)
+)`,
+				"insert into layer2tx_transfer(txhash, state, tt, fee, height, fromaddress, tokenaddress, toaddress, amount) select txhash, state, tt, fee, height, fromaddress, tokenaddress, toaddress, amount from layer2tx",
+				"drop table layer2tx",
+				"alter table layer2tx_transfer rename to layer2tx",
+				`create table depositquarantine_deposit (
+ layer2txhash VARCHAR(256) NOT NULL,
+ tt INTEGER DEFAULT 0,
+ height INTEGER DEFAULT 0,
+ id BIGINT DEFAULT 0,
+ txhash VARCHAR(256) DEFAULT '',
+ reason VARCHAR(1024) DEFAULT '',
+ PRIMARY KEY (layer2txhash, id)
+)`,
+				"insert into depositquarantine_deposit(layer2txhash, tt, height, id, txhash, reason) select layer2txhash, tt, height, id, txhash, reason from depositquarantine",
+				"drop table depositquarantine",
+				"alter table depositquarantine_deposit rename to depositquarantine",
+			},
+		},
+	},
+	{
+		Version:     5,
+		Description: "record the hashes of the parsed ontology blocks",
+		Statements: []string{
+			`create table ontologyblock (
+ height INTEGER NOT NULL,
+ hash VARCHAR(256) NOT NULL,
+ PRIMARY KEY (height)
+)`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "persist the token registry",
+		Statements: []string{
+			`create table token (
+ address VARCHAR(64) NOT NULL,
+ layer2address VARCHAR(64) NOT NULL,
+ name VARCHAR(64) DEFAULT '',
+ decimals INTEGER DEFAULT 0,
+ enabled TINYINT(1) DEFAULT 1,
+ PRIMARY KEY (address),
+ UNIQUE (layer2address)
+)`,
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				`create table token (
+ address VARCHAR(64) NOT NULL,
+ layer2address VARCHAR(64) NOT NULL,
+ name VARCHAR(64) DEFAULT '',
+ decimals INTEGER DEFAULT 0,
+ enabled BOOLEAN DEFAULT TRUE,
+ PRIMARY KEY (address),
+ UNIQUE (layer2address)
+)`,
+			},
+			DB_SQLITE_DRIVER_NAME: {
+				`create table token (
+ address VARCHAR(64) NOT NULL,
+ layer2address VARCHAR(64) NOT NULL,
+ name VARCHAR(64) DEFAULT '',
+ decimals INTEGER DEFAULT 0,
+ enabled INTEGER DEFAULT 1,
+ PRIMARY KEY (address),
+ UNIQUE (layer2address)
+)`,
+			},
+		},
+	},
+	{
+		Version:     7,
+		Description: "bridge the NFTs by token id",
+		Statements: []string{
+			"alter table deposit add column tokenid VARCHAR(256) DEFAULT ''",
+			"alter table withdraw add column tokenid VARCHAR(256) DEFAULT ''",
+			"alter table layer2tx add column tokenid VARCHAR(256) DEFAULT ''",
+			"alter table token add column standard VARCHAR(16) DEFAULT ''",
+		},
+	},
+	{
+		Version:     8,
+		Description: "add the parse height of the ethereum L1",
+		Statements: []string{
+			"insert ignore into chain_info(name, id, url, height) values ('ethereum', 3, 'http://127.0.0.1:8545', 0)",
+		},
+	},
+	{
+		Version:     9,
+		Description: "track the forced exits",
+		Statements: []string{
+			"create table `exit` (" + `
+ id BIGINT NOT NULL,
+ txhash VARCHAR(256) NOT NULL,
+ tt INTEGER NOT NULL,
+ state INTEGER NOT NULL,
+ height INTEGER NOT NULL,
+ player VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ layer2height INTEGER NOT NULL,
+ layer2txhash VARCHAR(256) DEFAULT '',
+ PRIMARY KEY (id)
+)`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "record the refunds of the deposits",
+		Statements: []string{
+			"alter table deposit add column refundtxhash VARCHAR(256) DEFAULT NULL",
+		},
+	},
+	{
+		Version:     11,
+		Description: "keep the ledger of the withdrawal fees",
+		Statements: []string{
+			`create table withdrawfee (
+ txhash VARCHAR(256) NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ fee BIGINT NOT NULL,
+ height INTEGER NOT NULL,
+ PRIMARY KEY (txhash)
+)`,
+			"create index withdrawfee_tokenaddress on withdrawfee (tokenaddress)",
+		},
+	},
+	{
+		Version:     12,
+		Description: "record the gas price of the commits",
+		Statements: []string{
+			"alter table layer2commit add column gasprice BIGINT DEFAULT 0",
+		},
+	},
+	{
+		Version:     13,
+		Description: "move the exhausted jobs to the dead letter table",
+		Statements: []string{
+			`create table deadletter (
+ id BIGINT NOT NULL AUTO_INCREMENT,
+ kind INTEGER NOT NULL,
+ jobkey BIGINT NOT NULL,
+ payload TEXT NOT NULL,
+ error VARCHAR(1024) NOT NULL,
+ attempts INTEGER DEFAULT 0,
+ tt INTEGER DEFAULT 0,
+ PRIMARY KEY (id)
+)`,
+			"create index deadletter_kind_jobkey on deadletter (kind, jobkey)",
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				`create table deadletter (
+ id BIGSERIAL NOT NULL,
+ kind INTEGER NOT NULL,
+ jobkey BIGINT NOT NULL,
+ payload TEXT NOT NULL,
+ error VARCHAR(1024) NOT NULL,
+ attempts INTEGER DEFAULT 0,
+ tt INTEGER DEFAULT 0,
+ PRIMARY KEY (id)
+)`,
+				"create index deadletter_kind_jobkey on deadletter (kind, jobkey)",
+			},
+			DB_SQLITE_DRIVER_NAME: {
+				`create table deadletter (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ kind INTEGER NOT NULL,
+ jobkey BIGINT NOT NULL,
+ payload TEXT NOT NULL,
+ error VARCHAR(1024) NOT NULL,
+ attempts INTEGER DEFAULT 0,
+ tt INTEGER DEFAULT 0
+)`,
+				"create index deadletter_kind_jobkey on deadletter (kind, jobkey)",
+			},
+		},
+	},
+	{
+		Version:     14,
+		Description: "persist the pauses of the deposits, the commits and the tokens",
+		Statements: []string{
+			`create table pause (
+ target VARCHAR(256) NOT NULL,
+ tt INTEGER DEFAULT 0,
+ PRIMARY KEY (target)
+)`,
+		},
+	},
+	{
+		Version:     15,
+		Description: "identify the deposit events by txhash and notifyindex",
+		Statements: []string{
+			"alter table deposit add column notifyindex INTEGER NOT NULL DEFAULT 0",
+			"alter table deposit drop index txhash",
+			"create unique index deposit_event on deposit (txhash, notifyindex)",
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				"alter table deposit add column notifyindex INTEGER NOT NULL DEFAULT 0",
+				"alter table deposit drop constraint deposit_txhash_key",
+				"create unique index deposit_event on deposit (txhash, notifyindex)",
+			},
+			// sqlite drops no constraint, the table is copied to a new one
+			DB_SQLITE_DRIVER_NAME: {
+				`create table deposit_event (
+ txhash VARCHAR(256) NOT NULL,
+ notifyindex INTEGER NOT NULL DEFAULT 0,
+ tt INTEGER NOT NULL,
+ state INTEGER NOT NULL,
+ height INTEGER NOT NULL,
+ fromaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ tokenid VARCHAR(256) DEFAULT '',
+ id INTEGER NOT NULL,
+ layer2txhash VARCHAR(256) DEFAULT NULL,
+ refundtxhash VARCHAR(256) DEFAULT NULL,
+ PRIMARY KEY (id),
+ UNIQUE (txhash, notifyindex)
+)`,
+				"insert into deposit_event(txhash, tt, state, height, fromaddress, amount, tokenaddress, tokenid, id, layer2txhash, refundtxhash) select txhash, tt, state, height, fromaddress, amount, tokenaddress, tokenid, id, layer2txhash, refundtxhash from deposit",
+				"drop table deposit",
+				"alter table deposit_event rename to deposit",
+			},
+		},
+	},
+	{
+		Version:     16,
+		Description: "track the rotations of the operator keys",
+		Statements: []string{
+			`create table keyrotation (
+ id BIGINT NOT NULL AUTO_INCREMENT,
+ state INTEGER NOT NULL,
+ ontologyaddress VARCHAR(256) NOT NULL,
+ nextontologyaddress VARCHAR(256) NOT NULL,
+ layer2address VARCHAR(256) NOT NULL,
+ nextlayer2address VARCHAR(256) NOT NULL,
+ transitionperiod BIGINT NOT NULL,
+ registertxhash VARCHAR(256) NOT NULL,
+ switchtxhash VARCHAR(256) NOT NULL DEFAULT '',
+ tt INTEGER NOT NULL,
+ PRIMARY KEY (id)
+)`,
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				`create table keyrotation (
+ id BIGSERIAL NOT NULL,
+ state INTEGER NOT NULL,
+ ontologyaddress VARCHAR(256) NOT NULL,
+ nextontologyaddress VARCHAR(256) NOT NULL,
+ layer2address VARCHAR(256) NOT NULL,
+ nextlayer2address VARCHAR(256) NOT NULL,
+ transitionperiod BIGINT NOT NULL,
+ registertxhash VARCHAR(256) NOT NULL,
+ switchtxhash VARCHAR(256) NOT NULL DEFAULT '',
+ tt INTEGER NOT NULL,
+ PRIMARY KEY (id)
+)`,
+			},
+			DB_SQLITE_DRIVER_NAME: {
+				`create table keyrotation (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ state INTEGER NOT NULL,
+ ontologyaddress VARCHAR(256) NOT NULL,
+ nextontologyaddress VARCHAR(256) NOT NULL,
+ layer2address VARCHAR(256) NOT NULL,
+ nextlayer2address VARCHAR(256) NOT NULL,
+ transitionperiod BIGINT NOT NULL,
+ registertxhash VARCHAR(256) NOT NULL,
+ switchtxhash VARCHAR(256) NOT NULL DEFAULT '',
+ tt INTEGER NOT NULL
+)`,
+			},
+		},
+	},
+	{
+		Version:     17,
+		Description: "append-only audit log of the operator actions",
+		Statements: []string{
+			`create table auditlog (
+ id BIGINT NOT NULL AUTO_INCREMENT,
+ action VARCHAR(64) NOT NULL,
+ initiator VARCHAR(256) NOT NULL,
+ detail VARCHAR(1024) NOT NULL,
+ txhash VARCHAR(256) NOT NULL DEFAULT '',
+ tt INTEGER NOT NULL,
+ PRIMARY KEY (id)
+)`,
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				`create table auditlog (
+ id BIGSERIAL NOT NULL,
+ action VARCHAR(64) NOT NULL,
+ initiator VARCHAR(256) NOT NULL,
+ detail VARCHAR(1024) NOT NULL,
+ txhash VARCHAR(256) NOT NULL DEFAULT '',
+ tt INTEGER NOT NULL,
+ PRIMARY KEY (id)
+)`,
+			},
+			DB_SQLITE_DRIVER_NAME: {
+				`create table auditlog (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ action VARCHAR(64) NOT NULL,
+ initiator VARCHAR(256) NOT NULL,
+ detail VARCHAR(1024) NOT NULL,
+ txhash VARCHAR(256) NOT NULL DEFAULT '',
+ tt INTEGER NOT NULL
+)`,
+			},
+		},
+	},
+	{
+		Version:     18,
+		Description: "record the actions of the dry run",
+		Statements: []string{
+			`create table dryrunaction (
+ id BIGINT NOT NULL AUTO_INCREMENT,
+ kind VARCHAR(64) NOT NULL,
+ subject VARCHAR(256) NOT NULL,
+ detail TEXT NOT NULL,
+ tt INTEGER NOT NULL,
+ PRIMARY KEY (id)
+)`,
+		},
+		Dialects: map[string][]string{
+			DB_POSTGRES_DRIVER_NAME: {
+				`create table dryrunaction (
+ id BIGSERIAL NOT NULL,
+ kind VARCHAR(64) NOT NULL,
+ subject VARCHAR(256) NOT NULL,
+ detail TEXT NOT NULL,
+ tt INTEGER NOT NULL,
+ PRIMARY KEY (id)
+)`,
+			},
+			DB_SQLITE_DRIVER_NAME: {
+				`create table dryrunaction (
+ id INTEGER PRIMARY KEY AUTOINCREMENT,
+ kind VARCHAR(64) NOT NULL,
+ subject VARCHAR(256) NOT NULL,
+ detail TEXT NOT NULL,
+ tt INTEGER NOT NULL
+)`,
+			},
+		},
+	},
+}
+
+// the table of the applied migrations, the statement is shared by the databases
+const createSchemaMigration = "create table if not exists schema_migration (version INTEGER NOT NULL, description VARCHAR(256) NOT NULL, tt INTEGER NOT NULL, PRIMARY KEY (version))"
+
+//SchemaVersion return the version of the latest migration applied to the database, 0 if none
+func SchemaVersion() (uint32, error) {
+	var version uint32
+	err := DefDB.QueryRow("select ifnull(max(version), 0) from schema_migration").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+//Migrate apply the migrations newer than the version of the database in order, each one in a transaction with the
+//record of its version. The operators sharing the database wait for the migration lock, so only the first one
+//migrates and the others find the schema up to date
+func Migrate() error {
+	_, err := DefDB.Exec(createSchemaMigration)
+	if err != nil {
+		return fmt.Errorf("create schema_migration error: %s", err)
+	}
+	lock := NewLeaderElector(DefDB, MIGRATION_LOCK)
+	deadline := time.Now().Add(MIGRATION_LOCK_TIMEOUT)
+	for !lock.Campaign() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("wait for the migration lock %s timeout", MIGRATION_LOCK)
+		}
+		time.Sleep(time.Second)
+	}
+	defer lock.Resign()
+	version, err := SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("load schema version error: %s", err)
+	}
+	for _, migration := range Migrations {
+		if migration.Version <= version {
+			continue
+		}
+		err = applyMigration(migration)
+		if err != nil {
+			return fmt.Errorf("migrate schema to version %d error: %s", migration.Version, err)
+		}
+		log.Infof("migrate - schema version %d: %s", migration.Version, migration.Description)
+		version = migration.Version
+	}
+	return nil
+}
+
+// mysql commits the alter and create statements implicitly, so a migration failing there is not rolled back and its
+// statements should be safe to run again
+func applyMigration(migration *Migration) error {
+	tx, dberr := DefDB.Begin()
+	if dberr != nil {
+		return dberr
+	}
+	for _, statement := range migration.statements(DefDialect) {
+		_, dberr = tx.Exec(statement)
+		if dberr != nil {
+			tx.Rollback()
+			return dberr
+		}
+	}
+	_, dberr = tx.Exec("insert into schema_migration(version, description, tt) values (?,?,?)",
+		migration.Version, migration.Description, time.Now().Unix())
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+)
+
+func TestMigrateSQLite(t *testing.T) {
+	connectTestDB(t)
+	// the records of the baseline tables are kept by the migrations
+	_, err := DefDB.Exec("insert into deposit(txhash, tt, state, height, fromaddress, amount, tokenaddress, id) values (?,?,?,?,?,?,?,?)",
+		"deposit", 1, DEPOSIT_EVENT, 10, "from", 100, "token", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DefDB.Exec("insert into layer2tx(txhash, state, tt, fee, height, fromaddress, tokenaddress, toaddress, amount) values (?,?,?,?,?,?,?,?,?)",
+		"transfer", 1, 1, 0, 20, "from", "token", "to", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Migrate()
+	if err != nil {
+		t.Fatalf("migrate err: %v", err)
+	}
+	latest := Migrations[len(Migrations) - 1].Version
+	version, err := SchemaVersion()
+	if err != nil || version != latest {
+		t.Fatalf("schema version %d, %v, want %d", version, err, latest)
+	}
+	deposit := LoadDepositByID(7)
+	if deposit == nil || deposit.TxHash != "deposit" || deposit.Amount != 100 || deposit.RefundTxHash != "" {
+		t.Errorf("deposit is not kept: %+v", deposit)
+	}
+	txs := LoadLayer2Tx("from")
+	if len(txs) != 1 || txs[0].TxHash != "transfer" || txs[0].Amount != 50 {
+		t.Errorf("layer2 tx is not kept: %v", txs)
+	}
+	if chain := LoadChainInfo("ethereum"); chain == nil {
+		t.Errorf("ethereum chain info is not added")
+	}
+
+	// the migrated database is up to date
+	err = Migrate()
+	if err != nil {
+		t.Fatalf("migrate again err: %v", err)
+	}
+	var count int
+	err = DefDB.QueryRow("select count(*) from schema_migration").Scan(&count)
+	if err != nil || count != len(Migrations) {
+		t.Errorf("%d migrations recorded, %v, want %d", count, err, len(Migrations))
+	}
+}
+
+func TestMigrationVersions(t *testing.T) {
+	for i, migration := range Migrations {
+		if migration.Version != uint32(i + 1) {
+			t.Errorf("migration %d has version %d", i, migration.Version)
+		}
+		if migration.Description == "" {
+			t.Errorf("migration %d has no description", migration.Version)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"github.com/ontio/ontology-crypto/keypair"
+	ontology_common "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/signature"
+	ontology_types "github.com/ontio/ontology/core/types"
+	"net"
+	"net/http"
+)
+
+const (
+	SIGN_STATE_PATH = "/api/v1/signstate"
+)
+
+//StateSignRequest ask a peer operator to sign the updateState transaction of the layer2 heights from FromHeight to
+//Height
+type StateSignRequest struct {
+	FromHeight      uint32
+	Height          uint32
+	Tx              string
+}
+
+type StateSignResponse struct {
+	PublicKey       string
+	Signature       string
+	Error           string
+}
+
+//multiSigner hold the M-of-N public keys of the operators, the layer2 state is committed by their multi-signature
+//address, so the contract accepts a state signed by M operators
+type multiSigner struct {
+	m               uint16
+	pubKeys         []keypair.PublicKey
+	address         ontology_common.Address
+	peers           []string
+	listenAddr      string
+	server          *http.Server
+}
+
+func newMultiSigner(cfg *config.MultiSigConfig, account *ontologySigner) (*multiSigner, error) {
+	pubKeys := make([]keypair.PublicKey, 0, len(cfg.PublicKeys))
+	found := false
+	for _, pubKeyHex := range cfg.PublicKeys {
+		data, err := hex.DecodeString(pubKeyHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multisig public key %s: %s", pubKeyHex, err)
+		}
+		pubKey, err := keypair.DeserializePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multisig public key %s: %s", pubKeyHex, err)
+		}
+		if keypair.ComparePublicKey(pubKey, account.PublicKey) {
+			found = true
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	if cfg.M == 0 || int(cfg.M) > len(pubKeys) {
+		return nil, fmt.Errorf("invalid multisig, m: %d, n: %d", cfg.M, len(pubKeys))
+	}
+	if !found {
+		return nil, fmt.Errorf("ontology account %s is not one of the multisig operators", account.Address.ToBase58())
+	}
+	address, err := ontology_types.AddressFromMultiPubKeys(pubKeys, int(cfg.M))
+	if err != nil {
+		return nil, fmt.Errorf("multisig address error: %s", err)
+	}
+	log.Infof("multisig - %d of %d operators, address: %s", cfg.M, len(pubKeys), address.ToBase58())
+	return &multiSigner{
+		m: cfg.M,
+		pubKeys: pubKeys,
+		address: address,
+		peers: cfg.Peers,
+		listenAddr: cfg.ListenAddr,
+	}, nil
+}
+
+//verify check the signature of the transaction hash is signed by one of the operators, and return the index of the
+//operator
+func (this *multiSigner) verify(hash ontology_common.Uint256, resp *StateSignResponse) (int, []byte, error) {
+	pubKeyData, err := hex.DecodeString(resp.PublicKey)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid public key: %s", err)
+	}
+	pubKey, err := keypair.DeserializePublicKey(pubKeyData)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid public key: %s", err)
+	}
+	sig, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid signature: %s", err)
+	}
+	for i, key := range this.pubKeys {
+		if !keypair.ComparePublicKey(key, pubKey) {
+			continue
+		}
+		err = signature.Verify(key, hash.ToArray(), sig)
+		if err != nil {
+			return 0, nil, fmt.Errorf("verify signature error: %s", err)
+		}
+		return i, sig, nil
+	}
+	return 0, nil, fmt.Errorf("public key %s is not one of the multisig operators", resp.PublicKey)
+}
+
+//startSignServer serve the signature requests of the peer operators, the standbys sign too as it only reads the
+//database and the layer2 node
+func (this *Layer2Operator) startSignServer() error {
+	if this.multiSig.listenAddr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", this.multiSig.listenAddr)
+	if err != nil {
+		return fmt.Errorf("multisig listen %s error: %s", this.multiSig.listenAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(SIGN_STATE_PATH, this.handleSignState)
+	this.multiSig.server = &http.Server{Handler: mux}
+	go this.multiSig.server.Serve(listener)
+	log.Infof("multisig - sign server started at %s", this.multiSig.listenAddr)
+	return nil
+}
+
+func (this *Layer2Operator) handleSignState(w http.ResponseWriter, r *http.Request) {
+	resp := &StateSignResponse{}
+	req := &StateSignRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %s", err)
+	} else {
+		resp, err = this.signLayer2Commit(req)
+		if err != nil {
+			log.Errorf("multisig - sign layer2 state of height %d err: %v", req.Height, err)
+			resp = &StateSignResponse{Error: err.Error()}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//signLayer2Commit sign the updateState transaction of a peer only if it is the same as the transaction built from
+//the commit msg parsed by this operator, so a state or a withdraw unknown to the local layer2 node is never signed
+func (this *Layer2Operator) signLayer2Commit(req *StateSignRequest) (*StateSignResponse, error) {
+	raw, err := hex.DecodeString(req.Tx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction: %s", err)
+	}
+	tx, err := ontology_types.TransactionFromRawBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction: %s", err)
+	}
+	mutable, err := tx.IntoMutable()
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction: %s", err)
+	}
+	if mutable.Payer != this.multiSig.address {
+		return nil, fmt.Errorf("payer %s is not the multisig address", mutable.Payer.ToBase58())
+	}
+	if maxGasPrice := this.config().OntologyConfig.MaxGasPrice; maxGasPrice > 0 && mutable.GasPrice > maxGasPrice {
+		return nil, fmt.Errorf("gas price %d exceeds the max gas price %d", mutable.GasPrice, maxGasPrice)
+	}
+	fromHeight := req.FromHeight
+	if fromHeight == 0 {
+		fromHeight = req.Height
+	}
+	if fromHeight > req.Height || int(req.Height - fromHeight) >= this.commitBatchSize() {
+		return nil, fmt.Errorf("invalid layer2 heights from %d to %d", fromHeight, req.Height)
+	}
+	msgs := make([]*Layer2CommitMsg, 0)
+	for height := fromHeight; height <= req.Height; height ++ {
+		job := LoadJob(JOB_COMMIT, uint64(height))
+		if job == nil {
+			return nil, fmt.Errorf("layer2 state of height %d is not parsed yet", height)
+		}
+		msg := &Layer2CommitMsg{}
+		err = json.Unmarshal([]byte(job.Payload), msg)
+		if err != nil || msg.Layer2State == nil {
+			return nil, fmt.Errorf("parse layer2 commit job %d err: %v", job.ID, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	params, err := this.layer2CommitParams(mergeLayer2CommitMsgs(msgs))
+	if err != nil {
+		return nil, err
+	}
+	expected, err := this.ontologySdk.NeoVM.NewNeoVMInvokeTransaction(mutable.GasPrice, mutable.GasLimit, this.l1.(*ontologyBackend).contract, params)
+	if err != nil {
+		return nil, fmt.Errorf("new layer2 state commit transaction failed! err: %s", err.Error())
+	}
+	expected.Nonce = mutable.Nonce
+	expected.Payer = mutable.Payer
+	hash := mutable.Hash()
+	if expected.Hash() != hash {
+		return nil, fmt.Errorf("commit transaction of height %d is not the same as the local layer2 state", req.Height)
+	}
+	sig, err := this.ontologyAccount.Sign(hash.ToArray())
+	if err != nil {
+		return nil, err
+	}
+	return &StateSignResponse{
+		PublicKey: hex.EncodeToString(keypair.SerializePublicKey(this.ontologyAccount.PublicKey)),
+		Signature: hex.EncodeToString(sig),
+	}, nil
+}
+
+//multiSignCommit send the updateState transaction of the layer2 heights from fromHeight to height signed by the
+//operators, the multisig is only supported by the ontology L1
+func (this *Layer2Operator) multiSignCommit(params []interface{}, fromHeight uint32, height uint32) (string, error) {
+	return this.l1.(*ontologyBackend).commitSignedBy(this.multiSig.address.ToBase58(), params, func(tx *ontology_types.MutableTransaction) error {
+		return this.multiSignLayer2Commit(tx, fromHeight, height)
+	})
+}
+
+//multiSignLayer2Commit sign the updateState transaction of the layer2 heights from fromHeight to height by this
+//operator and gather the signatures of the peers until M operators signed
+func (this *Layer2Operator) multiSignLayer2Commit(tx *ontology_types.MutableTransaction, fromHeight uint32, height uint32) error {
+	tx.Payer = this.multiSig.address
+	hash := tx.Hash()
+	unsigned, err := tx.IntoImmutable()
+	if err != nil {
+		return err
+	}
+	signed := make([]bool, len(this.multiSig.pubKeys))
+	sigs := make([][]byte, 0, this.multiSig.m)
+	sig, err := this.ontologyAccount.Sign(hash.ToArray())
+	if err != nil {
+		return err
+	}
+	for i, key := range this.multiSig.pubKeys {
+		if keypair.ComparePublicKey(key, this.ontologyAccount.PublicKey) {
+			signed[i] = true
+		}
+	}
+	sigs = append(sigs, sig)
+
+	data, err := json.Marshal(&StateSignRequest{
+		FromHeight: fromHeight,
+		Height: height,
+		Tx: hex.EncodeToString(unsigned.ToArray()),
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: config.MULTISIG_REQUEST_TIMEOUT}
+	for _, peer := range this.multiSig.peers {
+		if len(sigs) >= int(this.multiSig.m) {
+			break
+		}
+		resp, err := requestStateSignature(client, peer, data)
+		if err != nil {
+			log.Errorf("multisig - request signature of height %d from %s err: %v", height, peer, err)
+			continue
+		}
+		index, sig, err := this.multiSig.verify(hash, resp)
+		if err != nil {
+			log.Errorf("multisig - signature of height %d from %s err: %v", height, peer, err)
+			continue
+		}
+		if signed[index] {
+			continue
+		}
+		signed[index] = true
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) < int(this.multiSig.m) {
+		return fmt.Errorf("not enough signatures of layer2 state %d, %d of %d", height, len(sigs), this.multiSig.m)
+	}
+	tx.Sigs = []ontology_types.Sig{{
+		PubKeys: this.multiSig.pubKeys,
+		M: this.multiSig.m,
+		SigData: sigs,
+	}}
+	return nil
+}
+
+func requestStateSignature(client *http.Client, peer string, data []byte) (*StateSignResponse, error) {
+	httpResp, err := client.Post(peer + SIGN_STATE_PATH, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	resp := &StateSignResponse{}
+	err = json.NewDecoder(httpResp.Body).Decode(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	return resp, nil
+}
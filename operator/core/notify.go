@@ -0,0 +1,268 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	ALERT_COMMIT_FAILED      = "commit_failed"
+	ALERT_DEPOSIT_FAILED     = "deposit_failed"
+	ALERT_STATE_MISMATCH     = "state_mismatch"
+	ALERT_DEPOSIT_STUCK      = "deposit_stuck"
+	ALERT_LOW_GAS_BALANCE    = "low_gas_balance"
+	ALERT_LOW_LAYER2_BALANCE = "low_layer2_balance"
+
+	// the alerts waiting for the sinks, the alerts are dropped once it is full
+	NOTIFY_QUEUE_SIZE = 100
+)
+
+//Alert is an anomaly of the bridge, the alerts of the same Kind and Subject are of the same anomaly
+type Alert struct {
+	Kind      string
+	Subject   string
+	Message   string
+	TT        uint32
+}
+
+//AlertSink deliver the alerts, the sinks of NotifyConfig are built in, and more sinks are plugged in by AddAlertSink
+type AlertSink interface {
+	Name() string
+	Send(alert *Alert) error
+}
+
+//notifier queue the alerts for notifyLoop, the alert of the same anomaly is dropped in the cooldown
+type notifier struct {
+	alerts    chan *Alert
+	mu        sync.Mutex
+	sinks     []AlertSink
+	sent      map[string]time.Time
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		alerts: make(chan *Alert, NOTIFY_QUEUE_SIZE),
+		sent:   make(map[string]time.Time),
+	}
+}
+
+//AddAlertSink add the sink receiving the alerts besides the sinks of NotifyConfig
+func (this *Layer2Operator) AddAlertSink(sink AlertSink) {
+	this.notifier.mu.Lock()
+	defer this.notifier.mu.Unlock()
+	this.notifier.sinks = append(this.notifier.sinks, sink)
+}
+
+//alertSinks return the sinks of the current NotifyConfig and the added sinks
+func (this *Layer2Operator) alertSinks() []AlertSink {
+	this.notifier.mu.Lock()
+	sinks := append([]AlertSink{}, this.notifier.sinks...)
+	this.notifier.mu.Unlock()
+	notifyConfig := this.config().NotifyConfig
+	if notifyConfig == nil {
+		return sinks
+	}
+	client := &http.Client{Timeout: config.NOTIFY_REQUEST_TIMEOUT}
+	if notifyConfig.WebhookURL != "" {
+		sinks = append(sinks, &webhookSink{url: notifyConfig.WebhookURL, client: client})
+	}
+	if notifyConfig.SlackWebhookURL != "" {
+		sinks = append(sinks, &slackSink{url: notifyConfig.SlackWebhookURL, client: client})
+	}
+	if notifyConfig.Email != nil {
+		sinks = append(sinks, &emailSink{cfg: notifyConfig.Email})
+	}
+	return sinks
+}
+
+//notify queue the alert of the anomaly of kind and subject, unless it is alerted in the cooldown or there is no sink
+func (this *Layer2Operator) notify(kind string, subject string, format string, args ...interface{}) {
+	notifyConfig := this.config().NotifyConfig
+	cooldown := config.DEFAULT_NOTIFY_COOLDOWN
+	if notifyConfig != nil {
+		cooldown = notifyConfig.CooldownOrDefault()
+	}
+	this.notifier.mu.Lock()
+	if notifyConfig == nil && len(this.notifier.sinks) == 0 {
+		this.notifier.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	key := kind + "/" + subject
+	if last, ok := this.notifier.sent[key]; ok && now.Sub(last) < cooldown {
+		this.notifier.mu.Unlock()
+		return
+	}
+	for sentKey, last := range this.notifier.sent {
+		if now.Sub(last) >= cooldown {
+			delete(this.notifier.sent, sentKey)
+		}
+	}
+	this.notifier.sent[key] = now
+	this.notifier.mu.Unlock()
+	alert := &Alert{
+		Kind:    kind,
+		Subject: subject,
+		Message: fmt.Sprintf(format, args...),
+		TT:      uint32(now.Unix()),
+	}
+	select {
+	case this.notifier.alerts <- alert:
+	default:
+		log.Errorf("notify - queue is full, drop alert %s: %s", kind, alert.Message)
+	}
+}
+
+//notifyLoop send the queued alerts to every sink, the alert failed to send is logged and not sent again
+func (this *Layer2Operator) notifyLoop() {
+	log.Infof("start notifyLoop")
+	for true {
+		var alert *Alert
+		select {
+		case <- this.ctx.Done():
+			log.Infof("notifyLoop exit")
+			return
+		case alert = <- this.notifier.alerts:
+		}
+		for _, sink := range this.alertSinks() {
+			err := sink.Send(alert)
+			if err != nil {
+				log.Errorf("notify - send alert %s to %s err: %v", alert.Kind, sink.Name(), err)
+			}
+		}
+	}
+}
+
+//anomalyLoop check the stuck deposits and the balances of the operator accounts every NOTIFY_CHECK_INTERVAL
+func (this *Layer2Operator) anomalyLoop() {
+	log.Infof("start anomalyLoop")
+	for true {
+		select {
+		case <- this.ctx.Done():
+			log.Infof("anomalyLoop exit")
+			return
+		case <- time.After(config.NOTIFY_CHECK_INTERVAL):
+		}
+		notifyConfig := this.config().NotifyConfig
+		if notifyConfig == nil || !this.isLeading() {
+			continue
+		}
+		// the deposits are never minted in the dry run
+		if !this.dryRun() {
+			this.checkStuckDeposits(notifyConfig.DepositTimeoutOrDefault())
+		}
+		if notifyConfig.MinGasBalance > 0 {
+			this.checkGasBalance(notifyConfig.MinGasBalance)
+		}
+		if notifyConfig.MinLayer2Balance > 0 {
+			this.checkLayer2Balance(notifyConfig.MinLayer2Balance)
+		}
+	}
+}
+
+//checkStuckDeposits alert the deposits not committed to layer2 in timeout since the L1 block of the deposit
+func (this *Layer2Operator) checkStuckDeposits(timeout time.Duration) {
+	now := time.Now()
+	for _, deposit := range LoadDepositsByState(DEPOSIT_EVENT) {
+		elapsed := now.Sub(time.Unix(int64(deposit.TT), 0))
+		if elapsed < timeout {
+			continue
+		}
+		this.notify(ALERT_DEPOSIT_STUCK, fmt.Sprintf("%d", deposit.ID), "deposit %d of tx %s is not committed to layer2 in %s",
+			deposit.ID, deposit.TxHash, elapsed.Truncate(time.Second))
+	}
+}
+
+//webhookSink post the alert in json to the url
+type webhookSink struct {
+	url       string
+	client    *http.Client
+}
+
+func (this *webhookSink) Name() string {
+	return "webhook"
+}
+
+func (this *webhookSink) Send(alert *Alert) error {
+	return postJson(this.client, this.url, alert)
+}
+
+//slackSink post the alert as the message of the slack incoming webhook of the url
+type slackSink struct {
+	url       string
+	client    *http.Client
+}
+
+func (this *slackSink) Name() string {
+	return "slack"
+}
+
+func (this *slackSink) Send(alert *Alert) error {
+	return postJson(this.client, this.url, map[string]string{
+		"text": fmt.Sprintf("[layer2 operator] %s: %s", alert.Kind, alert.Message),
+	})
+}
+
+//emailSink mail the alert by the smtp server
+type emailSink struct {
+	cfg       *config.EmailConfig
+}
+
+func (this *emailSink) Name() string {
+	return "email"
+}
+
+func (this *emailSink) Send(alert *Alert) error {
+	var auth smtp.Auth
+	if this.cfg.Username != "" {
+		host := strings.Split(this.cfg.SMTPAddr, ":")[0]
+		auth = smtp.PlainAuth("", this.cfg.Username, this.cfg.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [layer2 operator] %s\r\n\r\n%s\r\n",
+		this.cfg.From, strings.Join(this.cfg.To, ", "), alert.Kind, alert.Message)
+	return smtp.SendMail(this.cfg.SMTPAddr, auth, this.cfg.From, this.cfg.To, []byte(msg))
+}
+
+func postJson(client *http.Client, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("response status %s", resp.Status)
+	}
+	return nil
+}
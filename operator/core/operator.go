@@ -20,83 +20,154 @@
 package core
 
 import (
+	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	layer2_sdk "github.com/ontio/layer2/go-sdk"
 	layer2_common "github.com/ontio/layer2/node/common"
 	layer2_types "github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/operator/bridge"
 	"github.com/ontio/layer2/operator/config"
 	"github.com/ontio/layer2/operator/log"
 	ontology_sdk "github.com/ontio/ontology-go-sdk"
-	ontology_sdk_common "github.com/ontio/ontology-go-sdk/common"
 	ontology_common "github.com/ontio/ontology/common"
-	"math/rand"
+	ontology_types "github.com/ontio/ontology/core/types"
+	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Layer2Operator struct {
-	config             *config.ServiceConfig
+	// the config and the layer2 sdk are replaced on reload
+	configValue        atomic.Value
+	configLoader       func() (*config.ServiceConfig, error)
+	reloadLock         sync.Mutex
+	bridge             bridge.Bridge
 
+	l1                 L1Backend
+	l1ChainInfo        *ChainInfo
 	ontologySdk        *ontology_sdk.OntologySdk
-	ontologyAccount    *ontology_sdk.Account
-	ontologyChainInfo  *ChainInfo
+	ontologyAccount    *ontologySigner
 
-	layer2Sdk          *layer2_sdk.OntologySdk
-	layer2Account      *layer2_sdk.Account
+	layer2SdkValue     atomic.Value
+	layer2Account      *layer2Signer
 	layer2ChainInfo    *ChainInfo
+	// the blocks pushed by the websocket of the layer2 node, nil if WebSocketURL is not set
+	layer2Subscription *blockSubscription
 
-	depositChain        chan *Deposit
-	msgChan             chan *Layer2CommitMsg
-	exitChan            chan int
+	tokens             *tokenRegistry
+	retry              *retryPolicy
+	submit             *submitQueue
+	metrics            *operatorMetrics
+	notifier           *notifier
+	tracer             *tracer
+	// the blocks fetched ahead of the leader when standby
+	standby            *standbyCache
+
+	depositNotify       chan struct{}
+	commitNotify        chan struct{}
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	drain               context.Context
+	abort               context.CancelFunc
+	loops               sync.WaitGroup
+	jobLoops            sync.WaitGroup
+	drained             chan struct{}
 	mu                  sync.Mutex
 	needCheck           bool
-
-	// use for test
-	fortest              int
-	deposit              int
-	withdraw             int
-	depositHeight        uint32
+	elector             *LeaderElector
+	multiSig            *multiSigner
+	adminServer         *http.Server
+	metricsServer       *http.Server
+	healthServer        *http.Server
+	leading             int32
 }
 
 func NewLayer2Operator(servCfg *config.ServiceConfig) (*Layer2Operator, error) {
+	err := SetAssets(servCfg.AssetConfig)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("native assets of network %s, ONT: %s, ONG: %s, collector: %s", assets.network, assets.ont, assets.ong,
+		assets.collector.ToBase58())
 	ontologySdk := ontology_sdk.NewOntologySdk()
-	ontologySdk.NewRpcClient().SetAddress(servCfg.OntologyConfig.RestURL)
+	if servCfg.OntologyConfig != nil {
+		ontologySdk.NewRpcClient().SetAddress(servCfg.OntologyConfig.RestURL)
+	}
 	layer2Sdk := layer2_sdk.NewOntologySdk()
 	layer2Sdk.NewRpcClient().SetAddress(servCfg.Layer2Config.RestURL)
-	return &Layer2Operator{
-		exitChan:           make(chan int),
-		depositChain:       make(chan *Deposit),
-		msgChan:            make(chan *Layer2CommitMsg),
-		config:             servCfg,
+	operatorMetrics := newOperatorMetrics(servCfg.MetricsConfig != nil)
+	// ctx stops taking new work on shutdown, and drain aborts the work still in flight after the shutdown timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	drain, abort := context.WithCancel(context.Background())
+	operator := &Layer2Operator{
+		ctx:                ctx,
+		cancel:             cancel,
+		drain:              drain,
+		abort:              abort,
+		drained:            make(chan struct{}),
+		depositNotify:      make(chan struct{}, 1),
+		commitNotify:       make(chan struct{}, 1),
 		ontologySdk:        ontologySdk,
-		layer2Sdk:          layer2Sdk,
+		tokens:             newTokenRegistry(),
+		retry:              newRetryPolicy(servCfg.RetryConfig, operatorMetrics.retries, drain.Done()),
+		submit:             newSubmitQueue(servCfg.SubmitConfig, drain.Done()),
+		metrics:            operatorMetrics,
+		notifier:           newNotifier(),
+		tracer:             newTracer(),
+		standby:            newStandbyCache(),
 		needCheck:          false,
-		fortest:            0,
-		deposit:            0,
-		withdraw:           0,
-		depositHeight:      0,
-	}, nil
+	}
+	operator.configValue.Store(servCfg)
+	operator.layer2SdkValue.Store(layer2Sdk)
+	if servCfg.Layer2Config.WebSocketURL != "" {
+		operator.layer2Subscription = newBlockSubscription("layer2", servCfg.Layer2Config.WebSocketURL, newLayer2Subscriber())
+	}
+	return operator, nil
+}
+
+//NewLayer2OperatorWithBackend create the operator on the given L1 instead of the L1 of the config, for example a
+//mock chain in tests
+func NewLayer2OperatorWithBackend(servCfg *config.ServiceConfig, l1 L1Backend) (*Layer2Operator, error) {
+	operator, err := NewLayer2Operator(servCfg)
+	if err != nil {
+		return nil, err
+	}
+	operator.l1 = l1
+	return operator, nil
 }
 
-func (this *Layer2Operator) getOntologyAccount() (*ontology_sdk.Account, error) {
+//getOntologyAccount return the account of the remote signer if it is configured, or the default account of the wallet
+func (this *Layer2Operator) getOntologyAccount(account *config.AccountConfig) (*ontologySigner, error) {
+	if cfg := account.Signer; cfg != nil {
+		remote, err := newRemoteSigner(cfg)
+		if err != nil {
+			return nil, err
+		}
+		address := ontology_types.AddressFromPubKey(remote.GetPublicKey())
+		log.Infof("ontologyAccount - signer: %s, ont account address: %s, %s", cfg.URL, address.ToBase58(), address.ToHexString())
+		return &ontologySigner{remote, address, remote.GetPublicKey()}, nil
+	}
 	var wallet *ontology_sdk.Wallet
 	var err error
-	if !ontology_common.FileExisted(this.config.OntologyConfig.WalletFile) {
-		wallet, err = this.ontologySdk.CreateWallet(this.config.OntologyConfig.WalletFile)
+	if !ontology_common.FileExisted(account.WalletFile) {
+		wallet, err = this.ontologySdk.CreateWallet(account.WalletFile)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		wallet, err = this.ontologySdk.OpenWallet(this.config.OntologyConfig.WalletFile)
+		wallet, err = this.ontologySdk.OpenWallet(account.WalletFile)
 		if err != nil {
 			log.Errorf("ontologyAccount - wallet open error: %s", err.Error())
 			return nil, err
 		}
 	}
-	signer, err := wallet.GetDefaultAccount([]byte(this.config.OntologyConfig.WalletPwd))
+	signer, err := wallet.GetDefaultAccount([]byte(account.WalletPwd))
 	if err != nil || signer == nil {
-		signer, err = wallet.NewDefaultSettingAccount([]byte(this.config.OntologyConfig.WalletPwd))
+		signer, err = wallet.NewDefaultSettingAccount([]byte(account.WalletPwd))
 		if err != nil {
 			log.Errorf("ontologyAccount - wallet password error")
 			return nil, err
@@ -108,27 +179,37 @@ func (this *Layer2Operator) getOntologyAccount() (*ontology_sdk.Account, error)
 		}
 	}
 	log.Infof("ontologyAccount - ont account address: %s, %s", signer.Address.ToBase58(), signer.Address.ToHexString())
-	return signer, nil
+	return &ontologySigner{signer, signer.Address, signer.PublicKey}, nil
 }
 
-func (this *Layer2Operator) getLyer2Account() (*layer2_sdk.Account, error) {
+//getLyer2Account return the layer2 account as getOntologyAccount
+func (this *Layer2Operator) getLyer2Account(account *config.AccountConfig) (*layer2Signer, error) {
+	if cfg := account.Signer; cfg != nil {
+		remote, err := newRemoteSigner(cfg)
+		if err != nil {
+			return nil, err
+		}
+		address := layer2_types.AddressFromPubKey(remote.GetPublicKey())
+		log.Infof("layer2Account - signer: %s, layer2 account address: %s, %s", cfg.URL, address.ToBase58(), address.ToHexString())
+		return &layer2Signer{remote, address, remote.GetPublicKey()}, nil
+	}
 	var wallet *layer2_sdk.Wallet
 	var err error
-	if !layer2_common.FileExisted(this.config.Layer2Config.WalletFile) {
-		wallet, err = this.layer2Sdk.CreateWallet(this.config.Layer2Config.WalletFile)
+	if !layer2_common.FileExisted(account.WalletFile) {
+		wallet, err = this.layer2Sdk().CreateWallet(account.WalletFile)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		wallet, err = this.layer2Sdk.OpenWallet(this.config.Layer2Config.WalletFile)
+		wallet, err = this.layer2Sdk().OpenWallet(account.WalletFile)
 		if err != nil {
 			log.Errorf("layer2Account - wallet open error: %s", err.Error())
 			return nil, err
 		}
 	}
-	signer, err := wallet.GetDefaultAccount([]byte(this.config.Layer2Config.WalletPwd))
+	signer, err := wallet.GetDefaultAccount([]byte(account.WalletPwd))
 	if err != nil || signer == nil {
-		signer, err = wallet.NewDefaultSettingAccount([]byte(this.config.Layer2Config.WalletPwd))
+		signer, err = wallet.NewDefaultSettingAccount([]byte(account.WalletPwd))
 		if err != nil {
 			log.Errorf("layer2Account - wallet password error")
 			return nil, err
@@ -140,55 +221,100 @@ func (this *Layer2Operator) getLyer2Account() (*layer2_sdk.Account, error) {
 		}
 	}
 	log.Infof("layer2Account - layer2 account address: %s, %s", signer.Address.ToBase58(), signer.Address.ToHexString())
-	return signer, nil
+	return &layer2Signer{signer, signer.Address, signer.PublicKey}, nil
 }
 
 func (this *Layer2Operator) Start() error {
 	// try to connect db
-	dberr := ConnectDB(this.config.DBConfig.ProjectDBUser, this.config.DBConfig.ProjectDBPassword, this.config.DBConfig.ProjectDBUrl, this.config.DBConfig.ProjectDBName)
+	dberr := ConnectDB(this.config().DBConfig)
 	if dberr != nil {
 		return fmt.Errorf(dberr.Error())
 	}
+	dberr = Migrate()
+	if dberr != nil {
+		return dberr
+	}
+	err := this.tokens.init(this.config().Tokens)
+	if err != nil {
+		return err
+	}
+
+	if this.l1 == nil {
+		this.l1, err = this.newL1Backend()
+		if err != nil {
+			return err
+		}
+	}
+	this.bridge = this.l1.Bridge()
 
 	//  try to load all chains
-	ontologyChain := LoadChainInfo("ontology")
-	if ontologyChain == nil {
-		return fmt.Errorf("load multichain info error")
+	l1Chain := LoadChainInfo(this.l1.Name())
+	if l1Chain == nil {
+		return fmt.Errorf("load %s chain info error", this.l1.Name())
 	}
-	this.ontologyChainInfo = ontologyChain
+	this.l1ChainInfo = l1Chain
 
 	layer2Chain := LoadChainInfo("layer2")
 	if layer2Chain == nil {
-		return fmt.Errorf("load ontology chain info error")
+		return fmt.Errorf("load layer2 chain info error")
 	}
 	this.layer2ChainInfo = layer2Chain
 	
-	ontologyAccount, err := this.getOntologyAccount()
+	layer2Account, err := this.getLyer2Account(this.config().Layer2Config.Account())
 	if err != nil {
 		return err
 	}
-	layer2Account, err := this.getLyer2Account()
+	this.layer2Account = layer2Account
+	err = this.applyKeyRotation()
+	if err != nil {
+		return err
+	}
+
+	if this.config().MultiSigConfig != nil {
+		if _, ok := this.l1.(*ontologyBackend); !ok {
+			return fmt.Errorf("multisig is only supported by the ontology L1")
+		}
+		multiSig, err := newMultiSigner(this.config().MultiSigConfig, this.ontologyAccount)
+		if err != nil {
+			return err
+		}
+		this.multiSig = multiSig
+		// the dry run signs no commit of the other operators
+		if !this.dryRun() {
+			err = this.startSignServer()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = this.startAdminServer()
+	if err != nil {
+		return err
+	}
+	err = this.startMetricsServer()
+	if err != nil {
+		return err
+	}
+	err = this.startHealthServer()
 	if err != nil {
 		return err
 	}
-	this.ontologyAccount = ontologyAccount
-	this.layer2Account = layer2Account
 
 	//
 	{
-		currentHeight, err := this.ontologySdk.GetCurrentBlockHeight()
+		currentHeight, err := this.l1.GetHeight()
 		if err != nil {
-			log.Errorf("get ontology current block heigh err: %s", err.Error())
+			log.Errorf("get %s current block heigh err: %s", this.l1.Name(), err.Error())
 		} else {
-			if this.ontologyChainInfo.Height <= 0 {
-				this.ontologyChainInfo.Height = currentHeight
+			if this.l1ChainInfo.Height <= 0 {
+				this.l1ChainInfo.Height = currentHeight
 			}
 		}
-		log.Infof("ontology current height: %d", this.ontologyChainInfo.Height)
+		log.Infof("%s current height: %d", this.l1.Name(), this.l1ChainInfo.Height)
 	}
 	/*
 	{
-		currentHeight, err := this.layer2Sdk.GetCurrentBlockHeight()
+		currentHeight, err := this.layer2Sdk().GetCurrentBlockHeight()
 		if err != nil {
 			fmt.Println(err)
 		} else {
@@ -198,326 +324,960 @@ func (this *Layer2Operator) Start() error {
 		}
 	}
 	 */
-	{
-		currentHeight := GetLayer2CommitHeight()
-		// check if next block commit
-		exit, _ := this.checkLayer2StateByHeight(uint64(currentHeight + 1))
-		if exit {
-			formatStr := "2006-01-02 15:04:05"
-			timehash := time.Now().Format(formatStr)
-			SaveLayer2Commit(timehash, "", uint64(currentHeight + 1))
-			UpdateLayer2Commit(timehash, uint64(currentHeight + 1), LAYER2MSG_FINISH)
-			currentHeight = currentHeight + 1
-		}
-		this.layer2ChainInfo.Height = currentHeight
-		log.Infof("layer2 current height: %d", this.layer2ChainInfo.Height)
-	}
-
-	go this.MonitorOntologyChain()
-	go this.MonitorLayer2Chain()
-	go this.depositLoop()
-	go this.commitMsgLoop()
-	go this.checkMsgLoop()
-	if this.fortest == 1 {
-		go this.testLoop()
+	if this.config().LeaderLock == "" {
+		err = this.recover()
+		if err != nil {
+			return err
+		}
+		this.setLeading(true)
+	} else {
+		if _, ok := DefDialect.(*sqliteDialect); ok {
+			return fmt.Errorf("leader lock %s is not supported by sqlite", this.config().LeaderLock)
+		}
+		this.elector = NewLeaderElector(DefDB, this.config().LeaderLock)
+		this.goLoop(this.electionLoop)
+	}
+
+	this.goLoop(this.MonitorL1Chain)
+	this.goLoop(this.MonitorLayer2Chain)
+	this.goJobLoop(this.depositLoop)
+	this.goJobLoop(this.commitMsgLoop)
+	this.goLoop(this.checkMsgLoop)
+	this.goLoop(this.notifyLoop)
+	this.goLoop(this.anomalyLoop)
+	this.goLoop(this.traceLoop)
+	if this.config().MassExitConfig != nil {
+		this.goLoop(this.massExitLoop)
+	}
+	if this.dryRun() {
+		// the exits, challenges and refunds are not handled by the dry run, which sends no transaction
+		log.Warnf("dry run - the deposit mints and state commits are recorded in the database instead of sent")
+		return nil
+	}
+	this.goLoop(this.exitLoop)
+	if this.config().ChallengerConfig != nil {
+		this.goLoop(this.challengeLoop)
+	}
+	// the multisig operator address can not sign the refund alone
+	if this.multiSig == nil {
+		this.goLoop(this.refundLoop)
+	}
+	if this.config().TrafficConfig != nil {
+		log.Warnf("traffic - the fake deposits and withdrawals are generated, never enable it on a live network")
+		this.goLoop(this.trafficLoop)
 	}
 	return nil
 }
 
-func (this *Layer2Operator) Stop() {
-	this.exitChan <- 1
-	this.exitChan <- 1
-	close(this.exitChan)
-	log.Infof("multi chain manager exit.")
+func (this *Layer2Operator) isLeading() bool {
+	return atomic.LoadInt32(&this.leading) == 1
 }
 
-func (this *Layer2Operator) MonitorOntologyChain() {
-	log.Infof("start MonitorOntologyChain")
+func (this *Layer2Operator) setLeading(leading bool) {
+	if leading {
+		atomic.StoreInt32(&this.leading, 1)
+	} else {
+		atomic.StoreInt32(&this.leading, 0)
+	}
+}
+
+//electionLoop campaign for the leader every LEADER_ELECTION_INTERVAL. Only the leader saves the parsed blocks and
+//submits transactions, the standbys fetch the blocks ahead of the leader into memory. The instance becoming leader
+//reloads the parse progress and runs the recovery first, and stops submitting once the lock is lost
+func (this *Layer2Operator) electionLoop() {
+	log.Infof("start electionLoop, leader lock: %s", this.config().LeaderLock)
+	updateTicker := time.NewTicker(config.LEADER_ELECTION_INTERVAL)
+	for {
+		leader := this.elector.Campaign()
+		if leader && !this.isLeading() {
+			err := this.takeOver()
+			if err != nil {
+				log.Errorf("take over as leader err: %v", err)
+				this.elector.Resign()
+			} else {
+				this.setLeading(true)
+				log.Infof("become leader")
+			}
+		} else if !leader && this.isLeading() {
+			this.setLeading(false)
+			log.Errorf("leader lock is lost, switch to standby")
+		}
+		select {
+		case <- updateTicker.C:
+		case <- this.ctx.Done():
+			updateTicker.Stop()
+			// keep the lock until the work in flight is drained, so that another operator does not take it over
+			<- this.drained
+			this.setLeading(false)
+			this.elector.Resign()
+			log.Infof("electionLoop exit!")
+			return
+		}
+	}
+}
+
+//takeOver reload the parse heights saved by the previous leader and reconcile the database with the chains
+func (this *Layer2Operator) takeOver() error {
+	l1Chain := LoadChainInfo(this.l1.Name())
+	if l1Chain == nil {
+		return fmt.Errorf("load %s chain info error", this.l1.Name())
+	}
+	if l1Chain.Height > 0 {
+		this.l1ChainInfo.Height = l1Chain.Height
+	}
+	// the registry may be changed by the admin api of the previous leader
+	err := this.tokens.load()
+	if err != nil {
+		return err
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.recover()
+}
+
+//MonitorL1Chain parse the L1 blocks every second, or when a block is pushed by the L1 node. The node pushing the
+//blocks is only polled every SUBSCRIBED_POLL_INTERVAL in case a push is missed
+func (this *Layer2Operator) MonitorL1Chain() {
+	log.Infof("start MonitorL1Chain, L1: %s", this.l1.Name())
 	updateTicker := time.NewTicker(time.Second * 1)
+	lastPoll := time.Time{}
 	for {
 		select {
 		case <- updateTicker.C:
-			currentHeight, err := this.ontologySdk.GetCurrentBlockHeight()
-			if err != nil {
-				log.Errorf("get ontology chain current height err: %s", err.Error())
-				continue
-			}
-			log.Infof("chain %s current height: %d, parser height: %d", this.ontologyChainInfo.Name, currentHeight, this.ontologyChainInfo.Height)
-			if currentHeight <= this.ontologyChainInfo.Height {
+			if this.l1.NewBlocks() != nil && time.Since(lastPoll) < SUBSCRIBED_POLL_INTERVAL {
 				continue
 			}
-			for currentHeight > this.ontologyChainInfo.Height {
-				this.ontologyChainInfo.Height ++
-				err = this.parseOntologyChainBlock(this.ontologyChainInfo)
-				if err != nil {
-					log.Errorf("parse ontology chain block err: %s", err.Error())
-					this.ontologyChainInfo.Height --
-					break
-				}
-				SetChainParseHeight(this.ontologyChainInfo.Id, this.ontologyChainInfo.Height)
-			}
-		case <- this.exitChan:
+		case <- this.l1.NewBlocks():
+		case <- this.ctx.Done():
 			updateTicker.Stop()
-			log.Infof("chain %s, exit!", this.ontologyChainInfo.Name)
+			log.Infof("chain %s, exit!", this.l1ChainInfo.Name)
 			return
 		}
+		lastPoll = time.Now()
+		this.pollL1Chain()
 	}
 }
 
-func (this *Layer2Operator) parseOntologyChainBlock(chain *ChainInfo) error {
-	block, err := this.ontologySdk.GetBlockByHeight(chain.Height)
+//pollL1Chain parse the confirmed L1 blocks above the parser height
+func (this *Layer2Operator) pollL1Chain() {
+	currentHeight, err := this.l1.GetHeight()
+	if err != nil {
+		log.Errorf("get %s chain current height err: %s", this.l1.Name(), err.Error())
+		return
+	}
+	this.metrics.l1Height.Update(int64(currentHeight))
+	if !this.isLeading() {
+		this.followL1Chain(currentHeight)
+		return
+	}
+	this.checkL1Reorg(currentHeight)
+	// the deposits are only acted on once the block is DepositConfirmations blocks deep
+	confirmedHeight := this.l1ConfirmedHeight(currentHeight)
+	chainLog(this.l1ChainInfo).Infof("chain %s current height: %d, confirmed height: %d, parser height: %d", this.l1ChainInfo.Name, currentHeight, confirmedHeight, this.l1ChainInfo.Height)
+	if confirmedHeight <= this.l1ChainInfo.Height {
+		return
+	}
+	for confirmedHeight > this.l1ChainInfo.Height && this.isLeading() && !this.stopping() {
+		this.l1ChainInfo.Height ++
+		err = this.parseL1ChainBlock(this.l1ChainInfo)
+		if err != nil {
+			log.Errorf("parse %s chain block err: %s", this.l1.Name(), err.Error())
+			this.l1ChainInfo.Height --
+			break
+		}
+		SetChainParseHeight(this.l1ChainInfo.Id, this.l1ChainInfo.Height)
+	}
+}
+
+//l1ConfirmedHeight return the last L1 height with at least DepositConfirmations blocks on top of it
+func (this *Layer2Operator) l1ConfirmedHeight(currentHeight uint32) uint32 {
+	confirmations := this.config().DepositConfirmations()
+	if currentHeight < confirmations {
+		return 0
+	}
+	return currentHeight - confirmations
+}
+
+//checkL1Reorg compare the hash of the parsed L1 blocks with the chain from the parser height down, and rewind the
+//parser to the highest block still on the chain
+func (this *Layer2Operator) checkL1Reorg(currentHeight uint32) {
+	height := this.l1ChainInfo.Height
+	for height > 0 {
+		hash := LoadOntologyBlockHash(height)
+		if hash == "" {
+			break
+		}
+		if height <= currentHeight {
+			blockHash, err := this.l1.BlockHash(height)
+			if err != nil {
+				log.Errorf("get %s block %d err: %v", this.l1.Name(), height, err)
+				return
+			}
+			if blockHash == hash {
+				break
+			}
+		}
+		log.WithFields(log.Fields{log.FIELD_CHAIN: this.l1.Name(), log.FIELD_HEIGHT: height}).Warnf("%s block %d is reorged, parsed hash: %s", this.l1.Name(), height, hash)
+		height --
+	}
+	if height == this.l1ChainInfo.Height {
+		return
+	}
+	err := this.rewindL1Parse(height)
+	if err != nil {
+		log.Errorf("rewind %s parser to %d err: %v", this.l1.Name(), height, err)
+	}
+}
+
+//rewindL1Parse set the parser back to height, the deposits above are marked reorged and parsed again. The deposits
+//already minted on layer2 can not be rolled back and are quarantined until they are found again
+func (this *Layer2Operator) rewindL1Parse(height uint32) error {
+	deposits := LoadDepositsAboveHeight(height)
+	if deposits == nil {
+		return fmt.Errorf("load deposits above height %d failed", height)
+	}
+	for _, deposit := range deposits {
+		if deposit.State == DEPOSIT_REORGED {
+			continue
+		}
+		if deposit.State == DEPOSIT_EVENT && deposit.Layer2TxHash == "" {
+			err := UpdateDepositByID(deposit.ID, DEPOSIT_REORGED, "")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		reason := fmt.Sprintf("%s block %d of deposit is reorged after mint", this.l1.Name(), deposit.Height)
+		depositLog(deposit).Errorf("deposit %d, tx: %s, layer2 tx: %s, %s", deposit.ID, deposit.TxHash, deposit.Layer2TxHash, reason)
+		err := SaveDepositQuarantine(&DepositQuarantine{
+			Layer2TxHash: deposit.Layer2TxHash,
+			TT: uint32(time.Now().Unix()),
+			Height: deposit.Height,
+			ID: deposit.ID,
+			TxHash: deposit.TxHash,
+			Reason: reason,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := DeleteOntologyBlocksAbove(height)
 	if err != nil {
 		return err
 	}
-	tt := block.Header.Timestamp
+	this.standby.dropL1Above(height)
+	log.Infof("rewind %s parser from %d to %d", this.l1.Name(), this.l1ChainInfo.Height, height)
+	this.l1ChainInfo.Height = height
+	return SetChainParseHeight(this.l1ChainInfo.Id, height)
+}
 
-	events, err := this.ontologySdk.GetSmartContractEventByBlock(chain.Height)
+//rewindLayer2Commits rewind the layer2 parser to the committed height of L1 after the commits above it are reverted by
+//a challenge, the layer2 blocks above are parsed and committed again
+func (this *Layer2Operator) rewindLayer2Commits() error {
+	committed, err := this.getCommittedHeight()
+	if err != nil {
+		return err
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.layer2ChainInfo.Height <= committed {
+		return nil
+	}
+	err = RevertLayer2CommitsAbove(committed)
+	if err != nil {
+		return err
+	}
+	err = CancelJobsAbove(JOB_COMMIT, uint64(committed))
+	if err != nil {
+		return err
+	}
+	err = DeleteLayer2RecordsAbove(committed)
 	if err != nil {
 		return err
 	}
+	log.Warnf("layer2 commits above %d are reverted, rewind layer2 parser from %d", committed, this.layer2ChainInfo.Height)
+	this.layer2ChainInfo.Height = committed
+	return SetChainParseHeight(this.layer2ChainInfo.Id, committed)
+}
 
-	//log.Infof("chain: %s, block height: %d, events num: %d", chain.Name, chain.Height, len(events))
-	for _, event := range events {
-		//log.Infof("tx hash: %s, state:%d, gas: %d", event.TxHash, event.State, event.GasConsumed)
-		for _, notify := range event.Notify {
-			if notify.ContractAddress != this.config.OntologyConfig.Layer2ContractAddress {
+func (this *Layer2Operator) parseL1ChainBlock(chain *ChainInfo) error {
+	// the block fetched when standby is taken if there is one
+	var err error
+	block := this.standby.takeL1(chain.Height)
+	if block == nil {
+		block, err = this.l1.GetEvents(chain.Height)
+		if err != nil {
+			return err
+		}
+	}
+	tt := block.Timestamp
+
+	//log.Infof("chain: %s, block height: %d, events num: %d", chain.Name, chain.Height, len(block.Events))
+	for _, event := range block.Events {
+		method, err := this.bridge.EventName(event.States)
+		if err != nil {
+			this.metrics.undecodedL1Events.Inc(1)
+			log.Errorf("parse layer2 transaction: %s event name err: %v", event.TxHash, err)
+			continue
+		}
+		log.Infof("find layer2 transaction: %s, method: %s", event.TxHash, method)
+		if method == bridge.METHOD_DEPOSIT || method == bridge.METHOD_DEPOSIT_NFT {
+			depositEvent, err := this.bridge.ParseDepositEvent(event.States)
+			if err != nil {
+				this.metrics.undecodedL1Events.Inc(1)
+				log.Errorf("parse deposit event of tx: %s err: %v", event.TxHash, err)
 				continue
 			}
-			// todo
-			states := notify.States.([]interface{})
-			method, _ := hex.DecodeString(states[0].(string))
-			log.Infof("find layer2 transaction: %s, method: %s", event.TxHash, string(method))
-			if string(method) == "deposit" {
-				id, _ := hex.DecodeString(states[1].(string))
-				player := revertHexString(states[2].(string))
-				playerAddr, _ := ontology_common.AddressFromHexString(player)
-				amount, _ := hex.DecodeString(states[3].(string))
-
-				deposit := &Deposit{}
-				deposit.TxHash = event.TxHash
-				deposit.TT = tt
-				deposit.Height = chain.Height
-				deposit.State = DEPOSIT_EVENT
-				deposit.FromAddress = playerAddr.ToBase58()
-				deposit.Amount = BytesToInt(amount)
-				deposit.TokenAddress = states[6].(string)
-				deposit.ID = BytesToInt(id)
-				err = SaveDeposit(deposit)
-				if err != nil && chain.Height != 8378403 {
-					log.Errorf("save deposit tx error: %v", err)
-					continue
-				}
-				//
-				this.depositChain <- deposit
-			}
-		}
-	}
-
-	if this.fortest == 1 {
-		rand.Seed(time.Now().UnixNano())
-		t := rand.Intn(20)
-		if t == 0 && this.deposit == 0 {
-			this.deposit = 1
-			this.depositHeight = this.layer2ChainInfo.Height
-			{
-				deposit := &Deposit{}
-				deposit.TxHash = fmt.Sprintf("%d", time.Now().Unix())
-				deposit.TT = uint32(time.Now().Unix())
-				deposit.Height = 0
-				deposit.State = DEPOSIT_EVENT
-				deposit.FromAddress = this.layer2Account.Address.ToBase58()
-				deposit.Amount = 100000
-				deposit.TokenAddress = ONT_CONTRACT_ADDRESS
-				deposit.ID = uint64(time.Now().Unix())
-				err = SaveDeposit(deposit)
-				if err != nil {
-					log.Errorf("save deposit tx error: %v", err)
-				}
-				//
-				this.depositChain <- deposit
-			}
-			{
-				deposit := &Deposit{}
-				deposit.TxHash = fmt.Sprintf("%d", time.Now().Unix()+1)
-				deposit.TT = uint32(time.Now().Unix()) + 1
-				deposit.Height = 0
-				deposit.State = DEPOSIT_EVENT
-				deposit.FromAddress = this.layer2Account.Address.ToBase58()
-				deposit.Amount = 100000
-				deposit.TokenAddress = ONG_CONTRACT_ADDRESS
-				deposit.ID = uint64(time.Now().Unix()) + 1
-				err = SaveDeposit(deposit)
+			playerAddr, _ := ontology_common.AddressParseFromBytes(depositEvent.Player)
+
+			deposit := &Deposit{}
+			deposit.TxHash = event.TxHash
+			deposit.NotifyIndex = event.NotifyIndex
+			deposit.TT = tt
+			deposit.Height = chain.Height
+			deposit.State = DEPOSIT_EVENT
+			deposit.FromAddress = playerAddr.ToBase58()
+			deposit.Amount = depositEvent.Amount
+			deposit.TokenAddress = depositEvent.AssetAddress
+			deposit.TokenId = depositEvent.TokenId
+			deposit.ID = depositEvent.ID
+			// the non-fungible deposit must be of the token registered as OEP-5 or OEP-8, and the fungible one not
+			if token := this.tokens.get(deposit.TokenAddress); token == nil || !token.Enabled || isNFTToken(token) != (deposit.TokenId != "") {
+				// the deposit of the token not registered or paused is not minted, but can be refunded
+				depositLog(deposit).Errorf("token %s of deposit %d is not enabled, tx: %s", deposit.TokenAddress, deposit.ID, event.TxHash)
+				deposit.State = DEPOSIT_REJECTED
+				_, err = SaveDeposit(deposit)
 				if err != nil {
-					log.Errorf("save deposit tx error: %v", err)
+					return fmt.Errorf("save rejected deposit of tx: %s, err: %v", event.TxHash, err)
 				}
-				//
-				this.depositChain <- deposit
+				this.traceDepositEvent(deposit, fmt.Errorf("token %s is not enabled", deposit.TokenAddress))
+				continue
+			}
+			err = this.acceptDeposit(deposit)
+			if err != nil {
+				return err
+			}
+		} else if method == bridge.METHOD_REQUEST_EXIT {
+			exitEvent, err := this.bridge.ParseExitEvent(event.States)
+			if err != nil {
+				this.metrics.undecodedL1Events.Inc(1)
+				log.Errorf("parse exit event of tx: %s err: %v", event.TxHash, err)
+				continue
+			}
+			playerAddr, _ := ontology_common.AddressParseFromBytes(exitEvent.Player)
+			exit := &Exit{
+				ID:           exitEvent.ID,
+				TxHash:       event.TxHash,
+				TT:           tt,
+				State:        EXIT_EVENT,
+				Height:       chain.Height,
+				Player:       playerAddr.ToBase58(),
+				Amount:       exitEvent.Amount,
+				TokenAddress: exitEvent.AssetAddress,
+				Layer2Height: uint32(exitEvent.Height),
+			}
+			log.Infof("exit requested: %s", exit.Dump())
+			err = SaveExit(exit)
+			if err != nil {
+				// the exit is saved already if the block is parsed again
+				log.Errorf("save exit of tx: %s err: %v", event.TxHash, err)
+			}
+		} else if method == bridge.METHOD_REVERT_STATE {
+			err = this.rewindLayer2Commits()
+			if err != nil {
+				return fmt.Errorf("rewind reverted layer2 commits of tx: %s, err: %v", event.TxHash, err)
+			}
+		}
+	}
+
+	err = SaveOntologyBlock(chain.Height, block.Hash)
+	if err != nil {
+		return fmt.Errorf("save %s block %d hash err: %v", this.l1.Name(), chain.Height, err)
+	}
+
+	return nil
+}
+
+//acceptDeposit save the deposit event and enqueue the job minting it, the deposit saved already is enqueued again only
+//if it is not minted yet
+func (this *Layer2Operator) acceptDeposit(deposit *Deposit) error {
+	saved, err := SaveDeposit(deposit)
+	if err != nil {
+		return fmt.Errorf("save deposit of tx: %s, err: %v", deposit.TxHash, err)
+	}
+	if !saved {
+		enqueue, err := this.resaveDeposit(deposit)
+		if err != nil {
+			return fmt.Errorf("save deposit of tx: %s, err: %v", deposit.TxHash, err)
+		}
+		if !enqueue {
+			return nil
+		}
+	}
+	err = this.enqueueJob(JOB_DEPOSIT, deposit.ID, deposit)
+	if err != nil {
+		return fmt.Errorf("save deposit job of tx: %s, err: %v", deposit.TxHash, err)
+	}
+	this.traceDepositEvent(deposit, nil)
+	return nil
+}
+
+//resaveDeposit handle the deposit whose id or event is saved already, when the block is parsed again or the event is
+//packed again after an ontology reorg, and return whether the job of the deposit is enqueued again. Only the deposit
+//not minted yet is enqueued, and the mint job skips the deposit minted before, so the event parsed again is never
+//credited twice
+func (this *Layer2Operator) resaveDeposit(deposit *Deposit) (bool, error) {
+	saved := LoadDepositByID(deposit.ID)
+	if saved == nil {
+		// the event is saved as the deposit of another id
+		log.Errorf("event %d of tx: %s is saved as another deposit than %d", deposit.NotifyIndex, deposit.TxHash, deposit.ID)
+		return false, nil
+	}
+	if saved.State == DEPOSIT_REORGED {
+		// the reorged deposit is found again in the new chain
+		return true, UpdateDeposit(deposit)
+	}
+	if !saved.sameEvent(deposit) {
+		log.Errorf("deposit %d of tx: %s is saved of another event, tx: %s", deposit.ID, deposit.TxHash, saved.TxHash)
+		return false, nil
+	}
+	if saved.Layer2TxHash != "" {
+		// the minted deposit is packed again in the new chain, it is not quarantined any more
+		err := DeleteDepositQuarantine(saved.Layer2TxHash, saved.ID)
+		if err != nil {
+			log.Errorf("delete quarantine of deposit %d err: %v", saved.ID, err)
+		}
+	}
+	// the deposit saved before exit is enqueued again if it is not committed
+	return saved.State == DEPOSIT_EVENT, nil
+}
+
+//enqueueJob save the job to database and wake up its loop, the job not done is resumed after restart
+func (this *Layer2Operator) enqueueJob(kind int, key uint64, value interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	err = SaveJob(kind, key, string(payload), uint32(time.Now().Unix()))
+	if err != nil {
+		return err
+	}
+	notify := this.depositNotify
+	if kind == JOB_COMMIT {
+		notify = this.commitNotify
+	}
+	select {
+	case notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+//jobLoop run the jobs of kind in order of enqueue. The job is set running before run and done after, so the job
+//interrupted by exit is run again on restart, and run must skip the work already done
+func (this *Layer2Operator) jobLoop(kind int, notify chan struct{}, run func(job *Job) error) {
+	defer log.Infof("jobLoop of kind %d exit", kind)
+	updateTicker := time.NewTicker(time.Second * 1)
+	defer updateTicker.Stop()
+	for !this.stopping() {
+		var job *Job
+		if jobs := this.loadRunnableJobs(kind, 1); len(jobs) > 0 {
+			job = jobs[0]
+		}
+		if job == nil {
+			select {
+			case <- notify:
+			case <- updateTicker.C:
+			case <- this.ctx.Done():
+				return
 			}
+			continue
 		}
+		if job.State == JOB_PENDING {
+			err := UpdateJobState(job.ID, JOB_RUNNING)
+			if err != nil {
+				log.Errorf("update job %d state err: %v", job.ID, err)
+				this.retry.Wait(0)
+				continue
+			}
+		} else {
+			log.Infof("resume job %d of kind %d, key: %d", job.ID, kind, job.Key)
+		}
+		done := false
+		attempts := 0
+		for this.isLeading() && !this.stopping() {
+			err := run(job)
+			if err == nil {
+				done = true
+				break
+			}
+			log.Errorf("run job %d of kind %d err: %s", job.ID, kind, err.Error())
+			// the job interrupted by shutdown is resumed on restart
+			if this.stopping() {
+				break
+			}
+			if isWaitError(err) {
+				this.retry.Wait(0)
+				continue
+			}
+			attempts ++
+			if this.retry.Exhausted(attempts) {
+				this.deadLetterJobs([]*Job{job}, attempts, err)
+				break
+			}
+			this.retry.Wait(attempts - 1)
+		}
+		if !done {
+			continue
+		}
+		this.finishJobs([]*Job{job})
+	}
+}
+
+//batchJobLoop run the jobs of kind in batches of at most size jobs as jobLoop, a batch smaller than size waits for
+//more jobs until the window passed since its first job is found. The size and the window are returned by batch every
+//round, so that they are changed by reload
+func (this *Layer2Operator) batchJobLoop(kind int, notify chan struct{}, batch func() (int, time.Duration), run func(jobs []*Job) error) {
+	defer log.Infof("batchJobLoop of kind %d exit", kind)
+	updateTicker := time.NewTicker(time.Second * 1)
+	defer updateTicker.Stop()
+	var since time.Time
+	for !this.stopping() {
+		size, window := batch()
+		jobs := this.loadRunnableJobs(kind, size)
+		if len(jobs) == 0 {
+			since = time.Time{}
+		} else if since.IsZero() {
+			since = time.Now()
+		}
+		if len(jobs) == 0 || (len(jobs) < size && time.Since(since) < window) {
+			var timeout <-chan time.Time
+			if len(jobs) > 0 {
+				timeout = time.After(window - time.Since(since))
+			}
+			select {
+			case <- notify:
+			case <- timeout:
+			case <- updateTicker.C:
+			case <- this.ctx.Done():
+				return
+			}
+			continue
+		}
+		since = time.Time{}
+		failed := false
+		for _, job := range jobs {
+			if job.State != JOB_PENDING {
+				log.Infof("resume job %d of kind %d, key: %d", job.ID, kind, job.Key)
+				continue
+			}
+			err := UpdateJobState(job.ID, JOB_RUNNING)
+			if err != nil {
+				log.Errorf("update job %d state err: %v", job.ID, err)
+				failed = true
+				break
+			}
+		}
+		if failed {
+			this.retry.Wait(0)
+			continue
+		}
+		done := false
+		attempts := 0
+		for this.isLeading() && !this.stopping() {
+			err := run(jobs)
+			if err == nil {
+				done = true
+				break
+			}
+			log.Errorf("run batch of %d jobs of kind %d err: %s", len(jobs), kind, err.Error())
+			// the job interrupted by shutdown is resumed on restart
+			if this.stopping() {
+				break
+			}
+			if isWaitError(err) {
+				this.retry.Wait(0)
+				continue
+			}
+			attempts ++
+			if this.retry.Exhausted(attempts) {
+				this.deadLetterJobs(jobs, attempts, err)
+				break
+			}
+			this.retry.Wait(attempts - 1)
+		}
+		if !done {
+			continue
+		}
+		this.finishJobs(jobs)
+	}
+}
+
+//loadRunnableJobs return at most limit jobs of kind to run if leading, the deposit jobs of the paused tokens are held
+//instead of returned
+func (this *Layer2Operator) loadRunnableJobs(kind int, limit int) []*Job {
+	if !this.isLeading() || this.isJobKindBlocked(kind) {
+		return nil
+	}
+	for {
+		loaded := LoadNextJobs(kind, limit)
+		jobs, err := this.holdPausedJobs(kind, loaded)
+		if err != nil {
+			log.Errorf("hold paused jobs of kind %d err: %v", kind, err)
+			return nil
+		}
+		if len(jobs) > 0 || len(loaded) == 0 {
+			return jobs
+		}
+	}
+}
+
+//finishJobs set the jobs done, the state is retried until saved so that the jobs are not run again
+func (this *Layer2Operator) finishJobs(jobs []*Job) {
+	for _, job := range jobs {
+		for attempt := 0; UpdateJobState(job.ID, JOB_DONE) != nil; attempt ++ {
+			log.Errorf("update job %d state to done failed, try again......", job.ID)
+			if !this.retry.Wait(attempt) {
+				return
+			}
+		}
+	}
+}
+
+func (this *Layer2Operator) depositLoop() {
+	log.Infof("start depositLoop")
+	if size, _ := this.depositBatch(); size > 1 {
+		this.batchJobLoop(JOB_DEPOSIT, this.depositNotify, this.depositBatch, this.runDepositBatch)
+		return
+	}
+	this.jobLoop(JOB_DEPOSIT, this.depositNotify, this.runDepositJob)
+}
+
+//depositBatch return the max number of deposits minted by one transaction and the window to wait for them
+func (this *Layer2Operator) depositBatch() (int, time.Duration) {
+	layer2Config := this.config().Layer2Config
+	size := layer2Config.DepositBatchSize
+	if size < 1 {
+		size = 1
+	}
+	return size, time.Duration(layer2Config.DepositBatchWindow) * time.Millisecond
+}
+
+//runDepositBatch mint the deposits of the jobs by one transaction of every token. The deposits are skipped as
+//runDepositJob, and the deposit whose mint sent before exit is on layer2 or in the transaction pool is not sent again
+func (this *Layer2Operator) runDepositBatch(jobs []*Job) error {
+	batches := make(map[string][]*Deposit)
+	tokens := make([]string, 0)
+	for _, job := range jobs {
+		deposit := &Deposit{}
+		err := json.Unmarshal([]byte(job.Payload), deposit)
+		if err != nil {
+			log.Errorf("parse deposit job %d err: %v", job.ID, err)
+			continue
+		}
+		saved := LoadDepositByID(deposit.ID)
+		if saved != nil && (saved.State != DEPOSIT_EVENT || !saved.sameEvent(deposit)) {
+			continue
+		}
+		minted, err := this.isMintedBeforeExit(deposit, saved)
+		if err != nil {
+			return err
+		}
+		if minted {
+			continue
+		}
+		if _, ok := batches[deposit.TokenAddress]; !ok {
+			tokens = append(tokens, deposit.TokenAddress)
+		}
+		batches[deposit.TokenAddress] = append(batches[deposit.TokenAddress], deposit)
+	}
+	for _, token := range tokens {
+		err := this.commitDeposits2Layer2(batches[token])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//commitDeposits2Layer2 mint the deposits of the same token by one multi-transfer transaction, the transfers are in
+//order of deposit id
+func (this *Layer2Operator) commitDeposits2Layer2(deposits []*Deposit) error {
+	if len(deposits) == 1 {
+		return this.commitDeposit2Layer2(deposits[0])
+	}
+	sort.Slice(deposits, func(i, j int) bool {
+		return deposits[i].ID < deposits[j].ID
+	})
+	spans := make([]*span, 0, len(deposits))
+	for _, deposit := range deposits {
+		depositLog(deposit).Infof("commit deposit to layer2 in batch: %s", deposit.Dump())
+		spans = append(spans, this.startSpan(depositTrace(deposit.ID), SPAN_DEPOSIT_MINT).set(log.FIELD_DEPOSIT_ID, deposit.ID).
+			set("batch", len(deposits)))
+	}
+	tokenAddress := deposits[0].TokenAddress
+	tx, err := this.newMintTransaction(tokenAddress, deposits)
+	if err != nil {
+		endSpans(spans, err)
+		return err
+	}
+	if this.dryRun() {
+		err = this.dryRunMintDeposits(tokenAddress, deposits)
+		endSpans(spans, err)
+		return err
+	}
+	// the nonce is fixed by the first deposit id as the single mint
+	tx.Nonce = uint32(deposits[0].ID)
+
+	this.layer2Sdk().SetPayer(tx, this.layer2Account.Address)
+	err = this.layer2Sdk().SignToTransaction(tx, this.layer2Account)
+	if err != nil {
+		endSpans(spans, err)
+		return err
+	}
+	// save the hash before sending, so that the mint sent before exit is found on restart
+	txHash := tx.Hash()
+	for _, deposit := range deposits {
+		err = UpdateDepositByID(deposit.ID, DEPOSIT_EVENT, txHash.ToHexString())
+		if err != nil {
+			return err
+		}
+	}
+	hash, err := this.sendLayer2Mint(tx)
+	if err == errShutdown {
+		// the deposits keep the hash and are sent again on restart
+		endSpans(spans, err)
+		return err
+	}
+	state := DEPOSIT_COMMIT
+	layer2TxHash := hash.ToHexString()
+	if err != nil {
+		// keep the hash of the failed mint, the deposits are refunded by refundLoop only if the mint is lost
+		state = DEPOSIT_FAILED
+		layer2TxHash = txHash.ToHexString()
 	}
+	for _, s := range spans {
+		s.set(log.FIELD_TXHASH, layer2TxHash)
+	}
+	endSpans(spans, err)
+	for _, deposit := range deposits {
+		UpdateDepositByID(deposit.ID, state, layer2TxHash)
+	}
+	if state == DEPOSIT_COMMIT {
+		audit(AUDIT_MINT, AUDIT_BY_OPERATOR, layer2TxHash, "mint %d deposits of token %s from deposit %d", len(deposits),
+			tokenAddress, deposits[0].ID)
+	}
+	log.WithFields(log.Fields{log.FIELD_CHAIN: "layer2", log.FIELD_TXHASH: layer2TxHash}).Infof("commit %d deposits of token %s to layer2, state: %d, tx hash: %s", len(deposits), tokenAddress, state, layer2TxHash)
 	return nil
 }
 
-func (this *Layer2Operator) depositLoop() {
-	log.Infof("start depositLoop")
-	for {
-		select {
-		case deposit := <-this.depositChain:
-			for true {
-				err := this.commitDeposit2Layer2(deposit)
-				if err != nil {
-					log.Errorf("commit deposit 2 layer2 error: %s", err.Error())
-					time.Sleep(time.Second * 1)
-				} else {
-					break
-				}
-			}
-		}
+//runDepositJob commit the deposit to layer2. The deposit committed before or saved of another event is skipped, and the mint transaction sent
+//before exit is looked up on layer2 and in the transaction pool by the saved hash instead of sent again
+func (this *Layer2Operator) runDepositJob(job *Job) error {
+	deposit := &Deposit{}
+	err := json.Unmarshal([]byte(job.Payload), deposit)
+	if err != nil {
+		log.Errorf("parse deposit job %d err: %v", job.ID, err)
+		return nil
+	}
+	saved := LoadDepositByID(deposit.ID)
+	if saved != nil && (saved.State != DEPOSIT_EVENT || !saved.sameEvent(deposit)) {
+		return nil
 	}
+	minted, err := this.isMintedBeforeExit(deposit, saved)
+	if err != nil || minted {
+		return err
+	}
+	return this.commitDeposit2Layer2(deposit)
 }
 
-func (this *Layer2Operator) commitDeposit2Layer2(deposit *Deposit) error {
-	log.Infof("commit deposit to layer2: %s", deposit.Dump())
-	toAddr, _ := layer2_common.AddressFromBase58(deposit.FromAddress)
-	var tx *layer2_types.MutableTransaction
-	var err error
-	if deposit.TokenAddress == ONT_CONTRACT_ADDRESS {
-		tx, err = this.layer2Sdk.Native.Ont.NewTransferTransaction(0, 20000, layer2_common.ADDRESS_EMPTY, toAddr, deposit.Amount)
-		if err != nil {
-			return err
+//sendLayer2Mint send the mint transaction with the backoff of the retry policy, return the last error if every attempt
+//failed, or errShutdown if the retries are aborted on shutdown
+func (this *Layer2Operator) sendLayer2Mint(tx *layer2_types.MutableTransaction) (layer2_common.Uint256, error) {
+	for attempt := 0; ; attempt ++ {
+		hash, err := this.layer2Sdk().SendTransaction(tx)
+		if err == nil {
+			return hash, nil
 		}
-	} else if deposit.TokenAddress == ONG_CONTRACT_ADDRESS {
-		tx, err = this.layer2Sdk.Native.Ong.NewTransferTransaction(0, 20000, layer2_common.ADDRESS_EMPTY, toAddr, deposit.Amount)
-		if err != nil {
-			return err
+		if this.retry.Exhausted(attempt + 1) {
+			return layer2_common.UINT256_EMPTY, err
+		}
+		log.Errorf("send mint transaction of layer2 failed! err: %s, try again......", err.Error())
+		if !this.retry.Wait(attempt) {
+			return layer2_common.UINT256_EMPTY, errShutdown
 		}
 	}
+}
 
-	this.layer2Sdk.SetPayer(tx, this.layer2Account.Address)
-	err = this.layer2Sdk.SignToTransaction(tx, this.layer2Account)
+func (this *Layer2Operator) commitDeposit2Layer2(deposit *Deposit) error {
+	depositLog(deposit).Infof("commit deposit to layer2: %s", deposit.Dump())
+	s := this.startSpan(depositTrace(deposit.ID), SPAN_DEPOSIT_MINT).set(log.FIELD_DEPOSIT_ID, deposit.ID)
+	tx, err := this.newMintTransaction(deposit.TokenAddress, []*Deposit{deposit})
 	if err != nil {
+		s.end(err)
 		return err
 	}
-	var hash layer2_common.Uint256
-	counter := 0
-	for true {
-		hash, err = this.layer2Sdk.SendTransaction(tx)
-		if err != nil {
-			log.Errorf("send transaction err when commit deposit 2 layer2, err: %s, try again......", err.Error())
-			if counter == 100 {
-				break
-			}
-			time.Sleep(time.Second * 1)
-			counter ++
-			// send error, we cannot send again, so ignore this error
-		} else {
-			break
-		}
+	if this.dryRun() {
+		err = this.dryRunMintDeposits(deposit.TokenAddress, []*Deposit{deposit})
+		s.end(err)
+		return err
+	}
+	// the nonce is fixed by the deposit id, so the mint sent again by another leader has the same hash and is rejected
+	tx.Nonce = uint32(deposit.ID)
+
+	this.layer2Sdk().SetPayer(tx, this.layer2Account.Address)
+	err = this.layer2Sdk().SignToTransaction(tx, this.layer2Account)
+	if err != nil {
+		s.end(err)
+		return err
 	}
-	if counter == 100 {
+	// save the hash before sending, so that the mint sent before exit is found on restart
+	txHash := tx.Hash()
+	s.set(log.FIELD_TXHASH, txHash.ToHexString())
+	err = UpdateDepositByID(deposit.ID, DEPOSIT_EVENT, txHash.ToHexString())
+	if err != nil {
+		s.end(err)
+		return err
+	}
+	hash, err := this.sendLayer2Mint(tx)
+	s.end(err)
+	if err == errShutdown {
+		return err
+	}
+	if err != nil {
+		// keep the hash of the failed mint, the deposit is refunded by refundLoop only if the mint is lost
 		deposit.State = DEPOSIT_FAILED
-		formatStr := "2006-01-02 15:04:05"
-		timehash := time.Now().Format(formatStr)
-		UpdateDepositByID(deposit.ID, deposit.State, timehash)
-		log.Infof("commit deposit to layer2, from : %s, to : %s, failed: %s", layer2_common.ADDRESS_EMPTY.ToBase58(), toAddr.ToBase58(), timehash)
+		UpdateDepositByID(deposit.ID, deposit.State, txHash.ToHexString())
+		depositLog(deposit).Infof("commit deposit to layer2, from : %s, to : %s, failed: %s", assets.collector.ToBase58(), deposit.FromAddress, txHash.ToHexString())
 	} else {
 		deposit.State = DEPOSIT_COMMIT
 		UpdateDepositByID(deposit.ID, deposit.State, hash.ToHexString())
-		log.Infof("commit deposit to layer2, from : %s, to : %s, tx hash: %s", layer2_common.ADDRESS_EMPTY.ToBase58(), toAddr.ToBase58(), hash.ToHexString())
+		audit(AUDIT_MINT, AUDIT_BY_OPERATOR, hash.ToHexString(), "mint deposit %d of token %s, amount: %d, to: %s", deposit.ID,
+			deposit.TokenAddress, deposit.Amount, deposit.FromAddress)
+		depositLog(deposit).Infof("commit deposit to layer2, from : %s, to : %s, tx hash: %s", assets.collector.ToBase58(), deposit.FromAddress, hash.ToHexString())
 	}
 	return nil
 }
 
+//layer2NewBlocks return the channel of the heights of the blocks pushed by the layer2 node, nil if the node is polled
+func (this *Layer2Operator) layer2NewBlocks() <-chan uint32 {
+	if this.layer2Subscription == nil {
+		return nil
+	}
+	return this.layer2Subscription.NewBlocks()
+}
+
+//MonitorLayer2Chain parse the layer2 blocks every second, or when a block is pushed by the layer2 node as
+//MonitorL1Chain
 func (this *Layer2Operator) MonitorLayer2Chain() {
 	log.Infof("start MonitorLayer2Chain")
 	updateTicker := time.NewTicker(time.Second * 1)
+	lastPoll := time.Time{}
 	for {
 		select {
 		case <- updateTicker.C:
-			currentHeight, err := this.layer2Sdk.GetCurrentBlockHeight()
-			if err != nil {
-				log.Errorf("get layer2 current block height err: %s", err.Error())
-				continue
-			}
-
-			this.mu.Lock()
-			log.Infof("chain %s current height: %d, parser height: %d", this.layer2ChainInfo.Name, currentHeight, this.layer2ChainInfo.Height)
-			if this.layer2ChainInfo.Height >= currentHeight {
-				this.mu.Unlock()
+			if this.layer2NewBlocks() != nil && time.Since(lastPoll) < SUBSCRIBED_POLL_INTERVAL {
 				continue
 			}
-			for this.layer2ChainInfo.Height < currentHeight - 1 {
-				commitHeight := GetLayer2CommitHeight()
-				if commitHeight < this.layer2ChainInfo.Height {
-					break
-				}
-				if this.needCheck {
-					this.needCheck = false
-					exit, _ := this.checkLayer2StateByHeight(uint64(this.layer2ChainInfo.Height + 1))
-					if exit {
-						this.layer2ChainInfo.Height ++
-					}
-				}
-				this.layer2ChainInfo.Height ++
-				err = this.parseLayer2ChainBlock(this.layer2ChainInfo)
-				if err != nil {
-					log.Errorf("parser layer2 chain block err: %s", err.Error())
-					this.layer2ChainInfo.Height --
-					break
-				}
-				SetChainParseHeight(this.layer2ChainInfo.Id, this.layer2ChainInfo.Height)
-			}
-			this.mu.Unlock()
-		case <- this.exitChan:
+		case <- this.layer2NewBlocks():
+		case <- this.ctx.Done():
 			updateTicker.Stop()
 			log.Infof("chain %s, exit!", this.layer2ChainInfo.Name)
 			return
 		}
+		lastPoll = time.Now()
+		this.pollLayer2Chain()
 	}
 }
 
-func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
-	block, err := this.layer2Sdk.GetBlockByHeight(chain.Height)
+//pollLayer2Chain parse the layer2 blocks above the parser height, up to the blocks aggregated into one commit ahead of
+//the last finished commit
+func (this *Layer2Operator) pollLayer2Chain() {
+	currentHeight, err := this.layer2Sdk().GetCurrentBlockHeight()
 	if err != nil {
-		return err
+		log.Errorf("get layer2 current block height err: %s", err.Error())
+		return
+	}
+	this.metrics.layer2Height.Update(int64(currentHeight))
+	if !this.isLeading() {
+		this.followLayer2Chain(currentHeight)
+		return
 	}
-	tt := block.Header.Timestamp
 
-	events, err := this.layer2Sdk.GetSmartContractEventByBlock(chain.Height)
-	if err != nil {
-		return err
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	chainLog(this.layer2ChainInfo).Infof("chain %s current height: %d, parser height: %d", this.layer2ChainInfo.Name, currentHeight, this.layer2ChainInfo.Height)
+	for this.layer2ChainInfo.Height < currentHeight - 1 && this.isLeading() && !this.stopping() {
+		// parse ahead of the last finished commit by the blocks aggregated into one commit
+		commitHeight := GetLayer2CommitHeight()
+		if commitHeight + uint32(this.commitBatchSize()) <= this.layer2ChainInfo.Height {
+			break
+		}
+		if this.needCheck {
+			this.needCheck = false
+			exit, _ := this.checkLayer2StateByHeight(uint64(this.layer2ChainInfo.Height + 1))
+			if exit {
+				this.layer2ChainInfo.Height ++
+			}
+		}
+		// fetch the blocks of several heights at the same time, they are saved and committed in height order
+		count := int64(currentHeight) - 1 - int64(this.layer2ChainInfo.Height)
+		if ahead := int64(commitHeight) + int64(this.commitBatchSize()) - int64(this.layer2ChainInfo.Height); ahead < count {
+			count = ahead
+		}
+		if concurrency := int64(this.layer2ParseConcurrency()); concurrency < count {
+			count = concurrency
+		}
+		if count <= 0 {
+			break
+		}
+		for _, block := range this.layer2Blocks(this.layer2ChainInfo.Height + 1, int(count)) {
+			err = block.err
+			if err == nil {
+				this.layer2ChainInfo.Height ++
+				err = this.parseLayer2ChainBlock(this.layer2ChainInfo, block)
+				if err != nil {
+					this.layer2ChainInfo.Height --
+				}
+			}
+			if err != nil {
+				log.Errorf("parser layer2 chain block err: %s", err.Error())
+				break
+			}
+			SetChainParseHeight(this.layer2ChainInfo.Id, this.layer2ChainInfo.Height)
+		}
+		if err != nil {
+			break
+		}
 	}
+}
+
+//parseLayer2ChainBlock save the transfers, deposits and withdraws of the fetched block at the parser height, and
+//enqueue the commit of its layer2 state
+func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo, fetched *layer2Block) error {
+	tt := fetched.block.Header.Timestamp
+	events := fetched.events
 	msg := &Layer2CommitMsg{}
-	insertLayer2TxBatch := NewMysqlInsertBatch(DefDB, 9, "(?,?,?,?,?,?,?,?,?)", "insert into layer2tx(txhash, tt, state, fee, height, fromaddress, tokenaddress, toaddress, amount)")
-	insertLayer2TxArgs := make([]interface{}, 9)
+	insertLayer2TxBatch := NewMysqlInsertBatch(DefDB, 11, "(?,?,?,?,?,?,?,?,?,?,?)", "insert into layer2tx(txhash, tt, state, fee, height, fromaddress, tokenaddress, toaddress, amount, notifyindex, tokenid)")
+	insertLayer2TxArgs := make([]interface{}, 11)
 	updateDepositBatch := NewMysqlUpdateBatch(DefDB, 9, "(?,?,?,?,?,?,?,?,?)", "insert into deposit(txhash, tt, state, height, fromaddress, amount, tokenaddress, id, layer2txhash)", "ON DUPLICATE KEY UPDATE state=VALUES(state)")
 	updateDepositArgs := make([]interface{}, 9)
-	insertWithdrawBatch := NewMysqlInsertBatch(DefDB, 7, "(?,?,?,?,?,?,?)", "insert into withdraw(txhash, tt, state, height, toaddress, amount, tokenaddress)")
-	insertWithdrawArgs := make([]interface{}, 7)
-	log.Infof("chain: %s, block height: %d, events num: %d\n", chain.Name, chain.Height, len(events))
+	insertWithdrawBatch := NewMysqlInsertBatch(DefDB, 8, "(?,?,?,?,?,?,?,?)", "insert into withdraw(txhash, tt, state, height, toaddress, amount, tokenaddress, tokenid)")
+	insertWithdrawArgs := make([]interface{}, 8)
+	chainLog(chain).Infof("chain: %s, block height: %d, events num: %d", chain.Name, chain.Height, len(events))
 	for _, event := range events {
 		log.Infof("tx hash: %s, state:%d, gas: %d\n", event.TxHash, event.State, event.GasConsumed)
-		for _, notify := range event.Notify {
-			if notify.ContractAddress != ONT_REV_CONTRACT_ADDRESS && notify.ContractAddress != ONG_REV_CONTRACT_ADDRESS  {
-				continue
-			}
-			states := notify.States.([]interface{})
-			if len(states) != 4 {
-				continue
-			}
-			if states[0] != NOTIFY_TRANSFER {
-				continue
-			}
-			transferFrom, ok := states[1].(string)
-			if !ok {
-				continue
-			}
-			transferTo, ok := states[2].(string)
-			if !ok {
+		// a batch mint credits several deposits, the mint transfers are in order of deposit id
+		var mintDeposits []*Deposit
+		mintIndex := 0
+		for notifyIndex, notify := range event.Notify {
+			transfer, err := this.decodeTransferNotify(notify)
+			if err != nil {
+				this.metrics.undecodedLayer2Events.Inc(1)
+				log.Errorf("skip the notify %d of tx: %s, err: %v", notifyIndex, event.TxHash, err)
 				continue
 			}
-			transferAmount, ok := states[3].(uint64)
-			if !ok {
+			if transfer == nil {
 				continue
 			}
 
@@ -527,10 +1287,11 @@ func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
 			layer2Tx.Fee = 0
 			layer2Tx.Height = chain.Height
 			layer2Tx.State = 1
-			layer2Tx.FromAddress = transferFrom
-			layer2Tx.Amount = transferAmount
-			layer2Tx.TokenAddress = revertHexString(notify.ContractAddress)
-			layer2Tx.ToAddress = transferTo
+			layer2Tx.FromAddress = transfer.From
+			layer2Tx.Amount = transfer.Amount
+			layer2Tx.TokenAddress = transfer.Token
+			layer2Tx.TokenId = transfer.TokenId
+			layer2Tx.ToAddress = transfer.To
 			insertLayer2TxArgs[0] = layer2Tx.TxHash
 			insertLayer2TxArgs[1] = layer2Tx.TT
 			insertLayer2TxArgs[2] = layer2Tx.State
@@ -540,6 +1301,8 @@ func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
 			insertLayer2TxArgs[6] = layer2Tx.TokenAddress
 			insertLayer2TxArgs[7] = layer2Tx.ToAddress
 			insertLayer2TxArgs[8] = layer2Tx.Amount
+			insertLayer2TxArgs[9] = notifyIndex
+			insertLayer2TxArgs[10] = layer2Tx.TokenId
 			insertLayer2TxBatch.Insert(insertLayer2TxArgs)
 			/*
 			err = SaveLayer2Tx(layer2Tx)
@@ -551,11 +1314,44 @@ func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
 			//
 			if isLayer2Tx(layer2Tx.FromAddress) {
 				//UpdateDepositByLayer2TxHash(layer2Tx.TxHash, DEPOSIT_FINISH)
-				deposit := LoadDepositByLayer2TxHash(layer2Tx.TxHash)
-				msg.Deposits = append(msg.Deposits, deposit.ID)
+				if mintDeposits == nil {
+					mintDeposits = LoadDepositsByLayer2TxHash(layer2Tx.TxHash)
+				}
+				var deposit *Deposit
+				if mintIndex < len(mintDeposits) {
+					deposit = mintDeposits[mintIndex]
+				}
+				reason, err := this.verifyDepositMint(deposit, layer2Tx.TxHash, mintIndex)
+				mintIndex ++
+				if err != nil {
+					return fmt.Errorf("verify mint of deposit, tx hash: %s, err: %v", layer2Tx.TxHash, err)
+				}
+				if deposit != nil {
+					this.traceDepositCredit(deposit, layer2Tx, reason)
+				}
+				if reason != "" {
+					log.Errorf("quarantine mint tx: %s, %s", layer2Tx.TxHash, reason)
+					quarantine := &DepositQuarantine{Layer2TxHash: layer2Tx.TxHash, TT: tt, Height: chain.Height, Reason: reason}
+					if deposit != nil {
+						quarantine.ID = deposit.ID
+						quarantine.TxHash = deposit.TxHash
+					}
+					err = SaveDepositQuarantine(quarantine)
+					if err != nil {
+						log.Errorf("save deposit quarantine error: %v", err)
+					}
+					if deposit == nil {
+						continue
+					}
+				} else {
+					msg.Deposits = append(msg.Deposits, deposit.ID)
+				}
 				updateDepositArgs[0] = ""
 				updateDepositArgs[1] = 0
 				updateDepositArgs[2] = DEPOSIT_FINISH
+				if reason != "" {
+					updateDepositArgs[2] = DEPOSIT_QUARANTINE
+				}
 				updateDepositArgs[3] = 0
 				updateDepositArgs[4] = ""
 				updateDepositArgs[5] = 0
@@ -571,9 +1367,10 @@ func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
 				withdraw.TT = tt
 				withdraw.Height = chain.Height
 				withdraw.State = WITHDRAW_INIT
-				withdraw.ToAddress = transferFrom
-				withdraw.Amount = transferAmount
-				withdraw.TokenAddress = revertHexString(notify.ContractAddress)
+				withdraw.ToAddress = transfer.From
+				withdraw.Amount = transfer.Amount
+				withdraw.TokenAddress = transfer.Token
+				withdraw.TokenId = transfer.TokenId
 				insertWithdrawArgs[0] = withdraw.TxHash
 				insertWithdrawArgs[1] = withdraw.TT
 				insertWithdrawArgs[2] = withdraw.State
@@ -581,7 +1378,9 @@ func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
 				insertWithdrawArgs[4] = withdraw.ToAddress
 				insertWithdrawArgs[5] = withdraw.Amount
 				insertWithdrawArgs[6] = withdraw.TokenAddress
+				insertWithdrawArgs[7] = withdraw.TokenId
 				insertWithdrawBatch.Insert(insertWithdrawArgs)
+				this.traceWithdrawEvent(withdraw)
 				/*
 				err = SaveWithdraw(withdraw)
 				if err != nil {
@@ -598,99 +1397,268 @@ func (this *Layer2Operator) parseLayer2ChainBlock(chain *ChainInfo) error {
 	insertWithdrawBatch.Close()
 
 	//
-	layer2State, _, _ := this.layer2Sdk.GetLayer2State(chain.Height)
-	msg.Layer2State = layer2State
+	msg.Layer2State = fetched.layer2State
 
-	this.msgChan <- msg
+	err := this.enqueueJob(JOB_COMMIT, uint64(chain.Height), msg)
+	if err != nil {
+		return fmt.Errorf("save layer2 commit job of height: %d, err: %v", chain.Height, err)
+	}
 	return nil
 }
 
 func (this *Layer2Operator) commitMsgLoop() {
 	log.Infof("start commitMsgLoop")
-	for {
-		select {
-		case msg := <-this.msgChan:
-			for true {
-				err := this.commitLayer2State2Ontology(msg)
-				if err != nil {
-					log.Errorf("commit layer2 state to ontology err: %s", err.Error())
-					time.Sleep(time.Second * 1)
-				} else {
-					break
-				}
-			}
+	if this.commitBatchSize() > 1 {
+		this.batchJobLoop(JOB_COMMIT, this.commitNotify, this.commitBatch, this.runCommitBatch)
+		return
+	}
+	this.jobLoop(JOB_COMMIT, this.commitNotify, this.runCommitJob)
+}
+
+//commitBatch return the max number of layer2 blocks aggregated into one commit and the window to wait for them
+func (this *Layer2Operator) commitBatch() (int, time.Duration) {
+	_, batchWindow := this.config().CommitBatch()
+	return this.commitBatchSize(), time.Duration(batchWindow) * time.Millisecond
+}
+
+//commitBatchSize return the max number of layer2 blocks aggregated into one commit
+func (this *Layer2Operator) commitBatchSize() int {
+	if batchSize, _ := this.config().CommitBatch(); batchSize > 1 {
+		return batchSize
+	}
+	return 1
+}
+
+//rewindLayer2Parse parse the layer2 blocks after the last finished commit again when a commit failed, so that their
+//layer2 states are committed again
+func (this *Layer2Operator) rewindLayer2Parse() {
+	height := GetLayer2CommitHeight()
+	if height < this.layer2ChainInfo.Height {
+		err := DeleteLayer2RecordsAbove(height)
+		if err != nil {
+			log.Errorf("delete layer2 records above height %d err: %v", height, err)
 		}
+		this.layer2ChainInfo.Height = height
 	}
+	this.needCheck = true
 }
 
-func (this *Layer2Operator) commitLayer2State2Ontology(msg *Layer2CommitMsg) error {
-	layer2Msg := msg.Dump()
-	log.Infof("commit layer2 state to ontology: %s", layer2Msg)
-	//
-	contractAddress, _ := ontology_common.AddressFromHexString(this.config.OntologyConfig.Layer2ContractAddress)
+//runCommitBatch commit the layer2 states of the jobs by one updateState transaction, which carries the state of the
+//last height and the deposits and withdraws of all heights. The heights committed to ontology before exit are
+//recorded as finished instead of sent again
+func (this *Layer2Operator) runCommitBatch(jobs []*Job) error {
+	committed, err := this.getCommittedHeight()
+	if err != nil {
+		return err
+	}
+	msgs := make([]*Layer2CommitMsg, 0, len(jobs))
+	for _, job := range jobs {
+		msg := &Layer2CommitMsg{}
+		err := json.Unmarshal([]byte(job.Payload), msg)
+		if err != nil || msg.Layer2State == nil {
+			log.Errorf("parse layer2 commit job %d err: %v", job.ID, err)
+			continue
+		}
+		if msg.Layer2State.Height <= committed {
+			commitLog(this.l1.Name(), msg.Layer2State.Height, "").Infof("layer2 state of height %d is committed to ontology before exit", msg.Layer2State.Height)
+			this.saveCommittedLayer2Msg(msg)
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	if this.multiSig != nil && !this.config().MultiSigConfig.Proposer {
+		return &waitError{fmt.Errorf("wait for the proposer to commit layer2 state of height %d", msgs[len(msgs) - 1].Layer2State.Height)}
+	}
+	return this.commitLayer2State2L1(mergeLayer2CommitMsgs(msgs))
+}
+
+//mergeLayer2CommitMsgs merge the commit msgs of consecutive heights into the msg of the last height, which carries
+//the deposits and withdraws of all
+func mergeLayer2CommitMsgs(msgs []*Layer2CommitMsg) *Layer2CommitMsg {
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	merged := &Layer2CommitMsg{
+		Layer2State: msgs[len(msgs) - 1].Layer2State,
+		Deposits: make([]uint64, 0),
+		WithDraws: make([]*Withdraw, 0),
+		FromHeight: msgs[0].fromHeight(),
+	}
+	for _, msg := range msgs {
+		merged.Deposits = append(merged.Deposits, msg.Deposits...)
+		merged.WithDraws = append(merged.WithDraws, msg.WithDraws...)
+	}
+	return merged
+}
+
+//getCommittedHeight return the last layer2 height committed to L1
+func (this *Layer2Operator) getCommittedHeight() (uint32, error) {
+	params, err := this.bridge.GetCurrentHeightParams()
+	if err != nil {
+		return 0, err
+	}
+	result, err := this.l1.Call(params)
+	if err != nil {
+		return 0, err
+	}
+	height, err := this.bridge.ParseCurrentHeight(result)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(height), nil
+}
+
+//runCommitJob commit the layer2 state to ontology. If the state of the height is already on ontology, which is
+//committed before exit, the commit is recorded as finished instead of sent again
+func (this *Layer2Operator) runCommitJob(job *Job) error {
+	msg := &Layer2CommitMsg{}
+	err := json.Unmarshal([]byte(job.Payload), msg)
+	if err != nil || msg.Layer2State == nil {
+		log.Errorf("parse layer2 commit job %d err: %v", job.ID, err)
+		return nil
+	}
+	exit, err := this.checkLayer2StateByHeight(uint64(msg.Layer2State.Height))
+	if err != nil {
+		return err
+	}
+	if exit {
+		commitLog(this.l1.Name(), msg.Layer2State.Height, "").Infof("layer2 state of height %d is committed to ontology before exit", msg.Layer2State.Height)
+		this.saveCommittedLayer2Msg(msg)
+		return nil
+	}
+	if this.multiSig != nil && !this.config().MultiSigConfig.Proposer {
+		return &waitError{fmt.Errorf("wait for the proposer to commit layer2 state of height %d", msg.Layer2State.Height)}
+	}
+	return this.commitLayer2State2L1(msg)
+}
+
+//layer2CommitParams build the params of updateState from the commit msg
+func (this *Layer2Operator) layer2CommitParams(msg *Layer2CommitMsg) ([]interface{}, error) {
 	depositids := make([]uint64, 0)
 	for _, id := range msg.Deposits {
 		depositids = append(depositids, id)
 	}
 	withdrawAmounts := make([]uint64, 0)
-	toAddresses := make([]ontology_common.Address, 0)
+	toAddresses := make([][]byte, 0)
 	assetAddress := make([][]byte, 0)
+	tokenIds := make([][]byte, 0)
+	layer2Assets := make([][]byte, 0)
+	withdrawProofs := make([][]byte, 0)
 	for _, withdraw := range msg.WithDraws {
-		withdrawAmounts = append(withdrawAmounts, withdraw.Amount)
+		// the fee stays in the contract on L1
+		withdrawAmounts = append(withdrawAmounts, withdraw.Amount - this.withdrawFee(withdraw))
 		toAddress, _ := ontology_common.AddressFromBase58(withdraw.ToAddress)
-		toAddresses = append(toAddresses,toAddress)
+		toAddresses = append(toAddresses,toAddress[:])
 		tokenAddress, _ := hex.DecodeString(withdraw.TokenAddress)
 		assetAddress = append(assetAddress, tokenAddress)
+		tokenId, _ := hex.DecodeString(withdraw.TokenId)
+		tokenIds = append(tokenIds, tokenId)
+		token := this.tokens.get(withdraw.TokenAddress)
+		if token == nil {
+			return nil, fmt.Errorf("token %s of withdraw %s is not registered", withdraw.TokenAddress, withdraw.TxHash)
+		}
+		layer2Asset, _ := hex.DecodeString(token.Layer2Address)
+		layer2Assets = append(layer2Assets, layer2Asset)
+		proof, err := this.withdrawProof(msg, layer2Asset, toAddress[:])
+		if err != nil {
+			return nil, fmt.Errorf("get proof of withdraw %s failed! err: %s", withdraw.TxHash, err.Error())
+		}
+		withdrawProofs = append(withdrawProofs, proof)
 	}
-	result, err := this.PreExecInvokeNeoVMContract(contractAddress, []interface{}{"updateState", []interface{}{
-		msg.Layer2State.StatesRoot.ToHexString(), msg.Layer2State.Height, string(msg.Layer2State.Version),
-		depositids, withdrawAmounts,toAddresses,assetAddress}})
-	var gasLimit uint64
+	params, err := this.bridge.UpdateStateParams(&bridge.UpdateStateParam{
+		StateRootHash:   msg.Layer2State.StatesRoot.ToHexString(),
+		Height:          msg.Layer2State.Height,
+		Version:         string(msg.Layer2State.Version),
+		DepositIds:      depositids,
+		WithdrawAmounts: withdrawAmounts,
+		ToAddresses:     toAddresses,
+		AssetAddresses:  assetAddress,
+		TokenIds:        tokenIds,
+		Layer2Assets:    layer2Assets,
+		WithdrawProofs:  withdrawProofs,
+	})
 	if err != nil {
-		gasLimit = 6000000
-	} else {
-		gasLimit = result.Gas
+		return nil, fmt.Errorf("build layer2 state commit params failed! err: %s", err.Error())
 	}
-	tx, err := this.ontologySdk.NeoVM.NewNeoVMInvokeTransaction(500, gasLimit, contractAddress, []interface{}{"updateState", []interface{}{
-		msg.Layer2State.StatesRoot.ToHexString(), msg.Layer2State.Height, string(msg.Layer2State.Version),
-		depositids, withdrawAmounts,toAddresses,assetAddress}})
+	return params, nil
+}
+
+func (this *Layer2Operator) commitLayer2State2L1(msg *Layer2CommitMsg) error {
+	layer2Msg := msg.Dump()
+	commitLog(this.l1.Name(), msg.Layer2State.Height, "").Infof("commit layer2 state to %s: %s", this.l1.Name(), layer2Msg)
+	start := time.Now()
+	//
+	params, err := this.layer2CommitParams(msg)
 	if err != nil {
-		return fmt.Errorf("new layer2 state commit transaction failed! err: %s", err.Error())
+		this.traceCommitMsg(msg, "", start, err)
+		return err
+	}
+	if this.dryRun() {
+		return this.dryRunCommitState(msg, params)
+	}
+	if err := this.checkCommitFunds(msg, params); err != nil {
+		return err
 	}
-	this.ontologySdk.SetPayer(tx, this.ontologyAccount.Address)
-	err = this.ontologySdk.SignToTransaction(tx, this.ontologyAccount)
+	var txHash string
+	if this.multiSig != nil {
+		txHash, err = this.multiSignCommit(params, msg.fromHeight(), msg.Layer2State.Height)
+	} else {
+		txHash, err = this.l1.SubmitStateCommit(params)
+	}
+	this.traceCommitMsg(msg, txHash, start, err)
 	if err != nil {
-		return fmt.Errorf("sign layer2 state commit transaction failed! err: %s", err.Error())
+		return err
 	}
+	commitLog(this.l1.Name(), msg.Layer2State.Height, txHash).Infof("layer2 state commit transaction hash: %s", txHash)
+	audit(AUDIT_COMMIT, AUDIT_BY_OPERATOR, txHash, "commit layer2 state of height %d, deposits: %d, withdraws: %d",
+		msg.Layer2State.Height, len(msg.Deposits), len(msg.WithDraws))
 
-	var txHash ontology_common.Uint256
-	for true {
-		txHash, err = this.ontologySdk.SendTransaction(tx)
-		if err != nil {
-			log.Errorf("send layer2 state commit transaction failed! err: %s, try again......", err.Error())
-			time.Sleep(time.Second * 1)
-		} else {
-			break
-		}
+	//
+	this.saveLayer2Commit(msg, txHash)
+	return nil
+}
+
+//saveCommittedLayer2Msg record the commit msg found committed on ontology as finished
+func (this *Layer2Operator) saveCommittedLayer2Msg(msg *Layer2CommitMsg) {
+	for _, id := range msg.Deposits {
+		UpdateDepositByID2(id, DEPOSIT_NOTIFY)
+	}
+	for _, withdraw := range msg.WithDraws {
+		UpdateWithdraw(withdraw.TxHash, WITHDRAW_COMMIT, "")
 	}
-	log.Infof("layer2 state commit transaction hash: %s", txHash.ToHexString())
+	this.saveWithdrawFees(msg)
+	saveFinishedLayer2Commit(msg.Layer2State.Height, msg.Dump1())
+	this.traceCommitMsg(msg, "", time.Now(), nil)
+}
 
-	//
+func (this *Layer2Operator) saveLayer2Commit(msg *Layer2CommitMsg, txHash string) {
 	for _, id := range msg.Deposits {
 		UpdateDepositByID2(id, DEPOSIT_NOTIFY)
 	}
 	for _, withdraw := range msg.WithDraws {
-		UpdateWithdraw(withdraw.TxHash, WITHDRAW_COMMIT, txHash.ToHexString())
+		UpdateWithdraw(withdraw.TxHash, WITHDRAW_COMMIT, txHash)
 	}
-	SaveLayer2Commit(txHash.ToHexString(), msg.Dump1(), uint64(msg.Layer2State.Height))
-	return nil
+	this.saveWithdrawFees(msg)
+	SaveLayer2Commit(txHash, msg.Dump1(), uint64(msg.Layer2State.Height), uint32(time.Now().Unix()))
 }
 
 func (this *Layer2Operator) checkMsgLoop() {
 	log.Infof("start checkMsgLoop")
+	defer close(this.drained)
 	for true {
-		this.checkLayer2State()
-		time.Sleep(time.Second * 1)
+		if this.isLeading() {
+			this.checkLayer2State()
+		}
+		select {
+		case <- time.After(time.Second * 1):
+		case <- this.ctx.Done():
+			this.drainCommits()
+			log.Infof("checkMsgLoop exit")
+			return
+		}
 	}
 }
 
@@ -707,41 +1675,39 @@ func (this *Layer2Operator) checkLayer2State() {
 				continue
 			} else if confirmed == 1 {
 				UpdateLayer2Commit(txHash, uint64(0), LAYER2MSG_FAILED)
-				log.Infof("layer2 commit: %s is failed.", txHash)
+				this.metrics.commitFailure.Inc(1)
+				commitLog(this.l1.Name(), 0, txHash).Infof("layer2 commit: %s is failed.", txHash)
+				this.traceCommitConfirm(txHash, fmt.Errorf("commit is not confirmed on %s", this.l1.Name()))
 				txConfirmed[i] = 0
 				this.mu.Lock()
-				this.layer2ChainInfo.Height --
-				this.needCheck = true
+				this.rewindLayer2Parse()
 				this.mu.Unlock()
 				continue
 			}
 
-			event, err := this.ontologySdk.GetSmartContractEvent(txHash)
-			if err != nil {
-				log.Errorf("get smart contract event failed! hash: %s, err: %s", txHash, err.Error())
-				txConfirmed[i] --
-				continue
-			}
-			heigth, err := this.ontologySdk.GetBlockHeightByTxHash(txHash)
+			result, err := this.l1.CheckCommit(txHash)
 			if err != nil {
-				log.Errorf("get tx height failed! hash: %s, err: %s", txHash, err.Error())
+				commitLog(this.l1.Name(), 0, txHash).Errorf("get tx result failed! hash: %s, err: %s", txHash, err.Error())
 				txConfirmed[i] --
 				continue
 			}
-			if event == nil {
+			if result == nil {
 				log.Infof("layer2 commit: %s is not finished.", txHash)
 				txConfirmed[i] --
 				continue
 			}
-			if event.State == 1 {
-				UpdateLayer2Commit(event.TxHash, uint64(heigth), LAYER2MSG_FINISH)
-				log.Infof("layer2 commit: %s is finished.", txHash)
+			UpdateLayer2CommitCost(txHash, result.GasPrice, result.Fee)
+			this.metrics.updateCommitResult(result)
+			if result.Success {
+				UpdateLayer2Commit(txHash, uint64(result.Height), LAYER2MSG_FINISH)
+				commitLog(this.l1.Name(), 0, txHash).Infof("layer2 commit: %s is finished.", txHash)
+				this.traceCommitConfirm(txHash, nil)
 			} else {
-				UpdateLayer2Commit(event.TxHash, uint64(heigth), LAYER2MSG_FAILED)
-				log.Infof("layer2 commit: %s is failed.", txHash)
+				UpdateLayer2Commit(txHash, uint64(result.Height), LAYER2MSG_FAILED)
+				commitLog(this.l1.Name(), 0, txHash).Infof("layer2 commit: %s is failed.", txHash)
+				this.traceCommitConfirm(txHash, fmt.Errorf("commit is failed on %s", this.l1.Name()))
 				this.mu.Lock()
-				this.layer2ChainInfo.Height --
-				this.needCheck = true
+				this.rewindLayer2Parse()
 				this.mu.Unlock()
 			}
 			txConfirmed[i] = 0
@@ -780,127 +1746,77 @@ func (this *Layer2Operator) checkLayer2State() {
 		}
 		if allConfired == true {
 			break
-		} else {
-			time.Sleep(time.Second * 1)
+		}
+		// the commits still unconfirmed are checked again on restart
+		select {
+		case <- time.After(time.Second * 1):
+		case <- this.drain.Done():
+			return
 		}
 	}
 }
 
 func (this *Layer2Operator) checkLayer2StateByHeight(height uint64) (bool, error) {
-	contractAddress, _ := ontology_common.AddressFromHexString(this.config.OntologyConfig.Layer2ContractAddress)
-	tx, err := this.ontologySdk.NeoVM.NewNeoVMInvokeTransaction(0, 0, contractAddress, []interface{}{"getStateRootByHeight", []interface{}{height}})
+	params, err := this.bridge.GetStateRootByHeightParams(height)
 	if err != nil {
-		return false, fmt.Errorf("new transaction failed!")
+		return false, err
 	}
-	result, err := this.ontologySdk.PreExecTransaction(tx)
+	result, err := this.l1.Call(params)
 	if err != nil {
 		return false, nil
 	}
-	if result == nil || result.Result == nil {
-		return false, nil
-	}
-	data, _ := result.Result.ToArray()
-	if len(data) != 3 {
+	stateRoot, err := this.bridge.ParseStateRoot(result)
+	if err != nil {
 		return false, nil
 	}
-	item1,_ := data[1].ToInteger()
-	if item1.Uint64() != height {
+	if stateRoot.Height != height {
 		return false, nil
 	} else {
 		return true, nil
 	}
 }
 
-func (this *Layer2Operator) PreExecInvokeNeoVMContract(contractAddress ontology_common.Address, params []interface{}) (*ontology_sdk_common.PreExecResult, error) {
-	tx, err := this.ontologySdk.NeoVM.NewNeoVMInvokeTransaction(0, 0, contractAddress, params)
-	if err != nil {
-		return nil, err
-	}
-	this.ontologySdk.SetPayer(tx, this.ontologyAccount.Address)
-	err = this.ontologySdk.SignToTransaction(tx, this.ontologyAccount)
-	if err != nil {
-		return nil, fmt.Errorf("sign layer2 state commit transaction failed! err: %s", err.Error())
-	}
-	return this.ontologySdk.PreExecTransaction(tx)
-}
-
-func isLayer2Tx(addr string) bool {
-	newAddr,_ := layer2_common.AddressFromBase58(addr)
-	if newAddr.ToHexString() == layer2_common.ADDRESS_EMPTY.ToHexString() {
-		return true
-	} else {
-		return false
-	}
-}
-
-func (this *Layer2Operator) testLoop() {
-	updateTicker := time.NewTicker(time.Second * 100)
-	for {
-		select {
-		case <- updateTicker.C:
-			this.test()
-		}
-	}
-}
-
-func (this *Layer2Operator) test() error {
-	if this.deposit != 1 || this.withdraw != 0 || this.layer2ChainInfo.Height <= this.depositHeight {
-		return nil
-	}
-
-	this.withdraw = 1
-	ontAddr, _ := layer2_common.AddressFromHexString(ONT_CONTRACT_ADDRESS)
-	ongAddr, _ := layer2_common.AddressFromHexString(ONG_CONTRACT_ADDRESS)
-
-	txhash, err := this.transfer(this.layer2Account, ontAddr, this.layer2Account.Address, layer2_common.ADDRESS_EMPTY, 1000)
-	if err != nil {
-		log.Errorf("test - withdraw ont err: %s", err.Error())
-	} else {
-		log.Infof("test - withdraw ont from %s to %s, hash: %s", this.layer2Account.Address.ToBase58(), layer2_common.ADDRESS_EMPTY.ToBase58(), txhash.ToHexString())
+//verifyDepositMint fetch the event of the mint transaction of deposit, and return the reason if the mint transfer of
+//index mismatches
+//the deposit, or empty if the amount, recipient, token and token id of the minted transfer match the deposit
+func (this *Layer2Operator) verifyDepositMint(deposit *Deposit, txHash string, index int) (string, error) {
+	if deposit == nil {
+		return "deposit of mint tx is not found", nil
 	}
-	txhash, err = this.transfer(this.layer2Account, ongAddr, this.layer2Account.Address, layer2_common.ADDRESS_EMPTY, 1000)
+	event, err := this.layer2Sdk().GetSmartContractEvent(txHash)
 	if err != nil {
-		log.Errorf("test - withdraw ong err: %s", err.Error())
-	} else {
-		log.Infof("test - withdraw ong from %s to %s, hash: %s", this.layer2Account.Address.ToBase58(), layer2_common.ADDRESS_EMPTY.ToBase58(), txhash.ToHexString())
+		return "", err
 	}
-
-	txhash, err = this.transfer(this.layer2Account, ontAddr, this.layer2Account.Address, this.layer2Account.Address, 1000)
-	if err != nil {
-		log.Errorf("test - transfer ont err: %s", err.Error())
-	} else {
-		log.Infof("test - transfer ont from %s to %s, hash: %s", this.layer2Account.Address.ToBase58(), this.ontologyAccount.Address.ToBase58(), txhash.ToHexString())
+	if event == nil {
+		return "", fmt.Errorf("event of mint tx is not found")
 	}
-	txhash, err = this.transfer(this.layer2Account, ongAddr, this.layer2Account.Address, this.layer2Account.Address, 1000)
-	if err != nil {
-		log.Errorf("test - transfer ong err: %s", err.Error())
-	} else {
-		log.Infof("test - transfer ong from %s to %s, hash: %s", this.layer2Account.Address.ToBase58(), this.ontologyAccount.Address.ToBase58(), txhash.ToHexString())
+	if event.State != 1 {
+		return "mint tx failed", nil
 	}
-
-	return nil
-}
-
-func (this *Layer2Operator) transfer(payer *layer2_sdk.Account, token layer2_common.Address, from layer2_common.Address, to layer2_common.Address, amount uint64) (layer2_common.Uint256, error) {
-	var tx *layer2_types.MutableTransaction
-	var err error
-	if token.ToHexString() == ONT_CONTRACT_ADDRESS {
-		tx, err = this.layer2Sdk.Native.Ont.NewTransferTransaction(0, 20000, from, to, amount)
-		if err != nil {
-			return layer2_common.UINT256_EMPTY, err
+	for _, notify := range event.Notify {
+		// the undecodable notify is counted when the block is parsed
+		transfer, err := this.decodeTransferNotify(notify)
+		if err != nil || transfer == nil || !isLayer2Tx(transfer.From) {
+			continue
 		}
-	} else if token.ToHexString() == ONG_CONTRACT_ADDRESS {
-		tx, err = this.layer2Sdk.Native.Ong.NewTransferTransaction(0, 20000, from, to, amount)
-		if err != nil {
-			return layer2_common.UINT256_EMPTY, err
+		if index > 0 {
+			index --
+			continue
 		}
-	}
-	if payer != nil {
-		this.layer2Sdk.SetPayer(tx, payer.Address)
-		err = this.layer2Sdk.SignToTransaction(tx, payer)
-		if err != nil {
-			return layer2_common.UINT256_EMPTY, err
+		if transfer.Token != deposit.TokenAddress {
+			return fmt.Sprintf("minted token %s, deposit token %s", transfer.Token, deposit.TokenAddress), nil
 		}
+		if transfer.TokenId != deposit.TokenId {
+			return fmt.Sprintf("minted token id %s, deposit token id %s", transfer.TokenId, deposit.TokenId), nil
+		}
+		if transfer.To != deposit.FromAddress {
+			return fmt.Sprintf("minted to %s, deposit from %s", transfer.To, deposit.FromAddress), nil
+		}
+		if transfer.Amount != deposit.Amount {
+			return fmt.Sprintf("minted amount %d, deposit amount %d", transfer.Amount, deposit.Amount), nil
+		}
+		return "", nil
 	}
-	return this.layer2Sdk.SendTransaction(tx)
+	return "mint transfer is not found in tx event", nil
 }
+
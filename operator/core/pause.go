@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+// the processing paused by the admin, the pause of a token is saved with the token address after PAUSE_TOKEN_PREFIX
+const (
+	PAUSE_DEPOSITS     = "deposits"
+	PAUSE_COMMITS      = "commits"
+	PAUSE_TOKEN        = "token"
+	PAUSE_TOKEN_PREFIX = "token:"
+)
+
+//PauseRequest pause or resume the minting of the deposits, the commits of the layer2 states, or the minting of the
+//deposits of the token of Address
+type PauseRequest struct {
+	Target          string
+	Address         string
+}
+
+//pauseTarget return the target saved in the database of the request
+func (this *Layer2Operator) pauseTarget(req *PauseRequest) (string, error) {
+	switch req.Target {
+	case PAUSE_DEPOSITS, PAUSE_COMMITS:
+		return req.Target, nil
+	case PAUSE_TOKEN:
+		if this.tokens.get(req.Address) == nil {
+			return "", fmt.Errorf("token %s is not registered", req.Address)
+		}
+		return PAUSE_TOKEN_PREFIX + req.Address, nil
+	default:
+		return "", fmt.Errorf("invalid pause target %s", req.Target)
+	}
+}
+
+//adminPause pause or resume the target of the request, the pause is saved in the database so that it is kept on
+//restart and shared by the operators of the high availability
+func (this *Layer2Operator) adminPause(r *http.Request, paused bool) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	req := &PauseRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %s", err)
+	}
+	target, err := this.pauseTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	if paused {
+		err = SavePause(target, uint32(time.Now().Unix()))
+	} else {
+		err = DeletePause(target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("admin - set %s paused: %v", target, paused)
+	if paused {
+		audit(AUDIT_PAUSE, adminInitiator(r), "", "pause %s", target)
+	} else {
+		audit(AUDIT_RESUME, adminInitiator(r), "", "resume %s", target)
+	}
+	// the held deposits of the resumed token are released by the deposit loop
+	if !paused {
+		this.notifyJobs()
+	}
+	return LoadPauses(), nil
+}
+
+//notifyJobs wake up the job loops to run the resumed jobs
+func (this *Layer2Operator) notifyJobs() {
+	for _, notify := range []chan struct{}{this.depositNotify, this.commitNotify} {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//isPaused return true if the jobs of kind are paused, or the pauses are unknown
+func isPaused(kind int) bool {
+	pauses := LoadPauses()
+	if pauses == nil {
+		log.Errorf("load pauses failed, the jobs of kind %d are paused", kind)
+		return true
+	}
+	target := PAUSE_DEPOSITS
+	if kind == JOB_COMMIT {
+		target = PAUSE_COMMITS
+	}
+	for _, pause := range pauses {
+		if pause == target {
+			return true
+		}
+	}
+	return false
+}
+
+//holdPausedJobs set the deposit jobs of the paused tokens held and return the jobs to run. The held jobs of the tokens
+//resumed are set pending again, so they are run after the jobs loaded this time
+func (this *Layer2Operator) holdPausedJobs(kind int, jobs []*Job) ([]*Job, error) {
+	if kind != JOB_DEPOSIT {
+		return jobs, nil
+	}
+	pauses := LoadPauses()
+	if pauses == nil {
+		return nil, fmt.Errorf("load pauses failed")
+	}
+	tokens := make(map[string]bool)
+	for _, pause := range pauses {
+		if strings.HasPrefix(pause, PAUSE_TOKEN_PREFIX) {
+			tokens[strings.TrimPrefix(pause, PAUSE_TOKEN_PREFIX)] = true
+		}
+	}
+	held := LoadJobsByState(JOB_DEPOSIT, JOB_HELD)
+	if held == nil {
+		return nil, fmt.Errorf("load held jobs failed")
+	}
+	for _, job := range held {
+		if tokens[jobTokenAddress(job)] {
+			continue
+		}
+		err := UpdateJobState(job.ID, JOB_PENDING)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("release job %d of deposit %d, the token is resumed", job.ID, job.Key)
+	}
+	result := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		if !tokens[jobTokenAddress(job)] {
+			result = append(result, job)
+			continue
+		}
+		err := UpdateJobState(job.ID, JOB_HELD)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("hold job %d of deposit %d, the token is paused", job.ID, job.Key)
+	}
+	return result, nil
+}
+
+//jobTokenAddress return the token address of the deposit of the job, empty if the payload is invalid
+func jobTokenAddress(job *Job) string {
+	deposit := &Deposit{}
+	if err := json.Unmarshal([]byte(job.Payload), deposit); err != nil {
+		return ""
+	}
+	return deposit.TokenAddress
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	ontology_common "github.com/ontio/ontology/common"
+)
+
+const (
+	// the payer failing to send the commit is tried after the other payers in the hold time
+	PAYER_HOLD_TIME = 10 * time.Minute
+)
+
+//payerPool is the accounts paying the updateState transactions in rotation, every commit starts from the payer after
+//the one the last commit started from. The transactions of a payer are sent one by one by the submission queue, so the
+//commits of different payers are not serialized by one nonce, and a stuck payer does not block the others
+type payerPool struct {
+	mu        sync.Mutex
+	payers    []*ontologySigner
+	next      int
+	// the time until the payer is held
+	held      map[ontology_common.Address]time.Time
+}
+
+func newPayerPool(payers []*ontologySigner) *payerPool {
+	return &payerPool{
+		payers: payers,
+		held:   make(map[ontology_common.Address]time.Time),
+	}
+}
+
+//Rotate return the payers in the order tried by the next commit, the held payers are put after the others
+func (this *payerPool) Rotate() []*ontologySigner {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	now := time.Now()
+	ready := make([]*ontologySigner, 0, len(this.payers))
+	held := make([]*ontologySigner, 0)
+	for i := 0; i < len(this.payers); i ++ {
+		payer := this.payers[(this.next + i) % len(this.payers)]
+		if until, ok := this.held[payer.Address]; ok && now.Before(until) {
+			held = append(held, payer)
+			continue
+		}
+		delete(this.held, payer.Address)
+		ready = append(ready, payer)
+	}
+	this.next = (this.next + 1) % len(this.payers)
+	return append(ready, held...)
+}
+
+//Hold put the payer after the others for PAYER_HOLD_TIME
+func (this *payerPool) Hold(payer *ontologySigner) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.held[payer.Address] = time.Now().Add(PAYER_HOLD_TIME)
+}
+
+//Release put the payer in rotation again once it sent a commit
+func (this *payerPool) Release(payer *ontologySigner) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	delete(this.held, payer.Address)
+}
+
+//Addresses return the base58 addresses of the payers
+func (this *payerPool) Addresses() []string {
+	addresses := make([]string, 0, len(this.payers))
+	for _, payer := range this.payers {
+		addresses = append(addresses, payer.Address.ToBase58())
+	}
+	return addresses
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	ontology_common "github.com/ontio/ontology/common"
+)
+
+func testPayers(n int) []*ontologySigner {
+	payers := make([]*ontologySigner, 0, n)
+	for i := 0; i < n; i ++ {
+		payers = append(payers, &ontologySigner{Address: ontology_common.Address{byte(i + 1)}})
+	}
+	return payers
+}
+
+func payerOrder(payers []*ontologySigner) []byte {
+	order := make([]byte, 0, len(payers))
+	for _, payer := range payers {
+		order = append(order, payer.Address[0])
+	}
+	return order
+}
+
+func TestPayerPoolRotate(t *testing.T) {
+	pool := newPayerPool(testPayers(3))
+	// every commit starts from the payer after the one the last commit started from
+	for _, want := range []string{"\x01\x02\x03", "\x02\x03\x01", "\x03\x01\x02", "\x01\x02\x03"} {
+		if order := payerOrder(pool.Rotate()); string(order) != want {
+			t.Errorf("payer order %v, want %v", order, []byte(want))
+		}
+	}
+}
+
+func TestPayerPoolHold(t *testing.T) {
+	payers := testPayers(3)
+	pool := newPayerPool(payers)
+	pool.Hold(payers[0])
+	// the held payer is tried after the others
+	if order := payerOrder(pool.Rotate()); string(order) != "\x02\x03\x01" {
+		t.Errorf("payer order %v with payer 1 held", order)
+	}
+	pool.Hold(payers[2])
+	if order := payerOrder(pool.Rotate()); string(order) != "\x02\x03\x01" {
+		t.Errorf("payer order %v with payers 1 and 3 held", order)
+	}
+	pool.Release(payers[2])
+	if order := payerOrder(pool.Rotate()); string(order) != "\x03\x02\x01" {
+		t.Errorf("payer order %v with payer 1 held", order)
+	}
+	// the hold expires after PAYER_HOLD_TIME
+	pool.held[payers[0].Address] = time.Now().Add(-time.Second)
+	if order := payerOrder(pool.Rotate()); string(order) != "\x01\x02\x03" {
+		t.Errorf("payer order %v after the hold expired", order)
+	}
+	if len(pool.held) != 0 {
+		t.Errorf("expired hold is kept")
+	}
+}
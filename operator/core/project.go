@@ -21,27 +21,35 @@ package core
 
 import (
 	"database/sql"
+	"math"
+
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/ontio/layer2/operator/config"
 	"github.com/ontio/layer2/operator/log"
 )
 
 var DefDB *sql.DB
 
-func ConnectDB(dbuser string, dbpassword string, dburl string, dbname string) error {
-	db, dberr := sql.Open("mysql",
-		dbuser+
-			":"+dbpassword+
-			"@tcp("+dburl+
-			")/"+dbname+
-			"?charset=utf8")
+//ConnectDB connect the database of the config by the driver of its dialect
+func ConnectDB(cfg *config.DBConfig) error {
+	dialect, err := NewDialect(cfg.ProjectDBDriver)
+	if err != nil {
+		return err
+	}
+	db, dberr := sql.Open(dialect.DriverName(), dialect.DataSource(cfg))
 	if dberr != nil {
 		return dberr
 	}
-	err := db.Ping()
+	err = db.Ping()
+	if err != nil {
+		return err
+	}
+	err = dialect.Init(db)
 	if err != nil {
 		return err
 	}
 	DefDB = db
+	DefDialect = dialect
 	return nil
 }
 
@@ -101,8 +109,31 @@ func SetChainParseHeight(id uint32, height uint32) error {
 	return dberr
 }
 
-func SaveDeposit(deposit *Deposit) error {
-	strSql := "insert into deposit(txhash, tt, state, height, fromaddress, amount, tokenaddress, id) values (?,?,?,?,?,?,?,?)"
+//SaveDeposit save the deposit if neither its id nor its event is saved, and return whether it is saved. The deposit
+//saved already is not changed, so the event parsed again is found by LoadDepositByID
+func SaveDeposit(deposit *Deposit) (bool, error) {
+	strSql := "insert ignore into deposit(txhash, notifyindex, tt, state, height, fromaddress, amount, tokenaddress, id, tokenid) values (?,?,?,?,?,?,?,?,?,?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return false, dberr
+	}
+	result, dberr := stmt.Exec(deposit.TxHash, deposit.NotifyIndex, deposit.TT, deposit.State,deposit.Height, deposit.FromAddress, deposit.Amount, deposit.TokenAddress, deposit.ID, deposit.TokenId)
+	if dberr != nil {
+		return false, dberr
+	}
+	rows, dberr := result.RowsAffected()
+	if dberr != nil {
+		return false, dberr
+	}
+	return rows > 0, nil
+}
+
+//UpdateDeposit overwrite the deposit of the id by the deposit event found again after an ontology reorg
+func UpdateDeposit(deposit *Deposit) error {
+	strSql := "update deposit set txhash = ?, notifyindex = ?, tt = ?, state = ?, height = ?, fromaddress = ?, amount = ?, tokenaddress = ?, tokenid = ?, layer2txhash = '' where id = ?"
 	stmt, dberr := DefDB.Prepare(strSql)
 	if stmt != nil {
 		defer stmt.Close()
@@ -110,7 +141,7 @@ func SaveDeposit(deposit *Deposit) error {
 	if dberr != nil {
 		return dberr
 	}
-	_, dberr = stmt.Exec(deposit.TxHash, deposit.TT, deposit.State,deposit.Height, deposit.FromAddress, deposit.Amount, deposit.TokenAddress, deposit.ID)
+	_, dberr = stmt.Exec(deposit.TxHash, deposit.NotifyIndex, deposit.TT, deposit.State, deposit.Height, deposit.FromAddress, deposit.Amount, deposit.TokenAddress, deposit.TokenId, deposit.ID)
 	return dberr
 }
 
@@ -127,6 +158,20 @@ func UpdateDepositByID(id uint64, state int, layer2TxHash string) error {
 	return dberr
 }
 
+//UpdateDepositRefund save the L1 transaction refunding the deposit
+func UpdateDepositRefund(id uint64, state int, refundTxHash string) error {
+	strSql := "update deposit set refundtxhash = ?, state = ? where id = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(refundTxHash, state, id)
+	return dberr
+}
+
 func UpdateDepositByID2(id uint64, state int) error {
 	strSql := "update deposit set state = ? where id = ?"
 	stmt, dberr := DefDB.Prepare(strSql)
@@ -140,8 +185,10 @@ func UpdateDepositByID2(id uint64, state int) error {
 	return dberr
 }
 
-func LoadDepositByLayer2TxHash(layer2TxHash string) *Deposit {
-	strsql := "select txhash,tt,state,height,fromaddress,amount,tokenaddress,id,layer2txhash from deposit where layer2txhash = ?"
+//LoadDepositsByLayer2TxHash return the deposits minted by the layer2 transaction in order of id, which is the order
+//of the transfers of a batch mint
+func LoadDepositsByLayer2TxHash(layer2TxHash string) []*Deposit {
+	strsql := "select txhash,tt,state,height,fromaddress,amount,tokenaddress,id,layer2txhash,ifnull(tokenid,'') from deposit where layer2txhash = ? order by id"
 	stmt, err := DefDB.Prepare(strsql)
 	if stmt != nil {
 		defer stmt.Close()
@@ -159,31 +206,45 @@ func LoadDepositByLayer2TxHash(layer2TxHash string) *Deposit {
 
 	var height,tt uint32
 	var state int
-	var txhash, fromaddress,tokenaddress string
+	var txhash, fromaddress,tokenaddress,tokenid string
 	var amount,id uint64
-	var deposit *Deposit
+	deposits := make([]*Deposit, 0)
 	for rows.Next() {
-		if err = rows.Scan(&txhash, &tt, &state, &height, &fromaddress, &amount, &tokenaddress, &id, &layer2TxHash); err != nil {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &fromaddress, &amount, &tokenaddress, &id, &layer2TxHash, &tokenid); err != nil {
 			return nil
 		} else {
-			deposit = &Deposit{
+			deposits = append(deposits, &Deposit{
 				TxHash : txhash,
 				TT: tt,
 				State: state,
 				Height: height,
 				FromAddress: fromaddress,
+				Amount: amount,
 				TokenAddress: tokenaddress,
+				TokenId: tokenid,
 				ID: id,
 				Layer2TxHash: layer2TxHash,
-			}
-			break
+			})
 		}
 	}
-	return deposit
+	return deposits
+}
+
+func SaveDepositQuarantine(quarantine *DepositQuarantine) error {
+	strSql := "insert into depositquarantine(layer2txhash, tt, height, id, txhash, reason) values (?,?,?,?,?,?) ON DUPLICATE KEY UPDATE reason=VALUES(reason)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(quarantine.Layer2TxHash, quarantine.TT, quarantine.Height, quarantine.ID, quarantine.TxHash, quarantine.Reason)
+	return dberr
 }
 
 func SaveWithdraw(withdraw *Withdraw) error {
-	strSql := "insert into withdraw(txhash, tt, state, height, toaddress, amount, tokenaddress) values (?,?,?,?,?,?,?)"
+	strSql := "insert into withdraw(txhash, tt, state, height, toaddress, amount, tokenaddress, tokenid) values (?,?,?,?,?,?,?,?)"
 	stmt, dberr := DefDB.Prepare(strSql)
 	if stmt != nil {
 		defer stmt.Close()
@@ -192,7 +253,7 @@ func SaveWithdraw(withdraw *Withdraw) error {
 	if dberr != nil {
 		return dberr
 	}
-	_, dberr = stmt.Exec(withdraw.TxHash, withdraw.TT, withdraw.State,withdraw.Height, withdraw.ToAddress, withdraw.Amount, withdraw.TokenAddress)
+	_, dberr = stmt.Exec(withdraw.TxHash, withdraw.TT, withdraw.State,withdraw.Height, withdraw.ToAddress, withdraw.Amount, withdraw.TokenAddress, withdraw.TokenId)
 	return dberr
 }
 
@@ -211,7 +272,7 @@ func UpdateWithdraw(txHash string, state int, ontologyTxHash string) error {
 
 
 func SaveLayer2Tx(layer2Tx *Layer2Tx) error {
-	strSql := "insert into layer2tx(txhash, tt, state, fee, height, fromaddress, tokenaddress, toaddress, amount) values (?,?,?,?,?,?,?,?,?)"
+	strSql := "insert into layer2tx(txhash, tt, state, fee, height, fromaddress, tokenaddress, toaddress, amount, tokenid) values (?,?,?,?,?,?,?,?,?,?)"
 	stmt, dberr := DefDB.Prepare(strSql)
 	if stmt != nil {
 		defer stmt.Close()
@@ -219,12 +280,12 @@ func SaveLayer2Tx(layer2Tx *Layer2Tx) error {
 	if dberr != nil {
 		return dberr
 	}
-	_, dberr = stmt.Exec(layer2Tx.TxHash, layer2Tx.TT, layer2Tx.State,layer2Tx.Fee,layer2Tx.Height, layer2Tx.FromAddress,layer2Tx.TokenAddress,layer2Tx.ToAddress, layer2Tx.Amount)
+	_, dberr = stmt.Exec(layer2Tx.TxHash, layer2Tx.TT, layer2Tx.State,layer2Tx.Fee,layer2Tx.Height, layer2Tx.FromAddress,layer2Tx.TokenAddress,layer2Tx.ToAddress, layer2Tx.Amount, layer2Tx.TokenId)
 	return dberr
 }
 
 func LoadLayer2Tx(address string) []*Layer2Tx {
-	strsql := "select txhash, state, tt, fee, height, fromaddress, tokenaddress, toaddress, amount from layer2tx where fromaddress = ? or toaddress = ? order by height"
+	strsql := "select txhash, state, tt, fee, height, fromaddress, tokenaddress, toaddress, amount, ifnull(tokenid,'') from layer2tx where fromaddress = ? or toaddress = ? order by height"
 	stmt, err := DefDB.Prepare(strsql)
 	if stmt != nil {
 		defer stmt.Close()
@@ -243,10 +304,10 @@ func LoadLayer2Tx(address string) []*Layer2Tx {
 	var tt, height uint32
 	var state int
 	var fee, amount uint64
-	var txhash, fromaddress, tokenaddress,toaddress string
+	var txhash, fromaddress, tokenaddress,toaddress, tokenid string
 	layer2Txs := make([]*Layer2Tx, 0)
 	for rows.Next() {
-		if err = rows.Scan(&txhash, &state, &tt, &fee, &height, &fromaddress, &tokenaddress, &toaddress, &amount); err != nil {
+		if err = rows.Scan(&txhash, &state, &tt, &fee, &height, &fromaddress, &tokenaddress, &toaddress, &amount, &tokenid); err != nil {
 			return nil
 		} else {
 			layer2Txs = append(layer2Txs, &Layer2Tx{
@@ -259,14 +320,15 @@ func LoadLayer2Tx(address string) []*Layer2Tx {
 				TokenAddress: tokenaddress,
 				ToAddress: toaddress,
 				Amount: amount,
+				TokenId: tokenid,
 			})
 		}
 	}
 	return layer2Txs
 }
 
-func SaveLayer2Commit(txHash string, layer2Msg string, layer2Height uint64) error {
-	strSql := "insert into layer2commit(txhash, layer2msg, layer2height) values (?,?,?)"
+func SaveLayer2Commit(txHash string, layer2Msg string, layer2Height uint64, tt uint32) error {
+	strSql := "insert into layer2commit(txhash, layer2msg, layer2height, tt) values (?,?,?,?)"
 	stmt, dberr := DefDB.Prepare(strSql)
 	if stmt != nil {
 		defer stmt.Close()
@@ -274,10 +336,110 @@ func SaveLayer2Commit(txHash string, layer2Msg string, layer2Height uint64) erro
 	if dberr != nil {
 		return dberr
 	}
-	_, dberr = stmt.Exec(txHash, layer2Msg, layer2Height)
+	_, dberr = stmt.Exec(txHash, layer2Msg, layer2Height, tt)
 	return dberr
 }
 
+//LoadLastLayer2Commit return the commit in state of the highest layer2 height, nil if there is no such commit
+func LoadLastLayer2Commit(state int) *Layer2Commit {
+	commits := LoadRecentLayer2Commits(state, 1)
+	if len(commits) == 0 {
+		return nil
+	}
+	return commits[0]
+}
+
+//LoadRecentLayer2Commits return at most limit commits in state in order of layer2 height, the highest first
+func LoadRecentLayer2Commits(state int, limit int) []*Layer2Commit {
+	strsql := "select txhash,state,tt,ontologyheight,layer2height,gasprice,fee from layer2commit where state = ? order by layer2height desc limit ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(state, limit)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	commits := make([]*Layer2Commit, 0)
+	for rows.Next() {
+		commit := &Layer2Commit{}
+		if err = rows.Scan(&commit.TxHash, &commit.State, &commit.TT, &commit.Height, &commit.Layer2Height, &commit.GasPrice, &commit.Fee); err != nil {
+			return nil
+		} else {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+//LoadUnfinishedLayer2Commits return at most limit unconfirmed or failed commits in order of layer2 height, the highest
+//first
+func LoadUnfinishedLayer2Commits(limit int) []*Layer2Commit {
+	strsql := "select txhash,state,tt,ontologyheight,layer2height,gasprice,fee from layer2commit where state in (?, ?) order by layer2height desc limit ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(LAYER2MSG_COMMIT, LAYER2MSG_FAILED, limit)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	commits := make([]*Layer2Commit, 0)
+	for rows.Next() {
+		commit := &Layer2Commit{}
+		if err = rows.Scan(&commit.TxHash, &commit.State, &commit.TT, &commit.Height, &commit.Layer2Height, &commit.GasPrice, &commit.Fee); err != nil {
+			return nil
+		} else {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+//LoadLayer2CommitsByHeight return the commits of the layer2 height with their commit msgs, in order of submission
+func LoadLayer2CommitsByHeight(height uint64) []*Layer2Commit {
+	strsql := "select txhash,state,tt,ontologyheight,layer2height,gasprice,fee,layer2msg from layer2commit where layer2height = ? order by tt"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(height)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	commits := make([]*Layer2Commit, 0)
+	for rows.Next() {
+		commit := &Layer2Commit{}
+		if err = rows.Scan(&commit.TxHash, &commit.State, &commit.TT, &commit.Height, &commit.Layer2Height, &commit.GasPrice, &commit.Fee, &commit.Layer2Msg); err != nil {
+			return nil
+		} else {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
 func UpdateLayer2Commit(txHash string, height uint64, state int) error {
 	strSql := "update layer2commit set state = ?, ontologyheight = ? where txhash = ?"
 	stmt, dberr := DefDB.Prepare(strSql)
@@ -291,6 +453,34 @@ func UpdateLayer2Commit(txHash string, height uint64, state int) error {
 	return dberr
 }
 
+//UpdateLayer2CommitCost record the gas price and the fee paid by the executed commit transaction
+func UpdateLayer2CommitCost(txHash string, gasPrice uint64, fee uint64) error {
+	strSql := "update layer2commit set gasprice = ?, fee = ? where txhash = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(gasPrice, fee, txHash)
+	return dberr
+}
+
+//RevertLayer2CommitsAbove mark the finished commits above height as failed, they are reverted on L1 by a challenge
+func RevertLayer2CommitsAbove(height uint32) error {
+	strSql := "update layer2commit set state = ? where layer2height > ? and state = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(LAYER2MSG_FAILED, height, LAYER2MSG_FINISH)
+	return dberr
+}
+
 func GetLayer2CommitHeight() uint32 {
 	strsql := "select max(layer2height) from layer2commit where state = ?"
 	stmt, err := DefDB.Prepare(strsql)
@@ -348,3 +538,1052 @@ func LoadLayer2Commit_Unconfirmed() []string {
 	return txHashs
 }
 
+
+func LoadDepositByID(id uint64) *Deposit {
+	strsql := "select txhash,notifyindex,tt,state,height,fromaddress,amount,tokenaddress,ifnull(layer2txhash,''),ifnull(tokenid,''),ifnull(refundtxhash,'') from deposit where id = ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(id)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var height,tt,notifyIndex uint32
+	var state int
+	var txhash, fromaddress, tokenaddress, layer2TxHash, tokenid, refundTxHash string
+	var amount uint64
+	var deposit *Deposit
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &notifyIndex, &tt, &state, &height, &fromaddress, &amount, &tokenaddress, &layer2TxHash, &tokenid, &refundTxHash); err != nil {
+			return nil
+		} else {
+			deposit = &Deposit{
+				TxHash : txhash,
+				NotifyIndex: notifyIndex,
+				TT: tt,
+				State: state,
+				Height: height,
+				FromAddress: fromaddress,
+				Amount: amount,
+				TokenAddress: tokenaddress,
+				TokenId: tokenid,
+				ID: id,
+				Layer2TxHash: layer2TxHash,
+				RefundTxHash: refundTxHash,
+			}
+			break
+		}
+	}
+	return deposit
+}
+
+//LoadMaxDepositID return the max id of the deposits not below from, 0 if none
+func LoadMaxDepositID(from uint64) (uint64, error) {
+	var id uint64
+	err := DefDB.QueryRow("select ifnull(max(id), 0) from deposit where id >= ?", from).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func LoadWithdrawByToAddress(address string) []*Withdraw {
+	strsql := "select txhash,tt,state,height,toaddress,amount,tokenaddress,ifnull(ontologytxhash,''),ifnull(tokenid,'') from withdraw where toaddress = ? order by height"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(address)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var height,tt uint32
+	var state int
+	var txhash, toaddress, tokenaddress, ontologyTxHash, tokenid string
+	var amount uint64
+	withdraws := make([]*Withdraw, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &toaddress, &amount, &tokenaddress, &ontologyTxHash, &tokenid); err != nil {
+			return nil
+		} else {
+			withdraws = append(withdraws, &Withdraw{
+				TxHash: txhash,
+				TT: tt,
+				State: state,
+				Height: height,
+				ToAddress: toaddress,
+				Amount: amount,
+				TokenAddress: tokenaddress,
+				TokenId: tokenid,
+				OntologyTxHash: ontologyTxHash,
+			})
+		}
+	}
+	return withdraws
+}
+
+func LoadDepositsByState(state int) []*Deposit {
+	return loadDepositsByState("select txhash,tt,state,height,fromaddress,amount,tokenaddress,id,ifnull(layer2txhash,''),ifnull(tokenid,'') from deposit where state = ? order by id", state)
+}
+
+//LoadRecentDepositsByState return at most limit deposits in state in order of id, the latest first
+func LoadRecentDepositsByState(state int, limit int) []*Deposit {
+	return loadDepositsByState("select txhash,tt,state,height,fromaddress,amount,tokenaddress,id,ifnull(layer2txhash,''),ifnull(tokenid,'') from deposit where state = ? order by id desc limit ?", state, limit)
+}
+
+func loadDepositsByState(strsql string, state int, args ...interface{}) []*Deposit {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(append([]interface{}{state}, args...)...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var height,tt uint32
+	var txhash, fromaddress, tokenaddress, layer2TxHash, tokenid string
+	var amount, id uint64
+	deposits := make([]*Deposit, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &fromaddress, &amount, &tokenaddress, &id, &layer2TxHash, &tokenid); err != nil {
+			return nil
+		} else {
+			deposits = append(deposits, &Deposit{
+				TxHash : txhash,
+				TT: tt,
+				State: state,
+				Height: height,
+				FromAddress: fromaddress,
+				Amount: amount,
+				TokenAddress: tokenaddress,
+				TokenId: tokenid,
+				ID: id,
+				Layer2TxHash: layer2TxHash,
+			})
+		}
+	}
+	return deposits
+}
+
+func LoadDepositsAboveHeight(height uint32) []*Deposit {
+	strsql := "select txhash,tt,state,height,fromaddress,amount,tokenaddress,id,ifnull(layer2txhash,''),ifnull(tokenid,'') from deposit where height > ? order by id"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(height)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var state int
+	var tt uint32
+	var txhash, fromaddress, tokenaddress, layer2TxHash, tokenid string
+	var amount, id uint64
+	deposits := make([]*Deposit, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &fromaddress, &amount, &tokenaddress, &id, &layer2TxHash, &tokenid); err != nil {
+			return nil
+		} else {
+			deposits = append(deposits, &Deposit{
+				TxHash : txhash,
+				TT: tt,
+				State: state,
+				Height: height,
+				FromAddress: fromaddress,
+				Amount: amount,
+				TokenAddress: tokenaddress,
+				TokenId: tokenid,
+				ID: id,
+				Layer2TxHash: layer2TxHash,
+			})
+		}
+	}
+	return deposits
+}
+
+func DeleteDepositQuarantine(layer2TxHash string, id uint64) error {
+	strSql := "delete from depositquarantine where layer2txhash = ? and id = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(layer2TxHash, id)
+	return dberr
+}
+
+//SaveToken insert the token to the registry, the token registered already is kept as it is
+func SaveToken(token *Token) error {
+	strSql := "insert ignore into token(address, layer2address, name, decimals, enabled, standard) values (?,?,?,?,?,?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(token.Address, token.Layer2Address, token.Name, token.Decimals, token.Enabled, token.Standard)
+	return dberr
+}
+
+func UpdateTokenEnabled(address string, enabled bool) error {
+	strSql := "update token set enabled = ? where address = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(enabled, address)
+	return dberr
+}
+
+func LoadTokens() []*Token {
+	strsql := "select address,layer2address,name,decimals,enabled,standard from token order by address"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query()
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var address, layer2address, name, standard string
+	var decimals uint32
+	var enabled bool
+	tokens := make([]*Token, 0)
+	for rows.Next() {
+		if err = rows.Scan(&address, &layer2address, &name, &decimals, &enabled, &standard); err != nil {
+			return nil
+		} else {
+			tokens = append(tokens, &Token{
+				Name: name,
+				Address: address,
+				Layer2Address: layer2address,
+				Decimals: decimals,
+				Enabled: enabled,
+				Standard: standard,
+			})
+		}
+	}
+	return tokens
+}
+
+//SaveOntologyBlock record the hash of the parsed ontology block, and forget the blocks deeper than the reorg depth
+//tracked
+func SaveOntologyBlock(height uint32, hash string) error {
+	strSql := "insert into ontologyblock(height, hash) values (?,?) ON DUPLICATE KEY UPDATE hash=VALUES(hash)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(height, hash)
+	if dberr != nil {
+		return dberr
+	}
+	if height <= config.ONT_REORG_TRACK_BLOCKS {
+		return nil
+	}
+	_, dberr = DefDB.Exec("delete from ontologyblock where height <= ?", height - config.ONT_REORG_TRACK_BLOCKS)
+	return dberr
+}
+
+//LoadOntologyBlockHash return the hash of the parsed ontology block, empty if it is not tracked
+func LoadOntologyBlockHash(height uint32) string {
+	strsql := "select hash from ontologyblock where height = ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return ""
+	}
+	rows, err := stmt.Query(height)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return ""
+	}
+
+	var hash string
+	for rows.Next() {
+		if err = rows.Scan(&hash); err != nil {
+			return ""
+		} else {
+			return hash
+		}
+	}
+	return ""
+}
+
+func DeleteOntologyBlocksAbove(height uint32) error {
+	strSql := "delete from ontologyblock where height > ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(height)
+	return dberr
+}
+
+//DeleteLayer2RecordsAbove delete the layer2 transactions and withdraws parsed from the layer2 blocks above height
+func DeleteLayer2RecordsAbove(height uint32) error {
+	strSqls := []string{
+		"delete from layer2tx where height > ?",
+		"delete from withdraw where height > ?",
+	}
+	for _, strSql := range strSqls {
+		_, dberr := DefDB.Exec(strSql, height)
+		if dberr != nil {
+			return dberr
+		}
+	}
+	return nil
+}
+
+func SaveExit(exit *Exit) error {
+	strSql := "insert into `exit`(id, txhash, tt, state, height, player, amount, tokenaddress, layer2height) values (?,?,?,?,?,?,?,?,?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(exit.ID, exit.TxHash, exit.TT, exit.State, exit.Height, exit.Player, exit.Amount, exit.TokenAddress, exit.Layer2Height)
+	return dberr
+}
+
+func UpdateExitByID(id uint64, state int, layer2TxHash string) error {
+	strSql := "update `exit` set layer2txhash = ?, state = ? where id = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(layer2TxHash, state, id)
+	return dberr
+}
+
+func LoadExitsByState(state int) []*Exit {
+	strsql := "select id,txhash,tt,state,height,player,amount,tokenaddress,layer2height,ifnull(layer2txhash,'') from `exit` where state = ? order by id"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(state)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var height, tt, layer2Height uint32
+	var txhash, player, tokenaddress, layer2TxHash string
+	var amount, id uint64
+	exits := make([]*Exit, 0)
+	for rows.Next() {
+		if err = rows.Scan(&id, &txhash, &tt, &state, &height, &player, &amount, &tokenaddress, &layer2Height, &layer2TxHash); err != nil {
+			return nil
+		} else {
+			exits = append(exits, &Exit{
+				ID: id,
+				TxHash: txhash,
+				TT: tt,
+				State: state,
+				Height: height,
+				Player: player,
+				Amount: amount,
+				TokenAddress: tokenaddress,
+				Layer2Height: layer2Height,
+				Layer2TxHash: layer2TxHash,
+			})
+		}
+	}
+	return exits
+}
+
+func SaveJob(kind int, key uint64, payload string, tt uint32) error {
+	strSql := "insert into job(kind, jobkey, state, payload, tt) values (?,?,?,?,?) ON DUPLICATE KEY UPDATE state=VALUES(state), payload=VALUES(payload), tt=VALUES(tt)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(kind, key, JOB_PENDING, payload, tt)
+	return dberr
+}
+
+func UpdateJobState(id uint64, state int) error {
+	strSql := "update job set state = ? where id = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(state, id)
+	return dberr
+}
+
+//CancelJobsAbove mark the jobs of kind not done whose key is above key as done, they are enqueued again when the data
+//of them is parsed again
+func CancelJobsAbove(kind int, key uint64) error {
+	strSql := "update job set state = ? where kind = ? and jobkey > ? and state in (?, ?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(JOB_DONE, kind, key, JOB_PENDING, JOB_RUNNING)
+	return dberr
+}
+
+//LoadNextJob return the first pending or running job of kind in order of enqueue, nil if there is no job to run
+func LoadNextJob(kind int) *Job {
+	jobs := LoadNextJobs(kind, 1)
+	if len(jobs) == 0 {
+		return nil
+	}
+	return jobs[0]
+}
+
+//LoadNextJobs return at most limit pending or running jobs of kind in order of enqueue
+func LoadNextJobs(kind int, limit int) []*Job {
+	strsql := "select id, jobkey, state, payload from job where kind = ? and state in (?, ?) order by id limit ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(kind, JOB_PENDING, JOB_RUNNING, limit)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var id, key uint64
+	var state int
+	var payload string
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		if err = rows.Scan(&id, &key, &state, &payload); err != nil {
+			return nil
+		} else {
+			jobs = append(jobs, &Job{
+				ID: id,
+				Kind: kind,
+				Key: key,
+				State: state,
+				Payload: payload,
+			})
+		}
+	}
+	return jobs
+}
+
+func LoadJob(kind int, key uint64) *Job {
+	strsql := "select id, state, payload from job where kind = ? and jobkey = ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(kind, key)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var id uint64
+	var state int
+	var payload string
+	var job *Job
+	for rows.Next() {
+		if err = rows.Scan(&id, &state, &payload); err != nil {
+			return nil
+		} else {
+			job = &Job{
+				ID: id,
+				Kind: kind,
+				Key: key,
+				State: state,
+				Payload: payload,
+			}
+			break
+		}
+	}
+	return job
+}
+
+//SaveWithdrawFee save the fee of the withdraw, the fee of the withdraw committed again after a revert is overwritten
+func SaveWithdrawFee(fee *WithdrawFee) error {
+	strSql := "insert into withdrawfee(txhash, tokenaddress, amount, fee, height) values (?,?,?,?,?) on duplicate key update fee = ?, height = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(fee.TxHash, fee.TokenAddress, fee.Amount, fee.Fee, fee.Height, fee.Fee, fee.Height)
+	return dberr
+}
+
+//LoadWithdrawFeeTotals return the fees accumulated by every token in order of token address
+func LoadWithdrawFeeTotals() []*WithdrawFeeTotal {
+	strsql := "select tokenaddress,sum(fee),count(*) from withdrawfee group by tokenaddress order by tokenaddress"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query()
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var tokenaddress string
+	var fee, count uint64
+	totals := make([]*WithdrawFeeTotal, 0)
+	for rows.Next() {
+		if err = rows.Scan(&tokenaddress, &fee, &count); err != nil {
+			return nil
+		} else {
+			totals = append(totals, &WithdrawFeeTotal{
+				TokenAddress: tokenaddress,
+				Fee: fee,
+				Count: count,
+			})
+		}
+	}
+	return totals
+}
+
+//LoadLayer2CommitCosts return the L1 fees paid by the executed commit transactions of every state
+func LoadLayer2CommitCosts() []*Layer2CommitCost {
+	strsql := "select state,count(*),ifnull(sum(fee),0),ifnull(max(gasprice),0) from layer2commit where state in (?,?) group by state order by state"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(LAYER2MSG_FINISH, LAYER2MSG_FAILED)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var state int
+	var count, fee, gasprice uint64
+	costs := make([]*Layer2CommitCost, 0)
+	for rows.Next() {
+		if err = rows.Scan(&state, &count, &fee, &gasprice); err != nil {
+			return nil
+		} else {
+			costs = append(costs, &Layer2CommitCost{
+				State: state,
+				Count: count,
+				Fee: fee,
+				MaxGasPrice: gasprice,
+			})
+		}
+	}
+	return costs
+}
+
+//SaveDeadLetter save the dead letter and set the job of jobID dead in one transaction
+func SaveDeadLetter(letter *DeadLetter, jobID uint64) error {
+	tx, dberr := DefDB.Begin()
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = tx.Exec("insert into deadletter(kind, jobkey, payload, error, attempts, tt) values (?,?,?,?,?,?)",
+		letter.Kind, letter.Key, letter.Payload, letter.Error, letter.Attempts, letter.TT)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	_, dberr = tx.Exec("update job set state = ? where id = ?", JOB_DEAD, jobID)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	return tx.Commit()
+}
+
+//RequeueDeadLetter set the job of jobID pending and delete the dead letter of id in one transaction
+func RequeueDeadLetter(id uint64, jobID uint64) error {
+	tx, dberr := DefDB.Begin()
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = tx.Exec("update job set state = ? where id = ? and state = ?", JOB_PENDING, jobID, JOB_DEAD)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	_, dberr = tx.Exec("delete from deadletter where id = ?", id)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	return tx.Commit()
+}
+
+//SetCommitJobState set the commit job of the layer2 height to state in one transaction, the dead letters of the job are
+//deleted and the unconfirmed commits of the height are marked as failed, so they are not waited for any more
+func SetCommitJobState(height uint64, state int) error {
+	tx, dberr := DefDB.Begin()
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = tx.Exec("update job set state = ? where kind = ? and jobkey = ?", state, JOB_COMMIT, height)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	_, dberr = tx.Exec("delete from deadletter where kind = ? and jobkey = ?", JOB_COMMIT, height)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	_, dberr = tx.Exec("update layer2commit set state = ? where layer2height = ? and state = ?", LAYER2MSG_FAILED, height, LAYER2MSG_COMMIT)
+	if dberr != nil {
+		tx.Rollback()
+		return dberr
+	}
+	return tx.Commit()
+}
+
+//HasDeadJob return true if a job of kind is dead, or the state of the jobs is unknown
+func HasDeadJob(kind int) bool {
+	strsql := "select count(*) from job where kind = ? and state = ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return true
+	}
+	var count uint64
+	err = stmt.QueryRow(kind, JOB_DEAD).Scan(&count)
+	if err != nil {
+		return true
+	}
+	return count > 0
+}
+
+//CountDepositsByState return the count of the deposits in state, or -1 if the count fails
+func CountDepositsByState(state int) int64 {
+	return countByState("select count(*) from deposit where state = ?", state)
+}
+
+//CountWithdrawsByState return the count of the withdraws in state, or -1 if the count fails
+func CountWithdrawsByState(state int) int64 {
+	return countByState("select count(*) from withdraw where state = ?", state)
+}
+
+//SavePause save the target paused by the admin
+func SavePause(target string, tt uint32) error {
+	strSql := "insert into pause(target, tt) values (?,?) on duplicate key update tt = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(target, tt, tt)
+	return dberr
+}
+
+func DeletePause(target string) error {
+	strSql := "delete from pause where target = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if dberr != nil {
+		return dberr
+	}
+	_, dberr = stmt.Exec(target)
+	return dberr
+}
+
+//LoadPauses return the targets paused by the admin, nil if the load fails
+func LoadPauses() []string {
+	strsql := "select target from pause order by target"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query()
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var target string
+	targets := make([]string, 0)
+	for rows.Next() {
+		if err = rows.Scan(&target); err != nil {
+			return nil
+		} else {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+//LoadJobsByState return the jobs of kind in state in order of enqueue, nil if the load fails
+func LoadJobsByState(kind int, state int) []*Job {
+	strsql := "select id, jobkey, payload from job where kind = ? and state = ? order by id"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(kind, state)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var id, key uint64
+	var payload string
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		if err = rows.Scan(&id, &key, &payload); err != nil {
+			return nil
+		} else {
+			jobs = append(jobs, &Job{
+				ID: id,
+				Kind: kind,
+				Key: key,
+				State: state,
+				Payload: payload,
+			})
+		}
+	}
+	return jobs
+}
+
+//CountJobsByState return the count of the jobs in state, or -1 if the count fails
+func CountJobsByState(state int) int64 {
+	return countByState("select count(*) from job where state = ?", state)
+}
+
+func countByState(strsql string, state int) int64 {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return -1
+	}
+	var count int64
+	err = stmt.QueryRow(state).Scan(&count)
+	if err != nil {
+		return -1
+	}
+	return count
+}
+
+//LoadDeadLetters return all dead letters in order of id
+func LoadDeadLetters() []*DeadLetter {
+	return loadDeadLetters("select id,kind,jobkey,payload,error,attempts,tt from deadletter order by id")
+}
+
+func LoadDeadLetter(id uint64) *DeadLetter {
+	letters := loadDeadLetters("select id,kind,jobkey,payload,error,attempts,tt from deadletter where id = ?", id)
+	if len(letters) == 0 {
+		return nil
+	}
+	return letters[0]
+}
+
+func loadDeadLetters(strsql string, args ...interface{}) []*DeadLetter {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	letters := make([]*DeadLetter, 0)
+	for rows.Next() {
+		letter := &DeadLetter{}
+		if err = rows.Scan(&letter.ID, &letter.Kind, &letter.Key, &letter.Payload, &letter.Error, &letter.Attempts, &letter.TT); err != nil {
+			return nil
+		} else {
+			letters = append(letters, letter)
+		}
+	}
+	return letters
+}
+
+func SaveKeyRotation(rotation *KeyRotation) error {
+	strSql := "insert into keyrotation(state, ontologyaddress, nextontologyaddress, layer2address, nextlayer2address, transitionperiod, registertxhash, switchtxhash, tt) values (?,?,?,?,?,?,?,?,?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if dberr != nil {
+		return dberr
+	}
+	defer stmt.Close()
+	_, dberr = stmt.Exec(rotation.State, rotation.OntologyAddress, rotation.NextOntologyAddress, rotation.Layer2Address,
+		rotation.NextLayer2Address, rotation.TransitionPeriod, rotation.RegisterTxHash, rotation.SwitchTxHash, rotation.TT)
+	return dberr
+}
+
+//UpdateKeyRotation set the state of the registered rotation of id, txHash is the switch or cancel transaction
+func UpdateKeyRotation(id uint64, state int, txHash string) error {
+	strSql := "update keyrotation set state = ?, switchtxhash = ? where id = ? and state = ?"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if dberr != nil {
+		return dberr
+	}
+	defer stmt.Close()
+	_, dberr = stmt.Exec(state, txHash, id, KEY_ROTATION_REGISTERED)
+	return dberr
+}
+
+//LoadKeyRotations return the key rotations in the order of registration
+func LoadKeyRotations() []*KeyRotation {
+	strsql := "select id,state,ontologyaddress,nextontologyaddress,layer2address,nextlayer2address,transitionperiod,registertxhash,switchtxhash,tt from keyrotation order by id"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query()
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	rotations := make([]*KeyRotation, 0)
+	for rows.Next() {
+		rotation := &KeyRotation{}
+		if err = rows.Scan(&rotation.ID, &rotation.State, &rotation.OntologyAddress, &rotation.NextOntologyAddress, &rotation.Layer2Address,
+			&rotation.NextLayer2Address, &rotation.TransitionPeriod, &rotation.RegisterTxHash, &rotation.SwitchTxHash, &rotation.TT); err != nil {
+			return nil
+		} else {
+			rotations = append(rotations, rotation)
+		}
+	}
+	return rotations
+}
+
+//SaveAuditEntry append the entry to the audit log, the entries are never updated or deleted
+func SaveAuditEntry(entry *AuditEntry) error {
+	strSql := "insert into auditlog(action, initiator, detail, txhash, tt) values (?,?,?,?,?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if dberr != nil {
+		return dberr
+	}
+	defer stmt.Close()
+	_, dberr = stmt.Exec(entry.Action, entry.Initiator, entry.Detail, entry.TxHash, entry.TT)
+	return dberr
+}
+
+//LoadAuditLog return at most limit entries whose id is below before, or the latest entries if before is 0, the latest
+//first
+func LoadAuditLog(before uint64, limit int) []*AuditEntry {
+	strsql := "select id,action,initiator,detail,txhash,tt from auditlog where id < ? order by id desc limit ?"
+	if before == 0 {
+		before = math.MaxInt64
+	}
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(before, limit)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		entry := &AuditEntry{}
+		if err = rows.Scan(&entry.ID, &entry.Action, &entry.Initiator, &entry.Detail, &entry.TxHash, &entry.TT); err != nil {
+			return nil
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func SaveDryRunAction(action *DryRunAction) error {
+	strSql := "insert into dryrunaction(kind, subject, detail, tt) values (?,?,?,?)"
+	stmt, dberr := DefDB.Prepare(strSql)
+	if dberr != nil {
+		return dberr
+	}
+	defer stmt.Close()
+	_, dberr = stmt.Exec(action.Kind, action.Subject, action.Detail, action.TT)
+	return dberr
+}
+
+//LoadDryRunActions return at most limit actions whose id is below before, or the latest actions if before is 0, the
+//latest first
+func LoadDryRunActions(before uint64, limit int) []*DryRunAction {
+	strsql := "select id,kind,subject,detail,tt from dryrunaction where id < ? order by id desc limit ?"
+	if before == 0 {
+		before = math.MaxInt64
+	}
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(before, limit)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	actions := make([]*DryRunAction, 0)
+	for rows.Next() {
+		action := &DryRunAction{}
+		if err = rows.Scan(&action.ID, &action.Kind, &action.Subject, &action.Detail, &action.TT); err != nil {
+			return nil
+		} else {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// ResetProjectDB clear all bridge records and parse heights, only used by the e2e runner. The audit log is kept
+func ResetProjectDB() error {
+	strSqls := []string{
+		"delete from deposit",
+		"delete from withdraw",
+		"delete from layer2tx",
+		"delete from layer2commit",
+		"delete from deadletter",
+		"delete from depositquarantine",
+		"delete from job",
+		"delete from ontologyblock",
+		"delete from token",
+		"delete from `exit`",
+		"delete from withdrawfee",
+		"delete from pause",
+		"delete from keyrotation",
+		"delete from dryrunaction",
+		"update chain_info set height = 0",
+	}
+	for _, strSql := range strSqls {
+		_, dberr := DefDB.Exec(strSql)
+		if dberr != nil {
+			return dberr
+		}
+	}
+	return nil
+}
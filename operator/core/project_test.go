@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/layer2/operator/config"
+)
+
+//openTestDB connect DefDB to a new SQLite database migrated to the latest version, it is closed with the test
+func openTestDB(t *testing.T) {
+	connectTestDB(t)
+	err := Migrate()
+	if err != nil {
+		t.Fatalf("migrate db: %v", err)
+	}
+}
+
+//connectTestDB connect DefDB to a new SQLite database of the baseline schema, it is closed with the test
+func connectTestDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	err = ConnectDB(&config.DBConfig{ProjectDBDriver: config.DB_DRIVER_SQLITE, ProjectDBName: filepath.Join(dir, "operator.db")})
+	if err != nil {
+		t.Fatalf("connect db: %v", err)
+	}
+	t.Cleanup(CloseDB)
+}
+
+func TestLoadMaxDepositID(t *testing.T) {
+	openTestDB(t)
+	for _, id := range []uint64{3, 7, 12} {
+		_, err := SaveDeposit(&Deposit{TxHash: "tx", NotifyIndex: uint32(id), ID: id})
+		if err != nil {
+			t.Fatalf("save deposit %d: %v", id, err)
+		}
+	}
+	cases := []struct {
+		from uint64
+		max  uint64
+	}{
+		{0, 12},
+		{8, 12},
+		{13, 0},
+	}
+	for _, c := range cases {
+		max, err := LoadMaxDepositID(c.from)
+		if err != nil || max != c.max {
+			t.Errorf("LoadMaxDepositID(%d) = %d, %v, want %d", c.from, max, err, c.max)
+		}
+	}
+}
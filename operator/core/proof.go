@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ontio/layer2/operator/bridge"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const LAYER2_PROOF_TIMEOUT = 10 * time.Second
+
+type layer2RpcResponse struct {
+	Error  int64           `json:"error"`
+	Desc   string          `json:"desc"`
+	Result json.RawMessage `json:"result"`
+}
+
+type layer2StateProof struct {
+	Type      string
+	AuditPath string
+}
+
+//callLayer2Rpc call the json rpc method of the layer2 node of rpcURL and return the result
+func callLayer2Rpc(rpcURL string, method string, params []interface{}, timeout time.Duration) (json.RawMessage, error) {
+	req, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(rpcURL, "application/json", bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("request %s failed! err: %s", method, err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s response failed! err: %s", method, err.Error())
+	}
+	rpcResp := &layer2RpcResponse{}
+	if err := json.Unmarshal(body, rpcResp); err != nil {
+		return nil, fmt.Errorf("parse %s response failed! err: %s", method, err.Error())
+	}
+	if rpcResp.Error != 0 {
+		return nil, fmt.Errorf("%s failed! error: %d, desc: %s", method, rpcResp.Error, rpcResp.Desc)
+	}
+	return rpcResp.Result, nil
+}
+
+//getLayer2StateProof get the sparse merkle proof of key in the layer2 state root of height by getlayer2stateproof of
+//the layer2 node, the sdk of the layer2 does not support it yet
+func (this *Layer2Operator) getLayer2StateProof(height uint32, key []byte) (*bridge.SMTProof, error) {
+	result, err := callLayer2Rpc(this.config().Layer2Config.RestURL, "getlayer2stateproof",
+		[]interface{}{height, hex.EncodeToString(key)}, LAYER2_PROOF_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	stateProof := &layer2StateProof{}
+	if err := json.Unmarshal(result, stateProof); err != nil {
+		return nil, fmt.Errorf("parse layer2 state proof failed! err: %s", err.Error())
+	}
+	auditPath, err := hex.DecodeString(stateProof.AuditPath)
+	if err != nil {
+		return nil, fmt.Errorf("decode layer2 state proof failed! err: %s", err.Error())
+	}
+	return bridge.ParseSMTProof(auditPath)
+}
+
+//withdrawProof build the proof of the account of the layer2 token contract and the to address of the withdraw in the
+//layer2 state root of msg, and verify it locally, so that the contract does not reject the commit
+func (this *Layer2Operator) withdrawProof(msg *Layer2CommitMsg, layer2Asset []byte, toAddress []byte) ([]byte, error) {
+	key := make([]byte, 0, len(layer2Asset) + len(toAddress))
+	key = append(append(key, layer2Asset...), toAddress...)
+	proof, err := this.getLayer2StateProof(msg.Layer2State.Height, key)
+	if err != nil {
+		return nil, err
+	}
+	data := bridge.EncodeWithdrawProof(key, proof)
+	if !bridge.VerifyWithdrawProof([32]byte(msg.Layer2State.StatesRoot), key, data) {
+		return nil, fmt.Errorf("proof of key %x does not match the layer2 state root of height %d", key, msg.Layer2State.Height)
+	}
+	return data, nil
+}
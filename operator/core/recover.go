@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"github.com/ontio/layer2/operator/log"
+	"time"
+)
+
+//recover reconcile the database with the chain state of layer2 and ontology on start, so that the operator resumes
+//from the same state whenever it exited. The unconfirmed commits found on ontology are finalized, the layer2 heights
+//committed to ontology are recorded as finished and parsing resumes after the last one, the records of the layer2
+//blocks parsed after it are removed to be parsed again, and the deposits not minted on layer2 are enqueued again
+func (this *Layer2Operator) recover() error {
+	this.recoverCommits()
+	height, err := this.recoverCommitHeight()
+	if err != nil {
+		return err
+	}
+	err = DeleteLayer2RecordsAbove(height)
+	if err != nil {
+		return fmt.Errorf("delete layer2 records above height %d err: %v", height, err)
+	}
+	this.layer2ChainInfo.Height = height
+	log.Infof("recover - layer2 committed height: %d", height)
+	return this.recoverDeposits()
+}
+
+//recoverCommits finalize the unconfirmed commits whose transaction is executed on ontology, and fail the commits
+//whose transaction is lost, the layer2 states of them are committed again after the layer2 blocks are parsed
+func (this *Layer2Operator) recoverCommits() {
+	for _, txHash := range LoadLayer2Commit_Unconfirmed() {
+		result, err := this.l1.CheckCommit(txHash)
+		if err != nil || result == nil {
+			if this.l1.IsTxLost(txHash) {
+				log.Infof("recover - layer2 commit: %s is lost.", txHash)
+				UpdateLayer2Commit(txHash, uint64(0), LAYER2MSG_FAILED)
+			}
+			continue
+		}
+		if result.Success {
+			UpdateLayer2Commit(txHash, uint64(result.Height), LAYER2MSG_FINISH)
+			log.Infof("recover - layer2 commit: %s is finished.", txHash)
+		} else {
+			UpdateLayer2Commit(txHash, uint64(result.Height), LAYER2MSG_FAILED)
+			log.Infof("recover - layer2 commit: %s is failed.", txHash)
+		}
+	}
+}
+
+//recoverCommitHeight return the last layer2 height committed to ontology, the heights committed on ontology but not
+//recorded as finished, for example the commit transaction sent before exit, are recorded as finished
+func (this *Layer2Operator) recoverCommitHeight() (uint32, error) {
+	height := GetLayer2CommitHeight()
+	if this.commitBatchSize() > 1 {
+		// the aggregated commit only saves the state root of its last height
+		committed, err := this.getCommittedHeight()
+		if err != nil {
+			return 0, err
+		}
+		if committed > height {
+			saveFinishedLayer2Commit(committed, "")
+			height = committed
+		}
+		return height, nil
+	}
+	for {
+		exit, err := this.checkLayer2StateByHeight(uint64(height + 1))
+		if err != nil {
+			return 0, err
+		}
+		if !exit {
+			return height, nil
+		}
+		saveFinishedLayer2Commit(height + 1, "")
+		height ++
+	}
+}
+
+//saveFinishedLayer2Commit record the layer2 height found committed on ontology as finished, the commit transaction is
+//unknown so the record is keyed by the time and the height
+func saveFinishedLayer2Commit(height uint32, layer2Msg string) {
+	formatStr := "2006-01-02 15:04:05"
+	timehash := fmt.Sprintf("%s-%d", time.Now().Format(formatStr), height)
+	SaveLayer2Commit(timehash, layer2Msg, uint64(height), uint32(time.Now().Unix()))
+	UpdateLayer2Commit(timehash, uint64(height), LAYER2MSG_FINISH)
+}
+
+//recoverDeposits enqueue the deposits not committed to layer2 again, and the committed deposits whose mint
+//transaction is lost on layer2 are reset to be minted again
+func (this *Layer2Operator) recoverDeposits() error {
+	for _, deposit := range LoadDepositsByState(DEPOSIT_COMMIT) {
+		if !this.isLayer2TxLost(deposit.Layer2TxHash) {
+			continue
+		}
+		log.Infof("recover - mint tx: %s of deposit %d is lost, mint again", deposit.Layer2TxHash, deposit.ID)
+		err := UpdateDepositByID(deposit.ID, DEPOSIT_EVENT, "")
+		if err != nil {
+			return err
+		}
+	}
+	for _, deposit := range LoadDepositsByState(DEPOSIT_EVENT) {
+		err := this.enqueueJob(JOB_DEPOSIT, deposit.ID, deposit)
+		if err != nil {
+			return fmt.Errorf("save deposit job of tx: %s, err: %v", deposit.TxHash, err)
+		}
+	}
+	return nil
+}
+
+//isLayer2TxLost return true if the layer2 node is reachable and the transaction is neither in a block nor in the
+//transaction pool
+func (this *Layer2Operator) isLayer2TxLost(txHash string) bool {
+	lost, _ := this.checkLayer2TxLost(txHash)
+	return lost
+}
+
+//checkLayer2TxLost return whether the transaction is neither in a block nor in the transaction pool of layer2, the
+//error is returned if the layer2 node is not reachable
+func (this *Layer2Operator) checkLayer2TxLost(txHash string) (bool, error) {
+	if txHash == "" {
+		return true, nil
+	}
+	if _, err := this.layer2Sdk().GetCurrentBlockHeight(); err != nil {
+		return false, err
+	}
+	if height, err := this.layer2Sdk().GetBlockHeightByTxHash(txHash); err == nil && height > 0 {
+		return false, nil
+	}
+	if _, err := this.layer2Sdk().GetMemPoolTxState(txHash); err == nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+//isMintedBeforeExit mark the deposit committed if its mint transaction sent before exit is on layer2 or in the
+//transaction pool, so the mint is not sent again. The error is returned if the layer2 node is not reachable
+func (this *Layer2Operator) isMintedBeforeExit(deposit *Deposit, saved *Deposit) (bool, error) {
+	if saved == nil || saved.Layer2TxHash == "" {
+		return false, nil
+	}
+	lost, err := this.checkLayer2TxLost(saved.Layer2TxHash)
+	if err != nil || lost {
+		return false, err
+	}
+	depositLog(deposit).Infof("deposit %d is committed to layer2 before exit, tx hash: %s", deposit.ID, saved.Layer2TxHash)
+	return true, UpdateDepositByID(deposit.ID, DEPOSIT_COMMIT, saved.Layer2TxHash)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+)
+
+//mockLayer2Node serve the json rpc of the blocks and the transaction pool of layer2, the transactions not found are
+//reported as the rpc error
+type mockLayer2Node struct {
+	server  *httptest.Server
+	blocks  map[string]uint32
+	pool    map[string]bool
+	sent    int
+}
+
+func newMockLayer2Node(t *testing.T) *mockLayer2Node {
+	node := &mockLayer2Node{blocks: make(map[string]uint32), pool: make(map[string]bool)}
+	node.server = httptest.NewServer(http.HandlerFunc(node.handle))
+	t.Cleanup(node.server.Close)
+	return node
+}
+
+func (this *mockLayer2Node) handle(w http.ResponseWriter, r *http.Request) {
+	req := &struct {
+		Id     string        `json:"id"`
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	txHash := ""
+	if len(req.Params) > 0 {
+		txHash, _ = req.Params[0].(string)
+	}
+	var result interface{}
+	switch req.Method {
+	case "getblockcount":
+		result = 10
+	case "getblockheightbytxhash":
+		if height, ok := this.blocks[txHash]; ok {
+			result = height
+		}
+	case "getmempooltxstate":
+		if this.pool[txHash] {
+			result = map[string]interface{}{"State": []interface{}{}}
+		}
+	case "sendrawtransaction":
+		this.sent++
+	}
+	resp := map[string]interface{}{"id": req.Id, "jsonrpc": "2.0", "error": 0, "desc": "SUCCESS", "result": result}
+	if result == nil {
+		resp["error"], resp["desc"] = 44001, "UNKNOWN TRANSACTION"
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func depositJob(t *testing.T, deposit *Deposit) *Job {
+	payload, err := json.Marshal(deposit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Job{Kind: JOB_DEPOSIT, Key: deposit.ID, Payload: string(payload)}
+}
+
+//the deposit whose mint sent before exit is in a layer2 block or in the pool is committed without sending it again by
+//both the single and the batch path, and the job fails if layer2 is not reachable
+func TestMintedBeforeExit(t *testing.T) {
+	operator := newTestOperator(t, newMockL1Backend())
+	node := newMockLayer2Node(t)
+	sdk := layer2_sdk.NewOntologySdk()
+	sdk.NewRpcClient().SetAddress(node.server.URL)
+	operator.layer2SdkValue.Store(sdk)
+	node.blocks["aa"] = 5
+	node.pool["bb"] = true
+
+	deposits := []*Deposit{
+		{TxHash: "t1", ID: 1, State: DEPOSIT_EVENT, TokenAddress: "0000000000000000000000000000000000000002", Amount: 1},
+		{TxHash: "t2", ID: 2, State: DEPOSIT_EVENT, TokenAddress: "0000000000000000000000000000000000000002", Amount: 1},
+		{TxHash: "t3", ID: 3, State: DEPOSIT_EVENT, TokenAddress: "0000000000000000000000000000000000000002", Amount: 1},
+		{TxHash: "t4", ID: 4, State: DEPOSIT_EVENT, TokenAddress: "0000000000000000000000000000000000000002", Amount: 1},
+	}
+	for i, deposit := range deposits {
+		if _, err := SaveDeposit(deposit); err != nil {
+			t.Fatal(err)
+		}
+		if err := UpdateDepositByID(deposit.ID, DEPOSIT_EVENT, []string{"aa", "bb", "aa", "bb"}[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := operator.runDepositJob(depositJob(t, deposits[0])); err != nil {
+		t.Fatalf("run deposit job err: %v", err)
+	}
+	if err := operator.runDepositJob(depositJob(t, deposits[1])); err != nil {
+		t.Fatalf("run deposit job err: %v", err)
+	}
+	if err := operator.runDepositBatch([]*Job{depositJob(t, deposits[2]), depositJob(t, deposits[3])}); err != nil {
+		t.Fatalf("run deposit batch err: %v", err)
+	}
+	for _, deposit := range deposits {
+		saved := LoadDepositByID(deposit.ID)
+		if saved == nil || saved.State != DEPOSIT_COMMIT {
+			t.Errorf("deposit %d is not committed: %+v", deposit.ID, saved)
+		}
+	}
+	if node.sent != 0 {
+		t.Errorf("%d mint transactions are sent again", node.sent)
+	}
+
+	deposit := &Deposit{TxHash: "t5", ID: 5, State: DEPOSIT_EVENT, TokenAddress: "0000000000000000000000000000000000000002", Amount: 1}
+	if _, err := SaveDeposit(deposit); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateDepositByID(deposit.ID, DEPOSIT_EVENT, "cc"); err != nil {
+		t.Fatal(err)
+	}
+	node.server.Close()
+	if err := operator.runDepositJob(depositJob(t, deposit)); err == nil {
+		t.Errorf("deposit job succeeds with layer2 not reachable")
+	}
+	if err := operator.runDepositBatch([]*Job{depositJob(t, deposit)}); err == nil {
+		t.Errorf("deposit batch succeeds with layer2 not reachable")
+	}
+	if saved := LoadDepositByID(deposit.ID); saved == nil || saved.State != DEPOSIT_EVENT {
+		t.Errorf("deposit is changed with layer2 not reachable: %+v", saved)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+	"time"
+)
+
+//refundLoop refund the deposits whose mint on layer2 failed, the refund transaction is saved in the deposit table
+func (this *Layer2Operator) refundLoop() {
+	log.Infof("start refundLoop")
+	for true {
+		select {
+		case <- this.ctx.Done():
+			log.Infof("refundLoop exit")
+			return
+		case <- time.After(config.DEPOSIT_REFUND_INTERVAL):
+		}
+		if !this.isLeading() {
+			continue
+		}
+		for _, deposit := range LoadDepositsByState(DEPOSIT_FAILED) {
+			err := this.refundFailedDeposit(deposit)
+			if err != nil {
+				depositLog(deposit).Errorf("refund - refund deposit %d err: %v", deposit.ID, err)
+			}
+		}
+	}
+}
+
+//refundFailedDeposit refund the deposit by refundDeposit of the contract if its mint is lost on layer2. The mint found
+//in a block is recorded as committed instead, and the mint still in the transaction pool is checked again later. The
+//deposits failed before the mint hash is kept are left to the admin api
+func (this *Layer2Operator) refundFailedDeposit(deposit *Deposit) error {
+	if len(deposit.Layer2TxHash) != 64 {
+		return nil
+	}
+	if height, err := this.layer2Sdk().GetBlockHeightByTxHash(deposit.Layer2TxHash); err == nil && height > 0 {
+		depositLog(deposit).Infof("refund - mint of deposit %d is found on layer2, tx hash: %s", deposit.ID, deposit.Layer2TxHash)
+		return UpdateDepositByID(deposit.ID, DEPOSIT_COMMIT, deposit.Layer2TxHash)
+	}
+	if !this.isLayer2TxLost(deposit.Layer2TxHash) {
+		return nil
+	}
+	params, err := this.bridge.RefundDepositParams(&bridge.RefundDepositParam{DepositId: deposit.ID})
+	if err != nil {
+		return err
+	}
+	txHash, err := this.l1.Invoke(params)
+	if err != nil {
+		return err
+	}
+	audit(AUDIT_REFUND, AUDIT_BY_OPERATOR, txHash, "refund deposit %d of lost mint %s", deposit.ID, deposit.Layer2TxHash)
+	err = UpdateDepositRefund(deposit.ID, DEPOSIT_REFUNDED, txHash)
+	if err != nil {
+		return err
+	}
+	depositLog(deposit).Infof("refund - refund failed deposit %d, tx hash: %s", deposit.ID, txHash)
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+)
+
+//ReloadResult is the result of the config reload, Restart is the settings changed which take effect on restart
+type ReloadResult struct {
+	Restart        []string
+}
+
+func (this *Layer2Operator) config() *config.ServiceConfig {
+	return this.configValue.Load().(*config.ServiceConfig)
+}
+
+func (this *Layer2Operator) layer2Sdk() *layer2_sdk.OntologySdk {
+	return this.layer2SdkValue.Load().(*layer2_sdk.OntologySdk)
+}
+
+//SetConfigLoader set the loader of the config reloaded by Reload, which reads the config file and the flags again
+func (this *Layer2Operator) SetConfigLoader(loader func() (*config.ServiceConfig, error)) {
+	this.configLoader = loader
+}
+
+//Reload load the config again and apply the settings reloadable without restart, the nodes of the changed urls are
+//connected again. Nothing is applied if the new tokens are invalid or a new node is not reachable. The reload is
+//recorded in the audit log with the initiator
+func (this *Layer2Operator) Reload(initiator string) (*ReloadResult, error) {
+	this.reloadLock.Lock()
+	defer this.reloadLock.Unlock()
+	if this.configLoader == nil {
+		return nil, fmt.Errorf("config reload is not supported")
+	}
+	next, err := this.configLoader()
+	if err != nil {
+		return nil, err
+	}
+	current := this.config()
+	reloaded, restart := current.Reload(next)
+	for _, token := range ConfigTokens(reloaded.Tokens) {
+		err = checkToken(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var layer2Sdk *layer2_sdk.OntologySdk
+	if reloaded.Layer2Config.RestURL != current.Layer2Config.RestURL {
+		layer2Sdk = layer2_sdk.NewOntologySdk()
+		layer2Sdk.NewRpcClient().SetAddress(reloaded.Layer2Config.RestURL)
+		_, err = layer2Sdk.GetCurrentBlockHeight()
+		if err != nil {
+			return nil, fmt.Errorf("connect layer2 node %s err: %v", reloaded.Layer2Config.RestURL, err)
+		}
+	}
+	err = this.l1.Reload(reloaded)
+	if err != nil {
+		return nil, err
+	}
+	if layer2Sdk != nil {
+		this.layer2SdkValue.Store(layer2Sdk)
+		log.Infof("reload - layer2 node changed to %s", reloaded.Layer2Config.RestURL)
+	}
+	this.configValue.Store(reloaded)
+	// the configured tokens not registered yet are added to the registry
+	err = this.tokens.init(reloaded.Tokens)
+	if err != nil {
+		return nil, fmt.Errorf("register the reloaded tokens err: %v", err)
+	}
+	if len(restart) > 0 {
+		log.Warnf("reload - the changes of %v take effect on restart", restart)
+	}
+	log.Infof("reload - config reloaded")
+	audit(AUDIT_RELOAD, initiator, "", "reload config, restart required by %v", restart)
+	return &ReloadResult{Restart: restart}, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+)
+
+//ResubmitCommit set the commit job of the layer2 height pending again, so the layer2 state of the height is committed
+//again. The commit found on L1 already is recorded as finished instead of sent again
+func ResubmitCommit(height uint64) error {
+	job := LoadJob(JOB_COMMIT, height)
+	if job == nil {
+		return fmt.Errorf("commit job of height %d is not found", height)
+	}
+	if job.State == JOB_PENDING {
+		return fmt.Errorf("commit job of height %d is pending already", height)
+	}
+	err := SetCommitJobState(height, JOB_PENDING)
+	if err != nil {
+		return err
+	}
+	audit(AUDIT_COMMIT_ADMIN, AUDIT_BY_CLI, "", "resubmit commit of height %d", height)
+	return nil
+}
+
+//SkipCommit set the commit job of the layer2 height done without committing it, the later commits are not blocked by
+//it any more. The deposits and withdraws of the height are not committed to L1 unless a later commit carries them
+func SkipCommit(height uint64) error {
+	job := LoadJob(JOB_COMMIT, height)
+	if job == nil {
+		return fmt.Errorf("commit job of height %d is not found", height)
+	}
+	if job.State == JOB_DONE {
+		return fmt.Errorf("commit job of height %d is done already", height)
+	}
+	err := SetCommitJobState(height, JOB_DONE)
+	if err != nil {
+		return err
+	}
+	audit(AUDIT_COMMIT_ADMIN, AUDIT_BY_CLI, "", "skip commit of height %d", height)
+	return nil
+}
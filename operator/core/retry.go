@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ontio/layer2/operator/config"
+)
+
+//retryPolicy is the backoff of all the retries of the operator, the delay starts from base and is doubled on every
+//attempt up to max. The work failed maxAttempts times is not retried any more
+type retryPolicy struct {
+	base          time.Duration
+	max           time.Duration
+	maxAttempts   int
+	retries       metrics.Counter
+	abort         <-chan struct{}
+}
+
+func newRetryPolicy(cfg *config.RetryConfig, retries metrics.Counter, abort <-chan struct{}) *retryPolicy {
+	policy := &retryPolicy{
+		base:        config.DEFAULT_RETRY_BASE_DELAY,
+		max:         config.DEFAULT_RETRY_MAX_DELAY,
+		maxAttempts: config.DEFAULT_RETRY_MAX_ATTEMPTS,
+		retries:     retries,
+		abort:       abort,
+	}
+	if cfg == nil {
+		return policy
+	}
+	if cfg.BaseDelay > 0 {
+		policy.base = time.Duration(cfg.BaseDelay) * time.Millisecond
+	}
+	if cfg.MaxDelay > 0 {
+		policy.max = time.Duration(cfg.MaxDelay) * time.Millisecond
+	}
+	if policy.max < policy.base {
+		policy.max = policy.base
+	}
+	if cfg.MaxAttempts > 0 {
+		policy.maxAttempts = cfg.MaxAttempts
+	}
+	return policy
+}
+
+//Delay return the backoff after the failed attempt, counted from 0. A random jitter of up to half of the delay is
+//subtracted, so the retries of the operators sharing a node are not in lockstep
+func (this *retryPolicy) Delay(attempt int) time.Duration {
+	delay := this.max
+	if attempt < 32 {
+		if backoff := this.base << uint(attempt); backoff > 0 && backoff < this.max {
+			delay = backoff
+		}
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay / 2) + 1))
+}
+
+//Wait sleep the backoff after the failed attempt, return false without waiting it out if the retries are aborted on
+//shutdown
+func (this *retryPolicy) Wait(attempt int) bool {
+	this.retries.Inc(1)
+	select {
+	case <- time.After(this.Delay(attempt)):
+		return true
+	case <- this.abort:
+		return false
+	}
+}
+
+//Exhausted return true if the work failed attempts times is not retried any more
+func (this *retryPolicy) Exhausted(attempts int) bool {
+	return attempts >= this.maxAttempts
+}
+
+//waitError is the error of the job waiting for another operator or for the gas balance, the attempts of it are not
+//counted
+type waitError struct {
+	error
+}
+
+func isWaitError(err error) bool {
+	_, ok := err.(*waitError)
+	return ok
+}
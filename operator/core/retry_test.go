@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ontio/layer2/operator/config"
+)
+
+func TestRetryPolicyConfig(t *testing.T) {
+	cases := []struct {
+		cfg         *config.RetryConfig
+		base        time.Duration
+		max         time.Duration
+		maxAttempts int
+	}{
+		{nil, config.DEFAULT_RETRY_BASE_DELAY, config.DEFAULT_RETRY_MAX_DELAY, config.DEFAULT_RETRY_MAX_ATTEMPTS},
+		{&config.RetryConfig{BaseDelay: 100, MaxDelay: 1000, MaxAttempts: 3}, 100 * time.Millisecond, time.Second, 3},
+		// the max delay is not below the base
+		{&config.RetryConfig{BaseDelay: 2000, MaxDelay: 1000}, 2 * time.Second, 2 * time.Second, config.DEFAULT_RETRY_MAX_ATTEMPTS},
+	}
+	for _, c := range cases {
+		policy := newRetryPolicy(c.cfg, metrics.NilCounter{}, nil)
+		if policy.base != c.base || policy.max != c.max || policy.maxAttempts != c.maxAttempts {
+			t.Errorf("policy of %+v is %s/%s/%d, want %s/%s/%d", c.cfg, policy.base, policy.max, policy.maxAttempts,
+				c.base, c.max, c.maxAttempts)
+		}
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	policy := newRetryPolicy(&config.RetryConfig{MaxAttempts: 3}, metrics.NilCounter{}, nil)
+	for attempts, exhausted := range []bool{false, false, false, true, true} {
+		if policy.Exhausted(attempts) != exhausted {
+			t.Errorf("Exhausted(%d) = %v, want %v", attempts, !exhausted, exhausted)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := newRetryPolicy(&config.RetryConfig{BaseDelay: 100, MaxDelay: 1000}, metrics.NilCounter{}, nil)
+	cases := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{64, time.Second},
+	}
+	for _, c := range cases {
+		// the jitter takes up to half of the delay
+		for i := 0; i < 10; i ++ {
+			delay := policy.Delay(c.attempt)
+			if delay > c.delay || delay < c.delay / 2 {
+				t.Errorf("Delay(%d) = %s, want in [%s, %s]", c.attempt, delay, c.delay / 2, c.delay)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	retries := metrics.NewCounterForced()
+	abort := make(chan struct{})
+	policy := newRetryPolicy(&config.RetryConfig{BaseDelay: 1, MaxDelay: 1}, retries, abort)
+	if !policy.Wait(0) {
+		t.Errorf("wait is aborted")
+	}
+
+	// the wait is aborted on shutdown without waiting the delay out
+	policy = newRetryPolicy(&config.RetryConfig{BaseDelay: 60000, MaxDelay: 60000}, retries, abort)
+	close(abort)
+	start := time.Now()
+	if policy.Wait(0) {
+		t.Errorf("wait is not aborted")
+	}
+	if time.Since(start) > 10 * time.Second {
+		t.Errorf("aborted wait takes %s", time.Since(start))
+	}
+	if retries.Count() != 2 {
+		t.Errorf("%d retries are counted, want 2", retries.Count())
+	}
+}
+
+func TestIsWaitError(t *testing.T) {
+	if !isWaitError(&waitError{errTest}) {
+		t.Errorf("wait error is not recognized")
+	}
+	if isWaitError(errTest) || isWaitError(nil) {
+		t.Errorf("error is taken as wait error")
+	}
+}
+
+var errTest = fmt.Errorf("test error")
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+)
+
+//errShutdown is the error of the work aborted on shutdown, it is resumed from the database on restart
+var errShutdown = fmt.Errorf("operator is shutting down")
+
+//goLoop run the loop in a goroutine waited by Stop
+func (this *Layer2Operator) goLoop(loop func()) {
+	this.loops.Add(1)
+	go func() {
+		defer this.loops.Done()
+		loop()
+	}()
+}
+
+//goJobLoop run the job loop as goLoop, the commits sent by it are drained after it exits
+func (this *Layer2Operator) goJobLoop(loop func()) {
+	this.jobLoops.Add(1)
+	this.goLoop(func() {
+		defer this.jobLoops.Done()
+		loop()
+	})
+}
+
+//stopping return true once Stop is called, the chains are not parsed and the jobs are not started any more
+func (this *Layer2Operator) stopping() bool {
+	return this.ctx.Err() != nil
+}
+
+//Stop shut down the operator gracefully. The chains are no longer parsed and no job is started, the jobs in flight are
+//finished and the commits sent are waited to be confirmed on L1 before the loops exit. The work not drained in the
+//shutdown timeout is aborted, the jobs and commits are kept in the database and resumed on restart
+func (this *Layer2Operator) Stop() {
+	timeout := this.config().ShutdownDuration()
+	log.Infof("operator is shutting down, drain the work in flight in %s", timeout)
+	this.cancel()
+	done := make(chan struct{})
+	go func() {
+		// the admin requests in flight may send transactions
+		if this.adminServer != nil {
+			this.adminServer.Shutdown(this.drain)
+		}
+		this.loops.Wait()
+		close(done)
+	}()
+	select {
+	case <- done:
+	case <- time.After(timeout):
+		log.Errorf("shutdown timeout, abort the work in flight")
+		this.abort()
+		select {
+		case <- done:
+		case <- time.After(config.SHUTDOWN_ABORT_TIMEOUT):
+			log.Errorf("the loops do not exit after abort, exit anyway")
+		}
+	}
+	this.abort()
+	if this.multiSig != nil && this.multiSig.server != nil {
+		this.multiSig.server.Close()
+	}
+	if this.adminServer != nil {
+		this.adminServer.Close()
+	}
+	if this.metricsServer != nil {
+		this.metricsServer.Close()
+	}
+	if this.healthServer != nil {
+		this.healthServer.Close()
+	}
+	log.Infof("multi chain manager exit.")
+}
+
+//drainCommits wait for the job loops to exit, then check the commits they sent until confirmed on L1 or the shutdown is
+//aborted
+func (this *Layer2Operator) drainCommits() {
+	this.jobLoops.Wait()
+	if this.isLeading() {
+		this.checkLayer2State()
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-crypto/signature"
+	ontology_sdk "github.com/ontio/ontology-go-sdk"
+	ontology_common "github.com/ontio/ontology/common"
+)
+
+const (
+	// the paths of the remote signer
+	SIGNER_PUBLIC_KEY_PATH = "/publickey"
+	SIGNER_SIGN_PATH       = "/sign"
+)
+
+//ontologySigner is the ontology account of the operator, the transactions are signed by the account of the wallet
+//file, or by the remote signer
+type ontologySigner struct {
+	ontology_sdk.Signer
+	Address   ontology_common.Address
+	PublicKey keypair.PublicKey
+}
+
+//layer2Signer is the layer2 account of the operator as ontologySigner
+type layer2Signer struct {
+	layer2_sdk.Signer
+	Address   layer2_common.Address
+	PublicKey keypair.PublicKey
+}
+
+//SignerPublicKeyResponse is the public key of the key of the remote signer, hex of the serialized public key
+type SignerPublicKeyResponse struct {
+	PublicKey string
+	Error     string
+}
+
+//SignerSignRequest ask the remote signer to sign the hex Data by the key, which is the hash of a transaction
+type SignerSignRequest struct {
+	Key  string
+	Data string
+}
+
+//SignerSignResponse is the hex of the signature serialized by ontology-crypto, signed by SHA256withECDSA
+type SignerSignResponse struct {
+	Signature string
+	Error     string
+}
+
+//remoteSigner sign by the key kept by the signing service, for example a service in front of a PKCS#11 HSM, so the
+//private key never lives on the operator host. The signatures are verified by the public key before they are used
+type remoteSigner struct {
+	cfg       *config.SignerConfig
+	client    *http.Client
+	publicKey keypair.PublicKey
+}
+
+func newRemoteSigner(cfg *config.SignerConfig) (*remoteSigner, error) {
+	transport := &http.Transport{}
+	if cfg.CAFile != "" || cfg.CertFile != "" {
+		tlsConfig := &tls.Config{}
+		if cfg.CAFile != "" {
+			ca, err := ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read signer ca file err: %v", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("invalid signer ca file %s", cfg.CAFile)
+			}
+		}
+		if cfg.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("load signer client certificate err: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	this := &remoteSigner{
+		cfg:    cfg,
+		client: &http.Client{Transport: transport, Timeout: config.SIGNER_REQUEST_TIMEOUT},
+	}
+	resp := &SignerPublicKeyResponse{}
+	err := this.request(http.MethodGet, SIGNER_PUBLIC_KEY_PATH + "?key=" + url.QueryEscape(cfg.KeyID), nil, resp)
+	if err != nil {
+		return nil, fmt.Errorf("get public key of signer %s err: %v", cfg.URL, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("get public key of signer %s err: %s", cfg.URL, resp.Error)
+	}
+	data, err := hex.DecodeString(resp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of signer %s: %v", cfg.URL, err)
+	}
+	this.publicKey, err = keypair.DeserializePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of signer %s: %v", cfg.URL, err)
+	}
+	return this, nil
+}
+
+func (this *remoteSigner) request(method string, path string, req interface{}, resp interface{}) error {
+	var body *bytes.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	httpReq, err := http.NewRequest(method, this.cfg.URL + path, body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if this.cfg.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer " + this.cfg.Token)
+	}
+	httpResp, err := this.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("signer response status %s", httpResp.Status)
+	}
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
+
+//Sign ask the signer to sign the data and verify the signature
+func (this *remoteSigner) Sign(data []byte) ([]byte, error) {
+	resp := &SignerSignResponse{}
+	err := this.request(http.MethodPost, SIGNER_SIGN_PATH, &SignerSignRequest{
+		Key:  this.cfg.KeyID,
+		Data: hex.EncodeToString(data),
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf(resp.Error)
+	}
+	sigData, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature of signer: %v", err)
+	}
+	sig, err := signature.Deserialize(sigData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature of signer: %v", err)
+	}
+	if !signature.Verify(this.publicKey, data, sig) {
+		return nil, fmt.Errorf("signature of signer %s does not match its public key", this.cfg.URL)
+	}
+	return sigData, nil
+}
+
+func (this *remoteSigner) GetPublicKey() keypair.PublicKey {
+	return this.publicKey
+}
+
+//GetPrivateKey return nil, the private key is kept by the signer
+func (this *remoteSigner) GetPrivateKey() keypair.PrivateKey {
+	return nil
+}
+
+func (this *remoteSigner) GetSigScheme() signature.SignatureScheme {
+	return signature.SHA256withECDSA
+}
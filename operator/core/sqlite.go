@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/ontio/layer2/operator/config"
+)
+
+//sqliteDialect rewrite the MySQL statements of the operator for the embedded SQLite database, which needs no database
+//server, the tables are created when the database is connected
+type sqliteDialect struct {
+}
+
+func (this *sqliteDialect) DriverName() string {
+	return DB_SQLITE_DRIVER_NAME
+}
+
+//DataSource return the database file of ProjectDBName, the writers wait for the lock of the file instead of failing
+//and the transactions take the write lock when they begin
+func (this *sqliteDialect) DataSource(cfg *config.DBConfig) string {
+	return "file:" + cfg.ProjectDBName + "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+}
+
+//Rewrite replace insert ignore and on duplicate key update by on conflict, sqlite has ifnull and the backtick quotes
+func (this *sqliteDialect) Rewrite(query string) string {
+	return rewriteConflicts(query)
+}
+
+//Init create the tables which do not exist
+func (this *sqliteDialect) Init(db *sql.DB) error {
+	for _, statement := range strings.Split(sqliteSchema, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the database file is of a single operator, so there is no leader election and the lock is always held
+
+func (this *sqliteDialect) TryLockSQL() string {
+	return "select 1 where ? is not null"
+}
+
+func (this *sqliteDialect) IsLockedSQL() string {
+	return "select 1 where ? is not null"
+}
+
+func (this *sqliteDialect) UnlockSQL() string {
+	return "select 1 where ? is not null"
+}
+
+// the tables of docs/explorer.sql for SQLite, the baseline of the schema migrations which create the later tables
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS chain_info (
+ name VARCHAR(100) NOT NULL,
+ id INTEGER NOT NULL,
+ url VARCHAR(256) NOT NULL,
+ height INTEGER NOT NULL,
+ PRIMARY KEY (id)
+);
+INSERT OR IGNORE INTO chain_info(name,id,url,height) VALUES('ontology',1,'http://138.91.6.125:20336',0);
+INSERT OR IGNORE INTO chain_info(name,id,url,height) VALUES('layer2',2,'http://47.90.189.186:40332',0);
+CREATE TABLE IF NOT EXISTS deposit (
+ txhash VARCHAR(256) NOT NULL,
+ tt INTEGER NOT NULL,
+ state INTEGER NOT NULL,
+ height INTEGER NOT NULL,
+ fromaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ id INTEGER NOT NULL,
+ layer2txhash VARCHAR(256) DEFAULT NULL,
+ PRIMARY KEY (id),
+ UNIQUE (txhash)
+);
+CREATE TABLE IF NOT EXISTS withdraw (
+ txhash VARCHAR(256) NOT NULL,
+ tt INTEGER NOT NULL,
+ state INTEGER NOT NULL,
+ height INTEGER NOT NULL,
+ toaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ ontologytxhash VARCHAR(256) DEFAULT NULL,
+ PRIMARY KEY (txhash)
+);
+CREATE TABLE IF NOT EXISTS layer2tx (
+ txhash VARCHAR(256) NOT NULL,
+ state INTEGER NOT NULL,
+ tt INTEGER NOT NULL,
+ fee BIGINT NOT NULL,
+ height INTEGER NOT NULL,
+ fromaddress VARCHAR(256) NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ toaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ PRIMARY KEY (txhash)
+);
+CREATE TABLE IF NOT EXISTS layer2commit (
+ txhash VARCHAR(256) NOT NULL,
+ state INTEGER DEFAULT 0,
+ tt INTEGER DEFAULT 0,
+ fee BIGINT DEFAULT 0,
+ ontologyheight INTEGER DEFAULT 0,
+ layer2height INTEGER DEFAULT 0,
+ layer2msg VARCHAR(1024) NOT NULL,
+ PRIMARY KEY (txhash)
+);`
@@ -0,0 +1,194 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"sync"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+// the max blocks of each chain the standby keeps ahead of the parse height of the leader
+const STANDBY_PREFETCH_BLOCKS = 1000
+
+//standbyCache keep the blocks fetched by the standby ahead of the parse heights of the leader. The standby saves and
+//submits nothing, the instance becoming leader parses the blocks kept instead of fetching them again, so it catches up
+//with the chains within seconds after taking over
+type standbyCache struct {
+	sync.Mutex
+	l1Blocks      map[uint32]*L1Block
+	layer2Blocks  map[uint32]*layer2Block
+	l1Leader      uint32
+	layer2Leader  uint32
+}
+
+func newStandbyCache() *standbyCache {
+	return &standbyCache{
+		l1Blocks:     make(map[uint32]*L1Block),
+		layer2Blocks: make(map[uint32]*layer2Block),
+	}
+}
+
+//followL1 drop the L1 blocks parsed by the leader, and all the blocks if the leader is rewound by a reorg, then return
+//the height after the blocks kept consecutively
+func (this *standbyCache) followL1(leaderHeight uint32) uint32 {
+	this.Lock()
+	defer this.Unlock()
+	for height := range this.l1Blocks {
+		if height <= leaderHeight || leaderHeight < this.l1Leader {
+			delete(this.l1Blocks, height)
+		}
+	}
+	this.l1Leader = leaderHeight
+	height := leaderHeight + 1
+	for this.l1Blocks[height] != nil {
+		height ++
+	}
+	return height
+}
+
+func (this *standbyCache) putL1(height uint32, block *L1Block) {
+	this.Lock()
+	defer this.Unlock()
+	this.l1Blocks[height] = block
+}
+
+//takeL1 return and remove the L1 block of height, nil if it is not kept
+func (this *standbyCache) takeL1(height uint32) *L1Block {
+	this.Lock()
+	defer this.Unlock()
+	block := this.l1Blocks[height]
+	delete(this.l1Blocks, height)
+	return block
+}
+
+//dropL1Above drop the L1 blocks above height, which may be reorged
+func (this *standbyCache) dropL1Above(height uint32) {
+	this.Lock()
+	defer this.Unlock()
+	for h := range this.l1Blocks {
+		if h > height {
+			delete(this.l1Blocks, h)
+		}
+	}
+}
+
+//followLayer2 drop the layer2 blocks parsed by the leader, and all the blocks if the leader is rewound by a challenge,
+//then return the height after the blocks kept consecutively
+func (this *standbyCache) followLayer2(leaderHeight uint32) uint32 {
+	this.Lock()
+	defer this.Unlock()
+	for height := range this.layer2Blocks {
+		if height <= leaderHeight || leaderHeight < this.layer2Leader {
+			delete(this.layer2Blocks, height)
+		}
+	}
+	this.layer2Leader = leaderHeight
+	height := leaderHeight + 1
+	for this.layer2Blocks[height] != nil {
+		height ++
+	}
+	return height
+}
+
+func (this *standbyCache) putLayer2(block *layer2Block) {
+	this.Lock()
+	defer this.Unlock()
+	this.layer2Blocks[block.height] = block
+}
+
+//takeLayer2 return and remove the layer2 blocks kept consecutively from height, at most count
+func (this *standbyCache) takeLayer2(height uint32, count int) []*layer2Block {
+	this.Lock()
+	defer this.Unlock()
+	blocks := make([]*layer2Block, 0)
+	for i := 0; i < count; i ++ {
+		block := this.layer2Blocks[height + uint32(i)]
+		if block == nil {
+			break
+		}
+		delete(this.layer2Blocks, block.height)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+//followL1Chain fetch the confirmed L1 blocks above the parse height of the leader into the standby cache, at most
+//STANDBY_PREFETCH_BLOCKS ahead of the leader
+func (this *Layer2Operator) followL1Chain(currentHeight uint32) {
+	chain := LoadChainInfo(this.l1.Name())
+	if chain == nil {
+		return
+	}
+	height := this.standby.followL1(chain.Height)
+	last := this.l1ConfirmedHeight(currentHeight)
+	if last > chain.Height + STANDBY_PREFETCH_BLOCKS {
+		last = chain.Height + STANDBY_PREFETCH_BLOCKS
+	}
+	log.Infof("standby - chain %s current height: %d, leader parse height: %d, fetched height: %d", this.l1.Name(),
+		currentHeight, chain.Height, height - 1)
+	for ; height <= last && !this.isLeading() && !this.stopping(); height ++ {
+		block, err := this.l1.GetEvents(height)
+		if err != nil {
+			log.Errorf("standby - get %s block %d err: %v", this.l1.Name(), height, err)
+			return
+		}
+		this.standby.putL1(height, block)
+	}
+}
+
+//followLayer2Chain fetch the layer2 blocks above the parse height of the leader into the standby cache, at most
+//STANDBY_PREFETCH_BLOCKS ahead of the leader
+func (this *Layer2Operator) followLayer2Chain(currentHeight uint32) {
+	chain := LoadChainInfo(this.layer2ChainInfo.Name)
+	if chain == nil || currentHeight == 0 {
+		return
+	}
+	height := this.standby.followLayer2(chain.Height)
+	last := currentHeight - 1
+	if last > chain.Height + STANDBY_PREFETCH_BLOCKS {
+		last = chain.Height + STANDBY_PREFETCH_BLOCKS
+	}
+	log.Infof("standby - chain %s current height: %d, leader parse height: %d, fetched height: %d",
+		this.layer2ChainInfo.Name, currentHeight, chain.Height, height - 1)
+	for height <= last && !this.isLeading() && !this.stopping() {
+		count := int(last - height + 1)
+		if concurrency := this.layer2ParseConcurrency(); concurrency < count {
+			count = concurrency
+		}
+		for _, block := range this.fetchLayer2Blocks(height, count) {
+			if block.err != nil {
+				log.Errorf("standby - get layer2 block %d err: %v", block.height, block.err)
+				return
+			}
+			this.standby.putLayer2(block)
+			height ++
+		}
+	}
+}
+
+//layer2Blocks return the blocks of count heights from height, the blocks kept by the standby are taken first and the
+//others are fetched. The blocks are in height order and end at the first one failed
+func (this *Layer2Operator) layer2Blocks(height uint32, count int) []*layer2Block {
+	blocks := this.standby.takeLayer2(height, count)
+	if len(blocks) < count {
+		blocks = append(blocks, this.fetchLayer2Blocks(height + uint32(len(blocks)), count - len(blocks))...)
+	}
+	return blocks
+}
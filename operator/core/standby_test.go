@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+)
+
+func TestStandbyCacheFollowL1(t *testing.T) {
+	cache := newStandbyCache()
+	if next := cache.followL1(10); next != 11 {
+		t.Fatalf("next height %d, want 11", next)
+	}
+	for height := uint32(11); height <= 15; height ++ {
+		cache.putL1(height, &L1Block{Height: height})
+	}
+	// the blocks parsed by the leader are dropped
+	if next := cache.followL1(12); next != 16 {
+		t.Fatalf("next height %d, want 16", next)
+	}
+	if cache.takeL1(12) != nil {
+		t.Fatalf("block parsed by the leader is kept")
+	}
+	block := cache.takeL1(13)
+	if block == nil || block.Height != 13 {
+		t.Fatalf("block 13 is not kept")
+	}
+	if next := cache.followL1(12); next != 13 {
+		t.Fatalf("next height %d, want 13 after block 13 is taken", next)
+	}
+	// the leader rewound by a reorg drops all the blocks
+	if next := cache.followL1(11); next != 12 {
+		t.Fatalf("next height %d, want 12 after the leader is rewound", next)
+	}
+	if cache.takeL1(14) != nil {
+		t.Fatalf("block above the rewound leader is kept")
+	}
+
+	cache.putL1(12, &L1Block{Height: 12})
+	cache.putL1(13, &L1Block{Height: 13})
+	cache.dropL1Above(12)
+	if cache.takeL1(13) != nil || cache.takeL1(12) == nil {
+		t.Fatalf("dropL1Above(12) drops the wrong blocks")
+	}
+}
+
+func TestStandbyCacheTakeLayer2(t *testing.T) {
+	cache := newStandbyCache()
+	cache.followLayer2(5)
+	for _, height := range []uint32{6, 7, 9} {
+		cache.putLayer2(&layer2Block{height: height})
+	}
+	if next := cache.followLayer2(5); next != 8 {
+		t.Fatalf("next height %d, want 8", next)
+	}
+	// the blocks are taken consecutively from the height
+	blocks := cache.takeLayer2(6, 5)
+	if len(blocks) != 2 || blocks[0].height != 6 || blocks[1].height != 7 {
+		t.Fatalf("take %d blocks, want 6 and 7", len(blocks))
+	}
+	if blocks = cache.takeLayer2(9, 1); len(blocks) != 1 || blocks[0].height != 9 {
+		t.Fatalf("block 9 is not taken")
+	}
+	if blocks = cache.takeLayer2(6, 5); len(blocks) != 0 {
+		t.Fatalf("taken blocks are kept")
+	}
+}
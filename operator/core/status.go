@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// the count of the recent failures of every kind in the status
+const STATUS_RECENT_FAILURES = 20
+
+//OperatorStatus is the state of the operator, the parse heights are of this operator, which follows the leader if it
+//is not leading
+type OperatorStatus struct {
+	Leading           bool
+	L1                string
+	L1ParseHeight     uint32
+	Layer2ParseHeight uint32
+	CommittedHeight   uint32
+	CommitTxHash      string
+	PendingDeposits   int64
+	PendingWithdraws  int64
+	DeadJobs          int64
+	Pauses            []string
+	Failures          *OperatorFailures
+	// the latest entries of the audit log
+	Audit             []*AuditEntry
+}
+
+//OperatorFailures is the recent failures of every kind, the latest first
+type OperatorFailures struct {
+	Deposits          []*Deposit
+	Commits           []*Layer2Commit
+	DeadLetters       []*DeadLetter
+}
+
+//adminStatus return the status of the operator from the parse heights and the database
+func (this *Layer2Operator) adminStatus(r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("method %s is not allowed", r.Method)
+	}
+	status := &OperatorStatus{
+		Leading:           this.isLeading(),
+		L1:                this.l1.Name(),
+		L1ParseHeight:     this.l1ChainInfo.Height,
+		Layer2ParseHeight: this.layer2ChainInfo.Height,
+		PendingDeposits:   CountDepositsByState(DEPOSIT_EVENT),
+		PendingWithdraws:  CountWithdrawsByState(WITHDRAW_INIT),
+		DeadJobs:          CountJobsByState(JOB_DEAD),
+		Pauses:            LoadPauses(),
+	}
+	commit := LoadLastLayer2Commit(LAYER2MSG_FINISH)
+	if commit != nil {
+		status.CommittedHeight = uint32(commit.Layer2Height)
+		status.CommitTxHash = commit.TxHash
+	}
+	limit, err := queryLimit(r, STATUS_RECENT_FAILURES)
+	if err != nil {
+		return nil, err
+	}
+	failures := &OperatorFailures{
+		Deposits:    LoadRecentDepositsByState(DEPOSIT_FAILED, limit),
+		Commits:     LoadRecentLayer2Commits(LAYER2MSG_FAILED, limit),
+		DeadLetters: LoadDeadLetters(),
+	}
+	if failures.Deposits == nil || failures.Commits == nil || failures.DeadLetters == nil {
+		return nil, fmt.Errorf("load recent failures failed")
+	}
+	// the dead letters are in order of id
+	letters := make([]*DeadLetter, 0, limit)
+	for i := len(failures.DeadLetters) - 1; i >= 0 && len(letters) < limit; i -- {
+		letters = append(letters, failures.DeadLetters[i])
+	}
+	failures.DeadLetters = letters
+	status.Failures = failures
+	status.Audit = LoadAuditLog(0, limit)
+	if status.Audit == nil {
+		return nil, fmt.Errorf("load audit log failed")
+	}
+	return status, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ontio/layer2/operator/config"
+)
+
+//submitQueue is in front of the L1 node for the transactions of the operator. The transactions of the same payer are
+//built, signed and sent one by one, so the nonce of a payer is taken after its last transaction is accepted, and the
+//commits and refunds pending at the same time never conflict. At most maxInFlight transactions of all payers are
+//being sent at the same time, and the submissions are paced interval apart
+type submitQueue struct {
+	slots      chan struct{}
+	interval   time.Duration
+	abort      <-chan struct{}
+	mu         sync.Mutex
+	// the lock of every payer, a channel so the wait is aborted on shutdown
+	payers     map[string]chan struct{}
+	// the earliest time of the next submission
+	next       time.Time
+}
+
+func newSubmitQueue(cfg *config.SubmitConfig, abort <-chan struct{}) *submitQueue {
+	maxInFlight := config.DEFAULT_SUBMIT_MAX_IN_FLIGHT
+	interval := config.DEFAULT_SUBMIT_INTERVAL
+	if cfg != nil {
+		if cfg.MaxInFlight > 0 {
+			maxInFlight = cfg.MaxInFlight
+		}
+		if cfg.Interval > 0 {
+			interval = time.Duration(cfg.Interval) * time.Millisecond
+		}
+	}
+	return &submitQueue{
+		slots:    make(chan struct{}, maxInFlight),
+		interval: interval,
+		abort:    abort,
+		payers:   make(map[string]chan struct{}),
+	}
+}
+
+//Submit run send once the transactions of the payer before it are sent, a slot of the transactions in flight is free
+//and the pace allows, send builds, signs and sends the transaction of the payer. errShutdown is returned if the wait
+//is aborted on shutdown
+func (this *submitQueue) Submit(payer string, send func() (string, error)) (string, error) {
+	lock := this.payerLock(payer)
+	select {
+	case lock <- struct{}{}:
+	case <- this.abort:
+		return "", errShutdown
+	}
+	defer func() { <- lock }()
+	select {
+	case this.slots <- struct{}{}:
+	case <- this.abort:
+		return "", errShutdown
+	}
+	defer func() { <- this.slots }()
+	if !this.pace() {
+		return "", errShutdown
+	}
+	return send()
+}
+
+func (this *submitQueue) payerLock(payer string) chan struct{} {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	lock, ok := this.payers[payer]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		this.payers[payer] = lock
+	}
+	return lock
+}
+
+//pace wait until interval after the last submission, return false if it is aborted on shutdown
+func (this *submitQueue) pace() bool {
+	this.mu.Lock()
+	now := time.Now()
+	at := this.next
+	if at.Before(now) {
+		at = now
+	}
+	this.next = at.Add(this.interval)
+	this.mu.Unlock()
+	if !at.After(now) {
+		return true
+	}
+	select {
+	case <- time.After(at.Sub(now)):
+		return true
+	case <- this.abort:
+		return false
+	}
+}
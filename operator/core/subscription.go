@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	layer2_client "github.com/ontio/layer2/go-sdk/client"
+	layer2_sdk_common "github.com/ontio/layer2/go-sdk/common"
+	layer2_types "github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/operator/log"
+	ontology_sdk "github.com/ontio/ontology-go-sdk"
+	ontology_client "github.com/ontio/ontology-go-sdk/client"
+	ontology_sdk_common "github.com/ontio/ontology-go-sdk/common"
+	ontology_types "github.com/ontio/ontology/core/types"
+)
+
+const (
+	// the chain is still polled at the interval while the blocks are pushed, in case a push is missed
+	SUBSCRIBED_POLL_INTERVAL = 10 * time.Second
+	// the interval to check the websocket is alive, or to connect it again until the first subscription succeeds.
+	// The sdk reconnects and subscribes again after it
+	WS_CHECK_INTERVAL = 10 * time.Second
+	// the heartbeat of the websocket, the connection without any message for the timeout is connected again
+	WS_HEARTBEAT_INTERVAL = 10
+	WS_HEARTBEAT_TIMEOUT  = 30
+	// the pushed blocks kept for the parser
+	WS_CACHE_SIZE = 100
+)
+
+//blockSubscriber is the websocket client of a chain node, the ontology and layer2 sdks have the same websocket api of
+//their own types
+type blockSubscriber interface {
+	Connect(url string) error
+	SubscribeBlock() error
+	//GetCurrentBlockHeight query the height over the websocket
+	GetCurrentBlockHeight() (uint32, error)
+	//receive call push with the pushed blocks, it never returns
+	receive(push func(height uint32, block interface{}))
+}
+
+//blockSubscription subscribe the blocks of a chain node by websocket. The height of the pushed block wakes up the
+//monitor of the chain, and the block is kept so the parser does not get it again. The websocket is checked by querying
+//the height over it, and the monitor polls the node while the check fails. The monitor parses from its parse height
+//to the current height, so the blocks pushed while the websocket is down are parsed after it is alive again
+type blockSubscription struct {
+	name       string
+	url        string
+	client     blockSubscriber
+	notify     chan uint32
+	mu         sync.Mutex
+	alive      bool
+	blocks     map[uint32]interface{}
+	maxHeight  uint32
+}
+
+func newBlockSubscription(name string, url string, client blockSubscriber) *blockSubscription {
+	this := &blockSubscription{
+		name:   name,
+		url:    url,
+		client: client,
+		notify: make(chan uint32, 1),
+		blocks: make(map[uint32]interface{}),
+	}
+	go this.checkLoop()
+	go this.client.receive(this.push)
+	return this
+}
+
+func (this *blockSubscription) setAlive(alive bool) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if alive != this.alive {
+		if alive {
+			log.Infof("%s websocket %s, blocks subscribed", this.name, this.url)
+			// wake up the monitor to parse the blocks missed while the websocket is down
+			this.wake(this.maxHeight)
+		} else {
+			log.Warnf("%s websocket %s is down, poll the %s node", this.name, this.url, this.name)
+		}
+	}
+	this.alive = alive
+}
+
+func (this *blockSubscription) wake(height uint32) {
+	// the monitor reads the current height from the node, so a pending notify is enough
+	select {
+	case this.notify <- height:
+	default:
+	}
+}
+
+//NewBlocks return the channel of the pushed heights, nil if the websocket is down
+func (this *blockSubscription) NewBlocks() <-chan uint32 {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.alive {
+		return nil
+	}
+	return this.notify
+}
+
+//Block return the pushed block of height and forget it, nil if it is not pushed
+func (this *blockSubscription) Block(height uint32) interface{} {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	block := this.blocks[height]
+	delete(this.blocks, height)
+	return block
+}
+
+//checkLoop connect and subscribe the websocket until it succeeds, then check the websocket by the height query
+func (this *blockSubscription) checkLoop() {
+	connected := false
+	subscribed := false
+	for {
+		var err error
+		if !connected {
+			err = this.client.Connect(this.url)
+			connected = err == nil
+		}
+		if connected && !subscribed {
+			err = this.client.SubscribeBlock()
+			subscribed = err == nil
+		}
+		if err != nil {
+			log.Warnf("subscribe blocks of %s websocket %s err: %v, poll the %s node", this.name, this.url, err, this.name)
+		}
+		if subscribed {
+			_, err = this.client.GetCurrentBlockHeight()
+			this.setAlive(err == nil)
+		}
+		time.Sleep(WS_CHECK_INTERVAL)
+	}
+}
+
+func (this *blockSubscription) push(height uint32, block interface{}) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.blocks[height] = block
+	if height > this.maxHeight {
+		this.maxHeight = height
+	}
+	for cached := range this.blocks {
+		if cached + WS_CACHE_SIZE <= this.maxHeight {
+			delete(this.blocks, cached)
+		}
+	}
+	this.wake(height)
+}
+
+//ontologySubscriber is the websocket of the ontology node, the queries of its sdk are sent over the websocket
+type ontologySubscriber struct {
+	*ontology_client.WSClient
+	sdk *ontology_sdk.OntologySdk
+}
+
+func newOntologySubscriber() *ontologySubscriber {
+	sdk := ontology_sdk.NewOntologySdk()
+	client := sdk.NewWebSocketClient()
+	client.SetHeartbeatInterval(WS_HEARTBEAT_INTERVAL)
+	client.SetHeartbeatTimeout(WS_HEARTBEAT_TIMEOUT)
+	return &ontologySubscriber{client, sdk}
+}
+
+func (this *ontologySubscriber) GetCurrentBlockHeight() (uint32, error) {
+	return this.sdk.GetCurrentBlockHeight()
+}
+
+func (this *ontologySubscriber) receive(push func(height uint32, block interface{})) {
+	for action := range this.GetActionCh() {
+		if action.Action != ontology_sdk_common.WS_SUBSCRIBE_ACTION_BLOCK {
+			continue
+		}
+		block, ok := action.Result.(*ontology_types.Block)
+		if ok && block.Header != nil {
+			push(block.Header.Height, block)
+		}
+	}
+}
+
+//layer2Subscriber is the websocket of the layer2 node, the queries of its sdk are sent over the websocket
+type layer2Subscriber struct {
+	*layer2_client.WSClient
+	sdk *layer2_sdk.OntologySdk
+}
+
+func newLayer2Subscriber() *layer2Subscriber {
+	sdk := layer2_sdk.NewOntologySdk()
+	client := sdk.NewWebSocketClient()
+	client.SetHeartbeatInterval(WS_HEARTBEAT_INTERVAL)
+	client.SetHeartbeatTimeout(WS_HEARTBEAT_TIMEOUT)
+	return &layer2Subscriber{client, sdk}
+}
+
+func (this *layer2Subscriber) GetCurrentBlockHeight() (uint32, error) {
+	return this.sdk.GetCurrentBlockHeight()
+}
+
+func (this *layer2Subscriber) receive(push func(height uint32, block interface{})) {
+	for action := range this.GetActionCh() {
+		if action.Action != layer2_sdk_common.WS_SUBSCRIBE_ACTION_BLOCK {
+			continue
+		}
+		block, ok := action.Result.(*layer2_types.Block)
+		if ok && block.Header != nil {
+			push(block.Header.Height, block)
+		}
+	}
+}
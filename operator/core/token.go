@@ -0,0 +1,340 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	layer2_sdk_common "github.com/ontio/layer2/go-sdk/common"
+	layer2_common "github.com/ontio/layer2/node/common"
+	layer2_types "github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/node/smartcontract/service/native/ont"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+	"sort"
+	"sync"
+)
+
+//tokenRegistry is the token registry of the database cached in memory and indexed by the ontology and the layer2
+//address, the admin api changes it while the loops read it
+type tokenRegistry struct {
+	lock            sync.RWMutex
+	tokens          map[string]*Token
+	layer2Tokens    map[string]*Token
+}
+
+func newTokenRegistry() *tokenRegistry {
+	return &tokenRegistry{
+		tokens:       make(map[string]*Token),
+		layer2Tokens: make(map[string]*Token),
+	}
+}
+
+//init register ONT, ONG and the configured tokens not registered yet, and load the registry. ONT and ONG are
+//always bridged to the layer2 native assets
+func (this *tokenRegistry) init(tokenConfigs []*config.TokenConfig) error {
+	for _, token := range ConfigTokens(tokenConfigs) {
+		err := checkToken(token)
+		if err != nil {
+			return err
+		}
+		err = SaveToken(token)
+		if err != nil {
+			return err
+		}
+	}
+	return this.load()
+}
+
+//load replace the cached tokens by the registry of the database
+func (this *tokenRegistry) load() error {
+	tokens := LoadTokens()
+	if tokens == nil {
+		return fmt.Errorf("load token registry failed")
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.tokens = make(map[string]*Token)
+	this.layer2Tokens = make(map[string]*Token)
+	for _, token := range tokens {
+		this.tokens[token.Address] = token
+		this.layer2Tokens[token.Layer2Address] = token
+	}
+	return nil
+}
+
+//get return the copy of the token of the ontology address, nil if not registered
+func (this *tokenRegistry) get(address string) *Token {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	token, ok := this.tokens[address]
+	if !ok {
+		return nil
+	}
+	result := *token
+	return &result
+}
+
+//getByLayer2 return the copy of the token of the layer2 address, nil if not registered
+func (this *tokenRegistry) getByLayer2(layer2Address string) *Token {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	token, ok := this.layer2Tokens[layer2Address]
+	if !ok {
+		return nil
+	}
+	result := *token
+	return &result
+}
+
+func (this *tokenRegistry) list() []*Token {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	tokens := make([]*Token, 0, len(this.tokens))
+	for _, token := range this.tokens {
+		result := *token
+		tokens = append(tokens, &result)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Address < tokens[j].Address
+	})
+	return tokens
+}
+
+//add register the token, the ontology and the layer2 address must both be new
+func (this *tokenRegistry) add(token *Token) error {
+	if token.Layer2Address == "" {
+		token.Layer2Address = token.Address
+	}
+	err := checkToken(token)
+	if err != nil {
+		return err
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if _, ok := this.tokens[token.Address]; ok {
+		return fmt.Errorf("token %s is registered already", token.Address)
+	}
+	if _, ok := this.layer2Tokens[token.Layer2Address]; ok {
+		return fmt.Errorf("layer2 address %s is bridged already", token.Layer2Address)
+	}
+	err = SaveToken(token)
+	if err != nil {
+		return err
+	}
+	this.tokens[token.Address] = token
+	this.layer2Tokens[token.Layer2Address] = token
+	return nil
+}
+
+//setEnabled enable or pause the deposits of the token
+func (this *tokenRegistry) setEnabled(address string, enabled bool) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	token, ok := this.tokens[address]
+	if !ok {
+		return fmt.Errorf("token %s is not registered", address)
+	}
+	err := UpdateTokenEnabled(address, enabled)
+	if err != nil {
+		return err
+	}
+	token.Enabled = enabled
+	return nil
+}
+
+func checkToken(token *Token) error {
+	if !isHexAddress(token.Address) || !isHexAddress(token.Layer2Address) {
+		return fmt.Errorf("token %s address %s or layer2 address %s is invalid", token.Name, token.Address, token.Layer2Address)
+	}
+	native := isNativeToken(token.Address) || isNativeToken(token.Layer2Address)
+	if native && token.Address != token.Layer2Address {
+		return fmt.Errorf("token %s can not be bridged to another native asset", token.Name)
+	}
+	if token.Standard != "" && (native || !isNFTToken(token)) {
+		return fmt.Errorf("token %s of standard %s is not supported", token.Name, token.Standard)
+	}
+	return nil
+}
+
+func isNFTToken(token *Token) bool {
+	return token.Standard == TOKEN_OEP5 || token.Standard == TOKEN_OEP8
+}
+
+//ConfigTokens return ONT and ONG of the native assets and the configured tokens
+func ConfigTokens(tokenConfigs []*config.TokenConfig) []*Token {
+	tokens := []*Token{
+		{Name: "ONT", Address: assets.ont, Layer2Address: LAYER2_ONT_ADDRESS, Decimals: 0, Enabled: true},
+		{Name: "ONG", Address: assets.ong, Layer2Address: LAYER2_ONG_ADDRESS, Decimals: 9, Enabled: true},
+	}
+	for _, tokenConfig := range tokenConfigs {
+		layer2Address := tokenConfig.Layer2Address
+		if layer2Address == "" {
+			layer2Address = tokenConfig.Address
+		}
+		tokens = append(tokens, &Token{Name: tokenConfig.Name, Address: tokenConfig.Address, Layer2Address: layer2Address, Decimals: tokenConfig.Decimals, Enabled: true, Standard: tokenConfig.Standard})
+	}
+	return tokens
+}
+
+func isHexAddress(address string) bool {
+	data, err := hex.DecodeString(address)
+	return err == nil && len(data) == 20
+}
+
+//newMintTransaction build the transaction transferring the deposits of the token from the empty address. The native
+//assets are minted by the ont or ong multi-transfer, the OEP-4 token by the transferMulti of its layer2 contract with
+//[from, to, amount], and the OEP-5 and OEP-8 token by the transferMulti with [from, to, tokenId, amount]
+func (this *Layer2Operator) newMintTransaction(tokenAddress string, deposits []*Deposit) (*layer2_types.MutableTransaction, error) {
+	token := this.tokens.get(tokenAddress)
+	if token == nil {
+		return nil, fmt.Errorf("token %s is not bridged", tokenAddress)
+	}
+	// the base fee of the node does not change with the latest blocks, so the mint built again has the same hash
+	fee, err := this.layer2Sdk().BaseFee(len(deposits))
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, gasLimit := fee.GasPrice, fee.GasLimit
+	if isNativeToken(token.Address) {
+		states := make([]*ont.State, 0, len(deposits))
+		for _, deposit := range deposits {
+			toAddr, _ := layer2_common.AddressFromBase58(deposit.FromAddress)
+			states = append(states, &ont.State{From: assets.collector, To: toAddr, Value: deposit.Amount})
+		}
+		if isOntToken(token) {
+			return this.layer2Sdk().Native.Ont.NewMultiTransferTransaction(gasPrice, gasLimit, states)
+		}
+		return this.layer2Sdk().Native.Ong.NewMultiTransferTransaction(gasPrice, gasLimit, states)
+	}
+	data, _ := hex.DecodeString(token.Layer2Address)
+	contractAddress, err := layer2_common.AddressParseFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	transfers := make([]interface{}, 0, len(deposits))
+	for _, deposit := range deposits {
+		toAddr, _ := layer2_common.AddressFromBase58(deposit.FromAddress)
+		if isNFTToken(token) {
+			tokenId, err := hex.DecodeString(deposit.TokenId)
+			if err != nil || len(tokenId) == 0 {
+				return nil, fmt.Errorf("token id %s of deposit %d is invalid", deposit.TokenId, deposit.ID)
+			}
+			transfers = append(transfers, []interface{}{assets.collector, toAddr, tokenId, deposit.Amount})
+		} else {
+			transfers = append(transfers, []interface{}{assets.collector, toAddr, deposit.Amount})
+		}
+	}
+	if this.config().Layer2Config.GasLimit > gasLimit {
+		gasLimit = this.config().Layer2Config.GasLimit
+	}
+	return this.layer2Sdk().NeoVM.NewNeoVMInvokeTransaction(gasPrice, gasLimit, contractAddress, []interface{}{"transferMulti", []interface{}{transfers}})
+}
+
+//transferNotify is the transfer of a bridged token on layer2, Token is the ontology address of the token and TokenId
+//is the hex of the token id of the OEP-5 and OEP-8 token
+type transferNotify struct {
+	Token           string
+	From            string
+	To              string
+	TokenId         string
+	Amount          uint64
+}
+
+//decodeTransferNotify return the transfer of the notify of a bridged token on layer2, nil if it is not a transfer of a
+//bridged token. The native notify is [transfer, from, to, amount], and the states of the neovm notify are the hex of
+//[transfer, from, to, amount] of OEP-4, [transfer, from, to, tokenId] of amount 1 of OEP-5, and [transfer, from, to,
+//tokenId, amount] of OEP-8. The transfer not matching the schema of its token is returned as bridge.DecodeError
+func (this *Layer2Operator) decodeTransferNotify(notify *layer2_sdk_common.NotifyEventInfo) (*transferNotify, error) {
+	token := this.tokens.getByLayer2(revertHexString(notify.ContractAddress))
+	if token == nil {
+		return nil, nil
+	}
+	states, ok := notify.States.([]interface{})
+	if !ok || len(states) == 0 {
+		return nil, transferDecodeError("states of token %s is not array", token.Address)
+	}
+	if isNativeToken(token.Layer2Address) {
+		if states[0] != NOTIFY_TRANSFER {
+			return nil, nil
+		}
+		if len(states) != 4 {
+			return nil, transferDecodeError("native transfer need 4 states, got %d", len(states))
+		}
+		from, ok1 := states[1].(string)
+		to, ok2 := states[2].(string)
+		amount, ok3 := states[3].(uint64)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, transferDecodeError("native transfer states are not [from, to, amount]")
+		}
+		return &transferNotify{Token: token.Address, From: from, To: to, Amount: amount}, nil
+	}
+	values := make([][]byte, 0, len(states))
+	for i, state := range states {
+		item, ok := state.(string)
+		if !ok {
+			return nil, transferDecodeError("state %d of token %s is not string", i, token.Address)
+		}
+		value, err := hex.DecodeString(item)
+		if err != nil {
+			return nil, transferDecodeError("state %d of token %s is not hex: %s", i, token.Address, err)
+		}
+		values = append(values, value)
+	}
+	if string(values[0]) != NOTIFY_TRANSFER {
+		return nil, nil
+	}
+	if len(values) < 4 {
+		return nil, transferDecodeError("transfer of token %s need at least 4 states, got %d", token.Address, len(values))
+	}
+	from, err := layer2_common.AddressParseFromBytes(values[1])
+	if err != nil {
+		return nil, transferDecodeError("transfer from is not address: %s", err)
+	}
+	to, err := layer2_common.AddressParseFromBytes(values[2])
+	if err != nil {
+		return nil, transferDecodeError("transfer to is not address: %s", err)
+	}
+	transfer := &transferNotify{Token: token.Address, From: from.ToBase58(), To: to.ToBase58()}
+	var amountValue []byte
+	switch {
+	case token.Standard == TOKEN_OEP5 && len(values) == 4:
+		transfer.TokenId = hex.EncodeToString(values[3])
+		transfer.Amount = 1
+		return transfer, nil
+	case token.Standard == TOKEN_OEP8 && len(values) == 5:
+		transfer.TokenId = hex.EncodeToString(values[3])
+		amountValue = values[4]
+	case !isNFTToken(token) && len(values) == 4:
+		amountValue = values[3]
+	default:
+		return nil, transferDecodeError("transfer of token %s of standard %q has %d states", token.Address, token.Standard, len(values))
+	}
+	amount := layer2_common.BigIntFromNeoBytes(amountValue)
+	if amount.Sign() < 0 || !amount.IsUint64() {
+		return nil, transferDecodeError("transfer amount %s is not uint64", amount.String())
+	}
+	transfer.Amount = amount.Uint64()
+	return transfer, nil
+}
+
+func transferDecodeError(format string, args ...interface{}) error {
+	return &bridge.DecodeError{Event: NOTIFY_TRANSFER, Reason: fmt.Sprintf(format, args...)}
+}
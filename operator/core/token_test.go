@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	layer2_sdk_common "github.com/ontio/layer2/go-sdk/common"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/config"
+)
+
+func TestDecodeTransferNotify(t *testing.T) {
+	err := SetAssets(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := layer2_common.Address{1}, layer2_common.Address{2}
+	fromHex, toHex := hex.EncodeToString(from[:]), hex.EncodeToString(to[:])
+	neoInt := func(value int64) string {
+		return hex.EncodeToString(layer2_common.BigIntToNeoBytes(big.NewInt(value)))
+	}
+	transfer := hex.EncodeToString([]byte(NOTIFY_TRANSFER))
+	operator := &Layer2Operator{tokens: newTokenRegistry()}
+	tokens := []*Token{
+		{Name: "ONT", Address: assets.ont, Layer2Address: LAYER2_ONT_ADDRESS},
+		{Name: "OEP4", Address: "0000000000000000000000000000000000000a01", Layer2Address: "0000000000000000000000000000000000000b01"},
+		{Name: "OEP5", Address: "0000000000000000000000000000000000000a02", Layer2Address: "0000000000000000000000000000000000000b02", Standard: TOKEN_OEP5},
+		{Name: "OEP8", Address: "0000000000000000000000000000000000000a03", Layer2Address: "0000000000000000000000000000000000000b03", Standard: TOKEN_OEP8},
+	}
+	for _, token := range tokens {
+		operator.tokens.tokens[token.Address] = token
+		operator.tokens.layer2Tokens[token.Layer2Address] = token
+	}
+	notify := func(token *Token, states ...interface{}) *layer2_sdk_common.NotifyEventInfo {
+		return &layer2_sdk_common.NotifyEventInfo{ContractAddress: revertHexString(token.Layer2Address), States: states}
+	}
+	ont, oep4, oep5, oep8 := tokens[0], tokens[1], tokens[2], tokens[3]
+
+	cases := []struct {
+		name   string
+		notify *layer2_sdk_common.NotifyEventInfo
+		want   *transferNotify
+	}{
+		{"native", notify(ont, NOTIFY_TRANSFER, from.ToBase58(), to.ToBase58(), uint64(10)),
+			&transferNotify{Token: ont.Address, From: from.ToBase58(), To: to.ToBase58(), Amount: 10}},
+		{"native not transfer", notify(ont, "approve", from.ToBase58(), to.ToBase58(), uint64(10)), nil},
+		{"fungible", notify(oep4, transfer, fromHex, toHex, neoInt(1000)),
+			&transferNotify{Token: oep4.Address, From: from.ToBase58(), To: to.ToBase58(), Amount: 1000}},
+		{"fungible not transfer", notify(oep4, hex.EncodeToString([]byte("approve")), fromHex, toHex, neoInt(1)), nil},
+		{"oep5", notify(oep5, transfer, fromHex, toHex, "0c"),
+			&transferNotify{Token: oep5.Address, From: from.ToBase58(), To: to.ToBase58(), TokenId: "0c", Amount: 1}},
+		{"oep8", notify(oep8, transfer, fromHex, toHex, "0c", neoInt(3)),
+			&transferNotify{Token: oep8.Address, From: from.ToBase58(), To: to.ToBase58(), TokenId: "0c", Amount: 3}},
+		{"not bridged", &layer2_sdk_common.NotifyEventInfo{ContractAddress: "0000000000000000000000000000000000000c01",
+			States: []interface{}{transfer, fromHex, toHex, neoInt(1)}}, nil},
+	}
+	for _, c := range cases {
+		got, err := operator.decodeTransferNotify(c.notify)
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: decode %+v, %v, want %+v", c.name, got, err, c.want)
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		notify *layer2_sdk_common.NotifyEventInfo
+	}{
+		{"states not array", &layer2_sdk_common.NotifyEventInfo{ContractAddress: revertHexString(oep4.Layer2Address), States: "transfer"}},
+		{"native states", notify(ont, NOTIFY_TRANSFER, from.ToBase58(), to.ToBase58())},
+		{"native amount", notify(ont, NOTIFY_TRANSFER, from.ToBase58(), to.ToBase58(), "10")},
+		{"state not hex", notify(oep4, transfer, "zz", toHex, neoInt(1))},
+		{"state not string", notify(oep4, transfer, fromHex, toHex, 1)},
+		{"from not address", notify(oep4, transfer, "0102", toHex, neoInt(1))},
+		{"negative amount", notify(oep4, transfer, fromHex, toHex, neoInt(-1))},
+		{"fungible with token id", notify(oep4, transfer, fromHex, toHex, "0c", neoInt(1))},
+		{"oep8 without token id", notify(oep8, transfer, fromHex, toHex, neoInt(1))},
+		{"too few states", notify(oep4, transfer, fromHex, toHex)},
+	}
+	for _, c := range invalid {
+		_, err := operator.decodeTransferNotify(c.notify)
+		if _, ok := err.(*bridge.DecodeError); !ok {
+			t.Errorf("%s: err %v, want DecodeError", c.name, err)
+		}
+	}
+}
+
+func TestTokenRegistry(t *testing.T) {
+	openTestDB(t)
+	err := SetAssets(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oep4 := "0000000000000000000000000000000000000a01"
+	registry := newTokenRegistry()
+	err = registry.init([]*config.TokenConfig{{Name: "OEP4", Address: oep4, Decimals: 8}})
+	if err != nil {
+		t.Fatalf("init registry: %v", err)
+	}
+	if tokens := registry.list(); len(tokens) != 3 {
+		t.Fatalf("registered %d tokens, want ONT, ONG and OEP4", len(tokens))
+	}
+	if token := registry.getByLayer2(oep4); token == nil || token.Address != oep4 || !token.Enabled {
+		t.Errorf("layer2 token %+v, want the enabled OEP4", token)
+	}
+
+	invalid := []*Token{
+		{Name: "registered", Address: oep4, Layer2Address: "0000000000000000000000000000000000000b01"},
+		{Name: "bridged", Address: "0000000000000000000000000000000000000a02", Layer2Address: oep4},
+		{Name: "short", Address: "0a02"},
+		{Name: "native", Address: "0000000000000000000000000000000000000a02", Layer2Address: LAYER2_ONT_ADDRESS},
+		{Name: "standard", Address: "0000000000000000000000000000000000000a02", Standard: "OEP9"},
+	}
+	for _, token := range invalid {
+		if err := registry.add(token); err == nil {
+			t.Errorf("%s: token is registered", token.Name)
+		}
+	}
+	err = registry.add(&Token{Name: "NFT", Address: "0000000000000000000000000000000000000a03", Standard: TOKEN_OEP5})
+	if err != nil {
+		t.Fatalf("add token: %v", err)
+	}
+	err = registry.setEnabled(oep4, false)
+	if err != nil {
+		t.Fatalf("pause token: %v", err)
+	}
+	if registry.setEnabled("0000000000000000000000000000000000000a04", false) == nil {
+		t.Errorf("unregistered token is paused")
+	}
+
+	// the registry is persisted, the configured tokens registered already are kept as they are
+	reloaded := newTokenRegistry()
+	err = reloaded.init([]*config.TokenConfig{{Name: "OEP4", Address: oep4, Decimals: 8}})
+	if err != nil {
+		t.Fatalf("reload registry: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.list(), registry.list()) {
+		t.Errorf("reloaded tokens %+v, want %+v", reloaded.list(), registry.list())
+	}
+	if token := reloaded.get(oep4); token == nil || token.Enabled {
+		t.Errorf("reloaded token %+v, want the paused OEP4", token)
+	}
+}
@@ -0,0 +1,409 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	// the stages of a deposit, from the event on L1 through the mint on layer2 to the commit of the layer2 state
+	SPAN_DEPOSIT_EVENT  = "deposit.event"
+	SPAN_DEPOSIT_MINT   = "deposit.mint"
+	SPAN_DEPOSIT_CREDIT = "deposit.credit"
+	// the stages of a withdrawal, from the event on layer2 to the commit of the layer2 state
+	SPAN_WITHDRAW_EVENT = "withdraw.event"
+	// the commit of the layer2 state including the deposit or withdrawal is sent and confirmed on L1
+	SPAN_STATE_COMMIT   = "state.commit"
+	SPAN_COMMIT_CONFIRM = "commit.confirm"
+
+	// the spans waiting for export, the spans are dropped once it is full
+	TRACE_QUEUE_SIZE = 2048
+	// the max number of spans exported by one request
+	TRACE_BATCH_SIZE = 512
+
+	TRACE_SCOPE_NAME = "github.com/ontio/layer2/operator"
+	// the span kind internal and the status code error of OTLP
+	OTLP_SPAN_KIND_INTERNAL = 1
+	OTLP_STATUS_ERROR       = 2
+)
+
+//traceRef identify the trace of a deposit or withdrawal and its root span, which are derived from the deposit id or
+//the withdraw tx hash, so the spans of the stages run by different loops or after restart are in the same trace
+type traceRef struct {
+	traceID    [16]byte
+	rootID     [8]byte
+}
+
+func newTraceRef(key string) traceRef {
+	hash := sha256.Sum256([]byte(key))
+	ref := traceRef{}
+	copy(ref.traceID[:], hash[:16])
+	copy(ref.rootID[:], hash[16:24])
+	return ref
+}
+
+func depositTrace(id uint64) traceRef {
+	return newTraceRef(fmt.Sprintf("deposit/%d", id))
+}
+
+func withdrawTrace(txHash string) traceRef {
+	return newTraceRef("withdraw/" + txHash)
+}
+
+//span is a stage of a deposit or withdrawal, the methods of the nil span are no-op so the stages are not traced if
+//TracingConfig is not set
+type span struct {
+	tracer     *tracer
+	trace      traceRef
+	id         [8]byte
+	parentID   [8]byte
+	name       string
+	start      time.Time
+	attrs      map[string]interface{}
+}
+
+//set the attribute of the span, the value is a string or an integer
+func (this *span) set(key string, value interface{}) *span {
+	if this != nil {
+		this.attrs[key] = value
+	}
+	return this
+}
+
+//end queue the span for export, the span is of error status if err is not nil
+func (this *span) end(err error) {
+	if this == nil {
+		return
+	}
+	otlp := &otlpSpan{
+		TraceId:           hex.EncodeToString(this.trace.traceID[:]),
+		SpanId:            hex.EncodeToString(this.id[:]),
+		Name:              this.name,
+		Kind:              OTLP_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: strconv.FormatInt(this.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        otlpAttributes(this.attrs),
+	}
+	if this.parentID != ([8]byte{}) {
+		otlp.ParentSpanId = hex.EncodeToString(this.parentID[:])
+	}
+	if err != nil {
+		otlp.Status = &otlpStatus{Code: OTLP_STATUS_ERROR, Message: err.Error()}
+	}
+	select {
+	case this.tracer.spans <- otlp:
+	default:
+		log.Errorf("trace - queue is full, drop span %s", this.name)
+	}
+}
+
+//tracer queue the ended spans for traceLoop, and keep the traces of the commits sent until they are confirmed
+type tracer struct {
+	spans      chan *otlpSpan
+	mu         sync.Mutex
+	commits    map[string]*tracedCommit
+}
+
+//tracedCommit is the commit sent to L1 and the traces of its deposits and withdrawals
+type tracedCommit struct {
+	height     uint32
+	traces     []traceRef
+	sent       time.Time
+}
+
+func newTracer() *tracer {
+	return &tracer{
+		spans:   make(chan *otlpSpan, TRACE_QUEUE_SIZE),
+		commits: make(map[string]*tracedCommit),
+	}
+}
+
+//startSpan start the span of a stage of the trace, which is the child of the root span, or nil if the tracing is not
+//configured
+func (this *Layer2Operator) startSpan(trace traceRef, name string) *span {
+	if this.config().TracingConfig == nil {
+		return nil
+	}
+	s := &span{
+		tracer:   this.tracer,
+		trace:    trace,
+		parentID: trace.rootID,
+		name:     name,
+		start:    time.Now(),
+		attrs:    make(map[string]interface{}),
+	}
+	rand.Read(s.id[:])
+	return s
+}
+
+//startRootSpan start the root span of the trace at start, which is the time of the event of the deposit or withdrawal
+func (this *Layer2Operator) startRootSpan(trace traceRef, name string, start time.Time) *span {
+	s := this.startSpan(trace, name)
+	if s != nil {
+		s.id = trace.rootID
+		s.parentID = [8]byte{}
+		s.start = start
+	}
+	return s
+}
+
+//endSpans end the spans of a batch of the same result
+func endSpans(spans []*span, err error) {
+	for _, s := range spans {
+		s.end(err)
+	}
+}
+
+//traceDepositEvent trace the deposit event parsed from the L1 block as the root span of the deposit, from the time of
+//the block to the deposit saved, which is of error status if the deposit is rejected
+func (this *Layer2Operator) traceDepositEvent(deposit *Deposit, err error) {
+	s := this.startRootSpan(depositTrace(deposit.ID), SPAN_DEPOSIT_EVENT, time.Unix(int64(deposit.TT), 0))
+	s.set(log.FIELD_CHAIN, this.l1.Name()).set(log.FIELD_HEIGHT, deposit.Height).set(log.FIELD_TXHASH, deposit.TxHash).
+		set(log.FIELD_DEPOSIT_ID, deposit.ID).set("token", deposit.TokenAddress).set("amount", deposit.Amount)
+	s.end(err)
+}
+
+//traceDepositCredit trace the mint of the deposit found in the layer2 block, from the time of the block to the block
+//parsed, which is of error status if the mint is quarantined for the reason
+func (this *Layer2Operator) traceDepositCredit(deposit *Deposit, layer2Tx *Layer2Tx, reason string) {
+	s := this.startSpan(depositTrace(deposit.ID), SPAN_DEPOSIT_CREDIT)
+	if s == nil {
+		return
+	}
+	s.start = time.Unix(int64(layer2Tx.TT), 0)
+	s.set(log.FIELD_CHAIN, "layer2").set(log.FIELD_HEIGHT, layer2Tx.Height).set(log.FIELD_TXHASH, layer2Tx.TxHash).
+		set(log.FIELD_DEPOSIT_ID, deposit.ID)
+	var err error
+	if reason != "" {
+		err = fmt.Errorf("quarantine: %s", reason)
+	}
+	s.end(err)
+}
+
+//traceWithdrawEvent trace the withdraw event parsed from the layer2 block as the root span of the withdrawal, from the
+//time of the block to the block parsed
+func (this *Layer2Operator) traceWithdrawEvent(withdraw *Withdraw) {
+	s := this.startRootSpan(withdrawTrace(withdraw.TxHash), SPAN_WITHDRAW_EVENT, time.Unix(int64(withdraw.TT), 0))
+	s.set(log.FIELD_CHAIN, "layer2").set(log.FIELD_HEIGHT, withdraw.Height).set(log.FIELD_TXHASH, withdraw.TxHash).
+		set("token", withdraw.TokenAddress).set("amount", withdraw.Amount).set("to", withdraw.ToAddress)
+	s.end(nil)
+}
+
+//traceCommitMsg trace the commit of the layer2 state of msg to L1 in every trace of its deposits and withdrawals. The
+//commit sent is traced again when it is confirmed, the commit not confirmed before exit is not
+func (this *Layer2Operator) traceCommitMsg(msg *Layer2CommitMsg, txHash string, start time.Time, err error) {
+	if this.config().TracingConfig == nil {
+		return
+	}
+	traces := make([]traceRef, 0, len(msg.Deposits) + len(msg.WithDraws))
+	for _, id := range msg.Deposits {
+		traces = append(traces, depositTrace(id))
+	}
+	for _, withdraw := range msg.WithDraws {
+		traces = append(traces, withdrawTrace(withdraw.TxHash))
+	}
+	for _, trace := range traces {
+		s := this.startSpan(trace, SPAN_STATE_COMMIT)
+		if s == nil {
+			return
+		}
+		s.start = start
+		s.set(log.FIELD_CHAIN, this.l1.Name()).set(log.FIELD_HEIGHT, msg.Layer2State.Height).set("from_height", msg.fromHeight())
+		if txHash != "" {
+			s.set(log.FIELD_TXHASH, txHash)
+		}
+		s.end(err)
+	}
+	if err != nil || txHash == "" || len(traces) == 0 {
+		return
+	}
+	this.tracer.mu.Lock()
+	this.tracer.commits[txHash] = &tracedCommit{height: msg.Layer2State.Height, traces: traces, sent: time.Now()}
+	this.tracer.mu.Unlock()
+}
+
+//traceCommitConfirm trace the confirmation of the commit sent in the traces of its deposits and withdrawals
+func (this *Layer2Operator) traceCommitConfirm(txHash string, err error) {
+	this.tracer.mu.Lock()
+	commit, ok := this.tracer.commits[txHash]
+	delete(this.tracer.commits, txHash)
+	this.tracer.mu.Unlock()
+	if !ok {
+		return
+	}
+	for _, trace := range commit.traces {
+		s := this.startSpan(trace, SPAN_COMMIT_CONFIRM)
+		if s == nil {
+			return
+		}
+		s.start = commit.sent
+		s.set(log.FIELD_CHAIN, this.l1.Name()).set(log.FIELD_HEIGHT, commit.height).set(log.FIELD_TXHASH, txHash)
+		s.end(err)
+	}
+}
+
+//traceLoop export the spans in batches every TRACE_EXPORT_INTERVAL. On shutdown, the spans of the work drained are
+//exported before exit
+func (this *Layer2Operator) traceLoop() {
+	log.Infof("start traceLoop")
+	batch := make([]*otlpSpan, 0, TRACE_BATCH_SIZE)
+	ticker := time.NewTicker(config.TRACE_EXPORT_INTERVAL)
+	defer ticker.Stop()
+	for true {
+		select {
+		case s := <- this.tracer.spans:
+			batch = append(batch, s)
+			if len(batch) < TRACE_BATCH_SIZE {
+				continue
+			}
+		case <- ticker.C:
+		case <- this.ctx.Done():
+			select {
+			case <- this.drained:
+			case <- this.drain.Done():
+			}
+			for len(this.tracer.spans) > 0 {
+				batch = append(batch, <- this.tracer.spans)
+			}
+			this.exportSpans(batch)
+			log.Infof("traceLoop exit")
+			return
+		}
+		this.exportSpans(batch)
+		batch = batch[:0]
+	}
+}
+
+//exportSpans send the spans to the collector of the current TracingConfig, the spans failed to export are dropped
+func (this *Layer2Operator) exportSpans(spans []*otlpSpan) {
+	tracingConfig := this.config().TracingConfig
+	if tracingConfig == nil || len(spans) == 0 {
+		return
+	}
+	for i := 0; i < len(spans); i += TRACE_BATCH_SIZE {
+		j := i + TRACE_BATCH_SIZE
+		if j > len(spans) {
+			j = len(spans)
+		}
+		err := postSpans(tracingConfig, spans[i:j])
+		if err != nil {
+			log.Errorf("trace - export %d spans to %s err: %v", j - i, tracingConfig.Endpoint, err)
+		}
+	}
+}
+
+//otlpSpan is the span of the OTLP json encoding, the ids are in hex and the times are in nanoseconds
+type otlpSpan struct {
+	TraceId           string `json:"traceId"`
+	SpanId            string `json:"spanId"`
+	ParentSpanId      string `json:"parentSpanId,omitempty"`
+	Name              string `json:"name"`
+	Kind              int `json:"kind"`
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string `json:"endTimeUnixNano"`
+	Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code       int `json:"code"`
+	Message    string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key        string `json:"key"`
+	Value      map[string]interface{} `json:"value"`
+}
+
+//otlpAttributes encode the attributes of the span, the integers are encoded as strings by OTLP json
+func otlpAttributes(attrs map[string]interface{}) []*otlpAttribute {
+	list := make([]*otlpAttribute, 0, len(attrs))
+	for key, value := range attrs {
+		var encoded map[string]interface{}
+		switch v := value.(type) {
+		case uint64:
+			encoded = map[string]interface{}{"intValue": strconv.FormatUint(v, 10)}
+		case uint32:
+			encoded = map[string]interface{}{"intValue": strconv.FormatUint(uint64(v), 10)}
+		case int:
+			encoded = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case bool:
+			encoded = map[string]interface{}{"boolValue": v}
+		default:
+			encoded = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		list = append(list, &otlpAttribute{Key: key, Value: encoded})
+	}
+	return list
+}
+
+//postSpans send the spans of the service to the OTLP/HTTP endpoint in json
+func postSpans(tracingConfig *config.TracingConfig, spans []*otlpSpan) error {
+	body := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": tracingConfig.ServiceNameOrDefault()}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": TRACE_SCOPE_NAME},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, tracingConfig.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range tracingConfig.Headers {
+		req.Header.Set(key, value)
+	}
+	client := &http.Client{Timeout: config.TRACE_REQUEST_TIMEOUT}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	layer2_common "github.com/ontio/layer2/node/common"
+	layer2_types "github.com/ontio/layer2/node/core/types"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/log"
+)
+
+//the fake deposits take the ids from TRAFFIC_DEPOSIT_ID_BASE, far above the ids of the deposits on L1, and below the
+//max of the INT id column of deposit, so the ids are kept by the database and the nonces of their mints never collide
+const (
+	TRAFFIC_DEPOSIT_ID_BASE uint64 = 2000000000
+	TRAFFIC_DEPOSIT_ID_MAX  uint64 = math.MaxInt32
+)
+
+//trafficLoop generate a round of the fake deposits every interval of TrafficConfig, and withdraw the tokens of the
+//round once its deposits are minted. The deposits are accepted as the deposit events parsed from L1, and the
+//withdrawals are parsed from layer2 as the others, so the traffic goes through the whole pipeline
+func (this *Layer2Operator) trafficLoop() {
+	log.Infof("start trafficLoop")
+	trafficConfig := this.config().TrafficConfig
+	var pending []*Deposit
+	rounds := 0
+	for true {
+		select {
+		case <- this.ctx.Done():
+			log.Infof("trafficLoop exit")
+			return
+		case <- time.After(trafficConfig.IntervalOrDefault()):
+		}
+		if !this.isLeading() {
+			continue
+		}
+		if len(pending) > 0 {
+			minted, done := this.trafficMinted(pending)
+			if !done {
+				continue
+			}
+			this.withdrawTraffic(trafficConfig, minted)
+			pending = nil
+			rounds ++
+		}
+		if trafficConfig.Rounds > 0 && rounds >= trafficConfig.Rounds {
+			log.Infof("traffic - %d rounds are generated, trafficLoop exit", rounds)
+			return
+		}
+		pending = this.depositTraffic(trafficConfig)
+	}
+}
+
+//trafficTokens return the tokens of the traffic, the tokens not enabled and the non-fungible tokens are skipped
+func (this *Layer2Operator) trafficTokens(trafficConfig *config.TrafficConfig) []*Token {
+	addresses := trafficConfig.Tokens
+	if len(addresses) == 0 {
+		addresses = []string{assets.ont, assets.ong}
+	}
+	tokens := make([]*Token, 0, len(addresses))
+	for _, address := range addresses {
+		token := this.tokens.get(address)
+		if token == nil || !token.Enabled || isNFTToken(token) {
+			log.Errorf("traffic - token %s is not an enabled fungible token", address)
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+//nextTrafficDepositID return the id after the last fake deposit saved
+func nextTrafficDepositID() (uint64, error) {
+	last, err := LoadMaxDepositID(TRAFFIC_DEPOSIT_ID_BASE)
+	if err != nil {
+		return 0, err
+	}
+	if last < TRAFFIC_DEPOSIT_ID_BASE {
+		return TRAFFIC_DEPOSIT_ID_BASE, nil
+	}
+	if last >= TRAFFIC_DEPOSIT_ID_MAX {
+		return 0, fmt.Errorf("the ids of the fake deposits are used up")
+	}
+	return last + 1, nil
+}
+
+//depositTraffic accept a fake deposit of every token to the layer2 account, the id of the deposit is the next one of
+//the range of the fake deposits
+func (this *Layer2Operator) depositTraffic(trafficConfig *config.TrafficConfig) []*Deposit {
+	deposits := make([]*Deposit, 0)
+	for _, token := range this.trafficTokens(trafficConfig) {
+		id, err := nextTrafficDepositID()
+		if err != nil {
+			log.Errorf("traffic - %v", err)
+			break
+		}
+		deposit := &Deposit{
+			TT:           uint32(time.Now().Unix()),
+			State:        DEPOSIT_EVENT,
+			FromAddress:  this.layer2Account.Address.ToBase58(),
+			Amount:       trafficConfig.DepositAmountOrDefault(),
+			TokenAddress: token.Address,
+			ID:           id,
+		}
+		deposit.TxHash = fmt.Sprintf("traffic-%d", deposit.ID)
+		err = this.acceptDeposit(deposit)
+		if err != nil {
+			log.Errorf("traffic - %v", err)
+			continue
+		}
+		depositLog(deposit).Infof("traffic - deposit %d of %d %s to %s", deposit.ID, deposit.Amount, token.Name, deposit.FromAddress)
+		deposits = append(deposits, deposit)
+	}
+	return deposits
+}
+
+//trafficMinted return the deposits credited on layer2 once none of them is waiting for the mint, the deposits failed
+//are not withdrawn
+func (this *Layer2Operator) trafficMinted(deposits []*Deposit) ([]*Deposit, bool) {
+	minted := make([]*Deposit, 0, len(deposits))
+	for _, deposit := range deposits {
+		saved := LoadDepositByID(deposit.ID)
+		if saved == nil {
+			continue
+		}
+		switch saved.State {
+		case DEPOSIT_EVENT, DEPOSIT_COMMIT:
+			return nil, false
+		case DEPOSIT_FINISH, DEPOSIT_NOTIFY:
+			minted = append(minted, saved)
+		default:
+			depositLog(saved).Errorf("traffic - deposit %d is not minted, state: %d", saved.ID, saved.State)
+		}
+	}
+	return minted, true
+}
+
+//withdrawTraffic withdraw the tokens of the minted deposits from the layer2 account
+func (this *Layer2Operator) withdrawTraffic(trafficConfig *config.TrafficConfig, deposits []*Deposit) {
+	amount := trafficConfig.WithdrawAmountOrDefault()
+	for _, deposit := range deposits {
+		token := this.tokens.get(deposit.TokenAddress)
+		if token == nil {
+			continue
+		}
+		tx, err := this.newWithdrawTransaction(token, amount)
+		if err == nil {
+			this.layer2Sdk().SetPayer(tx, this.layer2Account.Address)
+			err = this.layer2Sdk().SignToTransaction(tx, this.layer2Account)
+		}
+		if err != nil {
+			log.Errorf("traffic - build withdraw of %s err: %v", token.Name, err)
+			continue
+		}
+		hash, err := this.layer2Sdk().SendTransaction(tx)
+		if err != nil {
+			log.Errorf("traffic - withdraw %s err: %v", token.Name, err)
+			continue
+		}
+		log.Infof("traffic - withdraw %d %s from %s, hash: %s", amount, token.Name, this.layer2Account.Address.ToBase58(),
+			hash.ToHexString())
+	}
+}
+
+//newWithdrawTransaction build the transfer of amount of the fungible token from the layer2 account to the empty
+//address, which is parsed as a withdrawal. The fee is the gas price suggested by the latest blocks of the node
+func (this *Layer2Operator) newWithdrawTransaction(token *Token, amount uint64) (*layer2_types.MutableTransaction, error) {
+	from := this.layer2Account.Address
+	if isNativeToken(token.Address) {
+		fee, err := this.layer2Sdk().EstimateTransferFee()
+		if err != nil {
+			return nil, err
+		}
+		if isOntToken(token) {
+			return this.layer2Sdk().Native.Ont.NewTransferTransaction(fee.GasPrice, fee.GasLimit, from, assets.collector, amount)
+		}
+		return this.layer2Sdk().Native.Ong.NewTransferTransaction(fee.GasPrice, fee.GasLimit, from, assets.collector, amount)
+	}
+	data, _ := hex.DecodeString(token.Layer2Address)
+	contractAddress, err := layer2_common.AddressParseFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := this.layer2Sdk().EstimateOep4TransferFee(contractAddress, this.layer2Account, assets.collector,
+		new(big.Int).SetUint64(amount))
+	if err != nil {
+		return nil, err
+	}
+	gasLimit := fee.GasLimit
+	if this.config().Layer2Config.GasLimit > gasLimit {
+		gasLimit = this.config().Layer2Config.GasLimit
+	}
+	return this.layer2Sdk().NeoVM.NewNeoVMInvokeTransaction(fee.GasPrice, gasLimit, contractAddress,
+		[]interface{}{"transfer", []interface{}{from, assets.collector, amount}})
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+)
+
+func TestNextTrafficDepositID(t *testing.T) {
+	openTestDB(t)
+	// the deposits on L1 do not take the ids of the fake deposits
+	_, err := SaveDeposit(&Deposit{TxHash: "l1", ID: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < 3; i ++ {
+		id, err := nextTrafficDepositID()
+		if err != nil || id != TRAFFIC_DEPOSIT_ID_BASE+i {
+			t.Fatalf("next traffic deposit id = %d, %v, want %d", id, err, TRAFFIC_DEPOSIT_ID_BASE+i)
+		}
+		_, err = SaveDeposit(&Deposit{TxHash: "traffic", NotifyIndex: uint32(id), ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := LoadDepositByID(id)
+		if saved == nil || saved.ID != id {
+			t.Fatalf("fake deposit %d is not saved", id)
+		}
+	}
+
+	_, err = SaveDeposit(&Deposit{TxHash: "traffic-max", ID: TRAFFIC_DEPOSIT_ID_MAX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = nextTrafficDepositID()
+	if err == nil {
+		t.Fatalf("the ids above the INT column are allocated")
+	}
+}
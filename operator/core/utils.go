@@ -25,19 +25,17 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/ontio/layer2/go-sdk/common"
+	"github.com/ontio/layer2/operator/log"
 )
 
 const (
 	NOTIFY_TRANSFER = "transfer"
+)
 
-	ONT_CONTRACT_ADDRESS               = "0000000000000000000000000000000000000001"
-	ONT_REV_CONTRACT_ADDRESS               = "0100000000000000000000000000000000000000"
-	ONT_CONTRACT_ADDRESS_BASE58        = "AFmseVrdL9f9oyCzZefL9tG6UbvhUMqNMV"
-	ONG_CONTRACT_ADDRESS               = "0000000000000000000000000000000000000002"
-	ONG_REV_CONTRACT_ADDRESS               = "0200000000000000000000000000000000000000"
-	ONG_CONTRACT_ADDRESS_BASE58        = "AFmseVrdL9f9oyCzZefL9tG6UbvhfRZMHJ"
-	GOVERNANCE_CONTRACT_ADDRESS        = "0700000000000000000000000000000000000000"
-	GOVERNANCE_CONTRACT_ADDRESS_BASE58 = "AFmseVrdL9f9oyCzZefL9tG6UbviEH9ugK"
+//the standard of the non-fungible token in the registry, the standard of the fungible token is empty
+const (
+	TOKEN_OEP5 = "OEP5"
+	TOKEN_OEP8 = "OEP8"
 )
 
 const (
@@ -46,6 +44,10 @@ const (
 	DEPOSIT_FINISH
 	DEPOSIT_NOTIFY
 	DEPOSIT_FAILED
+	DEPOSIT_QUARANTINE
+	DEPOSIT_REORGED
+	DEPOSIT_REJECTED
+	DEPOSIT_REFUNDED
 )
 
 const (
@@ -59,6 +61,35 @@ const (
 	LAYER2MSG_FAILED
 )
 
+//the exit is requested on L1, then the account is frozen on layer2, then its balance in the state root committed after
+//the freeze is checked against the amount of the exit
+const (
+	EXIT_EVENT = iota
+	EXIT_FROZEN
+	EXIT_CHECKED
+)
+
+const (
+	JOB_DEPOSIT = iota
+	JOB_COMMIT
+)
+
+const (
+	JOB_PENDING = iota
+	JOB_RUNNING
+	JOB_DONE
+	JOB_DEAD
+	JOB_HELD
+)
+
+//the new key of the operator is registered on L1 and the L1 transactions are signed by both keys, then the new keys
+//are switched to the L1 operator and the layer2 payer, or the rotation is cancelled before the switch
+const (
+	KEY_ROTATION_REGISTERED = iota
+	KEY_ROTATION_SWITCHED
+	KEY_ROTATION_CANCELLED
+)
+
 type ChainInfo struct {
 	Name        string
 	Id          uint32
@@ -66,25 +97,129 @@ type ChainInfo struct {
 	Height      uint32
 }
 
+//Deposit is identified by ID of the bridge contract, and the event of it by TxHash and NotifyIndex
 type Deposit struct {
 	TxHash          string
+	NotifyIndex     uint32
 	TT              uint32
 	State           int
 	Height          uint32
 	FromAddress     string
 	Amount          uint64
 	TokenAddress    string
+	TokenId         string
 	ID              uint64
 	Layer2TxHash    string
+	// the L1 transaction refunding the deposit whose mint failed
+	RefundTxHash    string
+}
+
+//depositLog return the log entry of the deposit, which is filtered by its id and its L1 transaction
+func depositLog(deposit *Deposit) *log.Entry {
+	return log.WithFields(log.Fields{log.FIELD_DEPOSIT_ID: deposit.ID, log.FIELD_TXHASH: deposit.TxHash})
+}
+
+//chainLog return the log entry of the parser of the chain at its parse height
+func chainLog(chain *ChainInfo) *log.Entry {
+	return log.WithFields(log.Fields{log.FIELD_CHAIN: chain.Name, log.FIELD_HEIGHT: chain.Height})
+}
+
+//commitLog return the log entry of the commit of the layer2 height to the L1 chain, the height or the transaction is
+//omitted if it is unknown
+func commitLog(chain string, height uint32, txHash string) *log.Entry {
+	fields := log.Fields{log.FIELD_CHAIN: chain}
+	if height > 0 {
+		fields[log.FIELD_HEIGHT] = height
+	}
+	if txHash != "" {
+		fields[log.FIELD_TXHASH] = txHash
+	}
+	return log.WithFields(fields)
 }
 
 func (this *Deposit) Dump() string {
 	dumpStr := ""
-	dumpStr += fmt.Sprintf("Deposit: TxHash: %s, TT: %d, State: %d, Height: %d, FromAddress: %s, Amount: %d, TokenAddress: %s",
-		this.TxHash, this.TT, this.State, this.Height, this.FromAddress, this.Amount, this.TokenAddress)
+	dumpStr += fmt.Sprintf("Deposit: TxHash: %s, TT: %d, State: %d, Height: %d, FromAddress: %s, Amount: %d, TokenAddress: %s, TokenId: %s",
+		this.TxHash, this.TT, this.State, this.Height, this.FromAddress, this.Amount, this.TokenAddress, this.TokenId)
 	return dumpStr
 }
 
+//sameEvent return whether the deposit is of the event of other, the job of the deposit saved of another event is stale
+func (this *Deposit) sameEvent(other *Deposit) bool {
+	return this.TxHash == other.TxHash && this.NotifyIndex == other.NotifyIndex
+}
+
+//DepositQuarantine is the mint transaction on layer2 which mismatches its deposit, ID and TxHash are empty if the
+//deposit is not found
+type DepositQuarantine struct {
+	Layer2TxHash    string
+	TT              uint32
+	Height          uint32
+	ID              uint64
+	TxHash          string
+	Reason          string
+}
+
+//Exit is the forced exit of the balance of Player in the layer2 state root of Layer2Height, requested on L1
+type Exit struct {
+	ID              uint64
+	TxHash          string
+	TT              uint32
+	State           int
+	Height          uint32
+	Player          string
+	Amount          uint64
+	TokenAddress    string
+	Layer2Height    uint32
+	Layer2TxHash    string
+}
+
+func (this *Exit) Dump() string {
+	dumpStr := ""
+	dumpStr += fmt.Sprintf("Exit: ID: %d, TxHash: %s, TT: %d, State: %d, Height: %d, Player: %s, Amount: %d, TokenAddress: %s, Layer2Height: %d",
+		this.ID, this.TxHash, this.TT, this.State, this.Height, this.Player, this.Amount, this.TokenAddress, this.Layer2Height)
+	return dumpStr
+}
+
+//WithdrawFee is the fee deducted from the withdraw of TxHash on layer2 when it is committed to L1 at Height, Amount is
+//the amount withdrawn on layer2
+type WithdrawFee struct {
+	TxHash          string
+	TokenAddress    string
+	Amount          uint64
+	Fee             uint64
+	Height          uint32
+}
+
+//WithdrawFeeTotal is the fees accumulated by the withdraws of the token
+type WithdrawFeeTotal struct {
+	TokenAddress    string
+	Fee             uint64
+	Count           uint64
+}
+
+//Layer2Commit is the updateState transaction of the layer2 state of Layer2Height, Height is the L1 height it is
+//executed at
+type Layer2Commit struct {
+	TxHash          string
+	State           int
+	TT              uint32
+	Height          uint32
+	Layer2Height    uint64
+	GasPrice        uint64
+	Fee             uint64
+	// the commit msg of the transaction, it is only loaded by LoadLayer2CommitsByHeight
+	Layer2Msg       string
+}
+
+//Layer2CommitCost is the L1 fees paid by the commit transactions of the state
+type Layer2CommitCost struct {
+	State           int
+	Count           uint64
+	Fee             uint64
+	MaxGasPrice     uint64
+}
+
 type Withdraw struct {
 	TxHash          string
 	TT              uint32
@@ -93,13 +228,14 @@ type Withdraw struct {
 	ToAddress       string
 	Amount          uint64
 	TokenAddress    string
+	TokenId         string
 	OntologyTxHash  string
 }
 
 func (this *Withdraw) Dump() string {
 	dumpStr := ""
-	dumpStr += fmt.Sprintf("Withdraw: TxHash: %s, TT: %d, State: %d, Height: %d, ToAddress: %s, Amount: %d, TokenAddress: %s",
-		this.TxHash, this.TT, this.State, this.Height, this.ToAddress, this.Amount, this.TokenAddress)
+	dumpStr += fmt.Sprintf("Withdraw: TxHash: %s, TT: %d, State: %d, Height: %d, ToAddress: %s, Amount: %d, TokenAddress: %s, TokenId: %s",
+		this.TxHash, this.TT, this.State, this.Height, this.ToAddress, this.Amount, this.TokenAddress, this.TokenId)
 	return dumpStr
 }
 
@@ -111,21 +247,99 @@ type Layer2Tx struct {
 	Height           uint32
 	FromAddress      string
 	TokenAddress     string
+	TokenId          string
 	ToAddress        string
 	Amount           uint64
 }
 
 func (this *Layer2Tx) Dump() string {
 	dumpStr := ""
-	dumpStr += fmt.Sprintf("Layer2Tx: TxHash: %s, TT: %d, State: %d, Fee: %d, Height: %d, FromAddress: %s, ToAddress: %s, Amount: %d, TokenAddress: %s",
-		this.TxHash, this.TT, this.State, this.Fee, this.Height, this.FromAddress, this.ToAddress, this.Amount, this.TokenAddress)
+	dumpStr += fmt.Sprintf("Layer2Tx: TxHash: %s, TT: %d, State: %d, Fee: %d, Height: %d, FromAddress: %s, ToAddress: %s, Amount: %d, TokenAddress: %s, TokenId: %s",
+		this.TxHash, this.TT, this.State, this.Fee, this.Height, this.FromAddress, this.ToAddress, this.Amount, this.TokenAddress, this.TokenId)
 	return dumpStr
 }
 
+//Token is the entry of the token registry, the ontology asset of Address is minted as the layer2 asset of
+//Layer2Address, and the deposits of the token not enabled are rejected
+type Token struct {
+	Name            string
+	Address         string
+	Layer2Address   string
+	Decimals        uint32
+	Enabled         bool
+	Standard        string
+}
+
+//Job is the persistent work item of depositLoop or commitMsgLoop, Key is the deposit id or the layer2 height and
+//Payload is the json of the deposit or the layer2 commit msg
+type Job struct {
+	ID              uint64
+	Kind            int
+	Key             uint64
+	State           int
+	Payload         string
+}
+
+//AuditEntry is an external action of the operator in the append-only audit log, Initiator is the operator, the cli, the
+//signal or the admin api with the remote address. TxHash is the transaction sent by the action, empty if it sends none
+type AuditEntry struct {
+	ID              uint64
+	Action          string
+	Initiator       string
+	Detail          string
+	TxHash          string
+	TT              uint32
+}
+
+//DryRunAction is the transaction the operator would send in the dry run, Kind is mint or commit, Subject is the
+//deposit ids or the layer2 height, and Detail is the action in json
+type DryRunAction struct {
+	ID              uint64
+	Kind            string
+	Subject         string
+	Detail          string
+	TT              uint32
+}
+
+//DeadLetter is the job failed Attempts times, Error is the error of the last attempt
+type DeadLetter struct {
+	ID              uint64
+	Kind            int
+	Key             uint64
+	Payload         string
+	Error           string
+	Attempts        int
+	TT              uint32
+}
+
+//KeyRotation is the rotation of the operator keys from the ontology and layer2 accounts to the next ones,
+//TransitionPeriod is the L1 blocks before the new key can switch
+type KeyRotation struct {
+	ID                  uint64
+	State               int
+	OntologyAddress     string
+	NextOntologyAddress string
+	Layer2Address       string
+	NextLayer2Address   string
+	TransitionPeriod    uint64
+	RegisterTxHash      string
+	SwitchTxHash        string
+	TT                  uint32
+}
+
 type Layer2CommitMsg struct {
 	Layer2State       *common.Layer2State
 	Deposits          []uint64
 	WithDraws         []*Withdraw
+	FromHeight        uint32 `json:",omitempty"`
+}
+
+//fromHeight return the first layer2 height of the msg, which is not the height of the state if merged
+func (this *Layer2CommitMsg) fromHeight() uint32 {
+	if this.FromHeight != 0 {
+		return this.FromHeight
+	}
+	return this.Layer2State.Height
 }
 
 func (this *Layer2CommitMsg) Dump() string {
@@ -0,0 +1,68 @@
+-- the tables of docs/explorer.sql for PostgreSQL, create the database layer2 and run this script in it. The later
+-- changes of the tables are applied by the schema migrations of the operator at startup
+
+DROP TABLE IF EXISTS chain_info;
+CREATE TABLE chain_info (
+ name VARCHAR(100) NOT NULL,
+ id INTEGER NOT NULL,
+ url VARCHAR(256) NOT NULL,
+ height INTEGER NOT NULL,
+ PRIMARY KEY (id)
+);
+
+INSERT INTO chain_info(name,id,url,height) VALUES('ontology',1,'http://138.91.6.125:20336',0);
+INSERT INTO chain_info(name,id,url,height) VALUES('layer2',2,'http://47.90.189.186:40332',0);
+
+DROP TABLE IF EXISTS deposit;
+CREATE TABLE deposit (
+ txhash VARCHAR(256) NOT NULL,
+ tt INTEGER NOT NULL,
+ state INTEGER NOT NULL,
+ height INTEGER NOT NULL,
+ fromaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ id INTEGER NOT NULL,
+ layer2txhash VARCHAR(256) DEFAULT NULL,
+ PRIMARY KEY (id),
+ UNIQUE (txhash)
+);
+
+DROP TABLE IF EXISTS withdraw;
+CREATE TABLE withdraw (
+ txhash VARCHAR(256) NOT NULL,
+ tt INTEGER NOT NULL,
+ state INTEGER NOT NULL,
+ height INTEGER NOT NULL,
+ toaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ ontologytxhash VARCHAR(256) DEFAULT NULL,
+ PRIMARY KEY (txhash)
+);
+
+DROP TABLE IF EXISTS layer2tx;
+CREATE TABLE layer2tx (
+ txhash VARCHAR(256) NOT NULL,
+ state INTEGER NOT NULL,
+ tt INTEGER NOT NULL,
+ fee BIGINT NOT NULL,
+ height INTEGER NOT NULL,
+ fromaddress VARCHAR(256) NOT NULL,
+ tokenaddress VARCHAR(256) NOT NULL,
+ toaddress VARCHAR(256) NOT NULL,
+ amount BIGINT NOT NULL,
+ PRIMARY KEY (txhash)
+);
+
+DROP TABLE IF EXISTS layer2commit;
+CREATE TABLE layer2commit (
+ txhash VARCHAR(256) NOT NULL,
+ state INTEGER DEFAULT 0,
+ tt INTEGER DEFAULT 0,
+ fee BIGINT DEFAULT 0,
+ ontologyheight INTEGER DEFAULT 0,
+ layer2height INTEGER DEFAULT 0,
+ layer2msg VARCHAR(1024) NOT NULL,
+ PRIMARY KEY (txhash)
+);
@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ontio/layer2/operator/config"
+)
+
+const (
+	DEFAULT_E2E_CONFIG_FILE = "./e2e/e2e.json"
+	DEFAULT_WORK_DIR        = "./e2e-work"
+	DEFAULT_SCENARIO_DIR    = "./e2e/scenarios"
+	DEFAULT_LAYER2_RPC_PORT = 40336
+	DEFAULT_SOLO_RPC_PORT   = 20336
+)
+
+// Config is the environment of the scenarios
+type Config struct {
+	// the e2e-only operator config which the chain urls are replaced by the environment, it is required as its
+	// database is cleared before every scenario
+	OperatorConfig string
+	// clear the database even if its name is not marked as a test database by "test" or "e2e"
+	ResetDB bool
+	// the operator binary, the running binary is used if empty
+	OperatorBin string
+	// the directory of the chain data and the logs, cleared before every scenario
+	WorkDir   string
+	Scenarios string
+	LogLevel  uint
+	L1        *L1Config
+	Layer2    *NodeConfig
+}
+
+type L1Config struct {
+	// mock or solo
+	Driver string
+	// mock: the listen address of the rpc server
+	ListenAddr string
+	// mock: the block time in seconds
+	BlockTime uint32
+	// the hex address of the bridge contract, solo: overridden by the deployed contract if ContractCode is set
+	ContractAddress string
+	// solo: the avm code file of the bridge contract deployed on startup
+	ContractCode string
+	// solo: the ontology node, the wallet must be the bookkeeper which holds the ONT and ONG
+	Node *NodeConfig
+}
+
+// NodeConfig is a dev-mode node started for the scenario
+type NodeConfig struct {
+	Bin        string
+	Args       []string
+	RpcPort    uint
+	WalletFile string
+	WalletPwd  string
+	BlockTime  uint32
+}
+
+func NewConfig(configFilePath string) (*Config, error) {
+	fileContent, err := config.ReadFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	err = json.Unmarshal(fileContent, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("NewConfig: unmarshal %s error %s", configFilePath, err)
+	}
+	if cfg.OperatorConfig == "" {
+		return nil, fmt.Errorf("NewConfig: operator config of the e2e environment is not set")
+	}
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = DEFAULT_WORK_DIR
+	}
+	if cfg.Scenarios == "" {
+		cfg.Scenarios = DEFAULT_SCENARIO_DIR
+	}
+	if cfg.L1 == nil {
+		cfg.L1 = &L1Config{Driver: L1_DRIVER_MOCK}
+	}
+	if cfg.Layer2 == nil || cfg.Layer2.Bin == "" {
+		return nil, fmt.Errorf("NewConfig: layer2 node binary is not set")
+	}
+	if cfg.Layer2.RpcPort == 0 {
+		cfg.Layer2.RpcPort = DEFAULT_LAYER2_RPC_PORT
+	}
+	if cfg.L1.Driver == L1_DRIVER_SOLO {
+		if cfg.L1.Node == nil || cfg.L1.Node.Bin == "" {
+			return nil, fmt.Errorf("NewConfig: ontology node binary is not set")
+		}
+		if cfg.L1.Node.RpcPort == 0 {
+			cfg.L1.Node.RpcPort = DEFAULT_SOLO_RPC_PORT
+		}
+	}
+	return cfg, nil
+}
+
+// args return the command line of the dev-mode node
+func (this *NodeConfig) args(dataDir string) []string {
+	args := []string{
+		"--testmode",
+		"--data-dir", dataDir,
+		"--rpcport", fmt.Sprintf("%d", this.RpcPort),
+		"--disable-log-file",
+	}
+	if this.BlockTime != 0 {
+		args = append(args, "--testmode-gen-block-time", fmt.Sprintf("%d", this.BlockTime))
+	}
+	if this.WalletFile != "" {
+		args = append(args, "--wallet", this.WalletFile, "--password", this.WalletPwd)
+	}
+	return append(args, this.Args...)
+}
+
+func (this *NodeConfig) url() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", this.RpcPort)
+}
+
+//isTestDB return true if the name of the database, or of the database file, is marked as a test database
+func isTestDB(cfg *config.DBConfig) bool {
+	if cfg == nil {
+		return false
+	}
+	name := strings.ToLower(filepath.Base(cfg.ProjectDBName))
+	return strings.Contains(name, "test") || strings.Contains(name, "e2e")
+}
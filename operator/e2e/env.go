@@ -0,0 +1,155 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/core"
+	"github.com/ontio/layer2/operator/log"
+)
+
+// Env is the L1, the dev-mode layer2 node and the operator started for one scenario
+type Env struct {
+	cfg        *Config
+	workDir    string
+	L1         L1Driver
+	Layer2Sdk  *layer2_sdk.OntologySdk
+	layer2Node *process
+	operator   *process
+}
+
+func NewEnv(cfg *Config, name string) *Env {
+	return &Env{
+		cfg:     cfg,
+		workDir: filepath.Join(cfg.WorkDir, name),
+	}
+}
+
+func (this *Env) Start() error {
+	err := os.RemoveAll(this.workDir)
+	if err != nil {
+		return fmt.Errorf("clear work dir error: %s", err)
+	}
+	err = os.MkdirAll(this.workDir, 0755)
+	if err != nil {
+		return fmt.Errorf("create work dir error: %s", err)
+	}
+	servCfg := config.NewServiceConfig(this.cfg.OperatorConfig)
+	if servCfg == nil {
+		return fmt.Errorf("load operator config %s failed", this.cfg.OperatorConfig)
+	}
+	// the tables are cleared, never the database of a running bridge
+	if !this.cfg.ResetDB && !isTestDB(servCfg.DBConfig) {
+		return fmt.Errorf("database %s of %s is not a test database, its name must contain test or e2e, or set ResetDB",
+			servCfg.DBConfig.ProjectDBName, this.cfg.OperatorConfig)
+	}
+	err = core.ConnectDB(servCfg.DBConfig)
+	if err != nil {
+		return fmt.Errorf("connect db error: %s", err)
+	}
+	// the tables created by the migrations are cleared too
+	err = core.Migrate()
+	if err != nil {
+		return fmt.Errorf("migrate db error: %s", err)
+	}
+	err = core.ResetProjectDB()
+	if err != nil {
+		return fmt.Errorf("reset db error: %s", err)
+	}
+
+	this.L1, err = NewL1Driver(this.cfg.L1, this.workDir)
+	if err != nil {
+		return err
+	}
+	err = this.L1.Start()
+	if err != nil {
+		return fmt.Errorf("start L1 error: %s", err)
+	}
+
+	nodeCfg := this.cfg.Layer2
+	this.layer2Node, err = startProcess("layer2 node", filepath.Join(this.workDir, "layer2.log"),
+		nodeCfg.Bin, nodeCfg.args(filepath.Join(this.workDir, "layer2"))...)
+	if err != nil {
+		return err
+	}
+	this.Layer2Sdk = layer2_sdk.NewOntologySdk()
+	this.Layer2Sdk.NewRpcClient().SetAddress(nodeCfg.url())
+	err = this.layer2Node.waitReady(func() error {
+		_, err := this.Layer2Sdk.GetCurrentBlockHeight()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	servCfg.OntologyConfig.RestURL = this.L1.Url()
+	servCfg.OntologyConfig.Layer2ContractAddress = this.L1.ContractAddress()
+	servCfg.Layer2Config.RestURL = nodeCfg.url()
+	// the spec has been applied already, the child operator must keep the endpoints above
+	servCfg.ChainSpec = ""
+	return this.startOperator(servCfg)
+}
+
+func (this *Env) startOperator(servCfg *config.ServiceConfig) error {
+	data, err := json.MarshalIndent(servCfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(this.workDir, "operator.json")
+	err = ioutil.WriteFile(configPath, data, 0644)
+	if err != nil {
+		return fmt.Errorf("write operator config error: %s", err)
+	}
+	bin := this.cfg.OperatorBin
+	if bin == "" {
+		bin, err = os.Executable()
+		if err != nil {
+			return err
+		}
+	}
+	this.operator, err = startProcess("operator", filepath.Join(this.workDir, "operator.log"), bin,
+		"--cliconfig", configPath, "--loglevel", fmt.Sprintf("%d", this.cfg.LogLevel))
+	if err != nil {
+		return err
+	}
+	// the parse height is saved after the first L1 block is parsed
+	return this.operator.waitReady(func() error {
+		chain := core.LoadChainInfo("ontology")
+		if chain == nil || chain.Height == 0 {
+			return fmt.Errorf("operator has not parsed L1 block")
+		}
+		return nil
+	})
+}
+
+func (this *Env) Stop() {
+	this.operator.stop()
+	this.layer2Node.stop()
+	if this.L1 != nil {
+		this.L1.Stop()
+	}
+	core.CloseDB()
+	log.Infof("environment stopped, logs are kept in %s", this.workDir)
+}
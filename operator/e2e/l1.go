@@ -0,0 +1,58 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"fmt"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+)
+
+const (
+	L1_DRIVER_MOCK = "mock"
+	L1_DRIVER_SOLO = "solo"
+)
+
+// L1Driver is the ontology chain the operator is connected to during a scenario
+type L1Driver interface {
+	Start() error
+	Stop()
+	// the rpc address given to the operator
+	Url() string
+	// the hex address of the bridge contract given to the operator
+	ContractAddress() string
+	// Deposit lock the amount of token of the player in the bridge contract, return the deposit id
+	Deposit(player *layer2_sdk.Account, token string, amount uint64) (uint64, error)
+	// FailCommits make the following count of layer2 state commits fail on L1
+	FailCommits(count int) error
+	// Reorg roll back the latest depth blocks
+	Reorg(depth uint32, requeue bool) error
+	// HasStateRoot return whether the state root of the layer2 height is accepted by the bridge contract
+	HasStateRoot(height uint64) (bool, error)
+}
+
+// NewL1Driver create the L1 of the config, the data and logs of the driver are put into workDir
+func NewL1Driver(cfg *L1Config, workDir string) (L1Driver, error) {
+	switch cfg.Driver {
+	case L1_DRIVER_MOCK, "":
+		return NewMockL1(cfg), nil
+	case L1_DRIVER_SOLO:
+		return NewSoloL1(cfg, workDir), nil
+	}
+	return nil, fmt.Errorf("unknown L1 driver %s", cfg.Driver)
+}
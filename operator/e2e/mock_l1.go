@@ -0,0 +1,482 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/log"
+	ontology_sdk_client "github.com/ontio/ontology-go-sdk/client"
+	ontology_sdk_common "github.com/ontio/ontology-go-sdk/common"
+	ontology_common "github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/types"
+)
+
+const (
+	DEFAULT_MOCK_L1_ADDR     = "127.0.0.1:20336"
+	DEFAULT_MOCK_L1_CONTRACT = "4229a92d90d446d1598e12e35698b681ae4d4642"
+	DEFAULT_L1_BLOCK_TIME    = 1
+
+	MOCK_PRE_EXEC_GAS = 20000
+
+	// the error codes of the ontology rpc server
+	RPC_ERR_INVALID_PARAMS = 42002
+	RPC_ERR_INVALID_METHOD = 42001
+	RPC_ERR_UNKNOWN_TX     = 44001
+	RPC_ERR_UNKNOWN_BLOCK  = 44003
+	RPC_ERR_INTERNAL       = 45001
+)
+
+type mockTx struct {
+	hash   string
+	method string
+	// deposit
+	depositId uint64
+	player    ontology_common.Address
+	token     string
+	amount    uint64
+	// updateState
+	stateRoot *bridge.StateRoot
+	// filled when the tx is packed into a block
+	event *ontology_sdk_common.SmartContactEvent
+}
+
+type mockBlock struct {
+	block *types.Block
+	txs   []*mockTx
+}
+
+// MockL1 is an in-process ontology chain serving the rpc api used by the operator, the bridge
+// contract is emulated so that commit failures and reorgs can be injected
+type MockL1 struct {
+	cfg      *L1Config
+	server   *http.Server
+	listener net.Listener
+	exitChan chan struct{}
+
+	mu            sync.Mutex
+	blocks        []*mockBlock
+	txHeights     map[string]uint32
+	pending       []*mockTx
+	stateRoots    map[uint64]*bridge.StateRoot
+	currentHeight uint64
+	nextDepositId uint64
+	failCommits   int
+}
+
+func NewMockL1(cfg *L1Config) *MockL1 {
+	return &MockL1{
+		cfg:        cfg,
+		exitChan:   make(chan struct{}),
+		txHeights:  make(map[string]uint32),
+		stateRoots: make(map[uint64]*bridge.StateRoot),
+	}
+}
+
+func (this *MockL1) Start() error {
+	addr := this.cfg.ListenAddr
+	if addr == "" {
+		addr = DEFAULT_MOCK_L1_ADDR
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("mock L1 listen %s error: %s", addr, err)
+	}
+	this.listener = listener
+	this.mine()
+	this.server = &http.Server{Handler: http.HandlerFunc(this.handle)}
+	go this.server.Serve(listener)
+	go this.mineLoop()
+	log.Infof("mock L1 started at %s", this.Url())
+	return nil
+}
+
+func (this *MockL1) Stop() {
+	close(this.exitChan)
+	if this.server != nil {
+		this.server.Close()
+	}
+}
+
+func (this *MockL1) Url() string {
+	return "http://" + this.listener.Addr().String()
+}
+
+func (this *MockL1) ContractAddress() string {
+	if this.cfg.ContractAddress != "" {
+		return this.cfg.ContractAddress
+	}
+	return DEFAULT_MOCK_L1_CONTRACT
+}
+
+func (this *MockL1) Deposit(player *layer2_sdk.Account, token string, amount uint64) (uint64, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.nextDepositId++
+	tx := &mockTx{
+		method:    bridge.METHOD_DEPOSIT,
+		depositId: this.nextDepositId,
+		token:     token,
+		amount:    amount,
+	}
+	copy(tx.player[:], player.Address[:])
+	tx.hash = this.newTxHash([]byte(fmt.Sprintf("deposit-%d-%d", tx.depositId, time.Now().UnixNano())))
+	this.pending = append(this.pending, tx)
+	return tx.depositId, nil
+}
+
+func (this *MockL1) FailCommits(count int) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.failCommits += count
+	return nil
+}
+
+func (this *MockL1) Reorg(depth uint32, requeue bool) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	// the genesis block is never rolled back
+	if int(depth) >= len(this.blocks) {
+		return fmt.Errorf("reorg depth %d exceeds current height %d", depth, len(this.blocks)-1)
+	}
+	orphans := make([]*mockTx, 0)
+	for _, block := range this.blocks[len(this.blocks)-int(depth):] {
+		for _, tx := range block.txs {
+			delete(this.txHeights, tx.hash)
+			if tx.stateRoot != nil && tx.event.State == 1 {
+				delete(this.stateRoots, tx.stateRoot.Height)
+			}
+			tx.event = nil
+			orphans = append(orphans, tx)
+		}
+	}
+	this.blocks = this.blocks[:len(this.blocks)-int(depth)]
+	this.currentHeight = 0
+	for height := range this.stateRoots {
+		if height > this.currentHeight {
+			this.currentHeight = height
+		}
+	}
+	if requeue {
+		this.pending = append(orphans, this.pending...)
+	}
+	log.Infof("mock L1 reorg %d blocks, %d transactions orphaned, current height %d", depth, len(orphans), len(this.blocks)-1)
+	return nil
+}
+
+func (this *MockL1) HasStateRoot(height uint64) (bool, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	_, ok := this.stateRoots[height]
+	return ok, nil
+}
+
+func (this *MockL1) newTxHash(data []byte) string {
+	hash := ontology_common.Uint256(sha256.Sum256(data))
+	return hash.ToHexString()
+}
+
+func (this *MockL1) mineLoop() {
+	blockTime := this.cfg.BlockTime
+	if blockTime == 0 {
+		blockTime = DEFAULT_L1_BLOCK_TIME
+	}
+	ticker := time.NewTicker(time.Duration(blockTime) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			this.mu.Lock()
+			this.mine()
+			this.mu.Unlock()
+		case <-this.exitChan:
+			return
+		}
+	}
+}
+
+// mine pack all the pending txs into a new block, the caller must hold the lock unless starting
+func (this *MockL1) mine() {
+	height := uint32(len(this.blocks))
+	header := &types.Header{
+		Timestamp: uint32(time.Now().Unix()),
+		Height:    height,
+	}
+	if height > 0 {
+		header.PrevBlockHash = this.blocks[height-1].block.Hash()
+	}
+	// make the hash of the blocks at the same height different after reorg
+	header.ConsensusData = uint64(time.Now().UnixNano())
+	block := &mockBlock{
+		block: &types.Block{Header: header, Transactions: []*types.Transaction{}},
+		txs:   this.pending,
+	}
+	this.pending = make([]*mockTx, 0)
+	for _, tx := range block.txs {
+		tx.event = this.execute(tx, height)
+		this.txHeights[tx.hash] = height
+	}
+	this.blocks = append(this.blocks, block)
+}
+
+// execute emulate the bridge contract
+func (this *MockL1) execute(tx *mockTx, height uint32) *ontology_sdk_common.SmartContactEvent {
+	event := &ontology_sdk_common.SmartContactEvent{
+		TxHash:      tx.hash,
+		State:       1,
+		GasConsumed: MOCK_PRE_EXEC_GAS,
+		Notify:      make([]*ontology_sdk_common.NotifyEventInfo, 0),
+	}
+	switch tx.method {
+	case bridge.METHOD_DEPOSIT:
+		event.Notify = append(event.Notify, &ontology_sdk_common.NotifyEventInfo{
+			ContractAddress: this.ContractAddress(),
+			States: []interface{}{
+				hex.EncodeToString([]byte(bridge.METHOD_DEPOSIT)),
+				neoIntHex(tx.depositId),
+				hex.EncodeToString(tx.player[:]),
+				neoIntHex(tx.amount),
+				neoIntHex(uint64(height)),
+				neoIntHex(0),
+				tx.token,
+			},
+		})
+	case bridge.METHOD_UPDATE_STATE:
+		// the contract only accepts a state above the current height, which may skip the heights aggregated
+		if this.failCommits > 0 || tx.stateRoot.Height <= this.currentHeight {
+			if this.failCommits > 0 {
+				this.failCommits--
+			}
+			event.State = 0
+			log.Infof("mock L1 reject state root of layer2 height %d", tx.stateRoot.Height)
+			break
+		}
+		this.stateRoots[tx.stateRoot.Height] = tx.stateRoot
+		this.currentHeight = tx.stateRoot.Height
+		event.Notify = append(event.Notify, &ontology_sdk_common.NotifyEventInfo{
+			ContractAddress: this.ContractAddress(),
+			States: []interface{}{
+				hex.EncodeToString([]byte(bridge.METHOD_UPDATE_STATE)),
+				hex.EncodeToString([]byte(tx.stateRoot.StateRootHash)),
+				neoIntHex(tx.stateRoot.Height),
+			},
+		})
+	}
+	return event
+}
+
+func neoIntHex(value uint64) string {
+	return hex.EncodeToString(ontology_common.BigIntToNeoBytes(new(big.Int).SetUint64(value)))
+}
+
+func (this *MockL1) handle(w http.ResponseWriter, r *http.Request) {
+	resp := &ontology_sdk_client.JsonRpcResponse{}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		resp.Error, resp.Desc = RPC_ERR_INTERNAL, err.Error()
+		writeRpcResponse(w, resp)
+		return
+	}
+	req := &ontology_sdk_client.JsonRpcRequest{}
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		resp.Error, resp.Desc = RPC_ERR_INVALID_PARAMS, err.Error()
+		writeRpcResponse(w, resp)
+		return
+	}
+	resp.Id = req.Id
+
+	this.mu.Lock()
+	result, code, err := this.call(req.Method, req.Params)
+	this.mu.Unlock()
+	if err != nil {
+		resp.Error, resp.Desc = code, err.Error()
+	} else {
+		resp.Result, err = json.Marshal(result)
+		if err != nil {
+			resp.Error, resp.Desc = RPC_ERR_INTERNAL, err.Error()
+		}
+	}
+	writeRpcResponse(w, resp)
+}
+
+func writeRpcResponse(w http.ResponseWriter, resp *ontology_sdk_client.JsonRpcResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (this *MockL1) call(method string, params []interface{}) (interface{}, int64, error) {
+	switch method {
+	case "getblockcount":
+		return len(this.blocks), 0, nil
+	case "getblock":
+		height, ok := heightParam(params)
+		if !ok || len(params) != 1 {
+			return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("getblock only support height")
+		}
+		if int(height) >= len(this.blocks) {
+			return nil, RPC_ERR_UNKNOWN_BLOCK, fmt.Errorf("UNKNOWN BLOCK")
+		}
+		return hex.EncodeToString(this.blocks[height].block.ToArray()), 0, nil
+	case "getsmartcodeevent":
+		if height, ok := heightParam(params); ok {
+			if int(height) >= len(this.blocks) {
+				return nil, RPC_ERR_UNKNOWN_BLOCK, fmt.Errorf("UNKNOWN BLOCK")
+			}
+			events := make([]*ontology_sdk_common.SmartContactEvent, 0)
+			for _, tx := range this.blocks[height].txs {
+				events = append(events, tx.event)
+			}
+			return events, 0, nil
+		}
+		tx := this.findTx(params)
+		if tx == nil {
+			return nil, 0, nil
+		}
+		return tx.event, 0, nil
+	case "getblockheightbytxhash":
+		tx := this.findTx(params)
+		if tx == nil {
+			return nil, RPC_ERR_UNKNOWN_TX, fmt.Errorf("UNKNOWN TRANSACTION")
+		}
+		return this.txHeights[tx.hash], 0, nil
+	case "sendrawtransaction":
+		return this.sendRawTransaction(params)
+	}
+	return nil, RPC_ERR_INVALID_METHOD, fmt.Errorf("method %s is not supported by mock L1", method)
+}
+
+func heightParam(params []interface{}) (uint32, bool) {
+	if len(params) == 0 {
+		return 0, false
+	}
+	height, ok := params[0].(float64)
+	return uint32(height), ok
+}
+
+func (this *MockL1) findTx(params []interface{}) *mockTx {
+	if len(params) == 0 {
+		return nil
+	}
+	hash, ok := params[0].(string)
+	if !ok {
+		return nil
+	}
+	height, ok := this.txHeights[hash]
+	if !ok {
+		return nil
+	}
+	for _, tx := range this.blocks[height].txs {
+		if tx.hash == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (this *MockL1) sendRawTransaction(params []interface{}) (interface{}, int64, error) {
+	if len(params) == 0 {
+		return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("transaction is missing")
+	}
+	rawHex, ok := params[0].(string)
+	if !ok {
+		return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("transaction is not hex string")
+	}
+	raw, err := hex.DecodeString(rawHex)
+	if err != nil {
+		return nil, RPC_ERR_INVALID_PARAMS, err
+	}
+	tx, err := types.TransactionFromRawBytes(raw)
+	if err != nil {
+		return nil, RPC_ERR_INVALID_PARAMS, err
+	}
+	invokeCode, ok := tx.Payload.(*payload.InvokeCode)
+	if !ok {
+		return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("only invoke transaction is supported")
+	}
+	invoke, err := bridge.DecodeNeoVMInvocation(invokeCode.Code)
+	if err != nil {
+		return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("parse invoke code error: %s", err)
+	}
+	if invoke.Contract.ToHexString() != this.ContractAddress() {
+		return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("contract %s is not the bridge contract", invoke.Contract.ToHexString())
+	}
+	preExec := len(params) > 1
+	switch invoke.Method {
+	case bridge.METHOD_GET_STATE_ROOT_BY_HEIGHT:
+		if len(invoke.Args) != 1 {
+			return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("%s need 1 param", invoke.Method)
+		}
+		stateRoot, ok := this.stateRoots[bridge.NeoVMItemToInt(invoke.Args[0]).Uint64()]
+		if !ok {
+			return preExecResult(1, MOCK_PRE_EXEC_GAS, ""), 0, nil
+		}
+		return preExecResult(1, MOCK_PRE_EXEC_GAS, []string{
+			hex.EncodeToString([]byte(stateRoot.StateRootHash)),
+			neoIntHex(stateRoot.Height),
+			hex.EncodeToString([]byte(stateRoot.Version)),
+		}), 0, nil
+	case bridge.METHOD_GET_CURRENT_HEIGHT:
+		return preExecResult(1, MOCK_PRE_EXEC_GAS, neoIntHex(this.currentHeight)), 0, nil
+	case bridge.METHOD_UPDATE_STATE:
+		if len(invoke.Args) != 10 {
+			return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("%s need 10 params", invoke.Method)
+		}
+		if preExec {
+			return preExecResult(1, MOCK_PRE_EXEC_GAS, ""), 0, nil
+		}
+		hash := tx.Hash()
+		this.pending = append(this.pending, &mockTx{
+			hash:   hash.ToHexString(),
+			method: invoke.Method,
+			stateRoot: &bridge.StateRoot{
+				StateRootHash: bridge.NeoVMItemToString(invoke.Args[0]),
+				Height:        bridge.NeoVMItemToInt(invoke.Args[1]).Uint64(),
+				Version:       bridge.NeoVMItemToString(invoke.Args[2]),
+			},
+		})
+		return hash.ToHexString(), 0, nil
+	}
+	return nil, RPC_ERR_INVALID_PARAMS, fmt.Errorf("method %s is not supported by mock bridge contract", invoke.Method)
+}
+
+// preExecResult is the json form of the pre-execute result, in which Result is a hex string or array
+func preExecResult(state byte, gas uint64, result interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"State":  state,
+		"Gas":    gas,
+		"Result": result,
+	}
+}
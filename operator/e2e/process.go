@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	PROCESS_START_TIMEOUT = 60 * time.Second
+	PROCESS_STOP_TIMEOUT  = 10 * time.Second
+)
+
+// process is a child process of the runner whose output is written to a log file
+type process struct {
+	name    string
+	cmd     *exec.Cmd
+	logFile *os.File
+	done    chan error
+}
+
+func startProcess(name string, logPath string, bin string, args ...string) (*process, error) {
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return nil, fmt.Errorf("create log file of %s error: %s", name, err)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	err = cmd.Start()
+	if err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("start %s error: %s", name, err)
+	}
+	this := &process{
+		name:    name,
+		cmd:     cmd,
+		logFile: logFile,
+		done:    make(chan error, 1),
+	}
+	go func() {
+		this.done <- cmd.Wait()
+		close(this.done)
+	}()
+	log.Infof("%s started, pid: %d, log: %s", name, cmd.Process.Pid, logPath)
+	return this, nil
+}
+
+// waitReady call ready until it succeed, the process exits or timeout
+func (this *process) waitReady(ready func() error) error {
+	deadline := time.Now().Add(PROCESS_START_TIMEOUT)
+	for {
+		err := ready()
+		if err == nil {
+			return nil
+		}
+		select {
+		case exitErr := <-this.done:
+			return fmt.Errorf("%s exited before ready: %v, see %s", this.name, exitErr, this.logFile.Name())
+		case <-time.After(time.Second):
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is not ready in %s: %s", this.name, PROCESS_START_TIMEOUT, err)
+		}
+	}
+}
+
+func (this *process) stop() {
+	if this == nil {
+		return
+	}
+	this.cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-this.done:
+	case <-time.After(PROCESS_STOP_TIMEOUT):
+		log.Warnf("%s does not exit in %s, kill it", this.name, PROCESS_STOP_TIMEOUT)
+		this.cmd.Process.Kill()
+		<-this.done
+	}
+	this.logFile.Close()
+}
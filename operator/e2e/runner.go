@@ -0,0 +1,263 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"fmt"
+	"time"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	layer2_common "github.com/ontio/layer2/node/common"
+	"github.com/ontio/layer2/operator/core"
+	"github.com/ontio/layer2/operator/log"
+)
+
+const (
+	LAYER2_GAS_PRICE = 0
+	LAYER2_GAS_LIMIT = 20000
+	POLL_INTERVAL    = time.Second
+)
+
+type Result struct {
+	Scenario string
+	Duration time.Duration
+	Err      error
+}
+
+// Runner execute the scenarios one by one, every scenario has its own environment
+type Runner struct {
+	cfg *Config
+}
+
+func NewRunner(cfg *Config) *Runner {
+	return &Runner{cfg: cfg}
+}
+
+func (this *Runner) RunAll(scenarios []*Scenario) []*Result {
+	results := make([]*Result, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		start := time.Now()
+		err := this.Run(scenario)
+		result := &Result{Scenario: scenario.Name, Duration: time.Since(start), Err: err}
+		if err != nil {
+			log.Errorf("scenario %s FAILED in %s: %s", scenario.Name, result.Duration, err)
+		} else {
+			log.Infof("scenario %s PASSED in %s", scenario.Name, result.Duration)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// scenarioContext is the state shared by the steps of a running scenario
+type scenarioContext struct {
+	env        *Env
+	deadline   time.Time
+	accounts   map[string]*layer2_sdk.Account
+	depositIds []uint64
+}
+
+func (this *Runner) Run(scenario *Scenario) error {
+	log.Infof("run scenario %s: %s", scenario.Name, scenario.Description)
+	env := NewEnv(this.cfg, scenario.Name)
+	defer env.Stop()
+	err := env.Start()
+	if err != nil {
+		return fmt.Errorf("start environment error: %s", err)
+	}
+	ctx := &scenarioContext{
+		env:        env,
+		deadline:   time.Now().Add(time.Duration(scenario.Timeout) * time.Second),
+		accounts:   make(map[string]*layer2_sdk.Account),
+		depositIds: make([]uint64, 0),
+	}
+	for _, name := range scenario.Accounts {
+		ctx.accounts[name] = layer2_sdk.NewAccount()
+	}
+	for i, step := range scenario.Steps {
+		log.Infof("scenario %s step %d: %s", scenario.Name, i, step.Action)
+		err = ctx.runStep(step)
+		if err != nil {
+			return fmt.Errorf("step %d %s error: %s", i, step.Action, err)
+		}
+	}
+	if scenario.Expect == nil {
+		return nil
+	}
+	return ctx.poll(func() error {
+		return ctx.check(scenario.Expect)
+	})
+}
+
+// poll call cond until it succeed or the scenario timeout
+func (this *scenarioContext) poll(cond func() error) error {
+	for {
+		err := cond()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(this.deadline) {
+			return fmt.Errorf("timeout: %s", err)
+		}
+		time.Sleep(POLL_INTERVAL)
+	}
+}
+
+func (this *scenarioContext) runStep(step *Step) error {
+	switch step.Action {
+	case STEP_DEPOSIT:
+		token, _ := tokenAddress(step.Token)
+		id, err := this.env.L1.Deposit(this.accounts[step.Account], token, step.Amount)
+		if err != nil {
+			return err
+		}
+		this.depositIds = append(this.depositIds, id)
+		return nil
+	case STEP_TRANSFER:
+		return this.transfer(this.accounts[step.Account], this.accounts[step.To].Address, step.Token, step.Amount)
+	case STEP_WITHDRAW:
+		return this.transfer(this.accounts[step.Account], layer2_common.ADDRESS_EMPTY, step.Token, step.Amount)
+	case STEP_FAIL_COMMIT:
+		return this.env.L1.FailCommits(step.Count)
+	case STEP_REORG:
+		return this.env.L1.Reorg(step.Depth, step.Requeue)
+	case STEP_WAIT:
+		time.Sleep(time.Duration(step.Seconds) * time.Second)
+		if step.Deposits {
+			err := this.poll(this.depositsArrived)
+			if err != nil {
+				return err
+			}
+		}
+		if step.CommitHeight > 0 {
+			return this.poll(func() error {
+				return this.checkCommitHeight(step.CommitHeight)
+			})
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown action %s", step.Action)
+}
+
+func (this *scenarioContext) transfer(from *layer2_sdk.Account, to layer2_common.Address, token string, amount uint64) error {
+	var txHash layer2_common.Uint256
+	var err error
+	tokenAddr, _ := tokenAddress(token)
+	sdk := this.env.Layer2Sdk
+	if tokenAddr == nativeAssets.OntAddress {
+		txHash, err = sdk.Native.Ont.Transfer(LAYER2_GAS_PRICE, LAYER2_GAS_LIMIT, from, from, to, amount)
+	} else {
+		txHash, err = sdk.Native.Ong.Transfer(LAYER2_GAS_PRICE, LAYER2_GAS_LIMIT, from, from, to, amount)
+	}
+	if err != nil {
+		return err
+	}
+	var state byte
+	err = this.poll(func() error {
+		event, err := sdk.GetSmartContractEvent(txHash.ToHexString())
+		if err != nil || event == nil {
+			return fmt.Errorf("layer2 transaction %s is not confirmed", txHash.ToHexString())
+		}
+		state = event.State
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if state != 1 {
+		return fmt.Errorf("layer2 transaction %s failed", txHash.ToHexString())
+	}
+	return nil
+}
+
+// depositsArrived check that all the deposits are minted on layer2 or failed
+func (this *scenarioContext) depositsArrived() error {
+	for _, id := range this.depositIds {
+		deposit := core.LoadDepositByID(id)
+		if deposit == nil {
+			return fmt.Errorf("deposit %d is not found", id)
+		}
+		if deposit.State == core.DEPOSIT_EVENT || deposit.State == core.DEPOSIT_COMMIT {
+			return fmt.Errorf("deposit %d is in state %d", id, deposit.State)
+		}
+	}
+	return nil
+}
+
+func (this *scenarioContext) checkCommitHeight(height uint32) error {
+	commitHeight := core.GetLayer2CommitHeight()
+	if commitHeight < height {
+		return fmt.Errorf("layer2 commit height %d, want %d", commitHeight, height)
+	}
+	ok, err := this.env.L1.HasStateRoot(uint64(height))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("state root of layer2 height %d is not on L1", height)
+	}
+	return nil
+}
+
+func (this *scenarioContext) check(expect *Expect) error {
+	for _, want := range expect.Deposits {
+		id := this.depositIds[want.Index]
+		state, _ := depositState(want.State)
+		deposit := core.LoadDepositByID(id)
+		if deposit == nil {
+			return fmt.Errorf("deposit %d is not found", id)
+		}
+		if deposit.State != state {
+			return fmt.Errorf("deposit %d state %d, want %s", id, deposit.State, want.State)
+		}
+	}
+	for _, want := range expect.Withdraws {
+		state, _ := withdrawState(want.State)
+		address := this.accounts[want.Account].Address.ToBase58()
+		count := 0
+		for _, withdraw := range core.LoadWithdrawByToAddress(address) {
+			if withdraw.State == state {
+				count++
+			}
+		}
+		if count != want.Count {
+			return fmt.Errorf("%s has %d withdraws in state %s, want %d", want.Account, count, want.State, want.Count)
+		}
+	}
+	for _, want := range expect.Balances {
+		address := this.accounts[want.Account].Address
+		tokenAddr, _ := tokenAddress(want.Token)
+		var balance uint64
+		var err error
+		if tokenAddr == nativeAssets.OntAddress {
+			balance, err = this.env.Layer2Sdk.Native.Ont.BalanceOf(address)
+		} else {
+			balance, err = this.env.Layer2Sdk.Native.Ong.BalanceOf(address)
+		}
+		if err != nil {
+			return fmt.Errorf("get %s balance of %s error: %s", want.Token, want.Account, err)
+		}
+		if balance != want.Amount {
+			return fmt.Errorf("%s balance of %s is %d, want %d", want.Token, want.Account, balance, want.Amount)
+		}
+	}
+	if expect.CommitHeight > 0 {
+		return this.checkCommitHeight(expect.CommitHeight)
+	}
+	return nil
+}
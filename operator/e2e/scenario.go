@@ -0,0 +1,267 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ontio/layer2/operator/config"
+	"github.com/ontio/layer2/operator/core"
+)
+
+const (
+	STEP_DEPOSIT     = "deposit"
+	STEP_TRANSFER    = "transfer"
+	STEP_WITHDRAW    = "withdraw"
+	STEP_FAIL_COMMIT = "fail_commit"
+	STEP_REORG       = "reorg"
+	STEP_WAIT        = "wait"
+)
+
+const (
+	DEFAULT_SCENARIO_TIMEOUT = 180
+)
+
+// Scenario is a declarative description of one cross chain flow and the final states it must reach
+type Scenario struct {
+	Name        string
+	Description string
+	// the accounts used by the steps, every account is a fresh key pair
+	Accounts []string
+	Steps    []*Step
+	Expect   *Expect
+	// seconds to wait for the expectations, DEFAULT_SCENARIO_TIMEOUT if 0
+	Timeout uint32
+}
+
+// Step is one action of the scenario, the fields used depend on Action
+type Step struct {
+	Action string
+	// deposit: the depositor, transfer/withdraw: the sender
+	Account string
+	// transfer: the receiver
+	To     string
+	Token  string
+	Amount uint64
+	// fail_commit: the number of following layer2 state commits which fail on L1
+	Count int
+	// reorg: the number of L1 blocks rolled back
+	Depth uint32
+	// reorg: put the transactions of the rolled back blocks back to the pool
+	Requeue bool
+	// wait: seconds, then until the deposits are minted on layer2 and the layer2 height is committed on L1
+	Seconds      uint32
+	Deposits     bool
+	CommitHeight uint32
+}
+
+type Expect struct {
+	Deposits  []*DepositExpect
+	Withdraws []*WithdrawExpect
+	Balances  []*BalanceExpect
+	// the layer2 height which must be committed and confirmed
+	CommitHeight uint32
+}
+
+type DepositExpect struct {
+	// the index of the deposit step in the scenario, start from 0
+	Index int
+	State string
+}
+
+type WithdrawExpect struct {
+	Account string
+	Count   int
+	State   string
+}
+
+type BalanceExpect struct {
+	Account string
+	Token   string
+	Amount  uint64
+}
+
+func LoadScenario(fileName string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario %s error: %s", fileName, err)
+	}
+	scenario := &Scenario{}
+	err = json.Unmarshal(data, scenario)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal scenario %s error: %s", fileName, err)
+	}
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+	if scenario.Timeout == 0 {
+		scenario.Timeout = DEFAULT_SCENARIO_TIMEOUT
+	}
+	err = scenario.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s is invalid: %s", scenario.Name, err)
+	}
+	return scenario, nil
+}
+
+// LoadScenarios load a scenario file, or all the json files of a directory in name order
+func LoadScenarios(path string) ([]*Scenario, error) {
+	files := []string{path}
+	if !strings.HasSuffix(path, ".json") {
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = matches
+	}
+	scenarios := make([]*Scenario, 0, len(files))
+	for _, file := range files {
+		scenario, err := LoadScenario(file)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+func (this *Scenario) hasAccount(name string) bool {
+	for _, account := range this.Accounts {
+		if account == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *Scenario) Validate() error {
+	for i, step := range this.Steps {
+		switch step.Action {
+		case STEP_DEPOSIT, STEP_WITHDRAW:
+			if !this.hasAccount(step.Account) {
+				return fmt.Errorf("step %d: unknown account %s", i, step.Account)
+			}
+			if _, err := tokenAddress(step.Token); err != nil {
+				return fmt.Errorf("step %d: %s", i, err)
+			}
+		case STEP_TRANSFER:
+			if !this.hasAccount(step.Account) || !this.hasAccount(step.To) {
+				return fmt.Errorf("step %d: unknown account %s or %s", i, step.Account, step.To)
+			}
+			if _, err := tokenAddress(step.Token); err != nil {
+				return fmt.Errorf("step %d: %s", i, err)
+			}
+		case STEP_FAIL_COMMIT:
+			if step.Count <= 0 {
+				return fmt.Errorf("step %d: fail commit count must be positive", i)
+			}
+		case STEP_REORG:
+			if step.Depth == 0 {
+				return fmt.Errorf("step %d: reorg depth must be positive", i)
+			}
+		case STEP_WAIT:
+		default:
+			return fmt.Errorf("step %d: unknown action %s", i, step.Action)
+		}
+	}
+	if this.Expect == nil {
+		return nil
+	}
+	for _, deposit := range this.Expect.Deposits {
+		if deposit.Index < 0 || deposit.Index >= len(this.depositSteps()) {
+			return fmt.Errorf("expect deposit %d, but only %d deposit steps", deposit.Index, len(this.depositSteps()))
+		}
+		if _, err := depositState(deposit.State); err != nil {
+			return err
+		}
+	}
+	for _, withdraw := range this.Expect.Withdraws {
+		if !this.hasAccount(withdraw.Account) {
+			return fmt.Errorf("expect withdraw of unknown account %s", withdraw.Account)
+		}
+		if _, err := withdrawState(withdraw.State); err != nil {
+			return err
+		}
+	}
+	for _, balance := range this.Expect.Balances {
+		if !this.hasAccount(balance.Account) {
+			return fmt.Errorf("expect balance of unknown account %s", balance.Account)
+		}
+		if _, err := tokenAddress(balance.Token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Scenario) depositSteps() []*Step {
+	steps := make([]*Step, 0)
+	for _, step := range this.Steps {
+		if step.Action == STEP_DEPOSIT {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// the native assets of the operator config of the tests, which sets no AssetConfig
+var nativeAssets, _ = (&config.AssetConfig{}).Resolve()
+
+func tokenAddress(token string) (string, error) {
+	switch strings.ToLower(token) {
+	case "ont":
+		return nativeAssets.OntAddress, nil
+	case "ong":
+		return nativeAssets.OngAddress, nil
+	}
+	return "", fmt.Errorf("unknown token %s", token)
+}
+
+func depositState(state string) (int, error) {
+	switch state {
+	case "event":
+		return core.DEPOSIT_EVENT, nil
+	case "commit":
+		return core.DEPOSIT_COMMIT, nil
+	case "finish":
+		return core.DEPOSIT_FINISH, nil
+	case "notify":
+		return core.DEPOSIT_NOTIFY, nil
+	case "failed":
+		return core.DEPOSIT_FAILED, nil
+	case "quarantine":
+		return core.DEPOSIT_QUARANTINE, nil
+	}
+	return 0, fmt.Errorf("unknown deposit state %s", state)
+}
+
+func withdrawState(state string) (int, error) {
+	switch state {
+	case "init":
+		return core.WITHDRAW_INIT, nil
+	case "commit":
+		return core.WITHDRAW_COMMIT, nil
+	}
+	return 0, fmt.Errorf("unknown withdraw state %s", state)
+}
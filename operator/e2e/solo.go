@@ -0,0 +1,239 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package e2e
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	layer2_sdk "github.com/ontio/layer2/go-sdk"
+	"github.com/ontio/layer2/operator/bridge"
+	"github.com/ontio/layer2/operator/log"
+	ontology_sdk "github.com/ontio/ontology-go-sdk"
+	ontology_sdk_common "github.com/ontio/ontology-go-sdk/common"
+	ontology_common "github.com/ontio/ontology/common"
+)
+
+const (
+	SOLO_GAS_PRICE    = 0
+	SOLO_GAS_LIMIT    = 20000000
+	SOLO_WAIT_TIMEOUT = 30 * time.Second
+)
+
+// SoloL1 run an ontology node in test mode as L1, the bridge contract is the real one so that
+// commit failures and reorgs can not be injected
+type SoloL1 struct {
+	cfg      *L1Config
+	workDir  string
+	node     *process
+	sdk      *ontology_sdk.OntologySdk
+	admin    *ontology_sdk.Account
+	contract ontology_common.Address
+	bridge   *bridge.NeoVMBridge
+}
+
+func NewSoloL1(cfg *L1Config, workDir string) *SoloL1 {
+	return &SoloL1{
+		cfg:     cfg,
+		workDir: workDir,
+		bridge:  bridge.NewNeoVMBridge(),
+	}
+}
+
+func (this *SoloL1) Start() error {
+	nodeCfg := this.cfg.Node
+	node, err := startProcess("ontology solo node", filepath.Join(this.workDir, "ontology.log"),
+		nodeCfg.Bin, nodeCfg.args(filepath.Join(this.workDir, "ontology"))...)
+	if err != nil {
+		return err
+	}
+	this.node = node
+	this.sdk = ontology_sdk.NewOntologySdk()
+	this.sdk.NewRpcClient().SetAddress(nodeCfg.url())
+	err = node.waitReady(func() error {
+		_, err := this.sdk.GetCurrentBlockHeight()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	wallet, err := this.sdk.OpenWallet(nodeCfg.WalletFile)
+	if err != nil {
+		return fmt.Errorf("open ontology wallet error: %s", err)
+	}
+	this.admin, err = wallet.GetDefaultAccount([]byte(nodeCfg.WalletPwd))
+	if err != nil {
+		return fmt.Errorf("get ontology wallet account error: %s", err)
+	}
+	if this.cfg.ContractCode == "" {
+		this.contract, err = ontology_common.AddressFromHexString(this.cfg.ContractAddress)
+		if err != nil {
+			return fmt.Errorf("invalid bridge contract address: %s", err)
+		}
+		return nil
+	}
+	return this.deployContract()
+}
+
+func (this *SoloL1) deployContract() error {
+	data, err := ioutil.ReadFile(this.cfg.ContractCode)
+	if err != nil {
+		return fmt.Errorf("read bridge contract code error: %s", err)
+	}
+	code := strings.TrimSpace(string(data))
+	codeBytes, err := hex.DecodeString(code)
+	if err != nil {
+		return fmt.Errorf("bridge contract code is not hex: %s", err)
+	}
+	txHash, err := this.sdk.NeoVM.DeployNeoVMSmartContract(SOLO_GAS_PRICE, SOLO_GAS_LIMIT, this.admin, true, code,
+		"layer2", "1.0", "e2e", "", "layer2 bridge contract")
+	if err != nil {
+		return fmt.Errorf("deploy bridge contract error: %s", err)
+	}
+	_, err = this.waitEvent(txHash)
+	if err != nil {
+		return fmt.Errorf("deploy bridge contract error: %s", err)
+	}
+	this.contract = ontology_common.AddressFromVmCode(codeBytes)
+	log.Infof("bridge contract deployed at %s", this.contract.ToHexString())
+	return nil
+}
+
+func (this *SoloL1) Stop() {
+	this.node.stop()
+}
+
+func (this *SoloL1) Url() string {
+	return this.cfg.Node.url()
+}
+
+func (this *SoloL1) ContractAddress() string {
+	return this.contract.ToHexString()
+}
+
+func (this *SoloL1) waitEvent(txHash ontology_common.Uint256) (*ontology_sdk_common.SmartContactEvent, error) {
+	deadline := time.Now().Add(SOLO_WAIT_TIMEOUT)
+	for time.Now().Before(deadline) {
+		event, err := this.sdk.GetSmartContractEvent(txHash.ToHexString())
+		if err == nil && event != nil {
+			if event.State != 1 {
+				return nil, fmt.Errorf("transaction %s failed", txHash.ToHexString())
+			}
+			return event, nil
+		}
+		time.Sleep(time.Second)
+	}
+	return nil, fmt.Errorf("transaction %s is not confirmed in %s", txHash.ToHexString(), SOLO_WAIT_TIMEOUT)
+}
+
+// Deposit fund the player from the bookkeeper then deposit through the bridge contract
+func (this *SoloL1) Deposit(player *layer2_sdk.Account, token string, amount uint64) (uint64, error) {
+	account := &ontology_sdk.Account{
+		PrivateKey: player.PrivateKey,
+		PublicKey:  player.PublicKey,
+		Address:    ontology_common.Address(player.Address),
+		SigScheme:  player.SigScheme,
+	}
+	var txHash ontology_common.Uint256
+	var err error
+	if token == nativeAssets.OntAddress {
+		txHash, err = this.sdk.Native.Ont.Transfer(SOLO_GAS_PRICE, SOLO_GAS_LIMIT, this.admin, this.admin, account.Address, amount)
+	} else {
+		txHash, err = this.sdk.Native.Ong.Transfer(SOLO_GAS_PRICE, SOLO_GAS_LIMIT, this.admin, this.admin, account.Address, amount)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("fund player error: %s", err)
+	}
+	_, err = this.waitEvent(txHash)
+	if err != nil {
+		return 0, fmt.Errorf("fund player error: %s", err)
+	}
+
+	assetAddress, _ := hex.DecodeString(token)
+	params, err := this.bridge.DepositParams(&bridge.DepositParam{
+		Player:       account.Address[:],
+		Amount:       amount,
+		AssetAddress: assetAddress,
+	})
+	if err != nil {
+		return 0, err
+	}
+	tx, err := this.sdk.NeoVM.NewNeoVMInvokeTransaction(SOLO_GAS_PRICE, SOLO_GAS_LIMIT, this.contract, params)
+	if err != nil {
+		return 0, err
+	}
+	this.sdk.SetPayer(tx, this.admin.Address)
+	err = this.sdk.SignToTransaction(tx, this.admin)
+	if err != nil {
+		return 0, err
+	}
+	err = this.sdk.SignToTransaction(tx, account)
+	if err != nil {
+		return 0, err
+	}
+	txHash, err = this.sdk.SendTransaction(tx)
+	if err != nil {
+		return 0, fmt.Errorf("send deposit transaction error: %s", err)
+	}
+	event, err := this.waitEvent(txHash)
+	if err != nil {
+		return 0, err
+	}
+	for _, notify := range event.Notify {
+		if notify.ContractAddress != this.ContractAddress() {
+			continue
+		}
+		deposit, err := this.bridge.ParseDepositEvent(notify.States)
+		if err != nil {
+			continue
+		}
+		return deposit.ID, nil
+	}
+	return 0, fmt.Errorf("deposit event of %s not found", txHash.ToHexString())
+}
+
+func (this *SoloL1) FailCommits(count int) error {
+	return fmt.Errorf("commit failure can not be injected into the solo node")
+}
+
+func (this *SoloL1) Reorg(depth uint32, requeue bool) error {
+	return fmt.Errorf("reorg can not be injected into the solo node")
+}
+
+func (this *SoloL1) HasStateRoot(height uint64) (bool, error) {
+	params, err := this.bridge.GetStateRootByHeightParams(height)
+	if err != nil {
+		return false, err
+	}
+	result, err := this.sdk.NeoVM.PreExecInvokeNeoVMContract(this.contract, params)
+	if err != nil {
+		return false, err
+	}
+	if result == nil || result.Result == nil {
+		return false, nil
+	}
+	stateRoot, err := this.bridge.ParseStateRoot(result.Result)
+	if err != nil {
+		return false, nil
+	}
+	return stateRoot.Height == height, nil
+}
@@ -3,10 +3,18 @@ module github.com/ontio/layer2/operator
 go 1.14
 
 require (
+	github.com/ethereum/go-ethereum v1.9.13
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/lib/pq v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/ontio/layer2/go-sdk v0.0.0-20200429091234-c4911b865a2c
 	github.com/ontio/layer2/node v0.0.0-20200429091234-c4911b865a2c
 	github.com/ontio/ontology v1.9.0
+	github.com/ontio/ontology-crypto v1.0.8
 	github.com/ontio/ontology-go-sdk v1.11.1
 	github.com/urfave/cli v1.22.4
+	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd
 )
+
+// the fee helpers of the go-sdk are not published yet, the operator builds with the go-sdk of this repository
+replace github.com/ontio/layer2/go-sdk => ../go-sdk
@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the fields of the bridge operations, a deposit is filtered by deposit_id and a commit by chain and height
+const (
+	FIELD_MODULE     = "module"
+	FIELD_CHAIN      = "chain"
+	FIELD_HEIGHT     = "height"
+	FIELD_TXHASH     = "txhash"
+	FIELD_DEPOSIT_ID = "deposit_id"
+
+	// the module prefix of the messages like "challenger - ...", longer prefixes are part of the message
+	MAX_MODULE_LEN = 32
+)
+
+var levelKeys = map[int]string{
+	TraceLog: "trace",
+	DebugLog: "debug",
+	InfoLog:  "info",
+	WarnLog:  "warn",
+	ErrorLog: "error",
+	FatalLog: "fatal",
+}
+
+//ParseLevel return the level of the name, which is trace, debug, info, warn, error or fatal
+func ParseLevel(name string) (int, error) {
+	for level, key := range levelKeys {
+		if strings.EqualFold(key, name) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %s", name)
+}
+
+//moduleOf return the module prefix of the message, empty if the message has none
+func moduleOf(msg string) string {
+	index := strings.Index(msg, " - ")
+	if index <= 0 || index > MAX_MODULE_LEN {
+		return ""
+	}
+	module := msg[:index]
+	for _, c := range module {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ' ' || c == '_') {
+			return ""
+		}
+	}
+	return module
+}
+
+//Fields is the structured fields of a message, they are the keys of the json line, or appended to the text line
+type Fields map[string]interface{}
+
+//text return the fields in key order as " key=value" pairs of the text line
+func (this Fields) text() string {
+	if len(this) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(this))
+	for key := range this {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	for _, key := range keys {
+		builder.WriteString(fmt.Sprintf(" %s=%v", key, this[key]))
+	}
+	return builder.String()
+}
+
+//Entry log the messages with the fields, the messages without a module prefix are prefixed by the module of the entry
+type Entry struct {
+	module string
+	fields Fields
+}
+
+//WithFields return the entry of the fields
+func WithFields(fields Fields) *Entry {
+	return (&Entry{}).WithFields(fields)
+}
+
+//Module return the entry of the module, whose level is set by the module levels
+func Module(name string) *Entry {
+	return &Entry{module: name}
+}
+
+//WithFields return a copy of the entry with the fields added
+func (this *Entry) WithFields(fields Fields) *Entry {
+	entry := &Entry{
+		module: this.module,
+		fields: make(Fields, len(this.fields) + len(fields)),
+	}
+	for key, value := range this.fields {
+		entry.fields[key] = value
+	}
+	for key, value := range fields {
+		entry.fields[key] = value
+	}
+	return entry
+}
+
+//WithField return a copy of the entry with the field added
+func (this *Entry) WithField(key string, value interface{}) *Entry {
+	return this.WithFields(Fields{key: value})
+}
+
+func (this *Entry) output(level int, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	module := moduleOf(msg)
+	if this.module != "" && module == "" {
+		// the message is prefixed like the messages of the module logged without an entry
+		module = this.module
+		msg = module + " - " + msg
+	} else if this.module != "" {
+		module = this.module
+	}
+	if !Log.enabled(level, module) {
+		return
+	}
+	Log.write(level, module, this.fields, msg)
+}
+
+func (this *Entry) Debugf(format string, a ...interface{}) {
+	this.output(DebugLog, format, a...)
+}
+
+func (this *Entry) Infof(format string, a ...interface{}) {
+	this.output(InfoLog, format, a...)
+}
+
+func (this *Entry) Warnf(format string, a ...interface{}) {
+	this.output(WarnLog, format, a...)
+}
+
+func (this *Entry) Errorf(format string, a ...interface{}) {
+	this.output(ErrorLog, format, a...)
+}
@@ -20,6 +20,7 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -116,6 +117,12 @@ type Logger struct {
 	level   int
 	logger  *log.Logger
 	logFile *os.File
+	// the lines are json objects with the fields instead of text if json is set
+	json    bool
+	// the levels of the modules, the other modules are of level
+	modules map[string]int
+	// the writer closed by ClosePrintLog besides logFile, the rotating files
+	closer  io.Closer
 }
 
 func New(out io.Writer, prefix string, flag, level int, file *os.File) *Logger {
@@ -126,6 +133,14 @@ func New(out io.Writer, prefix string, flag, level int, file *os.File) *Logger {
 	}
 }
 
+//enabled return true if the level of the module is logged
+func (l *Logger) enabled(level int, module string) bool {
+	if moduleLevel, ok := l.modules[module]; ok {
+		return level >= moduleLevel
+	}
+	return level >= l.level
+}
+
 func (l *Logger) SetDebugLevel(level int) error {
 	if level > MaxLevelLog || level < 0 {
 		return errors.New("Invalid Debug Level")
@@ -149,14 +164,36 @@ func (l *Logger) Output(level int, a ...interface{}) error {
 }
 
 func (l *Logger) Outputf(level int, format string, v ...interface{}) error {
-	if level >= l.level {
-		gid := GetGID()
-		v = append([]interface{}{LevelName(level), "GID",
-			gid}, v...)
+	msg := fmt.Sprintf(format, v...)
+	module := moduleOf(msg)
+	if !l.enabled(level, module) {
+		return nil
+	}
+	return l.write(level, module, nil, msg)
+}
 
-		return l.logger.Output(CALL_DEPTH, fmt.Sprintf("%s %s %d, "+format+"\n", v...))
+//write output the message with the fields, the module of the message is a field of the json line
+func (l *Logger) write(level int, module string, fields Fields, msg string) error {
+	gid := GetGID()
+	if !l.json {
+		return l.logger.Output(CALL_DEPTH, fmt.Sprintf("%s GID %d, %s%s\n", LevelName(level), gid, msg, fields.text()))
 	}
-	return nil
+	line := make(map[string]interface{}, len(fields) + 5)
+	for key, value := range fields {
+		line[key] = value
+	}
+	line["time"] = time.Now().Format(time.RFC3339Nano)
+	line["level"] = levelKeys[level]
+	line["gid"] = gid
+	line["msg"] = msg
+	if module != "" {
+		line[FIELD_MODULE] = module
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	return l.logger.Output(CALL_DEPTH, string(data) + "\n")
 }
 
 func (l *Logger) Trace(a ...interface{}) {
@@ -228,7 +265,7 @@ func Trace(a ...interface{}) {
 }
 
 func Tracef(format string, a ...interface{}) {
-	if TraceLog < Log.level {
+	if !Log.enabled(TraceLog, moduleOf(format)) {
 		return
 	}
 
@@ -242,9 +279,8 @@ func Tracef(format string, a ...interface{}) {
 	nameEnd := filepath.Ext(nameFull)
 	funcName := strings.TrimPrefix(nameEnd, ".")
 
-	a = append([]interface{}{funcName, fileName, line}, a...)
-
-	Log.Tracef("%s() %s:%d "+format, a...)
+	msg := fmt.Sprintf(format, a...)
+	Log.write(TraceLog, moduleOf(msg), nil, fmt.Sprintf("%s() %s:%d %s", funcName, fileName, line, msg))
 }
 
 func Debug(a ...interface{}) {
@@ -264,7 +300,7 @@ func Debug(a ...interface{}) {
 }
 
 func Debugf(format string, a ...interface{}) {
-	if DebugLog < Log.level {
+	if !Log.enabled(DebugLog, moduleOf(format)) {
 		return
 	}
 
@@ -274,9 +310,8 @@ func Debugf(format string, a ...interface{}) {
 	file, line := f.FileLine(pc[0])
 	fileName := filepath.Base(file)
 
-	a = append([]interface{}{f.Name(), fileName, line}, a...)
-
-	Log.Debugf("%s %s:%d "+format, a...)
+	msg := fmt.Sprintf(format, a...)
+	Log.write(DebugLog, moduleOf(msg), nil, fmt.Sprintf("%s %s:%d %s", f.Name(), fileName, line, msg))
 }
 
 func Info(a ...interface{}) {
@@ -357,6 +392,8 @@ func InitLog(logLevel int, a ...interface{}) {
 				writers = append(writers, logFile)
 			case *os.File:
 				writers = append(writers, o.(*os.File))
+			case io.Writer:
+				writers = append(writers, o.(io.Writer))
 			default:
 				fmt.Println("error: invalid log location")
 				os.Exit(1)
@@ -397,9 +434,5 @@ func CheckIfNeedNewFile() bool {
 }
 
 func ClosePrintLog() error {
-	var err error
-	if Log.logFile != nil {
-		err = Log.logFile.Close()
-	}
-	return err
+	return ClosePrintLogger(Log)
 }
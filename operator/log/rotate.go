@@ -0,0 +1,208 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package log
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+
+	LOG_FILE_SUFFIX = "_LOG.log"
+)
+
+//Options is the format, the levels and the files of the log. The files are written in Path, a new file is started once
+//the current one exceeds MaxSize MB or is older than RotateInterval, and the oldest files beyond MaxBackups are
+//deleted. MaxSize is DEFAULT_MAX_LOG_SIZE if 0, the files are not rotated by time if RotateInterval is 0, and all
+//files are kept if MaxBackups is 0. The log is written to stdout too if Stdout is set, no file is written if Path is
+//empty
+type Options struct {
+	Level          int
+	Format         string
+	ModuleLevels   map[string]int
+	Path           string
+	MaxSize        int64
+	RotateInterval time.Duration
+	MaxBackups     int
+	Stdout         bool
+}
+
+//Setup replace the logger by the one of the options, the files of the previous logger are closed
+func Setup(opts *Options) error {
+	if opts.Format != "" && opts.Format != FORMAT_TEXT && opts.Format != FORMAT_JSON {
+		return fmt.Errorf("invalid log format %s", opts.Format)
+	}
+	writers := []io.Writer{}
+	if opts.Stdout {
+		writers = append(writers, Stdout)
+	}
+	var rotate *RotateWriter
+	if opts.Path != "" {
+		var err error
+		rotate, err = NewRotateWriter(opts.Path, GetMaxLogChangeInterval(opts.MaxSize), opts.RotateInterval, opts.MaxBackups)
+		if err != nil {
+			return err
+		}
+		writers = append(writers, rotate)
+	}
+	if len(writers) == 0 {
+		writers = append(writers, ioutil.Discard)
+	}
+	flag := log.Ldate|log.Lmicroseconds
+	if opts.Format == FORMAT_JSON {
+		// the time is a field of the json line
+		flag = 0
+	}
+	logger := New(io.MultiWriter(writers...), "", flag, opts.Level, nil)
+	logger.json = opts.Format == FORMAT_JSON
+	logger.modules = opts.ModuleLevels
+	if rotate != nil {
+		logger.closer = rotate
+	}
+	previous := Log
+	Log = logger
+	if previous != nil {
+		ClosePrintLogger(previous)
+	}
+	return nil
+}
+
+//ClosePrintLogger close the files of the logger
+func ClosePrintLogger(logger *Logger) error {
+	var err error
+	if logger.logFile != nil {
+		err = logger.logFile.Close()
+	}
+	if logger.closer != nil {
+		err = logger.closer.Close()
+	}
+	return err
+}
+
+//RotateWriter write the log to the files in dir, which are named by the time they are started. A new file is started
+//once the current one exceeds maxSize bytes or is older than interval, and the oldest files beyond maxBackups are
+//deleted
+type RotateWriter struct {
+	mu         sync.Mutex
+	dir        string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+	started    time.Time
+}
+
+func NewRotateWriter(dir string, maxSize int64, interval time.Duration, maxBackups int) (*RotateWriter, error) {
+	err := os.MkdirAll(dir, 0766)
+	if err != nil {
+		return nil, err
+	}
+	writer := &RotateWriter{
+		dir:        dir,
+		maxSize:    maxSize,
+		interval:   interval,
+		maxBackups: maxBackups,
+	}
+	err = writer.rotate()
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (this *RotateWriter) Write(p []byte) (int, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.file == nil {
+		return 0, os.ErrClosed
+	}
+	if this.size + int64(len(p)) > this.maxSize && this.size > 0 ||
+		this.interval > 0 && time.Since(this.started) >= this.interval {
+		err := this.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := this.file.Write(p)
+	this.size += int64(n)
+	return n, err
+}
+
+func (this *RotateWriter) Close() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.file == nil {
+		return nil
+	}
+	err := this.file.Close()
+	this.file = nil
+	return err
+}
+
+//rotate start a new file and delete the oldest files beyond maxBackups
+func (this *RotateWriter) rotate() error {
+	now := time.Now()
+	name := filepath.Join(this.dir, now.Format("2006-01-02_15.04.05.000") + LOG_FILE_SUFFIX)
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if this.file != nil {
+		this.file.Close()
+	}
+	this.file = file
+	this.size = info.Size()
+	this.started = now
+	if this.maxBackups <= 0 {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(this.dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), LOG_FILE_SUFFIX) {
+			names = append(names, entry.Name())
+		}
+	}
+	// the names start with the time, so the oldest are the first, the current file is the last
+	sort.Strings(names)
+	for i := 0; i < len(names) - this.maxBackups - 1; i ++ {
+		os.Remove(filepath.Join(this.dir, names[i]))
+	}
+	return nil
+}
@@ -25,7 +25,9 @@ import (
 	"github.com/ontio/layer2/operator/cmd"
 	"github.com/ontio/layer2/operator/config"
 	"github.com/ontio/layer2/operator/core"
+	"github.com/ontio/layer2/operator/e2e"
 	"github.com/ontio/layer2/operator/log"
+	ontology_common "github.com/ontio/ontology/common"
 	"github.com/urfave/cli"
 	"os"
 	"os/signal"
@@ -49,8 +51,128 @@ func setupApp() *cli.App {
 	app.Flags = []cli.Flag{
 		cmd.LogLevelFlag,
 		cmd.ConfigPathFlag,
+		cmd.ChainSpecFlag,
+		cmd.LeaderLockFlag,
+		cmd.DryRunFlag,
 	}
 	app.Commands = []cli.Command{
+		{
+			Name:   "e2e",
+			Usage:  "Run the end-to-end bridge scenarios against a dev-mode node and a mock L1",
+			Action: runE2E,
+			Flags: []cli.Flag{
+				cmd.E2EConfigFlag,
+				cmd.ScenarioFlag,
+			},
+		},
+		{
+			Name:   "massexit",
+			Usage:  "Export the accounts and proofs of the committed layer2 height for the users to claim in the mass exit",
+			Action: runMassExit,
+			Flags: []cli.Flag{
+				cmd.MassExitHeightFlag,
+				cmd.MassExitOutputFlag,
+			},
+		},
+		{
+			Name:  "deadletter",
+			Usage: "Inspect and retry the jobs failed the max attempts",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "List the dead letters",
+					Action: runDeadLetterList,
+				},
+				{
+					Name:   "retry",
+					Usage:  "Set the job of the dead letter pending again",
+					Action: runDeadLetterRetry,
+					Flags: []cli.Flag{
+						cmd.DeadLetterIDFlag,
+					},
+				},
+			},
+		},
+		{
+			Name:  "admin",
+			Usage: "Inspect the failed and unconfirmed layer2 commits, resubmit or skip the commit of a height, and list the actions of the dry run",
+			Subcommands: []cli.Command{
+				{
+					Name:   "commits",
+					Usage:  "List the unconfirmed and failed commits, and the commit jobs not done",
+					Action: runAdminCommits,
+					Flags: []cli.Flag{
+						cmd.CommitLimitFlag,
+					},
+				},
+				{
+					Name:   "show",
+					Usage:  "Show the commit job and the commits of the height with their payloads",
+					Action: runAdminShowCommit,
+					Flags: []cli.Flag{
+						cmd.CommitHeightFlag,
+					},
+				},
+				{
+					Name:   "resubmit",
+					Usage:  "Set the commit job of the height pending again",
+					Action: runAdminResubmitCommit,
+					Flags: []cli.Flag{
+						cmd.CommitHeightFlag,
+					},
+				},
+				{
+					Name:   "skip",
+					Usage:  "Set the commit job of the height done without committing it",
+					Action: runAdminSkipCommit,
+					Flags: []cli.Flag{
+						cmd.CommitHeightFlag,
+					},
+				},
+				{
+					Name:   "dryrun",
+					Usage:  "List the deposit mints and state commits recorded by the dry run, the latest first",
+					Action: runAdminDryRun,
+					Flags: []cli.Flag{
+						cmd.CommitLimitFlag,
+					},
+				},
+			},
+		},
+		{
+			Name:  "keyrotation",
+			Usage: "Rotate the operator keys to the NextAccount of the config",
+			Subcommands: []cli.Command{
+				{
+					Name:   "register",
+					Usage:  "Register the new key of the operator in the layer2 contract",
+					Action: runKeyRotationRegister,
+					Flags: []cli.Flag{
+						cmd.TransitionPeriodFlag,
+					},
+				},
+				{
+					Name:   "switch",
+					Usage:  "Switch the operator of the layer2 contract to the new key after the transition period",
+					Action: runKeyRotationSwitch,
+				},
+				{
+					Name:   "cancel",
+					Usage:  "Cancel the registered key rotation",
+					Action: runKeyRotationCancel,
+				},
+				{
+					Name:   "status",
+					Usage:  "List the key rotations and the operator of the layer2 contract",
+					Action: runKeyRotationStatus,
+				},
+			},
+		},
+		{
+			Name:   "migrate",
+			Usage:  "Apply the schema migrations to the database without starting the operator",
+			Action: runMigrate,
+		},
 	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -62,7 +184,7 @@ func setupApp() *cli.App {
 func startServer(ctx *cli.Context) {
 	// get all cmd flag
 	logLevel := ctx.GlobalInt(cmd.GetFlagName(cmd.LogLevelFlag))
-	log.InitLog(logLevel, log.PATH, log.Stdout)
+	log.InitLog(logLevel, log.Stdout)
 
 	configPath := ctx.GlobalString(cmd.GetFlagName(cmd.ConfigPathFlag))
 	if configPath != "" {
@@ -70,22 +192,75 @@ func startServer(ctx *cli.Context) {
 	}
 
 	// read config
-	servConfig := config.NewServiceConfig(ConfigPath)
-	if servConfig == nil {
-		log.Errorf("startServer - create config failed!")
+	servConfig, err := loadServiceConfig(ctx)
+	if err != nil {
+		log.Errorf("startServer - %s", err)
 		return
 	}
+	// the log files are written once the log config is read
+	logConfig := servConfig.LogConfig
+	if logConfig == nil {
+		logConfig = &config.LogConfig{}
+	}
+	logOptions, err := logConfig.Options(logLevel)
+	if err == nil {
+		err = log.Setup(logOptions)
+	}
+	if err != nil {
+		log.Errorf("startServer - setup log failed: %s", err)
+		return
+	}
+	if servConfig.Spec != nil {
+		log.Infof("startServer - chain spec %s loaded, network id %d", servConfig.Spec.Name, servConfig.Spec.NetworkId)
+	}
 
-	initOperatorServer(servConfig)
+	initOperatorServer(servConfig, func() (*config.ServiceConfig, error) {
+		return loadServiceConfig(ctx)
+	})
 	waitToExit()
 }
 
+//loadServiceConfig read the config file and apply the chain spec, the leader lock and the dry run of the flags, it is
+//called again on reload
+func loadServiceConfig(ctx *cli.Context) (*config.ServiceConfig, error) {
+	servConfig := config.NewServiceConfig(ConfigPath)
+	if servConfig == nil {
+		return nil, fmt.Errorf("create config failed!")
+	}
+	chainSpec := ctx.GlobalString(cmd.GetFlagName(cmd.ChainSpecFlag))
+	if chainSpec != "" {
+		spec, err := config.LoadChainSpec(chainSpec)
+		if err != nil {
+			return nil, fmt.Errorf("load chain spec failed: %s", err)
+		}
+		spec.Apply(servConfig)
+	}
+	leaderLock := ctx.GlobalString(cmd.GetFlagName(cmd.LeaderLockFlag))
+	if leaderLock != "" {
+		servConfig.LeaderLock = leaderLock
+	}
+	if ctx.GlobalBool(cmd.GetFlagName(cmd.DryRunFlag)) {
+		servConfig.DryRun = true
+	}
+	return servConfig, nil
+}
+
+//waitToExit stop the operator on SIGINT or SIGTERM, and reload the config on SIGHUP
 func waitToExit() {
 	exit := make(chan bool, 0)
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
 		for sig := range sc {
+			if sig == syscall.SIGHUP {
+				log.Infof("waitToExit - Layer2 Operator received reload signal:%v.", sig.String())
+				if mgr != nil {
+					if _, err := mgr.Reload(core.AUDIT_BY_SIGNAL); err != nil {
+						log.Errorf("waitToExit - reload config failed: %s", err)
+					}
+				}
+				continue
+			}
 			log.Infof("waitToExit - Layer2 Operator received exit signal:%v.", sig.String())
 			mgr.Stop()
 			close(exit)
@@ -95,16 +270,387 @@ func waitToExit() {
 	<-exit
 }
 
-func initOperatorServer(servConfig *config.ServiceConfig) {
+func initOperatorServer(servConfig *config.ServiceConfig, loader func() (*config.ServiceConfig, error)) {
 	var err error
 	mgr, err = core.NewLayer2Operator(servConfig)
 	if err != nil || mgr == nil {
 		log.Error("initOperatorServer failed!")
 		return
 	}
+	mgr.SetConfigLoader(loader)
 	mgr.Start()
 }
 
+func runE2E(ctx *cli.Context) error {
+	logLevel := ctx.GlobalInt(cmd.GetFlagName(cmd.LogLevelFlag))
+	log.InitLog(logLevel, log.Stdout)
+
+	e2eConfig, err := e2e.NewConfig(ctx.String(cmd.GetFlagName(cmd.E2EConfigFlag)))
+	if err != nil {
+		return err
+	}
+	e2eConfig.LogLevel = uint(logLevel)
+	scenarioPath := ctx.String(cmd.GetFlagName(cmd.ScenarioFlag))
+	if scenarioPath == "" {
+		scenarioPath = e2eConfig.Scenarios
+	}
+	scenarios, err := e2e.LoadScenarios(scenarioPath)
+	if err != nil {
+		return err
+	}
+	if len(scenarios) == 0 {
+		return fmt.Errorf("no scenario found in %s", scenarioPath)
+	}
+	failed := 0
+	for _, result := range e2e.NewRunner(e2eConfig).RunAll(scenarios) {
+		status := "PASS"
+		if result.Err != nil {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s\t%s\t%s\n", status, result.Scenario, result.Duration)
+		if result.Err != nil {
+			fmt.Printf("\t%s\n", result.Err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", failed, len(scenarios))
+	}
+	return nil
+}
+
+//runMassExit export the snapshot of the layer2 node of the config without the database and L1, as the standby tool
+//when the operator fails
+func runMassExit(ctx *cli.Context) error {
+	logLevel := ctx.GlobalInt(cmd.GetFlagName(cmd.LogLevelFlag))
+	log.InitLog(logLevel, log.Stdout)
+
+	configPath := ctx.GlobalString(cmd.GetFlagName(cmd.ConfigPathFlag))
+	if configPath != "" {
+		ConfigPath = configPath
+	}
+	servConfig := config.NewServiceConfig(ConfigPath)
+	if servConfig == nil {
+		return fmt.Errorf("create config failed")
+	}
+	height := uint32(ctx.Uint(cmd.GetFlagName(cmd.MassExitHeightFlag)))
+	if height == 0 {
+		return fmt.Errorf("height of the committed layer2 state root is required")
+	}
+	err := core.SetAssets(servConfig.AssetConfig)
+	if err != nil {
+		return err
+	}
+	snapshot, err := core.GetMassExitSnapshot(servConfig.Layer2Config.RestURL, height, core.ConfigTokens(servConfig.Tokens))
+	if err != nil {
+		return err
+	}
+	output := ctx.String(cmd.GetFlagName(cmd.MassExitOutputFlag))
+	err = core.WriteMassExitSnapshot(output, snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("export %d accounts of height %d, state root %s, to %s\n", len(snapshot.Accounts), snapshot.Height, snapshot.StateRoot, output)
+	return nil
+}
+
+//connectProjectDB connect the database of the config for the commands working on the database only
+func connectProjectDB(ctx *cli.Context) error {
+	logLevel := ctx.GlobalInt(cmd.GetFlagName(cmd.LogLevelFlag))
+	log.InitLog(logLevel, log.Stdout)
+
+	configPath := ctx.GlobalString(cmd.GetFlagName(cmd.ConfigPathFlag))
+	if configPath != "" {
+		ConfigPath = configPath
+	}
+	servConfig := config.NewServiceConfig(ConfigPath)
+	if servConfig == nil {
+		return fmt.Errorf("create config failed")
+	}
+	return core.ConnectDB(servConfig.DBConfig)
+}
+
+func runMigrate(ctx *cli.Context) error {
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	err = core.Migrate()
+	if err != nil {
+		return err
+	}
+	version, err := core.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version: %d\n", version)
+	return nil
+}
+
+func runDeadLetterList(ctx *cli.Context) error {
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	letters := core.LoadDeadLetters()
+	if letters == nil {
+		return fmt.Errorf("load dead letters failed")
+	}
+	for _, letter := range letters {
+		fmt.Printf("%d\tkind: %d\tkey: %d\tattempts: %d\ttime: %d\n", letter.ID, letter.Kind, letter.Key, letter.Attempts, letter.TT)
+		fmt.Printf("\terror: %s\n\tpayload: %s\n", letter.Error, letter.Payload)
+	}
+	return nil
+}
+
+func runDeadLetterRetry(ctx *cli.Context) error {
+	id := ctx.Uint64(cmd.GetFlagName(cmd.DeadLetterIDFlag))
+	if id == 0 {
+		return fmt.Errorf("id of the dead letter is required")
+	}
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	err = core.RetryDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("job of dead letter %d is pending again\n", id)
+	return nil
+}
+
+var commitStateNames = map[int]string{
+	core.LAYER2MSG_COMMIT: "unconfirmed",
+	core.LAYER2MSG_FINISH: "finished",
+	core.LAYER2MSG_FAILED: "failed",
+}
+
+var jobStateNames = map[int]string{
+	core.JOB_PENDING: "pending",
+	core.JOB_RUNNING: "running",
+	core.JOB_DONE:    "done",
+	core.JOB_DEAD:    "dead",
+	core.JOB_HELD:    "held",
+}
+
+func runAdminCommits(ctx *cli.Context) error {
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	limit := ctx.Int(cmd.GetFlagName(cmd.CommitLimitFlag))
+	commits := core.LoadUnfinishedLayer2Commits(limit)
+	if commits == nil {
+		return fmt.Errorf("load layer2 commits failed")
+	}
+	fmt.Printf("commits:\n")
+	for _, commit := range commits {
+		fmt.Printf("%d\t%s\ttx: %s\tL1 height: %d\ttime: %d\n", commit.Layer2Height, commitStateNames[commit.State], commit.TxHash,
+			commit.Height, commit.TT)
+	}
+	jobs := core.LoadNextJobs(core.JOB_COMMIT, limit)
+	dead := core.LoadJobsByState(core.JOB_COMMIT, core.JOB_DEAD)
+	if jobs == nil || dead == nil {
+		return fmt.Errorf("load commit jobs failed")
+	}
+	fmt.Printf("commit jobs:\n")
+	for _, job := range append(dead, jobs...) {
+		fmt.Printf("%d\t%s\n", job.Key, jobStateNames[job.State])
+	}
+	return nil
+}
+
+func runAdminDryRun(ctx *cli.Context) error {
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	actions := core.LoadDryRunActions(0, ctx.Int(cmd.GetFlagName(cmd.CommitLimitFlag)))
+	if actions == nil {
+		return fmt.Errorf("load dry run actions failed")
+	}
+	for _, action := range actions {
+		fmt.Printf("%d\t%s\t%s\ttime: %d\n\t%s\n", action.ID, action.Kind, action.Subject, action.TT, action.Detail)
+	}
+	return nil
+}
+
+func runAdminShowCommit(ctx *cli.Context) error {
+	height := ctx.Uint64(cmd.GetFlagName(cmd.CommitHeightFlag))
+	if height == 0 {
+		return fmt.Errorf("height of the commit is required")
+	}
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	job := core.LoadJob(core.JOB_COMMIT, height)
+	if job != nil {
+		fmt.Printf("commit job: %s\n\tpayload: %s\n", jobStateNames[job.State], job.Payload)
+	} else {
+		fmt.Printf("commit job: not found\n")
+	}
+	commits := core.LoadLayer2CommitsByHeight(height)
+	if commits == nil {
+		return fmt.Errorf("load layer2 commits of height %d failed", height)
+	}
+	for _, commit := range commits {
+		fmt.Printf("commit %s\t%s\tL1 height: %d\tgas price: %d\tfee: %d\ttime: %d\n", commit.TxHash, commitStateNames[commit.State],
+			commit.Height, commit.GasPrice, commit.Fee, commit.TT)
+		fmt.Printf("\tpayload: %s\n", commit.Layer2Msg)
+	}
+	return nil
+}
+
+func runAdminResubmitCommit(ctx *cli.Context) error {
+	height := ctx.Uint64(cmd.GetFlagName(cmd.CommitHeightFlag))
+	if height == 0 {
+		return fmt.Errorf("height of the commit is required")
+	}
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	err = core.ResubmitCommit(height)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("commit job of height %d is pending again\n", height)
+	return nil
+}
+
+func runAdminSkipCommit(ctx *cli.Context) error {
+	height := ctx.Uint64(cmd.GetFlagName(cmd.CommitHeightFlag))
+	if height == 0 {
+		return fmt.Errorf("height of the commit is required")
+	}
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	err = core.SkipCommit(height)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("commit job of height %d is skipped\n", height)
+	return nil
+}
+
+//newKeyRotationOperator connect the database and create the operator of the config for the key rotation commands, the
+//blocks are not subscribed by them
+func newKeyRotationOperator(ctx *cli.Context) (*core.Layer2Operator, error) {
+	err := connectProjectDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	servConfig := config.NewServiceConfig(ConfigPath)
+	if servConfig == nil {
+		core.CloseDB()
+		return nil, fmt.Errorf("create config failed")
+	}
+	if servConfig.OntologyConfig != nil {
+		servConfig.OntologyConfig.WebSocketURL = ""
+	}
+	servConfig.Layer2Config.WebSocketURL = ""
+	operator, err := core.NewLayer2Operator(servConfig)
+	if err != nil {
+		core.CloseDB()
+		return nil, err
+	}
+	return operator, nil
+}
+
+func printKeyRotation(rotation *core.KeyRotation) {
+	states := []string{"registered", "switched", "cancelled"}
+	fmt.Printf("%d\tstate: %s\tperiod: %d\ttime: %d\n", rotation.ID, states[rotation.State], rotation.TransitionPeriod, rotation.TT)
+	fmt.Printf("\tontology: %s -> %s\n\tlayer2: %s -> %s\n", rotation.OntologyAddress, rotation.NextOntologyAddress,
+		rotation.Layer2Address, rotation.NextLayer2Address)
+	fmt.Printf("\tregister tx: %s\n\tswitch tx: %s\n", rotation.RegisterTxHash, rotation.SwitchTxHash)
+}
+
+func runKeyRotationRegister(ctx *cli.Context) error {
+	operator, err := newKeyRotationOperator(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	rotation, err := operator.RegisterKeyRotation(ctx.Uint64(cmd.GetFlagName(cmd.TransitionPeriodFlag)))
+	if err != nil {
+		return err
+	}
+	printKeyRotation(rotation)
+	return nil
+}
+
+func runKeyRotationSwitch(ctx *cli.Context) error {
+	operator, err := newKeyRotationOperator(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	rotation, err := operator.SwitchKeyRotation()
+	if err != nil {
+		return err
+	}
+	printKeyRotation(rotation)
+	return nil
+}
+
+func runKeyRotationCancel(ctx *cli.Context) error {
+	operator, err := newKeyRotationOperator(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	rotation, err := operator.CancelKeyRotation()
+	if err != nil {
+		return err
+	}
+	printKeyRotation(rotation)
+	return nil
+}
+
+func runKeyRotationStatus(ctx *cli.Context) error {
+	operator, err := newKeyRotationOperator(ctx)
+	if err != nil {
+		return err
+	}
+	defer core.CloseDB()
+	rotations := core.LoadKeyRotations()
+	if rotations == nil {
+		return fmt.Errorf("load key rotations failed")
+	}
+	for _, rotation := range rotations {
+		printKeyRotation(rotation)
+	}
+	state, err := operator.OperatorState()
+	if err != nil {
+		return err
+	}
+	address, err := ontology_common.AddressParseFromBytes(state.Operator)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("layer2 contract operator: %s\n", address.ToBase58())
+	if len(state.NewOperator) > 0 {
+		address, err = ontology_common.AddressParseFromBytes(state.NewOperator)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rotating to: %s, switch block: %d\n", address.ToBase58(), state.SwitchBlock)
+	}
+	return nil
+}
+
 func main() {
 	log.Infof("main - Layer2 Operator Starting...")
 	if err := setupApp().Run(os.Args); err != nil {
@@ -25,12 +25,25 @@ import (
 	"os"
 )
 
+const (
+	DEFAULT_COMMIT_INTERVAL        = 1
+	DEFAULT_ONTOLOGY_BLOCK_TIME    = 1
+	DEFAULT_ONTOLOGY_CONFIRMATIONS = 1
+	DEFAULT_CHALLENGE_WINDOW       = 0
+	DEFAULT_STATS_DAYS             = 7
+)
+
 var DefConfig = Config{
-	RestPort:           20334,
-	Version:            "",
-	HttpMaxConnections: 10000,
-	HttpCertPath:       "",
-	HttpKeyPath:        "",
+	RestPort:              20334,
+	Version:               "",
+	HttpMaxConnections:    10000,
+	HttpCertPath:          "",
+	HttpKeyPath:           "",
+	CommitInterval:        DEFAULT_COMMIT_INTERVAL,
+	OntologyBlockTime:     DEFAULT_ONTOLOGY_BLOCK_TIME,
+	OntologyConfirmations: DEFAULT_ONTOLOGY_CONFIRMATIONS,
+	ChallengeWindow:       DEFAULT_CHALLENGE_WINDOW,
+	StatsDays:             DEFAULT_STATS_DAYS,
 }
 
 type Config struct {
@@ -44,6 +57,13 @@ type Config struct {
 	ProjectDBUser      string  `json:"explorerdb_user"`
 	ProjectDBPassword  string  `json:"explorerdb_password"`
 	ProjectDBName      string  `json:"explorerdb_name"`
+	// withdraw eta estimation, used when there is not enough history in db
+	CommitInterval        uint32 `json:"commit_interval"`
+	OntologyBlockTime     uint32 `json:"ontology_block_time"`
+	OntologyConfirmations uint32 `json:"ontology_confirmations"`
+	ChallengeWindow       uint32 `json:"challenge_window"`
+	// days of the bridge statistics window when the query does not give one
+	StatsDays             uint32 `json:"stats_days"`
 }
 
 func InitConfig() error {
@@ -63,6 +83,18 @@ func InitConfig() error {
 	if cfg.RestPort == 0 {
 		return fmt.Errorf("not config the rest port")
 	}
+	if cfg.CommitInterval == 0 {
+		cfg.CommitInterval = DEFAULT_COMMIT_INTERVAL
+	}
+	if cfg.OntologyBlockTime == 0 {
+		cfg.OntologyBlockTime = DEFAULT_ONTOLOGY_BLOCK_TIME
+	}
+	if cfg.OntologyConfirmations == 0 {
+		cfg.OntologyConfirmations = DEFAULT_ONTOLOGY_CONFIRMATIONS
+	}
+	if cfg.StatsDays == 0 {
+		cfg.StatsDays = DEFAULT_STATS_DAYS
+	}
 	DefConfig = cfg
 	return nil
 }
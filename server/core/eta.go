@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"github.com/ontio/layer2/server/config"
+	"math"
+	"time"
+)
+
+type withdrawEstimator struct {
+	now            uint32
+	commitInterval uint32
+	blockTime      uint32
+	confirmTime    uint32
+	challengeTime  uint32
+	ontologyHeight uint32
+}
+
+func newWithdrawEstimator() *withdrawEstimator {
+	// the cadence of the operator commits, the configured interval until two commits are confirmed
+	commitInterval := config.DefConfig.CommitInterval
+	if interval := LoadCommitInterval(); interval > 0 {
+		commitInterval = uint32(math.Ceil(interval))
+	}
+	blockTime := config.DefConfig.OntologyBlockTime
+	if ontologyBlockTime := LoadOntologyBlockTime(); ontologyBlockTime > 0 {
+		blockTime = uint32(math.Ceil(ontologyBlockTime))
+	}
+	estimator := &withdrawEstimator{
+		now:            uint32(time.Now().Unix()),
+		commitInterval: commitInterval,
+		blockTime:      blockTime,
+		confirmTime:    config.DefConfig.OntologyConfirmations * blockTime,
+		challengeTime:  config.DefConfig.ChallengeWindow * blockTime,
+	}
+	if chain := LoadChainInfo("ontology"); chain != nil {
+		estimator.ontologyHeight = chain.Height
+	}
+	return estimator
+}
+
+// fill the stage and eta of withdraw, return false if the withdraw is not pending any more
+func (this *withdrawEstimator) estimate(withdraw *PendingWithdraw) bool {
+	switch {
+	case withdraw.State == WITHDRAW_INT:
+		// waiting for the operator to commit the layer2 block to ontology
+		commitTime := withdraw.TT + this.commitInterval
+		if commitTime < this.now {
+			commitTime = this.now
+		}
+		withdraw.Stage = WITHDRAW_STAGE_COMMIT
+		withdraw.ETA = commitTime + this.confirmTime + this.challengeTime
+	case withdraw.CommitState == LAYER2MSG_COMMIT:
+		// commit transaction is sent, waiting for ontology confirmation
+		withdraw.Stage = WITHDRAW_STAGE_CONFIRM
+		withdraw.ETA = this.now + this.confirmTime + this.challengeTime
+	case withdraw.CommitState == LAYER2MSG_FINISH:
+		// commit is confirmed, waiting for the challenge window to close
+		releaseHeight := withdraw.OntologyHeight + config.DefConfig.ChallengeWindow
+		if releaseHeight <= this.ontologyHeight {
+			return false
+		}
+		withdraw.Stage = WITHDRAW_STAGE_CHALLENGE
+		withdraw.ETA = this.now + (releaseHeight-this.ontologyHeight)*this.blockTime
+	default:
+		withdraw.Stage = WITHDRAW_STAGE_FAILED
+		withdraw.ETA = 0
+	}
+	return true
+}
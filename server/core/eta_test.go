@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"testing"
+)
+
+func TestAverageInterval(t *testing.T) {
+	cases := []struct {
+		count        uint64
+		minTT, maxTT uint32
+		interval     float64
+	}{
+		{0, 0, 0, 0},
+		{1, 100, 100, 0},
+		{2, 100, 100, 0},
+		{2, 100, 160, 60},
+		{5, 100, 190, 22.5},
+	}
+	for _, c := range cases {
+		if interval := averageInterval(c.count, c.minTT, c.maxTT); interval != c.interval {
+			t.Errorf("averageInterval(%d, %d, %d) = %v, want %v", c.count, c.minTT, c.maxTT, interval, c.interval)
+		}
+	}
+}
+
+func TestWithdrawEstimate(t *testing.T) {
+	estimator := &withdrawEstimator{now: 1000, commitInterval: 60, blockTime: 1, confirmTime: 10, challengeTime: 100}
+	withdraw := &PendingWithdraw{Withdraw: Withdraw{State: WITHDRAW_INT, TT: 980}}
+	if !estimator.estimate(withdraw) || withdraw.Stage != WITHDRAW_STAGE_COMMIT || withdraw.ETA != 980+60+10+100 {
+		t.Errorf("withdraw before the commit: %s %d", withdraw.Stage, withdraw.ETA)
+	}
+	// the commit is late, it is expected now
+	withdraw = &PendingWithdraw{Withdraw: Withdraw{State: WITHDRAW_INT, TT: 900}}
+	if !estimator.estimate(withdraw) || withdraw.ETA != 1000+10+100 {
+		t.Errorf("withdraw of the late commit: %s %d", withdraw.Stage, withdraw.ETA)
+	}
+	withdraw = &PendingWithdraw{Withdraw: Withdraw{State: WITHDRAW_COMMIT}, CommitState: LAYER2MSG_COMMIT}
+	if !estimator.estimate(withdraw) || withdraw.Stage != WITHDRAW_STAGE_CONFIRM || withdraw.ETA != 1000+10+100 {
+		t.Errorf("withdraw of the commit sent: %s %d", withdraw.Stage, withdraw.ETA)
+	}
+}
@@ -111,3 +111,73 @@ func (self *explorer) GetLayer2Withdraw(address string) (int64,string) {
 	json_crosstx, _ := json.Marshal(newLayer2Tx)
 	return SUCCESS, string(json_crosstx)
 }
+
+func (self *explorer) GetLayer2PendingWithdraw(address string) (int64,string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("recover info:", r)
+		}
+	}()
+
+	withdraws := LoadWithdrawWithCommitByAddress(address)
+	if withdraws == nil {
+		return DB_LOADDATA_FAILED, ""
+	}
+	estimator := newWithdrawEstimator()
+	pendingWithdraws := make([]*PendingWithdraw, 0)
+	for _, withdraw := range withdraws {
+		if estimator.estimate(withdraw) {
+			pendingWithdraws = append(pendingWithdraws, withdraw)
+		}
+	}
+	json_withdraw, _ := json.Marshal(pendingWithdraws)
+	return SUCCESS, string(json_withdraw)
+}
+
+func (self *explorer) GetDepositHistory(filter *HistoryFilter) (int64,string) {
+	return self.getHistory(LoadDepositHistory, filter)
+}
+
+func (self *explorer) GetWithdrawHistory(filter *HistoryFilter) (int64,string) {
+	return self.getHistory(LoadWithdrawHistory, filter)
+}
+
+func (self *explorer) GetLayer2TxHistory(filter *HistoryFilter) (int64,string) {
+	return self.getHistory(LoadLayer2TxHistory, filter)
+}
+
+func (self *explorer) getHistory(load func(*HistoryFilter) *HistoryPage, filter *HistoryFilter) (int64,string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("recover info:", r)
+		}
+	}()
+
+	page := load(filter)
+	if page == nil {
+		return DB_LOADDATA_FAILED, ""
+	}
+	json_page, _ := json.Marshal(page)
+	return SUCCESS, string(json_page)
+}
+
+func (self *explorer) GetBridgeStats(days uint32) (int64,string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("recover info:", r)
+		}
+	}()
+
+	if days == 0 {
+		days = config.DefConfig.StatsDays
+	}
+	if days > STATS_MAX_DAYS {
+		days = STATS_MAX_DAYS
+	}
+	stats := loadBridgeStats(days)
+	if stats == nil {
+		return DB_LOADDATA_FAILED, ""
+	}
+	json_stats, _ := json.Marshal(stats)
+	return SUCCESS, string(json_stats)
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"strings"
+)
+
+const (
+	HISTORY_DEFAULT_PAGE_SIZE = 20
+	HISTORY_MAX_PAGE_SIZE     = 100
+)
+
+// the filter of the deposit, withdraw and layer2tx history, the zero value of a field matches all records
+type HistoryFilter struct {
+	Address      string
+	TokenAddress string
+	State        int
+	HasState     bool
+	StartTime    uint32
+	EndTime      uint32
+	PageNumber   uint32
+	PageSize     uint32
+}
+
+type HistoryPage struct {
+	Total      uint64
+	PageNumber uint32
+	PageSize   uint32
+	Records    interface{}
+}
+
+// the where clause of the filter, the address matches any of the address columns
+func (this *HistoryFilter) where(addressColumns ...string) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if this.Address != "" {
+		addresses := make([]string, 0, len(addressColumns))
+		for _, column := range addressColumns {
+			addresses = append(addresses, column+" = ?")
+			args = append(args, this.Address)
+		}
+		conditions = append(conditions, "("+strings.Join(addresses, " or ")+")")
+	}
+	if this.TokenAddress != "" {
+		conditions = append(conditions, "tokenaddress = ?")
+		args = append(args, this.TokenAddress)
+	}
+	if this.HasState {
+		conditions = append(conditions, "state = ?")
+		args = append(args, this.State)
+	}
+	if this.StartTime > 0 {
+		conditions = append(conditions, "tt >= ?")
+		args = append(args, this.StartTime)
+	}
+	if this.EndTime > 0 {
+		conditions = append(conditions, "tt <= ?")
+		args = append(args, this.EndTime)
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " where " + strings.Join(conditions, " and "), args
+}
+
+// the limit clause of the page, the page number starts from 1
+func (this *HistoryFilter) limit(args []interface{}) (string, []interface{}) {
+	pageSize := this.PageSize
+	if pageSize == 0 {
+		pageSize = HISTORY_DEFAULT_PAGE_SIZE
+	}
+	if pageSize > HISTORY_MAX_PAGE_SIZE {
+		pageSize = HISTORY_MAX_PAGE_SIZE
+	}
+	pageNumber := this.PageNumber
+	if pageNumber == 0 {
+		pageNumber = 1
+	}
+	this.PageSize, this.PageNumber = pageSize, pageNumber
+	return " limit ? offset ?", append(args, pageSize, uint64(pageNumber-1)*uint64(pageSize))
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistoryFilterWhere(t *testing.T) {
+	cases := []struct {
+		name    string
+		filter  HistoryFilter
+		columns []string
+		clause  string
+		args    []interface{}
+	}{
+		{"empty", HistoryFilter{}, []string{"fromaddress"}, "", []interface{}{}},
+		{"address of any column", HistoryFilter{Address: "addr"}, []string{"fromaddress", "toaddress"},
+			" where (fromaddress = ? or toaddress = ?)", []interface{}{"addr", "addr"}},
+		{"state 0", HistoryFilter{HasState: true}, []string{"fromaddress"}, " where state = ?", []interface{}{0}},
+		{"all", HistoryFilter{Address: "addr", TokenAddress: "token", State: 2, HasState: true, StartTime: 10, EndTime: 20},
+			[]string{"toaddress"},
+			" where (toaddress = ?) and tokenaddress = ? and state = ? and tt >= ? and tt <= ?",
+			[]interface{}{"addr", "token", 2, uint32(10), uint32(20)}},
+	}
+	for _, c := range cases {
+		clause, args := c.filter.where(c.columns...)
+		if clause != c.clause || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%s: where() = %q, %v, want %q, %v", c.name, clause, args, c.clause, c.args)
+		}
+	}
+}
+
+func TestHistoryFilterLimit(t *testing.T) {
+	cases := []struct {
+		filter     HistoryFilter
+		pageNumber uint32
+		pageSize   uint32
+		offset     uint64
+	}{
+		{HistoryFilter{}, 1, HISTORY_DEFAULT_PAGE_SIZE, 0},
+		{HistoryFilter{PageNumber: 3, PageSize: 10}, 3, 10, 20},
+		{HistoryFilter{PageNumber: 2, PageSize: HISTORY_MAX_PAGE_SIZE + 1}, 2, HISTORY_MAX_PAGE_SIZE, HISTORY_MAX_PAGE_SIZE},
+	}
+	for _, c := range cases {
+		filter := c.filter
+		clause, args := filter.limit([]interface{}{"addr"})
+		want := []interface{}{"addr", c.pageSize, c.offset}
+		if clause != " limit ? offset ?" || !reflect.DeepEqual(args, want) {
+			t.Errorf("limit() of %+v = %q, %v, want %v", c.filter, clause, args, want)
+		}
+		if filter.PageNumber != c.pageNumber || filter.PageSize != c.pageSize {
+			t.Errorf("page of %+v is %d/%d, want %d/%d", c.filter, filter.PageNumber, filter.PageSize, c.pageNumber, c.pageSize)
+		}
+	}
+}
@@ -360,6 +360,404 @@ func LoadLayer2Commit_Unconfirmed() []string {
 	return txHashs
 }
 
+func LoadWithdrawWithCommitByAddress(address string) []*PendingWithdraw {
+	strsql := "select w.txhash, w.tt, w.state, w.height, w.toaddress, w.amount, w.tokenaddress, ifnull(w.ontologytxhash,''), ifnull(c.state,?), ifnull(c.ontologyheight,0) " +
+		"from withdraw w left join layer2commit c on w.ontologytxhash = c.txhash where w.toaddress = ? order by w.height"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(LAYER2MSG_COMMIT, address)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var tt, height, ontologyHeight uint32
+	var state, commitState int
+	var amount uint64
+	var txhash, toaddress, tokenaddress, ontologytxhash string
+	withdraws := make([]*PendingWithdraw, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &toaddress, &amount, &tokenaddress, &ontologytxhash, &commitState, &ontologyHeight); err != nil {
+			return nil
+		} else {
+			withdraws = append(withdraws, &PendingWithdraw{
+				Withdraw: Withdraw{
+					TxHash:         txhash,
+					TT:             tt,
+					State:          state,
+					Height:         height,
+					ToAddress:      toaddress,
+					Amount:         amount,
+					TokenAddress:   tokenaddress,
+					OntologyTxHash: ontologytxhash,
+				},
+				CommitState:    commitState,
+				OntologyHeight: ontologyHeight,
+			})
+		}
+	}
+	return withdraws
+}
+
+// the average seconds between blocks over the latest records of a table with height and tt
+func loadAverageBlockTime(strsql string) float64 {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return 0
+	}
+	rows, err := stmt.Query(BLOCK_TIME_SAMPLES)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return 0
+	}
+
+	var minHeight, maxHeight, minTT, maxTT uint32
+	for rows.Next() {
+		if err = rows.Scan(&minHeight, &maxHeight, &minTT, &maxTT); err != nil {
+			return 0
+		}
+		if maxHeight <= minHeight || maxTT <= minTT {
+			return 0
+		}
+		return float64(maxTT-minTT) / float64(maxHeight-minHeight)
+	}
+	return 0
+}
+
+// the average seconds between the latest confirmed commits of layer2 to ontology
+func LoadCommitInterval() float64 {
+	strsql := "select count(*), ifnull(min(tt),0), ifnull(max(tt),0) from " +
+		"(select tt from layer2commit where state = ? order by layer2height desc limit ?) t"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return 0
+	}
+	var count uint64
+	var minTT, maxTT uint32
+	if err = stmt.QueryRow(LAYER2MSG_FINISH, BLOCK_TIME_SAMPLES).Scan(&count, &minTT, &maxTT); err != nil {
+		return 0
+	}
+	return averageInterval(count, minTT, maxTT)
+}
+
+// the average seconds between count records from minTT to maxTT, 0 if there are less than two records
+func averageInterval(count uint64, minTT, maxTT uint32) float64 {
+	if count < 2 || maxTT <= minTT {
+		return 0
+	}
+	return float64(maxTT-minTT) / float64(count-1)
+}
 
+func LoadOntologyBlockTime() float64 {
+	return loadAverageBlockTime("select ifnull(min(height),0), ifnull(max(height),0), ifnull(min(tt),0), ifnull(max(tt),0) from " +
+		"(select height, tt from deposit order by height desc limit ?) t")
+}
 
+func countHistory(strsql string, args []interface{}) (uint64, error) {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	err = stmt.QueryRow(args...).Scan(&total)
+	return total, err
+}
 
+func LoadDepositHistory(filter *HistoryFilter) *HistoryPage {
+	where, args := filter.where("fromaddress")
+	total, err := countHistory("select count(*) from deposit"+where, args)
+	if err != nil {
+		return nil
+	}
+	limit, args := filter.limit(args)
+	strsql := "select txhash, tt, state, height, fromaddress, amount, tokenaddress, id, ifnull(layer2txhash,'') from deposit" +
+		where + " order by id desc" + limit
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var tt, height uint32
+	var state int
+	var amount, id uint64
+	var txhash, fromaddress, tokenaddress, layer2txhash string
+	deposits := make([]*Deposit, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &fromaddress, &amount, &tokenaddress, &id, &layer2txhash); err != nil {
+			return nil
+		} else {
+			deposits = append(deposits, &Deposit{
+				TxHash:       txhash,
+				TT:           tt,
+				State:        state,
+				Height:       height,
+				FromAddress:  fromaddress,
+				Amount:       amount,
+				TokenAddress: tokenaddress,
+				ID:           id,
+				Layer2TxHash: layer2txhash,
+			})
+		}
+	}
+	return &HistoryPage{Total: total, PageNumber: filter.PageNumber, PageSize: filter.PageSize, Records: deposits}
+}
+
+func LoadWithdrawHistory(filter *HistoryFilter) *HistoryPage {
+	where, args := filter.where("toaddress")
+	total, err := countHistory("select count(*) from withdraw"+where, args)
+	if err != nil {
+		return nil
+	}
+	limit, args := filter.limit(args)
+	strsql := "select txhash, tt, state, height, toaddress, amount, tokenaddress, ifnull(ontologytxhash,'') from withdraw" +
+		where + " order by height desc, txhash" + limit
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var tt, height uint32
+	var state int
+	var amount uint64
+	var txhash, toaddress, tokenaddress, ontologytxhash string
+	withdraws := make([]*Withdraw, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &tt, &state, &height, &toaddress, &amount, &tokenaddress, &ontologytxhash); err != nil {
+			return nil
+		} else {
+			withdraws = append(withdraws, &Withdraw{
+				TxHash:         txhash,
+				TT:             tt,
+				State:          state,
+				Height:         height,
+				ToAddress:      toaddress,
+				Amount:         amount,
+				TokenAddress:   tokenaddress,
+				OntologyTxHash: ontologytxhash,
+			})
+		}
+	}
+	return &HistoryPage{Total: total, PageNumber: filter.PageNumber, PageSize: filter.PageSize, Records: withdraws}
+}
+
+func LoadLayer2TxHistory(filter *HistoryFilter) *HistoryPage {
+	where, args := filter.where("fromaddress", "toaddress")
+	total, err := countHistory("select count(*) from layer2tx"+where, args)
+	if err != nil {
+		return nil
+	}
+	limit, args := filter.limit(args)
+	strsql := "select txhash, state, tt, fee, height, fromaddress, tokenaddress, toaddress, amount from layer2tx" +
+		where + " order by height desc, txhash, notifyindex" + limit
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var tt, height uint32
+	var state int
+	var fee, amount uint64
+	var txhash, fromaddress, tokenaddress, toaddress string
+	layer2Txs := make([]*Layer2Tx, 0)
+	for rows.Next() {
+		if err = rows.Scan(&txhash, &state, &tt, &fee, &height, &fromaddress, &tokenaddress, &toaddress, &amount); err != nil {
+			return nil
+		} else {
+			layer2Txs = append(layer2Txs, &Layer2Tx{
+				TxHash:       txhash,
+				State:        state,
+				TT:           tt,
+				Fee:          fee,
+				Height:       height,
+				FromAddress:  fromaddress,
+				TokenAddress: tokenaddress,
+				ToAddress:    toaddress,
+				Amount:       amount,
+			})
+		}
+	}
+	return &HistoryPage{Total: total, PageNumber: filter.PageNumber, PageSize: filter.PageSize, Records: layer2Txs}
+}
+
+// the rows of day, token and two sums, the day is 0 when the sums are not grouped by day
+func loadTokenAggregates(strsql string, args ...interface{}) []*TokenAggregate {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query(args...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var day uint32
+	var token string
+	var first, second uint64
+	aggregates := make([]*TokenAggregate, 0)
+	for rows.Next() {
+		if err = rows.Scan(&day, &token, &first, &second); err != nil {
+			return nil
+		} else {
+			aggregates = append(aggregates, &TokenAggregate{Day: day, TokenAddress: token, First: first, Second: second})
+		}
+	}
+	return aggregates
+}
+
+func LoadDepositTVL() []*TokenAggregate {
+	return loadTokenAggregates("select 0, tokenaddress, "+
+		"ifnull(sum(case when state not in (?, ?) then amount else 0 end),0), "+
+		"ifnull(sum(case when state in (?, ?) then amount else 0 end),0) from deposit group by tokenaddress",
+		DEPOSIT_REORGED, DEPOSIT_REFUNDED, DEPOSIT_FINISH, DEPOSIT_NOTIFY)
+}
+
+// the withdraws found committed on ontology have no commit transaction hash and are released too
+func LoadWithdrawTVL() []*TokenAggregate {
+	return loadTokenAggregates("select 0, ifnull(t.address, w.tokenaddress), ifnull(sum(w.amount),0), "+
+		"ifnull(sum(case when w.state = ? and ifnull(c.state,?) = ? then w.amount else 0 end),0) "+
+		"from withdraw w left join token t on w.tokenaddress = t.layer2address left join layer2commit c on w.ontologytxhash = c.txhash "+
+		"group by ifnull(t.address, w.tokenaddress)",
+		WITHDRAW_COMMIT, LAYER2MSG_FINISH, LAYER2MSG_FINISH)
+}
+
+func LoadDailyDeposits(since uint32) []*TokenAggregate {
+	return loadTokenAggregates("select tt - tt % 86400, tokenaddress, count(*), ifnull(sum(amount),0) from deposit "+
+		"where tt >= ? and state not in (?, ?) group by tt - tt % 86400, tokenaddress",
+		since, DEPOSIT_REORGED, DEPOSIT_REFUNDED)
+}
+
+func LoadDailyWithdraws(since uint32) []*TokenAggregate {
+	return loadTokenAggregates("select w.tt - w.tt % 86400, ifnull(t.address, w.tokenaddress), count(*), ifnull(sum(w.amount),0) "+
+		"from withdraw w left join token t on w.tokenaddress = t.layer2address "+
+		"where w.tt >= ? group by w.tt - w.tt % 86400, ifnull(t.address, w.tokenaddress)",
+		since)
+}
+
+func loadAverageSeconds(strsql string, args ...interface{}) float64 {
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return 0
+	}
+	var average float64
+	if err = stmt.QueryRow(args...).Scan(&average); err != nil {
+		return 0
+	}
+	return average
+}
+
+// the seconds from the deposit on ontology to the mint transaction on layer2
+func LoadDepositFinalizationTime(since uint32) float64 {
+	return loadAverageSeconds("select ifnull(avg(l.tt - d.tt),0) from deposit d join layer2tx l on d.layer2txhash = l.txhash "+
+		"where d.tt >= ? and l.tt >= d.tt and d.state in (?, ?)",
+		since, DEPOSIT_FINISH, DEPOSIT_NOTIFY)
+}
+
+// the seconds from the withdraw on layer2 to the confirmed commit transaction on ontology
+func LoadWithdrawFinalizationTime(since uint32) float64 {
+	return loadAverageSeconds("select ifnull(avg(c.tt - w.tt),0) from withdraw w join layer2commit c on w.ontologytxhash = c.txhash "+
+		"where w.tt >= ? and c.tt >= w.tt and c.state = ?",
+		since, LAYER2MSG_FINISH)
+}
+
+func LoadCommitResults(since uint32) (uint64, uint64) {
+	strsql := "select ifnull(sum(case when state = ? then 1 else 0 end),0), ifnull(sum(case when state = ? then 1 else 0 end),0) " +
+		"from layer2commit where tt >= ?"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return 0, 0
+	}
+	var succeeded, failed uint64
+	if err = stmt.QueryRow(LAYER2MSG_FINISH, LAYER2MSG_FAILED, since).Scan(&succeeded, &failed); err != nil {
+		return 0, 0
+	}
+	return succeeded, failed
+}
+
+func LoadTokens() map[string]*Token {
+	strsql := "select address, layer2address, ifnull(name,'') from token"
+	stmt, err := DefDB.Prepare(strsql)
+	if stmt != nil {
+		defer stmt.Close()
+	}
+	if err != nil {
+		return nil
+	}
+	rows, err := stmt.Query()
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil
+	}
+
+	var address, layer2address, name string
+	tokens := make(map[string]*Token)
+	for rows.Next() {
+		if err = rows.Scan(&address, &layer2address, &name); err != nil {
+			return nil
+		} else {
+			tokens[address] = &Token{Address: address, Layer2Address: layer2address, Name: name}
+		}
+	}
+	return tokens
+}
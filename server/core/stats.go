@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2020 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	STATS_DAY_SECONDS = 86400
+	STATS_MAX_DAYS    = 365
+)
+
+// the value of a token locked in the contract on ontology and circulating on layer2
+type TokenTVL struct {
+	TokenAddress       string
+	Layer2TokenAddress string
+	Name               string
+	Locked             uint64
+	Circulating        uint64
+}
+
+type DailyVolume struct {
+	Day            uint32
+	TokenAddress   string
+	DepositCount   uint64
+	DepositAmount  uint64
+	WithdrawCount  uint64
+	WithdrawAmount uint64
+}
+
+type BridgeStats struct {
+	Days                     uint32
+	Since                    uint32
+	TVL                      []*TokenTVL
+	DailyVolumes             []*DailyVolume
+	DepositFinalizationTime  float64
+	WithdrawFinalizationTime float64
+	CommitSucceeded          uint64
+	CommitFailed             uint64
+	CommitSuccessRate        float64
+}
+
+// the tvl is of all the history, the volumes, finalization time and commit results are of the latest days
+func loadBridgeStats(days uint32) *BridgeStats {
+	now := uint32(time.Now().Unix())
+	since := now - now%STATS_DAY_SECONDS - (days-1)*STATS_DAY_SECONDS
+	tokens := LoadTokens()
+	depositTVL := LoadDepositTVL()
+	withdrawTVL := LoadWithdrawTVL()
+	dailyDeposits := LoadDailyDeposits(since)
+	dailyWithdraws := LoadDailyWithdraws(since)
+	if tokens == nil || depositTVL == nil || withdrawTVL == nil || dailyDeposits == nil || dailyWithdraws == nil {
+		return nil
+	}
+	stats := &BridgeStats{
+		Days:                     days,
+		Since:                    since,
+		TVL:                      make([]*TokenTVL, 0),
+		DailyVolumes:             make([]*DailyVolume, 0),
+		DepositFinalizationTime:  LoadDepositFinalizationTime(since),
+		WithdrawFinalizationTime: LoadWithdrawFinalizationTime(since),
+	}
+	stats.CommitSucceeded, stats.CommitFailed = LoadCommitResults(since)
+	if total := stats.CommitSucceeded + stats.CommitFailed; total > 0 {
+		stats.CommitSuccessRate = float64(stats.CommitSucceeded) / float64(total)
+	}
+
+	tvls := make(map[string]*TokenTVL)
+	tvl := func(tokenAddress string) *TokenTVL {
+		if _, ok := tvls[tokenAddress]; !ok {
+			tvls[tokenAddress] = &TokenTVL{TokenAddress: tokenAddress}
+			if token, ok := tokens[tokenAddress]; ok {
+				tvls[tokenAddress].Layer2TokenAddress = token.Layer2Address
+				tvls[tokenAddress].Name = token.Name
+			}
+			stats.TVL = append(stats.TVL, tvls[tokenAddress])
+		}
+		return tvls[tokenAddress]
+	}
+	// locked by deposits and released by the committed withdraws, minted by deposits and burned by all the withdraws
+	for _, deposit := range depositTVL {
+		token := tvl(deposit.TokenAddress)
+		token.Locked, token.Circulating = deposit.First, deposit.Second
+	}
+	for _, withdraw := range withdrawTVL {
+		token := tvl(withdraw.TokenAddress)
+		token.Circulating = subtractAmount(token.Circulating, withdraw.First)
+		token.Locked = subtractAmount(token.Locked, withdraw.Second)
+	}
+	sort.Slice(stats.TVL, func(i, j int) bool {
+		return stats.TVL[i].TokenAddress < stats.TVL[j].TokenAddress
+	})
+
+	volumes := make(map[TokenAggregate]*DailyVolume)
+	volume := func(day uint32, tokenAddress string) *DailyVolume {
+		key := TokenAggregate{Day: day, TokenAddress: tokenAddress}
+		if _, ok := volumes[key]; !ok {
+			volumes[key] = &DailyVolume{Day: day, TokenAddress: tokenAddress}
+			stats.DailyVolumes = append(stats.DailyVolumes, volumes[key])
+		}
+		return volumes[key]
+	}
+	for _, deposit := range dailyDeposits {
+		daily := volume(deposit.Day, deposit.TokenAddress)
+		daily.DepositCount, daily.DepositAmount = deposit.First, deposit.Second
+	}
+	for _, withdraw := range dailyWithdraws {
+		daily := volume(withdraw.Day, withdraw.TokenAddress)
+		daily.WithdrawCount, daily.WithdrawAmount = withdraw.First, withdraw.Second
+	}
+	sort.Slice(stats.DailyVolumes, func(i, j int) bool {
+		if stats.DailyVolumes[i].Day != stats.DailyVolumes[j].Day {
+			return stats.DailyVolumes[i].Day > stats.DailyVolumes[j].Day
+		}
+		return stats.DailyVolumes[i].TokenAddress < stats.DailyVolumes[j].TokenAddress
+	})
+	return stats
+}
+
+func subtractAmount(a uint64, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
@@ -40,6 +40,11 @@ const (
 	DEPOSIT_COMMIT
 	DEPOSIT_FINISH
 	DEPOSIT_NOTIFY
+	DEPOSIT_FAILED
+	DEPOSIT_QUARANTINE
+	DEPOSIT_REORGED
+	DEPOSIT_REJECTED
+	DEPOSIT_REFUNDED
 )
 
 const (
@@ -50,8 +55,18 @@ const (
 const (
 	LAYER2MSG_COMMIT = iota
 	LAYER2MSG_FINISH
+	LAYER2MSG_FAILED
 )
 
+const (
+	WITHDRAW_STAGE_COMMIT     = "commit"
+	WITHDRAW_STAGE_CONFIRM    = "confirm"
+	WITHDRAW_STAGE_CHALLENGE  = "challenge"
+	WITHDRAW_STAGE_FAILED     = "failed"
+)
+
+const BLOCK_TIME_SAMPLES = 1000
+
 type ChainInfo struct {
 	Name        string
 	Id          uint32
@@ -96,6 +111,14 @@ func (this *Withdraw) Dump() string {
 	return dumpStr
 }
 
+type PendingWithdraw struct {
+	Withdraw
+	CommitState       int
+	OntologyHeight    uint32
+	Stage             string
+	ETA               uint32
+}
+
 type Layer2Tx struct {
 	TxHash           string
 	State            int
@@ -115,6 +138,20 @@ func (this *Layer2Tx) Dump() string {
 	return dumpStr
 }
 
+type Token struct {
+	Address          string
+	Layer2Address    string
+	Name             string
+}
+
+// the sums of a token, grouped by the unix time of the day if Day is not 0
+type TokenAggregate struct {
+	Day              uint32
+	TokenAddress     string
+	First            uint64
+	Second           uint64
+}
+
 type Layer2CommitMsg struct {
 	Layer2State       *common.Layer2State
 	Deposits          []uint32
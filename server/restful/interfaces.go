@@ -20,6 +20,7 @@ package restful
 
 import (
 	"github.com/ontio/layer2/server/core"
+	"strconv"
 )
 
 //start
@@ -72,4 +73,99 @@ func GetLayer2Withdraw(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(core.SUCCESS)
 	resp["result"] = result
 	return resp
-}
\ No newline at end of file
+}
+
+func GetLayer2PendingWithdraw(cmd map[string]interface{}) map[string]interface{} {
+	if cmd["address"] == nil {
+		return ResponsePack(core.REST_PARAM_INVALID)
+	}
+	address, ok := cmd["address"].(string)
+	if !ok {
+		return ResponsePack(core.REST_PARAM_INVALID)
+	}
+	code, result := core.Explorer.GetLayer2PendingWithdraw(address)
+	if code != core.SUCCESS {
+		return ResponsePack(code)
+	}
+	resp := ResponsePack(core.SUCCESS)
+	resp["result"] = result
+	return resp
+}
+
+func GetDepositHistory(cmd map[string]interface{}) map[string]interface{} {
+	return getHistory(cmd, core.Explorer.GetDepositHistory)
+}
+
+func GetWithdrawHistory(cmd map[string]interface{}) map[string]interface{} {
+	return getHistory(cmd, core.Explorer.GetWithdrawHistory)
+}
+
+func GetLayer2TxHistory(cmd map[string]interface{}) map[string]interface{} {
+	return getHistory(cmd, core.Explorer.GetLayer2TxHistory)
+}
+
+func getHistory(cmd map[string]interface{}, query func(*core.HistoryFilter) (int64, string)) map[string]interface{} {
+	filter, ok := parseHistoryFilter(cmd)
+	if !ok {
+		return ResponsePack(core.REST_PARAM_INVALID)
+	}
+	code, result := query(filter)
+	if code != core.SUCCESS {
+		return ResponsePack(code)
+	}
+	resp := ResponsePack(core.SUCCESS)
+	resp["result"] = result
+	return resp
+}
+
+// the filter of the query parameters, a parameter not set matches all records
+func parseHistoryFilter(cmd map[string]interface{}) (*core.HistoryFilter, bool) {
+	filter := &core.HistoryFilter{}
+	filter.Address, _ = cmd["address"].(string)
+	filter.TokenAddress, _ = cmd["token"].(string)
+	if state, _ := cmd["state"].(string); state != "" {
+		value, err := strconv.Atoi(state)
+		if err != nil {
+			return nil, false
+		}
+		filter.State, filter.HasState = value, true
+	}
+	numbers := map[string]*uint32{
+		"start":    &filter.StartTime,
+		"end":      &filter.EndTime,
+		"page":     &filter.PageNumber,
+		"pagesize": &filter.PageSize,
+	}
+	for key, number := range numbers {
+		value, _ := cmd[key].(string)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, false
+		}
+		*number = uint32(parsed)
+	}
+	if filter.EndTime > 0 && filter.StartTime > filter.EndTime {
+		return nil, false
+	}
+	return filter, true
+}
+
+func GetBridgeStats(cmd map[string]interface{}) map[string]interface{} {
+	var days uint64
+	if value, _ := cmd["days"].(string); value != "" {
+		var err error
+		if days, err = strconv.ParseUint(value, 10, 32); err != nil {
+			return ResponsePack(core.REST_PARAM_INVALID)
+		}
+	}
+	code, result := core.Explorer.GetBridgeStats(uint32(days))
+	if code != core.SUCCESS {
+		return ResponsePack(code)
+	}
+	resp := ResponsePack(core.SUCCESS)
+	resp["result"] = result
+	return resp
+}
@@ -56,6 +56,11 @@ const (
 	GET_LAYER2TX    = "/api/v1/getlayer2tx/:address"
 	GET_LAYER2DEPOSIT    = "/api/v1/getlayer2deposit/:address"
 	GET_LAYER2WITHDRAW    = "/api/v1/getlayer2withdraw/:address"
+	GET_LAYER2PENDINGWITHDRAW    = "/api/v1/getlayer2pendingwithdraw/:address"
+	GET_DEPOSITHISTORY    = "/api/v1/history/deposit"
+	GET_WITHDRAWHISTORY    = "/api/v1/history/withdraw"
+	GET_LAYER2TXHISTORY    = "/api/v1/history/layer2tx"
+	GET_BRIDGESTATS    = "/api/v1/stats"
 )
 
 //init restful server
@@ -114,6 +119,11 @@ func (this *restServer) registryMethod() {
 		GET_LAYER2TX:  {name: "getlayer2tx", handler: GetLayer2Tx},
 		GET_LAYER2DEPOSIT:  {name: "getlayer2deposit", handler: GetLayer2Deposit},
 		GET_LAYER2WITHDRAW:  {name: "getlayer2withdraw", handler: GetLayer2Withdraw},
+		GET_LAYER2PENDINGWITHDRAW:  {name: "getlayer2pendingwithdraw", handler: GetLayer2PendingWithdraw},
+		GET_DEPOSITHISTORY:  {name: "getdeposithistory", handler: GetDepositHistory},
+		GET_WITHDRAWHISTORY:  {name: "getwithdrawhistory", handler: GetWithdrawHistory},
+		GET_LAYER2TXHISTORY:  {name: "getlayer2txhistory", handler: GetLayer2TxHistory},
+		GET_BRIDGESTATS:  {name: "getbridgestats", handler: GetBridgeStats},
 	}
 
 	// todo
@@ -133,6 +143,9 @@ func (this *restServer) getPath(url string) string {
 	if strings.Contains(url, strings.TrimRight(GET_LAYER2WITHDRAW, ":address")) {
 		return GET_LAYER2WITHDRAW
 	}
+	if strings.Contains(url, strings.TrimRight(GET_LAYER2PENDINGWITHDRAW, ":address")) {
+		return GET_LAYER2PENDINGWITHDRAW
+	}
 	return url
 }
 
@@ -145,6 +158,15 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 		req["address"] = getParam(r, "address")
 	case GET_LAYER2WITHDRAW:
 		req["address"] = getParam(r, "address")
+	case GET_LAYER2PENDINGWITHDRAW:
+		req["address"] = getParam(r, "address")
+	case GET_DEPOSITHISTORY, GET_WITHDRAWHISTORY, GET_LAYER2TXHISTORY:
+		query := r.URL.Query()
+		for _, key := range []string{"address", "token", "state", "start", "end", "page", "pagesize"} {
+			req[key] = query.Get(key)
+		}
+	case GET_BRIDGESTATS:
+		req["days"] = r.URL.Query().Get("days")
 	default:
 	}
 	return req